@@ -0,0 +1,199 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// max number of concurrent workers to copy model runs or worksets
+const maxWorkers = 32
+
+// copyCheckpoint is a list of completed steps of entire model copy: model runs, worksets and output tables.
+// Each completed step appended as a line into checkpoint file, it allows to resume interrupted copy.
+// Checkpoint can be nil, it means there is no checkpoint and all steps must be done.
+type copyCheckpoint struct {
+	path    string          // checkpoint file path
+	theLock sync.Mutex      // mutex to lock checkpoint file updates
+	done    map[string]bool // completed steps, e.g.: run:digest or set:name
+}
+
+// return checkpoint key of model run, key must be unique across all model runs
+func runCheckpointKey(runDigest string) string { return "run:" + runDigest }
+
+// return checkpoint key of workset, key must be unique across all model worksets
+func setCheckpointKey(setName string) string { return "set:" + setName }
+
+// newCheckpoint read completed steps from existing checkpoint file if isResume is true.
+// If isResume is false then return nil checkpoint: all steps must be done and checkpoint file is not created,
+// existing checkpoint file deleted because it is outdated by copy from the beginning.
+func newCheckpoint(path string, isResume bool) (*copyCheckpoint, error) {
+
+	if !isResume {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, helper.ErrorNew("Error: unable to delete:", path, err)
+		}
+		return nil, nil
+	}
+	cp := copyCheckpoint{path: path, done: map[string]bool{}}
+
+	// resume: read all completed steps from checkpoint file, if file exists
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			omppLog.Log("Checkpoint not found, copy from the beginning:", path)
+			return &cp, nil
+		}
+		return nil, helper.ErrorNew("Error at reading checkpoint:", path, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if s := strings.TrimSpace(sc.Text()); s != "" {
+			cp.done[s] = true
+		}
+	}
+	if err = sc.Err(); err != nil {
+		return nil, helper.ErrorNew("Error at reading checkpoint:", path, err)
+	}
+
+	omppLog.Log("Resume from checkpoint:", path, "completed steps:", len(cp.done))
+	return &cp, nil
+}
+
+// isDone return true if step is completed
+func (cp *copyCheckpoint) isDone(key string) bool {
+	if cp == nil {
+		return false
+	}
+	cp.theLock.Lock()
+	defer cp.theLock.Unlock()
+	return cp.done[key]
+}
+
+// setDone append completed step into checkpoint file
+func (cp *copyCheckpoint) setDone(key string) error {
+	if cp == nil {
+		return nil
+	}
+	cp.theLock.Lock()
+	defer cp.theLock.Unlock()
+
+	f, err := os.OpenFile(cp.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return helper.ErrorNew("Error at writing checkpoint:", cp.path, err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(key + "\n"); err != nil {
+		return helper.ErrorNew("Error at writing checkpoint:", cp.path, err)
+	}
+	cp.done[key] = true
+	return nil
+}
+
+// remove checkpoint file after successful copy of entire model
+func (cp *copyCheckpoint) remove() error {
+	if cp == nil {
+		return nil
+	}
+	if err := os.Remove(cp.path); err != nil && !os.IsNotExist(err) {
+		return helper.ErrorNew("Error: unable to delete:", cp.path, err)
+	}
+	return nil
+}
+
+// limit number of concurrent workers and database connections by database vendor.
+// SQLite allows only one writer: if destination is SQLite database then copy is done by single worker.
+// If there are multiple workers then source connections limited by number of workers
+// and destination connections limited by twice of that, each writer may use a query and a transaction.
+func workersByDb(nWorkers int, srcDb *sql.DB, dstDb *db.Dbc) int {
+
+	n := nWorkers
+	if n < 1 {
+		n = 1
+	}
+	if n > maxWorkers {
+		omppLog.Log("Warning: number of workers reduced to", maxWorkers)
+		n = maxWorkers
+	}
+	if n > 1 && dstDb != nil && dstDb.Dbf == db.SqliteFacet {
+		omppLog.Log("Warning: SQLite database allows only single writer, copy by one worker")
+		n = 1
+	}
+	if n <= 1 {
+		return 1 // single worker: do not limit database connections
+	}
+
+	srcDb.SetMaxOpenConns(n)
+	if dstDb != nil {
+		dstDb.SetMaxOpenConns(2 * n)
+	}
+	return n
+}
+
+// doByWorkers call doWork(k) for each k = 0,...,count-1 by nWorkers concurrent goroutines.
+// If there is an error then new work is not started and first error returned.
+func doByWorkers(nWorkers int, count int, doWork func(k int) error) error {
+
+	if nWorkers <= 1 || count <= 1 {
+		for k := 0; k < count; k++ {
+			if err := doWork(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if nWorkers > count {
+		nWorkers = count
+	}
+
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	var firstErr error
+
+	isError := func() bool {
+		errLock.Lock()
+		defer errLock.Unlock()
+		return firstErr != nil
+	}
+
+	idxCh := make(chan int)
+
+	for n := 0; n < nWorkers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range idxCh {
+				if isError() {
+					continue // skip all remaining work after error
+				}
+				if err := doWork(k); err != nil {
+					errLock.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errLock.Unlock()
+				}
+			}
+		}()
+	}
+
+	for k := 0; k < count && !isError(); k++ {
+		idxCh <- k
+	}
+	close(idxCh)
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/openmpp/go/ompp/db"
+)
+
+func TestCheckpointResume(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "test.checkpoint.txt")
+
+	// resume without checkpoint file: copy from the beginning
+	cp, err := newCheckpoint(path, true)
+	if err != nil {
+		t.Fatal("****FAIL: new checkpoint:", err)
+	}
+	if cp == nil || cp.isDone(runCheckpointKey("r1")) {
+		t.Fatal("****FAIL: expected empty checkpoint")
+	}
+
+	// partial copy: first run and first workset completed, second run started
+	for _, key := range []string{runCheckpointKey("r1"), setCheckpointKey("s1"), runCheckpointKey("r2") + ":start"} {
+		if err = cp.setDone(key); err != nil {
+			t.Fatal("****FAIL: set checkpoint:", key, err)
+		}
+	}
+
+	// resume from checkpoint file
+	cp, err = newCheckpoint(path, true)
+	if err != nil {
+		t.Fatal("****FAIL: resume checkpoint:", err)
+	}
+	expected := map[string]bool{
+		runCheckpointKey("r1"):            true,
+		setCheckpointKey("s1"):            true,
+		runCheckpointKey("r2") + ":start": true,
+		runCheckpointKey("r2"):            false,
+		setCheckpointKey("s2"):            false,
+		setCheckpointKey("r1"):            false,
+	}
+	for key, isDone := range expected {
+		if cp.isDone(key) != isDone {
+			t.Error("****FAIL: checkpoint:", key, "expected done:", isDone)
+		}
+	}
+
+	// completed steps appended to existing checkpoint
+	if err = cp.setDone(runCheckpointKey("r2")); err != nil {
+		t.Fatal("****FAIL: set checkpoint:", err)
+	}
+	cp, err = newCheckpoint(path, true)
+	if err != nil {
+		t.Fatal("****FAIL: resume checkpoint:", err)
+	}
+	if !cp.isDone(runCheckpointKey("r1")) || !cp.isDone(runCheckpointKey("r2")) {
+		t.Error("****FAIL: expected both runs completed")
+	}
+
+	// checkpoint removed after successful copy
+	if err = cp.remove(); err != nil {
+		t.Fatal("****FAIL: remove checkpoint:", err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Error("****FAIL: checkpoint file must be deleted:", path)
+	}
+
+	// copy without resume: outdated checkpoint file deleted and nothing is done
+	if err = os.WriteFile(path, []byte(runCheckpointKey("r1")+"\n"), 0644); err != nil {
+		t.Fatal("****FAIL: write checkpoint:", err)
+	}
+	cp, err = newCheckpoint(path, false)
+	if err != nil {
+		t.Fatal("****FAIL: new checkpoint:", err)
+	}
+	if cp != nil || cp.isDone(runCheckpointKey("r1")) {
+		t.Error("****FAIL: expected nil checkpoint")
+	}
+	if err = cp.setDone(runCheckpointKey("r1")); err != nil {
+		t.Error("****FAIL: set nil checkpoint:", err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Error("****FAIL: checkpoint file must be deleted:", path)
+	}
+}
+
+func TestDoByWorkers(t *testing.T) {
+
+	// all work done exactly once
+	for _, nWorkers := range []int{1, 4, 100} {
+
+		var theLock sync.Mutex
+		nDone := map[int]int{}
+
+		err := doByWorkers(nWorkers, 50, func(k int) error {
+			theLock.Lock()
+			defer theLock.Unlock()
+			nDone[k]++
+			return nil
+		})
+		if err != nil {
+			t.Fatal("****FAIL: workers:", nWorkers, err)
+		}
+		if len(nDone) != 50 {
+			t.Error("****FAIL: workers:", nWorkers, "expected 50 done, found:", len(nDone))
+		}
+		for k, n := range nDone {
+			if n != 1 {
+				t.Error("****FAIL: workers:", nWorkers, "work:", k, "done", n, "times")
+			}
+		}
+	}
+
+	// single worker: stop at first error
+	errFail := errors.New("fail at 10")
+	nCall := 0
+
+	err := doByWorkers(1, 50, func(k int) error {
+		nCall++
+		if k == 10 {
+			return errFail
+		}
+		return nil
+	})
+	if err != errFail {
+		t.Error("****FAIL: expected error:", errFail, "found:", err)
+	}
+	if nCall != 11 {
+		t.Error("****FAIL: expected 11 calls, found:", nCall)
+	}
+
+	// concurrent workers: first error returned and remaining work is not started
+	var theLock sync.Mutex
+	nCall = 0
+
+	err = doByWorkers(4, 1000, func(k int) error {
+		theLock.Lock()
+		nCall++
+		theLock.Unlock()
+		if k == 10 || k == 20 {
+			return errFail
+		}
+		return nil
+	})
+	if err != errFail {
+		t.Error("****FAIL: expected error:", errFail, "found:", err)
+	}
+	if nCall >= 1000 {
+		t.Error("****FAIL: expected work stopped after error, calls:", nCall)
+	}
+}
+
+func TestWorkersByDb(t *testing.T) {
+
+	dir := t.TempDir()
+
+	srcDb, err := db.Open("Database="+filepath.Join(dir, "src.sqlite")+"; Timeout=86400; ForeignKeys = 1; OpenMode=Create;", db.SQLiteDbDriver)
+	if err != nil {
+		t.Fatal("****FAIL: open source database:", err)
+	}
+	defer srcDb.Close()
+
+	dstDb, err := db.Open("Database="+filepath.Join(dir, "dst.sqlite")+"; Timeout=86400; ForeignKeys = 1; OpenMode=Create;", db.SQLiteDbDriver)
+	if err != nil {
+		t.Fatal("****FAIL: open destination database:", err)
+	}
+	defer dstDb.Close()
+
+	// SQLite destination: always one worker
+	for _, n := range []int{0, 1, 4, maxWorkers + 1} {
+		if nw := workersByDb(n, srcDb.DB, &dstDb); nw != 1 {
+			t.Error("****FAIL: SQLite destination, workers:", n, "expected 1, found:", nw)
+		}
+	}
+
+	// no destination database, e.g. copy into text files
+	expected := []struct {
+		n  int
+		nw int
+	}{
+		{n: 0, nw: 1},
+		{n: 1, nw: 1},
+		{n: 4, nw: 4},
+		{n: maxWorkers + 1, nw: maxWorkers},
+	}
+	for _, e := range expected {
+		if nw := workersByDb(e.n, srcDb.DB, nil); nw != e.nw {
+			t.Error("****FAIL: workers:", e.n, "expected:", e.nw, "found:", nw)
+		}
+	}
+	if n := srcDb.Stats().MaxOpenConnections; n != maxWorkers {
+		t.Error("****FAIL: expected source connections limit:", maxWorkers, "found:", n)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// list workset parameters history or compare two versions of workset parameter.
+// History of workset is saved into modelName.setName.history.json file.
+// If parameter name and version specified then difference between version and -dbcopy.ToVersion (default: current values)
+// saved into modelName.setName.paramName.history-diff.json file.
+func dbHistory(modelName string, modelDigest string, runOpts *config.RunOptions) error {
+
+	srcDb, modelDef, wsRow, err := openHistoryWorkset(modelName, modelDigest, runOpts)
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+
+	modelName = modelDef.Model.Name
+	paramName := runOpts.String(paramNameArgKey)
+
+	outDir := runOpts.String(outputDirArgKey)
+	if outDir != "" {
+		if err = os.MkdirAll(outDir, 0750); err != nil {
+			return err
+		}
+	}
+
+	// if version not specified then list workset history
+	if !runOpts.IsExist(versionArgKey) {
+
+		hLst, err := db.GetWorksetHistoryList(srcDb.DB, modelDef, wsRow.Name, paramName)
+		if err != nil {
+			return err
+		}
+		for _, h := range hLst {
+			omppLog.Log("Version:", h.VersionId, h.Name, "sub-values:", h.SubCount, h.Action, h.UserName, h.UpdateDateTime)
+		}
+
+		outPath := filepath.Join(outDir, modelName+"."+wsRow.Name+".history.json")
+		omppLog.Log("Workset history:", outPath)
+
+		return helper.ToJsonIndentFile(outPath, hLst)
+	}
+
+	// compare two versions of workset parameter
+	if paramName == "" {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s must be used with %s", versionArgKey, paramNameArgKey)
+	}
+	fromVer := runOpts.Int(versionArgKey, 0)
+	toVer := runOpts.Int(toVersionArgKey, 0)
+	if fromVer < 0 || toVer < 0 {
+		return helper.ErrorFmt("dbcopy invalid argument(s) for version: %s and/or to version: %s", runOpts.String(versionArgKey), runOpts.String(toVersionArgKey))
+	}
+
+	dLst, err := db.DiffWorksetHistory(srcDb.DB, modelDef, wsRow.Name, paramName, fromVer, toVer)
+	if err != nil {
+		return err
+	}
+
+	// convert cells from enum id's to enum codes
+	if !theCfg.isIdCsv {
+
+		cvt := db.CellParamConverter{ModelDef: modelDef, Name: paramName, DoubleFmt: theCfg.doubleFmt}
+		toCode, err := cvt.IdToCodeCell(modelDef, paramName)
+		if err != nil {
+			return err
+		}
+		for k := range dLst {
+			if dLst[k].Old != nil {
+				if dLst[k].Old, err = toCode(dLst[k].Old); err != nil {
+					return err
+				}
+			}
+			if dLst[k].New != nil {
+				if dLst[k].New, err = toCode(dLst[k].New); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	omppLog.Log("Compare:", wsRow.Name, paramName, "version:", fromVer, "to version:", toVer, "changed cells:", len(dLst))
+
+	outPath := filepath.Join(outDir, modelName+"."+wsRow.Name+"."+paramName+".history-diff.json")
+	omppLog.Log("Workset parameter difference:", outPath)
+
+	return helper.ToJsonIndentFile(outPath, dLst)
+}
+
+// restore workset parameter or all workset parameters from history version.
+// Current values are saved in history before restore, so restore can be undone.
+func dbRestoreHistory(modelName string, modelDigest string, runOpts *config.RunOptions) error {
+
+	srcDb, modelDef, wsRow, err := openHistoryWorkset(modelName, modelDigest, runOpts)
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+
+	verId := runOpts.Int(versionArgKey, 0)
+	if verId <= 0 {
+		return helper.ErrorFmt("dbcopy invalid argument for version: %s", runOpts.String(versionArgKey))
+	}
+	if wsRow.IsReadonly {
+		return helper.ErrorNew("workset is read-only:", wsRow.SetId, wsRow.Name)
+	}
+
+	// user name to save in history
+	userName := "dbcopy"
+	if u, e := user.Current(); e == nil && u.Username != "" {
+		userName = u.Username
+	}
+
+	paramName := runOpts.String(paramNameArgKey)
+	omppLog.Log("Restore workset:", wsRow.SetId, wsRow.Name, paramName, "version:", verId)
+
+	var nv int
+	if paramName != "" {
+		nv, err = db.RestoreWorksetParamHistory(srcDb, modelDef, wsRow.Name, paramName, verId, userName)
+	} else {
+		nv, err = db.RestoreWorksetHistory(srcDb, modelDef, wsRow.Name, verId, userName)
+	}
+	if err != nil {
+		return helper.ErrorNew("failed to restore workset", wsRow.SetId, wsRow.Name, paramName, ":", err)
+	}
+	if nv > 0 {
+		omppLog.Log("Values before restore saved as version:", strconv.Itoa(nv))
+	}
+	return nil
+}
+
+// open database, get model metadata and find workset by set id or set name
+func openHistoryWorkset(modelName string, modelDigest string, runOpts *config.RunOptions) (db.Dbc, *db.ModelMeta, *db.WorksetRow, error) {
+
+	// get workset name and id
+	setName := runOpts.String(setNameArgKey)
+	setId := runOpts.Int(setIdArgKey, 0)
+
+	// conflicting options: use set id if positive else use set name
+	if runOpts.IsExist(setNameArgKey) && runOpts.IsExist(setIdArgKey) {
+		if setId > 0 {
+			omppLog.LogFmt("dbcopy options conflict. Using set id: %d, not a set name: %s", setId, setName)
+			setName = ""
+		} else {
+			omppLog.LogFmt("dbcopy options conflict. Using set name: %s, not a set id: %d", setName, setId)
+			setId = 0
+		}
+	}
+
+	if setId < 0 || setId == 0 && setName == "" {
+		return db.Dbc{}, nil, nil, helper.ErrorFmt("dbcopy invalid argument(s) for set id: %s and/or set name: %s", runOpts.String(setIdArgKey), runOpts.String(setNameArgKey))
+	}
+
+	// open source database connection and check is it valid
+	cs, dn := db.IfEmptyMakeDefault(modelName, runOpts.String(fromSqliteArgKey), runOpts.String(dbConnStrArgKey), theCfg.srcDbDriver)
+
+	srcDb, err := db.Open(cs, dn)
+	if err != nil {
+		return db.Dbc{}, nil, nil, err
+	}
+
+	if err := db.CheckOpenmppSchemaVersion(srcDb.DB); err != nil {
+		srcDb.Close()
+		return db.Dbc{}, nil, nil, err
+	}
+
+	// get model metadata
+	modelDef, err := db.GetModel(srcDb.DB, modelName, modelDigest)
+	if err != nil {
+		srcDb.Close()
+		return db.Dbc{}, nil, nil, err
+	}
+
+	// get workset metadata by id or name
+	var wsRow *db.WorksetRow
+	if setId > 0 {
+		wsRow, err = db.GetWorkset(srcDb.DB, setId)
+	} else {
+		wsRow, err = db.GetWorksetByName(srcDb.DB, modelDef.Model.ModelId, setName)
+	}
+	if err == nil && wsRow == nil {
+		err = helper.ErrorNew("workset not found:", setId, setName)
+	}
+	if err == nil && wsRow.ModelId != modelDef.Model.ModelId {
+		err = helper.ErrorFmt("workset %d %s does not belong to model %s %s", wsRow.SetId, wsRow.Name, modelName, modelDigest)
+	}
+	if err != nil {
+		srcDb.Close()
+		return db.Dbc{}, nil, nil, err
+	}
+
+	return srcDb, modelDef, wsRow, nil
+}
+
+// delete old versions of workset parameters history and keep only -dbcopy.KeepVersions last versions of each parameter.
+func dbPruneHistory(modelName string, modelDigest string, runOpts *config.RunOptions) error {
+
+	keepCount := runOpts.Int(keepVersionsArgKey, 0)
+	if keepCount < 0 {
+		return helper.ErrorFmt("dbcopy invalid argument for number of versions to keep: %s", runOpts.String(keepVersionsArgKey))
+	}
+
+	srcDb, modelDef, wsRow, err := openHistoryWorkset(modelName, modelDigest, runOpts)
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+
+	omppLog.Log("Prune workset history:", wsRow.SetId, wsRow.Name, "keep versions:", keepCount)
+
+	n, err := db.PruneWorksetHistory(srcDb.DB, modelDef, wsRow.Name, keepCount)
+	if err != nil {
+		return helper.ErrorNew("failed to prune workset history", wsRow.SetId, wsRow.Name, ":", err)
+	}
+	omppLog.Log("Deleted history versions:", n)
+	return nil
+}
+
+// create workset history tables in database if tables not exist.
+// Database created by previous versions does not have history tables and workset history is not saved.
+func dbUpgradeHistory(modelName string, modelDigest string, runOpts *config.RunOptions) error {
+
+	// open source database connection and check is it valid
+	cs, dn := db.IfEmptyMakeDefault(modelName, runOpts.String(fromSqliteArgKey), runOpts.String(dbConnStrArgKey), theCfg.srcDbDriver)
+
+	srcDb, err := db.Open(cs, dn)
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+
+	if err := db.CheckOpenmppSchemaVersion(srcDb.DB); err != nil {
+		return err
+	}
+
+	// get model metadata
+	modelDef, err := db.GetModel(srcDb.DB, modelName, modelDigest)
+	if err != nil {
+		return err
+	}
+	omppLog.Log("Create workset history tables:", modelDef.Model.Name, modelDef.Model.Digest)
+
+	if err = db.UpgradeWorksetHistory(srcDb, modelDef); err != nil {
+		return helper.ErrorNew("failed to create workset history tables", modelDef.Model.Name, modelDef.Model.Digest, ":", err)
+	}
+	return nil
+}
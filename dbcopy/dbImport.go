@@ -0,0 +1,104 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// create downstream model workset from upstream model run using model parameters import.
+// Each downstream parameter imported from upstream model is included in new workset,
+// values are read from upstream model run output table or parameter.
+func dbImportFromRun(modelName string, modelDigest string, runOpts *config.RunOptions) error {
+
+	// upstream model name or digest and new workset name are required
+	fromName := runOpts.String(fromModelArgKey)
+	fromDigest := runOpts.String(fromDigestArgKey)
+	if fromName == "" && fromDigest == "" {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s must be used with %s or %s", importArgKey, fromModelArgKey, fromDigestArgKey)
+	}
+	setName := runOpts.String(setNameArgKey)
+	if setName == "" {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s must be used with %s", importArgKey, setNameArgKey)
+	}
+
+	// find upstream model run by id, digest or name
+	runId, runDigest, runName, isFirst, isLast := runIdDigestNameFromOptions(runOpts)
+	if runId < 0 || runId == 0 && runName == "" && runDigest == "" && !isFirst && !isLast {
+		return helper.ErrorFmt("dbcopy invalid argument(s) run id: %s, run name: %s, run digest: %s",
+			runOpts.String(runIdArgKey), runOpts.String(runNameArgKey), runOpts.String(runDigestArgKey))
+	}
+
+	// open upstream database: by default it is upstream model database
+	upName := fromName
+	if upName == "" {
+		upName = modelName
+	}
+	csInp, dnInp := db.IfEmptyMakeDefaultReadOnly(upName, runOpts.String(fromSqliteArgKey), runOpts.String(dbConnStrArgKey), theCfg.srcDbDriver)
+
+	srcDb, err := db.Open(csInp, dnInp)
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+
+	if err := db.CheckOpenmppSchemaVersion(srcDb.DB); err != nil {
+		return err
+	}
+
+	// open downstream database: by default it is downstream model database
+	csOut, dnOut := db.IfEmptyMakeDefault(modelName, runOpts.String(toSqliteArgKey), runOpts.String(toDbConnStrArgKey), theCfg.dstDbDriver)
+
+	dstDb := srcDb
+	if csInp != csOut || dnInp != dnOut {
+
+		dstDb, err = db.Open(csOut, dnOut)
+		if err != nil {
+			return err
+		}
+		defer dstDb.Close()
+
+		if err := db.CheckOpenmppSchemaVersion(dstDb.DB); err != nil {
+			return err
+		}
+	}
+
+	// get upstream and downstream model metadata
+	srcModel, err := db.GetModel(srcDb.DB, fromName, fromDigest)
+	if err != nil {
+		return err
+	}
+	dstModel, err := db.GetModel(dstDb.DB, modelName, modelDigest)
+	if err != nil {
+		return err
+	}
+
+	runRow, err := findModelRunByIdDigestName(srcDb.DB, srcModel.Model.ModelId, runId, runDigest, runName, isFirst, isLast)
+	if err != nil {
+		return err
+	}
+	if runRow == nil || runRow.ModelId != srcModel.Model.ModelId {
+		return helper.ErrorNew("Model run not found:", srcModel.Model.Name, runOpts.String(runIdArgKey), runOpts.String(runNameArgKey), runOpts.String(runDigestArgKey))
+	}
+
+	// destination: get list of languages
+	dstLang, err := db.GetLanguages(dstDb.DB)
+	if err != nil {
+		return err
+	}
+
+	omppLog.Log("Import workset", setName, "of model", dstModel.Model.Name, "from model", srcModel.Model.Name, "run", runRow.RunId, runRow.Name, runRow.RunDigest)
+
+	isLst, err := db.ImportWorksetFromRun(srcDb.DB, srcModel, runRow, dstDb, dstModel, dstLang, setName, nil)
+	if err != nil {
+		return err
+	}
+	for _, s := range isLst {
+		omppLog.Log("  ", s.Name, "from", s.FromKind, s.FromName, s.FromValue, "sub-values:", s.SubCount)
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// compare two versions of the model and migrate workset or model run parameters into destination model version.
+// Difference between model versions is saved into modelName.migrate.json file.
+// If workset or model run specified then parameters migrated into destination workset using -dbcopy.MigrateMap rules.
+func dbMigrate(modelName string, modelDigest string, runOpts *config.RunOptions) error {
+
+	toDigest := runOpts.String(toModelDigestArgKey)
+	if toDigest == "" {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s must be used with %s", migrateArgKey, toModelDigestArgKey)
+	}
+
+	// open source database connection and check is it valid
+	cs, dn := db.IfEmptyMakeDefault(modelName, runOpts.String(fromSqliteArgKey), runOpts.String(dbConnStrArgKey), theCfg.srcDbDriver)
+
+	srcDb, err := db.Open(cs, dn)
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+
+	if err := db.CheckOpenmppSchemaVersion(srcDb.DB); err != nil {
+		return err
+	}
+
+	// by default destination model version is in the same database
+	dstDb := srcDb
+	if runOpts.IsExist(toSqliteArgKey) || runOpts.IsExist(toDbConnStrArgKey) {
+
+		csOut, dnOut := db.IfEmptyMakeDefault(modelName, runOpts.String(toSqliteArgKey), runOpts.String(toDbConnStrArgKey), theCfg.dstDbDriver)
+
+		dstDb, err = db.Open(csOut, dnOut)
+		if err != nil {
+			return err
+		}
+		defer dstDb.Close()
+
+		if err := db.CheckOpenmppSchemaVersion(dstDb.DB); err != nil {
+			return err
+		}
+	}
+
+	// get source and destination model metadata
+	srcModel, err := db.GetModel(srcDb.DB, modelName, modelDigest)
+	if err != nil {
+		return err
+	}
+	modelName = srcModel.Model.Name // set model name: it can be empty and only model digest specified
+
+	dstModel, err := db.GetModel(dstDb.DB, modelName, toDigest)
+	if err != nil {
+		return err
+	}
+	if srcModel.Model.Digest == dstModel.Model.Digest {
+		return helper.ErrorNew("source and destination model digest are the same:", toDigest, "use", copyToArgKey, "to copy data")
+	}
+
+	// compare model versions and save difference report
+	md, err := db.DiffModel(srcModel, dstModel)
+	if err != nil {
+		return err
+	}
+	omppLog.Log("Model", modelName, "from", md.SrcDigest, "to", md.DstDigest)
+
+	for _, t := range md.Type {
+		omppLog.Log("  Type", t.Status, t.Name, "enums added:", len(t.EnumAdded), "removed:", len(t.EnumRemoved))
+	}
+	for _, p := range md.Param {
+		omppLog.Log("  Parameter", p.Status, p.Name)
+	}
+
+	outDir := runOpts.String(outputDirArgKey)
+	if outDir != "" {
+		if err = os.MkdirAll(outDir, 0750); err != nil {
+			return err
+		}
+	}
+	outPath := filepath.Join(outDir, modelName+".migrate.json")
+	omppLog.Log("Model difference:", outPath)
+
+	if err = helper.ToJsonIndentFile(outPath, md); err != nil {
+		return err
+	}
+
+	// read migration rules
+	mm := db.MigrateMap{}
+	if mp := runOpts.String(migrateMapArgKey); mp != "" {
+
+		isExist, err := helper.FromJsonFile(mp, &mm)
+		if err != nil {
+			return err
+		}
+		if !isExist {
+			return helper.ErrorNew("migration rules file not found or empty:", mp)
+		}
+	}
+
+	// migrate workset or model run parameters, if workset or run not specified then only difference report created
+	isRun := runOpts.IsExist(runNameArgKey) || runOpts.IsExist(runIdArgKey) || runOpts.IsExist(runDigestArgKey) ||
+		runOpts.IsExist(runFirstArgKey) || runOpts.IsExist(runLastArgKey)
+	isSet := runOpts.IsExist(setNameArgKey) || runOpts.IsExist(setIdArgKey)
+
+	if !isRun && !isSet {
+		return nil
+	}
+
+	// destination: get list of languages
+	dstLang, err := db.GetLanguages(dstDb.DB)
+	if err != nil {
+		return err
+	}
+
+	var pub *db.WorksetPub
+	paramLt := db.ReadParamLayout{}
+
+	if isSet {
+
+		// find source workset by id or name
+		setName := runOpts.String(setNameArgKey)
+		setId := runOpts.Int(setIdArgKey, 0)
+
+		var wsRow *db.WorksetRow
+		if setId > 0 {
+			wsRow, err = db.GetWorkset(srcDb.DB, setId)
+		} else {
+			wsRow, err = db.GetWorksetByName(srcDb.DB, srcModel.Model.ModelId, setName)
+		}
+		if err != nil {
+			return err
+		}
+		if wsRow == nil || wsRow.ModelId != srcModel.Model.ModelId {
+			return helper.ErrorNew("workset not found:", setId, setName, "model", modelName, srcModel.Model.Digest)
+		}
+
+		srcWs, err := db.GetWorksetFull(srcDb.DB, wsRow, "")
+		if err != nil {
+			return err
+		}
+		if pub, err = srcWs.ToPublic(srcDb.DB, srcModel); err != nil {
+			return err
+		}
+		paramLt = db.ReadParamLayout{ReadLayout: db.ReadLayout{FromId: wsRow.SetId}, IsFromSet: true}
+
+	} else {
+
+		// find source model run by id, digest or name, run must be completed
+		runId, runDigest, runName, isFirst, isLast := runIdDigestNameFromOptions(runOpts)
+
+		runRow, err := findModelRunByIdDigestName(srcDb.DB, srcModel.Model.ModelId, runId, runDigest, runName, isFirst, isLast)
+		if err != nil {
+			return err
+		}
+		if runRow == nil || runRow.ModelId != srcModel.Model.ModelId {
+			return helper.ErrorNew("Model run not found:", runOpts.String(runIdArgKey), runOpts.String(runNameArgKey), runOpts.String(runDigestArgKey))
+		}
+		if !db.IsRunCompleted(runRow.Status) {
+			return helper.ErrorNew("model run not completed:", runRow.RunId, runRow.Name, runRow.RunDigest)
+		}
+
+		meta, err := db.GetRunFullText(srcDb.DB, runRow, true, "")
+		if err != nil {
+			return err
+		}
+		rp, err := meta.ToPublic(srcModel)
+		if err != nil {
+			return err
+		}
+
+		// model run parameters are migrated into readonly workset with the same name as model run
+		pub = &db.WorksetPub{
+			WorksetHdrPub: db.WorksetHdrPub{Name: rp.Name, IsReadonly: true, Txt: rp.Txt},
+			Param:         rp.Param,
+		}
+		paramLt = db.ReadParamLayout{ReadLayout: db.ReadLayout{FromId: runRow.RunId}}
+	}
+
+	// rename destination workset
+	if runOpts.IsExist(setNewNameArgKey) {
+		pub.Name = runOpts.String(setNewNameArgKey)
+	}
+
+	return migrateWorkset(srcDb.DB, dstDb, srcModel, dstModel, &mm, pub, &paramLt, dstLang)
+}
+
+// migrateWorkset create or replace destination workset by converting source workset or model run parameters
+// from source model version into destination model version.
+func migrateWorkset(
+	srcDb *sql.DB, dstDb db.Dbc, srcModel *db.ModelMeta, dstModel *db.ModelMeta, mm *db.MigrateMap, pub *db.WorksetPub, paramLt *db.ReadParamLayout, dstLang *db.LangMeta,
+) error {
+
+	// destination workset belongs to destination model version
+	// source base run cannot be used by destination model version
+	pub.ModelName = dstModel.Model.Name
+	pub.ModelDigest = dstModel.Model.Digest
+
+	if pub.BaseRunDigest != "" {
+		omppLog.Log("Warning: workset", pub.Name, "base run is not migrated:", pub.BaseRunDigest)
+		pub.BaseRunDigest = ""
+	}
+
+	// create parameter converters before any update in destination database to validate migration rules
+	pmLst := []*db.ParamMigrator{}
+	paramLst := []db.ParamRunSetPub{}
+
+	for j := range pub.Param {
+
+		pm, err := db.NewParamMigrator(srcModel, dstModel, mm, pub.Param[j].Name)
+		if err != nil {
+			return err
+		}
+		if pm.IsSkip {
+			omppLog.Log("Skip parameter:", pm.SrcName)
+			continue
+		}
+		p := pub.Param[j]
+		p.Name = pm.DstName
+		pmLst = append(pmLst, pm)
+		paramLst = append(paramLst, p)
+	}
+
+	// save workset metadata as "read-write" and after importing all parameters set it as "readonly"
+	isReadonly := pub.IsReadonly
+	pub.IsReadonly = false
+	pub.Param = []db.ParamRunSetPub{}
+
+	dstWs, err := pub.FromPublic(dstDb.DB, dstModel)
+	if err != nil {
+		return err
+	}
+
+	// if destination workset exists then make it read-write and delete all existing parameters from workset
+	wsRow, err := db.GetWorksetByName(dstDb.DB, dstModel.Model.ModelId, pub.Name)
+	if err != nil {
+		return err
+	}
+	if wsRow != nil {
+		err = db.UpdateWorksetReadonly(dstDb.DB, wsRow.SetId, false)
+		if err != nil {
+			return helper.ErrorNew("failed to clear workset read-only status:", wsRow.SetId, wsRow.Name, err)
+		}
+		err = db.DeleteWorksetAllParameters(dstDb.DB, wsRow.SetId)
+		if err != nil {
+			return helper.ErrorNew("failed to delete workset", wsRow.SetId, wsRow.Name, err)
+		}
+	}
+
+	err = dstWs.UpdateWorkset(dstDb.DB, dstModel, true, dstLang)
+	if err != nil {
+		return err
+	}
+	dstId := dstWs.Set.SetId // actual set id from destination database
+
+	omppLog.LogFmt("Workset %s from id %d to %d", dstWs.Set.Name, paramLt.FromId, dstId)
+	nP := len(pmLst)
+	omppLog.Log("  Parameters:", nP)
+
+	for j, pm := range pmLst {
+
+		// source: read parameter values and convert into enum codes
+		paramLt.Name = pm.SrcName
+
+		srcCvt := db.CellParamConverter{ModelDef: srcModel, Name: pm.SrcName}
+		toCode, err := srcCvt.IdToCodeCell(srcModel, pm.SrcName)
+		if err != nil {
+			return err
+		}
+		srcLst := []db.CellCodeParam{}
+
+		_, err = db.ReadParameterTo(srcDb, srcModel, paramLt, func(src interface{}) (bool, error) {
+			c, e := toCode(src)
+			if e != nil {
+				return false, e
+			}
+			srcLst = append(srcLst, c.(db.CellCodeParam))
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(srcLst) <= 0 { // parameter data must exist for all parameters
+			return helper.ErrorFmt("missing parameter values %s id: %d", pm.SrcName, paramLt.FromId)
+		}
+
+		// convert parameter values into destination model version
+		dstLst, err := pm.Migrate(srcLst)
+		if err != nil {
+			return err
+		}
+		if pm.IsSame {
+			omppLog.Log("    ", j+1, "of", nP, pm.DstName, "copied")
+		} else {
+			omppLog.Log("    ", j+1, "of", nP, pm.DstName, "migrated, cells dropped:", pm.NDropped, "default:", pm.NDefault)
+		}
+
+		// destination: convert enum codes into id's and insert parameter values into workset
+		dstCvt := db.CellParamConverter{ModelDef: dstModel, Name: pm.DstName}
+		toId, err := dstCvt.CodeToIdCell(dstModel, pm.DstName)
+		if err != nil {
+			return err
+		}
+		k := 0
+		from := func() (interface{}, error) {
+			if k >= len(dstLst) {
+				return nil, nil // end of data
+			}
+			k++
+			return toId(dstLst[k-1])
+		}
+
+		_, err = dstWs.UpdateWorksetParameterFrom(dstDb, dstModel, true, &paramLst[j], dstLang, nil, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	// update workset readonly status with actual value
+	return db.UpdateWorksetReadonly(dstDb.DB, dstId, isReadonly)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"os"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// create workset from json or yaml template: base run or base workset and parameter overrides
+// or re-apply stored workset template using new base run.
+// If model run specified by run options then it is used as base run.
+func dbApplyTemplate(modelName string, modelDigest string, isReapply bool, runOpts *config.RunOptions) error {
+
+	setName := runOpts.String(setNameArgKey)
+	if setName == "" {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s or %s must be used with %s", templateArgKey, reapplyArgKey, setNameArgKey)
+	}
+
+	// read template from json or yaml file
+	var tpl db.WorksetTemplate
+	if !isReapply {
+
+		tp := runOpts.String(templateArgKey)
+		if tp == "" {
+			return helper.ErrorFmt("dbcopy invalid arguments: %s must be a path to template file", templateArgKey)
+		}
+		src, err := os.ReadFile(tp)
+		if err != nil {
+			return helper.ErrorNew("failed to read workset template:", tp, err)
+		}
+		isExist, err := helper.FromYaml(src, &tpl)
+		if err != nil {
+			return helper.ErrorNew("invalid workset template:", tp, err)
+		}
+		if !isExist {
+			return helper.ErrorNew("workset template is empty:", tp)
+		}
+	}
+
+	// open database connection and check is it valid
+	cs, dn := db.IfEmptyMakeDefault(modelName, runOpts.String(fromSqliteArgKey), runOpts.String(dbConnStrArgKey), theCfg.srcDbDriver)
+
+	srcDb, err := db.Open(cs, dn)
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+
+	if err := db.CheckOpenmppSchemaVersion(srcDb.DB); err != nil {
+		return err
+	}
+
+	// get model metadata and list of languages
+	modelDef, err := db.GetModel(srcDb.DB, modelName, modelDigest)
+	if err != nil {
+		return err
+	}
+	langDef, err := db.GetLanguages(srcDb.DB)
+	if err != nil {
+		return err
+	}
+
+	// if model run specified then it is a base run: find model run by id, digest or name
+	baseRun := ""
+	if runOpts.IsExist(runNameArgKey) || runOpts.IsExist(runIdArgKey) || runOpts.IsExist(runDigestArgKey) ||
+		runOpts.IsExist(runFirstArgKey) || runOpts.IsExist(runLastArgKey) {
+
+		runId, runDigest, runName, isFirst, isLast := runIdDigestNameFromOptions(runOpts)
+
+		runRow, err := findModelRunByIdDigestName(srcDb.DB, modelDef.Model.ModelId, runId, runDigest, runName, isFirst, isLast)
+		if err != nil {
+			return err
+		}
+		if runRow == nil || runRow.ModelId != modelDef.Model.ModelId {
+			return helper.ErrorNew("Model run not found:", runOpts.String(runIdArgKey), runOpts.String(runNameArgKey), runOpts.String(runDigestArgKey))
+		}
+		baseRun = runRow.RunDigest
+	}
+
+	// create workset from template or re-apply stored template
+	var t *db.WorksetTemplate
+	if !isReapply {
+
+		if baseRun != "" {
+			tpl.BaseRun = baseRun
+		}
+		omppLog.Log("Create workset", setName, "from template, base run:", tpl.BaseRun, "base workset:", tpl.BaseSet)
+
+		t, err = db.ApplyWorksetTemplate(srcDb, modelDef, langDef, setName, &tpl, false, nil)
+	} else {
+
+		omppLog.Log("Re-apply workset", setName, "template, base run:", baseRun)
+
+		t, err = db.ReapplyWorksetTemplate(srcDb, modelDef, langDef, setName, baseRun, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	omppLog.Log("Workset", setName, "base run:", t.BaseRunDigest)
+	for _, ov := range t.Override {
+		if ov.Formula != "" {
+			omppLog.Log("  ", ov.Name, "=", ov.Formula, "updated:", ov.Count)
+		} else {
+			omppLog.Log("  ", ov.Name, "=", ov.Value, "updated:", ov.Count)
+		}
+	}
+	return nil
+}
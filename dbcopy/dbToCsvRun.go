@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/openmpp/go/ompp/db"
@@ -135,6 +136,12 @@ func toRunCsv(
 		}
 	}
 
+	// derived measures of output tables: user-defined calculations stored in model database
+	dmLst, err := db.GetDerivedMeasureList(dbConn, modelDef)
+	if err != nil {
+		return err
+	}
+
 	// write output tables into csv file, if the table included in run results
 	nT := len(modelDef.Table)
 	omppLog.Log("  Tables:", nT)
@@ -179,6 +186,31 @@ func toRunCsv(
 			return err
 		}
 
+		// write output table derived measures into csv file, skip run comparison measures
+		calcLt := []db.CalculateTableLayout{}
+		for _, dm := range dmLst {
+			if dm.TableName == tblLt.Name && !strings.Contains(dm.Calculate, "[base]") && !strings.Contains(dm.Calculate, "[variant]") {
+				calcLt = append(calcLt, db.CalculateTableLayout{
+					CalculateLayout: db.CalculateLayout{Calculate: dm.Name, CalcId: db.CALCULATED_ID_OFFSET + len(calcLt), Name: dm.Name},
+				})
+			}
+		}
+		if len(calcLt) > 0 {
+
+			cvtCalc := &db.CellTableCalcConverter{CellTableConverter: ctc, CalcMaps: db.EmptyCalcMaps()}
+			if err = cvtCalc.SetCalcIdNameMap(calcLt); err != nil {
+				return err
+			}
+			cvtCalc.RunIdToLabel[runId] = meta.Run.RunDigest
+
+			logT = omppLog.LogIfTime(logT, logPeriod, helper.Fmt("    %d of %d: %s derived measures", j, nT, tblLt.Name))
+
+			err = toCellCsvFile(dbConn, modelDef, db.ReadCalculteTableLayout{ReadLayout: tblLt.ReadLayout, Calculation: calcLt}, cvtCalc, fileCreated, tableCsvDir, firstCol, firstVal)
+			if err != nil {
+				return err
+			}
+		}
+
 		// write output table accumulators into csv file
 		if !theCfg.isNoAccCsv {
 
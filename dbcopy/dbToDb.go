@@ -6,6 +6,7 @@ package main
 import (
 	"container/list"
 	"database/sql"
+	"path/filepath"
 
 	"github.com/openmpp/go/ompp/config"
 	"github.com/openmpp/go/ompp/db"
@@ -45,12 +46,22 @@ func dbToDb(modelName string, modelDigest string, runOpts *config.RunOptions) er
 		return err
 	}
 
+	// checkpoint of completed model runs and worksets
+	cp, err := newCheckpoint(
+		filepath.Join(runOpts.String(outputDirArgKey), modelName+".db2db.checkpoint.txt"), runOpts.Bool(resumeArgKey))
+	if err != nil {
+		return err
+	}
+	nWorkers := workersByDb(runOpts.Int(workersArgKey, 1), srcDb.DB, &dstDb)
+
 	// get source model metadata and languages, make a deep copy to use for destination database writing
-	err = copyDbToDb(srcDb.DB, dstDb, modelName, modelDigest)
+	err = copyDbToDb(srcDb.DB, dstDb, modelName, modelDigest, nWorkers, cp)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	// entire model copied: checkpoint is not required anymore
+	return cp.remove()
 }
 
 // copyDbToDb select from source database and insert or update existing
@@ -59,8 +70,10 @@ func dbToDb(modelName string, modelDigest string, runOpts *config.RunOptions) er
 // Model id's and hId's updated with destination database id's.
 // For example, in source db model id can be 11 and in destination it will be 200,
 // same for all other id's: type Hid, parameter Hid, table Hid, run id, set id, task id, etc.
+//
+// Model runs and worksets copied by nWorkers concurrent workers, runs and worksets completed in checkpoint are skipped.
 func copyDbToDb(
-	srcDb *sql.DB, dstDb db.Dbc, modelName string, modelDigest string) error {
+	srcDb *sql.DB, dstDb db.Dbc, modelName string, modelDigest string, nWorkers int, cp *copyCheckpoint) error {
 
 	// source: get model metadata
 	srcModel, err := db.GetModel(srcDb, modelName, modelDigest)
@@ -138,13 +151,13 @@ func copyDbToDb(
 	}
 
 	// source to destination: copy model runs: parameters, output expressions and accumulators
-	err = copyRunListDbToDb(srcDb, dstDb, srcModel, dstModel, dstLang)
+	err = copyRunListDbToDb(srcDb, dstDb, srcModel, dstModel, dstLang, nWorkers, cp)
 	if err != nil {
 		return err
 	}
 
 	// source to destination: copy all readonly worksets parameters
-	err = copyWorksetListDbToDb(srcDb, dstDb, srcModel, dstModel, dstLang)
+	err = copyWorksetListDbToDb(srcDb, dstDb, srcModel, dstModel, dstLang, nWorkers, cp)
 	if err != nil {
 		return err
 	}
@@ -118,8 +118,10 @@ func dbToDbRun(modelName string, modelDigest string, runOpts *config.RunOptions)
 
 // copyRunListDbToDb do copy all model runs parameters and output tables from source to destination database
 // Double format is used for float model types digest calculation, if non-empty format supplied
+// Model runs copied by nWorkers concurrent workers, runs which are completed in checkpoint are skipped.
+// If model run copy was started and not completed then partially copied run deleted from destination database and copied again.
 func copyRunListDbToDb(
-	srcDb *sql.DB, dstDb db.Dbc, srcModel *db.ModelMeta, dstModel *db.ModelMeta, dstLang *db.LangMeta) error {
+	srcDb *sql.DB, dstDb db.Dbc, srcModel *db.ModelMeta, dstModel *db.ModelMeta, dstLang *db.LangMeta, nWorkers int, cp *copyCheckpoint) error {
 
 	// source: get all successfully completed model runs in all languages
 	srcRl, err := db.GetRunFullTextList(srcDb, srcModel.Model.ModelId, true, "")
@@ -132,7 +134,31 @@ func copyRunListDbToDb(
 
 	// copy all run metadata, run parameters, output accumulators and expressions from source to destination
 	// model run "public" format is used
-	for k := range srcRl {
+	return doByWorkers(nWorkers, len(srcRl), func(k int) error {
+
+		key := runCheckpointKey(srcRl[k].Run.RunDigest)
+		if cp.isDone(key) {
+			omppLog.Log("Skip model run, it is already completed:", srcRl[k].Run.RunId, srcRl[k].Run.Name)
+			return nil
+		}
+
+		// if run copy started and not completed then delete partially copied run from destination
+		if cp.isDone(key + ":start") {
+			dstRow, err := db.GetRunByDigest(dstDb.DB, srcRl[k].Run.RunDigest)
+			if err != nil {
+				return err
+			}
+			if dstRow != nil {
+				omppLog.Log("Delete partially copied model run:", dstRow.RunId, dstRow.Name)
+
+				if err = db.DeleteRun(dstDb.DB, dstRow.RunId); err != nil {
+					return err
+				}
+			}
+		}
+		if err := cp.setDone(key + ":start"); err != nil {
+			return err
+		}
 
 		// convert model db rows into "public"" format
 		pub, err := srcRl[k].ToPublic(srcModel)
@@ -145,8 +171,8 @@ func copyRunListDbToDb(
 		if err != nil {
 			return err
 		}
-	}
-	return nil
+		return cp.setDone(key)
+	})
 }
 
 // copyRunDbToDb do copy model run metadata, run parameters and output tables from source to destination database
@@ -138,8 +138,9 @@ func dbToDbWorkset(modelName string, modelDigest string, runOpts *config.RunOpti
 }
 
 // copyWorksetListDbToDb do copy all readonly worksets parameters from source to destination database
+// Worksets copied by nWorkers concurrent workers, worksets which are completed in checkpoint are skipped.
 func copyWorksetListDbToDb(
-	srcDb *sql.DB, dstDb db.Dbc, srcModel *db.ModelMeta, dstModel *db.ModelMeta, dstLang *db.LangMeta) error {
+	srcDb *sql.DB, dstDb db.Dbc, srcModel *db.ModelMeta, dstModel *db.ModelMeta, dstLang *db.LangMeta, nWorkers int, cp *copyCheckpoint) error {
 
 	// source: get all readonly worksets in all languages
 	srcWl, err := db.GetWorksetFullList(srcDb, srcModel.Model.ModelId, true, "")
@@ -151,7 +152,13 @@ func copyWorksetListDbToDb(
 	}
 
 	// copy worksets from source to destination database by using "public" format
-	for k := range srcWl {
+	return doByWorkers(nWorkers, len(srcWl), func(k int) error {
+
+		key := setCheckpointKey(srcWl[k].Set.Name)
+		if cp.isDone(key) {
+			omppLog.Log("Skip workset, it is already completed:", srcWl[k].Set.SetId, srcWl[k].Set.Name)
+			return nil
+		}
 
 		// convert workset db rows into "public"" format
 		pub, err := srcWl[k].ToPublic(srcDb, srcModel)
@@ -164,8 +171,8 @@ func copyWorksetListDbToDb(
 		if err != nil {
 			return err
 		}
-	}
-	return nil
+		return cp.setDone(key)
+	})
 }
 
 // copyWorksetDbToDb do copy workset metadata and parameters from source to destination database
@@ -247,7 +254,7 @@ func copyWorksetDbToDb(
 		}
 
 		// destination: insert or update parameter values in workset
-		_, err = dstWs.UpdateWorksetParameterFrom(dstDb, dstModel, true, &paramLst[j], dstLang, makeFromList(cLst))
+		_, err = dstWs.UpdateWorksetParameterFrom(dstDb, dstModel, true, &paramLst[j], dstLang, nil, makeFromList(cLst))
 		if err != nil {
 			return 0, err
 		}
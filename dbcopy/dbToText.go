@@ -40,9 +40,11 @@ func dbToText(modelName string, modelDigest string, runOpts *config.RunOptions)
 	modelName = modelDef.Model.Name // set model name: it can be empty and only model digest specified
 
 	// create new output directory, use modelName subdirectory
+	// if copy resumed from checkpoint then keep existing output directory
 	outDir := filepath.Join(runOpts.String(outputDirArgKey), modelName)
+	isResume := runOpts.Bool(resumeArgKey)
 
-	if !theCfg.isKeepOutputDir {
+	if !theCfg.isKeepOutputDir && !isResume {
 		if ok := dirDeleteAndLog(outDir); !ok {
 			return helper.ErrorNew("Error: unable to delete:", outDir)
 		}
@@ -50,7 +52,13 @@ func dbToText(modelName string, modelDigest string, runOpts *config.RunOptions)
 	if err = os.MkdirAll(outDir, 0750); err != nil {
 		return err
 	}
-	fileCreated := make(map[string]bool)
+
+	// checkpoint of completed model runs, output tables and worksets
+	cp, err := newCheckpoint(outDir+".text.checkpoint.txt", isResume)
+	if err != nil {
+		return err
+	}
+	nWorkers := workersByDb(runOpts.Int(workersArgKey, 1), srcDb.DB, nil)
 
 	// write model definition to json file
 	if err = toModelJson(srcDb.DB, modelDef, outDir); err != nil {
@@ -71,12 +79,12 @@ func dbToText(modelName string, modelDigest string, runOpts *config.RunOptions)
 	isIdNames := false
 
 	// write all model run data into csv files: parameters, output expressions and accumulators
-	if isIdNames, err = toRunListText(srcDb.DB, modelDef, outDir, fileCreated, doUseIdNames); err != nil {
+	if isIdNames, err = toRunListText(srcDb.DB, modelDef, outDir, doUseIdNames, nWorkers, cp); err != nil {
 		return err
 	}
 
 	// write all readonly workset data into csv files: input parameters
-	if err = toWorksetListText(srcDb.DB, modelDef, outDir, fileCreated, isIdNames); err != nil {
+	if err = toWorksetListText(srcDb.DB, modelDef, outDir, isIdNames, nWorkers, cp); err != nil {
 		return err
 	}
 
@@ -94,7 +102,8 @@ func dbToText(modelName string, modelDigest string, runOpts *config.RunOptions)
 		omppLog.Log("Packed", zipPath)
 	}
 
-	return nil
+	// entire model copied: checkpoint is not required anymore
+	return cp.remove()
 }
 
 // toModelJson convert model metadata to json and write into json files.
@@ -251,6 +260,8 @@ func toCellCsvFile(
 		_, err = db.ReadParameterTo(dbConn, modelDef, &lt, cvtWr)
 	case db.ReadTableLayout:
 		_, err = db.ReadOutputTableTo(dbConn, modelDef, &lt, cvtWr)
+	case db.ReadCalculteTableLayout:
+		_, err = db.ReadOutputTableCalculteTo(dbConn, modelDef, &db.ReadTableLayout{ReadLayout: lt.ReadLayout}, lt.Calculation, []int{}, cvtWr)
 	case db.ReadMicroLayout:
 		_, err = db.ReadMicrodataTo(dbConn, modelDef, &lt, cvtWr)
 	default:
@@ -97,6 +97,11 @@ func dbToTextRun(modelName string, modelDigest string, runOpts *config.RunOption
 		outDir = filepath.Join(runOpts.String(outputDirArgKey), modelName+".run."+helper.CleanFileName(runRow.Name))
 	}
 
+	// write model run into .xlsx workbook instead of json and csv files
+	if runOpts.Bool(xlsxArgKey) {
+		return toRunXlsx(srcDb.DB, modelDef, meta, outDir+".xlsx", runOpts)
+	}
+
 	if !theCfg.isKeepOutputDir {
 		if ok := dirDeleteAndLog(outDir); !ok {
 			return helper.ErrorNew("Error: unable to delete:" + outDir)
@@ -108,7 +113,7 @@ func dbToTextRun(modelName string, modelDigest string, runOpts *config.RunOption
 	fileCreated := make(map[string]bool)
 
 	// write model run metadata into json, parameters and output result values into csv files
-	if err = toRunText(srcDb.DB, modelDef, meta, outDir, csvName, fileCreated, isUseIdNames); err != nil {
+	if err = toRunText(srcDb.DB, modelDef, meta, outDir, csvName, fileCreated, isUseIdNames, nil); err != nil {
 		return err
 	}
 
@@ -124,13 +129,15 @@ func dbToTextRun(modelName string, modelDigest string, runOpts *config.RunOption
 	return nil
 }
 
-// toRunListText write all model runs parameters and output tables into csv files, each run in separate subdirectory
+// toRunListText write all model runs parameters and output tables into csv files, each run in separate subdirectory.
+// Model runs are written by nWorkers concurrent workers, runs and output tables which are completed in checkpoint are skipped.
 func toRunListText(
 	dbConn *sql.DB,
 	modelDef *db.ModelMeta,
 	outDir string,
-	fileCreated map[string]bool,
 	doUseIdNames useIdNames,
+	nWorkers int,
+	cp *copyCheckpoint,
 ) (bool, error) {
 
 	// get all successfully completed model runs
@@ -160,18 +167,26 @@ func toRunListText(
 	}
 
 	// read all run parameters, output accumulators and expressions and dump it into csv files
-	for k := range rl {
-		err = toRunText(dbConn, modelDef, &rl[k], outDir, "", fileCreated, isUseIdNames)
-		if err != nil {
-			return isUseIdNames, err
+	// each run is written into separate subdirectory and can be done concurrently
+	err = doByWorkers(nWorkers, len(rl), func(k int) error {
+
+		key := runCheckpointKey(rl[k].Run.RunDigest)
+		if cp.isDone(key) {
+			omppLog.Log("Skip model run, it is already completed:", rl[k].Run.RunId, rl[k].Run.Name)
+			return nil
 		}
-	}
-	return isUseIdNames, nil
+		if e := toRunText(dbConn, modelDef, &rl[k], outDir, "", make(map[string]bool), isUseIdNames, cp); e != nil {
+			return e
+		}
+		return cp.setDone(key)
+	})
+	return isUseIdNames, err
 }
 
 // toRunText write model run metadata, parameters and output tables into csv files, in separate subdirectory
 // by default file name and directory name include run id: modelName.run.1234.RunName
 // user can explicitly disable it by IdNames=false
+// If checkpoint is not nil then skip parameters and output tables already completed in checkpoint.
 func toRunText(
 	dbConn *sql.DB,
 	modelDef *db.ModelMeta,
@@ -180,6 +195,7 @@ func toRunText(
 	csvName string,
 	fileCreated map[string]bool,
 	isUseIdNames bool,
+	cp *copyCheckpoint,
 ) error {
 
 	// convert db rows into "public" format
@@ -205,6 +221,7 @@ func toRunText(
 	tableCsvDir := filepath.Join(outDir, csvName, "output-tables")
 	microCsvDir := filepath.Join(outDir, csvName, "microdata")
 	nMd := len(meta.EntityGen)
+	runKey := runCheckpointKey(meta.Run.RunDigest)
 
 	if err = os.MkdirAll(paramCsvDir, 0750); err != nil {
 		return err
@@ -225,6 +242,11 @@ func toRunText(
 
 	for j := 0; j < nP; j++ {
 
+		pKey := runKey + ":parameter:" + modelDef.Param[j].Name
+		if cp.isDone(pKey) {
+			continue // parameter already completed
+		}
+
 		cvtParam := &db.CellParamConverter{
 			ModelDef:  modelDef,
 			Name:      modelDef.Param[j].Name,
@@ -242,6 +264,9 @@ func toRunText(
 		if err != nil {
 			return err
 		}
+		if err = cp.setDone(pKey); err != nil {
+			return err
+		}
 	}
 
 	// write output tables into csv files, if the table included in run results
@@ -261,6 +286,10 @@ func toRunText(
 		if !isFound {
 			continue // skip table: it is suppressed and not in run results
 		}
+		tKey := runKey + ":table:" + modelDef.Table[j].Name
+		if cp.isDone(tKey) {
+			continue // output table already completed
+		}
 
 		// write output table expression values into csv file
 		tblLt := db.ReadTableLayout{
@@ -312,6 +341,9 @@ func toRunText(
 				return err
 			}
 		}
+		if err = cp.setDone(tKey); err != nil {
+			return err
+		}
 	}
 
 	// write microdata into csv file, if there is any microdata for that model run and microadata write enabled
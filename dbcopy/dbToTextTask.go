@@ -190,7 +190,7 @@ func dbToTextTask(modelName string, modelDigest string, runOpts *config.RunOptio
 			}
 
 			// write model run metadata into json, parameters and output result values into csv files
-			if err = toRunText(srcDb.DB, modelDef, rm, outDir, "", fileCreated, isUseIdNames); err != nil {
+			if err = toRunText(srcDb.DB, modelDef, rm, outDir, "", fileCreated, isUseIdNames, nil); err != nil {
 				return err
 			}
 		}
@@ -100,6 +100,11 @@ func dbToTextWorkset(modelName string, modelDigest string, runOpts *config.RunOp
 		return helper.ErrorNew("workset must be readonly:", wsRow.SetId, wsRow.Name)
 	}
 
+	// write workset into .xlsx workbook instead of json and csv files
+	if runOpts.Bool(xlsxArgKey) {
+		return toWorksetXlsx(srcDb.DB, modelDef, wm, outDir+".xlsx", runOpts)
+	}
+
 	// create new output directory for workset metadata
 	if !theCfg.isKeepOutputDir {
 		if ok := dirDeleteAndLog(outDir); !ok {
@@ -133,13 +138,15 @@ func dbToTextWorkset(modelName string, modelDigest string, runOpts *config.RunOp
 	return nil
 }
 
-// toWorksetListText write all readonly worksets into csv files, each set in separate subdirectory
+// toWorksetListText write all readonly worksets into csv files, each set in separate subdirectory.
+// Worksets are written by nWorkers concurrent workers, worksets which are completed in checkpoint are skipped.
 func toWorksetListText(
 	dbConn *sql.DB,
 	modelDef *db.ModelMeta,
 	outDir string,
-	fileCreated map[string]bool,
-	isUseIdNames bool) error {
+	isUseIdNames bool,
+	nWorkers int,
+	cp *copyCheckpoint) error {
 
 	// get all readonly worksets
 	wl, err := db.GetWorksetFullList(dbConn, modelDef.Model.ModelId, true, "")
@@ -148,13 +155,19 @@ func toWorksetListText(
 	}
 
 	// read all workset parameters and dump it into csv files
-	for k := range wl {
-		err = toWorksetText(dbConn, modelDef, &wl[k], outDir, fileCreated, isUseIdNames)
-		if err != nil {
-			return err
+	// each workset is written into separate subdirectory and can be done concurrently
+	return doByWorkers(nWorkers, len(wl), func(k int) error {
+
+		key := setCheckpointKey(wl[k].Set.Name)
+		if cp.isDone(key) {
+			omppLog.Log("Skip workset, it is already completed:", wl[k].Set.SetId, wl[k].Set.Name)
+			return nil
 		}
-	}
-	return nil
+		if e := toWorksetText(dbConn, modelDef, &wl[k], outDir, make(map[string]bool), isUseIdNames); e != nil {
+			return e
+		}
+		return cp.setDone(key)
+	})
 }
 
 // toWorksetText write workset metadata into json file
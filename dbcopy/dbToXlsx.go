@@ -0,0 +1,107 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// write model run metadata, parameters and output tables into .xlsx workbook
+func toRunXlsx(dbConn *sql.DB, modelDef *db.ModelMeta, meta *db.RunMeta, xlsxPath string, runOpts *config.RunOptions) error {
+
+	opts, err := xlsxOptions(dbConn, modelDef, runOpts)
+	if err != nil {
+		return err
+	}
+	omppLog.Log("Model run", meta.Run.RunId, meta.Run.Name, "into:", xlsxPath)
+
+	xw, err := createXlsx(xlsxPath)
+	if err != nil {
+		return err
+	}
+	if err = db.WriteRunXlsx(dbConn, modelDef, meta, opts, xw); err != nil {
+		xw.Close()
+		return err
+	}
+	return xw.Close()
+}
+
+// write workset metadata and parameters into .xlsx workbook
+func toWorksetXlsx(dbConn *sql.DB, modelDef *db.ModelMeta, meta *db.WorksetMeta, xlsxPath string, runOpts *config.RunOptions) error {
+
+	opts, err := xlsxOptions(dbConn, modelDef, runOpts)
+	if err != nil {
+		return err
+	}
+	omppLog.Log("Workset", meta.Set.SetId, meta.Set.Name, "into:", xlsxPath)
+
+	xw, err := createXlsx(xlsxPath)
+	if err != nil {
+		return err
+	}
+	if err = db.WriteWorksetXlsx(dbConn, modelDef, meta, opts, xw); err != nil {
+		xw.Close()
+		return err
+	}
+	return xw.Close()
+}
+
+// create output directory, if required, and create .xlsx workbook file
+func createXlsx(xlsxPath string) (*helper.XlsxWriter, error) {
+
+	if d := filepath.Dir(xlsxPath); d != "" && d != "." {
+		if err := os.MkdirAll(d, 0750); err != nil {
+			return nil, err
+		}
+	}
+	return helper.CreateXlsx(xlsxPath)
+}
+
+// return .xlsx output options: if language specified then use language-specific labels else enum codes or enum id's
+func xlsxOptions(dbConn *sql.DB, modelDef *db.ModelMeta, runOpts *config.RunOptions) (*db.XlsxWriteOptions, error) {
+
+	opts := db.XlsxWriteOptions{
+		IsIdCsv:     theCfg.isIdCsv,
+		DoubleFmt:   theCfg.doubleFmt,
+		IsNoZeroCsv: theCfg.isNoZeroCsv,
+		IsNoNullCsv: theCfg.isNoNullCsv,
+	}
+
+	lang := runOpts.String(langArgKey)
+	if lang == "" || theCfg.isIdCsv {
+		return &opts, nil // language neutral output
+	}
+
+	// find model language, language code is case-insensitive
+	langDef, err := db.GetLanguages(dbConn)
+	if err != nil {
+		return nil, err
+	}
+	for k := range langDef.Lang {
+		if strings.EqualFold(langDef.Lang[k].LangCode, lang) {
+			opts.Lang = langDef.Lang[k].LangCode
+			break
+		}
+	}
+	if opts.Lang == "" {
+		return nil, helper.ErrorNew("language not found:", lang)
+	}
+
+	// language-specific labels of dimensions, enums and output table expressions
+	opts.TxtMeta, err = db.GetModelText(dbConn, modelDef.Model.ModelId, opts.Lang, true)
+	if err != nil {
+		return nil, err
+	}
+	opts.MsgDef = db.NewLangMsg(langDef.Lang, nil)
+
+	return &opts, nil
+}
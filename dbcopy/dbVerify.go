@@ -0,0 +1,128 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// verify model data integrity: recalculate parameters, output tables and microdata digests of model run(s),
+// find orphaned rows in value tables and metadata rows which are reference to missing Hid's.
+// If model run is not specified then all completed model runs are verified.
+func dbVerify(modelName string, modelDigest string, runOpts *config.RunOptions) error {
+
+	// open source database connection and check is it valid
+	cs, dn := db.IfEmptyMakeDefaultReadOnly(modelName, runOpts.String(fromSqliteArgKey), runOpts.String(dbConnStrArgKey), theCfg.srcDbDriver)
+
+	srcDb, err := db.Open(cs, dn)
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+
+	if err := db.CheckOpenmppSchemaVersion(srcDb.DB); err != nil {
+		return err
+	}
+
+	// get model metadata
+	modelDef, err := db.GetModel(srcDb.DB, modelName, modelDigest)
+	if err != nil {
+		return err
+	}
+	modelName = modelDef.Model.Name // set model name: it can be empty and only model digest specified
+
+	// if model run specified then find model run by id, run digest or name
+	runIdLst := []int{}
+
+	if runOpts.IsExist(runNameArgKey) || runOpts.IsExist(runIdArgKey) || runOpts.IsExist(runDigestArgKey) ||
+		runOpts.IsExist(runFirstArgKey) || runOpts.IsExist(runLastArgKey) {
+
+		runId, runDigest, runName, isFirst, isLast := runIdDigestNameFromOptions(runOpts)
+		if runId < 0 || runId == 0 && runName == "" && runDigest == "" && !isFirst && !isLast {
+			return helper.ErrorFmt("dbcopy invalid argument(s) run id: %s, run name: %s, run digest: %s",
+				runOpts.String(runIdArgKey), runOpts.String(runNameArgKey), runOpts.String(runDigestArgKey))
+		}
+		runRow, e := findModelRunByIdDigestName(srcDb.DB, modelDef.Model.ModelId, runId, runDigest, runName, isFirst, isLast)
+		if e != nil {
+			return e
+		}
+		if runRow == nil {
+			return helper.ErrorNew("Model run not found:", runOpts.String(runIdArgKey), runOpts.String(runNameArgKey), runOpts.String(runDigestArgKey))
+		}
+
+		// check is this run belong to the model
+		if runRow.ModelId != modelDef.Model.ModelId {
+			return helper.ErrorFmt("model run %d %s %s does not belong to model %s %s", runRow.RunId, runRow.Name, runRow.RunDigest, modelName, modelDigest)
+		}
+
+		// run must be completed: status success, error or exit
+		if !db.IsRunCompleted(runRow.Status) {
+			return helper.ErrorNew("model run not completed:", runRow.RunId, runRow.Name, runRow.RunDigest)
+		}
+		runIdLst = append(runIdLst, runRow.RunId)
+	}
+
+	// recalculate and compare value digests, find orphaned rows and missing Hid's
+	omppLog.Log("Verify:", modelName, modelDef.Model.Digest)
+
+	mv, err := db.VerifyModelRuns(srcDb.DB, modelDef, runIdLst, theCfg.doubleFmt)
+	if err != nil {
+		return helper.ErrorNew("model verification failed", modelName, modelDigest, ":", err)
+	}
+
+	// log verification results
+	for _, rv := range mv.Run {
+
+		omppLog.Log("Model run:", rv.RunId, rv.Name, rv.RunDigest)
+
+		logValueVerify := func(kind string, vLst []db.ValueVerify) {
+			for _, vv := range vLst {
+				switch {
+				case vv.Msg != "":
+					omppLog.Log("  Error:", kind, vv.Name, vv.Hid, ":", vv.Msg)
+				case !vv.IsValid:
+					omppLog.Log("  Digest mismatch:", kind, vv.Name, "base run:", vv.BaseRunId, "expected:", vv.Digest, "actual:", vv.ActualDigest)
+				}
+			}
+		}
+		logValueVerify("parameter", rv.Param)
+		logValueVerify("output table", rv.Table)
+		logValueVerify("microdata", rv.Entity)
+
+		if rv.IsValid {
+			omppLog.Log("  OK: parameters:", len(rv.Param), "output tables:", len(rv.Table), "microdata:", len(rv.Entity))
+		}
+	}
+	for _, ov := range mv.Orphan {
+		omppLog.Log("Orphaned rows:", ov.Kind, ov.Name, ov.DbTable, "id:", ov.Id, "rows:", ov.RowCount)
+	}
+	for _, mh := range mv.MissingHid {
+		omppLog.Log("Missing:", mh.DbTable, mh.Column, mh.Hid, "id:", mh.Id)
+	}
+
+	// write verification report into json file
+	outDir := runOpts.String(outputDirArgKey)
+	if outDir != "" {
+		if err = os.MkdirAll(outDir, 0750); err != nil {
+			return err
+		}
+	}
+	outPath := filepath.Join(outDir, modelName+".verify.json")
+	omppLog.Log("Verification report:", outPath)
+
+	if err = helper.ToJsonIndentFile(outPath, mv); err != nil {
+		return err
+	}
+
+	if !mv.IsValid {
+		return helper.ErrorNew("model data verification failed:", modelName, modelDef.Model.Digest)
+	}
+	return nil
+}
@@ -14,6 +14,7 @@ Dbcopy support 5 possible -dbcopy.To directions:
 
 Dbcopy also can delete entire model or model run results, set of input parameters or modeling task from database (see dbcopy.Delete below).
 Dbcopy also can rename model run results, set of input parameters or modeling task in database (see dbcopy.Rename below).
+Dbcopy also can verify model data integrity by recalculating value digests (see dbcopy.Verify below).
 
 Arguments for dbcopy can be specified on command line or through .ini file:
 
@@ -98,6 +99,12 @@ It is convenient to pack (unpack) text files into .zip archive:
 	dbcopy -m modelOne -dbcopy.Zip
 	dbcopy -m modelOne -dbcopy.SetName Default -dbcopy.Zip
 
+If .zip archive used as input then json and csv files are read directly from the archive,
+it is not unpacked on disk:
+
+	dbcopy -m modelOne -dbcopy.To db -dbcopy.Zip
+	dbcopy -m modelOne -dbcopy.SetName Default -dbcopy.To db -dbcopy.Zip
+
 By default model name is used to create output directory for text files or as input directory to import from.
 It may be a problem on Linux if current directory already contains executable "modelName".
 
@@ -121,7 +128,51 @@ If neccesary you can specify exact directory for input parameters by using "-dbc
 	dbcopy -m modelOne -s Custom -dbcopy.ParamDir two -dbcopy.Zip
 	dbcopy -m modelOne -s Custom -dbcopy.ParamDir two -dbcopy.To db
 	dbcopy -m modelOne -s Custom -dbcopy.ParamDir two -dbcopy.To db -dbcopy.Zip
-	dbcopy -m modelOne -s Custom -dbcopy.ParamDir two -dbcopy.OutputDir my-m1 -dbcopy.To db -dbcopy.Zip
+
+Input parameters set can be imported from Excel .xlsx workbook with one sheet per parameter:
+
+	dbcopy -m modelOne -s Custom -dbcopy.To db -dbcopy.Xlsx
+	dbcopy -m modelOne -s Custom -dbcopy.InputDir one -dbcopy.To db -dbcopy.Xlsx
+	dbcopy -m modelOne -s Custom -dbcopy.ParamDir two -dbcopy.To db -dbcopy.Xlsx
+
+It is reading modelOne.set.Custom.xlsx workbook (or two.xlsx if ParamDir specified).
+Sheet name must be a parameter name (or first 31 characters of parameter name) and sheet layout is the same as parameter csv file:
+first row is a header: sub_id,dim0,dim1,param_value and next rows are parameter values.
+Sheets which are not model parameters are ignored.
+Dimension items and enum-based parameter values validated against model metadata and import error report sheet and cell, e.g.: ageSex C5.
+
+Model run or input parameters set can be exported into Excel .xlsx workbook:
+
+	dbcopy -m modelOne -dbcopy.RunName Default -dbcopy.Xlsx
+	dbcopy -m modelOne -dbcopy.SetName Default -dbcopy.Xlsx
+	dbcopy -m modelOne -dbcopy.RunName Default -dbcopy.Xlsx -dbcopy.Language FR
+
+It is creating modelOne.run.Default.xlsx or modelOne.set.Default.xlsx workbook.
+First sheet of workbook is a metadata: run name, status, description, notes and run options,
+next sheets are model run parameters and output table expressions, one sheet per parameter or output table.
+By default dimension items and enum-based values are enum codes, same as in csv files,
+and input set workbook can be imported back into database by using -dbcopy.To db -dbcopy.Xlsx.
+If -dbcopy.Language specified then dimension names, dimension items and output table expressions are language-specific labels.
+
+Copy of entire model with thousands of model runs can take a long time.
+Model runs and input parameters sets can be copied by multiple concurrent workers:
+
+	dbcopy -m modelOne -dbcopy.Workers 4
+	dbcopy -m modelOne -dbcopy.Workers 4 -dbcopy.To db2db -dbcopy.ToDatabase "DSN=m1" -dbcopy.ToDatabaseDriver odbc
+
+Number of database connections is limited by number of workers.
+SQLite database allows only single writer and copy into SQLite database by "db2db" is always done by one worker.
+
+During copy of entire model dbcopy save completed model runs, output tables and input sets into checkpoint file:
+modelOne.text.checkpoint.txt or modelOne.db2db.checkpoint.txt, located in output directory.
+If copy is interrupted then it can be resumed from checkpoint:
+
+	dbcopy -m modelOne -dbcopy.Resume
+	dbcopy -m modelOne -dbcopy.Resume -dbcopy.Workers 4 -dbcopy.To db2db -dbcopy.ToSqlite dst.sqlite
+
+Resume keep existing output directory and skip all completed model runs, output tables and input sets.
+If copy "db2db" of model run was interrupted then partially copied model run deleted from destination database and copied again.
+Checkpoint file deleted after entire model successfully copied.
 
 Dbcopy create output directories (and json files) for model data by combining model name and run name or input set name.
 By default names may be combined with run id (set id) to make it unique.
@@ -196,6 +247,121 @@ To rename model run results, input set of parameters or modeling task:
 	dbcopy -m modelOne -dbcopy.Rename -dbcopy.TaskName taskOne -dbcopy.ToTaskName "New Task Name"
 	dbcopy -m modelOne -dbcopy.Rename -dbcopy.TaskId 1 -dbcopy.ToTaskName "New Task Name"
 
+To verify model data integrity: recalculate parameters, output tables and microdata value digests and compare it with digests stored in database:
+
+	dbcopy -m modelOne -dbcopy.Verify
+	dbcopy -m modelOne -dbcopy.Verify -dbcopy.RunId 101
+	dbcopy -m modelOne -dbcopy.Verify -dbcopy.RunName "My Model Run"
+	dbcopy -m modelOne -dbcopy.Verify -dbcopy.RunDigest d722febf683992aa624ce9844a2e597d
+	dbcopy -m modelOne -dbcopy.Verify -dbcopy.LastRun
+	dbcopy -m modelOne -dbcopy.Verify -dbcopy.OutputDir some/dir
+
+If model run is not specified then all completed model runs are verified.
+Verification also report orphaned rows in parameter, output table and microdata value tables, which are not referenced by any model run or workset,
+and model run or workset metadata rows which are reference to missing parameter, output table or entity generation Hid.
+Verification report saved into modelName.verify.json file and dbcopy exit with error if any mismatch found.
+Digest calculation is using -dbcopy.DoubleFormat and it must be the same format as used to create model run, by default: "%.15g".
+
+Workset parameter values are saved in history each time when parameter updated by oms web-service.
+To list workset history, compare two versions of workset parameter or restore workset from history version:
+
+	dbcopy -m modelOne -dbcopy.History -s Default
+	dbcopy -m modelOne -dbcopy.History -s Default -dbcopy.ParamName ageSex
+	dbcopy -m modelOne -dbcopy.History -s Default -dbcopy.ParamName ageSex -dbcopy.Version 3
+	dbcopy -m modelOne -dbcopy.History -s Default -dbcopy.ParamName ageSex -dbcopy.Version 3 -dbcopy.ToVersion 5
+	dbcopy -m modelOne -dbcopy.Restore -s Default -dbcopy.ParamName ageSex -dbcopy.Version 3
+	dbcopy -m modelOne -dbcopy.Restore -dbcopy.SetId 2 -dbcopy.Version 3
+
+History list saved into modelName.setName.history.json file.
+Difference between -dbcopy.Version and -dbcopy.ToVersion saved into modelName.setName.paramName.history-diff.json file,
+if -dbcopy.ToVersion not specified then version compared with current parameter values.
+Restore of single parameter replace parameter values by values of that version.
+If parameter name not specified then all workset parameters restored to the state as it was at the moment when version saved.
+Current values are saved in history before restore, so restore can be undone.
+
+To delete old versions of workset history and keep only 10 last versions of each workset parameter:
+
+	dbcopy -m modelOne -dbcopy.PruneHistory -s Default -dbcopy.KeepVersions 10
+
+If -dbcopy.KeepVersions 0 then all history versions of workset deleted.
+
+Database created by previous versions of openM++ does not have workset history tables and history is not saved.
+To create workset history tables for the model:
+
+	dbcopy -m modelOne -dbcopy.UpgradeHistory
+
+To compare two versions of the model and migrate input set of parameters or model run parameters into new model version:
+
+	dbcopy -m modelOne -dbcopy.ModelDigest 649f17f26d67c37b78dde94f79772445 -dbcopy.Migrate -dbcopy.ToModelDigest 8a04c4e10fb7a9a2ddb69bfb32a1f6c2
+	dbcopy -m modelOne -dbcopy.ModelDigest 649f17f26d67c37b78dde94f79772445 -dbcopy.Migrate -dbcopy.ToModelDigest 8a04c4e10fb7a9a2ddb69bfb32a1f6c2 -s MyData
+	dbcopy -m modelOne -dbcopy.ModelDigest 649f17f26d67c37b78dde94f79772445 -dbcopy.Migrate -dbcopy.ToModelDigest 8a04c4e10fb7a9a2ddb69bfb32a1f6c2 -s MyData -dbcopy.MigrateMap my-map.json
+	dbcopy -m modelOne -dbcopy.ModelDigest 649f17f26d67c37b78dde94f79772445 -dbcopy.Migrate -dbcopy.ToModelDigest 8a04c4e10fb7a9a2ddb69bfb32a1f6c2 -dbcopy.RunName MyRun -dbcopy.ToSetName MyRunData
+	dbcopy -m modelOne -dbcopy.Migrate -dbcopy.ToModelDigest 8a04c4e10fb7a9a2ddb69bfb32a1f6c2 -s MyData -dbcopy.ToSqlite new/modelOne.sqlite
+
+Added, removed and changed types, enums, parameters and parameter dimensions saved into modelName.migrate.json file.
+If input set or model run specified then parameters are migrated into destination model workset,
+model run parameters are migrated into workset with the same name as model run, use -dbcopy.ToSetName to rename it.
+By default destination model version must be in the same database, use -dbcopy.ToSqlite or -dbcopy.ToDatabase to specify other database.
+Parameters with the same digest copied as is, other parameters converted by enum codes using -dbcopy.MigrateMap rules, for example:
+
+	{
+	  "Type": [{
+	    "Name": "AGE_GROUP",
+	    "Rename": {"10-20": "10-19"},
+	    "Aggregate": [{"To": "60+", "From": ["60-70", "70+"], "Fnc": "avg"}],
+	    "Split": [{"From": "20-40", "To": ["20-29", "30-39"], "IsDivide": false}]
+	  }],
+	  "Param": [
+	    {"Name": "ageSex", "Default": "0"},
+	    {"Name": "salaryAge", "FromName": "salaryByAge"},
+	    {"Name": "oldParam", "IsSkip": true}
+	  ]
+	}
+
+Enum codes which do not exist in destination type are dropped.
+Parameter default value is used for cells of new enums, it is an error if such cells exist and parameter default value not specified.
+Aggregation function can be one of: sum, avg, min, max, first, default is sum.
+
+To create downstream model input set of parameters from upstream model run results:
+
+	dbcopy -m downModel -dbcopy.Import -dbcopy.FromModel upModel -dbcopy.RunName MyUpstreamRun -s MyData
+	dbcopy -m downModel -dbcopy.Import -dbcopy.FromModel upModel -dbcopy.LastRun -s MyData
+	dbcopy -m downModel -dbcopy.Import -dbcopy.FromModelDigest 649f17f26d67c37b78dde94f79772445 -dbcopy.RunId 101 -s MyData
+	dbcopy -m downModel -dbcopy.Import -dbcopy.FromModel upModel -dbcopy.LastRun -s MyData -dbcopy.FromSqlite up/upModel.sqlite -dbcopy.ToSqlite down/downModel.sqlite
+
+Downstream model parameters which are imported from upstream model (model_parameter_import) included into new input set,
+values are read from upstream model run output table, first table expression is used, or from upstream model run parameter.
+If parameter has a sample dimension then it is a first parameter dimension and values are from first table accumulator by sub-value.
+By default upstream model is in upModel.sqlite and downstream model is in downModel.sqlite database.
+Provenance of imported parameters: upstream model, model run, output table and value digest is stored in downstream database.
+
+To create input set of parameters from workset template: base run or base workset and parameter overrides:
+
+	dbcopy -m modelOne -dbcopy.Template scenario.yaml -s MyScenario
+	dbcopy -m modelOne -dbcopy.Template scenario.json -s MyScenario -dbcopy.RunName NewBaseRun
+
+Template can be json or yaml file, for example:
+
+	BaseRun: Default
+	Override:
+	  - Name: ageSex
+	    Filter:
+	      - Name: dim0
+	        Op: IN
+	        Values: [10-20, 20-30]
+	    Value: 0.5
+	  - Name: salaryFull
+	    Formula: salaryFull * 1.05
+
+If model run specified by run options then it is used as base run instead of template base run.
+Template is stored in database with the workset and can be re-applied to create the same workset from newer base run:
+
+	dbcopy -m modelOne -dbcopy.Reapply -s MyScenario
+	dbcopy -m modelOne -dbcopy.Reapply -s MyScenario -dbcopy.LastRun
+	dbcopy -m modelOne -dbcopy.Reapply -s MyScenario -dbcopy.RunName NewBaseRun
+
+All workset parameters deleted and created again from base run, base workset and template overrides.
+
 By default float and double values converted into csv text with "%.15g" format.
 It is possible to specify other format for float values values:
 
@@ -315,6 +481,23 @@ const (
 	copyToArgKey        = "dbcopy.To"                // copy to: text=db-to-text, db=text-to-db, db2db=db-to-db, csv=db-to-csv, csv-all=db-to-csv-all-in-one
 	deleteArgKey        = "dbcopy.Delete"            // delete model or workset or model run or modeling task from database
 	renameArgKey        = "dbcopy.Rename"            // rename workset or model run or modeling task
+	verifyArgKey        = "dbcopy.Verify"            // verify model runs data integrity: recalculate value digests
+	historyArgKey       = "dbcopy.History"           // list workset parameters history or compare two versions of workset parameter
+	restoreArgKey       = "dbcopy.Restore"           // restore workset or workset parameter from history version
+	paramNameArgKey     = "dbcopy.ParamName"         // workset parameter name, to list history, compare or restore
+	versionArgKey       = "dbcopy.Version"           // workset parameter history version id
+	toVersionArgKey     = "dbcopy.ToVersion"         // workset parameter history version id to compare with, default: current values
+	pruneHistoryArgKey  = "dbcopy.PruneHistory"      // delete old versions of workset parameters history
+	keepVersionsArgKey  = "dbcopy.KeepVersions"      // number of last history versions to keep for each workset parameter
+	upgradeHistArgKey   = "dbcopy.UpgradeHistory"    // create workset history tables in database if not exist
+	migrateArgKey       = "dbcopy.Migrate"           // compare model versions and migrate workset or model run parameters into other model version
+	toModelDigestArgKey = "dbcopy.ToModelDigest"     // destination model digest, to migrate into other model version
+	migrateMapArgKey    = "dbcopy.MigrateMap"        // path to json file with migration rules: enum renames, aggregation, split, default values
+	importArgKey        = "dbcopy.Import"            // create downstream model workset from upstream model run using parameters import
+	fromModelArgKey     = "dbcopy.FromModel"         // upstream model name, to import workset from upstream model run
+	fromDigestArgKey    = "dbcopy.FromModelDigest"   // upstream model digest, to import workset from upstream model run
+	templateArgKey      = "dbcopy.Template"          // path to json or yaml workset template, to create workset from template
+	reapplyArgKey       = "dbcopy.Reapply"           // re-apply stored workset template using new base run
 	modelNameArgKey     = "dbcopy.ModelName"         // model name
 	modelNameShortKey   = "m"                        // model name (short form)
 	modelDigestArgKey   = "dbcopy.ModelDigest"       // model hash digest
@@ -343,6 +526,10 @@ const (
 	paramDirArgKey      = "dbcopy.ParamDir"          // path to workset parameters directory
 	paramDirShortKey    = "p"                        // path to workset parameters directory (short form)
 	zipArgKey           = "dbcopy.Zip"               // create output or use as input model.zip
+	xlsxArgKey          = "dbcopy.Xlsx"              // create output model run or workset .xlsx or use as input workset .xlsx workbook
+	langArgKey          = "dbcopy.Language"          // language of labels in .xlsx output, e.g. fr-CA, default: enum codes
+	resumeArgKey        = "dbcopy.Resume"            // if true then resume interrupted copy of entire model from checkpoint
+	workersArgKey       = "dbcopy.Workers"           // number of concurrent workers to copy model runs and worksets
 	intoTsvArgKey       = "dbcopy.IntoTsv"           // if true then create .tsv output files instead of .csv by default
 	useIdCsvArgKey      = "dbcopy.IdCsv"             // if true then create csv files with enum id's default: enum code
 	useIdNamesArgKey    = "dbcopy.IdOutputNames"     // if true then always use id's in output directory and file names, false never use it
@@ -404,6 +591,23 @@ func mainBody(args []string) error {
 	_ = flag.String(copyToArgKey, "text", "copy to: `text`=db-to-text, db=text-to-db, db2db=db-to-db, csv=db-to-csv, csv-all=db-to-csv-all-in-one")
 	_ = flag.Bool(deleteArgKey, false, "delete from database: model, set of input parameters, model run or modeling task")
 	_ = flag.Bool(renameArgKey, false, "rename set of input parameters, model run or modeling task")
+	_ = flag.Bool(verifyArgKey, false, "verify model data integrity: recalculate value digests of model run(s)")
+	_ = flag.Bool(historyArgKey, false, "list workset parameters history or compare two versions of workset parameter")
+	_ = flag.Bool(restoreArgKey, false, "restore workset or workset parameter from history version")
+	_ = flag.String(paramNameArgKey, "", "workset parameter name, to list history, compare or restore")
+	_ = flag.Int(versionArgKey, 0, "workset parameter history version id")
+	_ = flag.Int(toVersionArgKey, 0, "workset parameter history version id to compare with, default: 0 is current values")
+	_ = flag.Bool(pruneHistoryArgKey, false, "delete old versions of workset parameters history")
+	_ = flag.Int(keepVersionsArgKey, 0, "number of last history versions to keep for each workset parameter")
+	_ = flag.Bool(upgradeHistArgKey, false, "create workset history tables in database if not exist")
+	_ = flag.Bool(migrateArgKey, false, "compare model versions and migrate workset or model run parameters into other model version")
+	_ = flag.String(toModelDigestArgKey, "", "destination model digest, to migrate into other model version")
+	_ = flag.String(migrateMapArgKey, "", "path to json file with migration rules: enum renames, aggregation, split, default values")
+	_ = flag.Bool(importArgKey, false, "create downstream model workset from upstream model run using parameters import")
+	_ = flag.String(fromModelArgKey, "", "upstream model name, to import workset from upstream model run")
+	_ = flag.String(fromDigestArgKey, "", "upstream model digest, to import workset from upstream model run")
+	_ = flag.String(templateArgKey, "", "path to json or yaml workset template, to create workset from template")
+	_ = flag.Bool(reapplyArgKey, false, "re-apply stored workset template using new base run")
 	_ = flag.String(modelNameArgKey, "", "model name")
 	_ = flag.String(modelNameShortKey, "", "model name (short of "+modelNameArgKey+")")
 	_ = flag.String(modelDigestArgKey, "", "model hash digest")
@@ -432,6 +636,10 @@ func mainBody(args []string) error {
 	_ = flag.String(paramDirArgKey, "", "path to parameters directory (input parameters set directory)")
 	_ = flag.String(paramDirShortKey, "", "path to parameters directory (short of "+paramDirArgKey+")")
 	_ = flag.Bool(zipArgKey, false, "create output model.zip or use model.zip as input")
+	_ = flag.Bool(xlsxArgKey, false, "create output model run or workset .xlsx or use workset .xlsx as input, one sheet per parameter or output table")
+	_ = flag.String(langArgKey, "", "language of dimension labels in .xlsx output, e.g.: fr-CA, default: enum codes")
+	_ = flag.Bool(resumeArgKey, false, "if true then resume interrupted copy of entire model from checkpoint")
+	_ = flag.Int(workersArgKey, 1, "number of concurrent workers to copy model runs and worksets")
 	_ = flag.Bool(intoTsvArgKey, theCfg.isTsv, "if true then create .tsv output files instead of .csv by default")
 	_ = flag.Bool(useIdNamesArgKey, false, "if true then always use id's in output directory names, false never use. Default for csv: only if name conflict")
 	_ = flag.Bool(useIdCsvArgKey, false, "if true then create csv files with enum id's default: enum code")
@@ -529,12 +737,70 @@ func mainBody(args []string) error {
 	copyToArg := strings.ToLower(runOpts.String(copyToArgKey))
 	isDel := runOpts.Bool(deleteArgKey)
 	isRename := runOpts.Bool(renameArgKey)
+	isVerify := runOpts.Bool(verifyArgKey)
+	isHistory := runOpts.Bool(historyArgKey)
+	isRestore := runOpts.Bool(restoreArgKey)
+	isPrune := runOpts.Bool(pruneHistoryArgKey)
+	isUpgrade := runOpts.Bool(upgradeHistArgKey)
+	isMigrate := runOpts.Bool(migrateArgKey)
+	isImport := runOpts.Bool(importArgKey)
+	isTemplate := runOpts.IsExist(templateArgKey)
+	isReapply := runOpts.Bool(reapplyArgKey)
 
 	if (isDel || isRename) && runOpts.IsExist(copyToArgKey) {
 		return helper.ErrorFmt("dbcopy invalid arguments: %s or %s cannot be used with %s", deleteArgKey, renameArgKey, copyToArgKey)
 	}
-	// to-database can be used only with "db" or "db2db"
-	if copyToArg != "db" && copyToArg != "db2db" &&
+	if isVerify && (isDel || isRename || runOpts.IsExist(copyToArgKey)) {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s cannot be used with %s or %s or %s", verifyArgKey, deleteArgKey, renameArgKey, copyToArgKey)
+	}
+	if (isHistory || isRestore) &&
+		(isHistory && isRestore || isDel || isRename || isVerify || runOpts.IsExist(copyToArgKey) ||
+			!runOpts.IsExist(setNameArgKey) && !runOpts.IsExist(setIdArgKey)) {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s or %s must be used with %s or %s and cannot be used with %s or %s or %s or %s",
+			historyArgKey, restoreArgKey, setNameArgKey, setIdArgKey, deleteArgKey, renameArgKey, verifyArgKey, copyToArgKey)
+	}
+	if isPrune &&
+		(isHistory || isRestore || isDel || isRename || isVerify || isMigrate || isImport || isTemplate || isReapply || runOpts.IsExist(copyToArgKey) ||
+			!runOpts.IsExist(setNameArgKey) && !runOpts.IsExist(setIdArgKey) || !runOpts.IsExist(keepVersionsArgKey)) {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s must be used with %s or %s and %s, it cannot be used with other dbcopy actions",
+			pruneHistoryArgKey, setNameArgKey, setIdArgKey, keepVersionsArgKey)
+	}
+	if runOpts.IsExist(keepVersionsArgKey) && !isPrune {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s can be used only with %s", keepVersionsArgKey, pruneHistoryArgKey)
+	}
+	if isUpgrade &&
+		(isHistory || isRestore || isPrune || isDel || isRename || isVerify || isMigrate || isImport || isTemplate || isReapply || runOpts.IsExist(copyToArgKey)) {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s cannot be used with other dbcopy actions", upgradeHistArgKey)
+	}
+	if (runOpts.IsExist(paramNameArgKey) || runOpts.IsExist(versionArgKey)) && !isHistory && !isRestore ||
+		runOpts.IsExist(toVersionArgKey) && (!isHistory || !runOpts.IsExist(versionArgKey)) {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s or %s or %s can be used only with %s or %s",
+			paramNameArgKey, versionArgKey, toVersionArgKey, historyArgKey, restoreArgKey)
+	}
+	if isMigrate && (isDel || isRename || isVerify || isHistory || isRestore || runOpts.IsExist(copyToArgKey) || !runOpts.IsExist(toModelDigestArgKey)) {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s must be used with %s and cannot be used with %s or %s or %s or %s or %s or %s",
+			migrateArgKey, toModelDigestArgKey, deleteArgKey, renameArgKey, verifyArgKey, historyArgKey, restoreArgKey, copyToArgKey)
+	}
+	if (runOpts.IsExist(toModelDigestArgKey) || runOpts.IsExist(migrateMapArgKey)) && !isMigrate {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s or %s can be used only with %s", toModelDigestArgKey, migrateMapArgKey, migrateArgKey)
+	}
+	if isImport &&
+		(isMigrate || isDel || isRename || isVerify || isHistory || isRestore || runOpts.IsExist(copyToArgKey) ||
+			!runOpts.IsExist(fromModelArgKey) && !runOpts.IsExist(fromDigestArgKey) || !runOpts.IsExist(setNameArgKey)) {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s must be used with %s or %s and %s, it cannot be used with %s or %s or %s or %s or %s or %s or %s",
+			importArgKey, fromModelArgKey, fromDigestArgKey, setNameArgKey, migrateArgKey, deleteArgKey, renameArgKey, verifyArgKey, historyArgKey, restoreArgKey, copyToArgKey)
+	}
+	if (runOpts.IsExist(fromModelArgKey) || runOpts.IsExist(fromDigestArgKey)) && !isImport {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s or %s can be used only with %s", fromModelArgKey, fromDigestArgKey, importArgKey)
+	}
+	if (isTemplate || isReapply) &&
+		(isTemplate && isReapply || isImport || isMigrate || isDel || isRename || isVerify || isHistory || isRestore ||
+			runOpts.IsExist(copyToArgKey) || !runOpts.IsExist(setNameArgKey)) {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s or %s must be used with %s, it cannot be used with %s or %s or %s or %s or %s or %s or %s or %s",
+			templateArgKey, reapplyArgKey, setNameArgKey, importArgKey, migrateArgKey, deleteArgKey, renameArgKey, verifyArgKey, historyArgKey, restoreArgKey, copyToArgKey)
+	}
+	// to-database can be used only with "db" or "db2db" or to migrate or to import
+	if copyToArg != "db" && copyToArg != "db2db" && !isMigrate && !isImport &&
 		(runOpts.IsExist(toDbConnStrArgKey) || runOpts.IsExist(toDbDriverArgKey) || runOpts.IsExist(toSqliteArgKey)) {
 		return helper.ErrorFmt("dbcopy invalid arguments: output database can be specified only if %s =db or =db2db", copyToArgKey)
 	}
@@ -546,6 +812,45 @@ func mainBody(args []string) error {
 	if copyToArg != "csv" && copyToArg != "csv-all" && (runOpts.IsExist(noZeroArgKey) || runOpts.IsExist(noNullArgKey)) {
 		return helper.ErrorFmt("dbcopy invalid arguments: %s or %s can be used only if %s =text or =csv or =csv-all", noZeroArgKey, noNullArgKey, copyToArgKey)
 	}
+	// xlsx workbook is only for single model run or workset copy db-to-text or for workset copy text-to-db
+	if runOpts.Bool(xlsxArgKey) {
+
+		isRun := runOpts.IsExist(runNameArgKey) || runOpts.IsExist(runIdArgKey) || runOpts.IsExist(runDigestArgKey) ||
+			runOpts.IsExist(runFirstArgKey) || runOpts.IsExist(runLastArgKey)
+		isSet := runOpts.IsExist(setNameArgKey) || runOpts.IsExist(setIdArgKey)
+
+		if runOpts.Bool(zipArgKey) ||
+			copyToArg == "text" && !isRun && !isSet ||
+			copyToArg == "db" && (isRun || !runOpts.IsExist(setNameArgKey)) ||
+			copyToArg != "text" && copyToArg != "db" {
+			return helper.ErrorFmt("dbcopy invalid arguments: %s can be used only with model run or workset and if %s =text or with %s and if %s =db, it cannot be used with %s",
+				xlsxArgKey, copyToArgKey, setNameArgKey, copyToArgKey, zipArgKey)
+		}
+	}
+	// zip input is read without unpacking: output directory cannot be used to import from zip
+	if copyToArg == "db" && runOpts.Bool(zipArgKey) && runOpts.IsExist(outputDirArgKey) {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s cannot be used with %s if %s =db, zip archive is not unpacked", outputDirArgKey, zipArgKey, copyToArgKey)
+	}
+	// language of labels is only for xlsx output
+	if runOpts.IsExist(langArgKey) && (!runOpts.Bool(xlsxArgKey) || copyToArg != "text") {
+		return helper.ErrorFmt("dbcopy invalid arguments: %s can be used only with %s and if %s =text", langArgKey, xlsxArgKey, copyToArgKey)
+	}
+	// resume and concurrent workers are only for entire model copy db-to-text or db-to-db
+	if runOpts.Bool(resumeArgKey) || runOpts.IsExist(workersArgKey) {
+
+		isPart := runOpts.IsExist(runNameArgKey) || runOpts.IsExist(runIdArgKey) || runOpts.IsExist(runDigestArgKey) ||
+			runOpts.IsExist(runFirstArgKey) || runOpts.IsExist(runLastArgKey) ||
+			runOpts.IsExist(setNameArgKey) || runOpts.IsExist(setIdArgKey) ||
+			runOpts.IsExist(taskNameArgKey) || runOpts.IsExist(taskIdArgKey)
+
+		if isDel || isRename || isVerify || isPart || copyToArg != "text" && copyToArg != "db2db" {
+			return helper.ErrorFmt("dbcopy invalid arguments: %s or %s can be used only to copy entire model and if %s =text or =db2db",
+				resumeArgKey, workersArgKey, copyToArgKey)
+		}
+		if runOpts.Int(workersArgKey, 1) < 1 {
+			return helper.ErrorFmt("dbcopy invalid argument %s: %s", workersArgKey, runOpts.String(workersArgKey))
+		}
+	}
 	// parameter directory is only for workset copy db-to-text or text-to-db
 	if runOpts.IsExist(paramDirArgKey) &&
 		(copyToArg != "text" && copyToArg != "db" || !runOpts.IsExist(setNameArgKey) && !runOpts.IsExist(setIdArgKey)) {
@@ -561,7 +866,7 @@ func mainBody(args []string) error {
 			runNameArgKey, runIdArgKey, runDigestArgKey, runFirstArgKey, runLastArgKey)
 	}
 	// new set name can be used with set name or set id arguments
-	if runOpts.IsExist(setNewNameArgKey) &&
+	if runOpts.IsExist(setNewNameArgKey) && !isMigrate &&
 		(isRename ||
 			!runOpts.IsExist(setNameArgKey) && !runOpts.IsExist(setIdArgKey)) {
 		return helper.ErrorFmt("dbcopy invalid arguments: %s must be used with %s any of: %s %s", setNewNameArgKey, renameArgKey, setNameArgKey, setIdArgKey)
@@ -611,6 +916,38 @@ func mainBody(args []string) error {
 			return helper.ErrorNew("dbcopy invalid argument(s) for", renameArgKey)
 		}
 
+	// do verify model runs data integrity
+	case isVerify:
+		err = dbVerify(modelName, modelDigest, runOpts)
+
+	// list workset history or compare versions of workset parameter
+	case isHistory:
+		err = dbHistory(modelName, modelDigest, runOpts)
+
+	// restore workset or workset parameter from history version
+	case isRestore:
+		err = dbRestoreHistory(modelName, modelDigest, runOpts)
+
+	// delete old versions of workset history
+	case isPrune:
+		err = dbPruneHistory(modelName, modelDigest, runOpts)
+
+	// create workset history tables if not exist
+	case isUpgrade:
+		err = dbUpgradeHistory(modelName, modelDigest, runOpts)
+
+	// compare model versions and migrate workset or model run parameters
+	case isMigrate:
+		err = dbMigrate(modelName, modelDigest, runOpts)
+
+	// create downstream model workset from upstream model run
+	case isImport:
+		err = dbImportFromRun(modelName, modelDigest, runOpts)
+
+	// create workset from template or re-apply stored workset template
+	case isTemplate || isReapply:
+		err = dbApplyTemplate(modelName, modelDigest, isReapply, runOpts)
+
 	// copy model run
 	case !isDel && !isRename &&
 		(runOpts.IsExist(runNameArgKey) || runOpts.IsExist(runIdArgKey) || runOpts.IsExist(runDigestArgKey) || runOpts.IsExist(runFirstArgKey) || runOpts.IsExist(runLastArgKey)):
@@ -5,6 +5,8 @@ package main
 
 import (
 	"database/sql"
+	"io/fs"
+	"os"
 	"path/filepath"
 
 	"github.com/openmpp/go/ompp/config"
@@ -34,59 +36,66 @@ func textToDb(modelName string, runOpts *config.RunOptions) error {
 		return err
 	}
 
-	// use modelName as subdirectory inside of input and output directories or as name of model.zip file
-	inpDir := runOpts.String(inputDirArgKey)
-
-	if !runOpts.Bool(zipArgKey) {
-		inpDir = filepath.Join(inpDir, modelName) // json and csv files located in modelName subdir
-	} else {
-		omppLog.Log("Unpack", modelName+".zip")
-
-		outDir := runOpts.String(outputDirArgKey)
-		if outDir == "" {
-			outDir = filepath.Join(inpDir, modelName)
-		}
-		if err = helper.UnpackZip(filepath.Join(inpDir, modelName+".zip"), !theCfg.isKeepOutputDir, outDir); err != nil {
-			return err
-		}
-		inpDir = filepath.Join(outDir, modelName)
+	// use modelName as subdirectory inside of input directory or as name of model.zip file
+	inpFs, closeFs, err := openInputFs(filepath.Join(runOpts.String(inputDirArgKey), modelName), runOpts.Bool(zipArgKey))
+	if err != nil {
+		return err
 	}
+	defer closeFs()
 
 	// insert model metadata from json file into database
-	modelDef, err := fromModelJsonToDb(dstDb, inpDir, modelName)
+	modelDef, err := fromModelJsonToDb(dstDb, inpFs, modelName)
 	if err != nil {
 		return err
 	}
 
 	// insert languages and model text metadata from json file into database
-	langDef, err := fromLangTextJsonToDb(dstDb.DB, modelDef, inpDir)
+	langDef, err := fromLangTextJsonToDb(dstDb.DB, modelDef, inpFs)
 	if err != nil {
 		return err
 	}
 
 	// insert model runs data from csv into database:
 	// parameters, output expressions and accumulators
-	if err = fromRunTextListToDb(dstDb, modelDef, langDef, inpDir); err != nil {
+	if err = fromRunTextListToDb(dstDb, modelDef, langDef, inpFs); err != nil {
 		return err
 	}
 
 	// insert model workset data from csv into database: input parameters
-	if err = fromWorksetTextListToDb(dstDb, modelDef, langDef, inpDir); err != nil {
+	if err = fromWorksetTextListToDb(dstDb, modelDef, langDef, inpFs); err != nil {
 		return err
 	}
 
 	// insert modeling tasks and tasks run history from json file into database
-	if err = fromTaskListJsonToDb(dstDb.DB, modelDef, langDef, inpDir); err != nil {
+	if err = fromTaskListJsonToDb(dstDb.DB, modelDef, langDef, inpFs); err != nil {
 		return err
 	}
 	return nil
 }
 
+// openInputFs return input directory or, if isZip is true, inpDir.zip archive as read-only file system.
+// Zip archive is not unpacked on disk, json and csv files are read directly from the archive,
+// archive root directory is a base name of input directory, e.g.: modelOne for modelOne.zip.
+// Caller must use returned close function to release input file system.
+func openInputFs(inpDir string, isZip bool) (fs.FS, func(), error) {
+
+	if !isZip {
+		return os.DirFS(inpDir), func() {}, nil
+	}
+	omppLog.Log("Read", inpDir+".zip")
+
+	fsys, zc, err := helper.OpenZipFS(inpDir+".zip", filepath.Base(inpDir))
+	if err != nil {
+		return nil, nil, err
+	}
+	return fsys, func() { zc.Close() }, nil
+}
+
 // fromModelJsonToDb reads model metadata from json file and insert it into database.
-func fromModelJsonToDb(dbConn db.Dbc, inpDir string, modelName string) (*db.ModelMeta, error) {
+func fromModelJsonToDb(dbConn db.Dbc, inpFs fs.FS, modelName string) (*db.ModelMeta, error) {
 
 	// restore  model metadta from json
-	js, err := helper.FileToUtf8(filepath.Join(inpDir, modelName+".model.json"), "")
+	js, err := helper.FsFileToUtf8(inpFs, modelName+".model.json", "")
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +119,7 @@ func fromModelJsonToDb(dbConn db.Dbc, inpDir string, modelName string) (*db.Mode
 
 	// insert, update or delete model default profile
 	var modelProfile db.ProfileMeta
-	isExist, err = helper.FromJsonFile(filepath.Join(inpDir, modelName+".profile.json"), &modelProfile)
+	isExist, err = helper.FromJsonFs(inpFs, modelName+".profile.json", &modelProfile)
 	if err != nil {
 		return nil, err
 	}
@@ -124,10 +133,10 @@ func fromModelJsonToDb(dbConn db.Dbc, inpDir string, modelName string) (*db.Mode
 }
 
 // fromLangTextJsonToDb reads languages and model text from json file and insert it into database.
-func fromLangTextJsonToDb(dbConn *sql.DB, modelDef *db.ModelMeta, inpDir string) (*db.LangMeta, error) {
+func fromLangTextJsonToDb(dbConn *sql.DB, modelDef *db.ModelMeta, inpFs fs.FS) (*db.LangMeta, error) {
 
 	// restore language list from json and if exist then update db tables
-	js, err := helper.FileToUtf8(filepath.Join(inpDir, modelDef.Model.Name+".lang.json"), "")
+	js, err := helper.FsFileToUtf8(inpFs, modelDef.Model.Name+".lang.json", "")
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +160,7 @@ func fromLangTextJsonToDb(dbConn *sql.DB, modelDef *db.ModelMeta, inpDir string)
 
 	// restore text data from json and if exist then update db tables
 	var modelTxt db.ModelTxtMeta
-	isExist, err = helper.FromJsonFile(filepath.Join(inpDir, modelDef.Model.Name+".text.json"), &modelTxt)
+	isExist, err = helper.FromJsonFs(inpFs, modelDef.Model.Name+".text.json", &modelTxt)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +172,7 @@ func fromLangTextJsonToDb(dbConn *sql.DB, modelDef *db.ModelMeta, inpDir string)
 
 	// restore model language-specific strings from json and if exist then update db table
 	var mwDef db.ModelWordMeta
-	isExist, err = helper.FromJsonFile(filepath.Join(inpDir, modelDef.Model.Name+".word.json"), &mwDef)
+	isExist, err = helper.FromJsonFs(inpFs, modelDef.Model.Name+".word.json", &mwDef)
 	if err != nil {
 		return nil, err
 	}
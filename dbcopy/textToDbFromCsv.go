@@ -6,8 +6,8 @@ package main
 import (
 	"encoding/csv"
 	"io"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
 	"strings"
 
 	"github.com/openmpp/go/ompp/db"
@@ -19,6 +19,7 @@ func writeParamFromCsvFile(
 	dbConn db.Dbc,
 	modelDef *db.ModelMeta,
 	layout db.WriteParamLayout,
+	inpFs fs.FS,
 	csvDir string,
 	csvCvt db.CellParamConverter,
 ) error {
@@ -41,7 +42,7 @@ func writeParamFromCsvFile(
 	}
 	ch := strings.Join(chs, ",")
 
-	f, err := os.Open(filepath.Join(csvDir, fn))
+	f, err := inpFs.Open(path.Join(csvDir, fn))
 	if err != nil {
 		return helper.ErrorNew("csv file open error:", err)
 	}
@@ -66,6 +67,7 @@ func writeTableFromCsvFiles(
 	dbConn db.Dbc,
 	modelDef *db.ModelMeta,
 	layout db.WriteTableLayout,
+	inpFs fs.FS,
 	csvDir string,
 	cvtExpr db.CellExprConverter,
 	cvtAcc db.CellAccConverter) error {
@@ -87,7 +89,7 @@ func writeTableFromCsvFiles(
 	}
 	ah := strings.Join(ahs, ",")
 
-	accFile, err := os.Open(filepath.Join(csvDir, aFn))
+	accFile, err := inpFs.Open(path.Join(csvDir, aFn))
 	if err != nil {
 		return helper.ErrorNew("accumulators csv file open error:", err)
 	}
@@ -115,7 +117,7 @@ func writeTableFromCsvFiles(
 	}
 	eh := strings.Join(ehs, ",")
 
-	exprFile, err := os.Open(filepath.Join(csvDir, eFn))
+	exprFile, err := inpFs.Open(path.Join(csvDir, eFn))
 	if err != nil {
 		return helper.ErrorNew("expressions csv file open error:", err)
 	}
@@ -142,6 +144,7 @@ func writeMicroFromCsvFile(
 	modelDef *db.ModelMeta,
 	runMeta *db.RunMeta,
 	layout db.WriteMicroLayout,
+	inpFs fs.FS,
 	csvDir string,
 	csvCvt db.CellMicroConverter,
 ) error {
@@ -164,7 +167,7 @@ func writeMicroFromCsvFile(
 	}
 	ch := strings.Join(chs, ",")
 
-	f, err := os.Open(filepath.Join(csvDir, fn))
+	f, err := inpFs.Open(path.Join(csvDir, fn))
 	if err != nil {
 		return helper.ErrorNew("csv file open error:", err)
 	}
@@ -186,11 +189,11 @@ func writeMicroFromCsvFile(
 
 // return closure to iterate over csv file rows
 func makeFromCsvReader(
-	fileName string, csvFile *os.File, csvHeader string, csvToCell func(row []string) (interface{}, error),
+	fileName string, csvFile io.Reader, csvHeader string, csvToCell func(row []string) (interface{}, error),
 ) (func() (interface{}, error), error) {
 
 	// create csv reader from utf-8 line
-	uRd, err := helper.Utf8Reader(csvFile, theCfg.encodingName)
+	uRd, err := helper.ReaderToUtf8(csvFile, theCfg.encodingName)
 	if err != nil {
 		return nil, helper.ErrorNew("fail to create utf-8 converter:", err)
 	}
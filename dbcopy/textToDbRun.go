@@ -4,7 +4,8 @@
 package main
 
 import (
-	"os"
+	"io/fs"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -48,28 +49,19 @@ func textToDbRun(modelName string, modelDigest string, runOpts *config.RunOption
 		inpDir = filepath.Join(runOpts.String(inputDirArgKey), modelName+".run."+helper.CleanFileName(runName))
 	}
 
-	// unzip if required and use unzipped directory as "root" input directory
-	if runOpts.Bool(zipArgKey) {
-		base := filepath.Base(inpDir)
-		omppLog.Log("Unpack", base+".zip")
-
-		outDir := runOpts.String(outputDirArgKey)
-		if outDir == "" {
-			outDir = inpDir
-		}
-		if err := helper.UnpackZip(inpDir+".zip", !theCfg.isKeepOutputDir, outDir); err != nil {
-			return err
-		}
-		inpDir = filepath.Join(outDir, base)
+	// use input directory or read directly from input.zip as "root" input directory
+	inpFs, closeFs, err := openInputFs(inpDir, runOpts.Bool(zipArgKey))
+	if err != nil {
+		return err
 	}
+	defer closeFs()
 
 	// get model run metadata json path and csv directory by run id or run name or both
 	var metaPath string
 
 	if runOpts.IsExist(runNameArgKey) && runOpts.IsExist(runIdArgKey) { // both: run id and name
 
-		metaPath = filepath.Join(inpDir,
-			modelName+".run."+strconv.Itoa(runId)+"."+helper.CleanFileName(runName)+".json")
+		metaPath = modelName + ".run." + strconv.Itoa(runId) + "." + helper.CleanFileName(runName) + ".json"
 
 	} else { // only run id or run name and/or run digest
 
@@ -86,7 +78,7 @@ func textToDbRun(modelName string, modelDigest string, runOpts *config.RunOption
 		}
 
 		// find path to metadata json by pattern
-		fl, err := filepath.Glob(inpDir + "/" + mp)
+		fl, err := fs.Glob(inpFs, mp)
 		if err != nil {
 			return err
 		}
@@ -95,7 +87,7 @@ func textToDbRun(modelName string, modelDigest string, runOpts *config.RunOption
 		}
 		metaPath = fl[0]
 		if len(fl) > 1 {
-			omppLog.Log("found multiple model run metadata json files, using:", filepath.Base(metaPath))
+			omppLog.Log("found multiple model run metadata json files, using:", path.Base(metaPath))
 		}
 	}
 
@@ -103,7 +95,7 @@ func textToDbRun(modelName string, modelDigest string, runOpts *config.RunOption
 	if metaPath == "" {
 		return helper.ErrorNew("no metadata json file found for model run:", runId, runName, runDigest)
 	}
-	if _, err := os.Stat(metaPath); err != nil {
+	if _, err := fs.Stat(inpFs, metaPath); err != nil {
 		return helper.ErrorNew("no metadata json file found for model run:", runId, runName, runDigest)
 	}
 
@@ -133,7 +125,7 @@ func textToDbRun(modelName string, modelDigest string, runOpts *config.RunOption
 	}
 
 	// read from metadata json and csv files and update target database
-	dstId, err := fromRunTextToDb(dstDb, modelDef, langDef, runName, metaPath)
+	dstId, err := fromRunTextToDb(dstDb, modelDef, langDef, runName, inpFs, metaPath)
 	if err != nil {
 		return err
 	}
@@ -151,12 +143,12 @@ func fromRunTextListToDb(
 	dbConn db.Dbc,
 	modelDef *db.ModelMeta,
 	langDef *db.LangMeta,
-	inpDir string,
+	inpFs fs.FS,
 
 ) error {
 
 	// get list of model run json files
-	fl, err := filepath.Glob(inpDir + "/" + modelDef.Model.Name + ".run.*.json")
+	fl, err := fs.Glob(inpFs, modelDef.Model.Name+".run.*.json")
 	if err != nil {
 		return err
 	}
@@ -170,7 +162,7 @@ func fromRunTextListToDb(
 	// update model run digest
 	for k := range fl {
 
-		_, err := fromRunTextToDb(dbConn, modelDef, langDef, "", fl[k])
+		_, err := fromRunTextToDb(dbConn, modelDef, langDef, "", inpFs, fl[k])
 		if err != nil {
 			return err
 		}
@@ -190,6 +182,7 @@ func fromRunTextToDb(
 	modelDef *db.ModelMeta,
 	langDef *db.LangMeta,
 	srcName string,
+	inpFs fs.FS,
 	metaPath string,
 ) (int, error) {
 
@@ -201,7 +194,7 @@ func fromRunTextToDb(
 	// get model run metadata
 	// model name and set name must be specified as parameter or inside of metadata json
 	var pub db.RunPub
-	isExist, err := helper.FromJsonFile(metaPath, &pub)
+	isExist, err := helper.FromJsonFs(inpFs, metaPath, &pub)
 	if err != nil {
 		return 0, err
 	}
@@ -210,24 +203,24 @@ func fromRunTextToDb(
 	}
 
 	// check if run subdir exist
-	d, f := filepath.Split(metaPath)
+	d, f := path.Split(metaPath)
 	c := strings.TrimSuffix(strings.TrimPrefix(f, pub.ModelName+"."), ".json")
-	pDir := filepath.Join(c, "parameters")
-	tDir := filepath.Join(c, "output-tables")
-	mDir := filepath.Join(c, "microdata")
+	pDir := path.Join(c, "parameters")
+	tDir := path.Join(c, "output-tables")
+	mDir := path.Join(c, "microdata")
 	nMd := len(pub.Entity)
 
-	paramCsvDir := filepath.Join(d, pDir)
-	if _, err := os.Stat(paramCsvDir); err != nil {
+	paramCsvDir := path.Join(d, pDir)
+	if _, err := fs.Stat(inpFs, paramCsvDir); err != nil {
 		return 0, helper.ErrorNew("csv parameters directory not found:" + pDir)
 	}
-	tableCsvDir := filepath.Join(d, tDir)
-	if _, err := os.Stat(tableCsvDir); err != nil {
+	tableCsvDir := path.Join(d, tDir)
+	if _, err := fs.Stat(inpFs, tableCsvDir); err != nil {
 		return 0, helper.ErrorNew("csv output tables directory not found:" + tDir)
 	}
-	microCsvDir := filepath.Join(d, mDir)
+	microCsvDir := path.Join(d, mDir)
 	if nMd > 0 {
-		if _, err := os.Stat(microCsvDir); err != nil {
+		if _, err := fs.Stat(inpFs, microCsvDir); err != nil {
 			return 0, helper.ErrorNew("csv microdata directory not found:" + mDir)
 		}
 	}
@@ -287,7 +280,7 @@ func fromRunTextToDb(
 			DoubleFmt: theCfg.doubleFmt,
 		}
 
-		err = writeParamFromCsvFile(dbConn, modelDef, paramLt, paramCsvDir, cvtParam)
+		err = writeParamFromCsvFile(dbConn, modelDef, paramLt, inpFs, paramCsvDir, cvtParam)
 		if err != nil {
 			omppLog.Log("Error at:", paramLt.Name, ":", err)
 			omppLog.Log("Cleanup on error: delete model run", srcName, dstId)
@@ -339,7 +332,7 @@ func fromRunTextToDb(
 
 		logT = omppLog.LogIfTime(logT, logPeriod, helper.Fmt("    %d of %d: %s", j, nT, tblLt.Name))
 
-		err := writeTableFromCsvFiles(dbConn, modelDef, tblLt, tableCsvDir, cvtExpr, cvtAcc)
+		err := writeTableFromCsvFiles(dbConn, modelDef, tblLt, inpFs, tableCsvDir, cvtExpr, cvtAcc)
 		if err != nil {
 			omppLog.Log("Error at:", tblLt.Name, ":", err)
 			omppLog.Log("Cleanup on error: delete model run", srcName, dstId)
@@ -388,7 +381,7 @@ func fromRunTextToDb(
 
 			logT = omppLog.LogIfTime(logT, logPeriod, helper.Fmt("    %d of %d: %s", j, nMd, microLt.Name))
 
-			err := writeMicroFromCsvFile(dbConn, modelDef, meta, microLt, microCsvDir, cvtMicro)
+			err := writeMicroFromCsvFile(dbConn, modelDef, meta, microLt, inpFs, microCsvDir, cvtMicro)
 			if err != nil {
 				omppLog.Log("Error at:", pub.Entity[j].Name, ":", err)
 				omppLog.Log("Cleanup on error: delete model run", srcName, dstId)
@@ -5,7 +5,8 @@ package main
 
 import (
 	"database/sql"
-	"os"
+	"io/fs"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -40,13 +41,19 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 		inpDir = filepath.Join(runOpts.String(inputDirArgKey), modelName+".task."+taskName)
 	}
 
+	// use input directory or read directly from input.zip as task root directory
+	inpFs, closeFs, err := openInputFs(inpDir, runOpts.Bool(zipArgKey))
+	if err != nil {
+		return err
+	}
+	defer closeFs()
+
 	// get model task metadata json path by task id or task name or both
 	var metaPath string
 
 	if runOpts.IsExist(taskNameArgKey) && runOpts.IsExist(taskIdArgKey) { // both: task id and name
 
-		metaPath = filepath.Join(inpDir,
-			modelName+".task."+strconv.Itoa(taskId)+"."+helper.CleanFileName(taskName)+".json")
+		metaPath = modelName + ".task." + strconv.Itoa(taskId) + "." + helper.CleanFileName(taskName) + ".json"
 
 	} else { // task id or task name only
 
@@ -60,7 +67,7 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 		}
 
 		// find path to metadata json by pattern
-		fl, err := filepath.Glob(inpDir + "/" + mp)
+		fl, err := fs.Glob(inpFs, mp)
 		if err != nil {
 			return err
 		}
@@ -68,7 +75,7 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 			return helper.ErrorNew("no metadata json file found for modeling task:", taskId, taskName)
 		}
 		if len(fl) > 1 {
-			omppLog.Log("found multiple modeling task metadata json files, using:", filepath.Base(metaPath))
+			omppLog.Log("found multiple modeling task metadata json files, using:", path.Base(metaPath))
 		}
 		metaPath = fl[0]
 	}
@@ -77,7 +84,7 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 	if metaPath == "" {
 		return helper.ErrorNew("no metadata json file found for modeling task:", taskId, taskName)
 	}
-	if _, err := os.Stat(metaPath); err != nil {
+	if _, err := fs.Stat(inpFs, metaPath); err != nil {
 		return helper.ErrorNew("no metadata json file found for modeling task:", taskId, taskName)
 	}
 
@@ -108,7 +115,7 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 
 	// read task metadata from json
 	var pub db.TaskPub
-	isExist, err := helper.FromJsonFile(metaPath, &pub)
+	isExist, err := helper.FromJsonFs(inpFs, metaPath, &pub)
 	if err != nil {
 		return err
 	}
@@ -157,7 +164,7 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 			var jsonPath, csvDir string
 
 			// find path to metadata json by pattern
-			fl, err := filepath.Glob(inpDir + "/" + mp)
+			fl, err := fs.Glob(inpFs, mp)
 			if err != nil {
 				return err
 			}
@@ -167,18 +174,18 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 			}
 			jsonPath = fl[0]
 			if len(fl) > 1 {
-				omppLog.Log("found multiple model run metadata json files, using:", filepath.Base(jsonPath))
+				omppLog.Log("found multiple model run metadata json files, using:", path.Base(jsonPath))
 			}
 
 			// csv directory: check if csv directory exist for that json file
-			d, f := filepath.Split(jsonPath)
+			d, f := path.Split(jsonPath)
 			c := strings.TrimSuffix(strings.TrimPrefix(f, modelName+"."), ".json")
 
 			if len(c) <= 4 { // expected csv directory: run.4.r or run.r
 				csvDir = ""
 			} else {
-				csvDir = filepath.Join(d, c)
-				if _, err := os.Stat(csvDir); err != nil {
+				csvDir = path.Join(d, c)
+				if _, err := fs.Stat(inpFs, csvDir); err != nil {
 					csvDir = ""
 				}
 			}
@@ -188,17 +195,17 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 				isRunNotFound = true // skip: no run metadata json file or csv directory
 				continue
 			}
-			if _, err := os.Stat(jsonPath); err != nil {
+			if _, err := fs.Stat(inpFs, jsonPath); err != nil {
 				isRunNotFound = true // skip: no run metadata json file
 				continue
 			}
-			if _, err := os.Stat(csvDir); err != nil {
+			if _, err := fs.Stat(inpFs, csvDir); err != nil {
 				isRunNotFound = true // skip: no run csv directory
 				continue
 			}
 
 			// read from metadata json and csv files and update target database
-			dstId, err := fromRunTextToDb(dstDb, modelDef, langDef, runName, jsonPath)
+			dstId, err := fromRunTextToDb(dstDb, modelDef, langDef, runName, inpFs, jsonPath)
 			if err != nil {
 				return err
 			}
@@ -228,14 +235,14 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 		var jsonPath, csvDir string
 
 		// find path to metadata json by pattern
-		fl, err := filepath.Glob(inpDir + "/" + mp)
+		fl, err := fs.Glob(inpFs, mp)
 		if err != nil {
 			return err
 		}
 		if len(fl) >= 1 { // set name is unique per model, it is expected to be only one file
 			jsonPath = fl[0]
 			if len(fl) > 1 {
-				omppLog.Log("found multiple workset metadata json files, using:", filepath.Base(jsonPath))
+				omppLog.Log("found multiple workset metadata json files, using:", path.Base(jsonPath))
 			}
 		}
 
@@ -243,28 +250,28 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 		// if metadata json file exist then check if csv directory for that json file
 		if jsonPath != "" {
 
-			d, f := filepath.Split(jsonPath)
+			d, f := path.Split(jsonPath)
 			c := strings.TrimSuffix(strings.TrimPrefix(f, modelName+"."), ".json")
 
 			if len(c) <= 4 { // expected csv directory: set.4.w or set.w
 				csvDir = ""
 			} else {
-				csvDir = filepath.Join(d, c)
-				if _, err := os.Stat(csvDir); err != nil {
+				csvDir = path.Join(d, c)
+				if _, err := fs.Stat(inpFs, csvDir); err != nil {
 					csvDir = ""
 				}
 			}
 
 		} else { // metadata json file not exist: search for csv directory by pattern
 
-			fl, err := filepath.Glob(inpDir + "/" + cp)
+			fl, err := fs.Glob(inpFs, cp)
 			if err != nil {
 				return err
 			}
 			if len(fl) >= 1 {
 				csvDir = fl[0]
 				if len(fl) > 1 {
-					omppLog.Log("found multiple workset csv directories, using:", filepath.Base(csvDir))
+					omppLog.Log("found multiple workset csv directories, using:", path.Base(csvDir))
 				}
 			}
 		}
@@ -276,7 +283,7 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 		}
 
 		// write workset metadata into json and parameter values into csv files
-		dstId, err := fromWorksetTextToDb(dbConn, modelDef, langDef, setName, "", jsonPath, csvDir)
+		dstId, err := fromWorksetTextToDb(dbConn, modelDef, langDef, setName, "", inpFs, jsonPath, csvDir)
 		if err != nil {
 			return err
 		}
@@ -332,10 +339,10 @@ func textToDbTask(modelName string, modelDigest string, runOpts *config.RunOptio
 
 // fromTaskListJsonToDb reads modeling tasks and tasks run history from json file and insert it into database.
 // it does update task id, set id's and run id's with actual id in destination database
-func fromTaskListJsonToDb(dbConn *sql.DB, modelDef *db.ModelMeta, langDef *db.LangMeta, inpDir string) error {
+func fromTaskListJsonToDb(dbConn *sql.DB, modelDef *db.ModelMeta, langDef *db.LangMeta, inpFs fs.FS) error {
 
 	// get list of task json files
-	fl, err := filepath.Glob(inpDir + "/" + modelDef.Model.Name + ".task.*.json")
+	fl, err := fs.Glob(inpFs, modelDef.Model.Name+".task.*.json")
 	if err != nil {
 		return err
 	}
@@ -348,7 +355,7 @@ func fromTaskListJsonToDb(dbConn *sql.DB, modelDef *db.ModelMeta, langDef *db.La
 
 		// read task metadata from json
 		var pub db.TaskPub
-		isExist, err := helper.FromJsonFile(fl[k], &pub)
+		isExist, err := helper.FromJsonFs(inpFs, fl[k], &pub)
 		if err != nil {
 			return err
 		}
@@ -5,7 +5,8 @@ package main
 
 import (
 	"errors"
-	"os"
+	"io/fs"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -47,20 +48,18 @@ func textToDbWorkset(modelName string, modelDigest string, runOpts *config.RunOp
 		}
 	}
 
-	// unzip if required and use unzipped directory as "root" input diretory
-	if runOpts.Bool(zipArgKey) {
-		base := filepath.Base(inpDir)
-		omppLog.Log("Unpack", base+".zip")
+	// if input is xlsx workbook then import workset parameters from workbook sheets:
+	// workbook path is input directory/modelName.set.setName.xlsx or parameter directory.xlsx
+	if runOpts.Bool(xlsxArgKey) {
+		return textToDbWorksetXlsx(modelName, modelDigest, setName, inpDir+".xlsx", runOpts)
+	}
 
-		outDir := runOpts.String(outputDirArgKey)
-		if outDir == "" {
-			outDir = inpDir
-		}
-		if err := helper.UnpackZip(inpDir+".zip", !theCfg.isKeepOutputDir, outDir); err != nil {
-			return err
-		}
-		inpDir = filepath.Join(outDir, base)
+	// use input directory or read directly from input.zip as "root" input diretory
+	inpFs, closeFs, err := openInputFs(inpDir, runOpts.Bool(zipArgKey))
+	if err != nil {
+		return err
 	}
+	defer closeFs()
 
 	// get workset metadata json path and csv directory by set id or set name or both
 	var metaPath string
@@ -68,17 +67,15 @@ func textToDbWorkset(modelName string, modelDigest string, runOpts *config.RunOp
 
 	if runOpts.IsExist(setNameArgKey) && runOpts.IsExist(setIdArgKey) { // both: set id and name
 
-		metaPath = filepath.Join(inpDir,
-			modelName+".set."+strconv.Itoa(setId)+"."+helper.CleanFileName(setName)+".json")
+		metaPath = modelName + ".set." + strconv.Itoa(setId) + "." + helper.CleanFileName(setName) + ".json"
 
-		if _, err := os.Stat(metaPath); err != nil { // clear path to indicate metadata json file does not exist
+		if _, err := fs.Stat(inpFs, metaPath); err != nil { // clear path to indicate metadata json file does not exist
 			metaPath = ""
 		}
 
-		csvDir = filepath.Join(inpDir,
-			"set."+strconv.Itoa(setId)+"."+helper.CleanFileName(setName))
+		csvDir = "set." + strconv.Itoa(setId) + "." + helper.CleanFileName(setName)
 
-		if _, err := os.Stat(csvDir); err != nil { // clear path to indicate csv directory does not exist
+		if _, err := fs.Stat(inpFs, csvDir); err != nil { // clear path to indicate csv directory does not exist
 			csvDir = ""
 		}
 
@@ -95,14 +92,14 @@ func textToDbWorkset(modelName string, modelDigest string, runOpts *config.RunOp
 		mp := modelName + "." + cp + ".json"
 
 		// find path to metadata json by pattern
-		fl, err := filepath.Glob(inpDir + "/" + mp)
+		fl, err := fs.Glob(inpFs, mp)
 		if err != nil {
 			return err
 		}
 		if len(fl) >= 1 {
 			metaPath = fl[0]
 			if len(fl) > 1 {
-				omppLog.Log("found multiple workset metadata json files, using:", filepath.Base(metaPath))
+				omppLog.Log("found multiple workset metadata json files, using:", path.Base(metaPath))
 			}
 		}
 
@@ -110,28 +107,28 @@ func textToDbWorkset(modelName string, modelDigest string, runOpts *config.RunOp
 		// if metadata json file exist then check if csv directory for that json file
 		if metaPath != "" {
 
-			d, f := filepath.Split(metaPath)
+			d, f := path.Split(metaPath)
 			c := strings.TrimSuffix(strings.TrimPrefix(f, modelName+"."), ".json")
 
 			if len(c) <= 4 { // expected csv directory: set.4.w or set.w
 				csvDir = ""
 			} else {
-				csvDir = filepath.Join(d, c)
-				if _, err := os.Stat(csvDir); err != nil {
+				csvDir = path.Join(d, c)
+				if _, err := fs.Stat(inpFs, csvDir); err != nil {
 					csvDir = ""
 				}
 			}
 
 		} else { // metadata json file not exist: search for csv directory by pattern
 
-			fl, err := filepath.Glob(inpDir + "/" + cp)
+			fl, err := fs.Glob(inpFs, cp)
 			if err != nil {
 				return err
 			}
 			if len(fl) >= 1 {
 				csvDir = fl[0]
 				if len(fl) > 1 {
-					omppLog.Log("found multiple workset csv directories, using:", filepath.Base(csvDir))
+					omppLog.Log("found multiple workset csv directories, using:", path.Base(csvDir))
 				}
 			}
 		}
@@ -170,7 +167,7 @@ func textToDbWorkset(modelName string, modelDigest string, runOpts *config.RunOp
 	// read from metadata json and csv files and update target database
 	dstSetName := runOpts.String(setNewNameArgKey)
 
-	dstId, err := fromWorksetTextToDb(dstDb, modelDef, langDef, setName, dstSetName, metaPath, csvDir)
+	dstId, err := fromWorksetTextToDb(dstDb, modelDef, langDef, setName, dstSetName, inpFs, metaPath, csvDir)
 	if err != nil {
 		return err
 	}
@@ -185,11 +182,11 @@ func textToDbWorkset(modelName string, modelDigest string, runOpts *config.RunOp
 // convert it to db cells and insert into database
 // update set id's and base run id's with actual id in database
 func fromWorksetTextListToDb(
-	dbConn db.Dbc, modelDef *db.ModelMeta, langDef *db.LangMeta, inpDir string,
+	dbConn db.Dbc, modelDef *db.ModelMeta, langDef *db.LangMeta, inpFs fs.FS,
 ) error {
 
 	// get list of workset json files
-	fl, err := filepath.Glob(inpDir + "/" + modelDef.Model.Name + ".set.*.json")
+	fl, err := fs.Glob(inpFs, modelDef.Model.Name+".set.*.json")
 	if err != nil {
 		return err
 	}
@@ -203,20 +200,20 @@ func fromWorksetTextListToDb(
 	for k := range fl {
 
 		// check if workset subdir exist
-		d, f := filepath.Split(fl[k])
+		d, f := path.Split(fl[k])
 		csvDir := strings.TrimSuffix(strings.TrimPrefix(f, modelDef.Model.Name+"."), ".json")
 
 		if len(csvDir) <= 4 { // expected csv directory: set.4.q or set.q
 			csvDir = ""
 		} else {
-			csvDir = filepath.Join(d, csvDir)
-			if _, err := os.Stat(csvDir); err != nil {
+			csvDir = path.Join(d, csvDir)
+			if _, err := fs.Stat(inpFs, csvDir); err != nil {
 				csvDir = ""
 			}
 		}
 
 		// update or insert workset metadata and parameters from csv if csv directory exist
-		_, err := fromWorksetTextToDb(dbConn, modelDef, langDef, "", "", fl[k], csvDir)
+		_, err := fromWorksetTextToDb(dbConn, modelDef, langDef, "", "", inpFs, fl[k], csvDir)
 		if err != nil {
 			return err
 		}
@@ -235,6 +232,7 @@ func fromWorksetTextToDb(
 	langDef *db.LangMeta,
 	srcSetName string,
 	dstSetName string,
+	inpFs fs.FS,
 	metaPath string,
 	csvDir string,
 ) (int, error) {
@@ -255,7 +253,7 @@ func fromWorksetTextToDb(
 
 	if metaPath != "" { // read metadata json file
 
-		isExist, err := helper.FromJsonFile(metaPath, &pub)
+		isExist, err := helper.FromJsonFs(inpFs, metaPath, &pub)
 		if err != nil {
 			return 0, err
 		}
@@ -284,14 +282,14 @@ func fromWorksetTextToDb(
 	//   assume only one parameter sub-value in csv file
 	if metaPath == "" && csvDir != "" {
 
-		fl, err := filepath.Glob(csvDir + "/*.csv")
+		fl, err := fs.Glob(inpFs, path.Join(csvDir, "*.csv"))
 		if err != nil {
 			return 0, err
 		}
 		pub.Param = make([]db.ParamRunSetPub, len(fl))
 
 		for j := range fl {
-			fn := filepath.Base(fl[j])
+			fn := path.Base(fl[j])
 			fn = fn[:len(fn)-4] // remove .csv extension
 			pub.Param[j].Name = fn
 			pub.Param[j].SubCount = 1 // only one sub-value
@@ -362,7 +360,7 @@ func fromWorksetTextToDb(
 			DoubleFmt: theCfg.doubleFmt,
 		}
 
-		err = updateWorksetParamFromCsvFile(dbConn, modelDef, ws, &paramLst[j], csvDir, langDef, cvtParam)
+		err = updateWorksetParamFromCsvFile(dbConn, modelDef, ws, &paramLst[j], inpFs, csvDir, langDef, cvtParam)
 		if err != nil {
 			return 0, err
 		}
@@ -383,6 +381,7 @@ func updateWorksetParamFromCsvFile(
 	modelDef *db.ModelMeta,
 	wsMeta *db.WorksetMeta,
 	paramPub *db.ParamRunSetPub,
+	inpFs fs.FS,
 	csvDir string,
 	langDef *db.LangMeta,
 	csvCvt db.CellParamConverter,
@@ -406,7 +405,7 @@ func updateWorksetParamFromCsvFile(
 	}
 	ch := strings.Join(chs, ",")
 
-	f, err := os.Open(filepath.Join(csvDir, fn))
+	f, err := inpFs.Open(path.Join(csvDir, fn))
 	if err != nil {
 		return helper.ErrorNew("csv file open error:", fn, ":", err)
 	}
@@ -418,7 +417,7 @@ func updateWorksetParamFromCsvFile(
 	}
 
 	// write each csv row into parameter or output table
-	_, err = wsMeta.UpdateWorksetParameterFrom(dbConn, modelDef, true, paramPub, langDef, from)
+	_, err = wsMeta.UpdateWorksetParameterFrom(dbConn, modelDef, true, paramPub, langDef, nil, from)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,344 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// copy workset parameters from xlsx workbook into database, one sheet per parameter.
+// Sheet layout is the same as parameter csv file: sub_id,dim0,dim1,param_value
+func textToDbWorksetXlsx(modelName string, modelDigest string, setName string, xlsxPath string, runOpts *config.RunOptions) error {
+
+	// validate parameters
+	if modelName == "" {
+		return errors.New("invalid (empty) model name")
+	}
+	if setName == "" {
+		return errors.New("invalid (empty) workset name, it is required to import from xlsx workbook")
+	}
+
+	// open xlsx workbook
+	omppLog.Log("Read", filepath.Base(xlsxPath))
+
+	xr, err := helper.OpenXlsx(xlsxPath)
+	if err != nil {
+		return err
+	}
+	defer xr.Close()
+
+	// open destination database connection and check is it valid
+	cs, dn := db.IfEmptyMakeDefault(modelName, runOpts.String(toSqliteArgKey), runOpts.String(toDbConnStrArgKey), theCfg.dstDbDriver)
+
+	dstDb, err := db.Open(cs, dn)
+	if err != nil {
+		return err
+	}
+	defer dstDb.Close()
+
+	if err := db.CheckOpenmppSchemaVersion(dstDb.DB); err != nil {
+		return err
+	}
+
+	// get model metadata
+	modelDef, err := db.GetModel(dstDb.DB, modelName, modelDigest)
+	if err != nil {
+		return err
+	}
+
+	// get full list of languages
+	langDef, err := db.GetLanguages(dstDb.DB)
+	if err != nil {
+		return err
+	}
+
+	// read parameters from xlsx workbook sheets and update target database
+	dstSetName := runOpts.String(setNewNameArgKey)
+
+	dstId, err := fromWorksetXlsxToDb(dstDb, modelDef, langDef, setName, dstSetName, xr)
+	if err != nil {
+		return err
+	}
+	if dstId <= 0 {
+		return errors.New("workset not found or empty: " + setName)
+	}
+
+	return nil
+}
+
+// fromWorksetXlsxToDb read all parameters from xlsx workbook sheets, convert it to db cells and insert into database.
+// Sheets which are not model parameters are skipped.
+// It return destination set id.
+func fromWorksetXlsxToDb(
+	dbConn db.Dbc,
+	modelDef *db.ModelMeta,
+	langDef *db.LangMeta,
+	srcSetName string,
+	dstSetName string,
+	xr *helper.XlsxReader,
+) (int, error) {
+
+	// make list of parameters from workbook sheet names
+	sheetLst := []string{}
+	paramLst := []db.ParamRunSetPub{}
+
+	snLst := xr.SheetNames()
+	pnLst := paramNamesBySheets(modelDef, snLst)
+
+	for j, sn := range snLst {
+
+		pName := pnLst[j]
+		if pName == "" {
+			omppLog.Log("Skip xlsx sheet, it is not a model parameter:", sn)
+			continue
+		}
+		for k := range paramLst {
+			if paramLst[k].Name == pName {
+				return 0, errors.New("xlsx workbook contains multiple sheets of parameter: " + pName + ": " + sheetLst[k] + ", " + sn)
+			}
+		}
+		sheetLst = append(sheetLst, sn)
+		paramLst = append(paramLst, db.ParamRunSetPub{ParamRunSetTxtPub: db.ParamRunSetTxtPub{Name: pName}})
+	}
+	if len(paramLst) <= 0 {
+		return 0, errors.New("xlsx workbook does not contain any model parameters, workset: " + srcSetName)
+	}
+
+	// workset metadata: model name and set name, save it as "read-write" and after importing all parameters set it as "readonly"
+	pub := db.WorksetPub{
+		WorksetHdrPub: db.WorksetHdrPub{
+			ModelName: modelDef.Model.Name,
+			Name:      srcSetName,
+		},
+		Param: []db.ParamRunSetPub{},
+	}
+
+	// rename destination workset
+	if dstSetName != "" {
+		pub.Name = dstSetName
+	}
+
+	// destination: convert from "public" format into destination db rows
+	ws, err := pub.FromPublic(dbConn.DB, modelDef)
+	if err != nil {
+		return 0, err
+	}
+
+	// if destination workset exists then make it read-write and delete all existing parameters from workset
+	wsRow, err := db.GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, ws.Set.Name)
+	if err != nil {
+		return 0, err
+	}
+	isReadonly := false
+
+	if wsRow != nil {
+		isReadonly = wsRow.IsReadonly
+
+		err = db.UpdateWorksetReadonly(dbConn.DB, wsRow.SetId, false) // make destination workset read-write
+		if err != nil {
+			return 0, errors.New("failed to clear workset read-only status: " + strconv.Itoa(wsRow.SetId) + " " + wsRow.Name + " " + err.Error())
+		}
+		err = db.DeleteWorksetAllParameters(dbConn.DB, wsRow.SetId) // delete all parameters from workset
+		if err != nil {
+			return 0, errors.New("failed to delete workset " + strconv.Itoa(wsRow.SetId) + " " + wsRow.Name + " " + err.Error())
+		}
+	}
+
+	// create empty workset metadata or update existing workset metadata
+	err = ws.UpdateWorkset(dbConn.DB, modelDef, true, langDef)
+	if err != nil {
+		return 0, err
+	}
+	dstId := ws.Set.SetId // actual set id from destination database
+
+	// read all workset parameters and copy into destination database
+	omppLog.Log("Workset ", srcSetName, " into: ", dstId, " "+ws.Set.Name)
+	nP := len(paramLst)
+	omppLog.Log("  Parameters:", nP)
+	logT := time.Now().Unix()
+
+	for j := range paramLst {
+
+		// read parameter values from xlsx sheet
+		logT = omppLog.LogIfTime(logT, logPeriod, helper.Fmt("    %d of %d: %s", j, nP, paramLst[j].Name))
+
+		cvtParam := db.CellParamConverter{
+			ModelDef:  modelDef,
+			Name:      paramLst[j].Name,
+			IsIdCsv:   false,
+			DoubleFmt: theCfg.doubleFmt,
+		}
+
+		err = updateWorksetParamFromXlsxSheet(dbConn, modelDef, ws, &paramLst[j], xr, sheetLst[j], langDef, cvtParam)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// restore workset readonly status
+	if isReadonly {
+		err = db.UpdateWorksetReadonly(dbConn.DB, dstId, isReadonly)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return dstId, nil
+}
+
+// updateWorksetParamFromXlsxSheet read parameter values from xlsx sheet, insert it into db parameter value table and update workset parameter metadata.
+// Parameter sub-value count and default sub-value id are defined by sub_id column values.
+func updateWorksetParamFromXlsxSheet(
+	dbConn db.Dbc,
+	modelDef *db.ModelMeta,
+	wsMeta *db.WorksetMeta,
+	paramPub *db.ParamRunSetPub,
+	xr *helper.XlsxReader,
+	sheetName string,
+	langDef *db.LangMeta,
+	csvCvt db.CellParamConverter,
+) error {
+
+	// converter from sheet row []string to db cell
+	cvt, err := csvCvt.ToCellByColumn()
+	if err != nil {
+		return errors.New("invalid converter from xlsx row: " + err.Error())
+	}
+	chs, err := csvCvt.CsvHeader()
+	if err != nil {
+		return errors.New("Error at building parameter header " + paramPub.Name + ": " + err.Error())
+	}
+
+	// read all sheet rows and convert into cells
+	cellLst, err := readXlsxSheetCells(xr, sheetName, chs, cvt)
+	if err != nil {
+		return err
+	}
+	if len(cellLst) <= 0 {
+		return errors.New("xlsx sheet is empty: " + sheetName)
+	}
+
+	// sub-value count is a number of distinct sub_id values
+	// if there is only one sub-value then it is a default sub-value id
+	subIds := map[int]bool{}
+	for _, c := range cellLst {
+		if cp, ok := c.(db.CellParam); ok {
+			subIds[cp.SubId] = true
+		}
+	}
+	paramPub.SubCount = len(subIds)
+	paramPub.DefaultSubId = 0
+	if len(subIds) == 1 {
+		for id := range subIds {
+			paramPub.DefaultSubId = id
+		}
+	}
+
+	// iterate over sheet cells
+	nNext := 0
+	from := func() (interface{}, error) {
+		if nNext >= len(cellLst) {
+			return nil, nil // eof
+		}
+		nNext++
+		return cellLst[nNext-1], nil
+	}
+
+	// write each sheet row into parameter value table
+	_, err = wsMeta.UpdateWorksetParameterFrom(dbConn, modelDef, true, paramPub, langDef, nil, from)
+	if err != nil {
+		return errors.New("xlsx sheet " + sheetName + ": " + err.Error())
+	}
+
+	return nil
+}
+
+// readXlsxSheetCells read xlsx sheet, validate header row and convert each sheet row into db cell.
+// Return error with sheet cell reference, e.g.: ageSex C5 if conversion failed.
+func readXlsxSheetCells(
+	xr *helper.XlsxReader, sheetName string, header []string, toCell func(row []string) (interface{}, int, error),
+) ([]interface{}, error) {
+
+	nCol := len(header)
+	isHdr := true
+	cellLst := []interface{}{}
+
+	err := xr.ReadSheet(sheetName, func(row []string, rowNum int) (bool, error) {
+
+		// trim trailing empty cells and pad short rows by empty values
+		for len(row) > nCol && row[len(row)-1] == "" {
+			row = row[:len(row)-1]
+		}
+		for len(row) < nCol {
+			row = append(row, "")
+		}
+
+		// first row is a header: it must be the same as csv file header
+		if isHdr {
+			isHdr = false
+
+			if h, eh := strings.Join(row, ","), strings.Join(header, ","); h != eh {
+				return false, errors.New("Invalid xlsx sheet header " + sheetName + ": " + h + " expected: " + eh)
+			}
+			return true, nil
+		}
+
+		c, nErr, err := toCell(row)
+		if err != nil {
+			return false, errors.New("xlsx sheet " + sheetName + " " + helper.XlsxCellRef(rowNum, nErr) + ": " + err.Error())
+		}
+		cellLst = append(cellLst, c)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if isHdr {
+		return nil, errors.New("invalid (empty) xlsx sheet: " + sheetName)
+	}
+
+	return cellLst, nil
+}
+
+// return parameter names by xlsx sheet names, name is "" empty string if sheet is not a model parameter.
+// Sheet name must be equal to parameter name or to sheet name created by xlsx export:
+// parameter name truncated to 31 characters and, if truncated name is not unique, with ~1, ~2,... suffix.
+// Export sheet names are reproduced in workbook sheets order,
+// if multiple parameters have the same truncated name then parameters are matched in model parameters order.
+func paramNamesBySheets(modelDef *db.ModelMeta, sheetNames []string) []string {
+
+	pnLst := make([]string, len(sheetNames))
+	isUsed := make([]bool, len(modelDef.Param))
+
+	for j, sn := range sheetNames {
+
+		if idx, ok := modelDef.ParamByName(sn); ok && !isUsed[idx] {
+			pnLst[j] = sn
+			isUsed[idx] = true
+			continue
+		}
+		if len(sn) < helper.XlsxMaxSheetName && !strings.Contains(sn, "~") {
+			continue // sheet name is not truncated and not unique-suffixed by export
+		}
+
+		// sheet name can be truncated parameter name: compare with sheet name created by export
+		for k := range modelDef.Param {
+			if !isUsed[k] && helper.XlsxUniqueSheetName(modelDef.Param[k].Name, sheetNames[:j]) == sn {
+				pnLst[j] = modelDef.Param[k].Name
+				isUsed[k] = true
+				break
+			}
+		}
+	}
+	return pnLst
+}
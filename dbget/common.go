@@ -161,18 +161,20 @@ func dirDeleteAndLog(path string) bool {
 	return true // OK: deleted successfully
 }
 
-// return file extension by output kind: .csv .tsv or .json
+// return file extension by output kind: .csv .tsv .json or .xlsx
 func extByKind() string {
 	switch theCfg.kind {
 	case asTsv:
 		return ".tsv"
 	case asJson:
 		return ".json"
+	case asXlsx:
+		return ".xlsx"
 	}
 	return ".csv" // by default
 }
 
-// return kind of by file extension: .csv .tsv or .json,
+// return kind of by file extension: .csv .tsv .json or .xlsx,
 // if file path is empty or extension is unknown then return csv by default
 func kindByExt(path string) outputAs {
 	if path != "" {
@@ -181,6 +183,8 @@ func kindByExt(path string) outputAs {
 			return asTsv
 		case ".json":
 			return asJson
+		case ".xlsx":
+			return asXlsx
 		}
 	}
 	return asCsv // csv by default
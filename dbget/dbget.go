@@ -3,7 +3,7 @@
 
 /*
 dbget is a command line tool to export OpenM++ model metadata, input parameters and run results.
-It is reading from model database and produce CSV, TSV or JSON output.
+It is reading from model database and produce CSV, TSV or JSON output, model runs and input sets also can be written into XLSX.
 
 You don't need to use driver name for SQLite database, it is enough to specify path to model.sqlite file:
 
@@ -151,6 +151,10 @@ In that case dimension items will be M, F codes instead of Male, Female lables.
 	all-sets         all input scenarios, all parameter values
 	parameter        model run parameter values
 	parameter-set    input scenario parameter values
+	parameter-compare compare parameter values between model runs and input scenarios
+	parameter-diff   list of parameters which are different between model runs and input scenarios
+	run-diff         list of run options, parameters and output tables which are different between model runs
+	parameter-lineage lineage of model run parameters: input scenario, parameter edits, source model runs and input scenarios
 	table            output table values (expressions)
 	sub-table        output table sub-values (a.k.a. sub-samples or accumulators)
 	sub-table-all    output table sub-values, including derived
@@ -250,9 +254,17 @@ Get model run parameters and output table values:
 	dbget -m modelOne -do run -r Default-4 -dbget.NoZeroCsv
 	dbget -m modelOne -do run -r Default-4 -dbget.NoNullCsv
 	dbget -m modelOne -do run -r Default-4 -dbget.NoZeroCsv -dbget.NoNullCsv
+	dbget -m modelOne -do run -r Default-4 -dbget.As xlsx
+	dbget -m modelOne -do run -r Default-4 -dbget.As xlsx -lang fr-CA
+	dbget -m modelOne -do run -r Default-4 -f my-run.xlsx
 
 	dbget -dbget.ModelName modelOne -dbget.Do run -dbget.Run Default
 
+Model run can be written into Excel .xlsx workbook, e.g.: run.Default-4.xlsx
+First sheet is a metadata: run name, status, description, notes and run options,
+next sheets are parameters and output table expressions, one sheet per parameter or output table.
+Sheets are written one by one without keeping output table rows in memory.
+
 Get parameter run values:
 
 	dbget -m modelOne -r Default -parameter ageSex
@@ -361,6 +373,8 @@ Get all parameters from input set (a.k.a. input scenario or workset):
 	dbget -m modelOne -s Default -do set -dbget.IdCsv
 	dbget -m modelOne -s Default -do set -tsv
 	dbget -m modelOne -s Default -do set -pipe
+	dbget -m modelOne -s Default -do set -dbget.As xlsx
+	dbget -m modelOne -s Default -do set -dbget.As xlsx -dbget.NoLanguage
 
 	dbget -dbget.ModelName modelOne -dbget.Do set -dbget.Set Default
 
@@ -377,6 +391,57 @@ Get entity microdata:
 
 	dbget -dbget.ModelName modelOne -dbget.Do micro -dbget.Run "Microdata in database" -dbget.Entity Person
 
+**Compare parameter values between model runs and input scenarios**
+
+Compare ageSex parameter values of first and last model runs: output base value, variant value and difference variant - base:
+
+	dbget -m modelOne -do parameter-compare
+	  -dbget.FirstRun
+	  -dbget.WithLastRun
+	  -dbget.Parameter ageSex
+
+Comparison can be: diff, ratio, percent or comma separated list of expressions of parameter [base] and [variant] values.
+Use -dbget.ChangedOnly to output only cells where variant value is different from base value:
+
+	dbget -m modelOne -do parameter-compare
+	  -dbget.Run       Default
+	  -dbget.WithSets  "New Scenario,Other Scenario"
+	  -dbget.Parameter ageSex
+	  -calc            ratio
+	  -dbget.ChangedOnly
+
+	dbget -m modelOne -do parameter-compare
+	  -s               Default
+	  -dbget.WithRuns  Default-4
+	  -dbget.Parameter ageSex
+	  -calc            "ageSex[variant] - ageSex[base] , OM_IF(ageSex[variant] > ageSex[base] THEN 1 ELSE 0)"
+	  -dbget.CalcName  "Difference , Is Increased"
+
+List all model parameters and check if parameter values are different between model runs and input scenarios:
+
+	dbget -m modelOne -do parameter-diff -dbget.FirstRun -dbget.WithLastRun
+	dbget -m modelOne -do parameter-diff -s Default -dbget.WithRuns Default-4
+	dbget -m modelOne -do parameter-diff -r Default -dbget.WithSets "New Scenario"
+
+**Report everything which is different between model runs**
+
+List run options, parameters and output tables which are different between base and variant model runs.
+For parameters it is a count of different cells, for output tables it is largest absolute and relative change of each expression:
+
+	dbget -m modelOne -do run-diff -dbget.FirstRun -dbget.WithLastRun
+	dbget -m modelOne -do run-diff -r Default -dbget.WithRuns "Default-4,First Task Run_Default"
+	dbget -m modelOne -do run-diff -dbget.FirstRun -dbget.WithLastRun -dbget.As json
+
+**Trace model run parameters back to input edits**
+
+List lineage of model run parameter: input scenario of the model run, saved versions of input scenario parameter,
+model runs and input scenarios where parameter values were copied from. Each step includes digest of parameter values
+and is_changed flag if values are different from previous step. If parameter name is empty then lineage of all parameters:
+
+	dbget -m modelOne -do parameter-lineage -dbget.LastRun -dbget.Parameter ageSex
+	dbget -m modelOne -do parameter-lineage -r Default-4
+	dbget -m modelOne -do parameter-lineage -r Default-4 -dbget.As json
+
 **Compare or aggregate values for model run output tables**
 
 Compare first and last RiskPaths model runs: calculate differnce of T04_FertilityRatesByAgeGroup.Expr0 values
@@ -409,6 +474,14 @@ Or:
 	  -dbget.Table T04_FertilityRatesByAgeGroup
 	  -aggr        OM_VAR(acc0)
 
+Aggregation functions with two arguments must be "quoted" in the list of expressions, for example:
+calculate median, 90th percentile and average of acc0 weighted by acc1:
+
+	dbget -m RiskPaths -do table-compare
+	  -dbget.LastRun
+	  -dbget.Table T04_FertilityRatesByAgeGroup
+	  -aggr        'OM_MEDIAN(acc0) , "OM_PERCENTILE(acc0, 0.9)" , "OM_WAVG(acc0, acc1)"'
+
 Compare and aggregate Riskpaths output table T04_FertilityRatesByAgeGroup:
 - output Expr0 measure values as-is, without any transformation
 - output the differnce between Expr0 variant and base run values (between last and first model runs)
@@ -442,6 +515,36 @@ Model run can be specfied by run id or by name, run stamp or run digest:
 	  -calc             "Expr0       , Expr0[variant] - Expr0[base]"
 	  -aggr             "OM_SD(acc0) , OM_SD(acc1)"
 
+Aggregate output table expressions across multiple model runs, for example, the same scenario with different seeds.
+Calculate average, standard deviation, 95% confidence interval, minimum and maximum of Expr0 across base and all variant runs,
+result of aggregation is written as base run values:
+
+	dbget -m RiskPaths -do table-compare
+	  -dbget.Run          RiskPaths_Default
+	  -dbget.WithRunIds   108,209,310
+	  -dbget.Table        T04_FertilityRatesByAgeGroup
+	  -dbget.RunAggregate 'OM_AVG(Expr0) , OM_SD(Expr0) , "OM_AVG(Expr0) - 1.96 * OM_SE(Expr0)" , "OM_AVG(Expr0) + 1.96 * OM_SE(Expr0)" , OM_MIN(Expr0) , OM_MAX(Expr0)'
+	  -dbget.RunAggrName  "Mean , SD , CI Low , CI High , Min , Max"
+
+Output table calculation can use expressions of other output tables as: table.Name.Expression.
+Other output table dimensions must match dimensions of current table by type, for example,
+calculate ratio of T04_FertilityRatesByAgeGroup.Expr0 to the same expression of other output table:
+
+	dbget -m RiskPaths -do table-compare
+	  -dbget.LastRun
+	  -dbget.Table T04_FertilityRatesByAgeGroup
+	  -calc        "Expr0 / table.OtherTable.Expr0"
+
+Name of derived measure saved in model database can be used as calculation or aggregation expression:
+
+	dbget -m modelOne -do table-compare -dbget.LastRun -dbget.Table salarySex -calc SalaryRatio
+
+By default output table calculations and microdata aggregations are translated into sql and done by database.
+Use -dbget.GoEval to read source values from database and calculate in Go, results are the same for any database vendor.
+Scalar parameters param.Name can be used, other output tables table.Name.Expression are not supported by Go calculation:
+
+	dbget -m modelOne -do table-compare -dbget.LastRun -dbget.Table salarySex -calc "expr0 / expr1" -dbget.GoEval
+
 Compare or aggregate microdata run values.
 
 Aggregate: average AgeGroup Income of entity Person in model run with id 219:
@@ -496,6 +599,24 @@ use -dbget.AggrName to specify desired labels:
 	  -aggr          "OM_AVG(Income), OM_VAR(Income)"
 	  -dbget.AggrName "Average Income, Income Variance"
 
+Numeric (float or integer) microdata attributes can be used as group by attributes if it is binned
+by fixed-width bins, explicit break points or quantile bins, use -dbget.Bins to specify binning:
+
+	dbget -m modelOne
+	  -do micro-compare
+	  -r "Microdata in database"
+	  -dbget.Entity   Person
+	  -dbget.GroupBy  AgeGroup,Income
+	  -dbget.Bins     "Income=width(0, 1000, 10)"
+	  -aggr           "OM_AVG(Salary)"
+
+	-dbget.Bins "Income=breaks(100, 1000, 10000)"
+	-dbget.Bins "Income=quantile(4)"
+	-dbget.Bins "Income=quantile(10); Salary=breaks(0, 500)"
+
+Quantile bins break points are calculated from base run attribute values.
+Result contains bin labels, for example: < 100, [100, 1000), [1000, 10000), >= 10000 or Q1, Q2, Q3, Q4.
+
 Backward compatibility (Modgen).
 
 Get model metadata from compatibility (Modgen) views:
@@ -584,7 +705,7 @@ import (
 const (
 	cmdArgKey           = "dbget.Do"             // action, what to do, for example: model-list
 	cmdShortKey         = "do"                   // action, what to do (short form)
-	asArgKey            = "dbget.As"             // output as csv, tsv or json, default: .csv
+	asArgKey            = "dbget.As"             // output as csv, tsv, json or xlsx, default: .csv
 	csvArgKey           = "csv"                  // short form of: dbget.As csv
 	tsvArgKey           = "tsv"                  // short form of: dbget.As tsv
 	jsonArgKey          = "json"                 // short form of: dbget.As json
@@ -621,6 +742,8 @@ const (
 	withRunIdsArgKey    = "dbget.WithRunIds"     // with list model run id's (variant runs)
 	withRunFirstArgKey  = "dbget.WithFirstRun"   // with first model run (with first run as variant)
 	withRunLastArgKey   = "dbget.WithLastRun"    // with last model run (with last run as variant)
+	withWsArgKey        = "dbget.WithSets"       // with model workset names (variant worksets)
+	changedOnlyArgKey   = "dbget.ChangedOnly"    // if true then output only changed parameter values
 	wsArgKey            = "dbget.Set"            // model workset name
 	wsShortKey          = "s"                    // model workset name (short form)
 	wsIdArgKey          = "dbget.SetId"          // model workset id
@@ -633,29 +756,34 @@ const (
 	subTableAllShortKey = "sub-table-all"        // short form of: -dbget.Do sub-table-all -dbget.Table Name
 	entityArgKey        = "dbget.Entity"         // microdata entity name
 	groupByArgKey       = "dbget.GroupBy"        // microdata group by attributes
+	binsArgKey          = "dbget.Bins"           // microdata bins of numeric group by attributes
 	aggrArgKey          = "dbget.Aggregate"      // outout table or microdata aggregation expression(s)
 	aggrShortKey        = "aggr"                 // short form of: -dbget.Aggregate
 	calcArgKey          = "dbget.Calculate"      // calculation expression(s) to compare or aggregate
 	calcShortKey        = "calc"                 // short form of: -dbget.Calculate
 	aggrNameArgKey      = "dbget.AggrName"       // names of aggregation expression(s)
+	runAggrArgKey       = "dbget.RunAggregate"   // outout table expression(s) aggregation across model runs
+	runAggrNameArgKey   = "dbget.RunAggrName"    // names of model runs aggregation expression(s)
 	calcNameArgKey      = "dbget.CalcName"       // names of calculation expression(s)
+	goEvalArgKey        = "dbget.GoEval"         // if true then calculate output table measures or microdata aggregations in Go
 	microdataShortKey   = "micro"                // short form of: -dbget.Do micro -dbget.Entity Name
 	pidFileArgKey       = "dbget.PidSaveTo"      // file path to save dbget processs ID
 )
 
-// output format: csv by default, or tsv or json
+// output format: csv by default, or tsv or json or xlsx
 type outputAs int
 
 const (
 	asCsv outputAs = iota
 	asTsv
 	asJson
+	asXlsx
 )
 
 // run options
 var theCfg = struct {
 	action          string   // action name (what to do)
-	kind            outputAs // output as csv, tsv or json
+	kind            outputAs // output as csv, tsv, json or xlsx
 	fileName        string   // output file name, default depends on action
 	dir             string   // output directory
 	binDir          string   // path to bin directory where dbget.exe is located
@@ -704,7 +832,7 @@ func mainBody(args []string) error {
 	doEntityName := ""
 	_ = flag.String(cmdArgKey, "", "action, what to do, for example: model-list")
 	_ = flag.String(cmdShortKey, "", "action, what to do (short of "+cmdArgKey+")")
-	_ = flag.String(asArgKey, "", "output as .csv, .tsv or .json, default: .csv")
+	_ = flag.String(asArgKey, "", "output as .csv, .tsv, .json or .xlsx, default: .csv")
 	_ = flag.Bool(csvArgKey, true, "output as .csv (short of "+asArgKey+" csv)")
 	_ = flag.Bool(tsvArgKey, false, "output as .tsv (short of "+asArgKey+" tsv)")
 	_ = flag.Bool(jsonArgKey, false, "output as .json (short of "+asArgKey+" json)")
@@ -741,6 +869,8 @@ func mainBody(args []string) error {
 	_ = flag.String(withRunIdsArgKey, "", "with list model run id's (variant runs)")
 	_ = flag.Bool(withRunFirstArgKey, false, "if true then use first model run (use as variant run)")
 	_ = flag.Bool(withRunLastArgKey, false, "if true then use last model run (use as variant run)")
+	_ = flag.String(withWsArgKey, "", "with input scenario (workset) names (variant worksets)")
+	_ = flag.Bool(changedOnlyArgKey, false, "if true then output only parameter values which are different from base")
 	_ = flag.String(wsArgKey, "", "input scenario (workset) name")
 	_ = flag.String(wsShortKey, "", "input scenario (workset) name (short of "+wsArgKey+")")
 	_ = flag.Int(wsIdArgKey, 0, "input scenario (workset) id")
@@ -754,12 +884,16 @@ func mainBody(args []string) error {
 	flag.StringVar(&doEntityName, microdataShortKey, "", "short form of: -"+cmdArgKey+" micro -"+entityArgKey+" Name")
 	_ = flag.String(entityArgKey, "", "microdata entity name")
 	_ = flag.String(groupByArgKey, "", "list of microdata group by attributes")
+	_ = flag.String(binsArgKey, "", "bins of numeric group by attributes, ex.: Income=width(0,1000,10);Age=breaks(18,65);Salary=quantile(4)")
 	_ = flag.String(aggrArgKey, "", "aggregation expression(s) to aggregate output table or microdata")
 	_ = flag.String(aggrShortKey, "", "aggregation expression(s) (short of "+aggrArgKey+")")
 	_ = flag.String(calcArgKey, "", "calculaton expression(s) to compare or caluculate output table measures")
 	_ = flag.String(calcShortKey, "", "calculaton expression(s) (short of "+calcArgKey+")")
 	_ = flag.String(aggrNameArgKey, "", "name list of aggregation expressions")
+	_ = flag.String(runAggrArgKey, "", "aggregation expression(s) to aggregate output table expressions across model runs")
+	_ = flag.String(runAggrNameArgKey, "", "name list of model runs aggregation expressions")
 	_ = flag.String(calcNameArgKey, "", "name list of calculation expressions")
+	_ = flag.Bool(goEvalArgKey, false, "if true then calculate in Go instead of translating calculation into sql")
 	_ = flag.String(pidFileArgKey, "", "file path to save dbget process ID")
 
 	// pairs of full and short argument names to map short name to full name
@@ -858,6 +992,8 @@ func mainBody(args []string) error {
 			theCfg.kind = asTsv
 		case "json":
 			theCfg.kind = asJson
+		case "xlsx":
+			theCfg.kind = asXlsx
 		default:
 			return helper.ErrorNew("invalid arguments:", asArgKey, f)
 		}
@@ -888,11 +1024,22 @@ func mainBody(args []string) error {
 	if theCfg.kind == asJson {
 		if theCfg.action != "model-list" &&
 			theCfg.action != "model" && theCfg.action != "old-model" &&
-			theCfg.action != "run-list" && theCfg.action != "set-list" {
+			theCfg.action != "run-list" && theCfg.action != "set-list" &&
+			theCfg.action != "run-diff" && theCfg.action != "parameter-lineage" {
 			return helper.ErrorNew("JSON output not allowed for:", theCfg.action)
 		}
 	}
 
+	// output to xlsx workbook supported only for model run and workset, xlsx cannot be written into console
+	if theCfg.kind == asXlsx {
+		if theCfg.action != "run" && theCfg.action != "set" {
+			return helper.ErrorNew("XLSX output not allowed for:", theCfg.action)
+		}
+		if theCfg.isConsole {
+			return helper.ErrorNew("XLSX output cannot be written into console:", theCfg.action)
+		}
+	}
+
 	// open source database connection and check is it valid
 	dn := runOpts.String(dbDriverArgKey)
 	if dn == "" {
@@ -1025,6 +1172,14 @@ func mainBody(args []string) error {
 		return parameterRunValue(srcDb.DB, modelId, runOpts)
 	case "parameter-set":
 		return parameterWsValue(srcDb.DB, modelId, runOpts)
+	case "parameter-compare":
+		return parameterCompare(srcDb.DB, modelId, runOpts)
+	case "parameter-diff":
+		return parameterDiff(srcDb.DB, modelId, runOpts)
+	case "run-diff":
+		return runDiff(srcDb.DB, modelId, runOpts)
+	case "parameter-lineage":
+		return parameterLineage(srcDb.DB, modelId, runOpts)
 	case "table":
 		return tableValue(srcDb.DB, modelId, runOpts)
 	case "table-compare":
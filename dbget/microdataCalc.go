@@ -143,11 +143,17 @@ func microdataCompare(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) er
 	if len(cLst) <= 0 {
 		return helper.ErrorNew("Invalid (empty) microdata aggregation expression(s)")
 	}
+	bins, err := db.ParseMicroBinList(runOpts.String(binsArgKey))
+	if err != nil {
+		return helper.ErrorNew("Invalid microdata group by bins:", err)
+	}
 
 	// set aggregation expressions
 	calcLt := db.CalculateMicroLayout{
 		Calculation: []db.CalculateLayout{},
 		GroupBy:     groupBy,
+		Bins:        bins,
+		IsGoEval:    runOpts.Bool(goEvalArgKey),
 	}
 	cn := helper.ParseCsvLine(runOpts.String(aggrNameArgKey), ',') // list of names, if not empty
 
@@ -190,6 +196,7 @@ func microdataCompare(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) er
 		},
 		CalcMaps: db.EmptyCalcMaps(),
 		GroupBy:  calcLt.GroupBy,
+		Bins:     calcLt.Bins,
 	}
 	if e := cvtMicro.SetCalcIdNameMap(calcLt.Calculation); e != nil {
 		return helper.ErrorNew("Failed to create microdata aggregation converter to csv:", entityName, e)
@@ -385,8 +392,12 @@ func microdataCompare(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) er
 		return true, nil
 	}
 
-	// read microdata values page
-	_, err = db.ReadMicrodataCalculateTo(srcDb, meta, &microLt, &calcLt, runIds, cvtWr)
+	// read microdata values page, aggregate in Go or translate aggregation into sql
+	if calcLt.IsGoEval {
+		_, err = db.EvalMicrodataCalculateTo(srcDb, meta, &microLt, &calcLt, runIds, cvtWr)
+	} else {
+		_, err = db.ReadMicrodataCalculateTo(srcDb, meta, &microLt, &calcLt, runIds, cvtWr)
+	}
 	if err != nil {
 		return helper.ErrorNew("Error at microdata run aggregation output:", entityName, ":", microLt.GenDigest, ":", err)
 	}
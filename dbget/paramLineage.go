@@ -0,0 +1,104 @@
+// Copyright OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strconv"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// Write lineage of model run parameter or all model run parameters into csv, tsv or json file.
+// Lineage starts from model run and goes back to input workset, saved versions of workset parameter
+// and model runs or worksets where parameter values copied from. Each step includes digest of parameter values.
+func parameterLineage(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error {
+
+	// get model metadata
+	meta, err := db.GetModelById(srcDb, modelId)
+	if err != nil {
+		return helper.ErrorNew("Error at get model metadata by id:", modelId, ":", err)
+	}
+
+	// find model run
+	msg, run, err := findRun(srcDb, modelId, runOpts.String(runArgKey), runOpts.Int(runIdArgKey, 0), runOpts.Bool(runFirstArgKey), runOpts.Bool(runLastArgKey))
+	if err != nil {
+		return helper.ErrorNew("Error at get model run:", msg, err.Error())
+	}
+	if run == nil {
+		return helper.ErrorNew("Error: model run not found")
+	}
+
+	// get lineage of parameter, if parameter name is empty then lineage of all parameters
+	name := runOpts.String(paramArgKey)
+
+	plLst, err := db.GetRunParamLineage(srcDb, meta, run, name)
+	if err != nil {
+		return helper.ErrorNew("Error at get parameter lineage:", run.Name, ":", name, ":", err)
+	}
+
+	// write parameter lineage into file or console
+	fp := ""
+
+	if theCfg.isConsole {
+		omppLog.Log("Do", theCfg.action, name)
+	} else {
+
+		fp = theCfg.fileName
+		if fp == "" {
+			if name != "" {
+				fp = name + ".lineage" + extByKind()
+			} else {
+				fp = theCfg.action + extByKind()
+			}
+		}
+		fp = filepath.Join(theCfg.dir, fp)
+
+		omppLog.Log("Do", theCfg.action, ":", fp)
+	}
+
+	if theCfg.kind == asJson {
+		return toJsonOutput(fp, plLst)
+	}
+
+	// write csv output: each line is a lineage step of the parameter
+	hdr := []string{
+		"run_name", "parameter_name", "step", "kind", "step_run_name", "step_run_digest", "set_name", "from_model", "version_id", "value_digest", "is_changed", "note", "user_name", "date_time",
+	}
+	rl := run.Name
+	if theCfg.isIdCsv {
+		hdr[0] = "run_id"
+		rl = strconv.Itoa(run.RunId)
+	}
+
+	// make list of output lines
+	lines := [][]string{}
+
+	for k := range plLst {
+		for j, s := range plLst[k].Step {
+
+			vId := ""
+			if s.VersionId > 0 {
+				vId = strconv.Itoa(s.VersionId)
+			}
+			lines = append(lines, []string{
+				rl, plLst[k].Name, strconv.Itoa(j), s.Kind, s.RunName, s.RunDigest, s.SetName, s.FromModel, vId, s.ValueDigest, strconv.FormatBool(s.IsChanged), s.Note, s.UserName, s.DateTime})
+		}
+	}
+
+	// write csv lines
+	n := 0
+	return toCsvOutput(fp, hdr, func() (bool, []string, error) {
+
+		if n >= len(lines) {
+			return true, nil, nil // end of lines
+		}
+		n++
+		return false, lines[n-1], nil
+	})
+}
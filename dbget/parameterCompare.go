@@ -0,0 +1,446 @@
+// Copyright OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strconv"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// Compare parameter values of base model run or workset with variant model runs or worksets.
+// Calculate comparison value(s), for example, difference or ratio and write results into csv or tsv file.
+func parameterCompare(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error {
+
+	// find base and variant model runs or worksets
+	base, varLst, srcLabels, err := findParamCompareSources(srcDb, modelId, runOpts)
+	if err != nil {
+		return err
+	}
+	if len(varLst) <= 0 {
+		return helper.ErrorNew("Error: there are no model runs or worksets to compare with")
+	}
+
+	// get model metadata and check if parameter exists in the model
+	meta, err := db.GetModelById(srcDb, modelId)
+	if err != nil {
+		return helper.ErrorNew("Error at get model metadata by id:", modelId, ":", err)
+	}
+	name := runOpts.String(paramArgKey)
+
+	if _, ok := meta.ParamByName(name); !ok {
+		return helper.ErrorNew("Error: model parameter not found:", name)
+	}
+
+	// set calculate layout: base value, variant value and comparison expressions
+	calcLt, err := db.ParamCompareCalculation(name, runOpts.String(calcArgKey))
+	if err != nil {
+		return helper.ErrorNew("Error: invalid parameter comparison expression:", runOpts.String(calcArgKey), ":", err)
+	}
+	cn := helper.ParseCsvLine(runOpts.String(calcNameArgKey), ',')
+	for j := 2; j < len(calcLt); j++ {
+		if j-2 < len(cn) && cn[j-2] != "" {
+			calcLt[j].Name = cn[j-2]
+		}
+	}
+
+	// create cell converter to csv
+	cvtParam := db.CellParamCalcConverter{
+		CellParamConverter: db.CellParamConverter{
+			ModelDef:  meta,
+			Name:      name,
+			IsIdCsv:   theCfg.isIdCsv,
+			DoubleFmt: theCfg.doubleFmt,
+		},
+		CalcMaps: db.EmptyCalcMaps(),
+	}
+	if e := cvtParam.SetCalcIdNameMap(calcLt); e != nil {
+		return helper.ErrorNew("Failed to create parameter converter to csv:", meta.Model.Name, name)
+	}
+	for id, label := range srcLabels {
+		cvtParam.CalcMaps.RunIdToLabel[id] = label // add names of base and variant runs or worksets
+	}
+
+	// setup read layout: page size =0, read all values
+	paramLt := db.ReadCalculteParamLayout{
+		ReadLayout: db.ReadLayout{
+			Name:   name,
+			FromId: base.Id,
+		},
+		IsFromSet:     base.IsFromSet,
+		IsChangedOnly: runOpts.Bool(changedOnlyArgKey),
+		Calculation:   calcLt,
+	}
+
+	// make csv header
+	// create converter from db cell into csv row []string
+	hdr := []string{}
+	var cvtRow func(interface{}, []string) (bool, error)
+
+	if theCfg.isNoLang || theCfg.isIdCsv {
+
+		hdr, err = cvtParam.CsvHeader()
+		if err != nil {
+			return helper.ErrorNew("Failed to make parameter csv header:", name, ":", err)
+		}
+		if theCfg.isIdCsv {
+			cvtRow, err = cvtParam.ToCsvIdRow()
+		} else {
+			cvtRow, err = cvtParam.ToCsvRow()
+		}
+		if err != nil {
+			return helper.ErrorNew("Failed to create parameter converter to csv:", name, ":", err)
+		}
+
+	} else { // get language-specific metadata
+
+		txt, err := db.GetModelText(srcDb, meta.Model.ModelId, theCfg.lang, true)
+		if err != nil {
+			return helper.ErrorNew("Error at get language-specific metadata:", err)
+		}
+
+		cvtLoc := &db.CellParamCalcLocaleConverter{
+			CellParamCalcConverter: cvtParam,
+			Lang:                   theCfg.lang,
+			DimsTxt:                txt.ParamDimsTxt,
+			EnumTxt:                txt.TypeEnumTxt,
+		}
+
+		hdr, err = cvtLoc.CsvHeader()
+		if err != nil {
+			return helper.ErrorNew("Failed to make parameter csv header:", name, ":", err)
+		}
+		cvtRow, err = cvtLoc.ToCsvRow()
+		if err != nil {
+			return helper.ErrorNew("Failed to create parameter converter to csv:", name, ":", err)
+		}
+	}
+
+	// write parameter comparison to csv or tsv file
+	fp := ""
+
+	if theCfg.isConsole {
+		omppLog.Log("Do", theCfg.action, name)
+	} else {
+
+		fp = theCfg.fileName
+		if fp == "" {
+			fp = name + ".compare" + extByKind()
+		}
+		fp = filepath.Join(theCfg.dir, fp)
+
+		omppLog.Log("Do", theCfg.action, ":", fp)
+	}
+
+	f, csvWr, err := createCsvWriter(fp)
+	if err != nil {
+		return err
+	}
+	isFile := f != nil
+
+	defer func() {
+		if isFile {
+			f.Close()
+		}
+	}()
+
+	// write csv header
+	if err := csvWr.Write(hdr); err != nil {
+		return helper.ErrorNew("Error at csv write:", name, ":", err)
+	}
+
+	// convert parameter comparison cell into []string and write line into csv file
+	cs := make([]string, len(hdr))
+
+	cvtWr := func(c interface{}) (bool, error) {
+
+		// if converter return empty line then skip it
+		isNotEmpty := true
+		var e2 error = nil
+
+		if isNotEmpty, e2 = cvtRow(c, cs); e2 != nil {
+			return false, e2
+		}
+		if isNotEmpty {
+			if e2 = csvWr.Write(cs); e2 != nil {
+				return false, e2
+			}
+		}
+		return true, nil
+	}
+
+	// read parameter comparison
+	_, err = db.CompareParameterTo(srcDb, meta, &paramLt, varLst, cvtWr)
+	if err != nil {
+		return helper.ErrorNew("Error at parameter comparison output:", name, ":", err)
+	}
+
+	csvWr.Flush() // flush csv to output stream
+
+	return nil
+}
+
+// Compare all parameters of base model run or workset with variant model runs or worksets.
+// For each parameter write into csv or tsv file: is parameter different
+// and count of base and variant cells which are not found in other source or have different value.
+func parameterDiff(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error {
+
+	// find base and variant model runs or worksets
+	base, varLst, srcLabels, err := findParamCompareSources(srcDb, modelId, runOpts)
+	if err != nil {
+		return err
+	}
+	if len(varLst) <= 0 {
+		return helper.ErrorNew("Error: there are no model runs or worksets to compare with")
+	}
+
+	// get model metadata
+	meta, err := db.GetModelById(srcDb, modelId)
+	if err != nil {
+		return helper.ErrorNew("Error at get model metadata by id:", modelId, ":", err)
+	}
+
+	// write parameters comparison summary to csv or tsv file
+	fp := ""
+
+	if theCfg.isConsole {
+		omppLog.Log("Do", theCfg.action)
+	} else {
+
+		fp = theCfg.fileName
+		if fp == "" {
+			fp = theCfg.action + extByKind()
+		}
+		fp = filepath.Join(theCfg.dir, fp)
+
+		omppLog.Log("Do", theCfg.action, ":", fp)
+	}
+
+	f, csvWr, err := createCsvWriter(fp)
+	if err != nil {
+		return err
+	}
+	isFile := f != nil
+
+	defer func() {
+		if isFile {
+			f.Close()
+		}
+	}()
+
+	// write csv header
+	hdr := []string{"base", "variant", "parameter_name", "is_diff", "base_count", "variant_count"}
+	if theCfg.isIdCsv {
+		hdr[0] = "base_id"
+		hdr[1] = "variant_id"
+	}
+	if err := csvWr.Write(hdr); err != nil {
+		return helper.ErrorNew("Error at csv write:", theCfg.action, ":", err)
+	}
+
+	// for each variant compare all model parameters and write summary lines
+	cs := make([]string, len(hdr))
+
+	for _, v := range varLst {
+
+		smLst, err := db.CompareParameterSummary(srcDb, meta, base, v)
+		if err != nil {
+			return helper.ErrorNew("Error at parameters compare:", srcLabels[base.Id], ":", srcLabels[v.Id], ":", err)
+		}
+
+		if theCfg.isIdCsv {
+			cs[0] = strconv.Itoa(base.Id)
+			cs[1] = strconv.Itoa(v.Id)
+		} else {
+			cs[0] = srcLabels[base.Id]
+			cs[1] = srcLabels[v.Id]
+		}
+		for k := range smLst {
+
+			cs[2] = smLst[k].Name
+			cs[3] = strconv.FormatBool(smLst[k].IsDiff)
+			cs[4] = strconv.FormatInt(smLst[k].BaseCount, 10)
+			cs[5] = strconv.FormatInt(smLst[k].VarCount, 10)
+
+			if err := csvWr.Write(cs); err != nil {
+				return helper.ErrorNew("Error at csv write:", theCfg.action, ":", err)
+			}
+		}
+	}
+
+	csvWr.Flush() // flush csv to output stream
+
+	return nil
+}
+
+// find base model run or workset and list of variant runs and worksets to compare parameters.
+// Base is a workset if workset name or id specified else it is a model run.
+// If base not specified then first variant is used as base.
+// Model runs must be completed successfully and worksets must be read-only.
+// Return base, list of variants and map of run id or set id to run name or workset name.
+func findParamCompareSources(
+	srcDb *sql.DB, modelId int, runOpts *config.RunOptions,
+) (db.CompareParamSource, []db.CompareParamSource, map[int]string, error) {
+
+	var base db.CompareParamSource
+	isBase := false
+	varLst := []db.CompareParamSource{}
+	srcLabels := map[int]string{}
+
+	// find base workset or base model run
+	if runOpts.String(wsArgKey) != "" || runOpts.IsExist(wsIdArgKey) {
+
+		ws, err := findWs(srcDb, modelId, runOpts)
+		if err != nil {
+			return base, varLst, srcLabels, err
+		}
+		base = db.CompareParamSource{Id: ws.SetId, IsFromSet: true}
+		isBase = true
+		srcLabels[ws.SetId] = ws.Name
+
+	} else {
+
+		msg, baseRun, err := findRun(srcDb, modelId, runOpts.String(runArgKey), runOpts.Int(runIdArgKey, 0), runOpts.Bool(runFirstArgKey), runOpts.Bool(runLastArgKey))
+		if err != nil {
+			return base, varLst, srcLabels, helper.ErrorNew("Error at get base model run:", msg, err)
+		}
+		if baseRun != nil {
+			if baseRun.Status != db.DoneRunStatus {
+				return base, varLst, srcLabels, helper.ErrorNew("Error: base model run not completed successfully:", msg)
+			}
+			base = db.CompareParamSource{Id: baseRun.RunId, IsFromSet: false}
+			isBase = true
+			srcLabels[baseRun.RunId] = baseRun.Name
+		} else {
+			if runOpts.String(runArgKey) != "" || runOpts.Int(runIdArgKey, 0) != 0 || runOpts.Bool(runFirstArgKey) || runOpts.Bool(runLastArgKey) {
+				return base, varLst, srcLabels, helper.ErrorNew("Error: base model run not found")
+			}
+		}
+	}
+
+	// push variant to the list of variants, if base not specified then use first variant as base
+	pushToVar := func(src string, label string, v db.CompareParamSource) {
+
+		if !isBase {
+			base = v
+			isBase = true
+			srcLabels[v.Id] = label
+			return
+		}
+		if _, isFound := srcLabels[v.Id]; isFound {
+			omppLog.Log("Warning: skip this model run or workset, it is the same as base or already in the list:", src)
+			return
+		}
+		srcLabels[v.Id] = label
+		varLst = append(varLst, v)
+	}
+
+	// check variant run search results and push to variants list
+	pushRun := func(src string, m string, r *db.RunRow) error {
+
+		if r == nil {
+			return helper.ErrorNew("Error: model run not found:", src)
+		}
+		if r.Status != db.DoneRunStatus {
+			return helper.ErrorNew("Error: model run not completed successfully:", m)
+		}
+		pushToVar(src, r.Name, db.CompareParamSource{Id: r.RunId, IsFromSet: false})
+		return nil
+	}
+
+	// get variant runs from comma separarted list of digest, stamp or name
+	if rdsnLst := helper.ParseCsvLine(runOpts.String(withRunsArgKey), ','); len(rdsnLst) > 0 {
+
+		for _, rdsn := range rdsnLst {
+
+			if rdsn == "" {
+				continue
+			}
+			m, r, e := findRun(srcDb, modelId, rdsn, 0, false, false)
+			if e != nil {
+				return base, varLst, srcLabels, helper.ErrorNew("Error at get model run:", m, e)
+			}
+			if e = pushRun(rdsn, m, r); e != nil {
+				return base, varLst, srcLabels, e
+			}
+		}
+	}
+	// get variant runs from comma separarted list of run id's
+	if idLst := helper.ParseCsvLine(runOpts.String(withRunIdsArgKey), ','); len(idLst) > 0 {
+
+		for _, sId := range idLst {
+
+			if sId == "" {
+				continue
+			}
+			rId, e := strconv.Atoi(sId)
+			if e != nil || rId <= 0 {
+				return base, varLst, srcLabels, helper.ErrorNew("Invalid model run id:", sId)
+			}
+
+			m, r, e := findRun(srcDb, modelId, "", rId, false, false)
+			if e != nil {
+				return base, varLst, srcLabels, helper.ErrorNew("Error at get model run:", m, e)
+			}
+			if e = pushRun(sId, m, r); e != nil {
+				return base, varLst, srcLabels, e
+			}
+		}
+	}
+	// check if first run must be used as variant run
+	if runOpts.Bool(withRunFirstArgKey) {
+
+		m, r, e := findRun(srcDb, modelId, "", 0, true, false)
+		if e != nil {
+			return base, varLst, srcLabels, helper.ErrorNew("Error at get first model run:", m, e)
+		}
+		if e = pushRun(m, m, r); e != nil {
+			return base, varLst, srcLabels, e
+		}
+	}
+	// check if last run must be used as variant run
+	if runOpts.Bool(withRunLastArgKey) {
+
+		m, r, e := findRun(srcDb, modelId, "", 0, false, true)
+		if e != nil {
+			return base, varLst, srcLabels, helper.ErrorNew("Error at get last model run:", m, e)
+		}
+		if e = pushRun(m, m, r); e != nil {
+			return base, varLst, srcLabels, e
+		}
+	}
+
+	// get variant worksets from comma separarted list of workset names
+	if wsLst := helper.ParseCsvLine(runOpts.String(withWsArgKey), ','); len(wsLst) > 0 {
+
+		for _, wsName := range wsLst {
+
+			if wsName == "" {
+				continue
+			}
+			ws, e := db.GetWorksetByName(srcDb, modelId, wsName)
+			if e != nil {
+				return base, varLst, srcLabels, helper.ErrorNew("Error at get workset:", wsName, e)
+			}
+			if ws == nil {
+				return base, varLst, srcLabels, helper.ErrorNew("Error: workset not found:", wsName)
+			}
+			if !ws.IsReadonly {
+				return base, varLst, srcLabels, helper.ErrorNew("Error: workset must be read-only:", wsName)
+			}
+			pushToVar(wsName, ws.Name, db.CompareParamSource{Id: ws.SetId, IsFromSet: true})
+		}
+	}
+
+	// check: base model run or workset must exist
+	if !isBase {
+		return base, varLst, srcLabels, helper.ErrorNew("Error: base model run or workset not found")
+	}
+	return base, varLst, srcLabels, nil
+}
@@ -0,0 +1,135 @@
+// Copyright OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// Compare base model run with variant model runs: run options, parameters and output tables.
+// Write into csv, tsv or json file everything which is different between base and each variant run:
+// run options, parameters where values are different and count of different cells,
+// output tables where values are different and largest absolute and relative change of each expression.
+func runDiff(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error {
+
+	// find base and variant model runs
+	base, varLst, srcLabels, err := findParamCompareSources(srcDb, modelId, runOpts)
+	if err != nil {
+		return err
+	}
+	if base.IsFromSet {
+		return helper.ErrorNew("Error: base must be a model run, not an input scenario:", srcLabels[base.Id])
+	}
+	if len(varLst) <= 0 {
+		return helper.ErrorNew("Error: there are no model runs to compare with")
+	}
+	for _, v := range varLst {
+		if v.IsFromSet {
+			return helper.ErrorNew("Error: variant must be a model run, not an input scenario:", srcLabels[v.Id])
+		}
+	}
+
+	// get model metadata
+	meta, err := db.GetModelById(srcDb, modelId)
+	if err != nil {
+		return helper.ErrorNew("Error at get model metadata by id:", modelId, ":", err)
+	}
+
+	// compare base run with each variant run
+	rdLst := []db.RunDiff{}
+
+	for _, v := range varLst {
+
+		rd, err := db.CompareRuns(srcDb, meta, base.Id, v.Id)
+		if err != nil {
+			return helper.ErrorNew("Error at model runs compare:", srcLabels[base.Id], ":", srcLabels[v.Id], ":", err)
+		}
+		rdLst = append(rdLst, *rd)
+	}
+
+	// write run difference into file or console
+	fp := ""
+
+	if theCfg.isConsole {
+		omppLog.Log("Do", theCfg.action)
+	} else {
+
+		fp = theCfg.fileName
+		if fp == "" {
+			fp = theCfg.action + extByKind()
+		}
+		fp = filepath.Join(theCfg.dir, fp)
+
+		omppLog.Log("Do", theCfg.action, ":", fp)
+	}
+
+	if theCfg.kind == asJson {
+		return toJsonOutput(fp, rdLst)
+	}
+
+	// write csv output: each line is option, parameter or output table expression which is different
+	// for options it is base and variant values
+	// for parameters it is count of base and variant cells not found in other run or with different value
+	// for output table expressions it is count of different cells, largest absolute and relative change
+	hdr := []string{
+		"base_run", "variant_run", "kind", "name", "expr_name", "base_value", "variant_value", "base_count", "variant_count", "max_abs", "max_rel",
+	}
+	if theCfg.isIdCsv {
+		hdr[0] = "base_run_id"
+		hdr[1] = "variant_run_id"
+	}
+
+	// make list of output lines
+	lines := [][]string{}
+
+	for k := range rdLst {
+
+		bl := srcLabels[base.Id]
+		vl := srcLabels[varLst[k].Id]
+		if theCfg.isIdCsv {
+			bl = strconv.Itoa(base.Id)
+			vl = strconv.Itoa(varLst[k].Id)
+		}
+
+		for _, o := range rdLst[k].Option {
+			lines = append(lines, []string{bl, vl, "option", o.Key, "", o.Base, o.Variant, "", "", "", ""})
+		}
+		for _, p := range rdLst[k].Param {
+			lines = append(lines, []string{
+				bl, vl, "parameter", p.Name, "", "", "", strconv.FormatInt(p.BaseCount, 10), strconv.FormatInt(p.VarCount, 10), "", ""})
+		}
+		for _, t := range rdLst[k].Table {
+
+			if t.IsMissing {
+				lines = append(lines, []string{bl, vl, "table", t.Name, "", "", "", "", "", "", ""})
+				continue
+			}
+			for _, e := range t.Expr {
+
+				nc := strconv.FormatInt(e.DiffCount, 10)
+				lines = append(lines, []string{
+					bl, vl, "table", t.Name, e.Name, "", "", nc, nc, fmt.Sprintf(theCfg.doubleFmt, e.MaxAbs), fmt.Sprintf(theCfg.doubleFmt, e.MaxRel)})
+			}
+		}
+	}
+
+	// write csv lines
+	n := 0
+	return toCsvOutput(fp, hdr, func() (bool, []string, error) {
+
+		if n >= len(lines) {
+			return true, nil, nil // end of lines
+		}
+		n++
+		return false, lines[n-1], nil
+	})
+}
@@ -30,10 +30,6 @@ func runValue(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error {
 	if run.Status != db.DoneRunStatus {
 		return helper.ErrorNew("Error: model run not completed successfully:", run.Name)
 	}
-	runMeta, err := db.GetRunFull(srcDb, run)
-	if err != nil {
-		return helper.ErrorNew("Error at get model run:", run.Name, err)
-	}
 
 	// get model metadata
 	meta, err := db.GetModelById(srcDb, modelId)
@@ -41,6 +37,16 @@ func runValue(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error {
 		return helper.ErrorNew("Error at get model metadata by id:", modelId, ":", err)
 	}
 
+	// write model run into .xlsx workbook
+	if theCfg.kind == asXlsx {
+		return runValueXlsx(srcDb, meta, run, runOpts)
+	}
+
+	runMeta, err := db.GetRunFull(srcDb, run)
+	if err != nil {
+		return helper.ErrorNew("Error at get model run:", run.Name, err)
+	}
+
 	// create output directory
 	// if output directory name not explicitly specified then use run.RunName by default
 	runTop := theCfg.dir
@@ -31,6 +31,11 @@ func setValue(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error {
 		return err
 	}
 
+	// write workset into .xlsx workbook
+	if theCfg.kind == asXlsx {
+		return setValueXlsx(srcDb, meta, wsRow, runOpts)
+	}
+
 	// create output directory
 	// if output directory name not explicitly specified then use set.SetName by default
 	wsDir := theCfg.dir
@@ -17,6 +17,7 @@ import (
 // Compare output table expression(s) between model runs or aggregate output tables sub-values.
 // Calculate non-aggregation value(s), for example, difference or ratio and write run results into csv or tsv file.
 // Aggregate output table sub-values: calculate new measure and write run results into csv or tsv file.
+// Aggregate output table expression(s) across model runs: calculate new measure and write it as base run results into csv or tsv file.
 func tableCompare(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error {
 
 	// find base model run
@@ -184,8 +185,28 @@ func tableCompare(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error
 			}
 		}
 	}
+	n += len(ce)
+
+	ce = helper.ParseCsvLine(runOpts.String(runAggrArgKey), ',')
+	cn = helper.ParseCsvLine(runOpts.String(runAggrNameArgKey), ',')
+	for j := range ce {
+
+		if ce[j] != "" {
+			calcLt = append(calcLt, db.CalculateTableLayout{
+				CalculateLayout: db.CalculateLayout{
+					Calculate: ce[j],
+					CalcId:    n + j + db.CALCULATED_ID_OFFSET,
+					Name:      "ex_" + strconv.Itoa(n+j+db.CALCULATED_ID_OFFSET),
+				},
+				IsRunAggr: true,
+			})
+			if j < len(cn) && cn[j] != "" {
+				calcLt[len(calcLt)-1].Name = cn[j]
+			}
+		}
+	}
 	if len(calcLt) <= 0 {
-		return helper.ErrorNew("Error: invalid (empty) calculation and aggregation expression", runOpts.String(calcArgKey), runOpts.String(aggrArgKey))
+		return helper.ErrorNew("Error: invalid (empty) calculation and aggregation expression", runOpts.String(calcArgKey), runOpts.String(aggrArgKey), runOpts.String(runAggrArgKey))
 	}
 
 	// create cell converter to csv
@@ -219,6 +240,7 @@ func tableCompare(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error
 			Name:   name,
 			FromId: baseRun.RunId,
 		},
+		IsGoEval: runOpts.Bool(goEvalArgKey),
 	}
 
 	// make csv header
@@ -333,8 +355,12 @@ func tableCompare(srcDb *sql.DB, modelId int, runOpts *config.RunOptions) error
 		return true, nil
 	}
 
-	// read output table page
-	_, err = db.ReadOutputTableCalculteTo(srcDb, meta, &tableLt, calcLt, runIds, cvtWr)
+	// read output table page, calculate in Go or translate calculation into sql
+	if tableLt.IsGoEval {
+		_, err = db.EvalOutputTableCalculteTo(srcDb, meta, &tableLt, calcLt, runIds, cvtWr)
+	} else {
+		_, err = db.ReadOutputTableCalculteTo(srcDb, meta, &tableLt, calcLt, runIds, cvtWr)
+	}
 	if err != nil {
 		return helper.ErrorNew("Error at output table aggregation output:", name, ":", err)
 	}
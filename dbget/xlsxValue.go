@@ -0,0 +1,121 @@
+// Copyright OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+
+	"github.com/openmpp/go/ompp/config"
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// write model run metadata, parameters and output tables into .xlsx workbook: one sheet per parameter or output table
+func runValueXlsx(srcDb *sql.DB, meta *db.ModelMeta, run *db.RunRow, runOpts *config.RunOptions) error {
+
+	runMeta, err := db.GetRunFullText(srcDb, run, true, "")
+	if err != nil {
+		return helper.ErrorNew("Error at get model run:", run.Name, err)
+	}
+
+	// use specified file name or make default as run.Name.xlsx
+	fp := theCfg.fileName
+	if fp == "" {
+		fp = "run." + helper.CleanFileName(run.Name) + ".xlsx"
+	}
+	fp = filepath.Join(theCfg.dir, fp)
+
+	omppLog.Log("Do", theCfg.action, ":", fp)
+
+	opts, err := xlsxOptions(srcDb, meta, runOpts)
+	if err != nil {
+		return err
+	}
+	xw, err := createXlsx(fp)
+	if err != nil {
+		return err
+	}
+	if err = db.WriteRunXlsx(srcDb, meta, runMeta, opts, xw); err != nil {
+		xw.Close()
+		return helper.ErrorNew("Error at model run output:", run.Name, ":", err)
+	}
+	return xw.Close()
+}
+
+// write workset metadata and parameters into .xlsx workbook: one sheet per parameter
+func setValueXlsx(srcDb *sql.DB, meta *db.ModelMeta, wsRow *db.WorksetRow, runOpts *config.RunOptions) error {
+
+	wsMeta, err := db.GetWorksetFull(srcDb, wsRow, "")
+	if err != nil {
+		return helper.ErrorNew("Error at get workset:", wsRow.Name, err)
+	}
+
+	// use specified file name or make default as set.Name.xlsx
+	fp := theCfg.fileName
+	if fp == "" {
+		fp = "set." + helper.CleanFileName(wsRow.Name) + ".xlsx"
+	}
+	fp = filepath.Join(theCfg.dir, fp)
+
+	omppLog.Log("Do", theCfg.action, ":", fp)
+
+	opts, err := xlsxOptions(srcDb, meta, runOpts)
+	if err != nil {
+		return err
+	}
+	xw, err := createXlsx(fp)
+	if err != nil {
+		return err
+	}
+	if err = db.WriteWorksetXlsx(srcDb, meta, wsMeta, opts, xw); err != nil {
+		xw.Close()
+		return helper.ErrorNew("Error at workset output:", wsRow.Name, ":", err)
+	}
+	return xw.Close()
+}
+
+// create output directory, if required, and create .xlsx workbook file
+func createXlsx(path string) (*helper.XlsxWriter, error) {
+
+	if err := makeOutputDir(theCfg.dir, true); err != nil {
+		return nil, err
+	}
+	return helper.CreateXlsx(path)
+}
+
+// return .xlsx output options: language-specific labels or language-neutral enum codes or enum id's
+func xlsxOptions(srcDb *sql.DB, meta *db.ModelMeta, runOpts *config.RunOptions) (*db.XlsxWriteOptions, error) {
+
+	opts := db.XlsxWriteOptions{
+		IsIdCsv:     theCfg.isIdCsv,
+		DoubleFmt:   theCfg.doubleFmt,
+		IsNoZeroCsv: runOpts.Bool(noZeroArgKey),
+		IsNoNullCsv: runOpts.Bool(noNullArgKey),
+	}
+	if theCfg.isNoLang || theCfg.isIdCsv || theCfg.lang == "" {
+		return &opts, nil
+	}
+
+	// make list of model translated strings: merge common.message.ini and lang_word
+	langDef, err := db.GetLanguages(srcDb)
+	if err != nil {
+		return nil, helper.ErrorNew("Error at get language-specific metadata:", err)
+	}
+	opts.MsgDef = db.NewLangMsg(langDef.Lang, nil)
+
+	if cmIni, e := config.ReadCommonMessageIni(theCfg.binDir, theCfg.encodingName); e == nil {
+		opts.MsgDef = db.AppendLangMsgFromIni(opts.MsgDef, cmIni)
+	}
+
+	// model language-specific lables for dimensions, items and tables
+	opts.TxtMeta, err = db.GetModelText(srcDb, meta.Model.ModelId, theCfg.lang, true)
+	if err != nil {
+		return nil, helper.ErrorNew("Error at get model text metadata:", err)
+	}
+	opts.Lang = theCfg.lang
+
+	return &opts, nil
+}
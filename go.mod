@@ -12,6 +12,7 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.48
 	github.com/microsoft/go-mssqldb v1.10.0
 	golang.org/x/text v0.40.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
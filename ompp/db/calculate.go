@@ -16,6 +16,7 @@ const CALCULATED_ID_OFFSET = 12000 // calculated exprssion id offset, for exampl
 // CalculateOutputTable read output table page (dimensions and values) and calculate extra measure(s).
 //
 // If calcLt.IsAggr true then do accumulator(s) aggregation else calculate expression value(s), ex: Expr1[variant] - Expr1[base].
+// If calcLt.IsRunAggr true then aggregate expression(s) across base run and all runIds, ex: OM_AVG(Expr1).
 func CalculateOutputTable(dbConn *sql.DB, modelDef *ModelMeta, tableLt *ReadCalculteTableLayout, runIds []int) (*list.List, *ReadPageLayout, error) {
 
 	// validate parameters
@@ -40,8 +41,17 @@ func CalculateOutputTable(dbConn *sql.DB, modelDef *ModelMeta, tableLt *ReadCalc
 		return nil, nil, errors.New("output table not found: " + tableLt.Name)
 	}
 
+	// replace derived measure names by measure calculation
+	cLt, err := resolveDerivedTableCalc(dbConn, modelDef, table, tableLt.Calculation)
+	if err != nil {
+		return nil, nil, err
+	}
+	tLt := *tableLt
+	tLt.Calculation = cLt
+	tableLt = &tLt
+
 	// translate calculation to sql
-	q, err := translateTableCalcToSql(modelDef, table, &tableLt.ReadLayout, tableLt.Calculation, runIds)
+	q, err := translateTableCalcToSql(facetOfDb(dbConn), modelDef, table, &tableLt.ReadLayout, tableLt.Calculation, runIds)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -95,7 +105,8 @@ func CalculateOutputTable(dbConn *sql.DB, modelDef *ModelMeta, tableLt *ReadCalc
 // It can be a multiple runs comparison and base run id is layout.FromId.
 // Or simple expression calculation inside of single run or accumulators aggregation inside of single run,
 // in that case layout.FromId and runIds[] are merged.
-func translateTableCalcToSql(modelDef *ModelMeta, table *TableMeta, readLt *ReadLayout, calcLt []CalculateTableLayout, runIds []int) (string, error) {
+// Or aggregation of expressions across all runs: layout.FromId and runIds[] are merged and result is a base run value.
+func translateTableCalcToSql(dbFacet Facet, modelDef *ModelMeta, table *TableMeta, readLt *ReadLayout, calcLt []CalculateTableLayout, runIds []int) (string, error) {
 
 	// translate each calculation to sql: CTE and main sql query
 	cteSql := []string{}
@@ -125,13 +136,21 @@ func translateTableCalcToSql(modelDef *ModelMeta, table *TableMeta, readLt *Read
 		cteAcc := ""
 		var err error
 
-		if !calcLt[k].IsAggr {
-			cte, mSql, _, err = partialTranslateToExprSql(modelDef, table, paramCols, readLt, &calcLt[k].CalculateLayout, runIds)
-		} else {
-			cteAcc, mSql, err = partialTranslateToAccSql(modelDef, table, paramCols, readLt, &calcLt[k].CalculateLayout, runIds)
+		switch {
+		case calcLt[k].IsAggr && calcLt[k].IsRunAggr:
+			err = errors.New("Error at " + table.Name + " " + calcLt[k].Calculate + ": calculation cannot be accumulators aggregation and model runs aggregation")
+		case calcLt[k].IsRunAggr:
+			cteAcc, mSql, err = partialTranslateToRunAggrSql(dbFacet, modelDef, table, paramCols, readLt, &calcLt[k].CalculateLayout, runIds)
 			if err == nil {
 				cte = []string{cteAcc}
 			}
+		case calcLt[k].IsAggr:
+			cteAcc, mSql, err = partialTranslateToAccSql(dbFacet, modelDef, table, paramCols, readLt, &calcLt[k].CalculateLayout, runIds)
+			if err == nil {
+				cte = []string{cteAcc}
+			}
+		default:
+			cte, mSql, _, err = partialTranslateToExprSql(modelDef, table, paramCols, readLt, &calcLt[k].CalculateLayout, runIds)
 		}
 		if err != nil {
 			return "", err
@@ -227,6 +246,15 @@ func CalculateMicrodata(dbConn *sql.DB, modelDef *ModelMeta, microLt *ReadCalcul
 		return nil, nil, errors.New("entity not found: " + microLt.Name)
 	}
 
+	// replace derived measure names by measure calculation
+	cLt, err := resolveDerivedMicroCalc(dbConn, modelDef, entity, &microLt.CalculateMicroLayout)
+	if err != nil {
+		return nil, nil, err
+	}
+	mLt := *microLt
+	mLt.CalculateMicroLayout = *cLt
+	microLt = &mLt
+
 	// find entity generation by entity id, as it is today model run has only one entity generation for each entity
 	egLst, err := GetEntityGenList(dbConn, microLt.FromId)
 	if err != nil {
@@ -245,6 +273,13 @@ func CalculateMicrodata(dbConn *sql.DB, modelDef *ModelMeta, microLt *ReadCalcul
 		return nil, nil, errors.New("Error: entity generation not found: " + microLt.Name + ": " + strconv.Itoa(microLt.FromId))
 	}
 
+	// calculate break points of quantile bins from base run attribute values
+	if len(microLt.Bins) > 0 {
+		if microLt.Bins, err = resolveMicroBins(dbConn, entity, entityGen, microLt.Bins, microLt.FromId); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// find group by microdata attributes by name
 	aGroupBy := []EntityAttrRow{}
 
@@ -263,8 +298,10 @@ func CalculateMicrodata(dbConn *sql.DB, modelDef *ModelMeta, microLt *ReadCalcul
 			}
 			aGroupBy = append(aGroupBy, entity.Attr[aIdx])
 
-			// group by attributes must boolean or not built-in
-			if entity.Attr[aIdx].typeOf.IsBuiltIn() && !entity.Attr[aIdx].typeOf.IsBool() {
+			// group by attributes must boolean or not built-in, numeric attributes must be binned
+			_, isBin := findMicroBin(microLt.Bins, entity.Attr[aIdx].Name)
+
+			if entity.Attr[aIdx].typeOf.IsBuiltIn() && !entity.Attr[aIdx].typeOf.IsBool() && !isBin {
 				return nil, nil, errors.New("invalid type of entity group by attribute not found by: " + entity.Name + "." + microLt.GroupBy[j] + " : " + entity.Attr[aIdx].typeOf.Name)
 			}
 
@@ -284,14 +321,14 @@ func CalculateMicrodata(dbConn *sql.DB, modelDef *ModelMeta, microLt *ReadCalcul
 	}
 
 	// translate calculation to sql
-	q, err := translateMicroToSql(modelDef, entity, entityGen, &microLt.ReadLayout, &microLt.CalculateMicroLayout, runIds)
+	q, err := translateMicroToSql(facetOfDb(dbConn), modelDef, entity, entityGen, &microLt.ReadLayout, &microLt.CalculateMicroLayout, runIds)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// prepare db-row scan conversion buffer: run_id, calculation id, group by attributes, value
 	// and define conversion function to make new cell from scan buffer
-	scanBuf, fc, err := scanSqlRowToCellMicroCalc(entity, aGroupBy)
+	scanBuf, fc, err := scanSqlRowToCellMicroCalc(entity, aGroupBy, microLt.Bins)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -324,7 +361,7 @@ func CalculateMicrodata(dbConn *sql.DB, modelDef *ModelMeta, microLt *ReadCalcul
 // Translate all microdata aggregations to sql query, apply group by, dimension filters, selected run id's and order by.
 // It can be a multiple runs comparison and base run id is layout.FromId.
 // Or simple aggreagtion inside of single run, in that case layout.FromId and runIds[] are merged.
-func translateMicroToSql(modelDef *ModelMeta, entity *EntityMeta, entityGen *EntityGenMeta, readLt *ReadLayout, calcLt *CalculateMicroLayout, runIds []int) (string, error) {
+func translateMicroToSql(dbFacet Facet, modelDef *ModelMeta, entity *EntityMeta, entityGen *EntityGenMeta, readLt *ReadLayout, calcLt *CalculateMicroLayout, runIds []int) (string, error) {
 
 	// translate each calculation to sql: CTE and main sql query
 	mainSql := []string{}
@@ -333,11 +370,19 @@ func translateMicroToSql(modelDef *ModelMeta, entity *EntityMeta, entityGen *Ent
 	if err != nil {
 		return "", err
 	}
+	aggrCols, err = applyMicroBins(entity.Name, aggrCols, calcLt.GroupBy, calcLt.Bins)
+	if err != nil {
+		return "", err
+	}
 	paramCols := makeParamCols(modelDef.Param)
 
 	// validate filter names: it must be name of attribute or name of calculated attribute
+	// filter by binned attribute is not supported
 	for k := range readLt.Filter {
 
+		if _, ok := findMicroBin(calcLt.Bins, readLt.Filter[k].Name); ok {
+			return "", errors.New("Error: entity " + entity.Name + " filter by binned attribute is not supported: " + readLt.Filter[k].Name)
+		}
 		isOk := false
 		for j := 0; !isOk && j < len(calcLt.Calculation); j++ {
 			isOk = calcLt.Calculation[j].Name == readLt.Filter[k].Name
@@ -349,11 +394,16 @@ func translateMicroToSql(modelDef *ModelMeta, entity *EntityMeta, entityGen *Ent
 			return "", errors.New("Error: entity " + entity.Name + " does not have attribute " + readLt.Filter[k].Name)
 		}
 	}
+	for k := range readLt.FilterById {
+		if _, ok := findMicroBin(calcLt.Bins, readLt.FilterById[k].Name); ok {
+			return "", errors.New("Error: entity " + entity.Name + " filter by binned attribute is not supported: " + readLt.FilterById[k].Name)
+		}
+	}
 
 	// translate all calculations to sql
 	for k := range calcLt.Calculation {
 
-		mSql, _, err := partialTranslateToMicroSql(modelDef, entity, entityGen, aggrCols, paramCols, readLt, &calcLt.Calculation[k], runIds)
+		mSql, _, err := partialTranslateToMicroSql(dbFacet, modelDef, entity, entityGen, aggrCols, paramCols, readLt, &calcLt.Calculation[k], runIds)
 		if err != nil {
 			return "", err
 		}
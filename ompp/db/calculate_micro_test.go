@@ -4,6 +4,7 @@
 package db
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -135,7 +136,7 @@ func TestTranslateMicroCalcToSql(t *testing.T) {
 		paramCols := makeParamCols(modelDef.Param)
 
 		// Translate microdata aggregation into main sql query.
-		mainSql, isCompare, e := translateMicroCalcToSql(entity, entityGen, aggrCols, paramCols, 2*CALCULATED_ID_OFFSET, srcCalc)
+		mainSql, isCompare, e := translateMicroCalcToSql(srcDb.Dbf, entity, entityGen, aggrCols, paramCols, 2*CALCULATED_ID_OFFSET, srcCalc)
 		if e != nil {
 			t.Fatal(e)
 		}
@@ -384,3 +385,159 @@ func TestCalculateMicrodata(t *testing.T) {
 		// valid := opts.String("CalculateMicrodata.Valid_"+strconv.Itoa(k+1)]
 	}
 }
+
+func TestEvalMicrodata(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.micro-aggregate.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modelName := opts.String("EvalMicrodata.ModelName")
+	modelDigest := opts.String("EvalMicrodata.ModelDigest")
+	modelSqliteDbPath := opts.String("EvalMicrodata.DbPath")
+	entityName := opts.String("EvalMicrodata.EntityName")
+	baseRunId := opts.Int("EvalMicrodata.BaseRunId", 0)
+
+	// open source database connection and check is it valid
+	cs := MakeSqliteDefaultReadOnly(modelSqliteDbPath)
+	t.Log(cs)
+
+	srcDb, err := Open(cs, SQLiteDbDriver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcDb.Close()
+
+	if err := CheckOpenmppSchemaVersion(srcDb.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	// get model metadata
+	modelDef, err := GetModel(srcDb.DB, modelName, modelDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modelDef == nil {
+		t.Fatalf("model not found: %s :%s:", modelName, modelDigest)
+	}
+	t.Log("Model:", modelDef.Model.Name, " ", modelDef.Model.Digest)
+
+	// find model entity by entity name and entity generation of base run
+	eIdx, ok := modelDef.EntityByName(entityName)
+	if !ok {
+		t.Fatal("entity not found:", entityName)
+	}
+	egLst, err := GetEntityGenList(srcDb.DB, baseRunId)
+	if err != nil {
+		t.Fatal("Error at get run entities: ", entityName, ": ", baseRunId, ": ", err.Error())
+	}
+	genDigest := ""
+	for k := range egLst {
+		if egLst[k].EntityId == modelDef.Entity[eIdx].EntityId {
+			genDigest = egLst[k].GenDigest
+			break
+		}
+	}
+	if genDigest == "" {
+		t.Fatal("Error: model run entity generation not found: ", entityName, ": ", baseRunId)
+	}
+
+	for k := 0; k < 400; k++ {
+
+		srcCalc := opts.String("EvalMicrodata.Calculate_" + strconv.Itoa(k+1))
+		if srcCalc == "" {
+			continue
+		}
+		t.Log(srcCalc)
+
+		groupBy := helper.ParseCsvLine(opts.String("EvalMicrodata.GroupBy_"+strconv.Itoa(k+1)), ',')
+		t.Log("Group by: ", groupBy)
+
+		bins, err := ParseMicroBinList(opts.String("EvalMicrodata.Bins_" + strconv.Itoa(k+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		runIds := []int{}
+		if sVal := opts.String("EvalMicrodata.RunIds_" + strconv.Itoa(k+1)); sVal != "" {
+
+			sArr := helper.ParseCsvLine(sVal, ',')
+			for j := range sArr {
+				if id, err := strconv.Atoi(sArr[j]); err != nil {
+					t.Fatal(err)
+				} else {
+					runIds = append(runIds, id)
+				}
+			}
+		}
+		t.Log("run id's: ", runIds)
+
+		calcLt := CalculateMicroLayout{GroupBy: groupBy, Bins: bins}
+
+		ce := helper.ParseCsvLine(srcCalc, ',')
+		for j := range ce {
+
+			c := strings.TrimSpace(ce[j])
+			if c != "" && c[0] == '"' && c[len(c)-1] == '"' {
+				c = c[1 : len(c)-1]
+			}
+			if c != "" {
+				calcLt.Calculation = append(calcLt.Calculation, CalculateLayout{
+					Calculate: c,
+					CalcId:    CALCULATED_ID_OFFSET + j,
+					Name:      "micro_" + strconv.Itoa(j),
+				})
+			}
+		}
+
+		// aggregate by sql and in Go, results must be the same
+		sqlLst := []CellMicroCalc{}
+		goLst := []CellMicroCalc{}
+
+		microLt := ReadMicroLayout{ReadLayout: ReadLayout{Name: entityName, FromId: baseRunId}, GenDigest: genDigest}
+
+		_, err = ReadMicrodataCalculateTo(srcDb.DB, modelDef, &microLt, &calcLt, runIds, func(c interface{}) (bool, error) {
+			sqlLst = append(sqlLst, c.(CellMicroCalc))
+			return true, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = EvalMicrodataCalculateTo(srcDb.DB, modelDef, &microLt, &calcLt, runIds, func(c interface{}) (bool, error) {
+			goLst = append(goLst, c.(CellMicroCalc))
+			return true, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Log("Row count:", len(sqlLst), len(goLst))
+
+		if len(sqlLst) != len(goLst) {
+			t.Fatal("****FAIL: row count sql and Go:", len(sqlLst), len(goLst))
+		}
+		for j := range sqlLst {
+
+			sc := sqlLst[j]
+			gc := goLst[j]
+			isOk := sc.RunId == gc.RunId && sc.CalcId == gc.CalcId && len(sc.Attr) == len(gc.Attr)
+
+			for n := 0; isOk && n < len(sc.Attr); n++ {
+
+				sv := evalValue{isNull: true}
+				if !sc.Attr[n].IsNull {
+					sv = toEvalValue(sc.Attr[n].Value)
+				}
+				gv := evalValue{isNull: true}
+				if !gc.Attr[n].IsNull {
+					gv = toEvalValue(gc.Attr[n].Value)
+				}
+				isOk = sv.isNull == gv.isNull && (sv.isNull || math.Abs(sv.val-gv.val) <= 1.0e-6*math.Max(1.0, math.Abs(sv.val)))
+			}
+			if !isOk {
+				t.Error("****FAIL at row:", j, "sql:", sc, "Go:", gc)
+			}
+		}
+	}
+}
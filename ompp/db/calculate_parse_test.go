@@ -4,12 +4,15 @@
 package db
 
 import (
+	"database/sql"
 	"errors"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 
 	"github.com/openmpp/go/ompp/config"
 	"github.com/openmpp/go/ompp/helper"
@@ -158,6 +161,448 @@ func TestTranslateAllSimpleFnc(t *testing.T) {
 	}
 }
 
+func TestTranslateAggregationFnc(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.calculate-parse.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// in-memory test data to check sql results: acc0 = 1, 2, 3, 4 and acc1 = 4, 3, 2, 1
+	memDb, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer memDb.Close()
+	memDb.SetMaxOpenConns(1)
+
+	if _, err = memDb.Exec(
+		"CREATE TABLE acc_test (acc0 FLOAT, acc1 FLOAT);" +
+			" INSERT INTO acc_test (acc0, acc1) VALUES (1, 4), (2, 3), (3, 2), (4, 1), (NULL, NULL)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// facet name suffix of expected sql key, e.g.: ValidMySql_1, if there is no such key then expected sql is Valid_1
+	facetLst := []Facet{SqliteFacet, PostgreSqlFacet, MySqlFacet, MsSqlFacet, OracleOdbcFacet, Db2OdbcFacet}
+	facetKey := []string{"Sqlite", "", "MySql", "MsSql", "Oracle", "Db2"}
+
+	for k := 0; k < 400; k++ {
+
+		src := opts.String("TranslateAggregationFnc.Src_" + strconv.Itoa(k+1))
+		if src == "" {
+			continue
+		}
+		t.Log(src)
+
+		isErr := opts.Bool("TranslateAggregationFnc.Error_" + strconv.Itoa(k+1))
+		valid := opts.String("TranslateAggregationFnc.Valid_" + strconv.Itoa(k+1))
+		value := opts.Float("TranslateAggregationFnc.Value_"+strconv.Itoa(k+1), 0.0)
+
+		// translate non-aggregation functions and find aggregation function name and argument
+		expr, e := translateAllSimpleFnc(src)
+		if e != nil {
+			t.Fatal(e)
+		}
+		fncName, _, arg, _, e := findFirstFnc(expr, aggrFncLst)
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		for j, f := range facetLst {
+
+			lps := &levelParseState{
+				levelDef: &levelDef{level: 1, fromAlias: "M1", innerAlias: "T1", nextInnerAlias: "T2"},
+				dbFacet:  f,
+			}
+
+			r, e := lps.translateAggregationFnc(fncName, arg, src)
+			if isErr {
+				if e == nil {
+					t.Error("****FAIL: expected an error:", f.String(), r)
+				} else {
+					t.Log("OK:", e)
+				}
+				break // same error for all facets
+			}
+			if e != nil {
+				t.Fatal(f.String(), ":", e)
+			}
+
+			fValid := valid
+			if facetKey[j] != "" && opts.IsExist("TranslateAggregationFnc.Valid"+facetKey[j]+"_"+strconv.Itoa(k+1)) {
+				fValid = opts.String("TranslateAggregationFnc.Valid" + facetKey[j] + "_" + strconv.Itoa(k+1))
+			}
+			if r != fValid {
+				t.Error("Expected:", f.String(), ":", fValid)
+				t.Error("****FAIL:", r)
+			}
+
+			if f == SqliteFacet { // check sql result value in sqlite
+
+				var v float64
+				if e = memDb.QueryRow("SELECT " + r + " FROM acc_test").Scan(&v); e != nil {
+					t.Fatal(e, ":", r)
+				}
+				if math.Abs(v-value) > 1.0e-9 {
+					t.Error("Expected value:", value, "****FAIL:", v, ":", r)
+				} else {
+					t.Log("=>", v)
+				}
+			}
+		}
+	}
+}
+
+// MySQL percentile is calculated from window function subquery at the FROM level of aggregation,
+// run MySQL sql in SQLite and compare results with SQLite percentile sql results.
+func TestMySqlPercentileLevelSql(t *testing.T) {
+
+	// SQLite driver with FLOOR() and CEIL() functions, it is not compiled with math functions by default
+	const drvName = "sqlite3_percentile_test"
+
+	if !slices.Contains(sql.Drivers(), drvName) {
+		sql.Register(drvName, &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				if err := conn.RegisterFunc("floor", math.Floor, true); err != nil {
+					return err
+				}
+				return conn.RegisterFunc("ceil", math.Ceil, true)
+			},
+		})
+	}
+	memDb, err := sql.Open(drvName, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer memDb.Close()
+	memDb.SetMaxOpenConns(1)
+
+	// two runs, dimension items 0 and 1, 4 sub-values with NULL value in each cell
+	// acc0 = 1, 2, 3, 4, NULL + 10 * dim0 + 100 * run_id and acc1 = 4, 3, 2, 1, NULL
+	q := "CREATE TABLE asrc (run_id INT, acc_id INT, sub_id INT, dim0 INT, acc_value FLOAT);"
+	for runId := 1; runId <= 2; runId++ {
+		for d := 0; d < 2; d++ {
+			for sub := 0; sub < 5; sub++ {
+
+				sr := strconv.Itoa(runId) + ", "
+				sd := ", " + strconv.Itoa(d) + ", "
+				v0 := "NULL"
+				v1 := "NULL"
+				if sub < 4 {
+					v0 = strconv.Itoa(sub + 1 + 10*d + 100*runId)
+					v1 = strconv.Itoa(4 - sub)
+				}
+				q += " INSERT INTO asrc VALUES (" + sr + "0, " + strconv.Itoa(sub) + sd + v0 + ");" +
+					" INSERT INTO asrc VALUES (" + sr + "1, " + strconv.Itoa(sub) + sd + v1 + ");"
+			}
+		}
+	}
+	if _, err = memDb.Exec(q); err != nil {
+		t.Fatal(err)
+	}
+
+	table := &TableMeta{Dim: []TableDimsRow{{Name: "dim0", colName: "dim0"}}}
+	aggrCols := []aggrColumn{{name: "acc0", colName: "acc0", isAggr: true}, {name: "acc1", colName: "acc1", isAggr: true}}
+
+	makeAccColName := func(name string, nameIdx int, isSimple, isVar bool, firstAlias string, levelAccAlias string, isFirstAcc bool) string {
+		if isFirstAcc {
+			return firstAlias + ".acc_value"
+		}
+		return levelAccAlias + "." + name
+	}
+	makeParamColName := func(colKey string, isSimple, isVar bool, alias string) (string, string, error) {
+		return "", "", errors.New("Error: parameter not found: " + colKey)
+	}
+
+	srcLst := []string{
+		"OM_MEDIAN(acc0)",
+		"OM_PERCENTILE(acc0 + acc1, 0.25)",
+		"OM_AVG(acc1) + OM_MEDIAN(acc0) - OM_PERCENTILE(acc1, 0.9)",
+		"OM_MEDIAN(acc0 - OM_AVG(acc0))",
+		"OM_SUM(acc1 - OM_MEDIAN(acc0))",
+		"OM_IF(OM_COUNT(acc0) > 3 THEN OM_PERCENTILE(acc1, 0.1) ELSE 0)",
+	}
+
+	// run sql and return values by run id and dimension item
+	selectValues := func(src string, facet Facet) map[string]float64 {
+
+		expr, e := translateAllSimpleFnc(src)
+		if e != nil {
+			t.Fatal(e)
+		}
+		levelArr, e := parseAggrCalculation(facet, aggrCols, map[string]paramColumn{}, expr, makeAccColName, makeParamColName)
+		if e != nil {
+			t.Fatal(e)
+		}
+		mainSql, e := makeAggrLevelSql(table, 0, levelArr, "asrc", []int{0, 1}, []string{"acc0", "acc1"})
+		if e != nil {
+			t.Fatal(e)
+		}
+		t.Log(facet.String(), ":", mainSql)
+
+		vals := map[string]float64{}
+		rows, e := memDb.Query(mainSql)
+		if e != nil {
+			t.Fatal(e, ":", mainSql)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var runId, calcId, d int
+			var v float64
+			if e = rows.Scan(&runId, &calcId, &d, &v); e != nil {
+				t.Fatal(e)
+			}
+			vals[strconv.Itoa(runId)+":"+strconv.Itoa(d)] = v
+		}
+		if e = rows.Err(); e != nil {
+			t.Fatal(e)
+		}
+		return vals
+	}
+
+	for _, src := range srcLst {
+
+		t.Log(src)
+
+		expected := selectValues(src, SqliteFacet)
+		vals := selectValues(src, MySqlFacet)
+
+		if len(expected) != 4 || len(vals) != len(expected) {
+			t.Fatal("****FAIL: expected 4 rows, found:", len(vals), len(expected))
+		}
+		for key, ev := range expected {
+			if v, ok := vals[key]; !ok || math.Abs(v-ev) > 1.0e-9 {
+				t.Error("****FAIL:", src, key, "expected:", ev, "found:", v)
+			}
+		}
+	}
+}
+
+func TestTranslateParamCompareExpr(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.calculate-parse.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := opts.String("TranslateParamCompareExpr.ParamName")
+
+	for k := 0; k < 400; k++ {
+
+		src := opts.String("TranslateParamCompareExpr.Src_" + strconv.Itoa(k+1))
+		if src == "" {
+			continue
+		}
+		t.Log(src)
+
+		isErr := opts.Bool("TranslateParamCompareExpr.Error_" + strconv.Itoa(k+1))
+		valid := opts.String("TranslateParamCompareExpr.Valid_" + strconv.Itoa(k+1))
+
+		r, e := translateParamCompareExpr(name, src)
+		if isErr {
+			if e == nil {
+				t.Error("****FAIL: expected an error:", r)
+			} else {
+				t.Log("OK:", e)
+			}
+			continue
+		}
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if r != valid {
+			t.Error("Expected:", valid)
+			t.Error("****FAIL:", r)
+		} else {
+			t.Log("=>", r)
+		}
+	}
+}
+
+func TestTranslateCrossTableExpr(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.calculate-parse.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modelDef := makeParseTestModel()
+	table := &modelDef.Table[0]
+
+	for k := 0; k < 400; k++ {
+
+		src := opts.String("TranslateCrossTableExpr.Src_" + strconv.Itoa(k+1))
+		if src == "" {
+			continue
+		}
+		t.Log(src)
+
+		isErr := opts.Bool("TranslateCrossTableExpr.Error_" + strconv.Itoa(k+1))
+		valid := opts.String("TranslateCrossTableExpr.Valid_" + strconv.Itoa(k+1))
+
+		cteSql, mainSql, _, e := translateExprCalcToSql(modelDef, table, map[string]paramColumn{}, 12000, src)
+		if isErr {
+			if e == nil {
+				t.Error("****FAIL: expected an error:", mainSql)
+			} else {
+				t.Log("OK:", e)
+			}
+			continue
+		}
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		r := strings.Join(cteSql, ", ") + " " + mainSql
+		if r != valid {
+			t.Error("Expected:", valid)
+			t.Error("****FAIL:", r)
+		} else {
+			t.Log("=>", r)
+		}
+	}
+}
+
+func TestValidateCalculate(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.calculate-parse.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modelDef := makeParseTestModel()
+	tableName := opts.String("ValidateCalculate.TableName")
+
+	for k := 0; k < 400; k++ {
+
+		src := opts.String("ValidateCalculate.Src_" + strconv.Itoa(k+1))
+		if src == "" {
+			continue
+		}
+		t.Log(src)
+
+		mode := opts.String("ValidateCalculate.Mode_" + strconv.Itoa(k+1))
+		resType := opts.String("ValidateCalculate.Type_" + strconv.Itoa(k+1))
+		isErr := opts.IsExist("ValidateCalculate.ErrorPos_" + strconv.Itoa(k+1))
+		errPos := opts.Int("ValidateCalculate.ErrorPos_"+strconv.Itoa(k+1), -1)
+		errName := opts.String("ValidateCalculate.ErrorName_" + strconv.Itoa(k+1))
+
+		cv, e := ValidateCalculate(modelDef, &ValidateCalcLayout{
+			Name:      tableName,
+			Calculate: src,
+			IsAggr:    mode == "aggr",
+			IsRunAggr: mode == "run-aggr",
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if isErr {
+			if cv.IsValid || len(cv.Error) <= 0 {
+				t.Error("****FAIL: expected an error:", src)
+				continue
+			}
+			if cv.Error[0].Pos != errPos || cv.Error[0].Name != errName {
+				t.Error("Expected error at:", errPos, errName)
+				t.Error("****FAIL:", cv.Error[0].Pos, cv.Error[0].Name, cv.Error[0].Msg)
+			} else {
+				t.Log("OK:", cv.Error[0].Pos, cv.Error[0].Len, cv.Error[0].Msg)
+			}
+			continue
+		}
+		if !cv.IsValid {
+			t.Error("****FAIL:", cv.Error)
+			continue
+		}
+		if cv.ResultType != resType {
+			t.Error("Expected:", resType)
+			t.Error("****FAIL:", cv.ResultType)
+		} else {
+			t.Log("=>", cv.ResultType)
+		}
+	}
+}
+
+func TestMicroBin(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.calculate-parse.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k := 0; k < 400; k++ {
+
+		src := opts.String("MicroBin.Src_" + strconv.Itoa(k+1))
+		if src == "" {
+			continue
+		}
+		t.Log(src)
+
+		isErr := opts.Bool("MicroBin.Error_" + strconv.Itoa(k+1))
+		sqlValid := opts.String("MicroBin.Sql_" + strconv.Itoa(k+1))
+		labels := opts.String("MicroBin.Labels_" + strconv.Itoa(k+1))
+
+		bins, e := ParseMicroBinList(src)
+		if isErr {
+			if e == nil {
+				t.Error("****FAIL: expected an error:", bins)
+			} else {
+				t.Log("OK:", e)
+			}
+			continue
+		}
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(bins) <= 0 {
+			t.Fatal("****FAIL: bins not found:", src)
+		}
+
+		// quantile bins: break points are calculated from microdata, sql cannot be created without it
+		if sqlValid != "" {
+
+			q, e := makeMicroBinSql(&bins[0], "C.attr4")
+			if e != nil {
+				t.Fatal(e)
+			}
+			if q != sqlValid {
+				t.Error("Expected:", sqlValid)
+				t.Error("****FAIL:", q)
+			} else {
+				t.Log("=>", q)
+			}
+		}
+
+		// bin index to label
+		if labels != "" {
+
+			cvt, e := makeMicroBinLabel(&bins[0], bins[0].Name)
+			if e != nil {
+				t.Fatal(e)
+			}
+			for j, lbl := range strings.Split(labels, "|") {
+
+				s, e := cvt(j)
+				if e != nil {
+					t.Fatal(e)
+				}
+				if s != lbl {
+					t.Error("****FAIL: expected:", lbl, "at index:", j, "but got:", s)
+				}
+			}
+			if _, e = cvt(len(strings.Split(labels, "|"))); e == nil {
+				t.Error("****FAIL: expected an error for bin index out of range:", src)
+			}
+		}
+	}
+}
+
 func TestTranslateToExprSql(t *testing.T) {
 
 	// load ini-file and parse test run options
@@ -436,7 +881,7 @@ func TestParseAggrCalculation(t *testing.T) {
 				return sqlName, innerJoin, nil
 			}
 
-			r, e = parseAggrCalculation(accAggrCols, paramCols, v.src, makeAccColName, makeParamColName)
+			r, e = parseAggrCalculation(SqliteFacet, accAggrCols, paramCols, v.src, makeAccColName, makeParamColName)
 			if e != nil {
 				t.Fatal(e)
 			}
@@ -537,7 +982,7 @@ func TestParseAggrCalculation(t *testing.T) {
 			}
 
 			// parse aggregation expression
-			r, e = parseAggrCalculation(attrAggrCols, paramCols, v.src, makeAttrColName, makeParamColName)
+			r, e = parseAggrCalculation(SqliteFacet, attrAggrCols, paramCols, v.src, makeAttrColName, makeParamColName)
 			if e != nil {
 				t.Fatal(e)
 			}
@@ -586,3 +1031,173 @@ func TestParseAggrCalculation(t *testing.T) {
 		}
 	}
 }
+
+// make test model metadata: double type and output tables income(age, sex), pop(sex), total() and region(region),
+// each table has two expressions Expr0, Expr1 and two accumulators acc0, acc1
+func makeParseTestModel() *ModelMeta {
+
+	mkTable := func(hId int, name string, dims ...TableDimsRow) TableMeta {
+		tm := TableMeta{
+			TableDicRow: TableDicRow{TableHid: hId, Name: name, Rank: len(dims), DbExprTable: name + "_v_" + strconv.Itoa(hId), DbAccTable: name + "_a_" + strconv.Itoa(hId)},
+			Dim:         dims,
+			Acc: []TableAccRow{
+				{AccId: 0, Name: "acc0", colName: "acc0"},
+				{AccId: 1, Name: "acc1", colName: "acc1"},
+			},
+			Expr: []TableExprRow{
+				{ExprId: 0, Name: "Expr0", colName: "expr0"},
+				{ExprId: 1, Name: "Expr1", colName: "expr1"},
+			},
+		}
+		for k := range tm.Dim {
+			tm.Dim[k].DimId = k
+			tm.Dim[k].colName = "dim" + strconv.Itoa(k)
+		}
+		return tm
+	}
+	return &ModelMeta{
+		Type: []TypeMeta{
+			{TypeDicRow: TypeDicRow{TypeId: 14, Name: "double", Digest: "_double_"}},
+		},
+		Table: []TableMeta{
+			mkTable(101, "income", TableDimsRow{Name: "age", TypeId: 1}, TableDimsRow{Name: "sex", TypeId: 2}),
+			mkTable(102, "pop", TableDimsRow{Name: "sex", TypeId: 2}),
+			mkTable(103, "total"),
+			mkTable(104, "region", TableDimsRow{Name: "region", TypeId: 3}),
+		},
+	}
+}
+
+func TestEvalCalcExpr(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.calculate-parse.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// source rows: acc0, acc1 and parameter value: param.Extra
+	nv := evalValue{isNull: true}
+	rows := [][]evalValue{
+		{{val: 1}, {val: 2}, {val: 10}},
+		{{val: 2}, nv, {val: 10}},
+		{{val: 3}, {val: 6}, {val: 10}},
+		{{val: 4}, {val: 8}, {val: 10}},
+	}
+	nameIdx := func(name, _ string) (int, error) {
+		switch name {
+		case "acc0":
+			return 0, nil
+		case "acc1":
+			return 1, nil
+		}
+		return -1, errors.New("accumulator not found: " + name)
+	}
+
+	for k := 0; k < 400; k++ {
+
+		src := opts.String("EvalCalcExpr.Src_" + strconv.Itoa(k+1))
+		if src == "" {
+			continue
+		}
+		t.Log(src)
+
+		isAggr := opts.String("EvalCalcExpr.Mode_"+strconv.Itoa(k+1)) == "aggr"
+		nRow := opts.Int("EvalCalcExpr.Row_"+strconv.Itoa(k+1), 0)
+		sVal := opts.String("EvalCalcExpr.Value_" + strconv.Itoa(k+1))
+		isErr := opts.Bool("EvalCalcExpr.Error_" + strconv.Itoa(k+1))
+
+		ex, e := parseEvalExpr(src)
+		if e == nil {
+			e = ex.bindNames(isAggr, 2, nameIdx)
+		}
+		if isErr {
+			if e == nil {
+				t.Error("****FAIL: expected an error:", src)
+			} else {
+				t.Log("OK:", e)
+			}
+			continue
+		}
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		var v evalValue
+		if isAggr {
+			v = ex.evalGroup(rows)
+		} else {
+			v = ex.evalRow(rows[nRow])
+		}
+
+		if sVal == "NULL" {
+			if !v.isNull {
+				t.Error("****FAIL: expected NULL but got:", v.val)
+			}
+			continue
+		}
+		f, e := strconv.ParseFloat(sVal, 64)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if v.isNull || math.Abs(v.val-f) > 1.0e-9 {
+			t.Error("Expected:", f)
+			t.Error("****FAIL:", v.isNull, v.val)
+		} else {
+			t.Log("=>", v.val)
+		}
+	}
+}
+
+func TestTranslateParamFormulaExpr(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.calculate-parse.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := opts.String("TranslateParamFormulaExpr.ParamName")
+	refName := opts.String("TranslateParamFormulaExpr.RefName")
+
+	nameToSql := func(src string) (string, bool, error) {
+		switch src {
+		case name:
+			return "T.param_value", true, nil
+		case refName:
+			return "(R)", true, nil
+		}
+		return "", false, nil
+	}
+
+	for k := 0; k < 400; k++ {
+
+		src := opts.String("TranslateParamFormulaExpr.Src_" + strconv.Itoa(k+1))
+		if src == "" {
+			continue
+		}
+		t.Log(src)
+
+		isErr := opts.Bool("TranslateParamFormulaExpr.Error_" + strconv.Itoa(k+1))
+		valid := opts.String("TranslateParamFormulaExpr.Valid_" + strconv.Itoa(k+1))
+
+		r, e := translateParamFormulaExpr(src, nameToSql)
+		if isErr {
+			if e == nil {
+				t.Error("****FAIL: expected an error:", r)
+			} else {
+				t.Log("OK:", e)
+			}
+			continue
+		}
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if r != valid {
+			t.Error("Expected:", valid)
+			t.Error("****FAIL:", r)
+		} else {
+			t.Log("=>", r)
+		}
+	}
+}
@@ -4,6 +4,7 @@
 package db
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -88,7 +89,7 @@ func TestTransalteAccAggrToSql(t *testing.T) {
 
 		paramCols := makeParamCols(modelDef.Param)
 
-		cteSql, mainSql, e := transalteAccAggrToSql(table, paramCols, 0, v.src)
+		cteSql, mainSql, e := transalteAccAggrToSql(srcDb.Dbf, table, paramCols, 0, v.src)
 		if e != nil {
 			t.Fatal(e)
 		}
@@ -108,6 +109,98 @@ func TestTransalteAccAggrToSql(t *testing.T) {
 	}
 }
 
+func TestTranslateRunAggrToSql(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.calculate.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modelName := opts.String("TranslateRunAggrToSql.ModelName")
+	modelDigest := opts.String("TranslateRunAggrToSql.ModelDigest")
+	modelSqliteDbPath := opts.String("TranslateRunAggrToSql.DbPath")
+	tableName := opts.String("TranslateRunAggrToSql.TableName")
+
+	// open source database connection and check is it valid
+	cs := MakeSqliteDefaultReadOnly(modelSqliteDbPath)
+	t.Log(cs)
+
+	srcDb, err := Open(cs, SQLiteDbDriver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcDb.Close()
+
+	if err := CheckOpenmppSchemaVersion(srcDb.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	// get model metadata
+	modelDef, err := GetModel(srcDb.DB, modelName, modelDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modelDef == nil {
+		t.Errorf("model not found: %s :%s:", modelName, modelDigest)
+	}
+	t.Log("Model:", modelDef.Model.Name, " ", modelDef.Model.Digest)
+
+	// find output table id by name
+	var table *TableMeta
+	if k, ok := modelDef.OutTableByName(tableName); ok {
+		table = &modelDef.Table[k]
+	} else {
+		t.Errorf("output table not found: %s", tableName)
+	}
+
+	type testItem struct {
+		src    string
+		runIds []int
+		valid  string
+	}
+	validLst := []testItem{}
+
+	for k := 0; k < 400; k++ {
+		s := opts.String("TranslateRunAggrToSql.Src_" + strconv.Itoa(k+1))
+		if s == "" {
+			continue
+		}
+		rIds := []int{}
+		for _, sId := range helper.ParseCsvLine(opts.String("TranslateRunAggrToSql.RunIds_"+strconv.Itoa(k+1)), ',') {
+			if id, e := strconv.Atoi(sId); e == nil {
+				rIds = append(rIds, id)
+			}
+		}
+		validLst = append(validLst, testItem{
+			src:    s,
+			runIds: rIds,
+			valid:  opts.String("TranslateRunAggrToSql.Valid_" + strconv.Itoa(k+1)),
+		})
+	}
+
+	t.Log("Check run aggregation SQL")
+	for _, v := range validLst {
+
+		t.Log(v.src)
+
+		paramCols := makeParamCols(modelDef.Param)
+
+		cteSql, mainSql, e := translateRunAggrToSql(srcDb.Dbf, table, paramCols, CALCULATED_ID_OFFSET, v.src, v.runIds)
+		if e != nil {
+			t.Fatal(e)
+		}
+		sql := "WITH " + cteSql + " " + mainSql
+
+		if sql != v.valid {
+			t.Error("Expected:", v.valid)
+			t.Error("****FAIL:", sql)
+		} else {
+			t.Log("=>", sql)
+		}
+	}
+}
+
 func TestTranslateTableCalcToSql(t *testing.T) {
 
 	// load ini-file and parse test run options
@@ -227,7 +320,7 @@ func TestTranslateTableCalcToSql(t *testing.T) {
 			tableLt.FromId = baseRunId
 		}
 
-		sql, e := translateTableCalcToSql(modelDef, table, &tableLt.ReadLayout, calcLt, runIds)
+		sql, e := translateTableCalcToSql(srcDb.Dbf, modelDef, table, &tableLt.ReadLayout, calcLt, runIds)
 		if e != nil {
 			t.Fatal(e)
 		}
@@ -387,3 +480,144 @@ func TestCalculateOutputTable(t *testing.T) {
 		// valid := opts.String("CalculateOutputTable.Valid_"+strconv.Itoa(k+1)]
 	}
 }
+
+func TestEvalOutputTable(t *testing.T) {
+
+	// load ini-file and parse test run options
+	opts, err := config.FromIni("testdata/test.ompp.db.calculate.ini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modelName := opts.String("EvalOutputTable.ModelName")
+	modelDigest := opts.String("EvalOutputTable.ModelDigest")
+	modelSqliteDbPath := opts.String("EvalOutputTable.DbPath")
+	tableName := opts.String("EvalOutputTable.TableName")
+
+	// open source database connection and check is it valid
+	cs := MakeSqliteDefaultReadOnly(modelSqliteDbPath)
+	t.Log(cs)
+
+	srcDb, err := Open(cs, SQLiteDbDriver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcDb.Close()
+
+	if err := CheckOpenmppSchemaVersion(srcDb.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	// get model metadata
+	modelDef, err := GetModel(srcDb.DB, modelName, modelDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modelDef == nil {
+		t.Fatalf("model not found: %s :%s:", modelName, modelDigest)
+	}
+	t.Log("Model:", modelDef.Model.Name, " ", modelDef.Model.Digest)
+
+	for k := 0; k < 400; k++ {
+
+		calcLt := []CalculateTableLayout{}
+
+		appendToCalc := func(src string, isAggr, isRunAggr bool, idOffset int) {
+
+			ce := helper.ParseCsvLine(src, ',')
+			for j := range ce {
+
+				c := strings.TrimSpace(ce[j])
+				if c != "" && c[0] == '"' && c[len(c)-1] == '"' {
+					c = c[1 : len(c)-1]
+				}
+
+				if c != "" {
+
+					calcLt = append(calcLt, CalculateTableLayout{
+						CalculateLayout: CalculateLayout{
+							Calculate: c,
+							CalcId:    idOffset + j,
+						},
+						IsAggr:    isAggr,
+						IsRunAggr: isRunAggr,
+					})
+					t.Log(calcLt[len(calcLt)-1].CalcId, "Calculate:", c)
+				}
+			}
+		}
+
+		if cLst := opts.String("EvalOutputTable.Calculate_" + strconv.Itoa(k+1)); cLst != "" {
+			appendToCalc(cLst, false, false, CALCULATED_ID_OFFSET)
+		}
+		if cLst := opts.String("EvalOutputTable.CalculateAggr_" + strconv.Itoa(k+1)); cLst != "" {
+			appendToCalc(cLst, true, false, 2*CALCULATED_ID_OFFSET)
+		}
+		if cLst := opts.String("EvalOutputTable.CalculateRunAggr_" + strconv.Itoa(k+1)); cLst != "" {
+			appendToCalc(cLst, false, true, 3*CALCULATED_ID_OFFSET)
+		}
+		if len(calcLt) <= 0 {
+			continue
+		}
+
+		runIds := []int{}
+		if sVal := opts.String("EvalOutputTable.RunIds_" + strconv.Itoa(k+1)); sVal != "" {
+
+			sArr := helper.ParseCsvLine(sVal, ',')
+			for j := range sArr {
+				if id, err := strconv.Atoi(sArr[j]); err != nil {
+					t.Fatal(err)
+				} else {
+					runIds = append(runIds, id)
+				}
+			}
+		}
+		if len(runIds) <= 0 {
+			t.Fatal("ERROR: empty run list at EvalOutputTable.RunIds", k+1)
+		}
+		t.Log("run id's:", runIds)
+
+		// calculate by sql and in Go, results must be the same
+		sqlLst := []CellTableCalc{}
+		goLst := []CellTableCalc{}
+
+		tableLt := ReadTableLayout{ReadLayout: ReadLayout{Name: tableName, FromId: runIds[0]}}
+
+		_, err = ReadOutputTableCalculteTo(srcDb.DB, modelDef, &tableLt, calcLt, runIds[1:], func(c interface{}) (bool, error) {
+			sqlLst = append(sqlLst, c.(CellTableCalc))
+			return true, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = EvalOutputTableCalculteTo(srcDb.DB, modelDef, &tableLt, calcLt, runIds[1:], func(c interface{}) (bool, error) {
+			goLst = append(goLst, c.(CellTableCalc))
+			return true, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Log("Row count:", len(sqlLst), len(goLst))
+
+		if len(sqlLst) != len(goLst) {
+			t.Fatal("****FAIL: row count sql and Go:", len(sqlLst), len(goLst))
+		}
+		for j := range sqlLst {
+
+			sc := sqlLst[j]
+			gc := goLst[j]
+			isOk := sc.RunId == gc.RunId && sc.CalcId == gc.CalcId && sc.IsNull == gc.IsNull && len(sc.DimIds) == len(gc.DimIds)
+			for n := 0; isOk && n < len(sc.DimIds); n++ {
+				isOk = sc.DimIds[n] == gc.DimIds[n]
+			}
+			if isOk && !sc.IsNull {
+				sv, _ := sc.Value.(float64)
+				gv, _ := gc.Value.(float64)
+				isOk = math.Abs(sv-gv) <= 1.0e-6*math.Max(1.0, math.Abs(sv))
+			}
+			if !isOk {
+				t.Error("****FAIL at row:", j, "sql:", sc, "Go:", gc)
+			}
+		}
+	}
+}
@@ -31,10 +31,11 @@ type CellCodeMicroCalc struct {
 
 // CellMicroCalcConverter is a converter for calculated microdata row to implement CsvConverter interface.
 type CellMicroCalcConverter struct {
-	CellEntityConverter                 // model metadata, entity generation and and attributes
-	CalcMaps                            // map between runs digest and id and calculations name and id
-	GroupBy             []string        // attributes to group by
-	theGroupBy          []EntityAttrRow // if not empty then entity generation attributes
+	CellEntityConverter                  // model metadata, entity generation and and attributes
+	CalcMaps                             // map between runs digest and id and calculations name and id
+	GroupBy             []string         // attributes to group by
+	Bins                []MicroBinLayout // binning of numeric group by attributes
+	theGroupBy          []EntityAttrRow  // if not empty then entity generation attributes
 }
 
 // Converter for calculated microdata to implement CsvLocaleConverter interface.
@@ -214,6 +215,7 @@ func (cellCvt *CellMicroCalcConverter) ToCsvIdRow() (func(interface{}, []string)
 // Converter simply does Sprint() for key and each attribute value.
 // If attribute type is float and double format is not empty "" string then converter does Sprintf(using double format).
 // If attribute type is enum based then converter return enum code for attribute enum id.
+// If attribute is binned then converter return bin label for bin index, ex.: [18, 65).
 // Converter will return error if len(row) not equal to number of fields in csv record.
 // If value is NULL then "null" string used.
 func (cellCvt *CellMicroCalcConverter) ToCsvRow() (func(interface{}, []string) (bool, error), error) {
@@ -233,7 +235,15 @@ func (cellCvt *CellMicroCalcConverter) ToCsvRow() (func(interface{}, []string) (
 
 	for k, ga := range aGroupBy {
 
-		if ga.typeOf.IsBuiltIn() { // built-in attribute type: format value by Sprint()
+		if n, isBin := findMicroBin(cellCvt.Bins, ga.Name); isBin { // binned attribute: return bin label by bin index
+
+			f, err := makeMicroBinLabel(&cellCvt.Bins[n], cellCvt.Name+"."+ga.Name)
+			if err != nil {
+				return nil, err
+			}
+			fa[k] = f
+
+		} else if ga.typeOf.IsBuiltIn() { // built-in attribute type: format value by Sprint()
 
 			fa[k] = func(v interface{}) (string, error) { return fmt.Sprint(v), nil }
 
@@ -336,7 +346,15 @@ func (cellCvt *CellMicroCalcLocaleConverter) ToCsvRow() (func(interface{}, []str
 
 	for k, ga := range aGroupBy {
 
-		if ga.typeOf.IsBuiltIn() { // built-in attribute type: format value by language-sapcific Sprint()
+		if n, isBin := findMicroBin(cellCvt.Bins, ga.Name); isBin { // binned attribute: return bin label by bin index
+
+			f, err := makeMicroBinLabel(&cellCvt.Bins[n], cellCvt.Name+"."+ga.Name)
+			if err != nil {
+				return nil, err
+			}
+			fa[k] = f
+
+		} else if ga.typeOf.IsBuiltIn() { // built-in attribute type: format value by language-sapcific Sprint()
 
 			fa[k] = func(v interface{}) (string, error) { return prt.Sprint(v), nil }
 
@@ -431,7 +449,15 @@ func (cellCvt *CellMicroCalcConverter) IdToCodeCell(modelDef *ModelMeta, _ strin
 
 	for k, ga := range aGroupBy {
 
-		if ga.typeOf.IsBuiltIn() {
+		if n, isBin := findMicroBin(cellCvt.Bins, ga.Name); isBin { // binned attribute: return bin label by bin index
+
+			f, err := makeMicroBinLabel(&cellCvt.Bins[n], cellCvt.Name+"."+ga.Name)
+			if err != nil {
+				return nil, err
+			}
+			fa[k] = f
+
+		} else if ga.typeOf.IsBuiltIn() {
 
 			fa[k] = nil // built-in attribute type: do not convert, do copy value
 
@@ -548,6 +574,9 @@ func (cellCvt *CellMicroCalcConverter) groupByAttrs() ([]EntityAttrRow, error) {
 	}
 
 	for k := range aGroupBy {
+		if _, isBin := findMicroBin(cellCvt.Bins, aGroupBy[k].Name); isBin {
+			continue // numeric attribute binned into ranges
+		}
 		if aGroupBy[k].typeOf.IsBuiltIn() && !aGroupBy[k].typeOf.IsBool() {
 			return []EntityAttrRow{}, errors.New("invalid type of entity group by attribute not found by: " + ent.Name + "." + aGroupBy[k].Name + " : " + aGroupBy[k].typeOf.Name)
 		}
@@ -413,6 +413,25 @@ func (cellCvt *CellParamLocaleConverter) ToCsvRow() (func(interface{}, []string)
 // If parameter type is enum based then csv row value is enum code and it is converted into value enum id.
 func (cellCvt *CellParamConverter) ToCell() (func(row []string) (interface{}, error), error) {
 
+	toCell, err := cellCvt.ToCellByColumn()
+	if err != nil {
+		return nil, err
+	}
+
+	cvt := func(row []string) (interface{}, error) {
+		c, _, err := toCell(row)
+		return c, err
+	}
+	return cvt, nil
+}
+
+// Return closure to convert csv row []string to parameter cell (sub id, dimensions, value)
+// and in case of error also return zero-based index of csv column where conversion failed.
+//
+// Column index is -1 if error is not related to any particular column, e.g. invalid size of csv row.
+// It is the same conversion as ToCell() and can be used to report position of invalid value, e.g. in a spreadsheet.
+func (cellCvt *CellParamConverter) ToCellByColumn() (func(row []string) (interface{}, int, error), error) {
+
 	// find parameter by name
 	param, err := cellCvt.paramByName()
 	if err != nil {
@@ -472,20 +491,20 @@ func (cellCvt *CellParamConverter) ToCell() (func(row []string) (interface{}, er
 	}
 
 	// do conversion
-	cvt := func(row []string) (interface{}, error) {
+	cvt := func(row []string) (interface{}, int, error) {
 
 		// make conversion buffer and check input csv row size
 		cell := CellParam{cellIdValue: cellIdValue{DimIds: make([]int, param.Rank)}}
 
 		n := len(cell.DimIds)
 		if len(row) != n+2 {
-			return nil, errors.New("invalid size of csv row, expected: " + strconv.Itoa(n+2) + ": " + cellCvt.Name)
+			return nil, -1, errors.New("invalid size of csv row, expected: " + strconv.Itoa(n+2) + ": " + cellCvt.Name)
 		}
 
 		// subvalue number
 		nSub, err := strconv.Atoi(row[0])
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		/* validation done at writing
 		if subCount < 1 || subCount == 1 && nSub != defaultSubId {
@@ -498,7 +517,7 @@ func (cellCvt *CellParamConverter) ToCell() (func(row []string) (interface{}, er
 		for k := range cell.DimIds {
 			i, err := fd[k](row[k+1])
 			if err != nil {
-				return nil, err
+				return nil, k + 1, err
 			}
 			cell.DimIds[k] = i
 		}
@@ -517,12 +536,12 @@ func (cellCvt *CellParamConverter) ToCell() (func(row []string) (interface{}, er
 			v, err = fc(row[n+1])
 		}
 		if err != nil {
-			return nil, err
+			return nil, n + 1, err
 		}
 		cell.IsNull = isNull
 		cell.Value = v
 
-		return cell, nil
+		return cell, -1, nil
 	}
 
 	return cvt, nil
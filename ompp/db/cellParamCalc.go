@@ -0,0 +1,409 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// CellParamCalc is value of input parameter comparison between base and variant run or workset.
+type CellParamCalc struct {
+	cellIdValue     // dimensions as enum id's and value
+	SubId       int // parameter subvalue id
+	CalcId      int // calculated expression id
+	SrcId       int // variant model run id or workset id
+}
+
+// CellCodeParamCalc is value of input parameter comparison between base and variant run or workset.
+// Dimension(s) items are enum codes, not enum ids.
+type CellCodeParamCalc struct {
+	cellCodeValue        // dimensions as enum codes and value
+	SubId         int    // parameter subvalue id
+	CalcName      string // calculated expression name
+	Variant       string // variant model run digest or workset name
+}
+
+// CellParamCalcConverter is a converter for input parameter comparison cell to implement CsvConverter interface.
+type CellParamCalcConverter struct {
+	CellParamConverter // model metadata and parameter name
+	CalcMaps           // map between runs digest or workset name and id and calculations name and id
+}
+
+// Converter for input parameter comparison to implement CsvLocaleConverter interface.
+type CellParamCalcLocaleConverter struct {
+	CellParamCalcConverter
+	Lang    string            // language code, expected to compatible with BCP 47 language tag
+	DimsTxt []ParamDimsTxtRow // parameter dimension text rows: parameter_dims_txt join to model_parameter_dic
+	EnumTxt []TypeEnumTxtRow  // type enum text rows: type_enum_txt join to model_type_dic
+}
+
+// Set calculation Id to name maps
+func (cellCvt *CellParamCalcConverter) SetCalcIdNameMap(calcLt []CalculateLayout) error {
+
+	cellCvt.CalcIdToName = map[int]string{}
+
+	for k, c := range calcLt {
+
+		if c.Name == "" {
+			return errors.New("invalid (empty) calculation name at index: [" + strconv.Itoa(k) + "], id: " + strconv.Itoa(c.CalcId) + ": " + cellCvt.Name)
+		}
+		cellCvt.CalcIdToName[c.CalcId] = c.Name
+	}
+	return nil
+}
+
+// return true if csv converter is using enum id's for dimensions
+func (cellCvt *CellParamCalcConverter) IsUseEnumId() bool { return cellCvt.IsIdCsv }
+
+// Return file name of csv file to store parameter comparison rows
+func (cellCvt *CellParamCalcConverter) CsvFileName() (string, error) {
+
+	// find parameter by name
+	_, err := cellCvt.paramByName()
+	if err != nil {
+		return "", err
+	}
+
+	// make csv file name
+	if cellCvt.IsIdCsv {
+		return cellCvt.Name + ".id.compare.csv", nil
+	}
+	return cellCvt.Name + ".compare.csv", nil
+}
+
+// Return first line for csv file: column names, for example: variant,calc_name,sub_id,dim0,dim1,calc_value
+func (cellCvt *CellParamCalcConverter) CsvHeader() ([]string, error) {
+
+	// find parameter by name
+	param, err := cellCvt.paramByName()
+	if err != nil {
+		return []string{}, err
+	}
+
+	// make first line columns
+	h := make([]string, param.Rank+4)
+
+	if cellCvt.IsIdCsv {
+		h[0] = "variant_id"
+		h[1] = "calc_id"
+	} else {
+		h[0] = "variant"
+		h[1] = "calc_name"
+	}
+	h[2] = "sub_id"
+	for k := range param.Dim {
+		h[k+3] = param.Dim[k].Name
+	}
+	h[param.Rank+3] = "calc_value"
+
+	return h, nil
+}
+
+// Return first line for csv file: column names.
+// For example: variant,calc_name,sub_id,Age,Sex,calc_value
+func (cellCvt *CellParamCalcLocaleConverter) CsvHeader() ([]string, error) {
+
+	// default column headers
+	h, err := cellCvt.CellParamCalcConverter.CsvHeader()
+	if err != nil {
+		return []string{}, err
+	}
+
+	// replace dimension name with description, where it exists
+	if cellCvt.Lang != "" {
+
+		dm := map[int]string{} // map id to dimension description
+
+		param, err := cellCvt.paramByName() // find parameter by name
+		if err != nil {
+			return []string{}, err
+		}
+		for j := range cellCvt.DimsTxt {
+			if cellCvt.DimsTxt[j].ModelId == param.ModelId && cellCvt.DimsTxt[j].ParamId == param.ParamId && cellCvt.DimsTxt[j].LangCode == cellCvt.Lang {
+				dm[cellCvt.DimsTxt[j].DimId] = cellCvt.DimsTxt[j].Descr
+			}
+		}
+		for k := range param.Dim {
+			if d, ok := dm[param.Dim[k].DimId]; ok {
+				h[k+3] = d
+			}
+		}
+	}
+	return h, nil
+}
+
+// Return converter from parameter comparison cell (variant id, calc_id, sub_id, dimensions, calc_value) to csv id's row []string.
+//
+// Converter return isNotEmpty flag, it is always true if there were no error during conversion.
+// Converter simply does Sprint() for each dimension item id, variant id and value.
+// Converter will return error if len(row) not equal to number of fields in csv record.
+func (cellCvt *CellParamCalcConverter) ToCsvIdRow() (func(interface{}, []string) (bool, error), error) {
+
+	// find parameter by name
+	_, err := cellCvt.paramByName()
+	if err != nil {
+		return nil, err
+	}
+
+	// return converter from id based cell to csv string array
+	cvt := func(src interface{}, row []string) (bool, error) {
+
+		cell, ok := src.(CellParamCalc)
+		if !ok {
+			return false, errors.New("invalid type, expected: CellParamCalc (internal error): " + cellCvt.Name)
+		}
+
+		n := len(cell.DimIds)
+		if len(row) != n+4 {
+			return false, errors.New("invalid size of csv row buffer, expected: " + strconv.Itoa(n+4) + ": " + cellCvt.Name)
+		}
+
+		row[0] = fmt.Sprint(cell.SrcId)
+		row[1] = fmt.Sprint(cell.CalcId)
+		row[2] = fmt.Sprint(cell.SubId)
+
+		for k, e := range cell.DimIds {
+			row[k+3] = fmt.Sprint(e)
+		}
+
+		// use "null" string for db NULL values and format for model float types
+		if cell.IsNull {
+			row[n+3] = "null"
+		} else {
+			if cellCvt.DoubleFmt != "" {
+				row[n+3] = fmt.Sprintf(cellCvt.DoubleFmt, cell.Value)
+			} else {
+				row[n+3] = fmt.Sprint(cell.Value)
+			}
+		}
+		return true, nil
+	}
+
+	return cvt, nil
+}
+
+// Return converter from parameter comparison cell (variant id, calc_id, sub_id, dimensions, calc_value)
+// to csv row []string (variant, calc_name, sub_id, dimensions, calc_value).
+//
+// Converter return isNotEmpty flag, it is always true if there were no error during conversion.
+// Converter will return error if len(row) not equal to number of fields in csv record.
+// Converter will return error if variant id not exist in the map of model runs and worksets.
+// If dimension type is enum based then csv row is enum code.
+func (cellCvt *CellParamCalcConverter) ToCsvRow() (func(interface{}, []string) (bool, error), error) {
+
+	// find parameter by name
+	param, err := cellCvt.paramByName()
+	if err != nil {
+		return nil, err
+	}
+
+	// for each dimension create converter from item id to code
+	fd := make([]func(itemId int) (string, error), param.Rank)
+
+	for k := 0; k < param.Rank; k++ {
+		f, err := param.Dim[k].typeOf.itemIdToCode(cellCvt.Name+"."+param.Dim[k].Name, false)
+		if err != nil {
+			return nil, err
+		}
+		fd[k] = f
+	}
+
+	cvt := func(src interface{}, row []string) (bool, error) {
+
+		cell, ok := src.(CellParamCalc)
+		if !ok {
+			return false, errors.New("invalid type, expected: parameter comparison cell (internal error): " + cellCvt.Name)
+		}
+
+		n := len(cell.DimIds)
+		if len(row) != n+4 {
+			return false, errors.New("invalid size of csv row buffer, expected: " + strconv.Itoa(n+4) + ": " + cellCvt.Name)
+		}
+
+		row[0] = cellCvt.RunIdToLabel[cell.SrcId]
+		if row[0] == "" {
+			return false, errors.New("invalid (missing) run or workset id: " + strconv.Itoa(cell.SrcId) + " parameter: " + cellCvt.Name)
+		}
+		row[1] = cellCvt.CalcIdToName[cell.CalcId]
+		if row[1] == "" {
+			return false, errors.New("invalid (missing) calculation id: " + strconv.Itoa(cell.CalcId) + " parameter: " + cellCvt.Name)
+		}
+		row[2] = fmt.Sprint(cell.SubId)
+
+		// convert dimension item id to code
+		for k, e := range cell.DimIds {
+			v, err := fd[k](e)
+			if err != nil {
+				return false, err
+			}
+			row[k+3] = v
+		}
+
+		// use "null" string for db NULL values and format for model float types
+		if cell.IsNull {
+			row[n+3] = "null"
+		} else {
+			if cellCvt.DoubleFmt != "" {
+				row[n+3] = fmt.Sprintf(cellCvt.DoubleFmt, cell.Value)
+			} else {
+				row[n+3] = fmt.Sprint(cell.Value)
+			}
+		}
+		return true, nil
+	}
+
+	return cvt, nil
+}
+
+// Return converter from parameter comparison cell (variant id, calc_id, sub_id, dimensions, calc_value)
+// to language-specific csv []string row of dimension enum labels and value.
+//
+// Converter return isNotEmpty flag, it is always true if there were no error during conversion.
+// If dimension type is enum based then csv row is enum label.
+// Value and dimesions of built-in types converted to locale-specific strings, e.g.: 1234.56 => 1 234,56
+// Converter will return error if len(row) not equal to number of fields in csv record.
+func (cellCvt *CellParamCalcLocaleConverter) ToCsvRow() (func(interface{}, []string) (bool, error), error) {
+
+	// find parameter by name
+	param, err := cellCvt.paramByName()
+	if err != nil {
+		return nil, err
+	}
+
+	// for each dimension create converter from item id to label
+	fd := make([]func(itemId int) (string, error), param.Rank)
+
+	for k := 0; k < param.Rank; k++ {
+		f, err := param.Dim[k].typeOf.itemIdToLabel(cellCvt.Lang, cellCvt.EnumTxt, nil, cellCvt.Name+"."+param.Dim[k].Name, false)
+		if err != nil {
+			return nil, err
+		}
+		fd[k] = f
+	}
+
+	// format value locale-specific strings, e.g.: 1234.56 => 1 234,56
+	prt := message.NewPrinter(language.Make(cellCvt.Lang))
+
+	cvt := func(src interface{}, row []string) (bool, error) {
+
+		cell, ok := src.(CellParamCalc)
+		if !ok {
+			return false, errors.New("invalid type, expected: parameter comparison cell (internal error): " + cellCvt.Name)
+		}
+
+		n := len(cell.DimIds)
+		if len(row) != n+4 {
+			return false, errors.New("invalid size of csv row buffer, expected: " + strconv.Itoa(n+4) + ": " + cellCvt.Name)
+		}
+
+		row[0] = cellCvt.RunIdToLabel[cell.SrcId]
+		if row[0] == "" {
+			return false, errors.New("invalid (missing) run or workset id: " + strconv.Itoa(cell.SrcId) + " parameter: " + cellCvt.Name)
+		}
+		row[1] = cellCvt.CalcIdToName[cell.CalcId]
+		if row[1] == "" {
+			return false, errors.New("invalid (missing) calculation id: " + strconv.Itoa(cell.CalcId) + " parameter: " + cellCvt.Name)
+		}
+		row[2] = prt.Sprint(cell.SubId)
+
+		// convert dimension item id to label
+		for k, e := range cell.DimIds {
+			v, err := fd[k](e)
+			if err != nil {
+				return false, err
+			}
+			row[k+3] = v
+		}
+
+		// use "null" string for db NULL values and format for model float types
+		if cell.IsNull {
+			row[n+3] = "null"
+		} else {
+			if cellCvt.DoubleFmt != "" {
+				row[n+3] = prt.Sprintf(cellCvt.DoubleFmt, cell.Value)
+			} else {
+				row[n+3] = prt.Sprint(cell.Value)
+			}
+		}
+		return true, nil
+	}
+
+	return cvt, nil
+}
+
+// Return converter from parameter comparison cell of ids: (variant id, calc_id, sub_id, dimensions enum ids, calc_value)
+// to cell of codes: (Variant, CalcName, sub_id, dimensions as enum codes, calc_value).
+// Output Variant value is coming from RunIdToLabel map and it can be run digest, run name or workset name.
+//
+// If dimension type is enum based then dimensions enum ids can be converted to enum code.
+// If dimension type is simple (bool or int) then dimension value converted to string.
+func (cellCvt *CellParamCalcConverter) IdToCodeCell(modelDef *ModelMeta, name string) (func(interface{}) (interface{}, error), error) {
+
+	// find parameter by name
+	param, err := cellCvt.paramByName()
+	if err != nil {
+		return nil, err
+	}
+
+	// for each dimension create converter from item id to code
+	fd := make([]func(itemId int) (string, error), param.Rank)
+
+	for k := 0; k < param.Rank; k++ {
+		f, err := param.Dim[k].typeOf.itemIdToCode(name+"."+param.Dim[k].Name, false)
+		if err != nil {
+			return nil, err
+		}
+		fd[k] = f
+	}
+
+	// create cell converter
+	cvt := func(src interface{}) (interface{}, error) {
+
+		srcCell, ok := src.(CellParamCalc)
+		if !ok {
+			return nil, errors.New("invalid type, expected: parameter comparison cell (internal error): " + name)
+		}
+		if len(srcCell.DimIds) != param.Rank {
+			return nil, errors.New("invalid cell rank: " + strconv.Itoa(len(srcCell.DimIds)) + ", expected: " + strconv.Itoa(param.Rank) + ": " + name)
+		}
+
+		vLabel := cellCvt.RunIdToLabel[srcCell.SrcId]
+		if vLabel == "" {
+			return nil, errors.New("invalid (missing) run or workset id: " + strconv.Itoa(srcCell.SrcId) + " parameter: " + name)
+		}
+		cName := cellCvt.CalcIdToName[srcCell.CalcId]
+		if cName == "" {
+			return nil, errors.New("invalid (missing) calculation id: " + strconv.Itoa(srcCell.CalcId) + " parameter: " + name)
+		}
+
+		dstCell := CellCodeParamCalc{
+			cellCodeValue: cellCodeValue{
+				Dims:   make([]string, param.Rank),
+				IsNull: srcCell.IsNull,
+				Value:  srcCell.Value,
+			},
+			SubId:    srcCell.SubId,
+			CalcName: cName,
+			Variant:  vLabel,
+		}
+
+		// convert dimension item id to code
+		for k := range srcCell.DimIds {
+			v, err := fd[k](srcCell.DimIds[k])
+			if err != nil {
+				return nil, err
+			}
+			dstCell.Dims[k] = v
+		}
+
+		return dstCell, nil // converted OK
+	}
+
+	return cvt, nil
+}
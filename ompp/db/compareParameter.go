@@ -0,0 +1,614 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// ParamCompareSummary is a result of comparison of all model parameters between base and variant run or workset.
+type ParamCompareSummary struct {
+	Name      string // parameter name
+	IsDiff    bool   // if true then base and variant parameter values are different
+	BaseCount int64  // number of base cells which are not found in variant or have different value
+	VarCount  int64  // number of variant cells which are not found in base or have different value
+}
+
+// ParamCompareCalculation return parameter comparison expressions by name of comparison: diff, ratio, percent.
+//
+// Result always include base and variant values, calculation id is 0 and 1,
+// following by comparison calculation, ex.: ageSex[variant] - ageSex[base], calculation id is CALCULATED_ID_OFFSET.
+// If comparison is not one of: diff, ratio, percent then it must be a comma separated list of comparison expressions.
+func ParamCompareCalculation(name, cmp string) ([]CalculateLayout, error) {
+
+	if name == "" {
+		return []CalculateLayout{}, errors.New("invalid (empty) parameter name")
+	}
+	cLt := []CalculateLayout{
+		{Calculate: name + "[base]", CalcId: 0, Name: "base"},
+		{Calculate: name + "[variant]", CalcId: 1, Name: "variant"},
+	}
+
+	switch strings.ToLower(cmp) {
+	case "", "diff":
+		return append(cLt, CalculateLayout{
+			Calculate: name + "[variant] - " + name + "[base]",
+			CalcId:    CALCULATED_ID_OFFSET,
+			Name:      "diff",
+		}), nil
+	case "ratio":
+		return append(cLt, CalculateLayout{
+			Calculate: name + "[variant] / OM_DIV_BY(" + name + "[base])",
+			CalcId:    CALCULATED_ID_OFFSET,
+			Name:      "ratio",
+		}), nil
+	case "percent":
+		return append(cLt, CalculateLayout{
+			Calculate: "100 * (" + name + "[variant] - " + name + "[base]) / OM_DIV_BY(" + name + "[base])",
+			CalcId:    CALCULATED_ID_OFFSET,
+			Name:      "percent",
+		}), nil
+	}
+
+	// comma separated list of comparison expressions
+	cArr := helper.ParseCsvLine(cmp, ',')
+
+	for j := range cArr {
+		if cArr[j] == "" {
+			continue
+		}
+		cLt = append(cLt, CalculateLayout{
+			Calculate: cArr[j],
+			CalcId:    CALCULATED_ID_OFFSET + j,
+			Name:      "ex_" + strconv.Itoa(CALCULATED_ID_OFFSET+j),
+		})
+	}
+	if len(cLt) <= 2 {
+		return []CalculateLayout{}, errors.New("Invalid (empty) parameter comparison: " + cmp)
+	}
+	return cLt, nil
+}
+
+// CompareParameterTo read input parameter comparison between base run or workset and variant runs and worksets
+// and process each row by cvtTo().
+//
+// Base and variant values are joined by sub-value id and dimension items.
+// Only cells which exist in base and variant are selected.
+// Comparison expression(s) must contain parameter [base] and [variant] values, ex.: ageSex[variant] - ageSex[base].
+// If layout IsChangedOnly is true then only cells where variant value is different from base value are selected.
+// Parameter must be numeric: float or integer type.
+func CompareParameterTo(
+	dbConn *sql.DB, modelDef *ModelMeta, layout *ReadCalculteParamLayout, variantLst []CompareParamSource, cvtTo func(src interface{}) (bool, error),
+) (*ReadPageLayout, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if layout == nil {
+		return nil, errors.New("invalid (empty) parameter compare layout")
+	}
+	if layout.Name == "" {
+		return nil, errors.New("invalid (empty) parameter name")
+	}
+	if len(layout.Calculation) <= 0 {
+		return nil, errors.New("invalid (empty) parameter comparison expression(s): " + layout.Name)
+	}
+	if len(variantLst) <= 0 {
+		return nil, errors.New("invalid (empty) list of model runs or worksets to compare parameter: " + layout.Name)
+	}
+
+	// find parameter by name
+	var param *ParamMeta
+	if k, ok := modelDef.ParamByName(layout.Name); ok {
+		param = &modelDef.Param[k]
+	} else {
+		return nil, errors.New("parameter not found: " + layout.Name)
+	}
+	if !param.typeOf.IsFloat() && !param.typeOf.IsInt() {
+		return nil, errors.New("invalid parameter type, expected: float or integer: " + param.Name)
+	}
+
+	// make sql to select parameter comparison from base and variant(s)
+	q, err := translateParamCompareToSql(dbConn, param, layout, variantLst)
+	if err != nil {
+		return nil, err
+	}
+
+	// prepare db-row scan conversion buffer: variant id, calculation id, sub_id, dimensions, value
+	var srcId int
+	var calcId int
+	var subId int
+	d := make([]int, param.Rank)
+	var vf sql.NullFloat64
+	var scanBuf []interface{}
+
+	scanBuf = append(scanBuf, &srcId)
+	scanBuf = append(scanBuf, &calcId)
+	scanBuf = append(scanBuf, &subId)
+
+	for k := 0; k < param.Rank; k++ {
+		scanBuf = append(scanBuf, &d[k])
+	}
+	scanBuf = append(scanBuf, &vf)
+
+	// make new cell from conversion buffer
+	makeCell := func() interface{} {
+		c := CellParamCalc{
+			cellIdValue: cellIdValue{DimIds: make([]int, param.Rank)},
+			SubId:       subId,
+			CalcId:      calcId,
+			SrcId:       srcId,
+		}
+		copy(c.DimIds, d)
+		c.IsNull = !vf.Valid
+		c.Value = 0.0
+		if !c.IsNull {
+			c.Value = vf.Float64
+		}
+		return c
+	}
+
+	// if full page requested:
+	// select rows into the list buffer and write rows from the list into output stream
+	if layout.IsFullPage {
+
+		// make a list of output cells
+		cLst, lt, e := SelectToList(dbConn, q, layout.ReadPageLayout,
+			func(rows *sql.Rows) (interface{}, error) {
+
+				if e := rows.Scan(scanBuf...); e != nil {
+					return nil, e
+				}
+				return makeCell(), nil
+			})
+		if e != nil {
+			return nil, e
+		}
+
+		// write page into output stream
+		for c := cLst.Front(); c != nil; c = c.Next() {
+
+			if _, e := cvtTo(c.Value); e != nil {
+				return nil, e
+			}
+		}
+
+		return lt, nil // done: return output page layout
+	}
+	// else: select rows and write it into output stream without buffering
+
+	// adjust page layout: starting offset and page size
+	nStart := layout.Offset
+	if nStart < 0 {
+		nStart = 0
+	}
+	nSize := layout.Size
+	if nSize < 0 {
+		nSize = 0
+	}
+	var nRow int64
+
+	lt := ReadPageLayout{
+		Offset:     nStart,
+		Size:       0,
+		IsLastPage: false,
+	}
+
+	// select cells: variant id, calculation id, sub_id, dimension(s) enum ids, calculated value
+	err = SelectRowsTo(dbConn, q,
+		func(rows *sql.Rows) (bool, error) {
+
+			// if page size is limited then select only a page of rows
+			nRow++
+			if nSize > 0 && nRow > nStart+nSize {
+				return false, nil
+			}
+			if nRow <= nStart {
+				return true, nil
+			}
+
+			// select next row
+			if e := rows.Scan(scanBuf...); e != nil {
+				return false, e
+			}
+			lt.Size++
+
+			// make new cell from conversion buffer
+			c := makeCell()
+
+			return cvtTo(c) // process cell
+		})
+	if err != nil && err != sql.ErrNoRows { // empty comparison result is not an error
+		return nil, err
+	}
+
+	// check for the empty result page or last page
+	if lt.Size <= 0 {
+		lt.Offset = nRow
+	}
+	lt.IsLastPage = nSize <= 0 || nSize > 0 && nRow <= nStart+nSize
+
+	return &lt, nil
+}
+
+// translateParamCompareToSql make sql to select parameter comparison between base and variant runs or worksets.
+//
+// For example:
+//
+//	WITH pb (sub_id, dim0, dim1, param_base) AS
+//	(
+//	  SELECT sub_id, dim0, dim1, param_value FROM ageSex_p2012_817 WHERE run_id = (SELECT base_run_id FROM run_parameter WHERE run_id = 102 AND parameter_hid = 1)
+//	),
+//	pv (src_id, sub_id, dim0, dim1, param_var) AS
+//	(
+//	  SELECT 103, sub_id, dim0, dim1, param_value FROM ageSex_p2012_817 WHERE run_id = (SELECT base_run_id FROM run_parameter WHERE run_id = 103 AND parameter_hid = 1)
+//	  UNION ALL
+//	  SELECT 104, sub_id, dim0, dim1, param_value FROM ageSex_w2012_817 WHERE set_id = 104
+//	)
+//	SELECT V.src_id, 12000 AS calc_id, B.sub_id, B.dim0, B.dim1, (V.param_var - B.param_base) AS calc_value
+//	FROM pb B
+//	INNER JOIN pv V ON (V.sub_id = B.sub_id AND V.dim0 = B.dim0 AND V.dim1 = B.dim1)
+//	WHERE B.dim1 IN (1, 2, 3, 4)
+//	ORDER BY 1, 2, 3, 4, 5
+func translateParamCompareToSql(dbConn *sql.DB, param *ParamMeta, layout *ReadCalculteParamLayout, variantLst []CompareParamSource) (string, error) {
+
+	// list of dimension columns: dim0, dim1,
+	dimCols := ""
+	for k := range param.Dim {
+		dimCols += param.Dim[k].colName + ", "
+	}
+
+	// base parameter values
+	baseSql, err := paramSourceSql(dbConn, param, layout.FromId, layout.IsFromSet, false)
+	if err != nil {
+		return "", err
+	}
+	q := "WITH pb (sub_id, " + dimCols + "param_base) AS" +
+		" (SELECT sub_id, " + dimCols + "param_value FROM " + baseSql + ")," +
+		" pv (src_id, sub_id, " + dimCols + "param_var) AS ("
+
+	// variant parameter values
+	for k, v := range variantLst {
+
+		varSql, err := paramSourceSql(dbConn, param, v.Id, v.IsFromSet, false)
+		if err != nil {
+			return "", err
+		}
+		if k > 0 {
+			q += " UNION ALL "
+		}
+		q += "SELECT " + strconv.Itoa(v.Id) + ", sub_id, " + dimCols + "param_value FROM " + varSql
+	}
+	q += ")"
+
+	// join base and variant by sub_id and dimensions
+	fromSql := " FROM pb B INNER JOIN pv V ON (V.sub_id = B.sub_id"
+	for k := range param.Dim {
+		fromSql += " AND V." + param.Dim[k].colName + " = B." + param.Dim[k].colName
+	}
+	fromSql += ")"
+
+	// append dimension enum code filters, if specified
+	where := ""
+
+	for k := range layout.Filter {
+
+		dix := -1
+		for j := range param.Dim {
+			if param.Dim[j].Name == layout.Filter[k].Name {
+				dix = j
+				break
+			}
+		}
+		if dix < 0 {
+			return "", errors.New("parameter " + param.Name + " does not have dimension " + layout.Filter[k].Name)
+		}
+
+		f, err := makeWhereFilter(
+			&layout.Filter[k], "B", param.Dim[dix].colName, param.Dim[dix].typeOf, false, param.Dim[dix].Name, "parameter "+param.Name)
+		if err != nil {
+			return "", err
+		}
+
+		if where == "" {
+			where = " WHERE " + f
+		} else {
+			where += " AND " + f
+		}
+	}
+
+	// append dimension enum id filters, if specified
+	for k := range layout.FilterById {
+
+		dix := -1
+		for j := range param.Dim {
+			if param.Dim[j].Name == layout.FilterById[k].Name {
+				dix = j
+				break
+			}
+		}
+		if dix < 0 {
+			return "", errors.New("parameter " + param.Name + " does not have dimension " + layout.FilterById[k].Name)
+		}
+
+		f, err := makeWhereIdFilter(
+			&layout.FilterById[k], "B", param.Dim[dix].colName, param.Dim[dix].typeOf, param.Dim[dix].Name, "parameter "+param.Name)
+		if err != nil {
+			return "", err
+		}
+
+		if where == "" {
+			where = " WHERE " + f
+		} else {
+			where += " AND " + f
+		}
+	}
+
+	// select only changed values: base value is not equal to variant value
+	if layout.IsChangedOnly {
+
+		f := "(B.param_base <> V.param_var" +
+			" OR (B.param_base IS NULL AND V.param_var IS NOT NULL)" +
+			" OR (B.param_base IS NOT NULL AND V.param_var IS NULL))"
+
+		if where == "" {
+			where = " WHERE " + f
+		} else {
+			where += " AND " + f
+		}
+	}
+
+	// for each comparison expression select calculated value
+	for k, c := range layout.Calculation {
+
+		expr, err := translateParamCompareExpr(param.Name, c.Calculate)
+		if err != nil {
+			return "", err
+		}
+		if k > 0 {
+			q += " UNION ALL"
+		}
+		q += " SELECT V.src_id, " + strconv.Itoa(c.CalcId) + " AS calc_id, B.sub_id, "
+		for j := range param.Dim {
+			q += "B." + param.Dim[j].colName + ", "
+		}
+		q += "(" + expr + ") AS calc_value" + fromSql + where
+	}
+
+	// append order by: variant id, calculation id, sub_id, dimensions
+	q += makeOrderBy(param.Rank+1, layout.OrderBy, 2)
+
+	return q, nil
+}
+
+// translateParamCompareExpr translate parameter comparison expression into sql, ex.:
+//
+//	ageSex[variant] / OM_DIV_BY(ageSex[base]) => V.param_var / CASE WHEN ABS(B.param_base) > 1.0e-37 THEN B.param_base ELSE NULL END
+//
+// Expression must contain parameter [base] or [variant] value.
+func translateParamCompareExpr(name string, src string) (string, error) {
+
+	if name == "" {
+		return "", errors.New("invalid (empty) parameter name")
+	}
+
+	// clean source expression and check for unsafe sql
+	expr := cleanSourceExpr(src)
+	if expr == "" {
+		return "", errors.New("invalid (empty) parameter comparison expression: " + name)
+	}
+	if err := errorIfUnsafeSqlOrComment(expr); err != nil {
+		return "", err
+	}
+
+	// translate non-aggregation functions: OM_IF, OM_DIV_BY
+	expr, err := translateAllSimpleFnc(expr)
+	if err != nil {
+		return "", err
+	}
+
+	// in each unquoted part of expression replace parameter[base] and parameter[variant]
+	baseName := name + "[base]"
+	varName := name + "[variant]"
+
+	var sb strings.Builder
+	nRef := 0
+	nPos := 0
+	nStart := 0
+
+	for nEnd := 0; nStart >= 0 && nEnd >= 0; {
+
+		if nStart, nEnd, err = nextUnquoted(expr, nStart); err != nil {
+			return "", err
+		}
+		if nStart < 0 || nEnd < 0 { // end of source expression
+			break
+		}
+		sb.WriteString(expr[nPos:nStart]) // copy 'quoted' part of expression
+
+		part := expr[nStart:nEnd]
+		var pb strings.Builder
+
+		for n := 0; n < len(part); {
+
+			isBase := strings.HasPrefix(part[n:], baseName)
+			isVar := !isBase && strings.HasPrefix(part[n:], varName)
+
+			// name must be delimited by space or left delimiter
+			if (isBase || isVar) && n > 0 {
+				r, _ := utf8.DecodeLastRuneInString(part[:n])
+				if !unicode.IsSpace(r) && !strings.ContainsRune(leftDelims, r) {
+					isBase, isVar = false, false
+				}
+			}
+
+			switch {
+			case isBase:
+				pb.WriteString("B.param_base")
+				n += len(baseName)
+				nRef++
+			case isVar:
+				pb.WriteString("V.param_var")
+				n += len(varName)
+				nRef++
+			default:
+				r, w := utf8.DecodeRuneInString(part[n:])
+				pb.WriteRune(r)
+				n += w
+			}
+		}
+
+		// parameter name must be followed by [base] or [variant] and only parameter can be [base] or [variant]
+		ps := pb.String()
+
+		if findNamePos(ps, name) >= 0 {
+			return "", errors.New("Error in expression, parameter " + name + " must be [base] or [variant]: " + src)
+		}
+		if strings.Contains(ps, "[base]") || strings.Contains(ps, "[variant]") {
+			return "", errors.New("Error in expression, only parameter " + name + " can be [base] or [variant]: " + src)
+		}
+		sb.WriteString(ps)
+
+		nPos = nEnd
+		nStart = nEnd
+	}
+	sb.WriteString(expr[nPos:]) // copy the rest of expression
+
+	if nRef <= 0 {
+		return "", errors.New("Error in expression, it must contain " + baseName + " or " + varName + ": " + src)
+	}
+	sq := sb.String()
+	return sq, nil
+}
+
+// CompareParameterSummary compare all model parameters between base and variant run or workset.
+//
+// For each model parameter it does count number of base cells not found in variant (or found with different value)
+// and number of variant cells not found in base (or found with different value).
+// If base and variant are model runs and parameter value digest is the same then parameter values are not different.
+func CompareParameterSummary(dbConn *sql.DB, modelDef *ModelMeta, base, variant CompareParamSource) ([]ParamCompareSummary, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+
+	// if both are model runs then get parameters value digests
+	var baseDgst, varDgst map[int]string
+	var err error
+
+	if !base.IsFromSet && !variant.IsFromSet {
+		if baseDgst, err = selectRunParamDigest(dbConn, base.Id); err != nil {
+			return nil, err
+		}
+		if varDgst, err = selectRunParamDigest(dbConn, variant.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	// count cells where base value not equal to variant value
+	countDiff := func(fromSql, toSql string, dimCols []string) (int64, error) {
+
+		q := "SELECT COUNT(*) FROM (SELECT sub_id, " + strings.Join(dimCols, ", ")
+		if len(dimCols) > 0 {
+			q += ", "
+		}
+		q += "param_value FROM " + fromSql + ") A" +
+			" WHERE NOT EXISTS" +
+			" (SELECT * FROM (SELECT sub_id, " + strings.Join(dimCols, ", ")
+		if len(dimCols) > 0 {
+			q += ", "
+		}
+		q += "param_value FROM " + toSql + ") X" +
+			" WHERE X.sub_id = A.sub_id"
+		for _, c := range dimCols {
+			q += " AND X." + c + " = A." + c
+		}
+		q += " AND (X.param_value = A.param_value OR (X.param_value IS NULL AND A.param_value IS NULL)))"
+
+		var n int64
+		err := SelectFirst(dbConn, q,
+			func(row *sql.Row) error {
+				return row.Scan(&n)
+			})
+		switch {
+		case err == sql.ErrNoRows: // unknown error: should never be there
+			return 0, errors.New("cannot count parameter difference: " + q)
+		case err != nil:
+			return 0, err
+		}
+		return n, nil
+	}
+
+	// for each parameter count different cells
+	smLst := make([]ParamCompareSummary, len(modelDef.Param))
+
+	for k := range modelDef.Param {
+
+		param := &modelDef.Param[k]
+		smLst[k].Name = param.Name
+
+		if baseDgst != nil && varDgst != nil {
+			if bd := baseDgst[param.ParamHid]; bd != "" && bd == varDgst[param.ParamHid] {
+				continue // parameter values are identical
+			}
+		}
+
+		baseSql, err := paramSourceSql(dbConn, param, base.Id, base.IsFromSet, false)
+		if err != nil {
+			return nil, err
+		}
+		varSql, err := paramSourceSql(dbConn, param, variant.Id, variant.IsFromSet, false)
+		if err != nil {
+			return nil, err
+		}
+
+		dimCols := make([]string, param.Rank)
+		for j := range param.Dim {
+			dimCols[j] = param.Dim[j].colName
+		}
+
+		if smLst[k].BaseCount, err = countDiff(baseSql, varSql, dimCols); err != nil {
+			return nil, err
+		}
+		if smLst[k].VarCount, err = countDiff(varSql, baseSql, dimCols); err != nil {
+			return nil, err
+		}
+		smLst[k].IsDiff = smLst[k].BaseCount != 0 || smLst[k].VarCount != 0
+	}
+
+	return smLst, nil
+}
+
+// return map of parameter Hid to parameter value digest for model run
+func selectRunParamDigest(dbConn *sql.DB, runId int) (map[int]string, error) {
+
+	dm := map[int]string{}
+
+	err := SelectRows(dbConn,
+		"SELECT parameter_hid, value_digest FROM run_parameter WHERE run_id = "+strconv.Itoa(runId),
+		func(rows *sql.Rows) error {
+			var hId int
+			var sd sql.NullString
+			if err := rows.Scan(&hId, &sd); err != nil {
+				return err
+			}
+			if sd.Valid {
+				dm[hId] = sd.String
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return dm, nil
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// RunDiff is a result of comparison between base and variant model runs:
+// run options, parameters and output tables which are different.
+type RunDiff struct {
+	BaseRunDigest    string                // base model run digest
+	VariantRunDigest string                // variant model run digest
+	IsSameValues     bool                  // if true then run value digests are equal: all parameters and output tables are identical
+	Option           []RunOptionDiff       // run options which are different
+	Param            []ParamCompareSummary // parameters where value digests are different
+	Table            []TableRunDiff        // output tables where value digests are different
+}
+
+// RunOptionDiff is a run option which is different between base and variant model runs.
+type RunOptionDiff struct {
+	Key     string // option key
+	Base    string // option value in base run, empty if option not exist
+	Variant string // option value in variant run, empty if option not exist
+}
+
+// TableRunDiff is an output table which is different between base and variant model runs.
+type TableRunDiff struct {
+	Name      string          // output table name
+	IsMissing bool            // if true then output table exists only in one of the runs, it is suppressed in other run
+	Expr      []TableExprDiff // largest changes of output table expressions
+}
+
+// TableExprDiff is a summary of changes of output table expression between base and variant model runs.
+type TableExprDiff struct {
+	Name      string  // expression name, ex.: Expr0
+	DiffCount int64   // number of cells where variant value is different from base value
+	MaxAbs    float64 // largest absolute change: abs(variant - base)
+	MaxRel    float64 // largest relative change: abs((variant - base) / base), only where base value is not zero
+}
+
+// CompareRuns compare base and variant model runs: run options, parameters and output tables.
+//
+// Parameters and output tables are compared by value digests.
+// For each parameter where value digests are different it does count number of different cells.
+// For each output table where value digests are different it does calculate largest absolute and relative changes of each expression,
+// using calculation: Expr0[variant] - Expr0[base] and (Expr0[variant] - Expr0[base]) / OM_DIV_BY(Expr0[base]).
+// Both model runs must be completed successfully.
+func CompareRuns(dbConn *sql.DB, modelDef *ModelMeta, baseRunId, varRunId int) (*RunDiff, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+
+	// both model runs must exist and completed successfully
+	getRun := func(runId int) (*RunRow, error) {
+
+		r, err := GetRun(dbConn, runId)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil || r.ModelId != modelDef.Model.ModelId {
+			return nil, errors.New("model run not found, id: " + strconv.Itoa(runId))
+		}
+		if r.Status != DoneRunStatus {
+			return nil, errors.New("model run not completed successfully, id: " + strconv.Itoa(runId))
+		}
+		return r, nil
+	}
+	baseRun, err := getRun(baseRunId)
+	if err != nil {
+		return nil, err
+	}
+	varRun, err := getRun(varRunId)
+	if err != nil {
+		return nil, err
+	}
+
+	rd := RunDiff{
+		BaseRunDigest:    baseRun.RunDigest,
+		VariantRunDigest: varRun.RunDigest,
+		IsSameValues:     baseRun.ValueDigest != "" && baseRun.ValueDigest == varRun.ValueDigest,
+		Option:           []RunOptionDiff{},
+		Param:            []ParamCompareSummary{},
+		Table:            []TableRunDiff{},
+	}
+
+	// compare run options
+	baseOpts, err := GetRunOptions(dbConn, baseRunId)
+	if err != nil {
+		return nil, err
+	}
+	varOpts, err := GetRunOptions(dbConn, varRunId)
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range baseOpts {
+		if v, ok := varOpts[key]; !ok || v != val {
+			rd.Option = append(rd.Option, RunOptionDiff{Key: key, Base: val, Variant: v})
+		}
+	}
+	for key, val := range varOpts {
+		if _, ok := baseOpts[key]; !ok {
+			rd.Option = append(rd.Option, RunOptionDiff{Key: key, Base: "", Variant: val})
+		}
+	}
+	sort.Slice(rd.Option, func(i, j int) bool { return rd.Option[i].Key < rd.Option[j].Key })
+
+	if rd.IsSameValues {
+		return &rd, nil // all parameters and output tables are identical
+	}
+
+	// compare parameters: parameter values with the same value digest are not compared
+	smLst, err := CompareParameterSummary(
+		dbConn, modelDef, CompareParamSource{Id: baseRunId, IsFromSet: false}, CompareParamSource{Id: varRunId, IsFromSet: false})
+	if err != nil {
+		return nil, err
+	}
+	for k := range smLst {
+		if smLst[k].IsDiff {
+			rd.Param = append(rd.Param, smLst[k])
+		}
+	}
+
+	// compare output tables by value digests
+	baseDgst, err := selectRunTableDigest(dbConn, baseRunId)
+	if err != nil {
+		return nil, err
+	}
+	varDgst, err := selectRunTableDigest(dbConn, varRunId)
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range modelDef.Table {
+
+		table := &modelDef.Table[k]
+
+		bd, isBase := baseDgst[table.TableHid]
+		vd, isVar := varDgst[table.TableHid]
+
+		if !isBase && !isVar || bd != "" && bd == vd {
+			continue // output table suppressed in both runs or values are identical
+		}
+		if !isBase || !isVar {
+			rd.Table = append(rd.Table, TableRunDiff{Name: table.Name, IsMissing: true, Expr: []TableExprDiff{}})
+			continue
+		}
+
+		// for each expression calculate absolute and relative difference
+		td := TableRunDiff{Name: table.Name, Expr: make([]TableExprDiff, len(table.Expr))}
+		calcLt := make([]CalculateTableLayout, 2*len(table.Expr))
+
+		for j := range table.Expr {
+
+			en := table.Expr[j].Name
+			td.Expr[j].Name = en
+
+			calcLt[2*j] = CalculateTableLayout{
+				CalculateLayout: CalculateLayout{
+					Calculate: en + "[variant] - " + en + "[base]",
+					CalcId:    CALCULATED_ID_OFFSET + 2*j,
+					Name:      en + "_abs",
+				},
+			}
+			calcLt[2*j+1] = CalculateTableLayout{
+				CalculateLayout: CalculateLayout{
+					Calculate: "(" + en + "[variant] - " + en + "[base]) / OM_DIV_BY(" + en + "[base])",
+					CalcId:    CALCULATED_ID_OFFSET + 2*j + 1,
+					Name:      en + "_rel",
+				},
+			}
+		}
+
+		// find largest absolute and relative changes
+		cvt := func(src interface{}) (bool, error) {
+
+			c, ok := src.(CellTableCalc)
+			if !ok {
+				return false, errors.New("invalid type, expected: output table calculated cell (internal error): " + table.Name)
+			}
+			if c.IsNull {
+				return true, nil
+			}
+			v, ok := c.Value.(float64)
+			if !ok {
+				return true, nil
+			}
+			v = math.Abs(v)
+
+			j := (c.CalcId - CALCULATED_ID_OFFSET) / 2
+			if j < 0 || j >= len(td.Expr) {
+				return false, errors.New("invalid calculation id: " + strconv.Itoa(c.CalcId) + ": " + table.Name)
+			}
+
+			if (c.CalcId-CALCULATED_ID_OFFSET)%2 == 0 {
+				if v != 0 {
+					td.Expr[j].DiffCount++
+				}
+				if v > td.Expr[j].MaxAbs {
+					td.Expr[j].MaxAbs = v
+				}
+			} else {
+				if v > td.Expr[j].MaxRel {
+					td.Expr[j].MaxRel = v
+				}
+			}
+			return true, nil
+		}
+
+		layout := ReadTableLayout{ReadLayout: ReadLayout{Name: table.Name, FromId: baseRunId}}
+
+		if _, err = ReadOutputTableCalculteTo(dbConn, modelDef, &layout, calcLt, []int{varRunId}, cvt); err != nil {
+			return nil, err
+		}
+		rd.Table = append(rd.Table, td)
+	}
+
+	return &rd, nil
+}
+
+// return map of output table Hid to table value digest for model run
+func selectRunTableDigest(dbConn *sql.DB, runId int) (map[int]string, error) {
+
+	dm := map[int]string{}
+
+	err := SelectRows(dbConn,
+		"SELECT table_hid, value_digest FROM run_table WHERE run_id = "+strconv.Itoa(runId),
+		func(rows *sql.Rows) error {
+			var hId int
+			var sd sql.NullString
+			if err := rows.Scan(&hId, &sd); err != nil {
+				return err
+			}
+			dm[hId] = ""
+			if sd.Valid {
+				dm[hId] = sd.String
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return dm, nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestCompareRuns(t *testing.T) {
+
+	dbConn, modelDef, langDef := createTestModel(t)
+
+	// second run is identical to first run, third run has different salarySex[F] and incomeSex[M] values
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, modelDef, langDef, "r1", &v)
+	r2 := createTestRun(t, dbConn, modelDef, langDef, "r2", &v)
+
+	v3 := testValues{salary: [2]float64{10, 25}, startAge: 18, income: [2]float64{150, 200}}
+	r3 := createTestRun(t, dbConn, modelDef, langDef, "r3", &v3)
+
+	for _, runId := range []int{r1, r2, r3} {
+		if _, err := UpdateRunValueDigest(dbConn.DB, runId); err != nil {
+			t.Fatal("****FAIL: update run value digest:", runId, err)
+		}
+	}
+
+	// identical runs: only run name option is different
+	rd, err := CompareRuns(dbConn.DB, modelDef, r1, r2)
+	if err != nil {
+		t.Fatal("****FAIL: compare runs:", err)
+	}
+	if !rd.IsSameValues || rd.BaseRunDigest != "t_run_r1" || rd.VariantRunDigest != "t_run_r2" {
+		t.Error("****FAIL: expected identical run values:", rd)
+	}
+	if len(rd.Param) != 0 || len(rd.Table) != 0 {
+		t.Error("****FAIL: expected no parameters and output tables differences:", rd.Param, rd.Table)
+	}
+	if len(rd.Option) != 1 || rd.Option[0] != (RunOptionDiff{Key: "OpenM.RunName", Base: "r1", Variant: "r2"}) {
+		t.Error("****FAIL: expected run name option difference, found:", rd.Option)
+	}
+
+	// different runs: one parameter and one output table are different
+	rd, err = CompareRuns(dbConn.DB, modelDef, r1, r3)
+	if err != nil {
+		t.Fatal("****FAIL: compare runs:", err)
+	}
+	if rd.IsSameValues {
+		t.Error("****FAIL: expected different run values")
+	}
+
+	if len(rd.Param) != 1 {
+		t.Fatal("****FAIL: expected one different parameter, found:", rd.Param)
+	}
+	if pd := rd.Param[0]; pd.Name != "salarySex" || !pd.IsDiff || pd.BaseCount != 1 || pd.VarCount != 1 {
+		t.Error("****FAIL: invalid parameter difference:", pd)
+	}
+
+	if len(rd.Table) != 1 {
+		t.Fatal("****FAIL: expected one different output table, found:", rd.Table)
+	}
+	td := rd.Table[0]
+	if td.Name != "incomeSex" || td.IsMissing || len(td.Expr) != 2 {
+		t.Fatal("****FAIL: invalid output table difference:", td)
+	}
+	for k, en := range []string{"expr0", "expr1"} {
+		ed := td.Expr[k]
+		if ed.Name != en || ed.DiffCount != 1 || math.Abs(ed.MaxAbs-50) > 1.0e-9 || math.Abs(ed.MaxRel-0.5) > 1.0e-9 {
+			t.Error("****FAIL: invalid expression difference, expected:", en, 1, 50, 0.5, "found:", ed)
+		}
+	}
+
+	// output table suppressed in variant run
+	if _, err = dbConn.Exec("DELETE FROM run_table WHERE run_id = " + strconv.Itoa(r3)); err != nil {
+		t.Fatal("****FAIL: delete run table:", err)
+	}
+	rd, err = CompareRuns(dbConn.DB, modelDef, r1, r3)
+	if err != nil {
+		t.Fatal("****FAIL: compare runs:", err)
+	}
+	if len(rd.Table) != 1 || rd.Table[0].Name != "incomeSex" || !rd.Table[0].IsMissing || len(rd.Table[0].Expr) != 0 {
+		t.Error("****FAIL: expected missing output table, found:", rd.Table)
+	}
+
+	// model run must exist and completed successfully
+	if _, err = CompareRuns(dbConn.DB, modelDef, r1, 9999); err == nil {
+		t.Error("****FAIL: expected error on compare with not existing run")
+	}
+	if _, err = dbConn.Exec("UPDATE run_lst SET status = 'e' WHERE run_id = " + strconv.Itoa(r2)); err != nil {
+		t.Fatal("****FAIL: update run status:", err)
+	}
+	if _, err = CompareRuns(dbConn.DB, modelDef, r1, r2); err == nil {
+		t.Error("****FAIL: expected error on compare with failed run")
+	}
+	if _, err = CompareRuns(dbConn.DB, nil, r1, r3); err == nil {
+		t.Error("****FAIL: expected error on empty model metadata")
+	}
+}
@@ -19,6 +19,12 @@ import (
 // Destination workset must be in read-write state.
 // Source model run must be completed, run status one of: s=success, x=exit, e=error.
 func CopyParameterFromRun(dbConn *sql.DB, modelDef *ModelMeta, ws *WorksetRow, paramName string, isReplace bool, rs *RunRow) error {
+	return CopyParameterFromRunChecked(dbConn, modelDef, ws, paramName, isReplace, rs, nil)
+}
+
+// CopyParameterFromRunChecked copy parameter metadata and parameter values into workset from model run.
+// If update action not nil then current parameter values saved in workset history in the same transaction.
+func CopyParameterFromRunChecked(dbConn *sql.DB, modelDef *ModelMeta, ws *WorksetRow, paramName string, isReplace bool, rs *RunRow, upd *WorksetUpdateAction) error {
 
 	// validate parameters
 	if modelDef == nil {
@@ -44,17 +50,42 @@ func CopyParameterFromRun(dbConn *sql.DB, modelDef *ModelMeta, ws *WorksetRow, p
 	}
 	pm := modelDef.Param[i]
 
+	// copied values must be valid by parameter validation rules
+	rules, err := GetParamRules(dbConn, modelDef, paramName)
+	if err != nil {
+		return err
+	}
+	isDstSparse, err := isSparseWorksetParam(dbConn, ws, paramName)
+	if err != nil {
+		return err
+	}
+
 	// copy parameter metadata and values from model run into workset inside of transaction scope
 	trx, err := dbConn.Begin()
 	if err != nil {
 		return err
 	}
-	if err = dbCopyParameterFromRun(trx, ws, &pm, isReplace, rs); err != nil {
+	if err = dbCopyParameterFromRun(trx, ws, &pm, isReplace, rs, upd.historyCheck(dbConn, &pm)); err != nil {
+		trx.Rollback()
+		return err
+	}
+	if err = trxValidateWorksetParam(trx, &pm, ws.SetId, rules); err != nil {
 		trx.Rollback()
 		return err
 	}
 	trx.Commit()
-	return nil
+
+	// all cells copied from model run: parameter is not sparse
+	if isDstSparse {
+		if err = updateWorksetSparseParam(dbConn, modelDef.Model.Digest, ws.SetId, paramName, false); err != nil {
+			return err
+		}
+	}
+
+	// store source of parameter values: model run
+	return updateWorksetParamSource(dbConn, modelDef, ws.Name, []WorksetParamSource{
+		{Name: paramName, Kind: "copy-run", RunName: rs.Name, RunDigest: rs.RunDigest},
+	})
 }
 
 // CopyParameterFromWorkset copy parameter metadata and parameter values from one workset to another.
@@ -63,6 +94,12 @@ func CopyParameterFromRun(dbConn *sql.DB, modelDef *ModelMeta, ws *WorksetRow, p
 // If isReplace is false then delete existing metadata and new insert new from source workset.
 // Destination workset must be in read-write state, source workset must be read-only.
 func CopyParameterFromWorkset(dbConn *sql.DB, modelDef *ModelMeta, dstWs *WorksetRow, paramName string, isReplace bool, srcWs *WorksetRow) error {
+	return CopyParameterFromWorksetChecked(dbConn, modelDef, dstWs, paramName, isReplace, srcWs, nil)
+}
+
+// CopyParameterFromWorksetChecked copy parameter metadata and parameter values from one workset to another.
+// If update action not nil then current parameter values saved in workset history in the same transaction.
+func CopyParameterFromWorksetChecked(dbConn *sql.DB, modelDef *ModelMeta, dstWs *WorksetRow, paramName string, isReplace bool, srcWs *WorksetRow, upd *WorksetUpdateAction) error {
 
 	// validate parameters
 	if modelDef == nil {
@@ -85,24 +122,58 @@ func CopyParameterFromWorkset(dbConn *sql.DB, modelDef *ModelMeta, dstWs *Workse
 	}
 	pm := modelDef.Param[i]
 
+	// copied values must be valid by parameter validation rules
+	rules, err := GetParamRules(dbConn, modelDef, paramName)
+	if err != nil {
+		return err
+	}
+
+	// sparse parameter can be copied only into workset with the same base run
+	isSparse, err := isSparseWorksetParam(dbConn, srcWs, paramName)
+	if err != nil {
+		return err
+	}
+	if isSparse && srcWs.BaseRunId != dstWs.BaseRunId {
+		return errors.New("parameter " + paramName + " is sparse and workset " + dstWs.Name + " base run is not the same as base run of " + srcWs.Name)
+	}
+	isDstSparse, err := isSparseWorksetParam(dbConn, dstWs, paramName)
+	if err != nil {
+		return err
+	}
+
 	// copy parameter metadata and values  from one workset to another inside of transaction scope
 	trx, err := dbConn.Begin()
 	if err != nil {
 		return err
 	}
-	if err = dbCopyParameterFromWorkset(trx, dstWs, &pm, isReplace, srcWs); err != nil {
+	if err = dbCopyParameterFromWorkset(trx, dstWs, &pm, isReplace, srcWs, upd.historyCheck(dbConn, &pm)); err != nil {
+		trx.Rollback()
+		return err
+	}
+	if err = trxValidateWorksetParam(trx, &pm, dstWs.SetId, rules); err != nil {
 		trx.Rollback()
 		return err
 	}
 	trx.Commit()
-	return nil
+
+	// only overridden cells copied from sparse parameter: destination parameter is sparse
+	if isSparse != isDstSparse {
+		if err = updateWorksetSparseParam(dbConn, modelDef.Model.Digest, dstWs.SetId, paramName, isSparse); err != nil {
+			return err
+		}
+	}
+
+	// store source of parameter values: source workset
+	return updateWorksetParamSource(dbConn, modelDef, dstWs.Name, []WorksetParamSource{
+		{Name: paramName, Kind: "copy-set", SetName: srcWs.Name},
+	})
 }
 
 // dbCopyParameterFromRun copy workset parameter metadata and values into destination workset from model run.
 // It does copy as part of transaction.
 // If isReplace is true and parameter already exist in destination workset then error returned.
 // If isReplace is false then delete existing metadata and new insert new from model run.
-func dbCopyParameterFromRun(trx *sql.Tx, ws *WorksetRow, pm *ParamMeta, isReplace bool, rs *RunRow) error {
+func dbCopyParameterFromRun(trx *sql.Tx, ws *WorksetRow, pm *ParamMeta, isReplace bool, rs *RunRow, upd *WorksetUpdateAction) error {
 
 	// "lock" workset to prevent update or use by the model
 	mId := strconv.Itoa(ws.ModelId)
@@ -137,7 +208,7 @@ func dbCopyParameterFromRun(trx *sql.Tx, ws *WorksetRow, pm *ParamMeta, isReplac
 
 	// check if parameter already exist in destination workset
 	// delete if it is merge or return error if if it is insert new
-	err = prepareWorksetForParameterInsert(trx, ws, pm, isReplace)
+	verId, err := prepareWorksetForParameterInsert(trx, ws, pm, isReplace, upd)
 	if err != nil {
 		return err
 	}
@@ -201,6 +272,11 @@ func dbCopyParameterFromRun(trx *sql.Tx, ws *WorksetRow, pm *ParamMeta, isReplac
 		return err
 	}
 
+	// keep in workset history only cells changed by copy
+	if err = trxTrimParamHistory(trx, dstSetId, pm, verId); err != nil {
+		return err
+	}
+
 	// "unlock" workset before commit: restore original value of is_readonly=0
 	err = TrxUpdate(trx,
 		"UPDATE workset_lst"+
@@ -217,7 +293,7 @@ func dbCopyParameterFromRun(trx *sql.Tx, ws *WorksetRow, pm *ParamMeta, isReplac
 // It does copy as part of transaction.
 // If isReplace is true and parameter already exist in destination workset then error returned.
 // If isReplace is false then delete existing metadata and new insert new from source workset.
-func dbCopyParameterFromWorkset(trx *sql.Tx, dstWs *WorksetRow, pm *ParamMeta, isReplace bool, srcWs *WorksetRow) error {
+func dbCopyParameterFromWorkset(trx *sql.Tx, dstWs *WorksetRow, pm *ParamMeta, isReplace bool, srcWs *WorksetRow, upd *WorksetUpdateAction) error {
 
 	// "lock" destination workset to prevent update or use by the model
 	mId := strconv.Itoa(dstWs.ModelId)
@@ -252,7 +328,7 @@ func dbCopyParameterFromWorkset(trx *sql.Tx, dstWs *WorksetRow, pm *ParamMeta, i
 
 	// check if parameter already exist in destination workset
 	// delete if it is merge or return error if if it is insert new
-	err = prepareWorksetForParameterInsert(trx, dstWs, pm, isReplace)
+	verId, err := prepareWorksetForParameterInsert(trx, dstWs, pm, isReplace, upd)
 	if err != nil {
 		return err
 	}
@@ -381,6 +457,11 @@ func dbCopyParameterFromWorkset(trx *sql.Tx, dstWs *WorksetRow, pm *ParamMeta, i
 		return err
 	}
 
+	// keep in workset history only cells changed by copy
+	if err = trxTrimParamHistory(trx, dstSetId, pm, verId); err != nil {
+		return err
+	}
+
 	// "unlock" source workset before commit: restore original value of is_readonly=1
 	err = TrxUpdate(trx,
 		"UPDATE workset_lst SET is_readonly = 1 WHERE set_id = "+sSrcId)
@@ -401,17 +482,31 @@ func dbCopyParameterFromWorkset(trx *sql.Tx, dstWs *WorksetRow, pm *ParamMeta, i
 // Check if parameter exist in destination workset and:
 //  - if isReplace is true then error returned.
 //  - if isReplace is false then delete existing metadata and new insert new from model run.
-func prepareWorksetForParameterInsert(trx *sql.Tx, dstWs *WorksetRow, pm *ParamMeta, isReplace bool) error {
+//
+// If update action not nil then workset update date-time checked and current parameter values saved in workset history.
+// Return workset history version id, it is zero if history not saved.
+func prepareWorksetForParameterInsert(trx *sql.Tx, dstWs *WorksetRow, pm *ParamMeta, isReplace bool, upd *WorksetUpdateAction) (int, error) {
+
+	// check if destination workset is not updated by other user
+	if err := trxCheckWorksetUpdate(trx, dstWs.SetId, upd); err != nil {
+		return 0, err
+	}
+
+	// save current parameter values in workset history
+	verId, err := trxSaveParamHistory(trx, dstWs.SetId, pm, upd)
+	if err != nil {
+		return 0, err
+	}
 
 	// check if parameter already exist in destination workset
 	sDstId := strconv.Itoa(dstWs.SetId)
 	sHid := strconv.Itoa(pm.ParamHid)
 
-	err := TrxUpdate(trx, "UPDATE workset_parameter"+
+	err = TrxUpdate(trx, "UPDATE workset_parameter"+
 		" SET parameter_hid = "+sHid+
 		" WHERE set_id = "+sDstId+" AND parameter_hid = "+sHid)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// if not merge then return error if parameter already exist in workset_parameter
@@ -425,31 +520,31 @@ func prepareWorksetForParameterInsert(trx *sql.Tx, dstWs *WorksetRow, pm *ParamM
 			return nil
 		})
 	if err != nil && err != sql.ErrNoRows {
-		return err
+		return 0, err
 	}
 
 	// check if parameter exist in destination workset
 	isExist := err != sql.ErrNoRows
 	if !isExist {
-		return nil // parameter does not exist
+		return verId, nil // parameter does not exist
 	}
 	// else parameter is exist: error if it is not a merge
 	if !isReplace {
-		return errors.New("failed to copy, destination workset already contains parameter: " + dstWs.Name + ": " + pm.Name)
+		return 0, errors.New("failed to copy, destination workset already contains parameter: " + dstWs.Name + ": " + pm.Name)
 	}
 	// else it is a merge: delete existing parameter from destination workset
 
 	err = TrxUpdate(trx, "DELETE FROM "+pm.DbSetTable+" WHERE set_id = "+sDstId)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	err = TrxUpdate(trx, "DELETE FROM workset_parameter_txt WHERE set_id = "+sDstId+" AND parameter_hid = "+sHid)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	err = TrxUpdate(trx, "DELETE FROM workset_parameter WHERE set_id = "+sDstId+" AND parameter_hid = "+sHid)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return nil // parameter does not exist and destination workset id ready for insert
+	return verId, nil // parameter does not exist and destination workset id ready for insert
 }
@@ -7,6 +7,11 @@ import (
 	"database/sql"
 	"strconv"
 	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	mssql "github.com/microsoft/go-mssqldb"
 )
 
 // Facet is type to define database engine and driver facets, e.g.: name of bigint type
@@ -180,6 +185,38 @@ func (facet Facet) PlaceHolder(pos int) string {
 	return "" // return empty "" string as invalid parameter palceholder
 }
 
+// return db facet of database connection: use driver information and if it is ODBC driver then detect db provider engine.
+func facetOfDb(dbConn *sql.DB) Facet {
+
+	switch dbConn.Driver().(type) {
+	case *sqlite3.SQLiteDriver:
+		return SqliteFacet
+	case *pq.Driver:
+		return PostgreSqlFacet
+	case *mysql.MySQLDriver:
+		return MySqlFacet
+	case *mssql.Driver:
+		return MsSqlFacet
+	}
+
+	// it is ODBC or unknown driver: detect db provider by quiering sql server
+	switch detectEngine(dbConn) {
+	case SqliteEngine:
+		return SqliteFacet
+	case PostgreSqlEngine:
+		return PostgreSqlOdbcFacet
+	case MySqlEngine:
+		return MySqlOdbcFacet
+	case MsSqlEngine:
+		return MsSqlOdbcFacet
+	case OracleEngine:
+		return OracleOdbcFacet
+	case Db2Engine:
+		return Db2OdbcFacet
+	}
+	return DefaultFacet
+}
+
 // Detect db provider engine by quiering sql server.
 // It may not be always reliable and even not true engine.
 // It is better to use driver information to determine db provider.
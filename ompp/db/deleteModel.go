@@ -17,12 +17,18 @@ func DeleteModel(dbConn *sql.DB, modelId int) error {
 		return errors.New("invalid model id: " + strconv.Itoa(modelId))
 	}
 
+	// find model parameters history tables
+	isHist, hTbls, err := modelHistoryTables(dbConn, modelId)
+	if err != nil {
+		return err
+	}
+
 	// delete inside of transaction scope
 	trx, err := dbConn.Begin()
 	if err != nil {
 		return err
 	}
-	if err := doDeleteModel(trx, modelId); err != nil {
+	if err := doDeleteModel(trx, modelId, isHist, hTbls); err != nil {
 		trx.Rollback()
 		return err
 	}
@@ -32,7 +38,7 @@ func DeleteModel(dbConn *sql.DB, modelId int) error {
 
 // delete existing model metadata and drop model data tables from database.
 // It does update as part of transaction
-func doDeleteModel(trx *sql.Tx, modelId int) error {
+func doDeleteModel(trx *sql.Tx, modelId int, isHistory bool, historyTbls map[int]string) error {
 
 	// update model master record to prevent model use
 	smId := strconv.Itoa(modelId)
@@ -75,6 +81,12 @@ func doDeleteModel(trx *sql.Tx, modelId int) error {
 		return err
 	}
 
+	// delete all versions of model worksets parameters
+	err = trxDeleteModelHistory(trx, modelId, isHistory, historyTbls)
+	if err != nil {
+		return err
+	}
+
 	// delete model worksets metadata
 	err = TrxUpdate(trx,
 		"DELETE FROM workset_parameter_txt WHERE EXISTS"+
@@ -704,6 +716,11 @@ func doDeleteModel(trx *sql.Tx, modelId int) error {
 
 	// skip model default profile: profile_lst and profile_option
 	// there is no explicit link between profile and model
+	// delete model extra data profiles: derived measures and other data stored by model digest
+	err = trxDeleteModelExtra(trx, smId)
+	if err != nil {
+		return err
+	}
 
 	// delete model groups
 	err = TrxUpdate(trx,
@@ -1308,10 +1325,17 @@ func doDeleteModel(trx *sql.Tx, modelId int) error {
 		}
 	}
 
-	// drop db-tables for parameter workset values and run values
+	// drop db-tables for parameter workset values, run values and history values
 	// where parameter not shared between models
 	for k := range paramArr {
 
+		if t, ok := historyTbls[paramArr[k].hId]; ok {
+			err = TrxUpdate(trx, "DROP TABLE "+t)
+			if err != nil {
+				return err
+			}
+		}
+
 		err = TrxUpdate(trx, "DROP TABLE "+paramArr[k].ws)
 		if err != nil {
 			return err
@@ -14,18 +14,34 @@ import (
 
 // DeleteWorkset delete workset metadata and workset parameter values from database.
 func DeleteWorkset(dbConn *sql.DB, setId int) error {
+	return DeleteWorksetChecked(dbConn, setId, nil)
+}
+
+// DeleteWorksetChecked delete workset metadata and workset parameter values from database.
+// If update action has expected update date-time then it is checked in the same transaction.
+func DeleteWorksetChecked(dbConn *sql.DB, setId int, upd *WorksetUpdateAction) error {
 
 	// validate parameters
 	if setId <= 0 {
 		return errors.New("invalid workset id: " + strconv.Itoa(setId))
 	}
 
+	// find workset parameters history tables
+	isHist, hTbls, err := worksetHistoryTables(dbConn, setId)
+	if err != nil {
+		return err
+	}
+
 	// delete inside of transaction scope
 	trx, err := dbConn.Begin()
 	if err != nil {
 		return err
 	}
-	if err := dbDeleteWorkset(trx, setId); err != nil {
+	if err := trxCheckWorksetUpdate(trx, setId, upd); err != nil {
+		trx.Rollback()
+		return err
+	}
+	if err := dbDeleteWorkset(trx, setId, isHist, hTbls); err != nil {
 		trx.Rollback()
 		return err
 	}
@@ -38,18 +54,36 @@ func DeleteWorkset(dbConn *sql.DB, setId int) error {
 // Workset must be read-write in order to delete all parameters.
 // If workset does not exist then nothing deleted and no errors returned, it is empty operation.
 func DeleteWorksetAllParameters(dbConn *sql.DB, setId int) error {
+	return deleteWorksetAllParameters(dbConn, nil, setId, nil)
+}
+
+// deleteWorksetAllParameters delete all parameters metadata and values from workset.
+// If update action not nil then current values of each parameter saved in workset history in the same transaction.
+func deleteWorksetAllParameters(dbConn *sql.DB, modelDef *ModelMeta, setId int, upd *WorksetUpdateAction) error {
 
 	// validate parameters
 	if setId <= 0 {
 		return errors.New("invalid workset id: " + strconv.Itoa(setId))
 	}
+	if upd != nil && modelDef == nil {
+		return errors.New("invalid (empty) model metadata")
+	}
+
+	// skip workset history if history tables not exist
+	if modelDef != nil {
+		pLst := make([]*ParamMeta, len(modelDef.Param))
+		for k := range modelDef.Param {
+			pLst[k] = &modelDef.Param[k]
+		}
+		upd = upd.historyCheck(dbConn, pLst...)
+	}
 
 	// delete inside of transaction scope
 	trx, err := dbConn.Begin()
 	if err != nil {
 		return err
 	}
-	if err := dbDeleteWorksetAllParameters(trx, setId); err != nil {
+	if err := dbDeleteWorksetAllParameters(trx, setId, modelDef, upd); err != nil {
 		trx.Rollback()
 		return err
 	}
@@ -57,9 +91,9 @@ func DeleteWorksetAllParameters(dbConn *sql.DB, setId int) error {
 	return nil
 }
 
-// doDeleteWorkset delete workset metadata and workset parameter values from database.
+// doDeleteWorkset delete workset metadata, workset parameter values and workset history from database.
 // It does update as part of transaction
-func dbDeleteWorkset(trx *sql.Tx, setId int) error {
+func dbDeleteWorkset(trx *sql.Tx, setId int, isHistory bool, historyTbls []string) error {
 
 	// update workset master record to prevent workset use
 	sId := strconv.Itoa(setId)
@@ -69,7 +103,13 @@ func dbDeleteWorkset(trx *sql.Tx, setId int) error {
 	}
 
 	// delete all parameters data and metadats from workset
-	err = dbDeleteWorksetAllParameters(trx, setId)
+	err = dbDeleteWorksetAllParameters(trx, setId, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	// delete all versions of workset parameters
+	err = trxDeleteWorksetHistory(trx, setId, isHistory, historyTbls)
 	if err != nil {
 		return err
 	}
@@ -80,6 +120,12 @@ func dbDeleteWorkset(trx *sql.Tx, setId int) error {
 		return err
 	}
 
+	// delete workset extra data: provenance of imported parameters and other data stored by workset id
+	err = trxDeleteWorksetExtra(trx, setId)
+	if err != nil {
+		return err
+	}
+
 	err = TrxUpdate(trx, "DELETE FROM workset_txt WHERE set_id = "+sId)
 	if err != nil {
 		return err
@@ -96,7 +142,8 @@ func dbDeleteWorkset(trx *sql.Tx, setId int) error {
 // dbDeleteWorksetAllParameters delete all parameters metadata and values from workset.
 // It does update as part of transaction
 // Workset must be read-write in order to delete all parameters.
-func dbDeleteWorksetAllParameters(trx *sql.Tx, setId int) error {
+// If update action not nil then current values of each parameter saved in workset history before delete.
+func dbDeleteWorksetAllParameters(trx *sql.Tx, setId int, modelDef *ModelMeta, upd *WorksetUpdateAction) error {
 
 	// "lock" workset to prevent update or use by the model
 	sId := strconv.Itoa(setId)
@@ -124,6 +171,16 @@ func dbDeleteWorksetAllParameters(trx *sql.Tx, setId int) error {
 	case nRd != 1:
 		return errors.New("failed to delete: workset is read-only: " + sId)
 	}
+	if err = trxCheckWorksetUpdate(trx, setId, upd); err != nil {
+		return err
+	}
+
+	// save current values of all workset parameters in workset history
+	if upd != nil {
+		if err = trxSaveWorksetHistory(trx, setId, modelDef, upd); err != nil {
+			return err
+		}
+	}
 
 	// build a list of workset parameters db-tables
 	var tblArr []string
@@ -185,6 +242,22 @@ func DeleteWorksetParameter(dbConn *sql.DB, modelId int, setName, paramName stri
 	if modelId <= 0 {
 		return 0, errors.New("invalid model id: " + strconv.Itoa(modelId))
 	}
+	modelDef, err := GetModelById(dbConn, modelId)
+	if err != nil {
+		return 0, err
+	}
+	return DeleteWorksetParameterChecked(dbConn, modelDef, setName, paramName, nil)
+}
+
+// DeleteWorksetParameterChecked do delete parameter metadata and values from workset.
+// It is return parameter Hid = 0 if nothing deleted.
+// If update action not nil then current parameter values saved in workset history in the same transaction.
+func DeleteWorksetParameterChecked(dbConn *sql.DB, modelDef *ModelMeta, setName, paramName string, upd *WorksetUpdateAction) (int, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return 0, errors.New("invalid (empty) model metadata")
+	}
 	if setName == "" {
 		return 0, errors.New("invalid (empty) workset name")
 	}
@@ -192,12 +265,17 @@ func DeleteWorksetParameter(dbConn *sql.DB, modelId int, setName, paramName stri
 		return 0, errors.New("invalid (empty) parameter name")
 	}
 
+	// skip workset history if history tables not exist
+	if k, ok := modelDef.ParamByName(paramName); ok {
+		upd = upd.historyCheck(dbConn, &modelDef.Param[k])
+	}
+
 	// delete inside of transaction scope
 	trx, err := dbConn.Begin()
 	if err != nil {
 		return 0, err
 	}
-	paramHid, err := dbDeleteWorksetParameter(trx, modelId, setName, paramName)
+	paramHid, err := dbDeleteWorksetParameter(trx, modelDef, setName, paramName, upd)
 	if err != nil {
 		trx.Rollback()
 		return 0, err
@@ -208,9 +286,11 @@ func DeleteWorksetParameter(dbConn *sql.DB, modelId int, setName, paramName stri
 
 // dbDeleteWorksetParameter delete workset parameter metadata and values from database.
 // It does update as part of transaction.
-func dbDeleteWorksetParameter(trx *sql.Tx, modelId int, setName, paramName string) (int, error) {
+func dbDeleteWorksetParameter(trx *sql.Tx, modelDef *ModelMeta, setName, paramName string, upd *WorksetUpdateAction) (int, error) {
 
 	// "lock" workset to prevent update or use by the model
+	modelId := modelDef.Model.ModelId
+
 	err := TrxUpdate(trx,
 		"UPDATE workset_lst"+
 			" SET is_readonly = is_readonly + 1"+
@@ -239,6 +319,9 @@ func dbDeleteWorksetParameter(trx *sql.Tx, modelId int, setName, paramName strin
 	case nRd != 1:
 		return 0, errors.New("failed to update: workset is read-only: " + setName)
 	}
+	if err = trxCheckWorksetUpdate(trx, setId, upd); err != nil {
+		return 0, err
+	}
 	sId := strconv.Itoa(setId)
 
 	// build a list of workset parameters db-tables
@@ -269,6 +352,13 @@ func dbDeleteWorksetParameter(trx *sql.Tx, modelId int, setName, paramName strin
 	}
 	spHid := strconv.Itoa(paramHid)
 
+	// save current parameter values in workset history
+	if k, ok := modelDef.ParamByHid(paramHid); ok {
+		if _, err = trxSaveParamHistory(trx, setId, &modelDef.Param[k], upd); err != nil {
+			return 0, err
+		}
+	}
+
 	// delete workset parameter values
 	err = TrxUpdate(trx, "DELETE FROM "+tblName+" WHERE set_id = "+sId)
 	if err != nil {
@@ -0,0 +1,235 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DerivedMeasure is a user-defined named calculated measure of output table or microdata entity.
+//
+// Derived measures are stored in model database as profile options: profile name is model digest + ".derived-measures",
+// option key is measure name and option value is json of measure definition.
+// Measure name can be used instead of calculation expression to read, calculate or compare output table or microdata values.
+type DerivedMeasure struct {
+	Name       string      // measure name, unique for the model, ex.: IncomeRatio
+	TableName  string      // output table name, empty if it is microdata measure
+	EntityName string      // entity name, empty if it is output table measure
+	Calculate  string      // calculation expression, ex.: Expr0 / OM_DIV_BY(Expr1) or OM_AVG(Income)
+	IsAggr     bool        // if true then it is output table accumulators aggregation
+	GroupBy    []string    // microdata group by attributes, used if group by is not specified in read layout
+	Txt        []DescrNote // measure label and notes in each language
+}
+
+// return name of profile where derived measures of the model are stored
+func derivedProfileName(modelDigest string) string {
+	return modelExtraProfileName(modelDigest, derivedModelExtra)
+}
+
+// GetDerivedMeasureList return list of derived measures of the model, sorted by measure name.
+func GetDerivedMeasureList(dbConn *sql.DB, modelDef *ModelMeta) ([]DerivedMeasure, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+
+	p, err := GetProfile(dbConn, derivedProfileName(modelDef.Model.Digest))
+	if err != nil {
+		return nil, err
+	}
+
+	dmLst := make([]DerivedMeasure, 0, len(p.Opts))
+
+	for key, val := range p.Opts {
+
+		var dm DerivedMeasure
+		if err = json.Unmarshal([]byte(val), &dm); err != nil {
+			return nil, errors.New("invalid derived measure: " + key + ": " + err.Error())
+		}
+		dm.Name = key
+		dmLst = append(dmLst, dm)
+	}
+	sort.Slice(dmLst, func(i, j int) bool { return dmLst[i].Name < dmLst[j].Name })
+
+	return dmLst, nil
+}
+
+// UpdateDerivedMeasure insert new or replace existing derived measure of the model.
+//
+// Measure name must be unique and cannot be the same as output table expression, accumulator or entity attribute name.
+// Measure calculation must be valid for output table or entity.
+func UpdateDerivedMeasure(dbConn *sql.DB, modelDef *ModelMeta, dm *DerivedMeasure) error {
+
+	// validate parameters
+	if modelDef == nil {
+		return errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if dm == nil {
+		return errors.New("invalid (empty) derived measure")
+	}
+	if dm.Name == "" {
+		return errors.New("invalid (empty) derived measure name")
+	}
+	for _, c := range dm.Name {
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return errors.New("invalid derived measure name, it must contain only letters, digits or _ underscore: " + dm.Name)
+		}
+	}
+	if strings.TrimSpace(dm.Calculate) == "" {
+		return errors.New("invalid (empty) derived measure calculation: " + dm.Name)
+	}
+	if dm.TableName == "" && dm.EntityName == "" || dm.TableName != "" && dm.EntityName != "" {
+		return errors.New("invalid derived measure, it must be output table or entity measure: " + dm.Name)
+	}
+
+	// measure name cannot be the same as expression, accumulator or attribute name
+	if dm.TableName != "" {
+		if k, ok := modelDef.OutTableByName(dm.TableName); ok {
+			for _, e := range modelDef.Table[k].Expr {
+				if e.Name == dm.Name {
+					return errors.New("invalid derived measure name, it is an output table expression name: " + dm.Name)
+				}
+			}
+			for _, a := range modelDef.Table[k].Acc {
+				if a.Name == dm.Name {
+					return errors.New("invalid derived measure name, it is an output table accumulator name: " + dm.Name)
+				}
+			}
+		}
+	} else {
+		if k, ok := modelDef.EntityByName(dm.EntityName); ok {
+			if _, ok = modelDef.Entity[k].AttrByName(dm.Name); ok {
+				return errors.New("invalid derived measure name, it is an entity attribute name: " + dm.Name)
+			}
+		}
+	}
+
+	// validate measure calculation
+	vl := ValidateCalcLayout{
+		Name:      dm.TableName,
+		IsMicro:   dm.EntityName != "",
+		Calculate: dm.Calculate,
+		IsAggr:    dm.IsAggr,
+		GroupBy:   dm.GroupBy,
+	}
+	if vl.IsMicro {
+		vl.Name = dm.EntityName
+	}
+	cv, err := ValidateCalculate(modelDef, &vl)
+	if err != nil {
+		return err
+	}
+	if !cv.IsValid {
+		msg := "invalid derived measure calculation: " + dm.Name + ": " + dm.Calculate
+		if len(cv.Error) > 0 {
+			msg += ": " + cv.Error[0].Msg
+		}
+		return errors.New(msg)
+	}
+
+	// store derived measure as profile option
+	if dm.GroupBy == nil {
+		dm.GroupBy = []string{}
+	}
+	if dm.Txt == nil {
+		dm.Txt = []DescrNote{}
+	}
+	js, err := json.Marshal(dm)
+	if err != nil {
+		return err
+	}
+	if len(js) > optionDbMax {
+		return errors.New("invalid derived measure, it is too long: " + dm.Name)
+	}
+
+	return UpdateProfileOption(dbConn, derivedProfileName(modelDef.Model.Digest), dm.Name, string(js))
+}
+
+// DeleteDerivedMeasure delete derived measure of the model by name.
+func DeleteDerivedMeasure(dbConn *sql.DB, modelDef *ModelMeta, name string) error {
+
+	// validate parameters
+	if modelDef == nil {
+		return errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if name == "" {
+		return errors.New("invalid (empty) derived measure name")
+	}
+
+	return DeleteProfileOption(dbConn, derivedProfileName(modelDef.Model.Digest), name)
+}
+
+// return copy of output table calculations where derived measure names replaced by measure calculation.
+// If calculation is an output table expression name or not a derived measure name then it is not changed.
+// If calculation name is empty then it is a derived measure name.
+func resolveDerivedTableCalc(dbConn *sql.DB, modelDef *ModelMeta, table *TableMeta, calcLt []CalculateTableLayout) ([]CalculateTableLayout, error) {
+
+	dmLst, err := GetDerivedMeasureList(dbConn, modelDef)
+	if err != nil {
+		return nil, err
+	}
+	cLt := append([]CalculateTableLayout{}, calcLt...)
+
+	for k := range cLt {
+
+		src := strings.TrimSpace(cLt[k].Calculate)
+
+		isExpr := false
+		for j := 0; !isExpr && j < len(table.Expr); j++ {
+			isExpr = table.Expr[j].Name == src
+		}
+		if isExpr {
+			continue // output table expression name
+		}
+
+		for j := range dmLst {
+			if dmLst[j].TableName == table.Name && dmLst[j].Name == src {
+				cLt[k].Calculate = dmLst[j].Calculate
+				cLt[k].IsAggr = dmLst[j].IsAggr
+				if cLt[k].Name == "" {
+					cLt[k].Name = dmLst[j].Name
+				}
+				break
+			}
+		}
+	}
+	return cLt, nil
+}
+
+// return copy of microdata calculations where derived measure names replaced by measure calculation.
+// If group by attributes are not specified then group by from first derived measure is used.
+func resolveDerivedMicroCalc(dbConn *sql.DB, modelDef *ModelMeta, entity *EntityMeta, calcLt *CalculateMicroLayout) (*CalculateMicroLayout, error) {
+
+	dmLst, err := GetDerivedMeasureList(dbConn, modelDef)
+	if err != nil {
+		return nil, err
+	}
+	cLt := *calcLt
+	cLt.Calculation = append([]CalculateLayout{}, calcLt.Calculation...)
+
+	for k := range cLt.Calculation {
+
+		src := strings.TrimSpace(cLt.Calculation[k].Calculate)
+
+		for j := range dmLst {
+			if dmLst[j].EntityName == entity.Name && dmLst[j].Name == src {
+				cLt.Calculation[k].Calculate = dmLst[j].Calculate
+				if cLt.Calculation[k].Name == "" {
+					cLt.Calculation[k].Name = dmLst[j].Name
+				}
+				if len(cLt.GroupBy) <= 0 {
+					cLt.GroupBy = dmLst[j].GroupBy
+				}
+				break
+			}
+		}
+	}
+	return &cLt, nil
+}
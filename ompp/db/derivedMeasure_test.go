@@ -0,0 +1,135 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDerivedMeasure(t *testing.T) {
+
+	dbConn, modelDef, langDef := createTestModel(t)
+
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, modelDef, langDef, "r1", &v)
+
+	// save derived measures: expression calculation and accumulators aggregation
+	dmLst := []DerivedMeasure{
+		{Name: "IncomeDbl", TableName: "incomeSex", Calculate: "expr0 * 2", Txt: []DescrNote{{LangCode: "EN", Descr: "Double income"}}},
+		{Name: "AccMax", TableName: "incomeSex", Calculate: "OM_MAX(acc0)", IsAggr: true},
+	}
+	for k := range dmLst {
+		if err := UpdateDerivedMeasure(dbConn.DB, modelDef, &dmLst[k]); err != nil {
+			t.Fatal("****FAIL: save derived measure:", dmLst[k].Name, err)
+		}
+	}
+
+	// replace existing measure
+	dmLst[0].Calculate = "expr0 * 3"
+	if err := UpdateDerivedMeasure(dbConn.DB, modelDef, &dmLst[0]); err != nil {
+		t.Fatal("****FAIL: update derived measure:", dmLst[0].Name, err)
+	}
+
+	// list of measures sorted by name
+	rLst, err := GetDerivedMeasureList(dbConn.DB, modelDef)
+	if err != nil {
+		t.Fatal("****FAIL: read derived measures:", err)
+	}
+	if len(rLst) != 2 || rLst[0].Name != "AccMax" || rLst[1].Name != "IncomeDbl" {
+		t.Fatal("****FAIL: expected derived measures: AccMax, IncomeDbl, found:", rLst)
+	}
+	if rLst[0].TableName != "incomeSex" || !rLst[0].IsAggr || rLst[0].Calculate != "OM_MAX(acc0)" {
+		t.Error("****FAIL: invalid derived measure:", rLst[0])
+	}
+	if rLst[1].Calculate != "expr0 * 3" || rLst[1].IsAggr || len(rLst[1].Txt) != 1 || rLst[1].Txt[0].Descr != "Double income" {
+		t.Error("****FAIL: invalid derived measure:", rLst[1])
+	}
+
+	// invalid measures are not saved
+	invalidLst := []DerivedMeasure{
+		{Name: "", TableName: "incomeSex", Calculate: "expr0 * 2"},
+		{Name: "bad name", TableName: "incomeSex", Calculate: "expr0 * 2"},
+		{Name: "expr1", TableName: "incomeSex", Calculate: "expr0 * 2"},
+		{Name: "acc0", TableName: "incomeSex", Calculate: "OM_SUM(acc0)", IsAggr: true},
+		{Name: "NoCalc", TableName: "incomeSex", Calculate: " "},
+		{Name: "NoTable", Calculate: "expr0 * 2"},
+		{Name: "TableAndEntity", TableName: "incomeSex", EntityName: "Person", Calculate: "expr0 * 2"},
+		{Name: "BadCalc", TableName: "incomeSex", Calculate: "exprNotExist * 2"},
+		{Name: "BadTable", TableName: "tableNotExist", Calculate: "expr0 * 2"},
+	}
+	for k := range invalidLst {
+		if err = UpdateDerivedMeasure(dbConn.DB, modelDef, &invalidLst[k]); err == nil {
+			t.Error("****FAIL: expected error on save invalid derived measure:", invalidLst[k])
+		}
+	}
+	if err = UpdateDerivedMeasure(dbConn.DB, nil, &dmLst[0]); err == nil {
+		t.Error("****FAIL: expected error on empty model metadata")
+	}
+
+	// read output table calculated by derived measure name and by output table expression name
+	calcLt := []CalculateTableLayout{
+		{CalculateLayout: CalculateLayout{Calculate: "IncomeDbl", CalcId: CALCULATED_ID_OFFSET}},
+		{CalculateLayout: CalculateLayout{Calculate: "AccMax", CalcId: CALCULATED_ID_OFFSET + 1}},
+		{CalculateLayout: CalculateLayout{Calculate: "expr1", CalcId: CALCULATED_ID_OFFSET + 2}},
+	}
+	expected := map[int][2]float64{
+		CALCULATED_ID_OFFSET:     {300, 600},
+		CALCULATED_ID_OFFSET + 1: {100, 200},
+		CALCULATED_ID_OFFSET + 2: {100, 200},
+	}
+	found := map[int][2]float64{}
+
+	_, err = ReadOutputTableCalculteTo(dbConn.DB, modelDef,
+		&ReadTableLayout{ReadLayout: ReadLayout{Name: "incomeSex", FromId: r1}},
+		calcLt,
+		nil,
+		func(src interface{}) (bool, error) {
+			c, ok := src.(CellTableCalc)
+			if !ok {
+				t.Fatal("****FAIL: invalid calculated cell type")
+			}
+			if c.IsNull || len(c.DimIds) != 1 {
+				t.Error("****FAIL: invalid calculated cell:", c)
+				return true, nil
+			}
+			fv, _ := c.Value.(float64)
+			r := found[c.CalcId]
+			r[c.DimIds[0]] = fv
+			found[c.CalcId] = r
+			return true, nil
+		})
+	if err != nil {
+		t.Fatal("****FAIL: read output table calculation:", err)
+	}
+	for id, ev := range expected {
+		fv := found[id]
+		if math.Abs(fv[0]-ev[0]) > 1.0e-9 || math.Abs(fv[1]-ev[1]) > 1.0e-9 {
+			t.Error("****FAIL: calculation id:", id, "expected:", ev, "found:", fv)
+		}
+	}
+
+	// delete measure
+	if err = DeleteDerivedMeasure(dbConn.DB, modelDef, "IncomeDbl"); err != nil {
+		t.Fatal("****FAIL: delete derived measure:", err)
+	}
+	if rLst, err = GetDerivedMeasureList(dbConn.DB, modelDef); err != nil {
+		t.Fatal("****FAIL: read derived measures:", err)
+	}
+	if len(rLst) != 1 || rLst[0].Name != "AccMax" {
+		t.Error("****FAIL: expected only AccMax derived measure, found:", rLst)
+	}
+
+	// derived measures deleted together with the model
+	if err = DeleteModel(dbConn.DB, modelDef.Model.ModelId); err != nil {
+		t.Fatal("****FAIL: delete model:", err)
+	}
+	p, err := GetProfile(dbConn.DB, derivedProfileName(modelDef.Model.Digest))
+	if err != nil {
+		t.Fatal("****FAIL: read derived measures profile:", err)
+	}
+	if len(p.Opts) != 0 {
+		t.Error("****FAIL: expected no derived measures after model delete, found:", p.Opts)
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// row of Go evaluation results: sort columns and result cell
+type evalResultRow struct {
+	cols []evalValue // run id, calculation id, dimensions or group by attributes and calculated value
+	cell interface{} // result cell: CellTableCalc or CellMicroCalc
+}
+
+// sort results of Go evaluation, select a page of rows and pass each row into cvtTo().
+//
+// Rows are sorted in the same way as sql ORDER BY does for calculation results:
+// by explicitly specified columns or by default: run_id, calc_id, dimensions or group by attributes.
+// Page of rows is selected in the same way as SelectToList() does, including full page mode.
+func writeEvalRows(rows []evalResultRow, readLt *ReadLayout, cvtTo func(src interface{}) (bool, error)) (*ReadPageLayout, error) {
+
+	// validate order by columns and sort result rows
+	nCol := 0
+	if len(rows) > 0 {
+		nCol = len(rows[0].cols)
+	}
+	orderBy := readLt.OrderBy
+
+	if len(orderBy) <= 0 { // default: order by all columns except of value
+		for k := 1; k < nCol; k++ {
+			orderBy = append(orderBy, OrderByColumn{IndexOne: k})
+		}
+	}
+	for _, ob := range orderBy {
+		if nCol > 0 && (ob.IndexOne < 1 || ob.IndexOne > nCol) {
+			return nil, errors.New("invalid order by column index: " + strconv.Itoa(ob.IndexOne))
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+
+		for _, ob := range orderBy {
+
+			a := rows[i].cols[ob.IndexOne-1]
+			b := rows[j].cols[ob.IndexOne-1]
+
+			// NULL is less than any value
+			if a.isNull == b.isNull && (a.isNull || a.val == b.val) {
+				continue
+			}
+			isLess := a.isNull || !b.isNull && a.val < b.val
+			if ob.IsDesc {
+				return !isLess
+			}
+			return isLess
+		}
+		return false
+	})
+
+	// adjust page layout: starting offset and page size
+	nStart := readLt.Offset
+	if nStart < 0 {
+		nStart = 0
+	}
+	nSize := readLt.Size
+	if nSize < 0 {
+		nSize = 0
+	}
+	nAll := int64(len(rows))
+
+	lt := ReadPageLayout{
+		Offset:     nStart,
+		Size:       0,
+		IsLastPage: nSize <= 0 || nAll <= nStart+nSize,
+		IsFullPage: nSize > 0 && readLt.IsFullPage,
+	}
+
+	// last row of the page and number of rows processed by sql select
+	nEnd := nAll
+	nRow := nAll
+	if nSize > 0 && nAll > nStart+nSize {
+		nEnd = nStart + nSize
+		nRow = nEnd + 1
+	}
+	nFirst := nStart
+	if lt.IsFullPage {
+		nFirst = max(0, nEnd-nSize)
+	}
+	if nFirst < nEnd {
+		lt.Size = nEnd - nFirst
+	}
+
+	// check for the empty result page, if this is a full page reading mode then adjust page start
+	if lt.Size <= 0 {
+		lt.Offset = nRow
+	}
+	if lt.IsFullPage {
+		lt.Offset = nFirst
+	}
+
+	// write page into output stream
+	for k := nFirst; k < nFirst+lt.Size; k++ {
+
+		isNext, err := cvtTo(rows[k].cell)
+		if err != nil {
+			return nil, err
+		}
+		if !isNext {
+			break
+		}
+	}
+
+	return &lt, nil
+}
+
+// return true if calculated value is matching filter by value, ex.: calc_value < 1234.
+// NULL value is not matching any filter.
+func isEvalValueFilter(flt *FilterColumn, v evalValue, msgParent string) (bool, error) {
+
+	// validate number of filter values
+	nFlt := len(flt.Values)
+	if nFlt <= 0 ||
+		nFlt != 1 && (flt.Op == EqOpFilter || flt.Op == NeOpFilter || flt.Op == GtOpFilter || flt.Op == GeOpFilter || flt.Op == LtOpFilter || flt.Op == LeOpFilter) ||
+		nFlt != 2 && flt.Op == BetweenOpFilter {
+		return false, errors.New("invalid number of arguments to filter " + msgParent + " " + flt.Name + ": " + strconv.Itoa(nFlt))
+	}
+
+	fv := make([]float64, nFlt)
+	for k := range flt.Values {
+		f, err := strconv.ParseFloat(strings.TrimSpace(flt.Values[k]), 64)
+		if err != nil {
+			return false, errors.New("invalid filter value, it must be a number: " + msgParent + " " + flt.Name + ": " + flt.Values[k])
+		}
+		fv[k] = f
+	}
+	if v.isNull {
+		return false, nil
+	}
+
+	switch flt.Op {
+	case EqOpFilter:
+		return v.val == fv[0], nil
+	case NeOpFilter:
+		return v.val != fv[0], nil
+	case GtOpFilter:
+		return v.val > fv[0], nil
+	case GeOpFilter:
+		return v.val >= fv[0], nil
+	case LtOpFilter:
+		return v.val < fv[0], nil
+	case LeOpFilter:
+		return v.val <= fv[0], nil
+	case InOpFilter, InAutoOpFilter:
+		for _, f := range fv {
+			if v.val == f {
+				return true, nil
+			}
+		}
+		return false, nil
+	case BetweenOpFilter:
+		return fv[0] <= v.val && v.val <= fv[1], nil
+	}
+	return false, errors.New("invalid filter operation to read " + msgParent + " " + flt.Name)
+}
+
+// convert source value into evaluation value: float, integer and boolean values are supported, anything else is NULL
+func toEvalValue(src interface{}) evalValue {
+
+	switch v := src.(type) {
+	case nil:
+		return evalValue{isNull: true}
+	case float64:
+		return evalFinite(v)
+	case float32:
+		return evalFinite(float64(v))
+	case bool:
+		return evalBool(v)
+	}
+	if i, ok := helper.ToIntValue(src); ok {
+		return evalValue{val: float64(i)}
+	}
+	return evalValue{isNull: true}
+}
+
+// source of scalar parameter values for Go evaluation: param.Name value of model run is an average of all sub-values
+type evalParamSource struct {
+	dbConn   *sql.DB                      // database connection
+	modelDef *ModelMeta                   // model metadata
+	vals     map[string]map[int]evalValue // map parameter name to model run values, map run id to value
+}
+
+// return values of parameters for model run, parameter values selected from database only once
+func (ps *evalParamSource) values(names []string, runId int) ([]evalValue, error) {
+
+	if ps.vals == nil {
+		ps.vals = map[string]map[int]evalValue{}
+	}
+	r := make([]evalValue, len(names))
+
+	for k, name := range names {
+
+		if v, ok := ps.vals[name][runId]; ok {
+			r[k] = v
+			continue
+		}
+
+		// parameter must be a numeric scalar: rank zero, float or integer type
+		pIdx, ok := ps.modelDef.ParamByName(strings.TrimPrefix(name, "param."))
+		if !ok {
+			return nil, errors.New("parameter not found: " + name)
+		}
+		param := &ps.modelDef.Param[pIdx]
+		if param.Rank != 0 || !param.typeOf.IsFloat() && !param.typeOf.IsInt() {
+			return nil, errors.New("parameter must be a numeric scalar: " + name)
+		}
+
+		// average of not NULL sub-values, NULL if there are no values
+		sum := 0.0
+		n := 0
+
+		lt := ReadParamLayout{ReadLayout: ReadLayout{Name: param.Name, FromId: runId}}
+
+		_, err := ReadParameterTo(ps.dbConn, ps.modelDef, &lt, func(c interface{}) (bool, error) {
+
+			cp, ok := c.(CellParam)
+			if !ok {
+				return false, errors.New("invalid type, expected: parameter cell (internal error)")
+			}
+			if !cp.IsNull {
+				if v := toEvalValue(cp.Value); !v.isNull {
+					sum += v.val
+					n++
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		v := evalValue{isNull: true}
+		if n > 0 {
+			v = evalFinite(sum / float64(n))
+		}
+		if _, ok := ps.vals[name]; !ok {
+			ps.vals[name] = map[int]evalValue{}
+		}
+		ps.vals[name][runId] = v
+		r[k] = v
+	}
+	return r, nil
+}
+
+// return source rows with parameter values appended to each row, source rows are not modified
+func appendEvalParams(rows [][]evalValue, pv []evalValue) [][]evalValue {
+
+	if len(pv) <= 0 {
+		return rows
+	}
+	pRows := make([][]evalValue, len(rows))
+	for k := range rows {
+		pRows[k] = append(slices.Clone(rows[k]), pv...)
+	}
+	return pRows
+}
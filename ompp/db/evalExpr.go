@@ -0,0 +1,988 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Go evaluation of output table and microdata calculations, it is an alternative to sql translation.
+//
+// Calculation expression is parsed into a tree of nodes and evaluated for a group of source rows.
+// Each source row is a list of values: output table expressions, accumulators or entity attributes,
+// values of scalar parameters param.Name are appended at the end of the row.
+// Aggregation function is evaluated over all rows of the group, ex.: OM_AVG(acc0),
+// nested aggregation is evaluated over the same rows, ex.: OM_SUM(acc0 - 0.5 * OM_AVG(acc1)).
+// Simple expression without aggregation is evaluated for a single source row, ex.: Expr0[variant] - Expr0[base].
+//
+// All arithmetic is done in double precision and result is the same for any database vendor.
+// SQL NULL is supported: arithmetic with NULL is NULL, aggregation functions skip NULL values.
+
+// kind of calculation expression node
+type evalKind int
+
+const (
+	numEval    evalKind = iota // number: 12 or 1.5
+	nullEval                   // NULL
+	nameEval                   // name of output table expression, accumulator, entity attribute or parameter: Expr0, acc1, Income[base], param.Extra
+	unaryEval                  // unary operator: - + NOT
+	binaryEval                 // binary operator: + - * / % = <> < <= > >= AND OR
+	isNullEval                 // IS NULL or IS NOT NULL
+	caseEval                   // CASE WHEN c1 THEN v1 WHEN c2 THEN v2 ELSE v3 END or OM_IF(c THEN v1 ELSE v2)
+	fncEval                    // non-aggregation function: OM_DIV_BY ABS SQRT...
+	aggrEval                   // aggregation function: OM_AVG OM_SUM OM_SD...
+)
+
+// node of calculation expression
+type evalNode struct {
+	kind    evalKind    // node kind: number, name, operator, function
+	op      string      // operator or function name: + <= AND OM_AVG ABS, IS NULL, IS NOT NULL
+	num     float64     // number value
+	name    string      // name of expression, accumulator, attribute or parameter: param.Name
+	suffix  string      // name suffix: base or variant or empty
+	isParam bool        // if true then name is a parameter: param.Name
+	args    []*evalNode // operands or function arguments, CASE: condition and value pairs and optional ELSE value
+	idx     int         // name: index of source value in the row, aggregation: index of aggregation in expression
+}
+
+// evaluation value: double value or NULL
+type evalValue struct {
+	val    float64 // value
+	isNull bool    // if true then value is NULL
+}
+
+// parsed calculation expression
+type evalExpr struct {
+	src       string    // source expression
+	root      *evalNode // top node of expression tree
+	aggrCount int       // number of aggregation functions in expression
+	isCompare bool      // if true then it is a run comparison: names used with [base] and [variant]
+	params    []string  // names of parameters in order of first use: param.Name
+}
+
+// group of source rows to evaluate calculation expression
+type evalGroup struct {
+	rows     [][]evalValue // source rows: values of expressions, accumulators or attributes
+	aggr     []evalValue   // values of aggregation functions, evaluated over all rows of the group
+	isAggrOk []bool        // if true then aggregation already evaluated
+}
+
+// aggregation functions and number of arguments
+var evalAggrArgCount = map[string]int{
+	"OM_AVG": 1, "OM_SUM": 1, "OM_COUNT": 1, "OM_COUNT_IF": 1, "OM_MIN": 1, "OM_MAX": 1,
+	"OM_VAR": 1, "OM_SD": 1, "OM_SE": 1, "OM_CV": 1,
+	"OM_MEDIAN": 1, "OM_PERCENTILE": 2, "OM_WAVG": 2, "OM_WSUM": 2,
+}
+
+// non-aggregation functions and number of arguments, OM_IF is translated into CASE node
+var evalFncArgCount = map[string]int{
+	"OM_DIV_BY": 1, "ABS": 1, "SQRT": 1, "EXP": 1, "LN": 1, "LOG10": 1, "POWER": 2,
+	"FLOOR": 1, "CEIL": 1, "CEILING": 1, "ROUND": 1, "SIGN": 1, "MOD": 2,
+}
+
+// calculation expression parser state
+type evalParser struct {
+	tl        []calcToken // expression tokens
+	n         int         // current token index
+	aggrCount int         // number of aggregation functions found
+}
+
+// parse calculation expression for Go evaluation.
+// Names of output table expressions, accumulators or attributes are not resolved, it must be done by bindNames().
+// Parameter param.Name must be a numeric scalar, value is an average of parameter sub-values.
+// Other output tables table.Name.Expr0 are not supported by Go evaluation.
+func parseEvalExpr(src string) (*evalExpr, error) {
+
+	expr := cleanSourceExpr(src)
+	if strings.TrimSpace(expr) == "" {
+		return nil, errors.New("invalid (empty) calculation expression")
+	}
+
+	tl, eLst := tokenizeCalc(expr)
+	if len(eLst) > 0 {
+		return nil, errors.New("error at position " + strconv.Itoa(eLst[0].Pos) + ": " + eLst[0].Msg + ": " + src)
+	}
+	if len(tl) <= 0 {
+		return nil, errors.New("invalid (empty) calculation expression")
+	}
+
+	p := evalParser{tl: tl}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, errors.New(err.Error() + ": " + src)
+	}
+	if p.n < len(p.tl) {
+		return nil, errors.New("unexpected " + p.tl[p.n].text + " at position " + strconv.Itoa(p.tl[p.n].pos) + ": " + src)
+	}
+
+	ex := &evalExpr{src: src, root: root, aggrCount: p.aggrCount}
+
+	// names must be either name[base] and name[variant] or simple names, it cannot be mixed
+	// at least one name must be not a parameter
+	isSimple := false
+	isBase := false
+	isVar := false
+	isSrc := false
+
+	walkEvalNodes(root, func(n *evalNode) {
+		if n.kind == nameEval {
+			if !n.isParam {
+				isSrc = true
+			} else if !slices.Contains(ex.params, n.name) {
+				ex.params = append(ex.params, n.name)
+			}
+			switch n.suffix {
+			case "base":
+				isBase = true
+			case "variant":
+				isVar = true
+			default:
+				isSimple = true
+			}
+		}
+	})
+	if isSimple && (isBase || isVar) || isBase != isVar {
+		return nil, errors.New("invalid (or mixed forms) of names used in: " + src)
+	}
+	if !isSrc {
+		return nil, errors.New("expression, accumulator or attribute name not found in: " + src)
+	}
+	ex.isCompare = isBase && isVar
+
+	return ex, nil
+}
+
+// call fn() for each node of expression tree
+func walkEvalNodes(n *evalNode, fn func(n *evalNode)) {
+	fn(n)
+	for _, a := range n.args {
+		walkEvalNodes(a, fn)
+	}
+}
+
+// resolve names into index of source value in the row.
+// If isAggr is true then aggregation function is required and all names must be inside of aggregation function
+// else aggregation functions are not allowed.
+// Parameters can be used outside of aggregation function, parameter values are at the end of the row:
+// after nSrc source values or for run comparison after [base] and [variant] source values,
+// [base] parameters followed by [variant] parameters.
+func (ex *evalExpr) bindNames(isAggr bool, nSrc int, nameIdx func(name, suffix string) (int, error)) error {
+
+	if isAggr && ex.aggrCount <= 0 {
+		return errors.New("aggregation function not found, ex.: OM_AVG: " + ex.src)
+	}
+	if !isAggr && ex.aggrCount > 0 {
+		return errors.New("aggregation function is not allowed in output table expression calculation: " + ex.src)
+	}
+
+	var bind func(n *evalNode, isInAggr bool) error
+
+	bind = func(n *evalNode, isInAggr bool) error {
+
+		if n.kind == nameEval && n.isParam {
+			n.idx = slices.Index(ex.params, n.name)
+			switch {
+			case !ex.isCompare:
+				n.idx += nSrc
+			case n.suffix == "variant":
+				n.idx += 2*nSrc + len(ex.params)
+			default:
+				n.idx += 2 * nSrc
+			}
+			return nil
+		}
+		if n.kind == nameEval {
+			if isAggr && !isInAggr {
+				return errors.New("name must be used inside of aggregation function: " + n.name + " : " + ex.src)
+			}
+			k, err := nameIdx(n.name, n.suffix)
+			if err != nil {
+				return errors.New(err.Error() + " : " + ex.src)
+			}
+			n.idx = k
+			return nil
+		}
+		for _, a := range n.args {
+			if err := bind(a, isInAggr || n.kind == aggrEval); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return bind(ex.root, false)
+}
+
+// evaluate expression for a group of source rows, group must have at least one row
+func (ex *evalExpr) evalGroup(rows [][]evalValue) evalValue {
+
+	g := evalGroup{
+		rows:     rows,
+		aggr:     make([]evalValue, ex.aggrCount),
+		isAggrOk: make([]bool, ex.aggrCount),
+	}
+	return g.eval(ex.root, 0)
+}
+
+// evaluate expression for a single source row
+func (ex *evalExpr) evalRow(row []evalValue) evalValue {
+	return ex.evalGroup([][]evalValue{row})
+}
+
+// return true if value is not NULL and not zero
+func (v evalValue) isTrue() bool {
+	return !v.isNull && v.val != 0
+}
+
+// return 1 or 0 value from bool
+func evalBool(is bool) evalValue {
+	if is {
+		return evalValue{val: 1}
+	}
+	return evalValue{val: 0}
+}
+
+// return value or NULL if value is not a finite number
+func evalFinite(v float64) evalValue {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return evalValue{isNull: true}
+	}
+	return evalValue{val: v}
+}
+
+// evaluate node for source row r
+func (g *evalGroup) eval(n *evalNode, r int) evalValue {
+
+	switch n.kind {
+	case numEval:
+		return evalValue{val: n.num}
+
+	case nullEval:
+		return evalValue{isNull: true}
+
+	case nameEval:
+		return g.rows[r][n.idx]
+
+	case unaryEval:
+		v := g.eval(n.args[0], r)
+		if v.isNull {
+			return v
+		}
+		switch n.op {
+		case "-":
+			return evalValue{val: -v.val}
+		case "NOT":
+			return evalBool(v.val == 0)
+		}
+		return v
+
+	case isNullEval:
+		v := g.eval(n.args[0], r)
+		return evalBool(v.isNull == (n.op == "IS NULL"))
+
+	case binaryEval:
+		return g.evalBinary(n, r)
+
+	case caseEval:
+		k := 0
+		for ; k+1 < len(n.args); k += 2 {
+			if g.eval(n.args[k], r).isTrue() {
+				return g.eval(n.args[k+1], r)
+			}
+		}
+		if k < len(n.args) {
+			return g.eval(n.args[k], r) // ELSE value
+		}
+		return evalValue{isNull: true}
+
+	case fncEval:
+		return g.evalFnc(n, r)
+
+	case aggrEval:
+		if !g.isAggrOk[n.idx] {
+			g.aggr[n.idx] = g.evalAggr(n)
+			g.isAggrOk[n.idx] = true
+		}
+		return g.aggr[n.idx]
+	}
+	return evalValue{isNull: true}
+}
+
+// evaluate binary operator for source row r
+func (g *evalGroup) evalBinary(n *evalNode, r int) evalValue {
+
+	a := g.eval(n.args[0], r)
+
+	// logical operators: SQL three-valued logic
+	switch n.op {
+	case "AND":
+		if !a.isNull && a.val == 0 {
+			return evalBool(false)
+		}
+		b := g.eval(n.args[1], r)
+		if !b.isNull && b.val == 0 {
+			return evalBool(false)
+		}
+		if a.isNull || b.isNull {
+			return evalValue{isNull: true}
+		}
+		return evalBool(true)
+
+	case "OR":
+		if a.isTrue() {
+			return evalBool(true)
+		}
+		b := g.eval(n.args[1], r)
+		if b.isTrue() {
+			return evalBool(true)
+		}
+		if a.isNull || b.isNull {
+			return evalValue{isNull: true}
+		}
+		return evalBool(false)
+	}
+
+	b := g.eval(n.args[1], r)
+	if a.isNull || b.isNull {
+		return evalValue{isNull: true}
+	}
+
+	switch n.op {
+	case "+":
+		return evalFinite(a.val + b.val)
+	case "-":
+		return evalFinite(a.val - b.val)
+	case "*":
+		return evalFinite(a.val * b.val)
+	case "/":
+		if b.val == 0 {
+			return evalValue{isNull: true}
+		}
+		return evalFinite(a.val / b.val)
+	case "%":
+		if b.val == 0 {
+			return evalValue{isNull: true}
+		}
+		return evalFinite(math.Mod(a.val, b.val))
+	case "=":
+		return evalBool(a.val == b.val)
+	case "<>":
+		return evalBool(a.val != b.val)
+	case "<":
+		return evalBool(a.val < b.val)
+	case "<=":
+		return evalBool(a.val <= b.val)
+	case ">":
+		return evalBool(a.val > b.val)
+	case ">=":
+		return evalBool(a.val >= b.val)
+	}
+	return evalValue{isNull: true}
+}
+
+// evaluate non-aggregation function for source row r
+func (g *evalGroup) evalFnc(n *evalNode, r int) evalValue {
+
+	args := make([]evalValue, len(n.args))
+	for k := range n.args {
+		args[k] = g.eval(n.args[k], r)
+		if args[k].isNull {
+			return evalValue{isNull: true}
+		}
+	}
+	x := args[0].val
+
+	switch n.op {
+	case "OM_DIV_BY":
+		if math.Abs(x) > 1.0e-37 {
+			return args[0]
+		}
+		return evalValue{isNull: true}
+	case "ABS":
+		return evalValue{val: math.Abs(x)}
+	case "SQRT":
+		if x < 0 {
+			return evalValue{isNull: true}
+		}
+		return evalValue{val: math.Sqrt(x)}
+	case "EXP":
+		return evalFinite(math.Exp(x))
+	case "LN":
+		if x <= 0 {
+			return evalValue{isNull: true}
+		}
+		return evalValue{val: math.Log(x)}
+	case "LOG10":
+		if x <= 0 {
+			return evalValue{isNull: true}
+		}
+		return evalValue{val: math.Log10(x)}
+	case "POWER":
+		return evalFinite(math.Pow(x, args[1].val))
+	case "FLOOR":
+		return evalValue{val: math.Floor(x)}
+	case "CEIL", "CEILING":
+		return evalValue{val: math.Ceil(x)}
+	case "ROUND":
+		if len(args) > 1 {
+			p := math.Pow(10, math.Trunc(args[1].val))
+			return evalFinite(math.Round(x*p) / p)
+		}
+		return evalValue{val: math.Round(x)}
+	case "SIGN":
+		switch {
+		case x > 0:
+			return evalValue{val: 1}
+		case x < 0:
+			return evalValue{val: -1}
+		}
+		return evalValue{val: 0}
+	case "MOD":
+		if args[1].val == 0 {
+			return evalValue{isNull: true}
+		}
+		return evalFinite(math.Mod(x, args[1].val))
+	}
+	return evalValue{isNull: true}
+}
+
+// evaluate aggregation function over all rows of the group, NULL values are skipped.
+//
+// OM_VAR is a sample variance: SUM((arg - OM_AVG(arg)) * (arg - OM_AVG(arg))) / (OM_COUNT(arg) - 1)
+// OM_PERCENTILE is continuous percentile with linear interpolation between two adjacent values.
+func (g *evalGroup) evalAggr(n *evalNode) evalValue {
+
+	// weighted aggregation: sum of value * weight and sum of weights
+	if n.op == "OM_WSUM" || n.op == "OM_WAVG" {
+
+		nXw := 0
+		sXw := 0.0
+		nW := 0
+		sW := 0.0
+		for r := range g.rows {
+			x := g.eval(n.args[0], r)
+			w := g.eval(n.args[1], r)
+			if !w.isNull {
+				nW++
+				sW += w.val
+			}
+			if !x.isNull && !w.isNull {
+				nXw++
+				sXw += x.val * w.val
+			}
+		}
+		if nXw <= 0 {
+			return evalValue{isNull: true}
+		}
+		if n.op == "OM_WSUM" {
+			return evalFinite(sXw)
+		}
+		if nW <= 0 || math.Abs(sW) <= 1.0e-37 {
+			return evalValue{isNull: true}
+		}
+		return evalFinite(sXw / sW)
+	}
+
+	// count rows where condition is true
+	if n.op == "OM_COUNT_IF" {
+		nc := 0
+		for r := range g.rows {
+			if g.eval(n.args[0], r).isTrue() {
+				nc++
+			}
+		}
+		return evalValue{val: float64(nc)}
+	}
+
+	// collect not NULL values of the argument
+	xs := make([]float64, 0, len(g.rows))
+	for r := range g.rows {
+		if x := g.eval(n.args[0], r); !x.isNull {
+			xs = append(xs, x.val)
+		}
+	}
+	nx := len(xs)
+
+	if n.op == "OM_COUNT" {
+		return evalValue{val: float64(nx)}
+	}
+	if nx <= 0 {
+		return evalValue{isNull: true}
+	}
+
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	avg := sum / float64(nx)
+
+	// sample variance, it is NULL if there is only one value
+	variance := func() evalValue {
+		if nx <= 1 {
+			return evalValue{isNull: true}
+		}
+		s := 0.0
+		for _, x := range xs {
+			s += (x - avg) * (x - avg)
+		}
+		return evalFinite(s / float64(nx-1))
+	}
+
+	switch n.op {
+	case "OM_AVG":
+		return evalFinite(avg)
+	case "OM_SUM":
+		return evalFinite(sum)
+	case "OM_MIN":
+		return evalValue{val: slices.Min(xs)}
+	case "OM_MAX":
+		return evalValue{val: slices.Max(xs)}
+	case "OM_VAR":
+		return variance()
+	case "OM_SD":
+		if v := variance(); !v.isNull {
+			return evalFinite(math.Sqrt(v.val))
+		}
+	case "OM_SE":
+		if v := variance(); !v.isNull {
+			return evalFinite(math.Sqrt(v.val / float64(nx)))
+		}
+	case "OM_CV":
+		if v := variance(); !v.isNull && math.Abs(avg) > 1.0e-37 {
+			return evalFinite(100 * (math.Sqrt(v.val) / avg))
+		}
+	case "OM_MEDIAN":
+		return evalPercentile(xs, 0.5)
+	case "OM_PERCENTILE":
+		return evalPercentile(xs, n.args[1].num)
+	}
+	return evalValue{isNull: true}
+}
+
+// return continuous percentile p of values with linear interpolation between two adjacent values:
+// rank r = p * (count - 1), result is value[FLOOR(r)] + (value[CEIL(r)] - value[FLOOR(r)]) * (r - FLOOR(r))
+func evalPercentile(xs []float64, p float64) evalValue {
+
+	if len(xs) <= 0 {
+		return evalValue{isNull: true}
+	}
+	vals := slices.Clone(xs)
+	slices.Sort(vals)
+
+	r := p * float64(len(vals)-1)
+	lo := int(math.Floor(r))
+	hi := int(math.Ceil(r))
+
+	return evalFinite(vals[lo] + (vals[hi]-vals[lo])*(r-float64(lo)))
+}
+
+// return current token or nil if end of expression
+func (p *evalParser) token() *calcToken {
+	if p.n < len(p.tl) {
+		return &p.tl[p.n]
+	}
+	return nil
+}
+
+// return true if current token is SQL keyword, ex.: AND, THEN, NULL
+func (p *evalParser) isKeyword(word string) bool {
+	t := p.token()
+	return t != nil && t.kind == nameToken && t.suffix == "" && strings.EqualFold(t.text, word)
+}
+
+// if current token is SQL keyword then move to the next token and return true
+func (p *evalParser) nextIfKeyword(word string) bool {
+	if p.isKeyword(word) {
+		p.n++
+		return true
+	}
+	return false
+}
+
+// return error if current token is not expected SQL keyword
+func (p *evalParser) expectKeyword(word string) error {
+	if !p.nextIfKeyword(word) {
+		return p.errorAt("expected " + word)
+	}
+	return nil
+}
+
+// return error if current token is not expected kind, move to the next token
+func (p *evalParser) expect(kind calcTokenKind, text string) error {
+	if t := p.token(); t == nil || t.kind != kind {
+		return p.errorAt("expected " + text)
+	}
+	p.n++
+	return nil
+}
+
+// return error with position of current token
+func (p *evalParser) errorAt(msg string) error {
+	if t := p.token(); t != nil {
+		return errors.New(msg + ", found: " + t.text + " at position " + strconv.Itoa(t.pos))
+	}
+	return errors.New(msg + ", found end of expression")
+}
+
+// parse: expr OR expr
+func (p *evalParser) parseOr() (*evalNode, error) {
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.nextIfKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &evalNode{kind: binaryEval, op: "OR", args: []*evalNode{left, right}}
+	}
+	return left, nil
+}
+
+// parse: expr AND expr
+func (p *evalParser) parseAnd() (*evalNode, error) {
+
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.nextIfKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &evalNode{kind: binaryEval, op: "AND", args: []*evalNode{left, right}}
+	}
+	return left, nil
+}
+
+// parse: NOT expr
+func (p *evalParser) parseNot() (*evalNode, error) {
+
+	if p.nextIfKeyword("NOT") {
+		arg, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &evalNode{kind: unaryEval, op: "NOT", args: []*evalNode{arg}}, nil
+	}
+	return p.parseCompare()
+}
+
+// parse comparison: a < b, a IS NULL, a IS NOT NULL, a BETWEEN b AND c
+func (p *evalParser) parseCompare() (*evalNode, error) {
+
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if p.nextIfKeyword("IS") {
+			op := "IS NULL"
+			if p.nextIfKeyword("NOT") {
+				op = "IS NOT NULL"
+			}
+			if err = p.expectKeyword("NULL"); err != nil {
+				return nil, err
+			}
+			left = &evalNode{kind: isNullEval, op: op, args: []*evalNode{left}}
+			continue
+		}
+		if p.nextIfKeyword("BETWEEN") {
+			lo, err := p.parseAdd()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectKeyword("AND"); err != nil {
+				return nil, err
+			}
+			hi, err := p.parseAdd()
+			if err != nil {
+				return nil, err
+			}
+			left = &evalNode{kind: binaryEval, op: "AND", args: []*evalNode{
+				{kind: binaryEval, op: ">=", args: []*evalNode{left, lo}},
+				{kind: binaryEval, op: "<=", args: []*evalNode{left, hi}},
+			}}
+			continue
+		}
+
+		t := p.token()
+		if t == nil || t.kind != opToken {
+			return left, nil
+		}
+		op := t.text
+		switch op {
+		case "=", "==":
+			op = "="
+		case "<>", "!=":
+			op = "<>"
+		case "<", "<=", ">", ">=":
+		default:
+			return left, nil
+		}
+		p.n++
+
+		right, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		left = &evalNode{kind: binaryEval, op: op, args: []*evalNode{left, right}}
+	}
+}
+
+// parse: a + b, a - b
+func (p *evalParser) parseAdd() (*evalNode, error) {
+
+	left, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.token()
+		if t == nil || t.kind != opToken || t.text != "+" && t.text != "-" {
+			return left, nil
+		}
+		p.n++
+
+		right, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		left = &evalNode{kind: binaryEval, op: t.text, args: []*evalNode{left, right}}
+	}
+}
+
+// parse: a * b, a / b, a % b
+func (p *evalParser) parseMul() (*evalNode, error) {
+
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.token()
+		if t == nil || t.kind != opToken || t.text != "*" && t.text != "/" && t.text != "%" {
+			return left, nil
+		}
+		p.n++
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &evalNode{kind: binaryEval, op: t.text, args: []*evalNode{left, right}}
+	}
+}
+
+// parse: -a, +a
+func (p *evalParser) parseUnary() (*evalNode, error) {
+
+	if t := p.token(); t != nil && t.kind == opToken && (t.text == "-" || t.text == "+") {
+		p.n++
+		arg, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &evalNode{kind: unaryEval, op: t.text, args: []*evalNode{arg}}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parse: number, name, NULL, (expr), CASE expression or function call
+func (p *evalParser) parsePrimary() (*evalNode, error) {
+
+	t := p.token()
+	if t == nil {
+		return nil, p.errorAt("expected a name, number or (expression)")
+	}
+
+	switch t.kind {
+	case numberToken:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorAt("invalid number")
+		}
+		p.n++
+		return &evalNode{kind: numEval, num: f}, nil
+
+	case openToken:
+		p.n++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(closeToken, ")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case quotedToken:
+		return nil, p.errorAt("'quoted' string value is not supported by Go evaluation")
+
+	case fncToken:
+		return p.parseFnc()
+
+	case nameToken:
+
+		switch {
+		case p.nextIfKeyword("NULL"):
+			return &evalNode{kind: nullEval}, nil
+		case p.nextIfKeyword("TRUE"):
+			return &evalNode{kind: numEval, num: 1}, nil
+		case p.nextIfKeyword("FALSE"):
+			return &evalNode{kind: numEval, num: 0}, nil
+		case p.nextIfKeyword("CASE"):
+			return p.parseCase()
+		}
+		for _, kw := range calcSqlKeywords {
+			if strings.EqualFold(kw, t.text) {
+				return nil, p.errorAt("unexpected SQL keyword")
+			}
+		}
+		isParam := strings.HasPrefix(t.text, "param.")
+		if isParam && (len(t.text) <= len("param.") || strings.Contains(t.text[len("param."):], ".")) {
+			return nil, p.errorAt("invalid parameter name, expected: param.Name")
+		}
+		if !isParam && strings.Contains(t.text, ".") {
+			return nil, p.errorAt("expression of other output table is not supported by Go evaluation")
+		}
+		if t.suffix != "" && t.suffix != "base" && t.suffix != "variant" {
+			return nil, p.errorAt("invalid name suffix [" + t.suffix + "], expected: [base] or [variant]")
+		}
+		p.n++
+		return &evalNode{kind: nameEval, name: t.text, suffix: t.suffix, isParam: isParam}, nil
+	}
+	return nil, p.errorAt("expected a name, number or (expression)")
+}
+
+// parse CASE expression after CASE keyword: WHEN c1 THEN v1 WHEN c2 THEN v2 ELSE v3 END
+func (p *evalParser) parseCase() (*evalNode, error) {
+
+	n := &evalNode{kind: caseEval, op: "CASE"}
+
+	for p.nextIfKeyword("WHEN") {
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectKeyword("THEN"); err != nil {
+			return nil, err
+		}
+		v, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		n.args = append(n.args, c, v)
+	}
+	if len(n.args) <= 0 {
+		return nil, p.errorAt("expected WHEN")
+	}
+	if p.nextIfKeyword("ELSE") {
+		v, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		n.args = append(n.args, v)
+	}
+	if err := p.expectKeyword("END"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// parse function call: OM_AVG(acc0), OM_IF(acc0 > 1 THEN acc0 ELSE 1), ABS(acc0), OM_PERCENTILE(acc0, 0.25)
+func (p *evalParser) parseFnc() (*evalNode, error) {
+
+	t := p.token()
+	name := strings.ToUpper(t.text)
+	if t.suffix != "" {
+		return nil, p.errorAt("invalid use of [base] or [variant] after function name")
+	}
+	p.n++
+	if err := p.expect(openToken, "("); err != nil {
+		return nil, err
+	}
+
+	// OM_IF(condition THEN value ELSE value) is the same as CASE expression
+	if name == "OM_IF" {
+
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectKeyword("THEN"); err != nil {
+			return nil, err
+		}
+		v, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		n := &evalNode{kind: caseEval, op: name, args: []*evalNode{c, v}}
+
+		if p.nextIfKeyword("ELSE") {
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, e)
+		}
+		if err = p.expect(closeToken, ")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+
+	// function arguments: comma separated list of expressions
+	n := &evalNode{kind: fncEval, op: name}
+
+	nArg, isAggr := evalAggrArgCount[name]
+	if isAggr {
+		n.kind = aggrEval
+	} else {
+		var ok bool
+		if nArg, ok = evalFncArgCount[name]; !ok {
+			return nil, errors.New("function is not supported by Go evaluation: " + t.text)
+		}
+	}
+
+	if tc := p.token(); tc == nil || tc.kind != closeToken {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, a)
+
+			if tc := p.token(); tc == nil || tc.kind != commaToken {
+				break
+			}
+			p.n++
+		}
+	}
+	if err := p.expect(closeToken, ")"); err != nil {
+		return nil, err
+	}
+
+	if len(n.args) != nArg && !(name == "ROUND" && len(n.args) == 2) {
+		return nil, errors.New("invalid number of function arguments: " + t.text + ", expected: " + strconv.Itoa(nArg))
+	}
+	if name == "OM_PERCENTILE" {
+		if a := n.args[1]; a.kind != numEval || a.num < 0 || a.num > 1 {
+			return nil, errors.New("invalid percentile, it must be a number between 0 and 1: " + t.text)
+		}
+	}
+	if isAggr {
+		n.idx = p.aggrCount
+		p.aggrCount++
+	}
+	return n, nil
+}
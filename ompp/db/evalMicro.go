@@ -0,0 +1,461 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// EvalMicrodataCalculateTo aggregate microdata in Go and process each result row (run_id, calc_id, group by attributes, calc_value) by cvtTo().
+//
+// It is an alternative to ReadMicrodataCalculateTo() which translate aggregations into sql.
+// Microdata rows are read by ReadMicrodataTo() and aggregation evaluated in Go,
+// results are the same for any database vendor and it does not require any sql math functions.
+//
+// It can calculate multiple measure values using simple aggregation of attributes, ex.: OM_AVG(Income)
+// or on aggregate for run comparison, ex.: OM_AVG(Income[variant] - Income[base]).
+// Optional list of run id's can be supplied to read more than one run from microdata table.
+// Calculation can use scalar parameters, ex.: OM_AVG(Income * param.Extra) or OM_AVG(Income[variant] - param.Extra[base]).
+func EvalMicrodataCalculateTo(
+	dbConn *sql.DB, modelDef *ModelMeta, layout *ReadMicroLayout, calcLt *CalculateMicroLayout, runIds []int, cvtTo func(src interface{}) (bool, error),
+) (*ReadPageLayout, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if layout == nil {
+		return nil, errors.New("invalid (empty) microdata read layout")
+	}
+	if layout.Name == "" {
+		return nil, errors.New("invalid (empty) entity name")
+	}
+	if calcLt == nil || len(calcLt.Calculation) <= 0 {
+		return nil, errors.New("invalid (empty) microdata calculation layout: " + layout.Name)
+	}
+
+	// find entity by name
+	eIdx, ok := modelDef.EntityByName(layout.Name)
+	if !ok {
+		return nil, errors.New("entity not found: " + layout.Name)
+	}
+	entity := &modelDef.Entity[eIdx]
+
+	// replace derived measure names by measure calculation, group by can be specified by derived measure
+	calcLt, err := resolveDerivedMicroCalc(dbConn, modelDef, entity, calcLt)
+	if err != nil {
+		return nil, err
+	}
+	if len(calcLt.GroupBy) <= 0 {
+		return nil, errors.New("invalid (empty) microdata group by attributes: " + layout.Name)
+	}
+
+	// check if model run exist and model run completed
+	runRow, err := GetRun(dbConn, layout.FromId)
+	if err != nil {
+		return nil, err
+	}
+	if runRow == nil {
+		return nil, errors.New("model run not found, id: " + strconv.Itoa(layout.FromId))
+	}
+	if runRow.Status != DoneRunStatus {
+		return nil, errors.New("model run not completed successfully, id: " + strconv.Itoa(layout.FromId))
+	}
+
+	// find entity generation and generation attributes
+	egLst, err := GetEntityGenList(dbConn, layout.FromId)
+	if err != nil {
+		return nil, err
+	}
+	var entityGen *EntityGenMeta
+
+	for k := range egLst {
+		if egLst[k].GenDigest == layout.GenDigest {
+			entityGen = &egLst[k]
+			break
+		}
+	}
+	if entityGen == nil {
+		return nil, errors.New("model run does not contain entity generation: " + layout.GenDigest + " " + entity.Name + " in run, id: " + strconv.Itoa(layout.FromId))
+	}
+
+	// calculate break points of quantile bins from base run attribute values
+	if len(calcLt.Bins) > 0 {
+		if calcLt.Bins, err = resolveMicroBins(dbConn, entity, entityGen, calcLt.Bins, layout.FromId); err != nil {
+			return nil, err
+		}
+	}
+
+	// entity generation attributes in the order of microdata row
+	// find group by attributes and bin break points of binned group by attributes
+	attrs := make([]EntityAttrRow, len(entityGen.GenAttr))
+	grpIdx := []int{}
+	grpBins := [][]float64{}
+
+	for k, ga := range entityGen.GenAttr {
+
+		aIdx, ok := entity.AttrByKey(ga.AttrId)
+		if !ok {
+			return nil, errors.New("entity attribute not found by id: " + strconv.Itoa(ga.AttrId) + " " + entity.Name)
+		}
+		attrs[k] = entity.Attr[aIdx]
+
+		if !slices.Contains(calcLt.GroupBy, attrs[k].Name) {
+			continue
+		}
+
+		// group by attributes must boolean or not built-in, numeric attributes must be binned
+		var bp []float64
+
+		if n, isBin := findMicroBin(calcLt.Bins, attrs[k].Name); isBin {
+
+			if !attrs[k].typeOf.IsFloat() && !attrs[k].typeOf.IsInt() {
+				return nil, errors.New("Entity " + entity.Name + " bin attribute must be float or integer: " + attrs[k].Name)
+			}
+			if bp, err = microBinBreaks(&calcLt.Bins[n]); err != nil {
+				return nil, err
+			}
+		} else {
+			if attrs[k].typeOf.IsBuiltIn() && !attrs[k].typeOf.IsBool() {
+				return nil, errors.New("invalid type of entity group by attribute not found by: " + entity.Name + "." + attrs[k].Name + " : " + attrs[k].typeOf.Name)
+			}
+		}
+		grpIdx = append(grpIdx, k)
+		grpBins = append(grpBins, bp)
+	}
+
+	// check: all group by attributes must be found and each bin must be group by attribute
+	for _, name := range calcLt.GroupBy {
+
+		isFound := false
+		for k := 0; !isFound && k < len(grpIdx); k++ {
+			isFound = attrs[grpIdx[k]].Name == name
+		}
+		if !isFound {
+			return nil, errors.New("entity group by attribute not found by: " + entity.Name + "." + name)
+		}
+	}
+	for k := range calcLt.Bins {
+		if !slices.Contains(calcLt.GroupBy, calcLt.Bins[k].Name) {
+			return nil, errors.New("Entity " + entity.Name + " bin attribute is not a group by attribute: " + calcLt.Bins[k].Name)
+		}
+	}
+
+	// validate filter names: it must be name of group by attribute or name of calculated attribute
+	// filter by binned attribute is not supported
+	// filters by attributes applied to microdata rows, filters by calculated value applied to results
+	src := evalMicroSource{
+		dbConn:   dbConn,
+		modelDef: modelDef,
+		readLt:   ReadMicroLayout{ReadLayout: ReadLayout{Name: entity.Name}, GenDigest: layout.GenDigest},
+		rows:     map[int][]CellMicro{},
+		fltRows:  map[int][]CellMicro{},
+		params:   evalParamSource{dbConn: dbConn, modelDef: modelDef},
+	}
+
+	isGroupAttr := func(name string) bool {
+		for _, k := range grpIdx {
+			if attrs[k].Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	for k := range layout.Filter {
+
+		if _, ok := findMicroBin(calcLt.Bins, layout.Filter[k].Name); ok {
+			return nil, errors.New("Error: entity " + entity.Name + " filter by binned attribute is not supported: " + layout.Filter[k].Name)
+		}
+		isCalc := false
+		for j := 0; !isCalc && j < len(calcLt.Calculation); j++ {
+			isCalc = calcLt.Calculation[j].Name == layout.Filter[k].Name
+		}
+		if isCalc {
+			continue
+		}
+		if !isGroupAttr(layout.Filter[k].Name) {
+			return nil, errors.New("Error: entity " + entity.Name + " does not have group by attribute " + layout.Filter[k].Name)
+		}
+		src.readLt.Filter = append(src.readLt.Filter, layout.Filter[k])
+	}
+	for k := range layout.FilterById {
+
+		if _, ok := findMicroBin(calcLt.Bins, layout.FilterById[k].Name); ok {
+			return nil, errors.New("Error: entity " + entity.Name + " filter by binned attribute is not supported: " + layout.FilterById[k].Name)
+		}
+		if !isGroupAttr(layout.FilterById[k].Name) {
+			return nil, errors.New("Error: entity " + entity.Name + " does not have group by attribute " + layout.FilterById[k].Name)
+		}
+		src.readLt.FilterById = append(src.readLt.FilterById, layout.FilterById[k])
+	}
+
+	// attribute index by name, only float and integer attributes can be aggregated
+	// for run comparison [variant] attributes are after [base] attributes
+	attrIdx := func(name, suffix string) (int, error) {
+		for k := range attrs {
+			if attrs[k].Name != name {
+				continue
+			}
+			if !attrs[k].typeOf.IsFloat() && !attrs[k].typeOf.IsInt() {
+				return -1, errors.New("entity attribute must be float or integer: " + entity.Name + "." + name)
+			}
+			if isGroupAttr(name) {
+				if _, isBin := findMicroBin(calcLt.Bins, name); !isBin {
+					return -1, errors.New("entity group by attribute cannot be aggregated: " + entity.Name + "." + name)
+				}
+			}
+			if suffix == "variant" {
+				return len(attrs) + k, nil
+			}
+			return k, nil
+		}
+		return -1, errors.New("Entity " + entity.Name + " does not have attribute " + name)
+	}
+
+	// group by values of microdata row: attribute value or bin index of binned attribute
+	groupOf := func(c *CellMicro) []attrValue {
+
+		gv := make([]attrValue, len(grpIdx))
+
+		for k, n := range grpIdx {
+
+			a := c.Attr[n]
+			switch {
+			case a.IsNull || a.Value == nil:
+				gv[k] = attrValue{IsNull: true}
+			case grpBins[k] != nil:
+				v := toEvalValue(a.Value)
+				if v.isNull {
+					gv[k] = attrValue{IsNull: true}
+				} else {
+					gv[k] = attrValue{Value: int64(microBinIndex(grpBins[k], v.val))}
+				}
+			default:
+				gv[k] = a
+			}
+		}
+		return gv
+	}
+
+	// evaluate each calculation and apply filters by calculated value, skip duplicate calculations
+	rows := []evalResultRow{}
+
+	for k := range calcLt.Calculation {
+
+		isDup := false
+		for j := 0; !isDup && j < k; j++ {
+			isDup = calcLt.Calculation[j] == calcLt.Calculation[k]
+		}
+		if isDup {
+			continue
+		}
+		cl := &calcLt.Calculation[k]
+
+		ex, err := parseEvalExpr(cl.Calculate)
+		if err == nil {
+			err = ex.bindNames(true, len(attrs), attrIdx)
+		}
+		if err != nil {
+			return nil, errors.New("Error at " + entity.Name + " " + cl.Calculate + ": " + err.Error())
+		}
+
+		// groups of microdata rows for each run: key is run id and group by values
+		grpKeys := []string{}
+		grpRun := map[string]int{}
+		grpVal := map[string][]attrValue{}
+		grpRows := map[string][][]evalValue{}
+
+		addRow := func(runId int, c *CellMicro, r []evalValue) {
+
+			gv := groupOf(c)
+			sk := make([]string, 1+len(gv))
+			sk[0] = strconv.Itoa(runId)
+			for j := range gv {
+				if gv[j].IsNull {
+					sk[1+j] = "NULL"
+				} else {
+					ev := toEvalValue(gv[j].Value)
+					sk[1+j] = strconv.FormatFloat(ev.val, 'g', -1, 64)
+				}
+			}
+			key := strings.Join(sk, ",")
+
+			if _, ok := grpRun[key]; !ok {
+				grpKeys = append(grpKeys, key)
+				grpRun[key] = runId
+				grpVal[key] = gv
+			}
+			grpRows[key] = append(grpRows[key], r)
+		}
+
+		if ex.isCompare {
+
+			// run comparison: base run microdata joined with variant runs microdata by entity key, groups are variant run groups
+			bLst, err := src.read(layout.FromId, false)
+			if err != nil {
+				return nil, err
+			}
+			base := make(map[uint64][]evalValue, len(bLst))
+			for j := range bLst {
+				base[bLst[j].Key] = microEvalRow(&bLst[j])
+			}
+			bpv, err := src.params.values(ex.params, layout.FromId)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, rId := range runIds {
+
+				vLst, err := src.read(rId, true)
+				if err != nil {
+					return nil, err
+				}
+				vpv, err := src.params.values(ex.params, rId)
+				if err != nil {
+					return nil, err
+				}
+				for j := range vLst {
+					if b, ok := base[vLst[j].Key]; ok {
+						r := append(slices.Clone(b), microEvalRow(&vLst[j])...)
+						addRow(rId, &vLst[j], append(append(r, bpv...), vpv...))
+					}
+				}
+			}
+		} else {
+
+			// aggregation for each run: base run and all other runs
+			rIds := []int{layout.FromId}
+			for _, rId := range runIds {
+				if !slices.Contains(rIds, rId) {
+					rIds = append(rIds, rId)
+				}
+			}
+			for _, rId := range rIds {
+
+				cLst, err := src.read(rId, true)
+				if err != nil {
+					return nil, err
+				}
+				pv, err := src.params.values(ex.params, rId)
+				if err != nil {
+					return nil, err
+				}
+				for j := range cLst {
+					addRow(rId, &cLst[j], append(microEvalRow(&cLst[j]), pv...))
+				}
+			}
+		}
+
+		// aggregate each group and apply filters by calculated value
+		for _, key := range grpKeys {
+
+			v := ex.evalGroup(grpRows[key])
+
+			isOk := true
+			for j := 0; isOk && j < len(layout.Filter); j++ {
+				if layout.Filter[j].Name == cl.Name {
+					if isOk, err = isEvalValueFilter(&layout.Filter[j], v, "entity "+entity.Name); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if !isOk {
+				continue
+			}
+
+			gv := grpVal[key]
+			c := CellMicroCalc{Attr: make([]attrValue, len(gv)+1), CalcId: cl.CalcId, RunId: grpRun[key]}
+			copy(c.Attr, gv)
+			if v.isNull {
+				c.Attr[len(gv)] = attrValue{IsNull: true}
+			} else {
+				c.Attr[len(gv)] = attrValue{Value: v.val}
+			}
+
+			cols := make([]evalValue, 3+len(gv))
+			cols[0] = evalValue{val: float64(c.RunId)}
+			cols[1] = evalValue{val: float64(c.CalcId)}
+			for j := range gv {
+				if gv[j].IsNull {
+					cols[2+j] = evalValue{isNull: true}
+				} else {
+					cols[2+j] = toEvalValue(gv[j].Value)
+				}
+			}
+			cols[2+len(gv)] = v
+
+			rows = append(rows, evalResultRow{cols: cols, cell: c})
+		}
+	}
+
+	// order by: run_id, calculation id, group by attributes, write result page
+	return writeEvalRows(rows, &layout.ReadLayout, cvtTo)
+}
+
+// source of microdata rows for Go evaluation
+type evalMicroSource struct {
+	dbConn   *sql.DB             // database connection
+	modelDef *ModelMeta          // model metadata
+	readLt   ReadMicroLayout     // entity name, generation digest and attribute filters
+	rows     map[int][]CellMicro // microdata rows of model runs without attribute filters, map run id to rows
+	fltRows  map[int][]CellMicro // microdata rows of model runs with attribute filters, map run id to rows
+	params   evalParamSource     // parameter values of model runs
+}
+
+// return model run microdata rows, rows selected from database only once.
+// If isFilter is true then attribute filters applied.
+func (src *evalMicroSource) read(runId int, isFilter bool) ([]CellMicro, error) {
+
+	m := src.rows
+	if isFilter {
+		m = src.fltRows
+	}
+	if cLst, ok := m[runId]; ok {
+		return cLst, nil
+	}
+
+	lt := src.readLt
+	lt.FromId = runId
+	if !isFilter {
+		lt.Filter = nil
+		lt.FilterById = nil
+	}
+
+	cLst := []CellMicro{}
+
+	_, err := ReadMicrodataTo(src.dbConn, src.modelDef, &lt, func(c interface{}) (bool, error) {
+
+		cm, ok := c.(CellMicro)
+		if !ok {
+			return false, errors.New("invalid type, expected: microdata cell (internal error)")
+		}
+		cLst = append(cLst, cm)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m[runId] = cLst
+	return cLst, nil
+}
+
+// return values of microdata row attributes: float, integer and boolean values, anything else is NULL
+func microEvalRow(c *CellMicro) []evalValue {
+
+	r := make([]evalValue, len(c.Attr))
+	for k := range c.Attr {
+		if c.Attr[k].IsNull {
+			r[k] = evalValue{isNull: true}
+		} else {
+			r[k] = toEvalValue(c.Attr[k].Value)
+		}
+	}
+	return r
+}
@@ -0,0 +1,438 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// EvalOutputTableCalculteTo calculate output table measures in Go and process each result row by cvtTo().
+//
+// It is an alternative to ReadOutputTableCalculteTo() which translate calculations into sql.
+// Output table expressions or accumulators are read by ReadOutputTableTo() and calculation evaluated in Go,
+// results are the same for any database vendor and it does not require any sql math functions.
+// Results are rows of the same run id, calculation id, dimensions and value as ReadOutputTableCalculteTo() return.
+//
+// It can be a multiple runs comparison, ex.: Expr0[variant] - Expr0[base], where base run id is layout.FromId
+// or simple expression calculation, ex.: Expr0 + Expr1, where layout.FromId and runIds[] are merged.
+// If calculation IsAggr then accumulators aggregated across sub-values, ex.: OM_AVG(acc0).
+// If calculation IsRunAggr then expressions aggregated across model runs, ex.: OM_SD(Expr0), result returned as base run value.
+// Calculation can use scalar parameters, ex.: Expr0 * param.Extra, in run aggregation parameter value is a base run value.
+// Expressions of other output tables table.Name.Expr0 are not supported.
+func EvalOutputTableCalculteTo(
+	dbConn *sql.DB, modelDef *ModelMeta, layout *ReadTableLayout, calcLt []CalculateTableLayout, runIds []int, cvtTo func(src interface{}) (bool, error),
+) (*ReadPageLayout, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if layout == nil {
+		return nil, errors.New("invalid (empty) output table read layout")
+	}
+	if layout.Name == "" {
+		return nil, errors.New("invalid (empty) output table name")
+	}
+	if len(calcLt) <= 0 {
+		return nil, errors.New("invalid (empty) output table calculation expression(s): " + layout.Name)
+	}
+
+	// find output table id by name
+	var table *TableMeta
+	if k, ok := modelDef.OutTableByName(layout.Name); ok {
+		table = &modelDef.Table[k]
+	} else {
+		return nil, errors.New("output table not found: " + layout.Name)
+	}
+
+	// replace derived measure names by measure calculation
+	calcLt, err := resolveDerivedTableCalc(dbConn, modelDef, table, calcLt)
+	if err != nil {
+		return nil, err
+	}
+
+	// filter names must be name of dimension or name of calculated expression
+	// filters by dimension applied to source expressions and accumulators, filters by calculated value applied to results
+	src := evalTableSource{
+		dbConn:   dbConn,
+		modelDef: modelDef,
+		table:    table,
+		readLt:   ReadLayout{Name: table.Name, FilterById: layout.FilterById},
+		exprRows: map[int]*evalTableRows{},
+		accRows:  map[int]*evalTableRows{},
+		params:   evalParamSource{dbConn: dbConn, modelDef: modelDef},
+	}
+	for k := range layout.Filter {
+
+		isCalc := false
+		for j := 0; !isCalc && j < len(calcLt); j++ {
+			isCalc = calcLt[j].Name == layout.Filter[k].Name
+		}
+		if isCalc {
+			continue
+		}
+		isOk := false
+		for j := 0; !isOk && j < len(table.Dim); j++ {
+			isOk = table.Dim[j].Name == layout.Filter[k].Name
+		}
+		if !isOk {
+			return nil, errors.New("Error: output table " + table.Name + " does not have dimension " + layout.Filter[k].Name)
+		}
+		src.readLt.Filter = append(src.readLt.Filter, layout.Filter[k])
+	}
+
+	// evaluate each calculation and apply filters by calculated value, skip duplicate calculations
+	rows := []evalResultRow{}
+
+	for k := range calcLt {
+
+		isDup := false
+		for j := 0; !isDup && j < k; j++ {
+			isDup = calcLt[j] == calcLt[k]
+		}
+		if isDup {
+			continue
+		}
+
+		cLst, err := src.evalCalc(&calcLt[k], layout.FromId, runIds)
+		if err != nil {
+			return nil, errors.New("Error at " + table.Name + " " + calcLt[k].Calculate + ": " + err.Error())
+		}
+
+		for _, c := range cLst {
+
+			v := evalValue{val: c.Value.(float64), isNull: c.IsNull}
+
+			isOk := true
+			for j := 0; isOk && j < len(layout.Filter); j++ {
+				if layout.Filter[j].Name == calcLt[k].Name {
+					if isOk, err = isEvalValueFilter(&layout.Filter[j], v, "output table "+table.Name); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if !isOk {
+				continue
+			}
+
+			cols := make([]evalValue, 3+table.Rank)
+			cols[0] = evalValue{val: float64(c.RunId)}
+			cols[1] = evalValue{val: float64(c.CalcId)}
+			for j := range c.DimIds {
+				cols[2+j] = evalValue{val: float64(c.DimIds[j])}
+			}
+			cols[2+table.Rank] = v
+
+			rows = append(rows, evalResultRow{cols: cols, cell: c})
+		}
+	}
+
+	// order by: run_id, calculation id, dimensions, write result page
+	return writeEvalRows(rows, &layout.ReadLayout, cvtTo)
+}
+
+// source of output table values for Go evaluation: expressions and accumulators of model runs
+type evalTableSource struct {
+	dbConn   *sql.DB                // database connection
+	modelDef *ModelMeta             // model metadata
+	table    *TableMeta             // output table
+	readLt   ReadLayout             // output table name and dimension filters
+	exprRows map[int]*evalTableRows // expressions of model runs, map run id to rows
+	accRows  map[int]*evalTableRows // accumulators of model runs, map run id to rows
+	params   evalParamSource        // parameter values of model runs
+}
+
+// output table rows of single model run: expression values or accumulator values of all sub-values
+type evalTableRows struct {
+	keys []string                 // dimension keys in the order of select
+	dims map[string][]int         // map dimension key to dimension enum ids
+	rows map[string][][]evalValue // map dimension key to rows of values: single row of expressions or row of accumulators for each sub-value
+}
+
+// return dimension key: dimension enum ids joined by comma
+func evalDimKey(dimIds []int) string {
+
+	s := make([]string, len(dimIds))
+	for k := range dimIds {
+		s[k] = strconv.Itoa(dimIds[k])
+	}
+	return strings.Join(s, ",")
+}
+
+// return model run rows of output table expressions, rows selected from database only once
+func (src *evalTableSource) exprs(runId int) (*evalTableRows, error) {
+
+	if tr, ok := src.exprRows[runId]; ok {
+		return tr, nil
+	}
+	tr := &evalTableRows{dims: map[string][]int{}, rows: map[string][][]evalValue{}}
+	nExpr := len(src.table.Expr)
+
+	lt := ReadTableLayout{ReadLayout: src.readLt}
+	lt.FromId = runId
+
+	_, err := ReadOutputTableTo(src.dbConn, src.modelDef, &lt, func(c interface{}) (bool, error) {
+
+		ce, ok := c.(CellExpr)
+		if !ok {
+			return false, errors.New("invalid type, expected: output table expression cell (internal error)")
+		}
+		eIdx := -1
+		for k := 0; eIdx < 0 && k < nExpr; k++ {
+			if src.table.Expr[k].ExprId == ce.ExprId {
+				eIdx = k
+			}
+		}
+		if eIdx < 0 {
+			return false, errors.New("output table expression not found by id: " + strconv.Itoa(ce.ExprId))
+		}
+
+		key := evalDimKey(ce.DimIds)
+		if _, ok := tr.dims[key]; !ok {
+
+			r := make([]evalValue, nExpr)
+			for k := range r {
+				r[k].isNull = true
+			}
+			tr.keys = append(tr.keys, key)
+			tr.dims[key] = slices.Clone(ce.DimIds)
+			tr.rows[key] = [][]evalValue{r}
+		}
+		if !ce.IsNull {
+			tr.rows[key][0][eIdx] = toEvalValue(ce.Value)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	src.exprRows[runId] = tr
+	return tr, nil
+}
+
+// return model run rows of output table accumulators, rows selected from database only once
+func (src *evalTableSource) accs(runId int) (*evalTableRows, error) {
+
+	if tr, ok := src.accRows[runId]; ok {
+		return tr, nil
+	}
+	tr := &evalTableRows{dims: map[string][]int{}, rows: map[string][][]evalValue{}}
+
+	lt := ReadTableLayout{ReadLayout: src.readLt, IsAccum: true, IsAllAccum: true}
+	lt.FromId = runId
+
+	_, err := ReadOutputTableTo(src.dbConn, src.modelDef, &lt, func(c interface{}) (bool, error) {
+
+		ca, ok := c.(CellAllAcc)
+		if !ok {
+			return false, errors.New("invalid type, expected: output table accumulators cell (internal error)")
+		}
+
+		r := make([]evalValue, len(ca.Value))
+		for k := range ca.Value {
+			if ca.IsNull[k] {
+				r[k].isNull = true
+			} else {
+				r[k] = evalFinite(ca.Value[k])
+			}
+		}
+
+		key := evalDimKey(ca.DimIds)
+		if _, ok := tr.dims[key]; !ok {
+			tr.keys = append(tr.keys, key)
+			tr.dims[key] = slices.Clone(ca.DimIds)
+		}
+		tr.rows[key] = append(tr.rows[key], r)
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	src.accRows[runId] = tr
+	return tr, nil
+}
+
+// evaluate output table calculation and return result cells
+func (src *evalTableSource) evalCalc(calcLt *CalculateTableLayout, fromId int, runIds []int) ([]CellTableCalc, error) {
+
+	if calcLt.IsAggr && calcLt.IsRunAggr {
+		return nil, errors.New("calculation cannot be accumulators aggregation and model runs aggregation")
+	}
+
+	ex, err := parseEvalExpr(calcLt.Calculate)
+	if err != nil {
+		return nil, err
+	}
+	if (calcLt.IsAggr || calcLt.IsRunAggr) && ex.isCompare {
+		return nil, errors.New("aggregation cannot be a run comparison [base] or [variant]: " + calcLt.Calculate)
+	}
+	nExpr := len(src.table.Expr)
+
+	// merge base run id and run id's: base run id is first
+	rIds := []int{}
+	if fromId > 0 {
+		rIds = append(rIds, fromId)
+	}
+	for _, rId := range runIds {
+		if !slices.Contains(rIds, rId) {
+			rIds = append(rIds, rId)
+		}
+	}
+	if len(rIds) <= 0 {
+		return nil, errors.New("invalid (empty) list of model runs")
+	}
+
+	cells := []CellTableCalc{}
+
+	makeCell := func(runId int, dimIds []int, v evalValue) {
+		cells = append(cells, CellTableCalc{
+			cellIdValue: cellIdValue{DimIds: slices.Clone(dimIds), IsNull: v.isNull, Value: v.val},
+			CalcId:      calcLt.CalcId,
+			RunId:       runId,
+		})
+	}
+
+	// expression index by name, for run comparison [variant] expressions are after [base] expressions
+	exprIdx := func(name, suffix string) (int, error) {
+		for k := range src.table.Expr {
+			if src.table.Expr[k].Name == name {
+				if suffix == "variant" {
+					return nExpr + k, nil
+				}
+				return k, nil
+			}
+		}
+		return -1, errors.New("output table " + src.table.Name + " does not have expression: " + name)
+	}
+
+	switch {
+	case calcLt.IsAggr: // aggregation of accumulators across sub-values, ex.: OM_AVG(acc0)
+
+		err = ex.bindNames(true, len(src.table.Acc), func(name, _ string) (int, error) {
+			for k := range src.table.Acc {
+				if src.table.Acc[k].Name == name {
+					if src.table.Acc[k].IsDerived {
+						return -1, errors.New("only native accumulators can be aggregated: " + name)
+					}
+					return k, nil
+				}
+			}
+			return -1, errors.New("output table " + src.table.Name + " does not have accumulator: " + name)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rId := range rIds {
+
+			tr, err := src.accs(rId)
+			if err != nil {
+				return nil, err
+			}
+			pv, err := src.params.values(ex.params, rId)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range tr.keys {
+				makeCell(rId, tr.dims[key], ex.evalGroup(appendEvalParams(tr.rows[key], pv)))
+			}
+		}
+
+	case calcLt.IsRunAggr: // aggregation of expressions across model runs, ex.: OM_SD(Expr0), result is a base run value
+
+		if err = ex.bindNames(true, nExpr, exprIdx); err != nil {
+			return nil, err
+		}
+		pv, err := src.params.values(ex.params, rIds[0])
+		if err != nil {
+			return nil, err
+		}
+
+		keys := []string{}
+		dims := map[string][]int{}
+		runRows := map[string][][]evalValue{}
+
+		for _, rId := range rIds {
+
+			tr, err := src.exprs(rId)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range tr.keys {
+				if _, ok := dims[key]; !ok {
+					keys = append(keys, key)
+					dims[key] = tr.dims[key]
+				}
+				runRows[key] = append(runRows[key], tr.rows[key][0])
+			}
+		}
+		for _, key := range keys {
+			makeCell(rIds[0], dims[key], ex.evalGroup(appendEvalParams(runRows[key], pv)))
+		}
+
+	case ex.isCompare: // run comparison: variant runs joined with base run by dimensions, ex.: Expr0[variant] - Expr0[base]
+
+		if err = ex.bindNames(false, nExpr, exprIdx); err != nil {
+			return nil, err
+		}
+
+		base, err := src.exprs(fromId)
+		if err != nil {
+			return nil, err
+		}
+		bpv, err := src.params.values(ex.params, fromId)
+		if err != nil {
+			return nil, err
+		}
+		for _, rId := range runIds {
+
+			tr, err := src.exprs(rId)
+			if err != nil {
+				return nil, err
+			}
+			vpv, err := src.params.values(ex.params, rId)
+			if err != nil {
+				return nil, err
+			}
+			pv := append(slices.Clone(bpv), vpv...)
+
+			for _, key := range tr.keys {
+				if bRows, ok := base.rows[key]; ok {
+					r := append(slices.Clone(bRows[0]), tr.rows[key][0]...)
+					makeCell(rId, tr.dims[key], ex.evalRow(append(r, pv...)))
+				}
+			}
+		}
+
+	default: // simple expression calculation, ex.: Expr0 + Expr1
+
+		if err = ex.bindNames(false, nExpr, exprIdx); err != nil {
+			return nil, err
+		}
+
+		for _, rId := range rIds {
+
+			tr, err := src.exprs(rId)
+			if err != nil {
+				return nil, err
+			}
+			pv, err := src.params.values(ex.params, rId)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range tr.keys {
+				makeCell(rId, tr.dims[key], ex.evalRow(append(slices.Clone(tr.rows[key][0]), pv...)))
+			}
+		}
+	}
+
+	return cells, nil
+}
@@ -0,0 +1,512 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// ParamImportSource is a provenance of workset parameter imported from upstream model run.
+type ParamImportSource struct {
+	Name           string // downstream parameter name
+	FromModel      string // upstream model name
+	FromDigest     string // upstream model digest
+	FromName       string // upstream output table or parameter name
+	FromKind       string // kind of upstream source: table or parameter
+	FromValue      string // upstream table expression or accumulator name, empty if source is a parameter
+	IsSampleDim    bool   // if true then first parameter dimension is a sample dimension: upstream sub-value id
+	RunName        string // upstream model run name
+	RunDigest      string // upstream model run digest
+	ValueDigest    string // upstream output table or parameter value digest in that model run
+	SubCount       int    // number of sub-values of downstream parameter
+	ImportDateTime string // date-time of import
+}
+
+// source cell of upstream output table or parameter
+type importCell struct {
+	subId  int         // sub-value id
+	dimIds []int       // dimension items
+	isNull bool        // if true then value is NULL
+	value  interface{} // value
+}
+
+// GetWorksetImport return provenance of workset parameters imported from upstream model run.
+// Return empty list if workset was not created by import from upstream model.
+func GetWorksetImport(dbConn *sql.DB, modelDef *ModelMeta, setName string) ([]ParamImportSource, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+
+	val, err := getWorksetExtra(dbConn, modelDef, setName, importWorksetExtra)
+	if err != nil {
+		return nil, err
+	}
+	if val == "" {
+		return []ParamImportSource{}, nil
+	}
+
+	var isLst []ParamImportSource
+	if err = json.Unmarshal([]byte(val), &isLst); err != nil {
+		return nil, errors.New("invalid workset import provenance: " + setName + ": " + err.Error())
+	}
+	return isLst, nil
+}
+
+// ImportWorksetFromRun create new downstream model workset from upstream model run.
+//
+// Each downstream parameter which has import from upstream model (model_parameter_import row) is included in workset.
+// Parameter values are read from upstream model run output table or parameter, see ReadParamImport for details.
+// Workset is created as read-write and provenance of each imported parameter is stored and can be retrieved by GetWorksetImport.
+// It is an error if workset already exists or none of downstream parameters imported from upstream model.
+// If update action not nil then import of each parameter is saved in workset history.
+func ImportWorksetFromRun(
+	srcDb *sql.DB, srcModel *ModelMeta, run *RunRow, dstDb Dbc, dstModel *ModelMeta, dstLang *LangMeta, setName string, upd *WorksetUpdateAction,
+) ([]ParamImportSource, error) {
+
+	// validate parameters
+	if srcModel == nil || dstModel == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if run == nil {
+		return nil, errors.New("invalid (empty) upstream model run")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+	if run.ModelId != srcModel.Model.ModelId {
+		return nil, errors.New("model run " + run.Name + " " + run.RunDigest + " does not belong to model " + srcModel.Model.Name + " " + srcModel.Model.Digest)
+	}
+	if !IsRunCompleted(run.Status) {
+		return nil, errors.New("model run not completed: " + run.Name + " " + run.RunDigest)
+	}
+
+	// workset must not exist
+	wsRow, err := GetWorksetByName(dstDb.DB, dstModel.Model.ModelId, setName)
+	if err != nil {
+		return nil, err
+	}
+	if wsRow != nil {
+		return nil, errors.New("workset already exists: " + setName)
+	}
+
+	// find downstream parameters imported from upstream model
+	nameLst := []string{}
+	for k := range dstModel.Param {
+		for j := range dstModel.Param[k].Import {
+			if dstModel.Param[k].Import[j].FromModel == srcModel.Model.Name {
+				nameLst = append(nameLst, dstModel.Param[k].Name)
+				break
+			}
+		}
+	}
+	if len(nameLst) <= 0 {
+		return nil, errors.New("model " + dstModel.Model.Name + " does not have any parameters imported from " + srcModel.Model.Name)
+	}
+
+	// read all parameters before creating workset
+	cellLst := make([][]CellParam, len(nameLst))
+	isLst := make([]ParamImportSource, len(nameLst))
+
+	for k, name := range nameLst {
+
+		cLst, src, err := ReadParamImport(srcDb, srcModel, run, dstModel, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(cLst) <= 0 {
+			return nil, errors.New("parameter " + name + " import is empty, upstream: " + src.FromName + " of model run " + run.Name + " " + run.RunDigest)
+		}
+		cellLst[k] = cLst
+		isLst[k] = *src
+	}
+
+	// create empty workset and insert parameter values
+	pub := WorksetPub{
+		WorksetHdrPub: WorksetHdrPub{
+			ModelName:   dstModel.Model.Name,
+			ModelDigest: dstModel.Model.Digest,
+			Name:        setName,
+		},
+		Param: []ParamRunSetPub{},
+	}
+	ws, err := pub.FromPublic(dstDb.DB, dstModel)
+	if err != nil {
+		return nil, err
+	}
+	if err = ws.UpdateWorkset(dstDb.DB, dstModel, false, dstLang); err != nil {
+		return nil, err
+	}
+
+	for k := range nameLst {
+
+		cLst := cellLst[k]
+		n := 0
+		from := func() (interface{}, error) {
+			if n >= len(cLst) {
+				return nil, nil // end of data
+			}
+			n++
+			return cLst[n-1], nil
+		}
+		p := ParamRunSetPub{
+			ParamRunSetTxtPub: ParamRunSetTxtPub{Name: nameLst[k]},
+			SubCount:          isLst[k].SubCount,
+		}
+		if _, err = ws.UpdateWorksetParameterFrom(dstDb, dstModel, true, &p, dstLang, upd, from); err != nil {
+			return nil, err
+		}
+	}
+
+	// store provenance of imported parameters
+	js, err := json.Marshal(isLst)
+	if err != nil {
+		return nil, err
+	}
+	if len(js) > optionDbMax {
+		return nil, errors.New("invalid workset import provenance, it is too long: " + setName)
+	}
+	if err = updateWorksetExtra(dstDb.DB, dstModel, setName, importWorksetExtra, string(js)); err != nil {
+		return nil, err
+	}
+
+	srcLst := make([]WorksetParamSource, len(isLst))
+	for k := range isLst {
+		srcLst[k] = WorksetParamSource{
+			Name:      isLst[k].Name,
+			Kind:      "import",
+			RunName:   isLst[k].RunName,
+			RunDigest: isLst[k].RunDigest,
+			FromModel: isLst[k].FromModel,
+		}
+	}
+	if err = updateWorksetParamSource(dstDb.DB, dstModel, setName, srcLst); err != nil {
+		return nil, err
+	}
+
+	return isLst, nil
+}
+
+// ReadParamImport read downstream parameter values from upstream model run and return provenance of parameter values.
+//
+// Parameter import from upstream model is defined by model_parameter_import row: upstream output table or parameter name.
+// If upstream is an output table then values are from first table expression and total items of dimensions are skipped.
+// If parameter has a sample dimension then it is a first parameter dimension and values are from first table accumulator:
+// each sample dimension item is an upstream sub-value, first item is sub-value 0, second item is sub-value 1, etc.
+// Expression values of each sub-value are not stored in database, only average across all sub-values.
+// In order to import accumulator values at the same scale as expression values
+// first table expression must be an average of first native accumulator: OM_AVG(acc0), otherwise it is an error.
+// Sub-values which are beyond of sample dimension size are ignored.
+// Other parameter dimensions must be the same as upstream output table (or parameter) dimensions and items matched by enum codes.
+// If upstream is an output table then downstream parameter must be float or integer, values are rounded to nearest integer.
+func ReadParamImport(srcDb *sql.DB, srcModel *ModelMeta, run *RunRow, dstModel *ModelMeta, name string) ([]CellParam, *ParamImportSource, error) {
+
+	// validate parameters
+	if srcModel == nil || dstModel == nil {
+		return nil, nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if run == nil {
+		return nil, nil, errors.New("invalid (empty) upstream model run")
+	}
+
+	// find downstream parameter and import from upstream model
+	idx, ok := dstModel.ParamByName(name)
+	if !ok {
+		return nil, nil, errors.New("parameter not found: " + name)
+	}
+	param := &dstModel.Param[idx]
+
+	var imp *ParamImportRow
+	for k := range param.Import {
+		if param.Import[k].FromModel == srcModel.Model.Name {
+			imp = &param.Import[k]
+			break
+		}
+	}
+	if imp == nil {
+		return nil, nil, errors.New("parameter " + name + " is not imported from model " + srcModel.Model.Name)
+	}
+
+	src := ParamImportSource{
+		Name:           name,
+		FromModel:      srcModel.Model.Name,
+		FromDigest:     srcModel.Model.Digest,
+		FromName:       imp.FromName,
+		IsSampleDim:    imp.IsSampleDim,
+		RunName:        run.Name,
+		RunDigest:      run.RunDigest,
+		SubCount:       1,
+		ImportDateTime: helper.MakeDateTime(time.Now()),
+	}
+
+	// sample dimension is a first parameter dimension
+	nSample := 0
+	if imp.IsSampleDim {
+		nSample = 1
+		if param.Rank < 1 {
+			return nil, nil, errors.New("invalid parameter rank, sample dimension expected: " + name)
+		}
+	}
+
+	// upstream dimensions and converters from upstream item id to downstream item id
+	var srcDims []*TypeMeta
+	var totalIds []int // total item id of upstream dimension or -1 if dimension does not have total item
+	var srcHid int
+	isTable := false
+	valueOf := param.typeOf
+
+	if tIdx, ok := srcModel.OutTableByName(imp.FromName); ok {
+
+		table := &srcModel.Table[tIdx]
+		isTable = true
+		srcHid = table.TableHid
+		src.FromKind = "table"
+
+		for k := range table.Dim {
+			srcDims = append(srcDims, table.Dim[k].typeOf)
+			if table.Dim[k].IsTotal {
+				totalIds = append(totalIds, table.Dim[k].typeOf.TotalEnumId)
+			} else {
+				totalIds = append(totalIds, -1)
+			}
+		}
+		if !param.typeOf.IsFloat() && !param.typeOf.IsInt() {
+			return nil, nil, errors.New("invalid parameter type, expected float or integer to import from output table: " + name + ": " + param.typeOf.Name)
+		}
+		switch {
+		case imp.IsSampleDim && len(table.Acc) > 0 && len(table.Expr) > 0:
+			if !isExprAvgOfAcc(&table.Expr[0], &table.Acc[0]) {
+				return nil, nil, errors.New("cannot import sub-values of output table " + table.Name + " into parameter " + name +
+					", expected first expression " + table.Expr[0].Name + " to be an average of first accumulator: OM_AVG(" + table.Acc[0].Name + ")")
+			}
+			src.FromValue = table.Acc[0].Name
+		case !imp.IsSampleDim && len(table.Expr) > 0:
+			src.FromValue = table.Expr[0].Name
+		default:
+			return nil, nil, errors.New("output table does not have any expressions or accumulators: " + table.Name)
+		}
+
+	} else {
+
+		pIdx, ok := srcModel.ParamByName(imp.FromName)
+		if !ok {
+			return nil, nil, errors.New("output table or parameter " + imp.FromName + " not found in model " + srcModel.Model.Name + ", imported by: " + name)
+		}
+		sp := &srcModel.Param[pIdx]
+		srcHid = sp.ParamHid
+		src.FromKind = "parameter"
+
+		for k := range sp.Dim {
+			srcDims = append(srcDims, sp.Dim[k].typeOf)
+			totalIds = append(totalIds, -1)
+		}
+		if sp.typeOf.IsBuiltIn() != param.typeOf.IsBuiltIn() || sp.typeOf.IsBuiltIn() && sp.typeOf.Name != param.typeOf.Name {
+			return nil, nil, errors.New("invalid parameter type: " + name + ": " + param.typeOf.Name + ", expected: " + sp.typeOf.Name)
+		}
+		valueOf = sp.typeOf
+	}
+	if len(srcDims)+nSample != param.Rank {
+		return nil, nil, errors.New("invalid parameter rank: " + name + ": " + strconv.Itoa(param.Rank) + ", expected: " + strconv.Itoa(len(srcDims)+nSample))
+	}
+
+	// for each dimension convert from upstream item id to code and from code to downstream item id
+	fromId := make([]func(int) (string, error), len(srcDims))
+	toId := make([]func(string) (int, error), len(srcDims))
+
+	for k := range srcDims {
+
+		f, err := srcDims[k].itemIdToCode(imp.FromName+"."+strconv.Itoa(k), totalIds[k] >= 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		fromId[k] = f
+
+		t, err := param.Dim[k+nSample].typeOf.itemCodeToId(name+"."+param.Dim[k+nSample].Name, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		toId[k] = t
+	}
+
+	// if parameter value is enum-based then convert enum value from upstream id to downstream id
+	var valFromId func(int) (string, error)
+	var valToId func(string) (int, error)
+
+	if !param.typeOf.IsBuiltIn() {
+		f, err := valueOf.itemIdToCode(imp.FromName, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		t, err := param.typeOf.itemCodeToId(name, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		valFromId = f
+		valToId = t
+	}
+
+	// sample dimension items: item id by sub-value id
+	var sampleIds []int
+	if imp.IsSampleDim {
+		if codes, isEnum := typeEnumCodesAll(param.Dim[0].typeOf); isEnum {
+			cvt, err := param.Dim[0].typeOf.itemCodeToId(name+"."+param.Dim[0].Name, false)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, c := range codes {
+				id, err := cvt(c)
+				if err != nil {
+					return nil, nil, err
+				}
+				sampleIds = append(sampleIds, id)
+			}
+		}
+	}
+
+	// convert upstream cell into downstream parameter cell
+	cLst := []CellParam{}
+	subMax := 0
+
+	cvtCell := func(ic *importCell) error {
+
+		c := CellParam{cellIdValue: cellIdValue{DimIds: make([]int, param.Rank), IsNull: ic.isNull}}
+
+		for k := range ic.dimIds {
+			if totalIds[k] >= 0 && ic.dimIds[k] == totalIds[k] {
+				return nil // skip total item
+			}
+			code, err := fromId[k](ic.dimIds[k])
+			if err != nil {
+				return err
+			}
+			if c.DimIds[k+nSample], err = toId[k](code); err != nil {
+				return err
+			}
+		}
+
+		if imp.IsSampleDim {
+			switch {
+			case sampleIds == nil:
+				c.DimIds[0] = ic.subId // sample dimension is simple integer type
+			case ic.subId < len(sampleIds):
+				c.DimIds[0] = sampleIds[ic.subId]
+			default:
+				return nil // sub-value id is beyond of sample dimension size
+			}
+		} else {
+			c.SubId = ic.subId
+			if ic.subId > subMax {
+				subMax = ic.subId
+			}
+		}
+
+		// convert value to parameter type
+		switch {
+		case ic.isNull:
+		case isTable && param.typeOf.IsFloat():
+			fv, ok := valueToFloat(ic.value)
+			if !ok {
+				return errors.New("invalid output table value, expected float: " + imp.FromName)
+			}
+			c.Value = fv
+		case isTable:
+			fv, ok := valueToFloat(ic.value)
+			if !ok {
+				return errors.New("invalid output table value, expected float: " + imp.FromName)
+			}
+			c.Value = int64(math.Round(fv))
+		case valFromId != nil:
+			iv, ok := helper.ToIntValue(ic.value)
+			if !ok {
+				return errors.New("invalid parameter value type, expected: integer enum: " + imp.FromName)
+			}
+			code, err := valFromId(iv)
+			if err != nil {
+				return err
+			}
+			id, err := valToId(code)
+			if err != nil {
+				return err
+			}
+			c.Value = id
+		default:
+			c.Value = ic.value
+		}
+
+		cLst = append(cLst, c)
+		return nil
+	}
+
+	// read upstream output table expression or accumulator or parameter values
+	var err error
+
+	switch {
+	case isTable && imp.IsSampleDim:
+		tl := ReadTableLayout{ReadLayout: ReadLayout{Name: imp.FromName, FromId: run.RunId}, ValueName: src.FromValue, IsAccum: true}
+		_, err = ReadOutputTableTo(srcDb, srcModel, &tl, func(s interface{}) (bool, error) {
+			a, ok := s.(CellAcc)
+			if !ok {
+				return false, errors.New("invalid type, expected: output table accumulator cell (internal error): " + imp.FromName)
+			}
+			return true, cvtCell(&importCell{subId: a.SubId, dimIds: a.DimIds, isNull: a.IsNull, value: a.Value})
+		})
+	case isTable:
+		tl := ReadTableLayout{ReadLayout: ReadLayout{Name: imp.FromName, FromId: run.RunId}, ValueName: src.FromValue}
+		_, err = ReadOutputTableTo(srcDb, srcModel, &tl, func(s interface{}) (bool, error) {
+			e, ok := s.(CellExpr)
+			if !ok {
+				return false, errors.New("invalid type, expected: output table expression cell (internal error): " + imp.FromName)
+			}
+			return true, cvtCell(&importCell{dimIds: e.DimIds, isNull: e.IsNull, value: e.Value})
+		})
+	default:
+		pl := ReadParamLayout{ReadLayout: ReadLayout{Name: imp.FromName, FromId: run.RunId}}
+		_, err = ReadParameterTo(srcDb, srcModel, &pl, func(s interface{}) (bool, error) {
+			p, ok := s.(CellParam)
+			if !ok {
+				return false, errors.New("invalid type, expected: parameter cell (internal error): " + imp.FromName)
+			}
+			return true, cvtCell(&importCell{subId: p.SubId, dimIds: p.DimIds, isNull: p.IsNull, value: p.Value})
+		})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	src.SubCount = subMax + 1
+
+	// upstream value digest in that model run
+	var dm map[int]string
+	if isTable {
+		dm, err = selectRunTableDigest(srcDb, run.RunId)
+	} else {
+		dm, err = selectRunParamDigest(srcDb, run.RunId)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	src.ValueDigest = dm[srcHid]
+
+	return cLst, &src, nil
+}
+
+// return true if output table expression is an average of native accumulator, ex.: OM_AVG(acc0)
+func isExprAvgOfAcc(expr *TableExprRow, acc *TableAccRow) bool {
+	if acc.IsDerived {
+		return false
+	}
+	src := strings.Join(strings.Fields(expr.SrcExpr), "")
+	return strings.EqualFold(src, "OM_AVG("+acc.Name+")")
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestIsExprAvgOfAcc(t *testing.T) {
+
+	acc := TableAccRow{Name: "acc0"}
+	derived := TableAccRow{Name: "acc1", IsDerived: true}
+
+	for _, tc := range []struct {
+		src      string
+		acc      *TableAccRow
+		expected bool
+	}{
+		{"OM_AVG(acc0)", &acc, true},
+		{" om_avg ( acc0 ) ", &acc, true},
+		{"OM_AVG(acc0) * 2", &acc, false},
+		{"OM_SUM(acc0)", &acc, false},
+		{"OM_AVG(acc01)", &acc, false},
+		{"OM_AVG(acc1)", &derived, false},
+	} {
+		if isAvg := isExprAvgOfAcc(&TableExprRow{Name: "expr0", SrcExpr: tc.src}, tc.acc); isAvg != tc.expected {
+			t.Error("****FAIL: expression:", tc.src, "accumulator:", tc.acc.Name, "expected:", tc.expected, "found:", isAvg)
+		}
+	}
+}
+
+func TestReadParamImport(t *testing.T) {
+
+	dbConn, srcModel, langDef := createTestModel(t)
+
+	// upstream model run: sub-value 0 is written by test run and sub-value 1 inserted into accumulator table
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, srcModel, langDef, "r1", &v)
+
+	acc := srcModel.Table[0].DbAccTable
+	for k, val := range []int{300, 400} {
+		_, err := dbConn.Exec("INSERT INTO " + acc + " (run_id, acc_id, sub_id, dim0, acc_value)" +
+			" VALUES (" + strconv.Itoa(r1) + ", 0, 1, " + strconv.Itoa(k) + ", " + strconv.Itoa(val) + ")")
+		if err != nil {
+			t.Fatal("****FAIL: insert accumulator sub-value:", err)
+		}
+	}
+	run, err := GetRun(dbConn.DB, r1)
+	if err != nil || run == nil {
+		t.Fatal("****FAIL: read model run:", r1, err)
+	}
+
+	// downstream model: import sub-values of output table by sample dimension,
+	// import output table expression and import parameter
+	dstModel := makeTestModel()
+	dstModel.Model.Name = "dstModel"
+	dstModel.Model.Digest = "t_dst_model_0001"
+	dstModel.Param = append(dstModel.Param,
+		ParamMeta{
+			ParamDicRow: ParamDicRow{ParamId: 2, Name: "incomeSample", Digest: "t_incomeSample_0001", Rank: 2, TypeId: 7},
+			Dim:         []ParamDimsRow{{ParamId: 2, DimId: 0, Name: "sample", TypeId: 4}, {ParamId: 2, DimId: 1, Name: "dim1", TypeId: 101}},
+			Import:      []ParamImportRow{{ParamId: 2, FromName: "incomeSex", FromModel: testModelName, IsSampleDim: true}},
+		},
+		ParamMeta{
+			ParamDicRow: ParamDicRow{ParamId: 3, Name: "incomeAvg", Digest: "t_incomeAvg_0001", Rank: 1, TypeId: 7},
+			Dim:         []ParamDimsRow{{ParamId: 3, DimId: 0, Name: "dim0", TypeId: 101}},
+			Import:      []ParamImportRow{{ParamId: 3, FromName: "incomeSex", FromModel: testModelName}},
+		},
+		ParamMeta{
+			ParamDicRow: ParamDicRow{ParamId: 4, Name: "salaryFrom", Digest: "t_salaryFrom_0001", Rank: 1, TypeId: 7},
+			Dim:         []ParamDimsRow{{ParamId: 4, DimId: 0, Name: "dim0", TypeId: 101}},
+			Import:      []ParamImportRow{{ParamId: 4, FromName: "salarySex", FromModel: testModelName}},
+		},
+	)
+	if err = dstModel.updateInternals(); err != nil {
+		t.Fatal("****FAIL: downstream model metadata:", err)
+	}
+
+	// return map of cell values by sample item (or sub-value id) and sex item
+	readImport := func(name string) (map[[2]int]float64, *ParamImportSource) {
+		cLst, src, err := ReadParamImport(dbConn.DB, srcModel, run, dstModel, name)
+		if err != nil {
+			t.Fatal("****FAIL: read parameter import:", name, err)
+		}
+		m := map[[2]int]float64{}
+		for _, c := range cLst {
+			fv, _ := c.Value.(float64)
+			if len(c.DimIds) == 2 {
+				m[[2]int{c.DimIds[0], c.DimIds[1]}] = fv
+			} else {
+				m[[2]int{c.SubId, c.DimIds[0]}] = fv
+			}
+		}
+		return m, src
+	}
+
+	// sample dimension: each accumulator sub-value imported as sample dimension item
+	cells, src := readImport("incomeSample")
+
+	expected := map[[2]int]float64{{0, 0}: 100, {0, 1}: 200, {1, 0}: 300, {1, 1}: 400}
+	if len(cells) != len(expected) {
+		t.Error("****FAIL: expected:", expected, "found:", cells)
+	}
+	for key, ev := range expected {
+		if cells[key] != ev {
+			t.Error("****FAIL: cell:", key, "expected:", ev, "found:", cells[key])
+		}
+	}
+	if src.FromKind != "table" || src.FromValue != "acc0" || !src.IsSampleDim || src.SubCount != 1 || src.RunDigest != run.RunDigest {
+		t.Error("****FAIL: invalid import source:", src)
+	}
+
+	// no sample dimension: first table expression imported
+	cells, src = readImport("incomeAvg")
+	if len(cells) != 2 || cells[[2]int{0, 0}] != 100 || cells[[2]int{0, 1}] != 200 {
+		t.Error("****FAIL: expected expression values: 100, 200 found:", cells)
+	}
+	if src.FromKind != "table" || src.FromValue != "expr0" || src.IsSampleDim {
+		t.Error("****FAIL: invalid import source:", src)
+	}
+
+	// import from upstream parameter
+	cells, src = readImport("salaryFrom")
+	if len(cells) != 2 || cells[[2]int{0, 0}] != 10 || cells[[2]int{0, 1}] != 20 {
+		t.Error("****FAIL: expected parameter values: 10, 20 found:", cells)
+	}
+	if src.FromKind != "parameter" || src.FromValue != "" || src.SubCount != 1 {
+		t.Error("****FAIL: invalid import source:", src)
+	}
+
+	// sample dimension cannot be imported if first expression is not an average of first accumulator
+	srcModel.Table[0].Expr[0].SrcExpr = "OM_SUM(acc0)"
+	if _, _, err = ReadParamImport(dbConn.DB, srcModel, run, dstModel, "incomeSample"); err == nil {
+		t.Error("****FAIL: expected error on import sub-values if first expression is not an average")
+	}
+	if _, _, err = ReadParamImport(dbConn.DB, srcModel, run, dstModel, "incomeAvg"); err != nil {
+		t.Error("****FAIL: expected import of expression values regardless of expression formula:", err)
+	}
+	if _, _, err = ReadParamImport(dbConn.DB, srcModel, run, dstModel, "startAge"); err == nil {
+		t.Error("****FAIL: expected error on import of not imported parameter")
+	}
+}
@@ -0,0 +1,536 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// WorksetMerge is a three-way merge of two worksets branched from the same base run.
+//
+// Parameter which is not included in workset considered as unchanged, it is the same as in base run.
+// Cells changed only in workset A or only in workset B merged automatically,
+// cells changed in both worksets to different values are conflicts.
+// Conflict resolved by Resolve rule: "a" use value from workset A, "b" use value from workset B,
+// "base" or empty "" keep base run value.
+type WorksetMerge struct {
+	Name    string // name of new merged workset
+	SetA    string // workset A name
+	SetB    string // workset B name
+	BaseRun string // base run digest, stamp or name, if empty then base run of workset A is used
+	Resolve string // conflict resolution: "base", "a", "b", default: base
+}
+
+// WorksetMergeReport is a result of three-way merge of worksets: merge summary by parameters and list of conflicts.
+type WorksetMergeReport struct {
+	ModelName     string                 // model name
+	ModelDigest   string                 // model digest
+	Name          string                 // merged workset name
+	SetA          string                 // workset A name
+	SetB          string                 // workset B name
+	BaseRunDigest string                 // base run digest
+	Resolve       string                 // conflict resolution: base, a, b
+	NConflict     int                    // total number of conflicts
+	IsTruncated   bool                   // if true then list of conflicts is truncated in stored report
+	Param         []WorksetMergeParam    // merge summary by parameters
+	Conflict      []WorksetMergeConflict // list of conflicts
+	MergeDateTime string                 // date-time of merge
+}
+
+// WorksetMergeParam is a merge summary of workset parameter.
+type WorksetMergeParam struct {
+	Name      string // parameter name
+	SubCount  int    // number of sub-values in merged workset
+	NFromA    int    // number of cells changed only in workset A
+	NFromB    int    // number of cells changed only in workset B
+	NSame     int    // number of cells changed in both worksets to the same value
+	NConflict int    // number of cells changed in both worksets to different values
+}
+
+// WorksetMergeConflict is a parameter cell changed in both worksets to different values.
+//
+// Dimension items and values are enum codes if dimension or parameter is enum-based.
+// Value is nil if cell does not exist or value is NULL.
+type WorksetMergeConflict struct {
+	Name  string      // parameter name
+	SubId int         // sub-value id
+	Dims  []string    // dimension items
+	Base  interface{} // base run value
+	A     interface{} // workset A value
+	B     interface{} // workset B value
+}
+
+// kind of three-way merge result for parameter cell
+const (
+	mergeCellBase     = iota // cell is unchanged
+	mergeCellA               // cell changed only in workset A
+	mergeCellB               // cell changed only in workset B
+	mergeCellSame            // cell changed in both worksets to the same value
+	mergeCellConflict        // cell changed in both worksets to different values
+)
+
+// three-way merge result of parameter cell: merged cell and source cells
+type mergeCell struct {
+	kind int        // merge kind: unchanged, from A, from B, same, conflict
+	cell *CellParam // merged cell, nil if cell deleted
+	base *CellParam // base run cell, nil if not exist
+	a    *CellParam // workset A cell, nil if not exist
+	b    *CellParam // workset B cell, nil if not exist
+}
+
+// GetWorksetMergeReport return report of three-way merge which created the workset.
+// Return nil if workset was not created by merge.
+func GetWorksetMergeReport(dbConn *sql.DB, modelDef *ModelMeta, setName string) (*WorksetMergeReport, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+
+	val, err := getWorksetExtra(dbConn, modelDef, setName, mergeWorksetExtra)
+	if err != nil {
+		return nil, err
+	}
+	if val == "" {
+		return nil, nil
+	}
+
+	var rpt WorksetMergeReport
+	if err = json.Unmarshal([]byte(val), &rpt); err != nil {
+		return nil, errors.New("invalid workset merge report: " + setName + ": " + err.Error())
+	}
+	return &rpt, nil
+}
+
+// MergeWorksets does three-way merge of workset A and workset B using base run and save result into new workset.
+//
+// Merged workset is created as read-write with the same base run, it includes all parameters from workset A and workset B.
+// Parameter cells are matched by sub-value id and dimension items.
+// Merge report is stored and can be retrieved by GetWorksetMergeReport, return report includes all conflicts.
+// It is an error if merged workset already exists.
+func MergeWorksets(dbConn Dbc, modelDef *ModelMeta, langDef *LangMeta, wm *WorksetMerge) (*WorksetMergeReport, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if wm == nil || wm.Name == "" {
+		return nil, errors.New("invalid (empty) merged workset name")
+	}
+	if wm.SetA == "" || wm.SetB == "" {
+		return nil, errors.New("invalid (empty) workset name to merge")
+	}
+	resolve := wm.Resolve
+	switch resolve {
+	case "":
+		resolve = "base"
+	case "base", "a", "b":
+	default:
+		return nil, errors.New("invalid conflict resolution: " + resolve + ", expected one of: base, a, b")
+	}
+
+	// merged workset must not exist, source worksets must exist
+	ws, err := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, wm.Name)
+	if err != nil {
+		return nil, err
+	}
+	if ws != nil {
+		return nil, errors.New("workset already exists: " + wm.Name)
+	}
+
+	getPub := func(name string) (*WorksetRow, *WorksetPub, error) {
+		w, e := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, name)
+		if e != nil {
+			return nil, nil, e
+		}
+		if w == nil {
+			return nil, nil, errors.New("workset not found: " + name)
+		}
+		wf, e := GetWorksetFull(dbConn.DB, w, "")
+		if e != nil {
+			return nil, nil, e
+		}
+		p, e := wf.ToPublic(dbConn.DB, modelDef)
+		if e != nil {
+			return nil, nil, e
+		}
+		return w, p, nil
+	}
+	wsA, pubA, err := getPub(wm.SetA)
+	if err != nil {
+		return nil, err
+	}
+	wsB, pubB, err := getPub(wm.SetB)
+	if err != nil {
+		return nil, err
+	}
+
+	// find base run: it must be completed, by default it is workset A base run
+	var baseRun *RunRow
+	if wm.BaseRun != "" {
+		baseRun, err = GetRunByDigestStampName(dbConn.DB, modelDef.Model.ModelId, wm.BaseRun)
+	} else {
+		if wsA.BaseRunId <= 0 {
+			return nil, errors.New("workset does not have a base run: " + wm.SetA)
+		}
+		baseRun, err = GetRun(dbConn.DB, wsA.BaseRunId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if baseRun == nil || baseRun.ModelId != modelDef.Model.ModelId {
+		return nil, errors.New("base run not found: " + wm.BaseRun)
+	}
+	if !IsRunCompleted(baseRun.Status) {
+		return nil, errors.New("base run not completed: " + baseRun.Name + " " + baseRun.RunDigest)
+	}
+
+	// list of parameters to merge: union of workset A and workset B parameters in model order
+	idxA := map[string]int{}
+	for k := range pubA.Param {
+		idxA[pubA.Param[k].Name] = k
+	}
+	idxB := map[string]int{}
+	for k := range pubB.Param {
+		idxB[pubB.Param[k].Name] = k
+	}
+
+	rpt := WorksetMergeReport{
+		ModelName:     modelDef.Model.Name,
+		ModelDigest:   modelDef.Model.Digest,
+		Name:          wm.Name,
+		SetA:          wm.SetA,
+		SetB:          wm.SetB,
+		BaseRunDigest: baseRun.RunDigest,
+		Resolve:       resolve,
+		Param:         []WorksetMergeParam{},
+		Conflict:      []WorksetMergeConflict{},
+	}
+	paramLst := []ParamRunSetPub{}
+	cellLst := [][]CellParam{}
+
+	// read cells of parameter from model run or workset
+	readCells := func(name string, fromId int, isFromSet bool) ([]CellParam, error) {
+		cLst := []CellParam{}
+		_, e := ReadParameterTo(dbConn.DB, modelDef,
+			&ReadParamLayout{ReadLayout: ReadLayout{Name: name, FromId: fromId}, IsFromSet: isFromSet},
+			func(src interface{}) (bool, error) {
+				cLst = append(cLst, src.(CellParam))
+				return true, nil
+			})
+		return cLst, e
+	}
+
+	for k := range modelDef.Param {
+
+		name := modelDef.Param[k].Name
+		ia, isA := idxA[name]
+		ib, isB := idxB[name]
+		if !isA && !isB {
+			continue // parameter not in any workset: it is the same as in base run
+		}
+
+		baseLst, err := readCells(name, baseRun.RunId, false)
+		if err != nil {
+			return nil, err
+		}
+
+		// parameter which is not in workset is the same as in base run
+		aLst := baseLst
+		if isA {
+			if aLst, err = readCells(name, wsA.SetId, true); err != nil {
+				return nil, err
+			}
+		}
+		bLst := baseLst
+		if isB {
+			if bLst, err = readCells(name, wsB.SetId, true); err != nil {
+				return nil, err
+			}
+		}
+
+		// merge parameter cells and convert conflicts into enum codes
+		mLst := mergeParamCells(baseLst, aLst, bLst)
+
+		cvt := CellParamConverter{ModelDef: modelDef, Name: name}
+		toCode, err := cvt.IdToCodeCell(modelDef, name)
+		if err != nil {
+			return nil, err
+		}
+		codeValue := func(c *CellParam) (interface{}, error) {
+			if c == nil || c.IsNull {
+				return nil, nil
+			}
+			cc, e := toCode(*c)
+			if e != nil {
+				return nil, e
+			}
+			return cc.(CellCodeParam).Value, nil
+		}
+
+		mp := WorksetMergeParam{Name: name}
+		cLst := []CellParam{}
+		subIds := map[int]bool{}
+
+		for j := range mLst {
+
+			m := &mLst[j]
+			switch m.kind {
+			case mergeCellA:
+				mp.NFromA++
+			case mergeCellB:
+				mp.NFromB++
+			case mergeCellSame:
+				mp.NSame++
+			case mergeCellConflict:
+				mp.NConflict++
+
+				// resolve conflict and add it to the report
+				switch resolve {
+				case "a":
+					m.cell = m.a
+				case "b":
+					m.cell = m.b
+				default:
+					m.cell = m.base
+				}
+				c := m.base
+				if c == nil {
+					c = m.a
+				}
+				if c == nil {
+					c = m.b
+				}
+				cc, e := toCode(*c)
+				if e != nil {
+					return nil, e
+				}
+				mc := WorksetMergeConflict{Name: name, SubId: c.SubId, Dims: cc.(CellCodeParam).Dims}
+
+				if mc.Base, e = codeValue(m.base); e != nil {
+					return nil, e
+				}
+				if mc.A, e = codeValue(m.a); e != nil {
+					return nil, e
+				}
+				if mc.B, e = codeValue(m.b); e != nil {
+					return nil, e
+				}
+				rpt.Conflict = append(rpt.Conflict, mc)
+			}
+			if m.cell != nil {
+				cLst = append(cLst, *m.cell)
+				subIds[m.cell.SubId] = true
+			}
+		}
+		if len(cLst) <= 0 {
+			return nil, errors.New("merged parameter is empty: " + name)
+		}
+
+		// parameter metadata from workset A if it is changed, else from workset B
+		var p ParamRunSetPub
+		if isA && (!isB || mp.NFromA+mp.NSame+mp.NConflict > 0 || mp.NFromB <= 0) {
+			p = pubA.Param[ia]
+		} else {
+			p = pubB.Param[ib]
+		}
+		p.ValueDigest = ""
+		p.SubCount = len(subIds)
+		if !subIds[p.DefaultSubId] {
+			p.DefaultSubId = cLst[0].SubId
+		}
+		mp.SubCount = p.SubCount
+
+		rpt.Param = append(rpt.Param, mp)
+		rpt.NConflict += mp.NConflict
+		paramLst = append(paramLst, p)
+		cellLst = append(cellLst, cLst)
+	}
+	if len(paramLst) <= 0 {
+		return nil, errors.New("worksets do not have any parameters to merge: " + wm.SetA + ", " + wm.SetB)
+	}
+
+	// create empty workset with the same base run and insert merged parameter values
+	pub := WorksetPub{
+		WorksetHdrPub: WorksetHdrPub{
+			ModelName:     modelDef.Model.Name,
+			ModelDigest:   modelDef.Model.Digest,
+			Name:          wm.Name,
+			BaseRunDigest: baseRun.RunDigest,
+		},
+		Param: []ParamRunSetPub{},
+	}
+	mws, err := pub.FromPublic(dbConn.DB, modelDef)
+	if err != nil {
+		return nil, err
+	}
+	if err = mws.UpdateWorkset(dbConn.DB, modelDef, false, langDef); err != nil {
+		return nil, err
+	}
+
+	for k := range paramLst {
+
+		cLst := cellLst[k]
+		n := 0
+		from := func() (interface{}, error) {
+			if n >= len(cLst) {
+				return nil, nil // end of data
+			}
+			n++
+			return cLst[n-1], nil
+		}
+		if _, err = mws.UpdateWorksetParameterFrom(dbConn, modelDef, true, &paramLst[k], langDef, nil, from); err != nil {
+			return nil, err
+		}
+	}
+
+	// store merge report, if it is too long then truncate list of conflicts
+	rpt.MergeDateTime = helper.MakeDateTime(time.Now())
+
+	sr := rpt
+	js, err := json.Marshal(sr)
+	if err != nil {
+		return nil, err
+	}
+	for len(js) > optionDbMax && len(sr.Conflict) > 0 {
+		sr.IsTruncated = true
+		sr.Conflict = sr.Conflict[:len(sr.Conflict)/2]
+		if js, err = json.Marshal(sr); err != nil {
+			return nil, err
+		}
+	}
+	if len(js) > optionDbMax {
+		return nil, errors.New("invalid workset merge report, it is too long: " + wm.Name)
+	}
+	if err = updateWorksetExtra(dbConn.DB, modelDef, wm.Name, mergeWorksetExtra, string(js)); err != nil {
+		return nil, err
+	}
+
+	// store source of merged parameters: source worksets and base run
+	srcLst := make([]WorksetParamSource, len(paramLst))
+	for k := range paramLst {
+		srcLst[k] = WorksetParamSource{
+			Name:      paramLst[k].Name,
+			Kind:      "merge",
+			RunName:   baseRun.Name,
+			RunDigest: baseRun.RunDigest,
+			SetName:   wm.SetA + ", " + wm.SetB,
+		}
+	}
+	if err = updateWorksetParamSource(dbConn.DB, modelDef, wm.Name, srcLst); err != nil {
+		return nil, err
+	}
+
+	return &rpt, nil
+}
+
+// mergeParamCells does three-way merge of parameter cells: base, A and B.
+// Cells are matched by sub-value id and dimension items, cell which does not exist is compared as deleted.
+// Result is ordered by sub-value id and dimension items.
+func mergeParamCells(baseLst, aLst, bLst []CellParam) []mergeCell {
+
+	// make a key of the cell: sub id and dimension items
+	cellKey := func(c *CellParam) string {
+		k := strconv.Itoa(c.SubId)
+		for _, d := range c.DimIds {
+			k += "," + strconv.Itoa(d)
+		}
+		return k
+	}
+	isEqual := func(a, b *CellParam) bool {
+		if a == nil || b == nil {
+			return a == nil && b == nil
+		}
+		return a.IsNull == b.IsNull && (a.IsNull || a.Value == b.Value)
+	}
+
+	// collect cells by key
+	type cellSrc struct {
+		base *CellParam
+		a    *CellParam
+		b    *CellParam
+	}
+	keyLst := []string{}
+	cm := map[string]*cellSrc{}
+
+	add := func(cLst []CellParam, set func(cs *cellSrc, c *CellParam)) {
+		for k := range cLst {
+			key := cellKey(&cLst[k])
+			cs, ok := cm[key]
+			if !ok {
+				cs = &cellSrc{}
+				cm[key] = cs
+				keyLst = append(keyLst, key)
+			}
+			set(cs, &cLst[k])
+		}
+	}
+	add(baseLst, func(cs *cellSrc, c *CellParam) { cs.base = c })
+	add(aLst, func(cs *cellSrc, c *CellParam) { cs.a = c })
+	add(bLst, func(cs *cellSrc, c *CellParam) { cs.b = c })
+
+	mLst := make([]mergeCell, 0, len(keyLst))
+
+	for _, key := range keyLst {
+
+		cs := cm[key]
+		m := mergeCell{base: cs.base, a: cs.a, b: cs.b}
+
+		isA := !isEqual(cs.base, cs.a)
+		isB := !isEqual(cs.base, cs.b)
+
+		switch {
+		case !isA && !isB:
+			m.kind = mergeCellBase
+			m.cell = cs.a
+		case isA && !isB:
+			m.kind = mergeCellA
+			m.cell = cs.a
+		case !isA && isB:
+			m.kind = mergeCellB
+			m.cell = cs.b
+		case isEqual(cs.a, cs.b):
+			m.kind = mergeCellSame
+			m.cell = cs.a
+		default:
+			m.kind = mergeCellConflict
+		}
+		mLst = append(mLst, m)
+	}
+
+	// order by cell sub-value id and dimension items
+	src := func(m *mergeCell) *CellParam {
+		if m.base != nil {
+			return m.base
+		}
+		if m.a != nil {
+			return m.a
+		}
+		return m.b
+	}
+	sort.SliceStable(mLst, func(i, j int) bool {
+		a := src(&mLst[i])
+		b := src(&mLst[j])
+		if a.SubId != b.SubId {
+			return a.SubId < b.SubId
+		}
+		for k := 0; k < len(a.DimIds) && k < len(b.DimIds); k++ {
+			if a.DimIds[k] != b.DimIds[k] {
+				return a.DimIds[k] < b.DimIds[k]
+			}
+		}
+		return false
+	})
+	return mLst
+}
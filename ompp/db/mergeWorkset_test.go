@@ -0,0 +1,67 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"testing"
+)
+
+func TestMergeParamCells(t *testing.T) {
+
+	// base: 0, 1, 2, 3, 4, 5 on dimension items 0...5
+	// A: item 1 changed, item 3 changed, item 4 changed, item 5 deleted
+	// B: item 2 changed, item 3 changed to the same value, item 4 changed to other value, item 6 inserted
+	base := []CellParam{testCell(0, 0, 0), testCell(0, 1, 1), testCell(0, 2, 2), testCell(0, 3, 3), testCell(0, 4, 4), testCell(0, 5, 5)}
+	a := []CellParam{testCell(0, 0, 0), testCell(0, 10, 1), testCell(0, 2, 2), testCell(0, 30, 3), testCell(0, 40, 4)}
+	b := []CellParam{testCell(0, 6, 6), testCell(0, 400, 4), testCell(0, 30, 3), testCell(0, 20, 2), testCell(0, 1, 1), testCell(0, 0, 0), testCell(0, 5, 5)}
+
+	mLst := mergeParamCells(base, a, b)
+
+	expected := []struct {
+		kind   int
+		isCell bool
+		value  float64
+	}{
+		{kind: mergeCellBase, isCell: true, value: 0},
+		{kind: mergeCellA, isCell: true, value: 10},
+		{kind: mergeCellB, isCell: true, value: 20},
+		{kind: mergeCellSame, isCell: true, value: 30},
+		{kind: mergeCellConflict, isCell: false},
+		{kind: mergeCellA, isCell: false},
+		{kind: mergeCellB, isCell: true, value: 6},
+	}
+	if len(mLst) != len(expected) {
+		t.Fatal("****FAIL: expected cells:", len(expected), "found:", len(mLst))
+	}
+
+	for k, e := range expected {
+
+		m := mLst[k]
+		if m.kind != e.kind {
+			t.Error("****FAIL: cell:", k, "expected kind:", e.kind, "found:", m.kind)
+		}
+		if e.isCell != (m.cell != nil) {
+			t.Error("****FAIL: cell:", k, "expected cell exist:", e.isCell)
+			continue
+		}
+		if m.cell != nil && (m.cell.DimIds[0] != k || m.cell.Value != e.value) {
+			t.Error("****FAIL: cell:", k, "expected value:", e.value, "found:", m.cell.DimIds, m.cell.Value)
+		}
+	}
+
+	// conflict cell must have all three source values
+	if m := mLst[4]; m.base == nil || m.a == nil || m.b == nil || m.a.Value != 40.0 || m.b.Value != 400.0 {
+		t.Error("****FAIL: conflict cell:", m)
+	}
+
+	// NULL value is a change
+	base = []CellParam{testCell(0, 1, 0)}
+	a = []CellParam{{cellIdValue: cellIdValue{DimIds: []int{0}, IsNull: true}}}
+	b = []CellParam{testCell(0, 1, 0)}
+
+	mLst = mergeParamCells(base, a, b)
+	if len(mLst) != 1 || mLst[0].kind != mergeCellA || mLst[0].cell == nil || !mLst[0].cell.IsNull {
+		t.Error("****FAIL: expected NULL cell from A:", mLst)
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+const (
+	maxMicroBinCount      = 1000 // max number of bins of microdata attribute
+	maxMicroQuantileCount = 100  // max number of quantile bins of microdata attribute
+)
+
+// ParseMicroBinList parse binning of microdata attributes from string, bins are separated by ; semicolon.
+//
+// Each bin is: name=kind(arguments), for example:
+//
+//	Income=width(0, 1000, 10)   fixed-width bins: start, width and count of bins
+//	AgeYears=breaks(0, 18, 65)  explicit break points
+//	Salary=quantile(4)          quantile bins: number of quantiles
+func ParseMicroBinList(src string) ([]MicroBinLayout, error) {
+
+	bins := []MicroBinLayout{}
+
+	for _, s := range strings.Split(src, ";") {
+
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		// split name=kind(arguments)
+		n := strings.IndexRune(s, '=')
+		if n <= 0 || n >= len(s)-1 {
+			return []MicroBinLayout{}, errors.New("invalid microdata bin, expected: name=kind(arguments): " + s)
+		}
+		bin := MicroBinLayout{Name: strings.TrimSpace(s[:n])}
+
+		fs := strings.TrimSpace(s[n+1:])
+		nOpen := strings.IndexRune(fs, '(')
+		if nOpen <= 0 || !strings.HasSuffix(fs, ")") {
+			return []MicroBinLayout{}, errors.New("invalid microdata bin, expected: name=kind(arguments): " + s)
+		}
+		bin.Kind = BinKind(strings.ToUpper(strings.TrimSpace(fs[:nOpen])))
+
+		// parse arguments as list of numbers
+		args := []float64{}
+		for _, a := range helper.ParseCsvLine(fs[nOpen+1:len(fs)-1], ',') {
+
+			if a == "" {
+				continue
+			}
+			f, e := strconv.ParseFloat(a, 64)
+			if e != nil {
+				return []MicroBinLayout{}, errors.New("invalid microdata bin argument, it must be a number: " + a + ": " + s)
+			}
+			args = append(args, f)
+		}
+
+		switch bin.Kind {
+		case WidthBin:
+			if len(args) != 3 {
+				return []MicroBinLayout{}, errors.New("invalid microdata fixed-width bin, expected: name=width(start, width, count): " + s)
+			}
+			bin.Start = args[0]
+			bin.Width = args[1]
+			bin.Count = int(args[2])
+		case BreaksBin:
+			if len(args) <= 0 {
+				return []MicroBinLayout{}, errors.New("invalid microdata bin, expected: name=breaks(break points): " + s)
+			}
+			bin.Breaks = args
+		case QuantileBin:
+			if len(args) != 1 {
+				return []MicroBinLayout{}, errors.New("invalid microdata quantile bin, expected: name=quantile(count): " + s)
+			}
+			bin.Count = int(args[0])
+		default:
+			return []MicroBinLayout{}, errors.New("invalid microdata bin kind, expected one of: width, breaks, quantile: " + s)
+		}
+
+		if bin.Kind == QuantileBin {
+			if bin.Count < 2 || bin.Count > maxMicroQuantileCount {
+				return []MicroBinLayout{}, errors.New("invalid number of microdata quantile bins, it must be between 2 and " + strconv.Itoa(maxMicroQuantileCount) + ": " + s)
+			}
+		} else {
+			if _, e := microBinBreaks(&bin); e != nil {
+				return []MicroBinLayout{}, e
+			}
+		}
+		bins = append(bins, bin)
+	}
+	return bins, nil
+}
+
+// return index of microdata bin by attribute name
+func findMicroBin(bins []MicroBinLayout, name string) (int, bool) {
+	for k := range bins {
+		if bins[k].Name == name {
+			return k, true
+		}
+	}
+	return -1, false
+}
+
+// return bin break points: n break points define n+1 bins.
+// Quantile break points must be calculated from microdata values before translation into sql.
+func microBinBreaks(bin *MicroBinLayout) ([]float64, error) {
+
+	if bin == nil {
+		return []float64{}, errors.New("invalid (empty) microdata bin")
+	}
+
+	var bp []float64
+
+	switch bin.Kind {
+	case WidthBin:
+		if bin.Width <= 0.0 || math.IsNaN(bin.Width) || math.IsInf(bin.Width, 0) {
+			return []float64{}, errors.New("invalid microdata bin width, it must be positive: " + bin.Name)
+		}
+		if bin.Count <= 0 || bin.Count > maxMicroBinCount {
+			return []float64{}, errors.New("invalid number of microdata bins, it must be between 1 and " + strconv.Itoa(maxMicroBinCount) + ": " + bin.Name)
+		}
+		bp = make([]float64, bin.Count+1)
+		for k := range bp {
+			bp[k] = bin.Start + float64(k)*bin.Width
+		}
+
+	case BreaksBin:
+		if len(bin.Breaks) <= 0 || len(bin.Breaks) > maxMicroBinCount {
+			return []float64{}, errors.New("invalid number of microdata bin break points, it must be between 1 and " + strconv.Itoa(maxMicroBinCount) + ": " + bin.Name)
+		}
+		bp = bin.Breaks
+
+	case QuantileBin:
+		if bin.Count < 2 || bin.Count > maxMicroQuantileCount {
+			return []float64{}, errors.New("invalid number of microdata quantile bins, it must be between 2 and " + strconv.Itoa(maxMicroQuantileCount) + ": " + bin.Name)
+		}
+		if len(bin.Breaks) != bin.Count-1 {
+			return []float64{}, errors.New("microdata quantile bin break points not found: " + bin.Name)
+		}
+		bp = bin.Breaks
+
+	default:
+		return []float64{}, errors.New("invalid microdata bin kind: " + string(bin.Kind) + ": " + bin.Name)
+	}
+
+	// break points must be finite numbers in ascending order, quantile break points can be equal
+	for k := range bp {
+		if math.IsNaN(bp[k]) || math.IsInf(bp[k], 0) {
+			return []float64{}, errors.New("invalid microdata bin break point: " + bin.Name)
+		}
+		if k > 0 && (bp[k] < bp[k-1] || bp[k] == bp[k-1] && bin.Kind != QuantileBin) {
+			return []float64{}, errors.New("invalid microdata bin break points, it must be in ascending order: " + bin.Name)
+		}
+	}
+	return bp, nil
+}
+
+// return sql CASE expression of zero-based bin index for column value, NULL value remain NULL:
+// CASE WHEN C.attr4 < 0 THEN 0 WHEN C.attr4 < 18 THEN 1 WHEN C.attr4 < 65 THEN 2 WHEN C.attr4 >= 65 THEN 3 END
+func makeMicroBinSql(bin *MicroBinLayout, colExpr string) (string, error) {
+
+	bp, err := microBinBreaks(bin)
+	if err != nil {
+		return "", err
+	}
+
+	q := "CASE"
+	for k := range bp {
+		q += " WHEN " + colExpr + " < " + strconv.FormatFloat(bp[k], 'f', -1, 64) + " THEN " + strconv.Itoa(k)
+	}
+	q += " WHEN " + colExpr + " >= " + strconv.FormatFloat(bp[len(bp)-1], 'f', -1, 64) + " THEN " + strconv.Itoa(len(bp)) + " END"
+
+	return q, nil
+}
+
+// return zero-based bin index of the value: index of first break point which is greater than value or number of break points
+func microBinIndex(bp []float64, v float64) int {
+	for k := range bp {
+		if v < bp[k] {
+			return k
+		}
+	}
+	return len(bp)
+}
+
+// return converter from zero-based bin index to bin label: < 0, [0, 18), [18, 65), >= 65 or Q1, Q2, Q3, Q4 for quantile bins
+func makeMicroBinLabel(bin *MicroBinLayout, msgName string) (func(v interface{}) (string, error), error) {
+
+	if bin == nil {
+		return nil, errors.New("invalid (empty) microdata bin: " + msgName)
+	}
+
+	var bp []float64
+	if bin.Kind != QuantileBin {
+
+		var err error
+		if bp, err = microBinBreaks(bin); err != nil {
+			return nil, err
+		}
+	} else {
+		if bin.Count < 2 || bin.Count > maxMicroQuantileCount {
+			return nil, errors.New("invalid number of microdata quantile bins, it must be between 2 and " + strconv.Itoa(maxMicroQuantileCount) + ": " + msgName)
+		}
+	}
+
+	fmtVal := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+	cvt := func(v interface{}) (string, error) {
+
+		// depending on sql + driver it can be different type
+		idx, ok := helper.ToIntValue(v)
+		if !ok {
+			return "", errors.New("invalid attribute bin, must be integer bin index: " + msgName)
+		}
+
+		if bin.Kind == QuantileBin {
+			if idx < 0 || idx >= bin.Count {
+				return "", errors.New("invalid attribute bin index: " + strconv.Itoa(idx) + ": " + msgName)
+			}
+			return "Q" + strconv.Itoa(idx+1), nil
+		}
+		// else: fixed-width or explicit break points bins
+		switch {
+		case idx < 0 || idx > len(bp):
+			return "", errors.New("invalid attribute bin index: " + strconv.Itoa(idx) + ": " + msgName)
+		case idx == 0:
+			return "< " + fmtVal(bp[0]), nil
+		case idx == len(bp):
+			return ">= " + fmtVal(bp[len(bp)-1]), nil
+		}
+		return "[" + fmtVal(bp[idx-1]) + ", " + fmtVal(bp[idx]) + ")", nil
+	}
+	return cvt, nil
+}
+
+// replace group by columns of binned attributes by bin columns.
+// Source attribute column is not a group by column anymore and it can be used in aggregation expression,
+// bin column is inserted after source column and it is a group by column of bin index: attr4_bin.
+func applyMicroBins(entityName string, aggrCols []aggrColumn, groupBy []string, bins []MicroBinLayout) ([]aggrColumn, error) {
+
+	if len(bins) <= 0 {
+		return aggrCols, nil // no bins
+	}
+
+	// each bin must be group by attribute
+	for k := range bins {
+
+		isOk := false
+		for j := 0; !isOk && j < len(groupBy); j++ {
+			isOk = bins[k].Name == groupBy[j]
+		}
+		if !isOk {
+			return []aggrColumn{}, errors.New("Entity " + entityName + " bin attribute is not a group by attribute: " + bins[k].Name)
+		}
+		if _, ok := findMicroBin(bins[k+1:], bins[k].Name); ok {
+			return []aggrColumn{}, errors.New("Entity " + entityName + " duplicate bin attribute: " + bins[k].Name)
+		}
+	}
+
+	cols := make([]aggrColumn, 0, len(aggrCols)+len(bins))
+
+	for _, c := range aggrCols {
+
+		n, ok := findMicroBin(bins, c.name)
+		if !ok || !c.isGroup {
+			cols = append(cols, c)
+			continue
+		}
+		if !c.isAggr {
+			return []aggrColumn{}, errors.New("Entity " + entityName + " bin attribute must be float or integer: " + c.name)
+		}
+
+		q, err := makeMicroBinSql(&bins[n], "C."+c.colName)
+		if err != nil {
+			return []aggrColumn{}, err
+		}
+
+		c.isGroup = false
+		cols = append(cols, c)
+		cols = append(cols, aggrColumn{
+			name:    c.name,
+			colName: c.colName + "_bin",
+			isGroup: true,
+			isAggr:  false,
+			binSql:  q,
+		})
+	}
+	return cols, nil
+}
+
+// return copy of microdata bins where quantile bins break points calculated from attribute values of model run.
+func resolveMicroBins(dbConn *sql.DB, entity *EntityMeta, entityGen *EntityGenMeta, bins []MicroBinLayout, runId int) ([]MicroBinLayout, error) {
+
+	bLst := append([]MicroBinLayout{}, bins...)
+
+	for k := range bLst {
+
+		if bLst[k].Kind != QuantileBin {
+			continue
+		}
+		if bLst[k].Count < 2 || bLst[k].Count > maxMicroQuantileCount {
+			return []MicroBinLayout{}, errors.New("invalid number of microdata quantile bins, it must be between 2 and " + strconv.Itoa(maxMicroQuantileCount) + ": " + entity.Name + "." + bLst[k].Name)
+		}
+
+		aIdx, ok := entity.AttrByName(bLst[k].Name)
+		if !ok {
+			return []MicroBinLayout{}, errors.New("entity bin attribute not found by: " + entity.Name + "." + bLst[k].Name)
+		}
+		colName := entity.Attr[aIdx].colName
+
+		// select all not NULL attribute values of the run in ascending order
+		vals := []float64{}
+
+		err := SelectRows(dbConn,
+			"SELECT C."+colName+
+				" FROM "+entityGen.DbEntityTable+" C"+
+				" INNER JOIN run_entity RE ON (RE.base_run_id = C.run_id AND RE.entity_gen_hid = "+strconv.Itoa(entityGen.GenHid)+")"+
+				" WHERE RE.run_id = "+strconv.Itoa(runId)+
+				" AND C."+colName+" IS NOT NULL"+
+				" ORDER BY 1",
+			func(rows *sql.Rows) error {
+				var f float64
+				if err := rows.Scan(&f); err != nil {
+					return err
+				}
+				vals = append(vals, f)
+				return nil
+			})
+		if err != nil {
+			return []MicroBinLayout{}, err
+		}
+		if len(vals) <= 0 {
+			return []MicroBinLayout{}, errors.New("there are no values found to calculate quantiles of: " + entity.Name + "." + bLst[k].Name)
+		}
+
+		// break point of quantile q is a value at position q * N / count
+		bLst[k].Breaks = make([]float64, bLst[k].Count-1)
+
+		for q := 1; q < bLst[k].Count; q++ {
+			bLst[k].Breaks[q-1] = vals[(q*len(vals))/bLst[k].Count]
+		}
+	}
+	return bLst, nil
+}
@@ -0,0 +1,751 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ModelDiff is a difference between metadata of two versions of the model: types, parameters and parameter dimensions.
+// Only added, removed or changed items are included, items which are the same in both versions are not included.
+type ModelDiff struct {
+	ModelName string      // model name
+	SrcDigest string      // source model version digest
+	DstDigest string      // destination model version digest
+	Type      []TypeDiff  // added, removed or changed types
+	Param     []ParamDiff // added, removed or changed parameters
+}
+
+// TypeDiff is a difference between source and destination model type
+type TypeDiff struct {
+	Name        string   // type name
+	Status      string   // added, removed or changed
+	SrcDigest   string   // source type digest, empty if type added
+	DstDigest   string   // destination type digest, empty if type removed
+	EnumAdded   []string // enum codes which exist only in destination type
+	EnumRemoved []string // enum codes which exist only in source type
+}
+
+// ParamDiff is a difference between source and destination model parameter
+type ParamDiff struct {
+	Name       string   // parameter name
+	Status     string   // added, removed or changed
+	SrcDigest  string   // source parameter digest, empty if parameter added
+	DstDigest  string   // destination parameter digest, empty if parameter removed
+	SrcType    string   // source parameter type name
+	DstType    string   // destination parameter type name
+	DimAdded   []string // dimension names which exist only in destination parameter
+	DimRemoved []string // dimension names which exist only in source parameter
+	DimChanged []string // dimension names where dimension type is different
+}
+
+// MigrateMap is a set of rules to migrate parameter values from source model version into destination model version.
+//
+// By default parameter values are copied as is if parameter digest is the same in both model versions.
+// Otherwise dimension items and enum-based values are mapped by enum code:
+// source enum code is renamed, aggregated or split according to the type rules, else the same code is used.
+// Cells of source enums which do not exist in destination are dropped.
+// Cells of new destination enums are filled by parameter default value.
+type MigrateMap struct {
+	Type  []MigrateType  // enum mapping rules by type
+	Param []MigrateParam // parameter mapping rules
+}
+
+// MigrateType is a mapping rules for enum codes of the type
+type MigrateType struct {
+	Name      string             // destination type name
+	FromName  string             // source type name, if empty then the same as destination type name
+	Rename    map[string]string  // source enum code => destination enum code
+	Aggregate []MigrateAggregate // many source enums into one destination enum
+	Split     []MigrateSplit     // one source enum into many destination enums
+}
+
+// MigrateAggregate is a rule to aggregate values of many source enums into one destination enum
+type MigrateAggregate struct {
+	To   string   // destination enum code
+	From []string // source enum codes
+	Fnc  string   // aggregation function: sum, avg, min, max or first, default: sum
+}
+
+// MigrateSplit is a rule to split value of one source enum into many destination enums
+type MigrateSplit struct {
+	From     string   // source enum code
+	To       []string // destination enum codes
+	IsDivide bool     // if true then value divided equally between destination enums else copied
+}
+
+// MigrateParam is a mapping rules for the parameter
+type MigrateParam struct {
+	Name     string // destination parameter name
+	FromName string // source parameter name, if empty then the same as destination parameter name
+	Default  string // default value for destination cells which do not exist in source, e.g. cells of new enums
+	IsSkip   bool   // if true then parameter is not migrated
+}
+
+// ParamMigrator convert parameter values from source model version into destination model version.
+type ParamMigrator struct {
+	SrcName  string               // source parameter name
+	DstName  string               // destination parameter name
+	IsSame   bool                 // if true then parameter digest is the same and values copied as is
+	IsSkip   bool                 // if true then parameter must not be migrated: skipped by rules or removed from destination model
+	NDropped int                  // number of source cells dropped on last Migrate() call
+	NDefault int                  // number of destination cells filled by default value on last Migrate() call
+	dstParam *ParamMeta           // destination parameter
+	dims     []migrateDim         // for each destination dimension: source dimension index and enum code mapping
+	valMap   map[string]migrateTo // if parameter value is enum-based then mapping of value enum codes
+	dflt     interface{}          // default value for missing cells, nil if not specified
+	isFloat  bool                 // if true then destination parameter type is float
+}
+
+// source dimension index and mapping of source enum codes to destination codes
+type migrateDim struct {
+	srcIdx  int                  // source dimension index
+	codeMap map[string]migrateTo // source code => destination codes, if nil then code is the same
+	codes   []string             // all destination enum codes, empty if dimension is simple integer type
+}
+
+// destination enum codes of source enum code
+type migrateTo struct {
+	To       []string // destination codes, empty if source enum does not exist in destination
+	IsDivide bool     // if true then value divided equally between destination codes
+	Fnc      string   // aggregation function if many source codes mapped into destination code
+}
+
+// DiffModel compare metadata of two versions of the model and return added, removed and changed types and parameters.
+func DiffModel(srcModel, dstModel *ModelMeta) (*ModelDiff, error) {
+
+	// validate parameters
+	if srcModel == nil || dstModel == nil {
+		return nil, errors.New("invalid (empty) model metadata")
+	}
+
+	md := ModelDiff{
+		ModelName: dstModel.Model.Name,
+		SrcDigest: srcModel.Model.Digest,
+		DstDigest: dstModel.Model.Digest,
+		Type:      []TypeDiff{},
+		Param:     []ParamDiff{},
+	}
+
+	// compare types by name, built-in types are always the same
+	for k := range srcModel.Type {
+
+		st := &srcModel.Type[k]
+		if st.IsBuiltIn() {
+			continue
+		}
+		dt, ok := typeByName(dstModel, st.Name)
+		if !ok {
+			md.Type = append(md.Type, TypeDiff{Name: st.Name, Status: "removed", SrcDigest: st.Digest, EnumRemoved: typeEnumCodes(st)})
+			continue
+		}
+		if st.Digest == dt.Digest {
+			continue
+		}
+		sc := typeEnumCodes(st)
+		dc := typeEnumCodes(dt)
+		md.Type = append(md.Type, TypeDiff{
+			Name:        st.Name,
+			Status:      "changed",
+			SrcDigest:   st.Digest,
+			DstDigest:   dt.Digest,
+			EnumAdded:   codesNotIn(dc, sc),
+			EnumRemoved: codesNotIn(sc, dc),
+		})
+	}
+	for k := range dstModel.Type {
+
+		dt := &dstModel.Type[k]
+		if dt.IsBuiltIn() {
+			continue
+		}
+		if _, ok := typeByName(srcModel, dt.Name); !ok {
+			md.Type = append(md.Type, TypeDiff{Name: dt.Name, Status: "added", DstDigest: dt.Digest, EnumAdded: typeEnumCodes(dt)})
+		}
+	}
+
+	// compare parameters by name
+	for k := range srcModel.Param {
+
+		sp := &srcModel.Param[k]
+		j, ok := dstModel.ParamByName(sp.Name)
+		if !ok {
+			md.Param = append(md.Param, ParamDiff{Name: sp.Name, Status: "removed", SrcDigest: sp.Digest, SrcType: sp.typeOf.Name})
+			continue
+		}
+		dp := &dstModel.Param[j]
+		if sp.Digest == dp.Digest {
+			continue
+		}
+		pd := ParamDiff{
+			Name:       sp.Name,
+			Status:     "changed",
+			SrcDigest:  sp.Digest,
+			DstDigest:  dp.Digest,
+			SrcType:    sp.typeOf.Name,
+			DstType:    dp.typeOf.Name,
+			DimAdded:   []string{},
+			DimRemoved: []string{},
+			DimChanged: []string{},
+		}
+		for n := range dp.Dim {
+			m, ok := dimByName(sp, dp.Dim[n].Name)
+			if !ok {
+				pd.DimAdded = append(pd.DimAdded, dp.Dim[n].Name)
+				continue
+			}
+			if sp.Dim[m].typeOf.Name != dp.Dim[n].typeOf.Name || sp.Dim[m].typeOf.Digest != dp.Dim[n].typeOf.Digest {
+				pd.DimChanged = append(pd.DimChanged, dp.Dim[n].Name)
+			}
+		}
+		for m := range sp.Dim {
+			if _, ok := dimByName(dp, sp.Dim[m].Name); !ok {
+				pd.DimRemoved = append(pd.DimRemoved, sp.Dim[m].Name)
+			}
+		}
+		md.Param = append(md.Param, pd)
+	}
+	for k := range dstModel.Param {
+		if _, ok := srcModel.ParamByName(dstModel.Param[k].Name); !ok {
+			md.Param = append(md.Param, ParamDiff{
+				Name: dstModel.Param[k].Name, Status: "added", DstDigest: dstModel.Param[k].Digest, DstType: dstModel.Param[k].typeOf.Name,
+			})
+		}
+	}
+
+	return &md, nil
+}
+
+// NewParamMigrator return converter of parameter values from source model version into destination model version.
+//
+// Destination parameter found by name using migration map parameter rules, by default it is the same name as source parameter.
+// Parameter rank must be the same in both model versions, dimensions matched by name or by position if name is changed.
+// Dimension items and enum-based values converted using migration map type rules, by default the same enum code is used.
+// If parameter skipped by migration map rules or not exist in destination model then IsSkip is true.
+func NewParamMigrator(srcModel, dstModel *ModelMeta, mm *MigrateMap, srcName string) (*ParamMigrator, error) {
+
+	// validate parameters
+	if srcModel == nil || dstModel == nil {
+		return nil, errors.New("invalid (empty) model metadata")
+	}
+	if srcName == "" {
+		return nil, errors.New("invalid (empty) parameter name")
+	}
+	if mm == nil {
+		mm = &MigrateMap{}
+	}
+
+	// find parameter mapping rules and destination parameter name
+	pm := ParamMigrator{SrcName: srcName, DstName: srcName}
+	var pRule *MigrateParam
+
+	for k := range mm.Param {
+		if mm.Param[k].FromName == srcName || mm.Param[k].FromName == "" && mm.Param[k].Name == srcName {
+			pRule = &mm.Param[k]
+			if pRule.Name != "" {
+				pm.DstName = pRule.Name
+			}
+			break
+		}
+	}
+	if pRule != nil && pRule.IsSkip {
+		pm.IsSkip = true
+		return &pm, nil
+	}
+
+	// find source and destination parameters
+	k, ok := srcModel.ParamByName(srcName)
+	if !ok {
+		return nil, errors.New("parameter not found: " + srcName + " in model " + srcModel.Model.Name + " " + srcModel.Model.Digest)
+	}
+	srcParam := &srcModel.Param[k]
+
+	k, ok = dstModel.ParamByName(pm.DstName)
+	if !ok {
+		pm.IsSkip = true // parameter removed from destination model
+		return &pm, nil
+	}
+	pm.dstParam = &dstModel.Param[k]
+	pm.isFloat = pm.dstParam.typeOf.IsFloat()
+
+	if srcParam.Rank != pm.dstParam.Rank {
+		return nil, errors.New("parameter rank changed from " + strconv.Itoa(srcParam.Rank) + " to " + strconv.Itoa(pm.dstParam.Rank) + ": " + srcName)
+	}
+
+	// default value for missing cells
+	if pRule != nil && pRule.Default != "" {
+		v, err := migrateDefaultValue(pm.dstParam, pRule.Default)
+		if err != nil {
+			return nil, err
+		}
+		pm.dflt = v
+	}
+
+	// if parameter digest the same then values copied as is
+	pm.IsSame = srcParam.Digest == pm.dstParam.Digest && srcParam.Name == pm.dstParam.Name
+	if pm.IsSame {
+		return &pm, nil
+	}
+
+	// match dimensions by name or by position if dimension name not exist in source
+	pm.dims = make([]migrateDim, pm.dstParam.Rank)
+
+	for k := range pm.dstParam.Dim {
+
+		dd := &pm.dstParam.Dim[k]
+		m, ok := dimByName(srcParam, dd.Name)
+		if !ok {
+			if _, isName := dimByName(pm.dstParam, srcParam.Dim[k].Name); isName {
+				return nil, errors.New("parameter dimension not found: " + dd.Name + " in source parameter: " + srcName)
+			}
+			m = k
+		}
+		cm, err := typeCodeMap(mm, srcParam.Dim[m].typeOf, dd.typeOf)
+		if err != nil {
+			return nil, errors.New(err.Error() + " of: " + srcName + "." + dd.Name)
+		}
+		codes, _ := typeEnumCodesAll(dd.typeOf)
+		pm.dims[k] = migrateDim{srcIdx: m, codeMap: cm, codes: codes}
+	}
+
+	// if parameter value is enum-based then map value enum codes
+	if !pm.dstParam.typeOf.IsBuiltIn() {
+
+		if srcParam.typeOf.IsBuiltIn() {
+			return nil, errors.New("parameter type changed from " + srcParam.typeOf.Name + " to " + pm.dstParam.typeOf.Name + ": " + srcName)
+		}
+		cm, err := typeCodeMap(mm, srcParam.typeOf, pm.dstParam.typeOf)
+		if err != nil {
+			return nil, errors.New(err.Error() + " of: " + srcName)
+		}
+		pm.valMap = cm
+	}
+
+	return &pm, nil
+}
+
+// Migrate convert source parameter cells into destination parameter cells.
+//
+// Dimension items and enum-based values are mapped according to migration rules.
+// If source cells mapped into the same destination cell then values aggregated, by default it is a sum.
+// If destination parameter default value specified then it is used for missing destination cells,
+// else it is an error if any destination cell is missing.
+func (pm *ParamMigrator) Migrate(src []CellCodeParam) ([]CellCodeParam, error) {
+
+	pm.NDropped = 0
+	pm.NDefault = 0
+
+	if pm.IsSkip {
+		return []CellCodeParam{}, nil
+	}
+	if pm.IsSame {
+		return src, nil
+	}
+
+	// destination cell and aggregation state
+	type migrateAcc struct {
+		cell CellCodeParam
+		fnc  string  // aggregation function
+		n    int     // number of not-null values aggregated
+		val  float64 // aggregated value
+	}
+	accLst := []*migrateAcc{}
+	accIdx := map[string]int{}
+
+	subIds := []int{}
+	subSet := map[int]bool{}
+
+	for _, c := range src {
+
+		if !subSet[c.SubId] {
+			subSet[c.SubId] = true
+			subIds = append(subIds, c.SubId)
+		}
+
+		// for each dimension find destination codes
+		to := make([]migrateTo, len(pm.dims))
+		isDrop := false
+		nProd := 1
+		fnc := ""
+
+		for k := range pm.dims {
+
+			code := c.Dims[pm.dims[k].srcIdx]
+
+			if pm.dims[k].codeMap == nil {
+				to[k] = migrateTo{To: []string{code}}
+			} else {
+				t, ok := pm.dims[k].codeMap[code]
+				if !ok || len(t.To) <= 0 {
+					isDrop = true
+					break
+				}
+				to[k] = t
+			}
+			nProd *= len(to[k].To)
+			if to[k].Fnc != "" {
+				if fnc != "" && fnc != to[k].Fnc {
+					return nil, errors.New("conflicting aggregation functions: " + fnc + " and " + to[k].Fnc + " of: " + pm.SrcName)
+				}
+				fnc = to[k].Fnc
+			}
+		}
+		if isDrop {
+			pm.NDropped++
+			continue
+		}
+
+		// convert cell value
+		isNull := c.IsNull
+		val := c.Value
+
+		if !isNull && pm.valMap != nil {
+
+			sv, ok := val.(string)
+			if !ok {
+				return nil, errors.New("invalid parameter value type, expected: string enum code: " + pm.SrcName)
+			}
+			t, ok := pm.valMap[sv]
+			if !ok || len(t.To) <= 0 {
+				return nil, errors.New("enum value not found in destination parameter type: " + sv + " of: " + pm.SrcName)
+			}
+			if len(t.To) > 1 {
+				return nil, errors.New("enum value cannot be split: " + sv + " of: " + pm.SrcName)
+			}
+			val = t.To[0]
+		}
+		if !isNull && pm.isFloat {
+			if fv, ok := valueToFloat(val); ok {
+				val = fv
+			}
+		}
+
+		// for each combination of destination dimension codes create destination cell
+		for n := 0; n < nProd; n++ {
+
+			dims := make([]string, len(to))
+			v := val
+			r := n
+
+			for k := len(to) - 1; k >= 0; k-- {
+				dims[k] = to[k].To[r%len(to[k].To)]
+				r /= len(to[k].To)
+
+				if to[k].IsDivide && !isNull && pm.isFloat {
+					if fv, ok := v.(float64); ok {
+						v = fv / float64(len(to[k].To))
+					}
+				}
+			}
+			key := strconv.Itoa(c.SubId) + "\x00" + strings.Join(dims, "\x00")
+
+			j, isExist := accIdx[key]
+			if !isExist {
+				a := migrateAcc{
+					cell: CellCodeParam{cellCodeValue: cellCodeValue{Dims: dims, IsNull: isNull, Value: v}, SubId: c.SubId},
+					fnc:  fnc,
+				}
+				if fv, ok := v.(float64); ok && !isNull {
+					a.n = 1
+					a.val = fv
+				}
+				accIdx[key] = len(accLst)
+				accLst = append(accLst, &a)
+				continue
+			}
+
+			// aggregate values of source cells mapped into the same destination cell
+			a := accLst[j]
+			if a.fnc == "" {
+				a.fnc = fnc
+			}
+			switch a.fnc {
+			case "first":
+				continue
+			case "", "sum", "avg", "min", "max":
+				if !pm.isFloat {
+					return nil, errors.New("cannot aggregate values of not float parameter: " + pm.SrcName + ", use aggregation function: first")
+				}
+			default:
+				return nil, errors.New("invalid aggregation function: " + a.fnc + " of: " + pm.SrcName)
+			}
+			if isNull {
+				continue
+			}
+			fv, _ := v.(float64)
+
+			switch {
+			case a.n <= 0:
+				a.val = fv
+			case a.fnc == "min":
+				a.val = math.Min(a.val, fv)
+			case a.fnc == "max":
+				a.val = math.Max(a.val, fv)
+			default:
+				a.val += fv
+			}
+			a.n++
+		}
+	}
+
+	// result: destination cells with aggregated values
+	dst := make([]CellCodeParam, len(accLst))
+
+	for k, a := range accLst {
+		dst[k] = a.cell
+		if a.n > 0 && pm.isFloat {
+			dst[k].IsNull = false
+			dst[k].Value = a.val
+			if a.fnc == "avg" {
+				dst[k].Value = a.val / float64(a.n)
+			}
+		}
+	}
+
+	// find missing destination cells and use default value
+	// it is not possible if any dimension is a simple integer type
+	for k := range pm.dims {
+		if len(pm.dims[k].codes) <= 0 {
+			return dst, nil
+		}
+	}
+	nMissing := 0
+	dims := make([]string, len(pm.dims))
+
+	for _, subId := range subIds {
+
+		ix := make([]int, len(pm.dims))
+		for {
+			for k := range ix {
+				dims[k] = pm.dims[k].codes[ix[k]]
+			}
+			if _, ok := accIdx[strconv.Itoa(subId)+"\x00"+strings.Join(dims, "\x00")]; !ok {
+				if pm.dflt == nil {
+					nMissing++
+				} else {
+					dst = append(dst, CellCodeParam{
+						cellCodeValue: cellCodeValue{Dims: append([]string{}, dims...), Value: pm.dflt},
+						SubId:         subId,
+					})
+					pm.NDefault++
+				}
+			}
+
+			// next combination of destination dimension items
+			k := len(ix) - 1
+			for ; k >= 0; k-- {
+				ix[k]++
+				if ix[k] < len(pm.dims[k].codes) {
+					break
+				}
+				ix[k] = 0
+			}
+			if k < 0 {
+				break
+			}
+		}
+	}
+	if nMissing > 0 {
+		return nil, errors.New("missing " + strconv.Itoa(nMissing) + " cells of parameter: " + pm.DstName + ", default value required")
+	}
+
+	return dst, nil
+}
+
+// return mapping of source type enum codes into destination type enum codes.
+// Return nil map if both types are simple integer or boolean types and code is the same.
+func typeCodeMap(mm *MigrateMap, srcType, dstType *TypeMeta) (map[string]migrateTo, error) {
+
+	// find type mapping rules
+	var tRule *MigrateType
+	for k := range mm.Type {
+		if mm.Type[k].Name == dstType.Name && (mm.Type[k].FromName == "" || mm.Type[k].FromName == srcType.Name) {
+			tRule = &mm.Type[k]
+			break
+		}
+	}
+
+	srcCodes, isSrc := typeEnumCodesAll(srcType)
+	dstCodes, isDst := typeEnumCodesAll(dstType)
+
+	if !isSrc || !isDst {
+		if isSrc != isDst || tRule != nil {
+			return nil, errors.New("invalid type mapping from " + srcType.Name + " to " + dstType.Name)
+		}
+		return nil, nil // simple integer types: code is the same
+	}
+
+	dstSet := make(map[string]bool, len(dstCodes))
+	for _, c := range dstCodes {
+		dstSet[c] = true
+	}
+	isDstCodes := func(codes []string) error {
+		for _, c := range codes {
+			if !dstSet[c] {
+				return errors.New("enum not found: " + c + " in type " + dstType.Name)
+			}
+		}
+		return nil
+	}
+
+	// by default map enums by code
+	cm := make(map[string]migrateTo, len(srcCodes))
+
+	for _, c := range srcCodes {
+		if dstSet[c] {
+			cm[c] = migrateTo{To: []string{c}}
+		} else {
+			cm[c] = migrateTo{}
+		}
+	}
+	if tRule == nil {
+		return cm, nil
+	}
+
+	// apply type rules: rename, aggregate and split
+	for c, to := range tRule.Rename {
+		if _, ok := cm[c]; !ok {
+			return nil, errors.New("enum not found: " + c + " in type " + srcType.Name)
+		}
+		if err := isDstCodes([]string{to}); err != nil {
+			return nil, err
+		}
+		cm[c] = migrateTo{To: []string{to}}
+	}
+	for _, ag := range tRule.Aggregate {
+
+		if err := isDstCodes([]string{ag.To}); err != nil {
+			return nil, err
+		}
+		fnc := strings.ToLower(ag.Fnc)
+		if fnc == "" {
+			fnc = "sum"
+		}
+		switch fnc {
+		case "sum", "avg", "min", "max", "first":
+		default:
+			return nil, errors.New("invalid aggregation function: " + ag.Fnc + " of enum: " + ag.To + " in type " + dstType.Name)
+		}
+		for _, c := range ag.From {
+			if _, ok := cm[c]; !ok {
+				return nil, errors.New("enum not found: " + c + " in type " + srcType.Name)
+			}
+			cm[c] = migrateTo{To: []string{ag.To}, Fnc: fnc}
+		}
+	}
+	for _, sp := range tRule.Split {
+
+		if _, ok := cm[sp.From]; !ok {
+			return nil, errors.New("enum not found: " + sp.From + " in type " + srcType.Name)
+		}
+		if len(sp.To) <= 0 {
+			return nil, errors.New("invalid (empty) split of enum: " + sp.From + " in type " + srcType.Name)
+		}
+		if err := isDstCodes(sp.To); err != nil {
+			return nil, err
+		}
+		cm[sp.From] = migrateTo{To: append([]string{}, sp.To...), IsDivide: sp.IsDivide}
+	}
+
+	return cm, nil
+}
+
+// convert default value of destination parameter from string
+func migrateDefaultValue(param *ParamMeta, src string) (interface{}, error) {
+
+	switch {
+	case !param.typeOf.IsBuiltIn():
+		codes, _ := typeEnumCodesAll(param.typeOf)
+		for _, c := range codes {
+			if c == src {
+				return src, nil
+			}
+		}
+	case param.typeOf.IsBool():
+		if v, err := strconv.ParseBool(src); err == nil {
+			return v, nil
+		}
+	case param.typeOf.IsString():
+		return src, nil
+	case param.typeOf.IsFloat():
+		if v, err := strconv.ParseFloat(src, 64); err == nil {
+			return v, nil
+		}
+	case param.typeOf.IsInt():
+		if v, err := strconv.ParseInt(src, 10, 64); err == nil {
+			return v, nil
+		}
+	}
+	return nil, errors.New("invalid default value: " + src + " of parameter: " + param.Name)
+}
+
+// return enum codes of the type, it is empty for built-in types
+func typeEnumCodes(t *TypeMeta) []string {
+	if t.IsBuiltIn() {
+		return []string{}
+	}
+	codes, _ := typeEnumCodesAll(t)
+	return codes
+}
+
+// return all item codes of the type and true, or false if type is simple integer and items cannot be enumerated
+func typeEnumCodesAll(t *TypeMeta) ([]string, bool) {
+
+	switch {
+	case !t.IsBuiltIn() && t.IsRange:
+		codes := make([]string, 0, t.MaxEnumId-t.MinEnumId+1)
+		for k := t.MinEnumId; k <= t.MaxEnumId; k++ {
+			codes = append(codes, strconv.Itoa(k))
+		}
+		return codes, true
+	case !t.IsBuiltIn():
+		codes := make([]string, len(t.Enum))
+		for k := range t.Enum {
+			codes[k] = t.Enum[k].Name
+		}
+		return codes, true
+	case t.IsBool():
+		return []string{"false", "true"}, true
+	}
+	return []string{}, false
+}
+
+// return codes from left list which do not exist in right list
+func codesNotIn(left, right []string) []string {
+
+	rs := make(map[string]bool, len(right))
+	for _, c := range right {
+		rs[c] = true
+	}
+	cLst := []string{}
+	for _, c := range left {
+		if !rs[c] {
+			cLst = append(cLst, c)
+		}
+	}
+	return cLst
+}
+
+// find model type by name
+func typeByName(modelDef *ModelMeta, name string) (*TypeMeta, bool) {
+	for k := range modelDef.Type {
+		if modelDef.Type[k].Name == name {
+			return &modelDef.Type[k], true
+		}
+	}
+	return nil, false
+}
+
+// find parameter dimension index by name
+func dimByName(param *ParamMeta, name string) (int, bool) {
+	for k := range param.Dim {
+		if param.Dim[k].Name == name {
+			return k, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"testing"
+)
+
+func TestParamMigrator(t *testing.T) {
+
+	// source model: salary(sex, level) with level enums: low, medium, high
+	// destination model: salary(sex, level) with level enums: L, M1, M2, top, new
+	dbl := &TypeMeta{TypeDicRow: TypeDicRow{TypeId: 14, Name: "double"}}
+	sex := TypeMeta{
+		TypeDicRow: TypeDicRow{TypeId: 101, Name: "SEX", Digest: "sex"},
+		Enum:       []TypeEnumRow{{TypeId: 101, EnumId: 0, Name: "F"}, {TypeId: 101, EnumId: 1, Name: "M"}},
+	}
+	srcLevel := TypeMeta{
+		TypeDicRow: TypeDicRow{TypeId: 102, Name: "LEVEL", Digest: "level-1"},
+		Enum:       []TypeEnumRow{{TypeId: 102, EnumId: 0, Name: "low"}, {TypeId: 102, EnumId: 1, Name: "medium"}, {TypeId: 102, EnumId: 2, Name: "high"}},
+	}
+	dstLevel := TypeMeta{
+		TypeDicRow: TypeDicRow{TypeId: 102, Name: "LEVEL", Digest: "level-2"},
+		Enum: []TypeEnumRow{
+			{TypeId: 102, EnumId: 0, Name: "L"}, {TypeId: 102, EnumId: 1, Name: "M1"}, {TypeId: 102, EnumId: 2, Name: "M2"},
+			{TypeId: 102, EnumId: 3, Name: "top"}, {TypeId: 102, EnumId: 4, Name: "new"},
+		},
+	}
+	mkModel := func(digest string, level *TypeMeta, paramDigest string) *ModelMeta {
+		return &ModelMeta{
+			Model: ModelDicRow{Name: "m", Digest: digest},
+			Type:  []TypeMeta{*dbl, sex, *level},
+			Param: []ParamMeta{{
+				ParamDicRow: ParamDicRow{Name: "salary", Digest: paramDigest, Rank: 2, TypeId: 14},
+				typeOf:      dbl,
+				Dim: []ParamDimsRow{
+					{Name: "sex", TypeId: 101, typeOf: &sex},
+					{Name: "level", TypeId: 102, typeOf: level},
+				},
+			}},
+		}
+	}
+	srcModel := mkModel("m-1", &srcLevel, "p-1")
+	dstModel := mkModel("m-2", &dstLevel, "p-2")
+
+	// compare model versions: level type and salary parameter changed
+	md, err := DiffModel(srcModel, dstModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(md.Type) != 1 || md.Type[0].Name != "LEVEL" || md.Type[0].Status != "changed" ||
+		len(md.Type[0].EnumAdded) != 5 || len(md.Type[0].EnumRemoved) != 3 {
+		t.Error("****FAIL: type difference:", md.Type)
+	}
+	if len(md.Param) != 1 || md.Param[0].Name != "salary" || len(md.Param[0].DimChanged) != 1 || md.Param[0].DimChanged[0] != "level" {
+		t.Error("****FAIL: parameter difference:", md.Param)
+	}
+
+	mkCell := func(sex, level string, v float64) CellCodeParam {
+		return CellCodeParam{cellCodeValue: cellCodeValue{Dims: []string{sex, level}, Value: v}}
+	}
+	src := []CellCodeParam{
+		mkCell("F", "low", 1), mkCell("F", "medium", 10), mkCell("F", "high", 100),
+		mkCell("M", "low", 2), mkCell("M", "medium", 20), mkCell("M", "high", 200),
+	}
+
+	// rename low => L, split medium => M1, M2 and aggregate high => top, no default for new enum: error expected
+	mm := MigrateMap{
+		Type: []MigrateType{{
+			Name:      "LEVEL",
+			Rename:    map[string]string{"low": "L"},
+			Split:     []MigrateSplit{{From: "medium", To: []string{"M1", "M2"}, IsDivide: true}},
+			Aggregate: []MigrateAggregate{{To: "top", From: []string{"high"}}},
+		}},
+	}
+	pm, err := NewParamMigrator(srcModel, dstModel, &mm, "salary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pm.Migrate(src); err == nil {
+		t.Error("****FAIL: expected an error of missing cells")
+	} else {
+		t.Log("OK:", err)
+	}
+
+	// use default value for new enum cells
+	mm.Param = []MigrateParam{{Name: "salary", Default: "0.5"}}
+
+	pm, err = NewParamMigrator(srcModel, dstModel, &mm, "salary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := pm.Migrate(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]float64{
+		"F,L": 1, "F,M1": 5, "F,M2": 5, "F,top": 100, "F,new": 0.5,
+		"M,L": 2, "M,M1": 10, "M,M2": 10, "M,top": 200, "M,new": 0.5,
+	}
+	if len(dst) != len(expected) || pm.NDefault != 2 || pm.NDropped != 0 {
+		t.Error("****FAIL: expected cells:", len(expected), "found:", len(dst), "default:", pm.NDefault, "dropped:", pm.NDropped)
+	}
+	for _, c := range dst {
+		key := c.Dims[0] + "," + c.Dims[1]
+		if v, ok := c.Value.(float64); !ok || v != expected[key] {
+			t.Error("****FAIL: cell:", key, "expected:", expected[key], "found:", c.Value)
+		}
+	}
+
+	// aggregate low and medium into L with max, high dropped
+	mm = MigrateMap{
+		Type: []MigrateType{{
+			Name:      "LEVEL",
+			Aggregate: []MigrateAggregate{{To: "L", From: []string{"low", "medium"}, Fnc: "max"}},
+		}},
+		Param: []MigrateParam{{Name: "salary", Default: "0"}},
+	}
+	pm, err = NewParamMigrator(srcModel, dstModel, &mm, "salary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dst, err = pm.Migrate(src); err != nil {
+		t.Fatal(err)
+	}
+	if pm.NDropped != 2 || pm.NDefault != 8 {
+		t.Error("****FAIL: expected dropped: 2 default: 8, found:", pm.NDropped, pm.NDefault)
+	}
+	for _, c := range dst {
+		if c.Dims[1] == "L" && (c.Dims[0] == "F" && c.Value != 10.0 || c.Dims[0] == "M" && c.Value != 20.0) {
+			t.Error("****FAIL: cell:", c.Dims, "found:", c.Value)
+		}
+	}
+
+	// invalid rules
+	for _, r := range []MigrateType{
+		{Name: "LEVEL", Rename: map[string]string{"low": "unknown"}},
+		{Name: "LEVEL", Rename: map[string]string{"unknown": "L"}},
+		{Name: "LEVEL", Split: []MigrateSplit{{From: "medium"}}},
+		{Name: "LEVEL", Aggregate: []MigrateAggregate{{To: "L", From: []string{"low"}, Fnc: "median"}}},
+	} {
+		if _, err := NewParamMigrator(srcModel, dstModel, &MigrateMap{Type: []MigrateType{r}}, "salary"); err == nil {
+			t.Error("****FAIL: expected an error:", r)
+		} else {
+			t.Log("OK:", err)
+		}
+	}
+
+	// same parameter digest: values copied as is
+	pm, err = NewParamMigrator(srcModel, srcModel, nil, "salary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pm.IsSame {
+		t.Error("****FAIL: expected same parameter")
+	}
+}
@@ -0,0 +1,504 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// WorksetParamSource is a source of workset parameter values: model run or workset where parameter values copied from.
+type WorksetParamSource struct {
+	Name           string // parameter name
+	Kind           string // source kind: copy-run, copy-set, import, merge, template
+	RunName        string // source model run name, if parameter values copied from model run
+	RunDigest      string // source model run digest, if parameter values copied from model run
+	SetName        string // source workset name, if parameter values copied from workset
+	FromModel      string // upstream model name, if parameter values imported from other model
+	ValueDigest    string // digest of workset parameter values after copy
+	UpdateDateTime string // date-time when parameter values copied
+}
+
+// ParamLineage is a lineage of model run parameter: list of steps from model run back to the origin of parameter values.
+type ParamLineage struct {
+	ModelName   string             // model name
+	ModelDigest string             // model digest
+	RunName     string             // model run name
+	RunDigest   string             // model run digest
+	Name        string             // parameter name
+	Step        []ParamLineageStep // lineage steps, first step is a model run, last step is the origin of parameter values
+}
+
+// ParamLineageStep is a step of parameter lineage: model run, workset, copy of parameter values or saved version of workset parameter.
+type ParamLineageStep struct {
+	Kind        string // step kind: run, value-run, base-run, run-option, workset, edit, copy-run, copy-set, import, merge, template
+	RunName     string // model run name
+	RunDigest   string // model run digest
+	SetName     string // workset name
+	FromModel   string // upstream model name, if parameter values imported from other model
+	VersionId   int    // workset parameter version id, if it is an edit step
+	ValueDigest string // digest of parameter values at that step, empty if values not available
+	IsChanged   bool   // if true then parameter values are different from values at previous step
+	Note        string // additional information, e.g.: parameter value from command line
+	UserName    string // user name, if it is an edit step
+	DateTime    string // date-time of the step
+}
+
+// max depth of parameter lineage: number of model runs and worksets to follow
+const maxLineageDepth = 32
+
+// GetWorksetParamSource return sources of workset parameters: model runs or worksets where parameter values copied from.
+// Return empty list if workset parameters were not copied from model run or other workset.
+func GetWorksetParamSource(dbConn *sql.DB, modelDef *ModelMeta, setName string) ([]WorksetParamSource, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+
+	val, err := getWorksetExtra(dbConn, modelDef, setName, lineageWorksetExtra)
+	if err != nil {
+		return nil, err
+	}
+	if val == "" {
+		return []WorksetParamSource{}, nil
+	}
+
+	var srcLst []WorksetParamSource
+	if err = json.Unmarshal([]byte(val), &srcLst); err != nil {
+		return nil, errors.New("invalid workset parameters source: " + setName + ": " + err.Error())
+	}
+	return srcLst, nil
+}
+
+// updateWorksetParamSource store sources of workset parameters.
+// Digest of each parameter values is calculated from current workset values.
+// Parameter source replaces previously stored source of the same parameter.
+// If list of sources is too long then oldest sources are removed.
+func updateWorksetParamSource(dbConn *sql.DB, modelDef *ModelMeta, setName string, srcLst []WorksetParamSource) error {
+
+	if len(srcLst) <= 0 {
+		return nil // nothing to update
+	}
+	dt := helper.MakeDateTime(time.Now())
+
+	for k := range srcLst {
+
+		idx, ok := modelDef.ParamByName(srcLst[k].Name)
+		if !ok {
+			return errors.New("parameter not found: " + srcLst[k].Name)
+		}
+		d, err := paramSetValueDigest(dbConn, modelDef, setName, &modelDef.Param[idx], 0)
+		if err != nil {
+			return err
+		}
+		srcLst[k].ValueDigest = d
+		srcLst[k].UpdateDateTime = dt
+	}
+
+	// merge with existing sources: new source of parameter replaces previous
+	oldLst, err := GetWorksetParamSource(dbConn, modelDef, setName)
+	if err != nil {
+		return err
+	}
+	isNew := map[string]bool{}
+	for k := range srcLst {
+		isNew[srcLst[k].Name] = true
+	}
+	sLst := []WorksetParamSource{}
+	for k := range oldLst {
+		if !isNew[oldLst[k].Name] {
+			sLst = append(sLst, oldLst[k])
+		}
+	}
+	sLst = append(sLst, srcLst...)
+
+	js, err := json.Marshal(sLst)
+	if err != nil {
+		return err
+	}
+	for len(js) > optionDbMax && len(sLst) > 1 {
+		sLst = sLst[1:]
+		if js, err = json.Marshal(sLst); err != nil {
+			return err
+		}
+	}
+	if len(js) > optionDbMax {
+		return errors.New("invalid workset parameters source, it is too long: " + setName)
+	}
+	return updateWorksetExtra(dbConn, modelDef, setName, lineageWorksetExtra, string(js))
+}
+
+// GetRunParamLineage return lineage of model run parameter or all model run parameters if name is empty.
+//
+// Lineage starts from model run and goes back to the origin of parameter values:
+// command line option, input workset, saved versions of workset parameter,
+// model run or workset where parameter values copied from, source model run or workset lineage and so on.
+// Value digest at each step is calculated from parameter values, it is empty if values are not available,
+// for example, if workset deleted or parameter values are from command line.
+func GetRunParamLineage(dbConn *sql.DB, modelDef *ModelMeta, run *RunRow, name string) ([]ParamLineage, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if run == nil {
+		return nil, errors.New("invalid (empty) model run")
+	}
+	if run.ModelId != modelDef.Model.ModelId {
+		return nil, errors.New("model run " + run.Name + " " + run.RunDigest + " does not belong to model " + modelDef.Model.Name + " " + modelDef.Model.Digest)
+	}
+	if !IsRunCompleted(run.Status) {
+		return nil, errors.New("model run not completed: " + run.Name + " " + run.RunDigest)
+	}
+
+	var pLst []*ParamMeta
+	if name != "" {
+		idx, ok := modelDef.ParamByName(name)
+		if !ok {
+			return nil, errors.New("parameter not found: " + name)
+		}
+		pLst = append(pLst, &modelDef.Param[idx])
+	} else {
+		for k := range modelDef.Param {
+			pLst = append(pLst, &modelDef.Param[k])
+		}
+	}
+
+	plLst := []ParamLineage{}
+
+	for _, param := range pLst {
+
+		lc := paramLineageCtx{
+			dbConn:   dbConn,
+			modelDef: modelDef,
+			param:    param,
+			isSeen:   map[string]bool{},
+			step:     []ParamLineageStep{},
+		}
+		if err := lc.runSteps(run, "run", "", 0); err != nil {
+			return nil, err
+		}
+
+		plLst = append(plLst, ParamLineage{
+			ModelName:   modelDef.Model.Name,
+			ModelDigest: modelDef.Model.Digest,
+			RunName:     run.Name,
+			RunDigest:   run.RunDigest,
+			Name:        param.Name,
+			Step:        lc.step,
+		})
+	}
+	return plLst, nil
+}
+
+// paramLineageCtx is a state of parameter lineage: list of steps and model runs and worksets already included
+type paramLineageCtx struct {
+	dbConn   *sql.DB            // database connection
+	modelDef *ModelMeta         // model metadata
+	param    *ParamMeta         // parameter
+	isSeen   map[string]bool    // model runs and worksets already included in lineage
+	step     []ParamLineageStep // lineage steps
+}
+
+// runSteps append model run to lineage steps and follow input workset of the model run.
+func (lc *paramLineageCtx) runSteps(run *RunRow, kind string, prevDigest string, depth int) error {
+
+	if depth > maxLineageDepth || lc.isSeen["run:"+run.RunDigest] {
+		return nil
+	}
+	lc.isSeen["run:"+run.RunDigest] = true
+
+	// parameter values can be stored in other model run if values are the same
+	baseId := 0
+	err := SelectFirst(lc.dbConn,
+		"SELECT base_run_id FROM run_parameter"+
+			" WHERE run_id = "+strconv.Itoa(run.RunId)+" AND parameter_hid = "+strconv.Itoa(lc.param.ParamHid),
+		func(row *sql.Row) error {
+			return row.Scan(&baseId)
+		})
+	switch {
+	case err == sql.ErrNoRows:
+		return errors.New("parameter " + lc.param.Name + " not found in model run " + run.Name + " " + run.RunDigest)
+	case err != nil:
+		return err
+	}
+
+	digest, err := paramRunValueDigest(lc.dbConn, lc.modelDef, lc.param, baseId)
+	if err != nil {
+		return err
+	}
+	lc.step = append(lc.step, ParamLineageStep{
+		Kind:        kind,
+		RunName:     run.Name,
+		RunDigest:   run.RunDigest,
+		ValueDigest: digest,
+		IsChanged:   isLineageDiff(prevDigest, digest),
+		DateTime:    run.UpdateDateTime,
+	})
+
+	if baseId != run.RunId {
+		br, err := GetRun(lc.dbConn, baseId)
+		if err != nil {
+			return err
+		}
+		if br != nil {
+			lc.step = append(lc.step, ParamLineageStep{
+				Kind:        "value-run",
+				RunName:     br.Name,
+				RunDigest:   br.RunDigest,
+				ValueDigest: digest,
+				Note:        "parameter values are stored in that model run",
+				DateTime:    br.UpdateDateTime,
+			})
+		}
+	}
+
+	// parameter value from command line or from input workset of the model run
+	opts, err := GetRunOptions(lc.dbConn, run.RunId)
+	if err != nil {
+		return err
+	}
+	if v, ok := opts["Parameter."+lc.param.Name]; ok {
+		lc.step = append(lc.step, ParamLineageStep{
+			Kind:     "run-option",
+			RunName:  run.Name,
+			Note:     "Parameter." + lc.param.Name + " = " + v,
+			DateTime: run.CreateDateTime,
+		})
+		return nil
+	}
+
+	// find input workset by id first: workset name can be changed or reused by other workset
+	setName := opts["OpenM.SetName"]
+	var ws *WorksetRow
+	if sId, e := strconv.Atoi(opts["OpenM.SetId"]); e == nil && sId > 0 {
+		if ws, err = GetWorkset(lc.dbConn, sId); err != nil {
+			return err
+		}
+		if ws != nil && ws.ModelId != lc.modelDef.Model.ModelId {
+			ws = nil // workset id does not belong to the model
+		}
+	}
+	if ws == nil && setName != "" {
+		if ws, err = GetWorksetByName(lc.dbConn, lc.modelDef.Model.ModelId, setName); err != nil {
+			return err
+		}
+	}
+	if ws == nil || ws.ModelId != lc.modelDef.Model.ModelId {
+		if setName != "" {
+			lc.step = append(lc.step, ParamLineageStep{Kind: "workset", SetName: setName, Note: "workset not found"})
+		}
+		return nil
+	}
+	return lc.setSteps(ws, digest, depth+1)
+}
+
+// setSteps append workset, saved versions of workset parameter and source of parameter values to lineage steps.
+// If parameter is not included in workset then follow workset base run.
+func (lc *paramLineageCtx) setSteps(ws *WorksetRow, prevDigest string, depth int) error {
+
+	if depth > maxLineageDepth || lc.isSeen["set:"+strconv.Itoa(ws.SetId)] {
+		return nil
+	}
+	lc.isSeen["set:"+strconv.Itoa(ws.SetId)] = true
+
+	// if parameter not in workset then values are from workset base run
+	nSub, _, err := GetWorksetParam(lc.dbConn, ws.SetId, lc.param.ParamHid)
+	if err != nil {
+		return err
+	}
+	if nSub <= 0 {
+		lc.step = append(lc.step, ParamLineageStep{
+			Kind:     "workset",
+			SetName:  ws.Name,
+			Note:     "parameter is not included in workset",
+			DateTime: ws.UpdateDateTime,
+		})
+		if ws.BaseRunId <= 0 {
+			return nil
+		}
+		br, err := GetRun(lc.dbConn, ws.BaseRunId)
+		if err != nil {
+			return err
+		}
+		if br == nil {
+			return nil
+		}
+		return lc.runSteps(br, "base-run", prevDigest, depth+1)
+	}
+
+	digest, err := paramSetValueDigest(lc.dbConn, lc.modelDef, ws.Name, lc.param, 0)
+	if err != nil {
+		return err
+	}
+	lc.step = append(lc.step, ParamLineageStep{
+		Kind:        "workset",
+		SetName:     ws.Name,
+		ValueDigest: digest,
+		IsChanged:   isLineageDiff(prevDigest, digest),
+		DateTime:    ws.UpdateDateTime,
+	})
+	prevDigest = digest
+
+	// saved versions of workset parameter, most recent first
+	hLst, err := GetWorksetHistoryList(lc.dbConn, lc.modelDef, ws.Name, lc.param.Name)
+	if err != nil {
+		return err
+	}
+	for k := len(hLst) - 1; k >= 0; k-- {
+
+		d := ""
+		if hLst[k].SubCount > 0 {
+			if d, err = paramSetValueDigest(lc.dbConn, lc.modelDef, ws.Name, lc.param, hLst[k].VersionId); err != nil {
+				return err
+			}
+		}
+		lc.step = append(lc.step, ParamLineageStep{
+			Kind:        "edit",
+			SetName:     ws.Name,
+			VersionId:   hLst[k].VersionId,
+			ValueDigest: d,
+			IsChanged:   isLineageDiff(prevDigest, d),
+			Note:        hLst[k].Action,
+			UserName:    hLst[k].UserName,
+			DateTime:    hLst[k].UpdateDateTime,
+		})
+		if d != "" {
+			prevDigest = d
+		}
+	}
+
+	// source of parameter values: model run or workset where values copied from
+	srcLst, err := GetWorksetParamSource(lc.dbConn, lc.modelDef, ws.Name)
+	if err != nil {
+		return err
+	}
+	var src *WorksetParamSource
+	for k := range srcLst {
+		if srcLst[k].Name == lc.param.Name {
+			src = &srcLst[k]
+			break
+		}
+	}
+	if src == nil {
+		return nil // parameter values entered by user
+	}
+	lc.step = append(lc.step, ParamLineageStep{
+		Kind:        src.Kind,
+		RunName:     src.RunName,
+		RunDigest:   src.RunDigest,
+		SetName:     src.SetName,
+		FromModel:   src.FromModel,
+		ValueDigest: src.ValueDigest,
+		IsChanged:   isLineageDiff(prevDigest, src.ValueDigest),
+		DateTime:    src.UpdateDateTime,
+	})
+	if src.ValueDigest != "" {
+		prevDigest = src.ValueDigest
+	}
+
+	// follow source workset or source model run, upstream model runs are not included
+	switch {
+	case src.FromModel != "":
+		return nil
+	case src.SetName != "" && src.Kind != "merge":
+		w, err := GetWorksetByName(lc.dbConn, lc.modelDef.Model.ModelId, src.SetName)
+		if err != nil {
+			return err
+		}
+		if w != nil {
+			return lc.setSteps(w, prevDigest, depth+1)
+		}
+	case src.RunDigest != "":
+		r, err := GetRunByDigest(lc.dbConn, src.RunDigest)
+		if err != nil {
+			return err
+		}
+		if r != nil && r.ModelId == lc.modelDef.Model.ModelId {
+			return lc.runSteps(r, "run", prevDigest, depth+1)
+		}
+	}
+	return nil
+}
+
+// return true if both digests are not empty and different
+func isLineageDiff(prevDigest, digest string) bool {
+	return prevDigest != "" && digest != "" && prevDigest != digest
+}
+
+// paramRunValueDigest return digest of model run parameter values, run id must be an id of run where values are stored.
+func paramRunValueDigest(dbConn *sql.DB, modelDef *ModelMeta, param *ParamMeta, runId int) (string, error) {
+
+	hMd5, digestFrom, _, err := digestParameterFrom(modelDef, param, "")
+	if err != nil {
+		return "", err
+	}
+
+	// SELECT sub_id, dim0, dim1, param_value FROM ageSex_p2012_817 WHERE run_id = 1234 ORDER BY 1, 2, 3
+	q := "SELECT sub_id, "
+	for k := range param.Dim {
+		q += param.Dim[k].colName + ", "
+	}
+	q += "param_value FROM " + param.DbRunTable + " WHERE run_id = " + strconv.Itoa(runId)
+	q += makeOrderBy(param.Rank, nil, 1)
+
+	scanBuf, fc := scanSqlRowToCellParam(param)
+
+	err = SelectRows(dbConn, q,
+		func(rows *sql.Rows) error {
+
+			if e := rows.Scan(scanBuf...); e != nil {
+				return e
+			}
+			var c = CellParam{cellIdValue: cellIdValue{DimIds: make([]int, param.Rank)}}
+			if e := fc(&c); e != nil {
+				return e
+			}
+			return digestFrom(c)
+		})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hMd5.Sum(nil)), nil
+}
+
+// paramSetValueDigest return digest of workset parameter values or saved version of workset parameter values.
+// If version id is zero then digest of current workset parameter values returned.
+func paramSetValueDigest(dbConn *sql.DB, modelDef *ModelMeta, setName string, param *ParamMeta, versionId int) (string, error) {
+
+	hMd5, digestFrom, _, err := digestParameterFrom(modelDef, param, "")
+	if err != nil {
+		return "", err
+	}
+	cvt := func(src interface{}) (bool, error) {
+		return true, digestFrom(src)
+	}
+
+	// current values are resolved values: if parameter is sparse then it includes cells from base run or default workset
+	if versionId > 0 {
+		err = ReadWorksetHistoryTo(dbConn, modelDef, setName, param.Name, versionId, cvt)
+	} else {
+		var w *WorksetRow
+		if w, err = GetWorksetByName(dbConn, modelDef.Model.ModelId, setName); err == nil {
+			if w == nil {
+				return "", errors.New("workset not found: " + setName)
+			}
+			_, err = ReadParameterTo(dbConn, modelDef, &ReadParamLayout{ReadLayout: ReadLayout{Name: param.Name, FromId: w.SetId}, IsFromSet: true}, cvt)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hMd5.Sum(nil)), nil
+}
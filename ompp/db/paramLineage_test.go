@@ -0,0 +1,150 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRunParamLineage(t *testing.T) {
+
+	dbConn, modelDef, langDef := createTestModel(t)
+
+	// insert model run option
+	addRunOption := func(runId int, key, val string) {
+		_, err := dbConn.Exec("INSERT INTO run_option (run_id, option_key, option_value)" +
+			" VALUES (" + strconv.Itoa(runId) + ", " + ToQuoted(key) + ", " + ToQuoted(val) + ")")
+		if err != nil {
+			t.Fatal("****FAIL: insert run option:", runId, key, err)
+		}
+	}
+	// return lineage of model run parameter
+	lineageOf := func(runId int, name string) []ParamLineageStep {
+		run, err := GetRun(dbConn.DB, runId)
+		if err != nil || run == nil {
+			t.Fatal("****FAIL: read model run:", runId, err)
+		}
+		plLst, err := GetRunParamLineage(dbConn.DB, modelDef, run, name)
+		if err != nil {
+			t.Fatal("****FAIL: parameter lineage:", runId, name, err)
+		}
+		if len(plLst) != 1 || plLst[0].Name != name || plLst[0].RunDigest != run.RunDigest {
+			t.Fatal("****FAIL: expected lineage of:", name, "found:", plLst)
+		}
+		return plLst[0].Step
+	}
+	// check lineage steps kind and if parameter values changed at that step
+	checkSteps := func(name string, sLst []ParamLineageStep, kinds []string, isChanged []bool) {
+		if len(sLst) != len(kinds) {
+			t.Fatal("****FAIL:", name, "expected steps:", kinds, "found:", sLst)
+		}
+		for k := range sLst {
+			if sLst[k].Kind != kinds[k] || sLst[k].IsChanged != isChanged[k] {
+				t.Error("****FAIL:", name, "step:", k, "expected:", kinds[k], isChanged[k], "found:", sLst[k].Kind, sLst[k].IsChanged)
+			}
+		}
+	}
+
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, modelDef, langDef, "r1", &v)
+
+	// workset parameter copied from model run and edited after copy
+	v0 := testValues{salary: [2]float64{1, 2}, startAge: 18}
+	ws1 := createTestWorkset(t, dbConn, modelDef, langDef, "ws1", r1, &v0)
+
+	wsRow, err := GetWorkset(dbConn.DB, ws1)
+	if err != nil || wsRow == nil {
+		t.Fatal("****FAIL: read workset:", ws1, err)
+	}
+	runRow, err := GetRun(dbConn.DB, r1)
+	if err != nil || runRow == nil {
+		t.Fatal("****FAIL: read model run:", r1, err)
+	}
+	if err = CopyParameterFromRun(dbConn.DB, modelDef, wsRow, "salarySex", true, runRow); err != nil {
+		t.Fatal("****FAIL: copy parameter from model run:", err)
+	}
+	err = WriteParameterFrom(dbConn, modelDef,
+		&WriteParamLayout{
+			WriteLayout:  WriteLayout{Name: "salarySex", ToId: ws1},
+			SubCount:     1,
+			IsPage:       true,
+			UpdateAction: &WorksetUpdateAction{UserName: "u1", Action: "page"},
+		},
+		paramCellsFrom([]CellParam{testCell(0, 25, 1)}))
+	if err != nil {
+		t.Fatal("****FAIL: update workset parameter:", err)
+	}
+
+	// model run from workset: run, workset, edit, copy from model run, source model run
+	v2 := testValues{salary: [2]float64{10, 25}, startAge: 18, income: [2]float64{100, 200}}
+	r2 := createTestRun(t, dbConn, modelDef, langDef, "r2", &v2)
+	addRunOption(r2, "OpenM.SetId", strconv.Itoa(ws1))
+	addRunOption(r2, "OpenM.SetName", "ws1")
+
+	sLst := lineageOf(r2, "salarySex")
+	checkSteps("salarySex", sLst,
+		[]string{"run", "workset", "edit", "copy-run", "run"},
+		[]bool{false, false, true, false, false})
+
+	if s := sLst[2]; s.SetName != "ws1" || s.VersionId != 1 || s.UserName != "u1" || s.Note != "page" {
+		t.Error("****FAIL: invalid edit step:", s)
+	}
+	if s := sLst[3]; s.RunName != "r1" || s.RunDigest != runRow.RunDigest || s.ValueDigest == "" {
+		t.Error("****FAIL: invalid copy step:", s)
+	}
+	if sLst[0].ValueDigest != sLst[1].ValueDigest || sLst[3].ValueDigest != sLst[4].ValueDigest || sLst[1].ValueDigest == sLst[4].ValueDigest {
+		t.Error("****FAIL: invalid value digests:", sLst)
+	}
+
+	// parameter not copied or edited: values of the run are stored in the first run with the same values
+	checkSteps("startAge", lineageOf(r2, "startAge"), []string{"run", "value-run", "workset"}, []bool{false, false, false})
+
+	// parameter value from command line
+	r3 := createTestRun(t, dbConn, modelDef, langDef, "r3", &v)
+	addRunOption(r3, "Parameter.startAge", "18")
+	addRunOption(r3, "OpenM.SetId", strconv.Itoa(ws1))
+
+	sLst = lineageOf(r3, "startAge")
+	checkSteps("startAge", sLst, []string{"run", "value-run", "run-option"}, []bool{false, false, false})
+	if sLst[2].Note != "Parameter.startAge = 18" {
+		t.Error("****FAIL: invalid run option step:", sLst[2])
+	}
+
+	// parameter not included in workset: follow workset base run
+	ws2 := WorksetMeta{Set: WorksetRow{ModelId: modelDef.Model.ModelId, Name: "ws2", BaseRunId: r1}}
+	if err = ws2.UpdateWorkset(dbConn.DB, modelDef, true, langDef); err != nil {
+		t.Fatal("****FAIL: insert workset:", err)
+	}
+	r4 := createTestRun(t, dbConn, modelDef, langDef, "r4", &v)
+	addRunOption(r4, "OpenM.SetId", strconv.Itoa(ws2.Set.SetId))
+
+	sLst = lineageOf(r4, "salarySex")
+	checkSteps("salarySex", sLst, []string{"run", "value-run", "workset", "base-run"}, []bool{false, false, false, false})
+	if sLst[2].SetName != "ws2" || sLst[2].Note != "parameter is not included in workset" || sLst[3].RunName != "r1" {
+		t.Error("****FAIL: expected workset ws2 and base run r1, found:", sLst[2], sLst[3])
+	}
+
+	// input workset deleted
+	r5 := createTestRun(t, dbConn, modelDef, langDef, "r5", &v)
+	addRunOption(r5, "OpenM.SetName", "deleted")
+	sLst = lineageOf(r5, "salarySex")
+	checkSteps("salarySex", sLst, []string{"run", "value-run", "workset"}, []bool{false, false, false})
+	if sLst[2].SetName != "deleted" || sLst[2].Note != "workset not found" {
+		t.Error("****FAIL: expected not found workset step, found:", sLst[2])
+	}
+
+	// lineage of all model run parameters
+	run, err := GetRun(dbConn.DB, r2)
+	if err != nil {
+		t.Fatal("****FAIL: read model run:", r2, err)
+	}
+	plLst, err := GetRunParamLineage(dbConn.DB, modelDef, run, "")
+	if err != nil || len(plLst) != len(modelDef.Param) {
+		t.Error("****FAIL: expected lineage of all parameters, found:", plLst, err)
+	}
+	if _, err = GetRunParamLineage(dbConn.DB, modelDef, run, "notExist"); err == nil {
+		t.Error("****FAIL: expected error on lineage of not existing parameter")
+	}
+}
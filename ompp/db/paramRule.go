@@ -0,0 +1,623 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParamRule is a validation rule of parameter values.
+//
+// Validation rules are stored in model database as profile options: profile name is model digest + ".parameter-rules",
+// option key is parameter name and option value is json array of parameter rules.
+// Rules are enforced on each update of workset parameter values, model run parameters are not validated.
+//
+// Rule kind is one of:
+//
+//	not-null:  parameter value cannot be NULL
+//	range:     parameter value must be between Min and Max, Min or Max can be omitted
+//	in:        parameter value must be one of the Values, for enum-based parameters values are enum codes
+//	sum:       sum of parameter values along the dimension must be equal to Sum, ex.: probabilities sum to one
+//	monotonic: parameter values must be increasing (or decreasing) along the dimension
+type ParamRule struct {
+	Kind         string   // rule kind: not-null, range, in, sum, monotonic
+	Min          *float64 // range: minimum value, if not null
+	Max          *float64 // range: maximum value, if not null
+	Values       []string // in: list of allowed values, enum codes for enum-based parameters
+	Dim          string   // sum or monotonic: dimension name
+	Sum          float64  // sum: expected sum of values along the dimension
+	Tolerance    float64  // sum: absolute tolerance of the sum, if zero then default 1.0e-6 is used
+	IsDecreasing bool     // monotonic: if true then values must be decreasing else increasing
+	IsStrict     bool     // monotonic: if true then values must be strictly increasing (or decreasing)
+}
+
+// ParamRules is a list of validation rules of the parameter.
+type ParamRules struct {
+	Name  string      // parameter name
+	Rules []ParamRule // parameter validation rules
+}
+
+// ParamRuleViolation describes parameter cell (or group of cells) which violates validation rule.
+//
+// Dimension items are enum codes, for sum and monotonic rules items of the rule dimension are "*".
+type ParamRuleViolation struct {
+	Name  string   // parameter name
+	Kind  string   // rule kind: not-null, range, in, sum, monotonic
+	SubId int      // sub-value id
+	Dims  []string // dimension items as enum codes
+	Msg   string   // violation message, ex.: value 1.2 is greater than 1
+}
+
+// parameter validation rule kinds
+const (
+	notNullParamRule   = "not-null"
+	rangeParamRule     = "range"
+	inParamRule        = "in"
+	sumParamRule       = "sum"
+	monotonicParamRule = "monotonic"
+)
+
+// default absolute tolerance of sum parameter rule
+const defaultParamRuleTolerance = 1.0e-6
+
+// return name of profile where parameter validation rules of the model are stored
+func paramRuleProfileName(modelDigest string) string {
+	return modelExtraProfileName(modelDigest, paramRuleModelExtra)
+}
+
+// GetParamRuleList return validation rules of all model parameters, sorted by parameter name.
+func GetParamRuleList(dbConn *sql.DB, modelDef *ModelMeta) ([]ParamRules, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+
+	p, err := GetProfile(dbConn, paramRuleProfileName(modelDef.Model.Digest))
+	if err != nil {
+		return nil, err
+	}
+
+	prLst := make([]ParamRules, 0, len(p.Opts))
+
+	for key, val := range p.Opts {
+
+		pr := ParamRules{Name: key}
+		if err = json.Unmarshal([]byte(val), &pr.Rules); err != nil {
+			return nil, errors.New("invalid parameter rules: " + key + ": " + err.Error())
+		}
+		prLst = append(prLst, pr)
+	}
+	sort.Slice(prLst, func(i, j int) bool { return prLst[i].Name < prLst[j].Name })
+
+	return prLst, nil
+}
+
+// GetParamRules return validation rules of the parameter or empty list if parameter does not have any rules.
+func GetParamRules(dbConn *sql.DB, modelDef *ModelMeta, name string) ([]ParamRule, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if name == "" {
+		return nil, errors.New("invalid (empty) parameter name")
+	}
+
+	var val string
+	err := SelectFirst(dbConn,
+		"SELECT option_value FROM profile_option"+
+			" WHERE profile_name = "+ToQuoted(paramRuleProfileName(modelDef.Model.Digest))+
+			" AND option_key = "+ToQuoted(name),
+		func(row *sql.Row) error {
+			return row.Scan(&val)
+		})
+	switch {
+	case err == sql.ErrNoRows:
+		return []ParamRule{}, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var rules []ParamRule
+	if err = json.Unmarshal([]byte(val), &rules); err != nil {
+		return nil, errors.New("invalid parameter rules: " + name + ": " + err.Error())
+	}
+	return rules, nil
+}
+
+// UpdateParamRules insert new or replace existing validation rules of the parameter.
+// If list of rules is empty then parameter rules are deleted.
+//
+// Rules are checked before update: rule kind must be known, range, sum and monotonic rules require numeric parameter,
+// dimension of sum or monotonic rule must exist and values of in rule must be valid enum codes.
+func UpdateParamRules(dbConn *sql.DB, modelDef *ModelMeta, name string, rules []ParamRule) error {
+
+	// validate parameters
+	if modelDef == nil {
+		return errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if name == "" {
+		return errors.New("invalid (empty) parameter name")
+	}
+	k, ok := modelDef.ParamByName(name)
+	if !ok {
+		return errors.New("parameter not found: " + name)
+	}
+
+	if len(rules) <= 0 {
+		return DeleteProfileOption(dbConn, paramRuleProfileName(modelDef.Model.Digest), name)
+	}
+
+	// check rules and store it as profile option
+	if _, err := compileParamRules(&modelDef.Param[k], rules); err != nil {
+		return err
+	}
+	js, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	if len(js) > optionDbMax {
+		return errors.New("invalid parameter rules, it is too long: " + name)
+	}
+
+	return UpdateProfileOption(dbConn, paramRuleProfileName(modelDef.Model.Digest), name, string(js))
+}
+
+// DeleteParamRules delete validation rules of the parameter.
+func DeleteParamRules(dbConn *sql.DB, modelDef *ModelMeta, name string) error {
+
+	// validate parameters
+	if modelDef == nil {
+		return errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if name == "" {
+		return errors.New("invalid (empty) parameter name")
+	}
+
+	return DeleteProfileOption(dbConn, paramRuleProfileName(modelDef.Model.Digest), name)
+}
+
+// ValidateWorkset check values of all workset parameters by parameter validation rules and return list of violations.
+//
+// Only parameters included in workset are validated, parameters from workset base run are not.
+// If there are no violations then return is empty list.
+func ValidateWorkset(dbConn *sql.DB, modelDef *ModelMeta, setName string) ([]ParamRuleViolation, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+
+	ws, err := GetWorksetByName(dbConn, modelDef.Model.ModelId, setName)
+	if err != nil {
+		return nil, err
+	}
+	if ws == nil {
+		return nil, errors.New("workset not found: " + setName)
+	}
+
+	prLst, err := GetParamRuleList(dbConn, modelDef)
+	if err != nil {
+		return nil, err
+	}
+
+	vLst := []ParamRuleViolation{}
+
+	for _, pr := range prLst {
+
+		k, ok := modelDef.ParamByName(pr.Name)
+		if !ok || len(pr.Rules) <= 0 {
+			continue // rules of parameter which is not in the model
+		}
+		param := &modelDef.Param[k]
+
+		nSub, _, err := GetWorksetParam(dbConn, ws.SetId, param.ParamHid)
+		if err != nil {
+			return nil, err
+		}
+		if nSub <= 0 {
+			continue // parameter not in workset
+		}
+
+		cLst := []CellParam{}
+		scanBuf, fc := scanSqlRowToCellParam(param)
+
+		err = SelectRows(dbConn, paramRuleSelectSql(param, ws.SetId),
+			func(rows *sql.Rows) error {
+				if e := rows.Scan(scanBuf...); e != nil {
+					return e
+				}
+				c := CellParam{cellIdValue: cellIdValue{DimIds: make([]int, param.Rank)}}
+				if e := fc(&c); e != nil {
+					return e
+				}
+				cLst = append(cLst, c)
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		vr, err := validateParamCells(param, pr.Rules, cLst)
+		if err != nil {
+			return nil, err
+		}
+		vLst = append(vLst, vr...)
+	}
+
+	return vLst, nil
+}
+
+// trxValidateWorksetParam check workset parameter values by parameter validation rules.
+// It does select parameter values as part of transaction and return an error if there are any violations.
+func trxValidateWorksetParam(trx *sql.Tx, param *ParamMeta, setId int, rules []ParamRule) error {
+
+	if len(rules) <= 0 {
+		return nil // no validation rules
+	}
+
+	cLst := []CellParam{}
+	err := trxReadParameterTo(trx, param, paramRuleSelectSql(param, setId), func(src interface{}) error {
+		if c, ok := src.(CellParam); ok {
+			cLst = append(cLst, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	vLst, err := validateParamCells(param, rules, cLst)
+	if err != nil {
+		return err
+	}
+	if len(vLst) <= 0 {
+		return nil
+	}
+
+	// report first few violations
+	msg := "parameter " + param.Name + " validation failed, violations: " + strconv.Itoa(len(vLst))
+	for k := 0; k < len(vLst) && k < 3; k++ {
+		msg += "; " + vLst[k].Kind + " [" + strconv.Itoa(vLst[k].SubId) + "] [" + strings.Join(vLst[k].Dims, ", ") + "] " + vLst[k].Msg
+	}
+	return errors.New(msg)
+}
+
+// return sql to select workset parameter values ordered by sub-value id and dimensions
+func paramRuleSelectSql(param *ParamMeta, setId int) string {
+
+	q := "SELECT sub_id, "
+	for k := range param.Dim {
+		q += param.Dim[k].colName + ", "
+	}
+	q += "param_value FROM " + param.DbSetTable + " WHERE set_id = " + strconv.Itoa(setId) + makeOrderBy(param.Rank, nil, 1)
+	return q
+}
+
+// compiled parameter validation rule
+type paramRuleCheck struct {
+	ParamRule
+	dix    int              // sum or monotonic rule: dimension index
+	numSet map[float64]bool // in rule: allowed numeric values or enum ids
+	strSet map[string]bool  // in rule: allowed string values
+}
+
+// check parameter validation rules and return compiled rules
+func compileParamRules(param *ParamMeta, rules []ParamRule) ([]paramRuleCheck, error) {
+
+	isNum := param.typeOf.IsFloat() || param.typeOf.IsInt()
+	cLst := make([]paramRuleCheck, len(rules))
+
+	for k := range rules {
+
+		rc := paramRuleCheck{ParamRule: rules[k], dix: -1}
+
+		switch rules[k].Kind {
+		case notNullParamRule:
+
+		case rangeParamRule:
+			if !isNum {
+				return nil, errors.New("invalid parameter rule, " + rules[k].Kind + " rule require numeric parameter: " + param.Name)
+			}
+			if rules[k].Min == nil && rules[k].Max == nil {
+				return nil, errors.New("invalid parameter rule, " + rules[k].Kind + " rule require min or max value: " + param.Name)
+			}
+			if rules[k].Min != nil && rules[k].Max != nil && *rules[k].Min > *rules[k].Max {
+				return nil, errors.New("invalid parameter rule, " + rules[k].Kind + " min value greater than max value: " + param.Name)
+			}
+
+		case inParamRule:
+			if len(rules[k].Values) <= 0 {
+				return nil, errors.New("invalid parameter rule, " + rules[k].Kind + " rule require list of values: " + param.Name)
+			}
+
+			switch {
+			case param.typeOf.IsBool() || !param.typeOf.IsBuiltIn():
+
+				cvt, err := param.typeOf.itemCodeToId(param.Name, false)
+				if err != nil {
+					return nil, err
+				}
+				rc.numSet = map[float64]bool{}
+				for _, v := range rules[k].Values {
+					id, err := cvt(v)
+					if err != nil {
+						return nil, errors.New("invalid parameter rule, " + rules[k].Kind + " invalid value: " + v + ": " + param.Name)
+					}
+					rc.numSet[float64(id)] = true
+				}
+
+			case param.typeOf.IsString():
+				rc.strSet = map[string]bool{}
+				for _, v := range rules[k].Values {
+					rc.strSet[v] = true
+				}
+
+			default:
+				rc.numSet = map[float64]bool{}
+				for _, v := range rules[k].Values {
+					f, err := strconv.ParseFloat(v, 64)
+					if err != nil {
+						return nil, errors.New("invalid parameter rule, " + rules[k].Kind + " invalid value: " + v + ": " + param.Name)
+					}
+					rc.numSet[f] = true
+				}
+			}
+
+		case sumParamRule, monotonicParamRule:
+			if !isNum {
+				return nil, errors.New("invalid parameter rule, " + rules[k].Kind + " rule require numeric parameter: " + param.Name)
+			}
+			for j := range param.Dim {
+				if param.Dim[j].Name == rules[k].Dim {
+					rc.dix = j
+					break
+				}
+			}
+			if rc.dix < 0 {
+				return nil, errors.New("invalid parameter rule, " + rules[k].Kind + " parameter " + param.Name + " does not have dimension " + rules[k].Dim)
+			}
+			if rules[k].Tolerance < 0 {
+				return nil, errors.New("invalid parameter rule, " + rules[k].Kind + " tolerance cannot be negative: " + param.Name)
+			}
+
+		default:
+			return nil, errors.New("invalid parameter rule kind: " + rules[k].Kind + ": " + param.Name)
+		}
+		cLst[k] = rc
+	}
+	return cLst, nil
+}
+
+// validateParamCells check parameter cells by validation rules and return list of violations.
+// Cells expected to be ordered by sub-value id and dimensions.
+func validateParamCells(param *ParamMeta, rules []ParamRule, cells []CellParam) ([]ParamRuleViolation, error) {
+
+	rcLst, err := compileParamRules(param, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// dimension enum id to enum code converters
+	dimCvt := make([]func(int) (string, error), param.Rank)
+	for k := range param.Dim {
+		f, err := param.Dim[k].typeOf.itemIdToCode(param.Dim[k].Name, false)
+		if err != nil {
+			return nil, err
+		}
+		dimCvt[k] = f
+	}
+
+	// make violation of cell or cells group, dimension items of group dimension are "*"
+	mkViolation := func(kind string, c *CellParam, dix int, msg string) ParamRuleViolation {
+
+		v := ParamRuleViolation{Name: param.Name, Kind: kind, SubId: c.SubId, Dims: make([]string, param.Rank), Msg: msg}
+		for k := range c.DimIds {
+			if k == dix {
+				v.Dims[k] = "*"
+				continue
+			}
+			if s, e := dimCvt[k](c.DimIds[k]); e == nil {
+				v.Dims[k] = s
+			} else {
+				v.Dims[k] = strconv.Itoa(c.DimIds[k])
+			}
+		}
+		return v
+	}
+	fmtVal := func(f float64) string {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	vLst := []ParamRuleViolation{}
+
+	for _, rc := range rcLst {
+
+		switch rc.Kind {
+
+		case notNullParamRule:
+			for k := range cells {
+				if cells[k].IsNull {
+					vLst = append(vLst, mkViolation(rc.Kind, &cells[k], -1, "value is NULL"))
+				}
+			}
+
+		case rangeParamRule:
+			for k := range cells {
+				f, ok := paramValueToFloat(&cells[k])
+				if !ok {
+					continue // NULL value checked by not-null rule
+				}
+				if rc.Min != nil && f < *rc.Min {
+					vLst = append(vLst, mkViolation(rc.Kind, &cells[k], -1, "value "+fmtVal(f)+" is less than "+fmtVal(*rc.Min)))
+				}
+				if rc.Max != nil && f > *rc.Max {
+					vLst = append(vLst, mkViolation(rc.Kind, &cells[k], -1, "value "+fmtVal(f)+" is greater than "+fmtVal(*rc.Max)))
+				}
+			}
+
+		case inParamRule:
+			for k := range cells {
+				if cells[k].IsNull {
+					continue // NULL value checked by not-null rule
+				}
+				if rc.strSet != nil {
+					if s, ok := cells[k].Value.(string); !ok || !rc.strSet[s] {
+						vLst = append(vLst, mkViolation(rc.Kind, &cells[k], -1, "value is not in the list of allowed values"))
+					}
+					continue
+				}
+				f, ok := paramValueToFloat(&cells[k])
+				if !ok || !rc.numSet[f] {
+					vLst = append(vLst, mkViolation(rc.Kind, &cells[k], -1, "value is not in the list of allowed values"))
+				}
+			}
+
+		case sumParamRule:
+
+			tol := rc.Tolerance
+			if tol <= 0 {
+				tol = defaultParamRuleTolerance
+			}
+			for _, g := range groupParamCells(cells, rc.dix) {
+
+				sum := 0.0
+				for _, k := range g {
+					if f, ok := paramValueToFloat(&cells[k]); ok {
+						sum += f
+					}
+				}
+				if math.Abs(sum-rc.Sum) > tol {
+					vLst = append(vLst, mkViolation(rc.Kind, &cells[g[0]], rc.dix, "sum "+fmtVal(sum)+" is not equal to "+fmtVal(rc.Sum)))
+				}
+			}
+
+		case monotonicParamRule:
+
+			for _, g := range groupParamCells(cells, rc.dix) {
+
+				isPrev := false
+				prev := 0.0
+				for _, k := range g {
+
+					f, ok := paramValueToFloat(&cells[k])
+					if !ok {
+						continue // NULL value checked by not-null rule
+					}
+					if isPrev {
+						isOk := true
+						switch {
+						case !rc.IsDecreasing && rc.IsStrict:
+							isOk = f > prev
+						case !rc.IsDecreasing:
+							isOk = f >= prev
+						case rc.IsStrict:
+							isOk = f < prev
+						default:
+							isOk = f <= prev
+						}
+						if !isOk {
+							msg := "values are not increasing: "
+							if rc.IsDecreasing {
+								msg = "values are not decreasing: "
+							}
+							vLst = append(vLst, mkViolation(rc.Kind, &cells[k], rc.dix, msg+fmtVal(prev)+", "+fmtVal(f)))
+							break
+						}
+					}
+					isPrev = true
+					prev = f
+				}
+			}
+		}
+	}
+
+	return vLst, nil
+}
+
+// return groups of cells indices where cells have the same sub-value id and all dimensions except of group dimension.
+// Inside of each group cells are ordered by enum id of group dimension.
+func groupParamCells(cells []CellParam, dix int) [][]int {
+
+	gMap := map[string]int{}
+	gLst := [][]int{}
+
+	for k := range cells {
+
+		key := strconv.Itoa(cells[k].SubId)
+		for j := range cells[k].DimIds {
+			if j != dix {
+				key += "," + strconv.Itoa(cells[k].DimIds[j])
+			}
+		}
+		n, ok := gMap[key]
+		if !ok {
+			n = len(gLst)
+			gMap[key] = n
+			gLst = append(gLst, []int{})
+		}
+		gLst[n] = append(gLst[n], k)
+	}
+
+	for _, g := range gLst {
+		sort.SliceStable(g, func(i, j int) bool { return cells[g[i]].DimIds[dix] < cells[g[j]].DimIds[dix] })
+	}
+	return gLst
+}
+
+// return parameter cell value as float64 and true or false if value is NULL or not numeric
+func paramValueToFloat(c *CellParam) (float64, bool) {
+
+	if c.IsNull {
+		return 0, false
+	}
+	return valueToFloat(c.Value)
+}
+
+// return value as float64 and true or false if value is not numeric
+func valueToFloat(src interface{}) (float64, bool) {
+
+	switch v := src.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"testing"
+)
+
+func TestValidateParamCells(t *testing.T) {
+
+	// test parameter: probability(age, sex) of double type with age and sex dimensions of int type
+	it := &TypeMeta{TypeDicRow: TypeDicRow{TypeId: 7, Name: "int"}}
+	param := testParamOf("probability", 14, "double", false,
+		ParamDimsRow{Name: "age", TypeId: 7, typeOf: it, colName: "dim0"},
+		ParamDimsRow{Name: "sex", TypeId: 7, typeOf: it, colName: "dim1"},
+	)
+	cells := []CellParam{
+		testCell(0, 0.4, 10, 0),
+		testCell(0, 0.6, 10, 1),
+		testCell(0, 0.5, 20, 0),
+		testCell(0, 0.7, 20, 1),
+		testCell(0, 0.3, 30, 0),
+		testNullCell(0, 30, 1),
+	}
+	zero := 0.0
+	one := 1.0
+
+	check := func(name string, rules []ParamRule, nExpected int) {
+
+		vLst, err := validateParamCells(param, rules, cells)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		for _, v := range vLst {
+			t.Log(name, ":", v.Kind, v.SubId, v.Dims, v.Msg)
+		}
+		if len(vLst) != nExpected {
+			t.Error("****FAIL:", name, "expected violations:", nExpected, "found:", len(vLst))
+		}
+	}
+
+	check("not-null", []ParamRule{{Kind: "not-null"}}, 1)
+	check("range", []ParamRule{{Kind: "range", Min: &zero, Max: &one}}, 0)
+	check("max", []ParamRule{{Kind: "range", Max: &zero}}, 5)
+	check("in", []ParamRule{{Kind: "in", Values: []string{"0.3", "0.4", "0.5", "0.6"}}}, 1)
+	check("sum", []ParamRule{{Kind: "sum", Dim: "sex", Sum: 1}}, 2)
+	check("monotonic", []ParamRule{{Kind: "monotonic", Dim: "age"}}, 1)
+	check("monotonic-decreasing", []ParamRule{{Kind: "monotonic", Dim: "age", IsDecreasing: true}}, 2)
+
+	// invalid rules
+	for _, r := range []ParamRule{
+		{Kind: "unknown"},
+		{Kind: "range"},
+		{Kind: "range", Min: &one, Max: &zero},
+		{Kind: "in"},
+		{Kind: "sum", Dim: "region"},
+		{Kind: "monotonic"},
+	} {
+		if _, err := validateParamCells(param, []ParamRule{r}, cells); err == nil {
+			t.Error("****FAIL: expected an error:", r.Kind)
+		} else {
+			t.Log("OK:", err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Model extra data, e.g. derived measures, is stored in profile_option table.
+// Profile name is model digest and kind of extra data, e.g.: a1b2c3d4.derived-measures,
+// it is specific to model version and deleted together with the model.
+
+// kinds of model extra data, it is a suffix of profile name
+const (
+	derivedModelExtra   = "derived-measures" // derived measures of output tables and microdata
+	paramRuleModelExtra = "parameter-rules"  // parameter validation rules
+)
+
+// list of all kinds of model extra data, it is used to delete extra data together with the model
+var modelExtraKinds = []string{derivedModelExtra, paramRuleModelExtra}
+
+// Workset extra data, e.g. provenance of imported parameters, is stored in profile_option table.
+// Profile name is model digest and kind of extra data, e.g.: a1b2c3d4.workset-import, option key is workset id.
+// Workset id is not changed if workset renamed and it is never reused,
+// workset extra data deleted together with the workset or with the model.
+
+// kinds of workset extra data, it is a suffix of profile name
+const (
+	importWorksetExtra   = "workset-import"   // provenance of parameters imported from upstream model run
+	mergeWorksetExtra    = "workset-merge"    // report of three-way merge which created the workset
+	templateWorksetExtra = "workset-template" // template which was applied to create the workset
+	lineageWorksetExtra  = "workset-lineage"  // sources of workset parameters: model runs or worksets where values copied from
+	sparseWorksetExtra   = "workset-sparse"   // sparse workset parameters: only overridden cells are stored in workset
+)
+
+// list of all kinds of workset extra data, it is used to delete extra data together with the workset
+var worksetExtraKinds = []string{importWorksetExtra, mergeWorksetExtra, templateWorksetExtra, lineageWorksetExtra, sparseWorksetExtra}
+
+// return name of profile where model extra data of specified kind is stored
+func modelExtraProfileName(modelDigest string, kind string) string {
+	return modelDigest + "." + kind
+}
+
+// delete all extra data of the model: profile_option and profile_lst rows.
+// It does update as part of transaction.
+func trxDeleteModelExtra(trx *sql.Tx, modelId string) error {
+
+	// get model digest
+	digest := ""
+	err := TrxSelectFirst(trx,
+		"SELECT model_digest FROM model_dic WHERE model_id = "+modelId,
+		func(row *sql.Row) error {
+			return row.Scan(&digest)
+		})
+	switch {
+	case err == sql.ErrNoRows || err == nil && digest == "":
+		return nil // model not found: nothing to do
+	case err != nil:
+		return err
+	}
+
+	// delete all kinds of model extra data and extra data of all model worksets
+	pnLst := make([]string, 0, len(modelExtraKinds)+len(worksetExtraKinds))
+	for _, kind := range append(append([]string{}, modelExtraKinds...), worksetExtraKinds...) {
+		pnLst = append(pnLst, ToQuoted(modelExtraProfileName(digest, kind)))
+	}
+	inLst := strings.Join(pnLst, ", ")
+
+	if err = TrxUpdate(trx, "DELETE FROM profile_option WHERE profile_name IN ("+inLst+")"); err != nil {
+		return err
+	}
+	return TrxUpdate(trx, "DELETE FROM profile_lst WHERE profile_name IN ("+inLst+")")
+}
+
+// return workset extra data by workset name
+// or "" empty string if workset not found or if it does not have extra data of that kind.
+func getWorksetExtra(dbConn *sql.DB, modelDef *ModelMeta, setName string, kind string) (string, error) {
+
+	ws, err := GetWorksetByName(dbConn, modelDef.Model.ModelId, setName)
+	if err != nil {
+		return "", err
+	}
+	if ws == nil {
+		return "", nil // workset not found
+	}
+	return getWorksetExtraById(dbConn, modelDef.Model.Digest, ws.SetId, kind)
+}
+
+// return workset extra data by workset id or "" empty string if workset does not have extra data of that kind.
+func getWorksetExtraById(dbConn *sql.DB, modelDigest string, setId int, kind string) (string, error) {
+
+	val := ""
+	err := SelectFirst(dbConn,
+		"SELECT option_value FROM profile_option"+
+			" WHERE profile_name = "+ToQuoted(modelExtraProfileName(modelDigest, kind))+
+			" AND option_key = "+ToQuoted(strconv.Itoa(setId)),
+		func(row *sql.Row) error {
+			return row.Scan(&val)
+		})
+	switch {
+	case err == sql.ErrNoRows:
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+	return val, nil
+}
+
+// insert new or replace existing workset extra data, workset must exist.
+func updateWorksetExtra(dbConn *sql.DB, modelDef *ModelMeta, setName string, kind string, val string) error {
+
+	ws, err := GetWorksetByName(dbConn, modelDef.Model.ModelId, setName)
+	if err != nil {
+		return err
+	}
+	if ws == nil {
+		return errors.New("workset not found: " + setName)
+	}
+	return UpdateProfileOption(dbConn, modelExtraProfileName(modelDef.Model.Digest, kind), strconv.Itoa(ws.SetId), val)
+}
+
+// delete extra data of the workset: all kinds of workset extra data.
+// It does update as part of transaction.
+func trxDeleteWorksetExtra(trx *sql.Tx, setId int) error {
+
+	// get model digest
+	digest := ""
+	err := TrxSelectFirst(trx,
+		"SELECT M.model_digest"+
+			" FROM workset_lst W"+
+			" INNER JOIN model_dic M ON (M.model_id = W.model_id)"+
+			" WHERE W.set_id = "+strconv.Itoa(setId),
+		func(row *sql.Row) error {
+			return row.Scan(&digest)
+		})
+	switch {
+	case err == sql.ErrNoRows || err == nil && digest == "":
+		return nil // workset not found: nothing to do
+	case err != nil:
+		return err
+	}
+
+	pnLst := make([]string, len(worksetExtraKinds))
+	for k := range worksetExtraKinds {
+		pnLst[k] = ToQuoted(modelExtraProfileName(digest, worksetExtraKinds[k]))
+	}
+	return TrxUpdate(trx,
+		"DELETE FROM profile_option"+
+			" WHERE profile_name IN ("+strings.Join(pnLst, ", ")+")"+
+			" AND option_key = "+ToQuoted(strconv.Itoa(setId)))
+}
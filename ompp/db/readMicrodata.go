@@ -407,9 +407,6 @@ func ReadMicrodataCalculateTo(
 	if calcLt == nil || len(calcLt.Calculation) <= 0 {
 		return nil, errors.New("invalid (empty) microdata calculation layout: " + layout.Name)
 	}
-	if len(calcLt.GroupBy) <= 0 {
-		return nil, errors.New("invalid (empty) microdata group by attributes: " + layout.Name)
-	}
 	if len(calcLt.Calculation) <= 0 {
 		return nil, errors.New("invalid (empty) microdata calculation expression(s): " + layout.Name)
 	}
@@ -421,6 +418,15 @@ func ReadMicrodataCalculateTo(
 	}
 	entity := &modelDef.Entity[eIdx]
 
+	// replace derived measure names by measure calculation, group by can be specified by derived measure
+	calcLt, err := resolveDerivedMicroCalc(dbConn, modelDef, entity, calcLt)
+	if err != nil {
+		return nil, err
+	}
+	if len(calcLt.GroupBy) <= 0 {
+		return nil, errors.New("invalid (empty) microdata group by attributes: " + layout.Name)
+	}
+
 	// check if model run exist and model run completed
 	runRow, err := GetRun(dbConn, layout.FromId)
 	if err != nil {
@@ -450,6 +456,13 @@ func ReadMicrodataCalculateTo(
 		return nil, errors.New("model run does not contain entity generation: " + layout.GenDigest + " " + entity.Name + " in run, id: " + strconv.Itoa(layout.FromId))
 	}
 
+	// calculate break points of quantile bins from base run attribute values
+	if len(calcLt.Bins) > 0 {
+		if calcLt.Bins, err = resolveMicroBins(dbConn, entity, entityGen, calcLt.Bins, layout.FromId); err != nil {
+			return nil, err
+		}
+	}
+
 	// find group by microdata attributes by name
 	aGroupBy := []EntityAttrRow{}
 
@@ -468,8 +481,10 @@ func ReadMicrodataCalculateTo(
 			}
 			aGroupBy = append(aGroupBy, entity.Attr[aIdx])
 
-			// group by attributes must boolean or not built-in
-			if entity.Attr[aIdx].typeOf.IsBuiltIn() && !entity.Attr[aIdx].typeOf.IsBool() {
+			// group by attributes must boolean or not built-in, numeric attributes must be binned
+			_, isBin := findMicroBin(calcLt.Bins, entity.Attr[aIdx].Name)
+
+			if entity.Attr[aIdx].typeOf.IsBuiltIn() && !entity.Attr[aIdx].typeOf.IsBool() && !isBin {
 				return nil, errors.New("invalid type of entity group by attribute not found by: " + entity.Name + "." + calcLt.GroupBy[j] + " : " + entity.Attr[aIdx].typeOf.Name)
 			}
 		}
@@ -488,14 +503,14 @@ func ReadMicrodataCalculateTo(
 	}
 
 	// translate calculation to sql
-	q, err := translateMicroToSql(modelDef, entity, entityGen, &layout.ReadLayout, calcLt, runIds)
+	q, err := translateMicroToSql(facetOfDb(dbConn), modelDef, entity, entityGen, &layout.ReadLayout, calcLt, runIds)
 	if err != nil {
 		return nil, err
 	}
 
 	// prepare db-row scan conversion buffer: run_id, calculation id, group by attributes, value
 	// and define conversion function to make new cell from scan buffer
-	scanBuf, fc, err := scanSqlRowToCellMicroCalc(entity, aGroupBy)
+	scanBuf, fc, err := scanSqlRowToCellMicroCalc(entity, aGroupBy, calcLt.Bins)
 	if err != nil {
 		return nil, err
 	}
@@ -593,7 +608,8 @@ func ReadMicrodataCalculateTo(
 
 // prepare to scan sql rows and convert each row to CellMicroCalc
 // retun scan buffer to be popualted by rows.Scan() and closure to that buffer into CellMicroCalc
-func scanSqlRowToCellMicroCalc(entity *EntityMeta, aGroupBy []EntityAttrRow) ([]interface{}, func(*CellMicroCalc) error, error) {
+// Binned attribute value is an integer bin index.
+func scanSqlRowToCellMicroCalc(entity *EntityMeta, aGroupBy []EntityAttrRow, bins []MicroBinLayout) ([]interface{}, func(*CellMicroCalc) error, error) {
 
 	nGrp := len(aGroupBy)
 	scanBuf := make([]interface{}, 3+nGrp) // run id, calculation id, group by attributes, calculated value
@@ -607,7 +623,16 @@ func scanSqlRowToCellMicroCalc(entity *EntityMeta, aGroupBy []EntityAttrRow) ([]
 	// for each attribute create conversion function by type
 	for na, ga := range aGroupBy {
 
+		_, isBin := findMicroBin(bins, ga.Name)
+
 		switch {
+		case isBin: // binned attribute: bin index
+
+			var v interface{}
+			scanBuf[2+na] = &v
+
+			fd[na] = func(src interface{}) (attrValue, error) { return attrValue{IsNull: v == nil, Value: v}, nil }
+
 		case ga.typeOf.IsBool(): // logical attribute
 
 			var v interface{}
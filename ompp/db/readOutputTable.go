@@ -494,8 +494,14 @@ func ReadOutputTableCalculteTo(
 		return nil, errors.New("output table not found: " + layout.Name)
 	}
 
+	// replace derived measure names by measure calculation
+	calcLt, err := resolveDerivedTableCalc(dbConn, modelDef, table, calcLt)
+	if err != nil {
+		return nil, err
+	}
+
 	// make sql to select calculated output table expression(s) from model run(s)
-	q, err := translateTableCalcToSql(modelDef, table, &layout.ReadLayout, calcLt, runIds)
+	q, err := translateTableCalcToSql(facetOfDb(dbConn), modelDef, table, &layout.ReadLayout, calcLt, runIds)
 	if err != nil {
 		return nil, err
 	}
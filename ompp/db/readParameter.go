@@ -31,75 +31,6 @@ func ReadParameterTo(dbConn *sql.DB, modelDef *ModelMeta, layout *ReadParamLayou
 		return nil, errors.New("parameter not found: " + layout.Name)
 	}
 
-	// if this is workset parameter then:
-	//   if source workset exist
-	//   check readonly status: if layout.IsEditSet then read-only must be false
-	//   if parameter not in workset then select base run id, it must be >0
-	var srcRunId int
-	var isWsParam bool
-
-	if !layout.IsFromSet {
-		srcRunId = layout.FromId // this is parameter from existing run
-	} else {
-
-		// validate workset: it must exist
-		setRow, err := GetWorkset(dbConn, layout.FromId)
-		if err != nil {
-			return nil, err
-		}
-		if setRow == nil {
-			return nil, errors.New("workset not found, id: " + strconv.Itoa(layout.FromId))
-		}
-
-		// workset readonly status must be compatible with (oposite to) "edit workset" status
-		if layout.IsEditSet && setRow.IsReadonly {
-			return nil, errors.New("cannot edit parameter " + param.Name + " from read-only workset, id: " + strconv.Itoa(layout.FromId))
-		}
-
-		// check is this workset contain the parameter
-		err = SelectFirst(dbConn,
-			"SELECT COUNT(*) FROM workset_parameter"+
-				" WHERE set_id = "+strconv.Itoa(layout.FromId)+
-				" AND parameter_hid = "+strconv.Itoa(param.ParamHid),
-			func(row *sql.Row) error {
-				var n int
-				if err := row.Scan(&n); err != nil {
-					return err
-				}
-				isWsParam = n != 0
-				return nil
-			})
-		switch {
-		case err == sql.ErrNoRows: // unknown error: should never be there
-			return nil, errors.New("cannot count parameter " + param.Name + " in workset, id: " + strconv.Itoa(layout.FromId))
-		case err != nil:
-			return nil, err
-		}
-
-		// if parameter not in that workset then workset must have base run
-		if !isWsParam {
-			if setRow.BaseRunId <= 0 {
-				return nil, errors.New("workset does not contain parameter " + param.Name + " and not run-based, workset id: " + strconv.Itoa(layout.FromId))
-			}
-			srcRunId = setRow.BaseRunId
-		}
-	}
-
-	// if parameter from run (or from workset base run) then:
-	//   check if model run exist and model run completed is completed or in progress
-	if !isWsParam {
-		runRow, err := GetRun(dbConn, srcRunId)
-		if err != nil {
-			return nil, err
-		}
-		if runRow == nil {
-			return nil, errors.New("model run not found, id: " + strconv.Itoa(srcRunId))
-		}
-		if !IsRunCompleted(runRow.Status) && runRow.Status != ProgressRunStatus {
-			return nil, errors.New("model run not completed, id: " + strconv.Itoa(srcRunId))
-		}
-	}
-
 	// make sql to select parameter from model run or workset:
 	//   SELECT sub_id, dim0, dim1, param_value
 	//   FROM ageSex_p2012_817
@@ -114,22 +45,16 @@ func ReadParameterTo(dbConn *sql.DB, modelDef *ModelMeta, layout *ReadParamLayou
 	//   WHERE set_id = 9876
 	//   AND dim1 IN (1, 2, 3, 4)
 	//   ORDER BY 1, 2, 3
+	srcSql, err := paramSourceSql(dbConn, param, layout.FromId, layout.IsFromSet, layout.IsEditSet)
+	if err != nil {
+		return nil, err
+	}
+
 	q := "SELECT sub_id, "
 	for k := range param.Dim {
 		q += param.Dim[k].colName + ", "
 	}
-	q += "param_value FROM "
-
-	if isWsParam {
-		q += param.DbSetTable +
-			" WHERE set_id = " + strconv.Itoa(layout.FromId)
-	} else {
-		q += param.DbRunTable +
-			" WHERE run_id =" +
-			" (SELECT base_run_id FROM run_parameter" +
-			" WHERE run_id = " + strconv.Itoa(srcRunId) +
-			" AND parameter_hid = " + strconv.Itoa(param.ParamHid) + ")"
-	}
+	q += "param_value FROM " + srcSql
 
 	// append sub-value id filter
 	if layout.IsSubId {
@@ -257,7 +182,7 @@ func ReadParameterTo(dbConn *sql.DB, modelDef *ModelMeta, layout *ReadParamLayou
 	}
 
 	// select parameter cells: (sub id, dimension(s) enum ids, parameter value)
-	err := SelectRowsTo(dbConn, q,
+	err = SelectRowsTo(dbConn, q,
 		func(rows *sql.Rows) (bool, error) {
 
 			// if page size is limited then select only a page of rows
@@ -297,6 +222,109 @@ func ReadParameterTo(dbConn *sql.DB, modelDef *ModelMeta, layout *ReadParamLayou
 	return &lt, nil
 }
 
+// Return FROM and WHERE parts of sql to select parameter values from model run or workset, for example:
+//
+//	ageSex_p2012_817 WHERE run_id = (SELECT base_run_id FROM run_parameter WHERE run_id = 1234 AND parameter_hid = 1)
+//
+// or:
+//
+//	ageSex_w2012_817 WHERE set_id = 9876
+//
+// If this is workset parameter then workset must exist and if isEditSet is true then workset must be read-write.
+// If parameter not in workset then it is selected from workset base run.
+// If workset parameter is sparse then overridden cells selected from workset and other cells from base run or default workset.
+// If parameter from model run (or from workset base run) then run must be completed or in progress.
+func paramSourceSql(dbConn *sql.DB, param *ParamMeta, fromId int, isFromSet, isEditSet bool) (string, error) {
+
+	// if this is workset parameter then:
+	//   if source workset exist
+	//   check readonly status: if isEditSet then read-only must be false
+	//   if parameter not in workset then select base run id, it must be >0
+	var srcRunId int
+	var isWsParam bool
+	var setRow *WorksetRow
+
+	if !isFromSet {
+		srcRunId = fromId // this is parameter from existing run
+	} else {
+
+		// validate workset: it must exist
+		var err error
+		setRow, err = GetWorkset(dbConn, fromId)
+		if err != nil {
+			return "", err
+		}
+		if setRow == nil {
+			return "", errors.New("workset not found, id: " + strconv.Itoa(fromId))
+		}
+
+		// workset readonly status must be compatible with (oposite to) "edit workset" status
+		if isEditSet && setRow.IsReadonly {
+			return "", errors.New("cannot edit parameter " + param.Name + " from read-only workset, id: " + strconv.Itoa(fromId))
+		}
+
+		// check is this workset contain the parameter
+		err = SelectFirst(dbConn,
+			"SELECT COUNT(*) FROM workset_parameter"+
+				" WHERE set_id = "+strconv.Itoa(fromId)+
+				" AND parameter_hid = "+strconv.Itoa(param.ParamHid),
+			func(row *sql.Row) error {
+				var n int
+				if err := row.Scan(&n); err != nil {
+					return err
+				}
+				isWsParam = n != 0
+				return nil
+			})
+		switch {
+		case err == sql.ErrNoRows: // unknown error: should never be there
+			return "", errors.New("cannot count parameter " + param.Name + " in workset, id: " + strconv.Itoa(fromId))
+		case err != nil:
+			return "", err
+		}
+
+		// if parameter not in that workset then workset must have base run
+		if !isWsParam {
+			if setRow.BaseRunId <= 0 {
+				return "", errors.New("workset does not contain parameter " + param.Name + " and not run-based, workset id: " + strconv.Itoa(fromId))
+			}
+			srcRunId = setRow.BaseRunId
+		}
+	}
+
+	// if parameter from run (or from workset base run) then:
+	//   check if model run exist and model run completed is completed or in progress
+	if !isWsParam {
+		runRow, err := GetRun(dbConn, srcRunId)
+		if err != nil {
+			return "", err
+		}
+		if runRow == nil {
+			return "", errors.New("model run not found, id: " + strconv.Itoa(srcRunId))
+		}
+		if !IsRunCompleted(runRow.Status) && runRow.Status != ProgressRunStatus {
+			return "", errors.New("model run not completed, id: " + strconv.Itoa(srcRunId))
+		}
+	}
+
+	if isWsParam {
+		isSparse, err := isSparseWorksetParam(dbConn, setRow, param.Name)
+		if err != nil {
+			return "", err
+		}
+		if isSparse {
+			return sparseParamSourceSql(dbConn, param, setRow)
+		}
+		return param.DbSetTable + " WHERE set_id = " + strconv.Itoa(fromId), nil
+	}
+	return param.DbRunTable +
+			" WHERE run_id =" +
+			" (SELECT base_run_id FROM run_parameter" +
+			" WHERE run_id = " + strconv.Itoa(srcRunId) +
+			" AND parameter_hid = " + strconv.Itoa(param.ParamHid) + ")",
+		nil
+}
+
 // trxReadParameterTo read input parameter rows (sub id, dimensions, value) from workset or model run results and process each row by cvtTo().
 func trxReadParameterTo(trx *sql.Tx, param *ParamMeta, query string, cvtTo func(src interface{}) error) error {
 
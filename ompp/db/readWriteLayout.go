@@ -22,11 +22,12 @@ type WriteLayout struct {
 //
 // Double format string is used for digest calculation if value type if float or double.
 type WriteParamLayout struct {
-	WriteLayout        // common write layout: parameter name, run or set id
-	SubCount    int    // sub-values count
-	IsToRun     bool   // if true then write into into model run else into workset
-	IsPage      bool   // if true then write only page of data else all parameter values
-	DoubleFmt   string // used for float model types digest calculation
+	WriteLayout                       // common write layout: parameter name, run or set id
+	SubCount     int                  // sub-values count
+	IsToRun      bool                 // if true then write into into model run else into workset
+	IsPage       bool                 // if true then write only page of data else all parameter values
+	DoubleFmt    string               // used for float model types digest calculation
+	UpdateAction *WorksetUpdateAction // if not nil then save workset parameter version in history and check workset update date-time
 }
 
 // WriteTableLayout describes output table values for insert or update.
@@ -104,6 +105,7 @@ type ReadTableLayout struct {
 	ValueName       string // if not empty then expression or accumulator name to select
 	IsAccum         bool   // if true then select output table accumulator else expression
 	IsAllAccum      bool   // if true then select from all accumulators view else from accumulators table
+	IsGoEval        bool   // if true then calculate in Go else translate calculation into sql
 	ReadSubIdLayout        // sub-value id filter: select rows with only one sub-value id
 }
 
@@ -134,6 +136,7 @@ type ReadPageLayout struct {
 // Comparison expression(s) must contain [base] and [variant] expression(s), ex.: Expr0[base] - Expr0[variant].
 // Calculation measure(s) can include table exprissions, ex.: Expr0 + Expr1
 // or aggregation of table accumulators, ex.: OM_SUM(acc0) / OM_COUNT(acc0)
+// or aggregation of table expressions across all runs, ex.: OM_AVG(Expr0) or OM_SD(Expr0).
 type ReadCompareTableLayout struct {
 	ReadCalculteTableLayout          // output table, base run and comparison expressions or calculations
 	Runs                    []string // runs to compare: list of digest, stamp or name
@@ -143,13 +146,16 @@ type ReadCompareTableLayout struct {
 type ReadCalculteTableLayout struct {
 	ReadLayout                         // output table name, run id, page size, where filters and order by
 	Calculation []CalculateTableLayout // additional measures to calculate
+	IsGoEval    bool                   // if true then calculate in Go else translate calculation into sql
 }
 
 // CalculateLayout describes calculation of output table values.
 // It can be comparison calculation for multiple model runs, ex.: Expr0[base] - Expr0[variant].
+// Or it can be aggregation of output table expressions across multiple model runs, ex.: OM_AVG(Expr0) or OM_SD(Expr0).
 type CalculateTableLayout struct {
 	CalculateLayout      // expression to calculate and layout
 	IsAggr          bool // if true then select output table accumulator else expression
+	IsRunAggr       bool // if true then aggregate output table expressions across all model runs, result returned as base run value
 }
 
 // CalculateLayout describes calculation expression for parameters, output table values or microdata entity.
@@ -160,6 +166,32 @@ type CalculateLayout struct {
 	Name      string // calculated expression name, calc_name column in csv, ex.: Expr0, AVG_Expr0, RATIO_Expro0
 }
 
+// ReadCompareParamLayout to compare parameter values of model runs and worksets with base run or workset.
+//
+// Comparison expression(s) must contain [base] and [variant] parameter values, ex.: ageSex[variant] - ageSex[base].
+// Base and variant values are joined by sub-value id and dimension items.
+// If IsChangedOnly is true then only cells where variant value is different from base value are selected.
+type ReadCompareParamLayout struct {
+	ReadCalculteParamLayout          // parameter name, base run or workset, comparison expressions
+	Runs                    []string // variant runs to compare: list of digest, stamp or name
+	Worksets                []string // variant worksets to compare: list of workset names
+}
+
+// ReadCalculteParamLayout describe parameter read layout and comparison expressions.
+type ReadCalculteParamLayout struct {
+	ReadLayout                      // parameter name, base run id or set id, page size, where filters and order by
+	IsFromSet     bool              // if true then base values are from workset else from model run
+	IsChangedOnly bool              // if true then select only cells where variant value is different from base value
+	Calculation   []CalculateLayout // comparison expressions, ex.: ageSex[variant] - ageSex[base]
+}
+
+// CompareParamSource is a model run or workset to compare parameter values.
+// Run id's and set id's are unique, there is no workset with the same id as model run id.
+type CompareParamSource struct {
+	Id        int  // run id or set id
+	IsFromSet bool // if true then it is a workset else model run
+}
+
 // ReadCompareMicroLayout to compare microdata runs with base run using multiple comparison aggregations and/or calculation aggregations.
 //
 // Comparison aggregation must contain [base] and [variant] attribute(s), ex.: OM_AVG(Income[base] - Income[variant]).
@@ -183,6 +215,32 @@ type ReadCalculteMicroLayout struct {
 type CalculateMicroLayout struct {
 	Calculation []CalculateLayout // aggregation measures, ex.: OM_MIN(Salary), OM_AVG(Income[base] - Income[variant])
 	GroupBy     []string          // attributes to group by
+	Bins        []MicroBinLayout  // binning of numeric group by attributes, ex.: Income into fixed-width ranges
+	IsGoEval    bool              // if true then aggregate in Go else translate aggregation into sql
+}
+
+// BinKind is enum type for binning of numeric microdata attribute values
+type BinKind string
+
+// Kinds of numeric microdata attribute binning.
+const (
+	WidthBin    BinKind = "WIDTH"    // fixed-width bins: Count bins of Width starting from Start, ex.: [0, 10), [10, 20), [20, 30)
+	BreaksBin   BinKind = "BREAKS"   // explicit break points: [0, 18, 65] => < 0, [0, 18), [18, 65), >= 65
+	QuantileBin BinKind = "QUANTILE" // quantile bins: Count of quantiles, ex.: 4 for quartiles, break points found from base run values
+)
+
+// MicroBinLayout define binning of numeric (float or integer) microdata attribute to use it as group by attribute.
+//
+// Bin of attribute value is a zero-based bin index: values below the first break point are in bin 0,
+// values above the last break point are in the last bin.
+// Bin labels are: < 0, [0, 18), [18, 65), >= 65 or Q1, Q2, Q3, Q4 for quantile bins.
+type MicroBinLayout struct {
+	Name   string    // attribute name, it must be a group by attribute, ex.: Income
+	Kind   BinKind   // binning kind: fixed-width, explicit break points or quantile bins
+	Start  float64   // fixed-width bins: start of the first bin
+	Width  float64   // fixed-width bins: bin width
+	Count  int       // fixed-width bins: number of bins, quantile bins: number of quantiles
+	Breaks []float64 // explicit bin break points, in ascending order
 }
 
 // FilterOp is enum type for filter operators in select where conditions
@@ -0,0 +1,680 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// WorksetSparseParam is a sparse workset parameter: only overridden cells are stored in workset.
+type WorksetSparseParam struct {
+	Name           string // parameter name
+	UpdateDateTime string // date-time when parameter became sparse
+}
+
+// WorksetParamStorage is a storage size of workset parameter: number of cells stored in workset and number of cells of resolved parameter.
+type WorksetParamStorage struct {
+	Name          string  // parameter name
+	IsSparse      bool    // if true then only overridden cells are stored in workset
+	BaseRunDigest string  // base run digest, if parameter is sparse and workset is run-based
+	BaseSetName   string  // default workset name, if parameter is sparse and workset is not run-based
+	SubCount      int     // number of parameter sub-values
+	CellCount     int64   // number of cells stored in workset, if parameter is sparse then it is number of overridden cells
+	FullCount     int64   // number of cells of resolved parameter
+	SavedCount    int64   // number of cells which are not stored in workset: resolved from base run or default workset
+	SavedPercent  float64 // saved cells as percent of resolved parameter cells
+}
+
+// return list of sparse workset parameters, it is empty if workset does not have sparse parameters
+func getWorksetSparseParam(dbConn *sql.DB, modelDigest string, setId int) ([]WorksetSparseParam, error) {
+
+	val, err := getWorksetExtraById(dbConn, modelDigest, setId, sparseWorksetExtra)
+	if err != nil {
+		return nil, err
+	}
+	if val == "" {
+		return []WorksetSparseParam{}, nil
+	}
+
+	var spLst []WorksetSparseParam
+	if err = json.Unmarshal([]byte(val), &spLst); err != nil {
+		return nil, errors.New("invalid workset sparse parameters, workset id: " + strconv.Itoa(setId) + ": " + err.Error())
+	}
+	return spLst, nil
+}
+
+// add or remove parameter from the list of sparse workset parameters
+func updateWorksetSparseParam(dbConn *sql.DB, modelDigest string, setId int, paramName string, isSparse bool) error {
+
+	spLst, err := getWorksetSparseParam(dbConn, modelDigest, setId)
+	if err != nil {
+		return err
+	}
+	nLst := []WorksetSparseParam{}
+	for k := range spLst {
+		if spLst[k].Name != paramName {
+			nLst = append(nLst, spLst[k])
+		}
+	}
+	if isSparse {
+		nLst = append(nLst, WorksetSparseParam{Name: paramName, UpdateDateTime: helper.MakeDateTime(time.Now())})
+	}
+
+	js, err := json.Marshal(nLst)
+	if err != nil {
+		return err
+	}
+	if len(js) > optionDbMax {
+		return errors.New("invalid workset sparse parameters, it is too long, workset id: " + strconv.Itoa(setId))
+	}
+	return UpdateProfileOption(dbConn, modelExtraProfileName(modelDigest, sparseWorksetExtra), strconv.Itoa(setId), string(js))
+}
+
+// return list of sparse workset parameters by workset row, it is empty if workset does not have sparse parameters
+func getWorksetSparseParamByRow(dbConn *sql.DB, setRow *WorksetRow) ([]WorksetSparseParam, error) {
+
+	var digest string
+	err := SelectFirst(dbConn,
+		"SELECT model_digest FROM model_dic WHERE model_id = "+strconv.Itoa(setRow.ModelId),
+		func(row *sql.Row) error {
+			return row.Scan(&digest)
+		})
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.New("model not found, id: " + strconv.Itoa(setRow.ModelId))
+	case err != nil:
+		return nil, err
+	}
+	return getWorksetSparseParam(dbConn, digest, setRow.SetId)
+}
+
+// return true if workset parameter is sparse: only overridden cells are stored in workset
+func isSparseWorksetParam(dbConn *sql.DB, setRow *WorksetRow, paramName string) (bool, error) {
+
+	spLst, err := getWorksetSparseParamByRow(dbConn, setRow)
+	if err != nil {
+		return false, err
+	}
+	for k := range spLst {
+		if spLst[k].Name == paramName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// return error if workset has sparse parameters: such workset cannot be read-only.
+// Model does not resolve sparse parameters and input workset of the model run must be read-only.
+func checkWorksetNotSparse(dbConn *sql.DB, setRow *WorksetRow) error {
+
+	spLst, err := getWorksetSparseParamByRow(dbConn, setRow)
+	if err != nil {
+		return err
+	}
+	if len(spLst) > 0 {
+		return errors.New("workset cannot be read-only, it has sparse parameter(s): " + setRow.Name + ": " + spLst[0].Name)
+	}
+	return nil
+}
+
+// return base of sparse workset parameter: base run id if workset is run-based else default workset id.
+// It is an error if workset is not run-based and it is a default workset.
+func sparseParamBase(dbConn *sql.DB, setRow *WorksetRow) (*RunRow, *WorksetRow, error) {
+
+	if setRow.BaseRunId > 0 {
+		runRow, err := GetRun(dbConn, setRow.BaseRunId)
+		if err != nil {
+			return nil, nil, err
+		}
+		if runRow == nil {
+			return nil, nil, errors.New("workset base run not found, id: " + strconv.Itoa(setRow.BaseRunId))
+		}
+		if !IsRunCompleted(runRow.Status) {
+			return nil, nil, errors.New("workset base run not completed: " + runRow.Name + " " + runRow.RunDigest)
+		}
+		return runRow, nil, nil
+	}
+
+	defRow, err := GetDefaultWorkset(dbConn, setRow.ModelId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if defRow == nil || defRow.SetId == setRow.SetId {
+		return nil, nil, errors.New("workset does not have base run and it is a default workset: " + setRow.Name)
+	}
+	return nil, defRow, nil
+}
+
+// Return FROM and WHERE parts of sql to select resolved values of sparse workset parameter:
+// overridden cells from workset and all other cells from base run or from default workset, for example:
+//
+//	(
+//	SELECT sub_id, dim0, dim1, param_value FROM ageSex_w2012_817 WHERE set_id = 22
+//	UNION ALL
+//	SELECT SB.sub_id, SB.dim0, SB.dim1, SB.param_value FROM ageSex_p2012_817 SB
+//	WHERE SB.run_id = (SELECT base_run_id FROM run_parameter WHERE run_id = 11 AND parameter_hid = 1)
+//	AND NOT EXISTS
+//	(
+//	  SELECT * FROM ageSex_w2012_817 SW
+//	  WHERE SW.set_id = 22 AND SW.sub_id = SB.sub_id AND SW.dim0 = SB.dim0 AND SW.dim1 = SB.dim1
+//	)
+//	) SP WHERE 1 = 1
+//
+// Result ends with WHERE clause to allow caller append AND filters, same as for non-sparse parameter.
+func sparseParamSourceSql(dbConn *sql.DB, param *ParamMeta, setRow *WorksetRow) (string, error) {
+
+	runRow, defRow, err := sparseParamBase(dbConn, setRow)
+	if err != nil {
+		return "", err
+	}
+	sId := strconv.Itoa(setRow.SetId)
+
+	q := "(SELECT sub_id, "
+	for k := range param.Dim {
+		q += param.Dim[k].colName + ", "
+	}
+	q += "param_value FROM " + param.DbSetTable + " WHERE set_id = " + sId +
+		" UNION ALL" +
+		" SELECT SB.sub_id, "
+	for k := range param.Dim {
+		q += "SB." + param.Dim[k].colName + ", "
+	}
+
+	if runRow != nil {
+		q += "SB.param_value FROM " + param.DbRunTable + " SB" +
+			" WHERE SB.run_id =" +
+			" (SELECT base_run_id FROM run_parameter" +
+			" WHERE run_id = " + strconv.Itoa(runRow.RunId) +
+			" AND parameter_hid = " + strconv.Itoa(param.ParamHid) + ")"
+	} else {
+		q += "SB.param_value FROM " + param.DbSetTable + " SB" +
+			" WHERE SB.set_id = " + strconv.Itoa(defRow.SetId)
+	}
+
+	q += " AND NOT EXISTS" +
+		" (SELECT * FROM " + param.DbSetTable + " SW" +
+		" WHERE SW.set_id = " + sId + " AND SW.sub_id = SB.sub_id"
+	for k := range param.Dim {
+		q += " AND SW." + param.Dim[k].colName + " = SB." + param.Dim[k].colName
+	}
+	q += ")) SP WHERE 1 = 1"
+
+	return q, nil
+}
+
+// GetWorksetParamStorage return storage size of each workset parameter:
+// number of cells stored in workset, number of cells of resolved parameter and number of saved cells.
+// If parameter is sparse then only overridden cells are stored in workset and other cells resolved from base run or default workset.
+func GetWorksetParamStorage(dbConn *sql.DB, modelDef *ModelMeta, setName string) ([]WorksetParamStorage, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+
+	setRow, err := GetWorksetByName(dbConn, modelDef.Model.ModelId, setName)
+	if err != nil {
+		return nil, err
+	}
+	if setRow == nil {
+		return nil, errors.New("workset not found: " + setName)
+	}
+
+	hIds, nSubs, _, err := GetWorksetParamList(dbConn, setRow.SetId)
+	if err != nil {
+		return nil, err
+	}
+	spLst, err := getWorksetSparseParam(dbConn, modelDef.Model.Digest, setRow.SetId)
+	if err != nil {
+		return nil, err
+	}
+	isSparse := map[string]bool{}
+	for k := range spLst {
+		isSparse[spLst[k].Name] = true
+	}
+
+	count := func(q string) (int64, error) {
+		var n int64
+		err := SelectFirst(dbConn, q,
+			func(row *sql.Row) error {
+				return row.Scan(&n)
+			})
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+		return n, nil
+	}
+
+	psLst := []WorksetParamStorage{}
+
+	for k := range hIds {
+
+		idx, ok := modelDef.ParamByHid(hIds[k])
+		if !ok {
+			return nil, errors.New("parameter not found by Hid: " + strconv.Itoa(hIds[k]))
+		}
+		param := &modelDef.Param[idx]
+
+		ps := WorksetParamStorage{Name: param.Name, IsSparse: isSparse[param.Name], SubCount: nSubs[k]}
+
+		if ps.CellCount, err = count("SELECT COUNT(*) FROM " + param.DbSetTable + " WHERE set_id = " + strconv.Itoa(setRow.SetId)); err != nil {
+			return nil, err
+		}
+		ps.FullCount = ps.CellCount
+
+		if ps.IsSparse {
+
+			runRow, defRow, err := sparseParamBase(dbConn, setRow)
+			if err != nil {
+				return nil, err
+			}
+			if runRow != nil {
+				ps.BaseRunDigest = runRow.RunDigest
+			} else {
+				ps.BaseSetName = defRow.Name
+			}
+
+			srcSql, err := sparseParamSourceSql(dbConn, param, setRow)
+			if err != nil {
+				return nil, err
+			}
+			if ps.FullCount, err = count("SELECT COUNT(*) FROM " + srcSql); err != nil {
+				return nil, err
+			}
+		}
+
+		ps.SavedCount = ps.FullCount - ps.CellCount
+		if ps.FullCount > 0 {
+			ps.SavedPercent = 100.0 * float64(ps.SavedCount) / float64(ps.FullCount)
+		}
+		psLst = append(psLst, ps)
+	}
+	return psLst, nil
+}
+
+// MakeWorksetParameterSparse remove from workset parameter all cells which are the same as in base run or default workset.
+//
+// Only overridden cells are stored in workset and all other cells are resolved from workset base run,
+// or, if workset is not run-based, from default workset.
+// Parameter sub-values must be the same as in base run or default workset.
+// Workset must be read-write and must contain the parameter.
+// If update action not nil then current parameter values saved in workset history.
+// Return storage size of the parameter after update.
+func MakeWorksetParameterSparse(dbConn Dbc, modelDef *ModelMeta, setName string, paramName string, upd *WorksetUpdateAction) (*WorksetParamStorage, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+	idx, ok := modelDef.ParamByName(paramName)
+	if !ok {
+		return nil, errors.New("parameter not found: " + paramName)
+	}
+	param := &modelDef.Param[idx]
+
+	// workset must be read-write and must contain the parameter
+	setRow, err := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, setName)
+	if err != nil {
+		return nil, err
+	}
+	if setRow == nil {
+		return nil, errors.New("workset not found: " + setName)
+	}
+	if setRow.IsReadonly {
+		return nil, errors.New("failed to update: workset is read-only: " + setName)
+	}
+	nSub, _, err := GetWorksetParam(dbConn.DB, setRow.SetId, param.ParamHid)
+	if err != nil {
+		return nil, err
+	}
+	if nSub <= 0 {
+		return nil, errors.New("workset " + setName + " does not contain parameter " + paramName)
+	}
+
+	// read base cells and current workset cells
+	runRow, defRow, err := sparseParamBase(dbConn.DB, setRow)
+	if err != nil {
+		return nil, err
+	}
+	baseLayout := ReadParamLayout{ReadLayout: ReadLayout{Name: paramName}}
+	if runRow != nil {
+		baseLayout.FromId = runRow.RunId
+	} else {
+		baseLayout.FromId = defRow.SetId
+		baseLayout.IsFromSet = true
+	}
+
+	baseCells := map[string]CellParam{}
+	subIds := map[int]bool{}
+
+	_, err = ReadParameterTo(dbConn.DB, modelDef, &baseLayout, func(src interface{}) (bool, error) {
+		c := src.(CellParam)
+		baseCells[sparseCellKey(&c)] = c
+		subIds[c.SubId] = true
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(subIds) != nSub {
+		return nil, errors.New("parameter " + paramName + " sub-values count " + strconv.Itoa(nSub) + " is not the same as base sub-values count " + strconv.Itoa(len(subIds)))
+	}
+
+	cLst := []CellParam{}
+	_, err = ReadParameterTo(dbConn.DB, modelDef, &ReadParamLayout{ReadLayout: ReadLayout{Name: paramName, FromId: setRow.SetId}, IsFromSet: true},
+		func(src interface{}) (bool, error) {
+			c := src.(CellParam)
+			if b, ok := baseCells[sparseCellKey(&c)]; ok && b.IsNull == c.IsNull && (c.IsNull || b.Value == c.Value) {
+				return true, nil // cell is the same as base cell
+			}
+			cLst = append(cLst, c)
+			return true, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// mark parameter as sparse before deleting cells: resolved values are the same before and after update
+	if err = updateWorksetSparseParam(dbConn.DB, modelDef.Model.Digest, setRow.SetId, paramName, true); err != nil {
+		return nil, err
+	}
+
+	n := 0
+	from := func() (interface{}, error) {
+		if n >= len(cLst) {
+			return nil, nil // end of data
+		}
+		n++
+		return cLst[n-1], nil
+	}
+	err = WriteParameterFrom(dbConn, modelDef, &WriteParamLayout{WriteLayout: WriteLayout{Name: paramName, ToId: setRow.SetId}, SubCount: nSub, UpdateAction: upd}, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return findWorksetParamStorage(dbConn.DB, modelDef, setName, paramName)
+}
+
+// ResolveWorksetParameter store all cells of sparse workset parameter in workset: overridden cells and cells resolved from base.
+//
+// After update parameter is not sparse anymore.
+// Workset must be read-write and must contain the parameter.
+// If update action not nil then current parameter values saved in workset history.
+// Return storage size of the parameter after update.
+func ResolveWorksetParameter(dbConn Dbc, modelDef *ModelMeta, setName string, paramName string, upd *WorksetUpdateAction) (*WorksetParamStorage, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+	idx, ok := modelDef.ParamByName(paramName)
+	if !ok {
+		return nil, errors.New("parameter not found: " + paramName)
+	}
+	param := &modelDef.Param[idx]
+
+	// workset must be read-write and must contain the parameter
+	setRow, err := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, setName)
+	if err != nil {
+		return nil, err
+	}
+	if setRow == nil {
+		return nil, errors.New("workset not found: " + setName)
+	}
+	if setRow.IsReadonly {
+		return nil, errors.New("failed to update: workset is read-only: " + setName)
+	}
+	nSub, _, err := GetWorksetParam(dbConn.DB, setRow.SetId, param.ParamHid)
+	if err != nil {
+		return nil, err
+	}
+	if nSub <= 0 {
+		return nil, errors.New("workset " + setName + " does not contain parameter " + paramName)
+	}
+
+	isSparse, err := isSparseWorksetParam(dbConn.DB, setRow, paramName)
+	if err != nil {
+		return nil, err
+	}
+	if !isSparse {
+		return findWorksetParamStorage(dbConn.DB, modelDef, setName, paramName) // nothing to do: parameter is not sparse
+	}
+
+	// read resolved parameter values and write it back into workset
+	cLst := []CellParam{}
+	_, err = ReadParameterTo(dbConn.DB, modelDef, &ReadParamLayout{ReadLayout: ReadLayout{Name: paramName, FromId: setRow.SetId}, IsFromSet: true},
+		func(src interface{}) (bool, error) {
+			cLst = append(cLst, src.(CellParam))
+			return true, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	from := func() (interface{}, error) {
+		if n >= len(cLst) {
+			return nil, nil // end of data
+		}
+		n++
+		return cLst[n-1], nil
+	}
+	err = WriteParameterFrom(dbConn, modelDef, &WriteParamLayout{WriteLayout: WriteLayout{Name: paramName, ToId: setRow.SetId}, SubCount: nSub, UpdateAction: upd}, from)
+	if err != nil {
+		return nil, err
+	}
+
+	// all cells are stored in workset: parameter is not sparse
+	if err = updateWorksetSparseParam(dbConn.DB, modelDef.Model.Digest, setRow.SetId, paramName, false); err != nil {
+		return nil, err
+	}
+
+	return findWorksetParamStorage(dbConn.DB, modelDef, setName, paramName)
+}
+
+// ResolveSparseWorkset create new read-only workset where all parameters are resolved: sparse parameters stored with all cells.
+//
+// New workset has the same base run and the same parameters as source workset, it must not already exist.
+// It is used to run the model, model does not resolve sparse parameters.
+// Return false if source workset does not have any sparse parameters, new workset is not created in that case.
+func ResolveSparseWorkset(dbConn Dbc, modelDef *ModelMeta, langDef *LangMeta, setName string, dstName string) (bool, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return false, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if setName == "" || dstName == "" {
+		return false, errors.New("invalid (empty) workset name")
+	}
+
+	setRow, err := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, setName)
+	if err != nil {
+		return false, err
+	}
+	if setRow == nil {
+		return false, errors.New("workset not found: " + setName)
+	}
+	spLst, err := getWorksetSparseParam(dbConn.DB, modelDef.Model.Digest, setRow.SetId)
+	if err != nil {
+		return false, err
+	}
+	if len(spLst) <= 0 {
+		return false, nil // workset does not have sparse parameters
+	}
+
+	// new workset must not exist
+	w, err := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, dstName)
+	if err != nil {
+		return false, err
+	}
+	if w != nil {
+		return false, errors.New("workset already exists: " + dstName)
+	}
+
+	// create new workset with the same metadata and copy resolved parameter values
+	wf, err := GetWorksetFull(dbConn.DB, setRow, "")
+	if err != nil {
+		return false, err
+	}
+	srcPub, err := wf.ToPublic(dbConn.DB, modelDef)
+	if err != nil {
+		return false, err
+	}
+
+	pub := WorksetPub{
+		WorksetHdrPub: srcPub.WorksetHdrPub,
+		Param:         []ParamRunSetPub{},
+	}
+	pub.Name = dstName
+	pub.IsReadonly = false
+
+	ws, err := pub.FromPublic(dbConn.DB, modelDef)
+	if err != nil {
+		return false, err
+	}
+	if err = ws.UpdateWorkset(dbConn.DB, modelDef, false, langDef); err != nil {
+		return false, err
+	}
+	dstId := ws.Set.SetId
+
+	for k := range srcPub.Param {
+
+		p := srcPub.Param[k]
+		p.ValueDigest = ""
+
+		cLst := []CellParam{}
+		_, err = ReadParameterTo(dbConn.DB, modelDef, &ReadParamLayout{ReadLayout: ReadLayout{Name: p.Name, FromId: setRow.SetId}, IsFromSet: true},
+			func(src interface{}) (bool, error) {
+				cLst = append(cLst, src.(CellParam))
+				return true, nil
+			})
+		if err != nil {
+			return false, err
+		}
+
+		n := 0
+		from := func() (interface{}, error) {
+			if n >= len(cLst) {
+				return nil, nil // end of data
+			}
+			n++
+			return cLst[n-1], nil
+		}
+		if _, err = ws.UpdateWorksetParameterFrom(dbConn, modelDef, true, &p, langDef, nil, from); err != nil {
+			return false, err
+		}
+	}
+
+	if err = UpdateWorksetReadonly(dbConn.DB, dstId, true); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateRunSparseWorkset replace resolved copy of input workset by source sparse workset in model run options.
+//
+// Model does not resolve sparse parameters, model run is using resolved copy of input workset and it is deleted after the run.
+// Run options OpenM.SetName and OpenM.SetId updated to source workset to keep model run input workset, e.g. for parameter lineage.
+// Run options are updated only if model run has such options.
+func UpdateRunSparseWorkset(dbConn *sql.DB, runId int, setRow *WorksetRow) error {
+
+	// validate parameters
+	if runId <= 0 {
+		return errors.New("invalid run id: " + strconv.Itoa(runId))
+	}
+	if setRow == nil || setRow.SetId <= 0 {
+		return errors.New("invalid (empty) source workset")
+	}
+	rId := strconv.Itoa(runId)
+
+	// do update in transaction scope
+	trx, err := dbConn.Begin()
+	if err != nil {
+		return err
+	}
+	err = TrxUpdate(trx,
+		"UPDATE run_option SET option_value = "+ToQuoted(setRow.Name)+
+			" WHERE run_id = "+rId+" AND option_key = "+ToQuoted("OpenM.SetName"))
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+	err = TrxUpdate(trx,
+		"UPDATE run_option SET option_value = "+ToQuoted(strconv.Itoa(setRow.SetId))+
+			" WHERE run_id = "+rId+" AND option_key = "+ToQuoted("OpenM.SetId"))
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+	trx.Commit()
+
+	return nil
+}
+
+// FindTaskSparseWorkset return first workset of modeling task which has sparse parameters
+// or nil if task worksets do not have sparse parameters.
+func FindTaskSparseWorkset(dbConn *sql.DB, taskId int) (*WorksetRow, error) {
+
+	idLst, err := GetTaskSetIds(dbConn, taskId)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range idLst {
+
+		setRow, err := GetWorkset(dbConn, id)
+		if err != nil {
+			return nil, err
+		}
+		if setRow == nil {
+			continue
+		}
+		spLst, err := getWorksetSparseParamByRow(dbConn, setRow)
+		if err != nil {
+			return nil, err
+		}
+		if len(spLst) > 0 {
+			return setRow, nil
+		}
+	}
+	return nil, nil
+}
+
+// return storage size of workset parameter
+func findWorksetParamStorage(dbConn *sql.DB, modelDef *ModelMeta, setName string, paramName string) (*WorksetParamStorage, error) {
+
+	psLst, err := GetWorksetParamStorage(dbConn, modelDef, setName)
+	if err != nil {
+		return nil, err
+	}
+	for k := range psLst {
+		if psLst[k].Name == paramName {
+			return &psLst[k], nil
+		}
+	}
+	return nil, errors.New("workset " + setName + " does not contain parameter " + paramName)
+}
+
+// return parameter cell key: sub-value id and dimension items
+func sparseCellKey(c *CellParam) string {
+	k := strconv.Itoa(c.SubId)
+	for _, d := range c.DimIds {
+		k += "," + strconv.Itoa(d)
+	}
+	return k
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSparseParamSourceSql(t *testing.T) {
+
+	dbConn, modelDef, langDef := createTestModel(t)
+
+	// default workset: first workset of the model, it is not run-based
+	vd := testValues{salary: [2]float64{1, 2}, startAge: 5}
+	defId := createTestWorkset(t, dbConn, modelDef, langDef, "def", 0, &vd)
+
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, modelDef, langDef, "r1", &v)
+
+	// run-based workset and workset based on default workset, only one overridden cell stored in each workset
+	vw := testValues{salary: [2]float64{10, 25}, startAge: 18}
+	ws1 := createTestWorkset(t, dbConn, modelDef, langDef, "ws1", r1, &vw)
+	ws2 := createTestWorkset(t, dbConn, modelDef, langDef, "ws2", 0, &vw)
+
+	k, ok := modelDef.ParamByName("salarySex")
+	if !ok {
+		t.Fatal("****FAIL: parameter not found: salarySex")
+	}
+	param := &modelDef.Param[k]
+
+	for _, setId := range []int{ws1, ws2} {
+		if _, err := dbConn.Exec("DELETE FROM " + param.DbSetTable + " WHERE set_id = " + strconv.Itoa(setId) + " AND dim0 = 0"); err != nil {
+			t.Fatal("****FAIL: delete workset cell:", setId, err)
+		}
+	}
+
+	// return resolved parameter values as map of (dimension item id, value)
+	readSource := func(setId int) (string, map[int]float64) {
+		setRow, err := GetWorkset(dbConn.DB, setId)
+		if err != nil || setRow == nil {
+			t.Fatal("****FAIL: read workset:", setId, err)
+		}
+		q, err := sparseParamSourceSql(dbConn.DB, param, setRow)
+		if err != nil {
+			t.Fatal("****FAIL: sparse parameter sql:", setRow.Name, err)
+		}
+		m := map[int]float64{}
+		err = SelectRows(dbConn.DB, "SELECT sub_id, dim0, param_value FROM "+q+" AND sub_id = 0",
+			func(rows *sql.Rows) error {
+				var nSub, d int
+				var fv float64
+				if err := rows.Scan(&nSub, &d, &fv); err != nil {
+					return err
+				}
+				if _, ok := m[d]; ok {
+					t.Error("****FAIL: duplicate cell:", setRow.Name, d)
+				}
+				m[d] = fv
+				return nil
+			})
+		if err != nil {
+			t.Fatal("****FAIL: select sparse parameter:", setRow.Name, err)
+		}
+		return q, m
+	}
+
+	// run-based workset: cells which are not in workset selected from base run
+	q, m := readSource(ws1)
+	if !strings.Contains(q, " UNION ALL ") || !strings.Contains(q, param.DbRunTable+" SB") {
+		t.Error("****FAIL: expected union with base run parameter table:", q)
+	}
+	if len(m) != 2 || m[0] != 10 || m[1] != 25 {
+		t.Error("****FAIL: expected resolved values: 10, 25 found:", m)
+	}
+
+	// not run-based workset: cells which are not in workset selected from default workset
+	q, m = readSource(ws2)
+	if !strings.Contains(q, " UNION ALL ") || !strings.Contains(q, "SB.set_id = "+strconv.Itoa(defId)) {
+		t.Error("****FAIL: expected union with default workset:", q)
+	}
+	if len(m) != 2 || m[0] != 1 || m[1] != 25 {
+		t.Error("****FAIL: expected resolved values: 1, 25 found:", m)
+	}
+
+	// default workset cannot be resolved from itself
+	defRow, err := GetWorkset(dbConn.DB, defId)
+	if err != nil || defRow == nil {
+		t.Fatal("****FAIL: read workset:", defId, err)
+	}
+	if _, err = sparseParamSourceSql(dbConn.DB, param, defRow); err == nil {
+		t.Error("****FAIL: expected error on sparse parameter of default workset")
+	}
+}
+
+func TestSparseWorkset(t *testing.T) {
+
+	dbConn, modelDef, langDef := createTestModel(t)
+
+	vd := testValues{salary: [2]float64{1, 2}, startAge: 5}
+	createTestWorkset(t, dbConn, modelDef, langDef, "def", 0, &vd)
+
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, modelDef, langDef, "r1", &v)
+
+	vw := testValues{salary: [2]float64{10, 25}, startAge: 18}
+	ws1 := createTestWorkset(t, dbConn, modelDef, langDef, "ws1", r1, &vw)
+	ws2 := createTestWorkset(t, dbConn, modelDef, langDef, "ws2", 0, &vw)
+
+	// make parameter sparse: only overridden cell stored in workset, resolved values are the same
+	upd := &WorksetUpdateAction{UserName: "u1", Action: "sparse"}
+
+	ps, err := MakeWorksetParameterSparse(dbConn, modelDef, "ws1", "salarySex", upd)
+	if err != nil {
+		t.Fatal("****FAIL: make workset parameter sparse:", err)
+	}
+	if !ps.IsSparse || ps.CellCount != 1 || ps.FullCount != 2 || ps.SavedCount != 1 || ps.BaseRunDigest != "t_run_r1" || ps.BaseSetName != "" {
+		t.Error("****FAIL: invalid sparse parameter storage:", ps)
+	}
+	if fv := readTestParam(t, dbConn, modelDef, "salarySex", ws1, true); fv[0] != 10.0 || fv[1] != 25.0 {
+		t.Error("****FAIL: expected resolved values: 10, 25 found:", fv)
+	}
+
+	ps, err = MakeWorksetParameterSparse(dbConn, modelDef, "ws2", "salarySex", upd)
+	if err != nil {
+		t.Fatal("****FAIL: make workset parameter sparse:", err)
+	}
+	if !ps.IsSparse || ps.CellCount != 2 || ps.FullCount != 2 || ps.BaseSetName != "def" {
+		t.Error("****FAIL: expected no cells same as default workset, found:", ps)
+	}
+
+	if _, err = MakeWorksetParameterSparse(dbConn, modelDef, "def", "salarySex", upd); err == nil {
+		t.Error("****FAIL: expected error on sparse parameter of default workset")
+	}
+	if _, err = MakeWorksetParameterSparse(dbConn, modelDef, "ws1", "notExist", upd); err == nil {
+		t.Error("****FAIL: expected error on not existing parameter")
+	}
+
+	// workset which has sparse parameters cannot be read-only
+	if err = UpdateWorksetReadonly(dbConn.DB, ws1, true); err == nil {
+		t.Error("****FAIL: expected error on read-only sparse workset")
+	}
+	if err = UpdateWorksetReadonlyByName(dbConn.DB, modelDef.Model.ModelId, "ws1", true); err == nil {
+		t.Error("****FAIL: expected error on read-only sparse workset by name")
+	}
+	meta := WorksetMeta{Set: WorksetRow{ModelId: modelDef.Model.ModelId, Name: "ws1", IsReadonly: true}}
+	if err = meta.UpdateWorkset(dbConn.DB, modelDef, false, langDef); err == nil {
+		t.Error("****FAIL: expected error on merge read-only sparse workset")
+	}
+	if wr, err := GetWorkset(dbConn.DB, ws1); err != nil || wr == nil || wr.IsReadonly {
+		t.Error("****FAIL: expected read-write workset:", wr, err)
+	}
+
+	// resolved copy of sparse workset: read-only and all cells stored in workset
+	isResolved, err := ResolveSparseWorkset(dbConn, modelDef, langDef, "ws1", "ws1_resolved")
+	if err != nil || !isResolved {
+		t.Fatal("****FAIL: resolve sparse workset:", isResolved, err)
+	}
+	rw, err := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, "ws1_resolved")
+	if err != nil || rw == nil || !rw.IsReadonly || rw.BaseRunId != r1 {
+		t.Fatal("****FAIL: expected read-only resolved workset based on r1, found:", rw, err)
+	}
+	psLst, err := GetWorksetParamStorage(dbConn.DB, modelDef, "ws1_resolved")
+	if err != nil || len(psLst) != len(modelDef.Param) {
+		t.Fatal("****FAIL: expected storage of all parameters, found:", psLst, err)
+	}
+	for _, p := range psLst {
+		if p.IsSparse || p.CellCount != p.FullCount {
+			t.Error("****FAIL: expected not sparse parameter:", p)
+		}
+	}
+	if fv := readTestParam(t, dbConn, modelDef, "salarySex", rw.SetId, true); fv[0] != 10.0 || fv[1] != 25.0 {
+		t.Error("****FAIL: expected resolved values: 10, 25 found:", fv)
+	}
+	if fv := readTestParam(t, dbConn, modelDef, "startAge", rw.SetId, true); fv[0] != 18 && fv[0] != int64(18) {
+		t.Error("****FAIL: expected startAge: 18 found:", fv)
+	}
+
+	if _, err = ResolveSparseWorkset(dbConn, modelDef, langDef, "ws1", "ws1_resolved"); err == nil {
+		t.Error("****FAIL: expected error if resolved workset already exists")
+	}
+	if isResolved, err = ResolveSparseWorkset(dbConn, modelDef, langDef, "def", "def_resolved"); err != nil || isResolved {
+		t.Error("****FAIL: expected workset without sparse parameters not resolved:", isResolved, err)
+	}
+	if w, err := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, "def_resolved"); err != nil || w != nil {
+		t.Error("****FAIL: expected no resolved copy of workset without sparse parameters:", w, err)
+	}
+
+	// resolve parameter in workset: workset can be read-only after all parameters resolved
+	if ps, err = ResolveWorksetParameter(dbConn, modelDef, "ws1", "salarySex", upd); err != nil {
+		t.Fatal("****FAIL: resolve workset parameter:", err)
+	}
+	if ps.IsSparse || ps.CellCount != 2 {
+		t.Error("****FAIL: expected not sparse parameter with 2 cells, found:", ps)
+	}
+	if err = UpdateWorksetReadonly(dbConn.DB, ws1, true); err != nil {
+		t.Error("****FAIL: update workset read-only:", err)
+	}
+	if ps, err = ResolveWorksetParameter(dbConn, modelDef, "ws2", "salarySex", upd); err != nil || ps.IsSparse {
+		t.Error("****FAIL: resolve workset parameter:", ps, err)
+	}
+	if err = UpdateWorksetReadonly(dbConn.DB, ws2, true); err != nil {
+		t.Error("****FAIL: update workset read-only:", err)
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// test model: two parameters and one output table, dimensions are sex: M, F
+//
+//	salarySex[sex] double parameter
+//	startAge       scalar int parameter
+//	incomeSex[sex] output table, accumulator acc0 and expressions expr0 = OM_AVG(acc0), expr1 = OM_SUM(acc0)
+const (
+	testModelName   = "testModel"
+	testModelDigest = "t_model_0001"
+)
+
+// openTestDb create new empty openM++ SQLite database in test temporary directory.
+func openTestDb(t *testing.T) Dbc {
+
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+
+	dbConn, err := Open("Database="+dbPath+"; Timeout=86400; ForeignKeys = 1; OpenMode=Create;", SQLiteDbDriver)
+	if err != nil {
+		t.Fatal("****FAIL: open database:", err)
+	}
+	t.Cleanup(func() { dbConn.Close() })
+
+	bt, err := os.ReadFile(filepath.Join("testdata", "test.ompp.db.create-sqlite.sql"))
+	if err != nil {
+		t.Fatal("****FAIL: read database schema:", err)
+	}
+
+	// remove comments and execute each sql statement
+	src := ""
+	for _, ln := range strings.Split(string(bt), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(ln), "--") {
+			src += ln + "\n"
+		}
+	}
+	for _, q := range strings.Split(src, ";") {
+		if q = strings.TrimSpace(q); q != "" {
+			if _, err = dbConn.Exec(q); err != nil {
+				t.Fatal("****FAIL: create database:", err, q)
+			}
+		}
+	}
+	return dbConn
+}
+
+// makeTestModel return test model metadata, model not yet inserted into database.
+func makeTestModel() *ModelMeta {
+
+	modelDef := ModelMeta{
+		Model: ModelDicRow{
+			Name:            testModelName,
+			Digest:          testModelDigest,
+			Version:         "1.0.0.0",
+			CreateDateTime:  "2021-01-01 00:00:00.000",
+			DefaultLangCode: "EN",
+		},
+		Type: []TypeMeta{
+			{TypeDicRow: TypeDicRow{TypeId: 4, Name: "int", Digest: "_int_"}},
+			{TypeDicRow: TypeDicRow{TypeId: 7, Name: "double", Digest: "_double_"}},
+			{
+				TypeDicRow: TypeDicRow{TypeId: 101, Name: "sex", Digest: "t_sex_0001", DicId: 2, TotalEnumId: 2},
+				Enum: []TypeEnumRow{
+					{TypeId: 101, EnumId: 0, Name: "M"},
+					{TypeId: 101, EnumId: 1, Name: "F"},
+				},
+			},
+		},
+		Param: []ParamMeta{
+			{
+				ParamDicRow: ParamDicRow{ParamId: 0, Name: "salarySex", Digest: "t_salarySex_0001", Rank: 1, TypeId: 7, ImportDigest: "t_salarySex_0001"},
+				Dim:         []ParamDimsRow{{ParamId: 0, DimId: 0, Name: "dim0", TypeId: 101}},
+			},
+			{
+				ParamDicRow: ParamDicRow{ParamId: 1, Name: "startAge", Digest: "t_startAge_0001", Rank: 0, TypeId: 4, ImportDigest: "t_startAge_0001"},
+			},
+		},
+		Table: []TableMeta{
+			{
+				TableDicRow: TableDicRow{TableId: 0, Name: "incomeSex", Digest: "t_incomeSex_0001", IsUser: false, Rank: 1, ExprPos: 0, ImportDigest: "t_incomeSex_0001"},
+				Dim:         []TableDimsRow{{TableId: 0, DimId: 0, Name: "dim0", TypeId: 101, DimSize: 2}},
+				Acc: []TableAccRow{
+					{TableId: 0, AccId: 0, Name: "acc0", SrcAcc: "value_sum()", AccSql: "A.acc_value"},
+				},
+				Expr: []TableExprRow{
+					{TableId: 0, ExprId: 0, Name: "expr0", Decimals: 4, SrcExpr: "OM_AVG(acc0)", ExprSql: "SELECT M1.run_id, M1.dim0, AVG(M1.acc_value) AS expr0 FROM incomeSex_a M1 GROUP BY M1.run_id, M1.dim0"},
+					{TableId: 0, ExprId: 1, Name: "expr1", Decimals: 4, SrcExpr: "OM_SUM(acc0)", ExprSql: "SELECT M1.run_id, M1.dim0, SUM(M1.acc_value) AS expr1 FROM incomeSex_a M1 GROUP BY M1.run_id, M1.dim0"},
+				},
+			},
+		},
+	}
+	modelDef.updateInternals()
+	return &modelDef
+}
+
+// createTestModel create new test database, insert test model and return model metadata as it is in database.
+func createTestModel(t *testing.T) (Dbc, *ModelMeta, *LangMeta) {
+
+	dbConn := openTestDb(t)
+
+	md := makeTestModel()
+	if _, err := UpdateModel(dbConn, md); err != nil {
+		t.Fatal("****FAIL: insert model:", err)
+	}
+	modelDef, err := GetModel(dbConn.DB, testModelName, testModelDigest)
+	if err != nil {
+		t.Fatal("****FAIL: read model:", err)
+	}
+	langDef, err := GetLanguages(dbConn.DB)
+	if err != nil {
+		t.Fatal("****FAIL: read languages:", err)
+	}
+	return dbConn, modelDef, langDef
+}
+
+// testParamOf return parameter metadata of simple type with optional dimensions, parameter is not in database.
+func testParamOf(name string, typeId int, typeName string, isExt bool, dims ...ParamDimsRow) *ParamMeta {
+	return &ParamMeta{
+		ParamDicRow: ParamDicRow{Name: name, Rank: len(dims), IsExtendable: isExt},
+		typeOf:      &TypeMeta{TypeDicRow: TypeDicRow{TypeId: typeId, Name: typeName}},
+		Dim:         dims,
+	}
+}
+
+// testCell return parameter cell of sub-value id with value and dimension items.
+func testCell(subId int, v float64, dimIds ...int) CellParam {
+	return CellParam{cellIdValue: cellIdValue{DimIds: dimIds, Value: v}, SubId: subId}
+}
+
+// testNullCell return parameter cell of sub-value id with NULL value and dimension items.
+func testNullCell(subId int, dimIds ...int) CellParam {
+	return CellParam{cellIdValue: cellIdValue{DimIds: dimIds, IsNull: true, Value: 0.0}, SubId: subId}
+}
+
+// testValues is a values of test model run or workset: salarySex parameter by sex, startAge parameter and incomeSex accumulator by sex.
+type testValues struct {
+	salary   [2]float64
+	startAge int
+	income   [2]float64
+}
+
+// paramCellsFrom return reader of parameter cells, cells must be in order of primary key: sub_id, dim0.
+func paramCellsFrom(cells []CellParam) func() (interface{}, error) {
+	k := 0
+	return func() (interface{}, error) {
+		if k >= len(cells) {
+			return nil, nil
+		}
+		k++
+		return cells[k-1], nil
+	}
+}
+
+// testParamCells return cells of test model parameter.
+func testParamCells(name string, v *testValues) []CellParam {
+	if name == "startAge" {
+		return []CellParam{{cellIdValue: cellIdValue{DimIds: []int{}, Value: v.startAge}}}
+	}
+	return []CellParam{
+		{cellIdValue: cellIdValue{DimIds: []int{0}, Value: v.salary[0]}},
+		{cellIdValue: cellIdValue{DimIds: []int{1}, Value: v.salary[1]}},
+	}
+}
+
+// createTestRun insert completed model run with parameters and output table values.
+func createTestRun(t *testing.T, dbConn Dbc, modelDef *ModelMeta, langDef *LangMeta, name string, v *testValues) int {
+
+	meta := RunMeta{
+		Run: RunRow{
+			ModelId:        modelDef.Model.ModelId,
+			Name:           name,
+			SubCount:       1,
+			SubStarted:     1,
+			SubCompleted:   1,
+			CreateDateTime: "2021-01-01 00:00:00.000",
+			Status:         DoneRunStatus,
+			RunDigest:      "t_run_" + name,
+			RunStamp:       "2021_01_01_00_00_00_000",
+		},
+		Txt:  []RunTxtRow{{LangCode: "EN", Descr: "run " + name}},
+		Opts: map[string]string{"OpenM.RunName": name},
+	}
+	if _, err := meta.UpdateRun(dbConn.DB, modelDef, langDef, ""); err != nil {
+		t.Fatal("****FAIL: insert run:", name, err)
+	}
+	runId := meta.Run.RunId
+
+	for k := range modelDef.Param {
+		err := WriteParameterFrom(dbConn, modelDef,
+			&WriteParamLayout{WriteLayout: WriteLayout{Name: modelDef.Param[k].Name, ToId: runId}, SubCount: 1, IsToRun: true},
+			paramCellsFrom(testParamCells(modelDef.Param[k].Name, v)))
+		if err != nil {
+			t.Fatal("****FAIL: write run parameter:", name, modelDef.Param[k].Name, err)
+		}
+	}
+
+	accLst := []CellAcc{
+		{cellIdValue: cellIdValue{DimIds: []int{0}, Value: v.income[0]}},
+		{cellIdValue: cellIdValue{DimIds: []int{1}, Value: v.income[1]}},
+	}
+	exprLst := []CellExpr{
+		{cellIdValue: cellIdValue{DimIds: []int{0}, Value: v.income[0]}, ExprId: 0},
+		{cellIdValue: cellIdValue{DimIds: []int{1}, Value: v.income[1]}, ExprId: 0},
+		{cellIdValue: cellIdValue{DimIds: []int{0}, Value: v.income[0]}, ExprId: 1},
+		{cellIdValue: cellIdValue{DimIds: []int{1}, Value: v.income[1]}, ExprId: 1},
+	}
+	nAcc, nExpr := 0, 0
+
+	err := WriteOutputTableFrom(dbConn, modelDef,
+		&WriteTableLayout{WriteLayout: WriteLayout{Name: "incomeSex", ToId: runId}, SubCount: 1},
+		func() (interface{}, error) {
+			if nAcc >= len(accLst) {
+				return nil, nil
+			}
+			nAcc++
+			return accLst[nAcc-1], nil
+		},
+		func() (interface{}, error) {
+			if nExpr >= len(exprLst) {
+				return nil, nil
+			}
+			nExpr++
+			return exprLst[nExpr-1], nil
+		})
+	if err != nil {
+		t.Fatal("****FAIL: write run output table:", name, err)
+	}
+
+	return runId
+}
+
+// createTestWorkset insert read-write workset with all test model parameters.
+func createTestWorkset(t *testing.T, dbConn Dbc, modelDef *ModelMeta, langDef *LangMeta, name string, baseRunId int, v *testValues) int {
+
+	meta := WorksetMeta{
+		Set: WorksetRow{ModelId: modelDef.Model.ModelId, Name: name, BaseRunId: baseRunId},
+		Txt: []WorksetTxtRow{{LangCode: "EN", Descr: "workset " + name}},
+	}
+	if err := meta.UpdateWorkset(dbConn.DB, modelDef, true, langDef); err != nil {
+		t.Fatal("****FAIL: insert workset:", name, err)
+	}
+
+	for k := range modelDef.Param {
+		_, err := meta.UpdateWorksetParameterFrom(dbConn, modelDef, true,
+			&ParamRunSetPub{ParamRunSetTxtPub: ParamRunSetTxtPub{Name: modelDef.Param[k].Name}, SubCount: 1},
+			langDef,
+			nil,
+			paramCellsFrom(testParamCells(modelDef.Param[k].Name, v)))
+		if err != nil {
+			t.Fatal("****FAIL: write workset parameter:", name, modelDef.Param[k].Name, err)
+		}
+	}
+	return meta.Set.SetId
+}
+
+// readTestParam return parameter values from model run or workset as map of (dimension item id, value).
+func readTestParam(t *testing.T, dbConn Dbc, modelDef *ModelMeta, name string, fromId int, isFromSet bool) map[int]interface{} {
+
+	vals := map[int]interface{}{}
+
+	_, err := ReadParameterTo(dbConn.DB, modelDef,
+		&ReadParamLayout{ReadLayout: ReadLayout{Name: name, FromId: fromId}, IsFromSet: isFromSet},
+		func(src interface{}) (bool, error) {
+			c, ok := src.(CellParam)
+			if !ok {
+				t.Fatal("****FAIL: invalid parameter cell type:", name)
+			}
+			d := 0
+			if len(c.DimIds) > 0 {
+				d = c.DimIds[0]
+			}
+			vals[d] = c.Value
+			return true, nil
+		})
+	if err != nil {
+		t.Fatal("****FAIL: read parameter:", name, strconv.Itoa(fromId), err)
+	}
+	return vals
+}
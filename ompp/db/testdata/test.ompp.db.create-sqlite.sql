@@ -0,0 +1,605 @@
+--
+-- Copyright (c) 2021 OpenM++
+-- This code is licensed under the MIT license (see LICENSE.txt for details)
+--
+-- openM++ database tables required by ompp/db tests: create empty database in test temporary directory
+-- this is a subset of openM++ SQLite create_db.sql without foreign keys, primary keys and unique constraints are the same
+--
+
+CREATE TABLE id_lst
+(
+  id_key   VARCHAR(32) NOT NULL,
+  id_value INT         NOT NULL,
+  PRIMARY KEY (id_key)
+);
+
+INSERT INTO id_lst (id_key, id_value) VALUES ('openmpp',       105);
+INSERT INTO id_lst (id_key, id_value) VALUES ('lang_id',       100);
+INSERT INTO id_lst (id_key, id_value) VALUES ('model_id',      100);
+INSERT INTO id_lst (id_key, id_value) VALUES ('type_hid',      100);
+INSERT INTO id_lst (id_key, id_value) VALUES ('parameter_hid', 100);
+INSERT INTO id_lst (id_key, id_value) VALUES ('table_hid',     100);
+INSERT INTO id_lst (id_key, id_value) VALUES ('entity_hid',    100);
+INSERT INTO id_lst (id_key, id_value) VALUES ('run_id_set_id', 100);
+
+CREATE TABLE lang_lst
+(
+  lang_id   INT          NOT NULL,
+  lang_code VARCHAR(32)  NOT NULL,
+  lang_name VARCHAR(255) NOT NULL,
+  PRIMARY KEY (lang_id),
+  CONSTRAINT lang_un UNIQUE (lang_code)
+);
+
+INSERT INTO lang_lst (lang_id, lang_code, lang_name) VALUES (0, 'EN', 'English');
+
+CREATE TABLE lang_word
+(
+  lang_id    INT          NOT NULL,
+  word_code  VARCHAR(255) NOT NULL,
+  word_value VARCHAR(255) NOT NULL,
+  PRIMARY KEY (lang_id, word_code)
+);
+
+CREATE TABLE model_dic
+(
+  model_id        INT          NOT NULL,
+  model_name      VARCHAR(255) NOT NULL,
+  model_digest    VARCHAR(32)  NOT NULL,
+  model_type      INT          NOT NULL,
+  model_ver       VARCHAR(32)  NOT NULL,
+  create_dt       VARCHAR(32)  NOT NULL,
+  default_lang_id INT          NOT NULL,
+  PRIMARY KEY (model_id),
+  CONSTRAINT model_dic_un UNIQUE (model_digest)
+);
+
+CREATE TABLE model_dic_txt
+(
+  model_id INT             NOT NULL,
+  lang_id  INT             NOT NULL,
+  descr    VARCHAR(255)    NOT NULL,
+  note     VARCHAR(32000),
+  PRIMARY KEY (model_id, lang_id)
+);
+
+CREATE TABLE model_word
+(
+  model_id   INT          NOT NULL,
+  lang_id    INT          NOT NULL,
+  word_code  VARCHAR(255) NOT NULL,
+  word_value VARCHAR(255),
+  PRIMARY KEY (model_id, lang_id, word_code)
+);
+
+CREATE TABLE type_dic
+(
+  type_hid      INT          NOT NULL,
+  type_name     VARCHAR(255) NOT NULL,
+  type_digest   VARCHAR(32)  NOT NULL,
+  dic_id        INT          NOT NULL,
+  total_enum_id INT          NOT NULL,
+  PRIMARY KEY (type_hid),
+  CONSTRAINT type_dic_un UNIQUE (type_digest)
+);
+
+CREATE TABLE model_type_dic
+(
+  model_id      INT NOT NULL,
+  model_type_id INT NOT NULL,
+  type_hid      INT NOT NULL,
+  PRIMARY KEY (model_id, model_type_id)
+);
+
+CREATE TABLE type_dic_txt
+(
+  type_hid INT             NOT NULL,
+  lang_id  INT             NOT NULL,
+  descr    VARCHAR(255)    NOT NULL,
+  note     VARCHAR(32000),
+  PRIMARY KEY (type_hid, lang_id)
+);
+
+CREATE TABLE type_enum_lst
+(
+  type_hid  INT          NOT NULL,
+  enum_id   INT          NOT NULL,
+  enum_name VARCHAR(255) NOT NULL,
+  PRIMARY KEY (type_hid, enum_id)
+);
+
+CREATE TABLE type_enum_txt
+(
+  type_hid INT             NOT NULL,
+  enum_id  INT             NOT NULL,
+  lang_id  INT             NOT NULL,
+  descr    VARCHAR(255)    NOT NULL,
+  note     VARCHAR(32000),
+  PRIMARY KEY (type_hid, enum_id, lang_id)
+);
+
+CREATE TABLE parameter_dic
+(
+  parameter_hid    INT          NOT NULL,
+  parameter_name   VARCHAR(255) NOT NULL,
+  parameter_digest VARCHAR(32)  NOT NULL,
+  db_run_table     VARCHAR(64)  NOT NULL,
+  db_set_table     VARCHAR(64)  NOT NULL,
+  parameter_rank   INT          NOT NULL,
+  type_hid         INT          NOT NULL,
+  is_extendable    SMALLINT     NOT NULL,
+  num_cumulated    INT          NOT NULL,
+  import_digest    VARCHAR(32)  NOT NULL,
+  PRIMARY KEY (parameter_hid),
+  CONSTRAINT parameter_dic_un UNIQUE (parameter_digest)
+);
+
+CREATE TABLE model_parameter_dic
+(
+  model_id           INT      NOT NULL,
+  model_parameter_id INT      NOT NULL,
+  parameter_hid      INT      NOT NULL,
+  is_hidden          SMALLINT NOT NULL,
+  PRIMARY KEY (model_id, model_parameter_id)
+);
+
+CREATE TABLE model_parameter_import
+(
+  model_id           INT          NOT NULL,
+  model_parameter_id INT          NOT NULL,
+  from_name          VARCHAR(255) NOT NULL,
+  from_model_name    VARCHAR(255) NOT NULL,
+  is_sample_dim      SMALLINT     NOT NULL,
+  PRIMARY KEY (model_id, model_parameter_id, from_name, from_model_name)
+);
+
+CREATE TABLE parameter_dic_txt
+(
+  parameter_hid INT             NOT NULL,
+  lang_id       INT             NOT NULL,
+  descr         VARCHAR(255)    NOT NULL,
+  note          VARCHAR(32000),
+  PRIMARY KEY (parameter_hid, lang_id)
+);
+
+CREATE TABLE parameter_dims
+(
+  parameter_hid INT          NOT NULL,
+  dim_id        INT          NOT NULL,
+  dim_name      VARCHAR(255) NOT NULL,
+  type_hid      INT          NOT NULL,
+  PRIMARY KEY (parameter_hid, dim_id)
+);
+
+CREATE TABLE parameter_dims_txt
+(
+  parameter_hid INT             NOT NULL,
+  dim_id        INT             NOT NULL,
+  lang_id       INT             NOT NULL,
+  descr         VARCHAR(255)    NOT NULL,
+  note          VARCHAR(32000),
+  PRIMARY KEY (parameter_hid, dim_id, lang_id)
+);
+
+CREATE TABLE table_dic
+(
+  table_hid       INT          NOT NULL,
+  table_name      VARCHAR(255) NOT NULL,
+  table_digest    VARCHAR(32)  NOT NULL,
+  table_rank      INT          NOT NULL,
+  is_sparse       SMALLINT     NOT NULL,
+  db_expr_table   VARCHAR(64)  NOT NULL,
+  db_acc_table    VARCHAR(64)  NOT NULL,
+  db_acc_all_view VARCHAR(64)  NOT NULL,
+  import_digest   VARCHAR(32)  NOT NULL,
+  PRIMARY KEY (table_hid),
+  CONSTRAINT table_dic_un UNIQUE (table_digest)
+);
+
+CREATE TABLE model_table_dic
+(
+  model_id       INT      NOT NULL,
+  model_table_id INT      NOT NULL,
+  table_hid      INT      NOT NULL,
+  is_user        SMALLINT NOT NULL,
+  expr_dim_pos   INT      NOT NULL,
+  is_hidden      SMALLINT NOT NULL,
+  PRIMARY KEY (model_id, model_table_id)
+);
+
+CREATE TABLE table_dic_txt
+(
+  table_hid  INT             NOT NULL,
+  lang_id    INT             NOT NULL,
+  descr      VARCHAR(255)    NOT NULL,
+  note       VARCHAR(32000),
+  expr_descr VARCHAR(255)    NOT NULL,
+  expr_note  VARCHAR(32000),
+  PRIMARY KEY (table_hid, lang_id)
+);
+
+CREATE TABLE table_dims
+(
+  table_hid INT          NOT NULL,
+  dim_id    INT          NOT NULL,
+  dim_name  VARCHAR(255) NOT NULL,
+  type_hid  INT          NOT NULL,
+  is_total  SMALLINT     NOT NULL,
+  dim_size  INT          NOT NULL,
+  PRIMARY KEY (table_hid, dim_id)
+);
+
+CREATE TABLE table_dims_txt
+(
+  table_hid INT             NOT NULL,
+  dim_id    INT             NOT NULL,
+  lang_id   INT             NOT NULL,
+  descr     VARCHAR(255)    NOT NULL,
+  note      VARCHAR(32000),
+  PRIMARY KEY (table_hid, dim_id, lang_id)
+);
+
+CREATE TABLE table_acc
+(
+  table_hid  INT             NOT NULL,
+  acc_id     INT             NOT NULL,
+  acc_name   VARCHAR(255)    NOT NULL,
+  is_derived SMALLINT        NOT NULL,
+  acc_src    VARCHAR(255)    NOT NULL,
+  acc_sql    VARCHAR(2048)   NOT NULL,
+  PRIMARY KEY (table_hid, acc_id)
+);
+
+CREATE TABLE table_acc_txt
+(
+  table_hid INT             NOT NULL,
+  acc_id    INT             NOT NULL,
+  lang_id   INT             NOT NULL,
+  descr     VARCHAR(255)    NOT NULL,
+  note      VARCHAR(32000),
+  PRIMARY KEY (table_hid, acc_id, lang_id)
+);
+
+CREATE TABLE table_expr
+(
+  table_hid     INT           NOT NULL,
+  expr_id       INT           NOT NULL,
+  expr_name     VARCHAR(255)  NOT NULL,
+  expr_decimals INT           NOT NULL,
+  expr_src      VARCHAR(255)  NOT NULL,
+  expr_sql      VARCHAR(2048) NOT NULL,
+  PRIMARY KEY (table_hid, expr_id)
+);
+
+CREATE TABLE table_expr_txt
+(
+  table_hid INT             NOT NULL,
+  expr_id   INT             NOT NULL,
+  lang_id   INT             NOT NULL,
+  descr     VARCHAR(255)    NOT NULL,
+  note      VARCHAR(32000),
+  PRIMARY KEY (table_hid, expr_id, lang_id)
+);
+
+CREATE TABLE entity_dic
+(
+  entity_hid    INT          NOT NULL,
+  entity_name   VARCHAR(255) NOT NULL,
+  entity_digest VARCHAR(32)  NOT NULL,
+  PRIMARY KEY (entity_hid),
+  CONSTRAINT entity_dic_un UNIQUE (entity_digest)
+);
+
+CREATE TABLE model_entity_dic
+(
+  model_id        INT NOT NULL,
+  model_entity_id INT NOT NULL,
+  entity_hid      INT NOT NULL,
+  PRIMARY KEY (model_id, model_entity_id)
+);
+
+CREATE TABLE entity_dic_txt
+(
+  entity_hid INT             NOT NULL,
+  lang_id    INT             NOT NULL,
+  descr      VARCHAR(255)    NOT NULL,
+  note       VARCHAR(32000),
+  PRIMARY KEY (entity_hid, lang_id)
+);
+
+CREATE TABLE entity_attr
+(
+  entity_hid  INT          NOT NULL,
+  attr_id     INT          NOT NULL,
+  attr_name   VARCHAR(255) NOT NULL,
+  type_hid    INT          NOT NULL,
+  is_internal SMALLINT     NOT NULL,
+  PRIMARY KEY (entity_hid, attr_id)
+);
+
+CREATE TABLE entity_attr_txt
+(
+  entity_hid INT             NOT NULL,
+  attr_id    INT             NOT NULL,
+  lang_id    INT             NOT NULL,
+  descr      VARCHAR(255)    NOT NULL,
+  note       VARCHAR(32000),
+  PRIMARY KEY (entity_hid, attr_id, lang_id)
+);
+
+CREATE TABLE group_lst
+(
+  model_id     INT          NOT NULL,
+  group_id     INT          NOT NULL,
+  is_parameter SMALLINT     NOT NULL,
+  group_name   VARCHAR(255) NOT NULL,
+  is_hidden    SMALLINT     NOT NULL,
+  PRIMARY KEY (model_id, group_id)
+);
+
+CREATE TABLE group_pc
+(
+  model_id       INT NOT NULL,
+  group_id       INT NOT NULL,
+  child_pos      INT NOT NULL,
+  child_group_id INT NULL,
+  leaf_id        INT NULL,
+  PRIMARY KEY (model_id, group_id, child_pos)
+);
+
+CREATE TABLE group_txt
+(
+  model_id INT             NOT NULL,
+  group_id INT             NOT NULL,
+  lang_id  INT             NOT NULL,
+  descr    VARCHAR(255)    NOT NULL,
+  note     VARCHAR(32000),
+  PRIMARY KEY (model_id, group_id, lang_id)
+);
+
+CREATE TABLE entity_group_lst
+(
+  model_id        INT          NOT NULL,
+  model_entity_id INT          NOT NULL,
+  group_id        INT          NOT NULL,
+  group_name      VARCHAR(255) NOT NULL,
+  is_hidden       SMALLINT     NOT NULL,
+  PRIMARY KEY (model_id, model_entity_id, group_id)
+);
+
+CREATE TABLE entity_group_pc
+(
+  model_id        INT NOT NULL,
+  model_entity_id INT NOT NULL,
+  group_id        INT NOT NULL,
+  child_pos       INT NOT NULL,
+  child_group_id  INT NULL,
+  attr_id         INT NULL,
+  PRIMARY KEY (model_id, model_entity_id, group_id, child_pos)
+);
+
+CREATE TABLE entity_group_txt
+(
+  model_id        INT             NOT NULL,
+  model_entity_id INT             NOT NULL,
+  group_id        INT             NOT NULL,
+  lang_id         INT             NOT NULL,
+  descr           VARCHAR(255)    NOT NULL,
+  note            VARCHAR(32000),
+  PRIMARY KEY (model_id, model_entity_id, group_id, lang_id)
+);
+
+CREATE TABLE profile_lst
+(
+  profile_name VARCHAR(255) NOT NULL,
+  PRIMARY KEY (profile_name)
+);
+
+CREATE TABLE profile_option
+(
+  profile_name VARCHAR(255)   NOT NULL,
+  option_key   VARCHAR(255)   NOT NULL,
+  option_value VARCHAR(32000) NOT NULL,
+  PRIMARY KEY (profile_name, option_key)
+);
+
+CREATE TABLE run_lst
+(
+  run_id        INT          NOT NULL,
+  model_id      INT          NOT NULL,
+  run_name      VARCHAR(255) NOT NULL,
+  sub_count     INT          NOT NULL,
+  sub_started   INT          NOT NULL,
+  sub_completed INT          NOT NULL,
+  sub_restart   INT          NOT NULL,
+  create_dt     VARCHAR(32)  NOT NULL,
+  status        VARCHAR(1)   NOT NULL,
+  update_dt     VARCHAR(32)  NOT NULL,
+  run_digest    VARCHAR(32)  NULL,
+  value_digest  VARCHAR(32)  NULL,
+  run_stamp     VARCHAR(32)  NOT NULL,
+  PRIMARY KEY (run_id)
+);
+
+CREATE TABLE run_txt
+(
+  run_id  INT             NOT NULL,
+  lang_id INT             NOT NULL,
+  descr   VARCHAR(255)    NOT NULL,
+  note    VARCHAR(32000),
+  PRIMARY KEY (run_id, lang_id)
+);
+
+CREATE TABLE run_option
+(
+  run_id       INT            NOT NULL,
+  option_key   VARCHAR(255)   NOT NULL,
+  option_value VARCHAR(32000) NOT NULL,
+  PRIMARY KEY (run_id, option_key)
+);
+
+CREATE TABLE run_parameter
+(
+  run_id        INT         NOT NULL,
+  parameter_hid INT         NOT NULL,
+  base_run_id   INT         NOT NULL,
+  sub_count     INT         NOT NULL,
+  value_digest  VARCHAR(32) NULL,
+  PRIMARY KEY (run_id, parameter_hid)
+);
+
+CREATE TABLE run_parameter_txt
+(
+  run_id        INT            NOT NULL,
+  parameter_hid INT            NOT NULL,
+  lang_id       INT            NOT NULL,
+  note          VARCHAR(32000),
+  PRIMARY KEY (run_id, parameter_hid, lang_id)
+);
+
+CREATE TABLE run_parameter_import
+(
+  run_id             INT          NOT NULL,
+  parameter_hid      INT          NOT NULL,
+  is_from_parameter  SMALLINT     NOT NULL,
+  from_model_id      INT          NOT NULL,
+  from_model_digest  VARCHAR(32)  NOT NULL,
+  from_run_id        INT          NOT NULL,
+  from_run_digest    VARCHAR(32)  NOT NULL,
+  PRIMARY KEY (run_id, parameter_hid)
+);
+
+CREATE TABLE run_table
+(
+  run_id       INT         NOT NULL,
+  table_hid    INT         NOT NULL,
+  base_run_id  INT         NOT NULL,
+  value_digest VARCHAR(32) NULL,
+  PRIMARY KEY (run_id, table_hid)
+);
+
+CREATE TABLE run_progress
+(
+  run_id         INT         NOT NULL,
+  sub_id         INT         NOT NULL,
+  create_dt      VARCHAR(32) NOT NULL,
+  status         VARCHAR(1)  NOT NULL,
+  update_dt      VARCHAR(32) NOT NULL,
+  progress_count INT         NOT NULL,
+  progress_value FLOAT       NOT NULL,
+  PRIMARY KEY (run_id, sub_id)
+);
+
+CREATE TABLE entity_gen
+(
+  entity_gen_hid  INT         NOT NULL,
+  entity_hid      INT         NOT NULL,
+  db_entity_table VARCHAR(64) NOT NULL,
+  gen_digest      VARCHAR(32) NOT NULL,
+  PRIMARY KEY (entity_gen_hid),
+  CONSTRAINT entity_gen_un UNIQUE (gen_digest)
+);
+
+CREATE TABLE entity_gen_attr
+(
+  entity_gen_hid INT NOT NULL,
+  attr_id        INT NOT NULL,
+  entity_hid     INT NOT NULL,
+  PRIMARY KEY (entity_gen_hid, attr_id)
+);
+
+CREATE TABLE run_entity
+(
+  run_id         INT         NOT NULL,
+  entity_gen_hid INT         NOT NULL,
+  base_run_id    INT         NOT NULL,
+  row_count      INT         NOT NULL,
+  value_digest   VARCHAR(32) NULL,
+  PRIMARY KEY (run_id, entity_gen_hid)
+);
+
+CREATE TABLE workset_lst
+(
+  set_id      INT          NOT NULL,
+  base_run_id INT          NULL,
+  model_id    INT          NOT NULL,
+  set_name    VARCHAR(255) NOT NULL,
+  is_readonly SMALLINT     NOT NULL,
+  update_dt   VARCHAR(32)  NOT NULL,
+  PRIMARY KEY (set_id),
+  CONSTRAINT workset_lst_un UNIQUE (model_id, set_name)
+);
+
+CREATE TABLE workset_txt
+(
+  set_id  INT             NOT NULL,
+  lang_id INT             NOT NULL,
+  descr   VARCHAR(255)    NOT NULL,
+  note    VARCHAR(32000),
+  PRIMARY KEY (set_id, lang_id)
+);
+
+CREATE TABLE workset_parameter
+(
+  set_id         INT NOT NULL,
+  parameter_hid  INT NOT NULL,
+  sub_count      INT NOT NULL,
+  default_sub_id INT NOT NULL,
+  PRIMARY KEY (set_id, parameter_hid)
+);
+
+CREATE TABLE workset_parameter_txt
+(
+  set_id        INT            NOT NULL,
+  parameter_hid INT            NOT NULL,
+  lang_id       INT            NOT NULL,
+  note          VARCHAR(32000),
+  PRIMARY KEY (set_id, parameter_hid, lang_id)
+);
+
+CREATE TABLE task_lst
+(
+  task_id   INT          NOT NULL,
+  model_id  INT          NOT NULL,
+  task_name VARCHAR(255) NOT NULL,
+  PRIMARY KEY (task_id),
+  CONSTRAINT task_lst_un UNIQUE (model_id, task_name)
+);
+
+CREATE TABLE task_txt
+(
+  task_id INT             NOT NULL,
+  lang_id INT             NOT NULL,
+  descr   VARCHAR(255)    NOT NULL,
+  note    VARCHAR(32000),
+  PRIMARY KEY (task_id, lang_id)
+);
+
+CREATE TABLE task_set
+(
+  task_id INT NOT NULL,
+  set_id  INT NOT NULL,
+  PRIMARY KEY (task_id, set_id)
+);
+
+CREATE TABLE task_run_lst
+(
+  task_run_id INT          NOT NULL,
+  task_id     INT          NOT NULL,
+  run_name    VARCHAR(255) NOT NULL,
+  sub_count   INT          NOT NULL,
+  create_dt   VARCHAR(32)  NOT NULL,
+  status      VARCHAR(1)   NOT NULL,
+  update_dt   VARCHAR(32)  NOT NULL,
+  run_stamp   VARCHAR(32)  NOT NULL,
+  PRIMARY KEY (task_run_id)
+);
+
+CREATE TABLE task_run_set
+(
+  task_run_id INT NOT NULL,
+  run_id      INT NOT NULL,
+  set_id      INT NOT NULL,
+  task_id     INT NOT NULL,
+  PRIMARY KEY (task_run_id, run_id)
+);
@@ -5,6 +5,7 @@ package db
 
 import (
 	"errors"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -25,6 +26,7 @@ type aggrColumn struct {
 	isBase   bool   // if true then attribute is used for the base run in comparison
 	isVar    bool   // if true then attribute is used for the variant run in comparison
 	isSimple bool   // if true then attribute is used in aggregation without comparison
+	binSql   string // if not empty then it is sql CASE expression of bin index for binned group by attribute
 }
 
 // scalar parameter column used as value in calculated expression
@@ -36,6 +38,12 @@ type paramColumn struct {
 	paramRow *ParamDicRow // db row of parameter_dic join to model_parameter_dic table
 }
 
+// MySQL percentile window columns: percentile argument value, rank of value in the group and count of not NULL values in the group
+type aggrWinColumn struct {
+	colName string // column name prefix, ie: pc1 => pc1_v, pc1_rn, pc1_cnt
+	sqlArg  string // percentile argument sql, ie: M1.acc_value
+}
+
 // Parsed aggregation expressions for each nesting level
 type levelDef struct {
 	level          int              // nesting level
@@ -46,17 +54,20 @@ type levelDef struct {
 	paramJoinArr   []string         // parameters inner join: inner join between parameter CTE and main table
 	firstAgcIdx    int              // first used aggregation column index (accumulator or attribute index)
 	agcUsageArr    []bool           // contains true if aggregation column (accumulator or attribute) used at current level
+	winArr         []aggrWinColumn  // MySQL percentile window columns: if not empty then level is aggregated from window function subquery
 }
 
 // level parse state
 type levelParseState struct {
 	*levelDef                       // current level
+	dbFacet        Facet            // db facet to translate vendor-specific functions, e.g.: OM_PERCENTILE
 	nextExprNumber int              // number of aggregation epxpressions
 	nextExprArr    []aggrExprColumn // aggregation expressions for the next level
 }
 
 // Parse output table accumulators calculation.
 func parseAggrCalculation(
+	dbFacet Facet,
 	aggrCols []aggrColumn,
 	paramCols map[string]paramColumn,
 	calculateExpr string,
@@ -84,6 +95,7 @@ func parseAggrCalculation(
 		}}
 	lps := &levelParseState{
 		levelDef:       &levelArr[len(levelArr)-1],
+		dbFacet:        dbFacet,
 		nextExprNumber: 1,
 		nextExprArr:    []aggrExprColumn{},
 	}
@@ -177,28 +189,15 @@ func parseAggrCalculation(
 			lps.exprArr[nL].sqlExpr = sqlExpr
 
 			// accumultors first pass: collect accumulators usage in current sql expression
-			var err error = nil
-
-			nStart := 0
-			for nEnd := 0; nStart >= 0 && nEnd >= 0; {
-
-				nStart, nEnd, err = nextUnquoted(sqlExpr, nStart)
-				if err != nil {
-					return []levelDef{}, err
-				}
-				if nStart < 0 || nEnd < 0 { // end of source formula
-					break
-				}
-
-				//  for each accumulator name check if name exist in that unquoted part of sql
-				for k := 0; k < len(aggrCols); k++ {
-
-					if findNamePos(sqlExpr[nStart:nEnd], aggrCols[k].name) >= 0 {
-						lps.agcUsageArr[k] = true
-					}
-				}
+			if err := lps.collectAggrUsage(sqlExpr, aggrCols); err != nil {
+				return []levelDef{}, err
+			}
+		}
 
-				nStart = nEnd // to the next 'unquoted part' of calculation string
+		// MySQL percentile arguments are columns of window function subquery and not included in sql expressions
+		for nw := range lps.winArr {
+			if err := lps.collectAggrUsage(lps.winArr[nw].sqlArg, aggrCols); err != nil {
+				return []levelDef{}, err
 			}
 		}
 
@@ -213,6 +212,13 @@ func parseAggrCalculation(
 				return []levelDef{}, e
 			}
 		}
+		for nw := range lps.winArr {
+
+			var e error
+			if lps.winArr[nw].sqlArg, e = lps.processAggrColumns(lps.winArr[nw].sqlArg, aggrCols, makeAggrColName); e != nil {
+				return []levelDef{}, e
+			}
+		}
 
 		// find parameter names and replace with column name:
 		//   param.Name          => M1P103.param_value
@@ -225,6 +231,13 @@ func parseAggrCalculation(
 				return []levelDef{}, e
 			}
 		}
+		for nw := range lps.winArr {
+
+			var e error
+			if lps.winArr[nw].sqlArg, e = lps.processParamColumns(lps.winArr[nw].sqlArg, paramCols, makeParamColName); e != nil {
+				return []levelDef{}, e
+			}
+		}
 
 		// if any expressions pushed to the next level then continue parsing
 		if len(lps.nextExprArr) <= 0 {
@@ -265,6 +278,35 @@ func (lps *levelParseState) pushToNextLevel(fncExpr string) string {
 	return colName
 }
 
+// Collect accumulators (or attributes) usage at current level: set usage flag if aggregation column name found in sql expression.
+func (lps *levelParseState) collectAggrUsage(sqlExpr string, aggrCols []aggrColumn) error {
+
+	var err error = nil
+
+	nStart := 0
+	for nEnd := 0; nStart >= 0 && nEnd >= 0; {
+
+		nStart, nEnd, err = nextUnquoted(sqlExpr, nStart)
+		if err != nil {
+			return err
+		}
+		if nStart < 0 || nEnd < 0 { // end of source formula
+			break
+		}
+
+		//  for each accumulator name check if name exist in that unquoted part of sql
+		for k := 0; k < len(aggrCols); k++ {
+
+			if findNamePos(sqlExpr[nStart:nEnd], aggrCols[k].name) >= 0 {
+				lps.agcUsageArr[k] = true
+			}
+		}
+
+		nStart = nEnd // to the next 'unquoted part' of calculation string
+	}
+	return nil
+}
+
 // Translate accumulator names by inserting table alias.
 // If this is the first accumulator at this level then do: acc1 => M2.acc_value
 // else use joined accumulator table: L1A4.acc4
@@ -434,6 +476,11 @@ func (lps *levelParseState) processParamColumns(
 //	OM_SUM((acc0 - OM_AVG(acc0)) * (acc0 - OM_AVG(acc0))) / (OM_COUNT(acc0) – 1)
 //	=>
 //	SUM((M1.acc0 - T2.ex2) * (acc0 - T2.ex2)) / (COUNT(acc0) – 1)
+//
+//	OM_WAVG(acc0, acc1) => SUM((acc0) * (acc1)) / SUM(acc1)
+//
+//	OM_MEDIAN(acc0)           => OM_PERCENTILE(acc0, 0.5)
+//	OM_PERCENTILE(acc0, 0.25) => PERCENTILE_CONT(0.25) WITHIN GROUP (ORDER BY acc0)
 func (lps *levelParseState) translateAggregationFnc(name, arg string, src string) (string, error) {
 
 	if len(arg) <= 0 {
@@ -516,10 +563,216 @@ func (lps *levelParseState) translateAggregationFnc(name, arg string, src string
 				" / CASE WHEN ABS( AVG(" + sqlArg + ") ) > 1.0e-37 THEN AVG(" + sqlArg + ") ELSE NULL END" +
 				" )",
 			nil
+
+	case "OM_WSUM": // SUM(arg * weight)
+
+		args, err := splitFncArgs(name, sqlArg, src)
+		if err != nil {
+			return "", err
+		}
+		if len(args) != 2 {
+			return "", errors.New("invalid function arguments, expected: " + name + "(value, weight) : " + src)
+		}
+		return "SUM((" + args[0] + ") * (" + args[1] + "))", nil
+
+	case "OM_WAVG": // SUM(arg * weight) / SUM(weight)
+
+		args, err := splitFncArgs(name, sqlArg, src)
+		if err != nil {
+			return "", err
+		}
+		if len(args) != 2 {
+			return "", errors.New("invalid function arguments, expected: " + name + "(value, weight) : " + src)
+		}
+		return "SUM((" + args[0] + ") * (" + args[1] + "))" +
+				" / CASE WHEN ABS( SUM(" + args[1] + ") ) > 1.0e-37 THEN SUM(" + args[1] + ") ELSE NULL END",
+			nil
+
+	case "OM_MEDIAN":
+		return lps.percentileSql(sqlArg, "0.5"), nil
+
+	case "OM_PERCENTILE": // percentile must be a number between 0 and 1, e.g.: 0.9
+
+		args, err := splitFncArgs(name, sqlArg, src)
+		if err != nil {
+			return "", err
+		}
+		if len(args) != 2 {
+			return "", errors.New("invalid function arguments, expected: " + name + "(value, percentile) : " + src)
+		}
+		if p, e := strconv.ParseFloat(args[1], 64); e != nil || p < 0 || p > 1 {
+			return "", errors.New("invalid percentile, it must be a number between 0 and 1: " + name + " : " + src)
+		}
+		return lps.percentileSql(args[0], args[1]), nil
 	}
 	return "", errors.New("unknown non-aggregation function: " + name + " : " + src)
 }
 
+// Return sql to calculate continuous percentile with linear interpolation between two adjacent values.
+//
+// PostgreSQL, Oracle and DB2 have ordered-set aggregate:
+//
+//	PERCENTILE_CONT(0.25) WITHIN GROUP (ORDER BY arg)
+//
+// Other vendors do not have percentile aggregate function and sql is using ordered list of values:
+// SQLite json array, MSSQL json array from string aggregation.
+// List of values is sorted, nulls excluded, and rank of percentile is: r = p * (COUNT(arg) - 1).
+// Result is interpolated between value[FLOOR(r)] and value[CEIL(r)] where value[] index is zero-based:
+//
+//	value[FLOOR(r)] + (value[CEIL(r)] - value[FLOOR(r)]) * (r - FLOOR(r))
+//
+// MySQL does not have ordered list aggregation: GROUP_CONCAT() result is truncated at group_concat_max_len, 1024 bytes by default,
+// and JSON_ARRAYAGG() cannot be ordered. Values ranked by ROW_NUMBER() window function in subquery at the FROM level,
+// see makeWinLevelSql(), and value at the rank selected by MAX(CASE WHEN rank = idx THEN value END).
+func (lps *levelParseState) percentileSql(arg, p string) string {
+
+	rank := "(" + p + " * (COUNT(" + arg + ") - 1))"
+	var lo, hi, loRank string
+	var valueAt func(idx string) string
+
+	switch lps.dbFacet.engine() {
+
+	case SqliteEngine: // sqlite: json_extract(json_group_array(arg ORDER BY arg) FILTER (WHERE arg IS NOT NULL), '$[' || idx || ']')
+
+		lo = "CAST(" + rank + " AS INTEGER)"
+		hi = "(" + lo + " + CASE WHEN " + rank + " > " + lo + " THEN 1 ELSE 0 END)"
+		valueAt = func(idx string) string {
+			return "json_extract(json_group_array(" + arg + " ORDER BY " + arg + ") FILTER (WHERE " + arg + " IS NOT NULL), '$[' || " + idx + " || ']')"
+		}
+
+	case MySqlEngine: // mysql: MAX(CASE WHEN W1.pc1_rn = idx THEN W1.pc1_v END) from window function subquery W1
+
+		wc := aggrWinColumn{colName: "pc" + strconv.Itoa(len(lps.winArr)+1), sqlArg: arg}
+		lps.winArr = append(lps.winArr, wc)
+		w := lps.winAlias() + "." + wc.colName
+
+		rank = "(" + p + " * (MAX(" + w + "_cnt) - 1))"
+		rowRank := "(" + p + " * (" + w + "_cnt - 1))"
+		lo = "FLOOR(" + rowRank + ")"
+		hi = "CEIL(" + rowRank + ")"
+		loRank = "FLOOR(" + rank + ")"
+		valueAt = func(idx string) string {
+			return "MAX(CASE WHEN " + w + "_v IS NOT NULL AND " + w + "_rn = " + idx + " THEN " + w + "_v END)"
+		}
+
+	case MsSqlEngine: // mssql: JSON_VALUE('[' + STRING_AGG(arg, ',') WITHIN GROUP (ORDER BY arg) + ']', '$[' + idx + ']')
+
+		lo = "FLOOR(" + rank + ")"
+		hi = "CEILING(" + rank + ")"
+		valueAt = func(idx string) string {
+			return "CAST(JSON_VALUE(" +
+				"'[' + STRING_AGG(CONVERT(VARCHAR(MAX), CAST(" + arg + " AS FLOAT), 3), ',') WITHIN GROUP (ORDER BY " + arg + ") + ']'" +
+				", '$[' + CAST(CAST(" + idx + " AS INT) AS VARCHAR(20)) + ']') AS FLOAT)"
+		}
+
+	default: // PostgreSQL, Oracle, DB2: ordered-set aggregate function
+		return "PERCENTILE_CONT(" + p + ") WITHIN GROUP (ORDER BY " + arg + ")"
+	}
+
+	if loRank == "" {
+		loRank = lo
+	}
+	return valueAt(lo) + " + (" + valueAt(hi) + " - " + valueAt(lo) + ") * (" + rank + " - " + loRank + ")"
+}
+
+// return alias of window function subquery of the level, ie: W1
+func (lv *levelDef) winAlias() string { return "W" + strconv.Itoa(lv.level) }
+
+// level column reference, ie: M1.acc_value, L1A1.acc1, T2.ex1, M1P103.param_value, M1PB103.param_base
+var levelColRefRx = regexp.MustCompile(`\b(M[0-9]+(?:P[BV]?[0-9]+)?|L[0-9]+A[0-9]+|T[0-9]+)\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Return select and group by sql for the level which is aggregated from window function subquery, it is used by MySQL percentile.
+// Key columns are group by columns: run_id, dim0, dim1 for output tables or run_id and group by attributes for microdata.
+// Level expressions columns are selected from subquery, ie: M1.acc_value => W1.M1_acc_value.
+// Return outer select and subquery select, caller must append FROM and WHERE of the level and closing group by:
+//
+//	SELECT W1.run_id, W1.dim0,
+//	  MAX(CASE WHEN W1.pc1_v IS NOT NULL AND W1.pc1_rn = FLOOR((0.5 * (W1.pc1_cnt - 1))) THEN W1.pc1_v END) + ... AS calc_value
+//	FROM
+//	(
+//	  SELECT M1.run_id, M1.dim0,
+//	    M1.acc_value AS pc1_v,
+//	    ROW_NUMBER() OVER (PARTITION BY M1.run_id, M1.dim0, (M1.acc_value) IS NULL ORDER BY M1.acc_value) - 1 AS pc1_rn,
+//	    COUNT(M1.acc_value) OVER (PARTITION BY M1.run_id, M1.dim0) AS pc1_cnt
+//	  FROM asrc M1
+//	  WHERE M1.acc_id = 0
+//	) W1
+//	GROUP BY W1.run_id, W1.dim0
+func (lv *levelDef) makeWinLevelSql(keyCols []string) (string, string, string, error) {
+
+	wa := lv.winAlias()
+
+	// replace level columns references in expressions by subquery columns
+	refArr := []string{}
+	refCols := map[string]string{}
+
+	outerSql := "SELECT " + wa + "." + keyCols[0]
+	for _, c := range keyCols[1:] {
+		outerSql += ", " + wa + "." + c
+	}
+
+	for _, expr := range lv.exprArr {
+
+		src := expr.sqlExpr
+		sql := ""
+		nStart := 0
+		for nEnd := 0; nStart >= 0 && nEnd >= 0; {
+
+			nPrev := nStart
+			var err error
+			nStart, nEnd, err = nextUnquoted(src, nStart)
+			if err != nil {
+				return "", "", "", err
+			}
+			if nStart < 0 || nEnd < 0 { // end of source: append the rest of quoted constants
+				sql += src[nPrev:]
+				break
+			}
+			sql += src[nPrev:nStart] // quoted constant before unquoted part
+
+			sql += levelColRefRx.ReplaceAllStringFunc(src[nStart:nEnd], func(ref string) string {
+				col, ok := refCols[ref]
+				if !ok {
+					col = strings.Replace(ref, ".", "_", 1)
+					refCols[ref] = col
+					refArr = append(refArr, ref)
+				}
+				return wa + "." + col
+			})
+			nStart = nEnd
+		}
+
+		outerSql += ", " + sql
+		if expr.colName != "" {
+			outerSql += " AS " + expr.colName
+		}
+	}
+	outerSql += " FROM ("
+
+	// subquery: select key columns, level columns and percentile window columns
+	partSql := lv.fromAlias + "." + keyCols[0]
+	for _, c := range keyCols[1:] {
+		partSql += ", " + lv.fromAlias + "." + c
+	}
+	innerSql := "SELECT " + partSql
+
+	for _, ref := range refArr {
+		innerSql += ", " + ref + " AS " + refCols[ref]
+	}
+	for _, wc := range lv.winArr {
+		innerSql += ", " + wc.sqlArg + " AS " + wc.colName + "_v" +
+			", ROW_NUMBER() OVER (PARTITION BY " + partSql + ", (" + wc.sqlArg + ") IS NULL ORDER BY " + wc.sqlArg + ") - 1 AS " + wc.colName + "_rn" +
+			", COUNT(" + wc.sqlArg + ") OVER (PARTITION BY " + partSql + ") AS " + wc.colName + "_cnt"
+	}
+
+	groupSql := ") " + wa + " GROUP BY " + wa + "." + keyCols[0]
+	for _, c := range keyCols[1:] {
+		groupSql += ", " + wa + "." + c
+	}
+
+	return outerSql, innerSql, groupSql, nil
+}
+
 // Translate function argument into sql fragment and push nested OM_ functions to next aggregation level:
 //
 //	argument: acc0 - 0.5 * OM_AVG(acc0)
@@ -20,7 +20,10 @@ const rightDelims = ")+-*/%^|&~!=<>["
 var simpleFncLst = []string{"OM_IF", "OM_DIV_BY"}
 
 // aggregation functions
-var aggrFncLst = []string{"OM_AVG", "OM_SUM", "OM_COUNT", "OM_COUNT_IF", "OM_AVG", "OM_MIN", "OM_MAX", "OM_VAR", "OM_SD", "OM_SE", "OM_CV"}
+var aggrFncLst = []string{
+	"OM_AVG", "OM_SUM", "OM_COUNT", "OM_COUNT_IF", "OM_AVG", "OM_MIN", "OM_MAX", "OM_VAR", "OM_SD", "OM_SE", "OM_CV",
+	"OM_MEDIAN", "OM_PERCENTILE", "OM_WAVG", "OM_WSUM",
+}
 
 // translate (substitute) all non-aggregation functions: OM_DIV_BY OM_IF...
 func translateAllSimpleFnc(expr string) (string, error) {
@@ -116,6 +119,44 @@ func findFirstFnc(src string, fncNameLst []string) (string, int, string, int, er
 	return fncNameLst[nFnc], namePos, src[nOpen+1 : nClose], nClose + 1, nil
 }
 
+// split function argument by top level commas, outside of brackets and 'quotes', and return trimmed arguments:
+//
+//	acc0 + OM_IF(acc1 > 0 THEN 1 ELSE 2), 0.5
+//	=>
+//	[acc0 + OM_IF(acc1 > 0 THEN 1 ELSE 2)] [0.5]
+func splitFncArgs(name, arg string, src string) ([]string, error) {
+
+	args := []string{}
+	level := 0
+	nStart := 0
+	isInside := false
+
+	for n, c := range arg {
+
+		switch {
+		case c == '\'':
+			isInside = !isInside // begin or end of 'quoted' sql
+		case isInside:
+			continue
+		case c == '(':
+			level++
+		case c == ')':
+			level--
+		case c == ',' && level == 0:
+			args = append(args, strings.TrimSpace(arg[nStart:n]))
+			nStart = n + 1
+		}
+	}
+	args = append(args, strings.TrimSpace(arg[nStart:]))
+
+	for _, a := range args {
+		if a == "" {
+			return []string{}, errors.New("invalid (empty) function argument: " + name + " : " + src)
+		}
+	}
+	return args, nil
+}
+
 // find first (left most) function name in src source expression from the fncNameLst name list.
 // return index of function and name position.
 func findFirstNameFnc(src string, fncNameLst []string) (int, int, error) {
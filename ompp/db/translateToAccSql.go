@@ -10,7 +10,7 @@ import (
 )
 
 // Translate output table accumulators calculation into sql query.
-func translateToAccSql(modelDef *ModelMeta, table *TableMeta, paramMeta []ParamMeta, readLt *ReadLayout, calcLt *CalculateLayout, runIds []int) (string, error) {
+func translateToAccSql(dbFacet Facet, modelDef *ModelMeta, table *TableMeta, paramMeta []ParamMeta, readLt *ReadLayout, calcLt *CalculateLayout, runIds []int) (string, error) {
 
 	// make sql:
 	// WITH cte
@@ -34,7 +34,7 @@ func translateToAccSql(modelDef *ModelMeta, table *TableMeta, paramMeta []ParamM
 	}
 
 	// translate calculation to sql
-	cteSql, mainSql, err := partialTranslateToAccSql(modelDef, table, paramCols, readLt, calcLt, runIds)
+	cteSql, mainSql, err := partialTranslateToAccSql(dbFacet, modelDef, table, paramCols, readLt, calcLt, runIds)
 	if err != nil {
 		return "", err
 	}
@@ -57,7 +57,7 @@ func translateToAccSql(modelDef *ModelMeta, table *TableMeta, paramMeta []ParamM
 // Translate output table accumulators aggregation to sql query, apply dimension filters and selected run id's.
 // Return list of CTE sql's and main sql's.
 func partialTranslateToAccSql(
-	modelDef *ModelMeta, table *TableMeta, paramCols map[string]paramColumn, readLt *ReadLayout, calcLt *CalculateLayout, runIds []int,
+	dbFacet Facet, modelDef *ModelMeta, table *TableMeta, paramCols map[string]paramColumn, readLt *ReadLayout, calcLt *CalculateLayout, runIds []int,
 ) (
 	string, string, error,
 ) {
@@ -86,14 +86,26 @@ func partialTranslateToAccSql(
 	// AND A.dim0 = .....
 	// ORDER BY 1, 2, 3, 4
 	//
-	cteSql, mainSql, err := transalteAccAggrToSql(table, paramCols, calcLt.CalcId, calcLt.Calculate)
+	cteSql, mainSql, err := transalteAccAggrToSql(dbFacet, table, paramCols, calcLt.CalcId, calcLt.Calculate)
 	if err != nil {
 		return "", "", errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": " + err.Error())
 	}
 
-	// make where clause and dimension filters:
-	// WHERE A.run_id IN (103, 104, 105, 106, 107, 108, 109, 110, 111, 112)
-	// AND A.dim0 = .....
+	// make where clause and dimension filters and append it to main sql query
+	where, err := makeAggrWhereSql(modelDef, table, readLt, calcLt, runIds)
+	if err != nil {
+		return "", "", err
+	}
+	mainSql += where
+
+	return cteSql, mainSql, nil
+}
+
+// Make where clause for aggregation sql: selected run id's, dimension filters and calculated value filters:
+//
+//	WHERE A.run_id IN (103, 104, 105, 106, 107, 108, 109, 110, 111, 112)
+//	AND A.dim0 = .....
+func makeAggrWhereSql(modelDef *ModelMeta, table *TableMeta, readLt *ReadLayout, calcLt *CalculateLayout, runIds []int) (string, error) {
 
 	// append run id's
 	where := " WHERE A.run_id IN ("
@@ -121,7 +133,7 @@ func partialTranslateToAccSql(
 	// append dimension enum code filters and value filter, if specified: A.dim1 = 'M' AND (calc_value < 1234 AND calc_id = 12001)
 	iDbl, ok := modelDef.TypeOfDouble()
 	if !ok {
-		return "", "", errors.New("double type not found, output table " + table.Name)
+		return "", errors.New("double type not found, output table " + table.Name)
 	}
 
 	for k := range readLt.Filter {
@@ -134,7 +146,7 @@ func partialTranslateToAccSql(
 			f, err = makeWhereValueFilter(
 				&readLt.Filter[k], "", "calc_value", "calc_id", calcLt.CalcId, &modelDef.Type[iDbl], readLt.Filter[k].Name, "output table "+table.Name)
 			if err != nil {
-				return "", "", err
+				return "", err
 			}
 		}
 		if f == "" { // if not a filter by value then it can be filter by dimension
@@ -151,7 +163,7 @@ func partialTranslateToAccSql(
 				f, err = makeWhereFilter(
 					&readLt.Filter[k], "A", table.Dim[dix].colName, table.Dim[dix].typeOf, table.Dim[dix].IsTotal, table.Dim[dix].Name, "output table "+table.Name)
 				if err != nil {
-					return "", "", errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": " + err.Error())
+					return "", errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": " + err.Error())
 				}
 			}
 		}
@@ -173,22 +185,19 @@ func partialTranslateToAccSql(
 			}
 		}
 		if dix < 0 {
-			return "", "", errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": output table " + table.Name + " does not have dimension " + readLt.FilterById[k].Name)
+			return "", errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": output table " + table.Name + " does not have dimension " + readLt.FilterById[k].Name)
 		}
 
 		f, err := makeWhereIdFilter(
 			&readLt.FilterById[k], "A", table.Dim[dix].colName, table.Dim[dix].typeOf, table.Dim[dix].Name, "output table "+table.Name)
 		if err != nil {
-			return "", "", errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": " + err.Error())
+			return "", errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": " + err.Error())
 		}
 
 		where += " AND " + f
 	}
 
-	// append WHERE to main sql query and return result
-	mainSql += where
-
-	return cteSql, mainSql, nil
+	return where, nil
 }
 
 // Translate output table aggregation expression into sql query.
@@ -214,7 +223,7 @@ func partialTranslateToAccSql(
 //	  WHERE M1.acc_id = 0
 //	  GROUP BY M1.run_id, M1.dim0, M1.dim1
 //	) A
-func transalteAccAggrToSql(table *TableMeta, paramCols map[string]paramColumn, calcId int, calculateExpr string) (string, string, error) {
+func transalteAccAggrToSql(dbFacet Facet, table *TableMeta, paramCols map[string]paramColumn, calcId int, calculateExpr string) (string, string, error) {
 
 	// clean source calculation from cr lf and unsafe sql quotes
 	// return error if unsafe sql or comment found outside of 'quotes', ex.: -- ; DELETE INSERT UPDATE...
@@ -252,12 +261,36 @@ func transalteAccAggrToSql(table *TableMeta, paramCols map[string]paramColumn, c
 		return levelAccAlias + "." + name // any other accumulator: acc4 => acc4
 	}
 
-	// translate parameter names by replacing it with CTE alias and CTE parameter value name:
-	//	param.Name          => M1P103.param_value
-	// also return INNER JOIN between parameter CTE view and main table:
-	//  INNER JOIN par_103   M1P103 ON (M1P103.run_id = M1.run_id)
-	// it cannot be run comparison
-	makeParamColName := func(colKey string, isSimple, isVar bool, alias string) (string, string, error) {
+	// translate parameter names: param.Name => M1P103.param_value, it cannot be run comparison
+	makeParamColName := makeSimpleParamColName(paramCols)
+
+	// parse aggregation expression
+	levelArr, err := parseAggrCalculation(dbFacet, aggrCols, paramCols, startExpr, makeAccColName, makeParamColName)
+	if err != nil {
+		return "", "", err
+	}
+
+	// build output sql from parser state: CTE and main sql query
+	cteSql, mainSql, err := makeAccAggrSql(table, calcId, levelArr)
+	if err != nil {
+		return "", "", err
+	}
+
+	return cteSql, mainSql, nil
+}
+
+// Return function to translate parameter names by replacing it with CTE alias and CTE parameter value name:
+//
+//	param.Name          => M1P103.param_value
+//
+// also return INNER JOIN between parameter CTE view and main table:
+//
+//	INNER JOIN par_103   M1P103 ON (M1P103.run_id = M1.run_id)
+//
+// It cannot be run comparison, parameter[base] or parameter[variant] is an error.
+func makeSimpleParamColName(paramCols map[string]paramColumn) func(string, bool, bool, string) (string, string, error) {
+
+	return func(colKey string, isSimple, isVar bool, alias string) (string, string, error) {
 
 		pCol, ok := paramCols[colKey]
 		if !ok {
@@ -280,20 +313,6 @@ func transalteAccAggrToSql(table *TableMeta, paramCols map[string]paramColumn, c
 
 		return sqlName, innerJoin, nil
 	}
-
-	// parse aggregation expression
-	levelArr, err := parseAggrCalculation(aggrCols, paramCols, startExpr, makeAccColName, makeParamColName)
-	if err != nil {
-		return "", "", err
-	}
-
-	// build output sql from parser state: CTE and main sql query
-	cteSql, mainSql, err := makeAccAggrSql(table, calcId, levelArr)
-	if err != nil {
-		return "", "", err
-	}
-
-	return cteSql, mainSql, nil
 }
 
 // Build aggregation sql from parser state.
@@ -371,6 +390,37 @@ func makeAccAggrSql(table *TableMeta, calcId int, levelArr []levelDef) (string,
 		" INNER JOIN run_table BR ON (BR.base_run_id = C.run_id AND BR.table_hid = " + strconv.Itoa(table.TableHid) + ")" +
 		")"
 
+	// main aggregation sql body: accumulators are selected from asrc CTE by acc_id
+	aggrIds := make([]int, len(table.Acc))
+	aggrNames := make([]string, len(table.Acc))
+
+	for k := range table.Acc {
+		aggrIds[k] = table.Acc[k].AccId
+		aggrNames[k] = table.Acc[k].colName
+	}
+	mainSql, err := makeAggrLevelSql(table, calcId, levelArr, "asrc", aggrIds, aggrNames)
+	if err != nil {
+		return "", "", err
+	}
+
+	return cteSql, mainSql, nil
+}
+
+// Build main body of aggregation sql from parser state.
+//
+// Source CTE must have columns: run_id, acc_id, sub_id, dim0, dim1,..., acc_value.
+// Aggregation is done by group by run_id, dim0, dim1,... and
+// all other aggregation columns of the same level joined by run_id, dim0, dim1,..., sub_id.
+// Aggregation columns are selected from the source CTE by acc_id: aggrIds[] are acc_id values and aggrNames[] are column names.
+// If level has MySQL percentile window columns then level is aggregated from window function subquery.
+func makeAggrLevelSql(table *TableMeta, calcId int, levelArr []levelDef, srcName string, aggrIds []int, aggrNames []string) (string, error) {
+
+	// group by columns: run_id, dim0, dim1,...
+	keyCols := []string{"run_id"}
+	for _, d := range table.Dim {
+		keyCols = append(keyCols, d.colName)
+	}
+
 	// SELECT A.run_id, CalcId AS calc_id, A.dim0, A.dim1, A.calc_value FROM (
 	//
 	mainSql := "SELECT A.run_id, " + strconv.Itoa(calcId) + " AS calc_id"
@@ -386,20 +436,31 @@ func makeAccAggrSql(table *TableMeta, calcId int, levelArr []levelDef) (string,
 		// select run_id, dim0,...,sub_id, acc_value
 		// from accumulator table where acc_id = first accumulator
 		//
-		mainSql += "SELECT " + lv.fromAlias + ".run_id"
+		if len(lv.winArr) > 0 {
 
-		for _, d := range table.Dim {
-			mainSql += ", " + lv.fromAlias + "." + d.colName
-		}
+			outerSql, innerSql, _, err := lv.makeWinLevelSql(keyCols)
+			if err != nil {
+				return "", err
+			}
+			mainSql += outerSql + innerSql
 
-		for _, expr := range lv.exprArr {
-			mainSql += ", " + expr.sqlExpr
-			if expr.colName != "" {
-				mainSql += " AS " + expr.colName
+		} else {
+
+			mainSql += "SELECT " + lv.fromAlias + ".run_id"
+
+			for _, d := range table.Dim {
+				mainSql += ", " + lv.fromAlias + "." + d.colName
+			}
+
+			for _, expr := range lv.exprArr {
+				mainSql += ", " + expr.sqlExpr
+				if expr.colName != "" {
+					mainSql += " AS " + expr.colName
+				}
 			}
 		}
 
-		mainSql += " FROM asrc " + lv.fromAlias
+		mainSql += " FROM " + srcName + " " + lv.fromAlias
 
 		// INNER JOIN parameters CTE ON run_id
 		slices.Sort(lv.paramJoinArr)
@@ -409,7 +470,7 @@ func makeAccAggrSql(table *TableMeta, calcId int, levelArr []levelDef) (string,
 		}
 
 		// INNER JOIN accumulator table for all other accumulators ON run_id, dim0,...,sub_id
-		for nAcc, acc := range table.Acc {
+		for nAcc := range aggrIds {
 
 			if !lv.agcUsageArr[nAcc] || nAcc == lv.firstAgcIdx { // skip first accumulator and unused accumulators
 				continue
@@ -422,9 +483,9 @@ func makeAccAggrSql(table *TableMeta, calcId int, levelArr []levelDef) (string,
 				mainSql += d.colName + ", "
 			}
 
-			mainSql += "sub_id, acc_value AS " + acc.colName +
-				" FROM asrc" +
-				" WHERE acc_id = " + strconv.Itoa(acc.AccId) +
+			mainSql += "sub_id, acc_value AS " + aggrNames[nAcc] +
+				" FROM " + srcName +
+				" WHERE acc_id = " + strconv.Itoa(aggrIds[nAcc]) +
 				") " + accAlias
 
 			mainSql += " ON (" + accAlias + ".run_id = " + lv.fromAlias + ".run_id"
@@ -448,16 +509,27 @@ func makeAccAggrSql(table *TableMeta, calcId int, levelArr []levelDef) (string,
 	for nLev := len(levelArr) - 1; nLev >= 0; nLev-- {
 
 		firstId := 0
-		if levelArr[nLev].firstAgcIdx >= 0 && levelArr[nLev].firstAgcIdx < len(table.Acc) {
-			firstId = table.Acc[levelArr[nLev].firstAgcIdx].AccId
+		if levelArr[nLev].firstAgcIdx >= 0 && levelArr[nLev].firstAgcIdx < len(aggrIds) {
+			firstId = aggrIds[levelArr[nLev].firstAgcIdx]
 		}
 
 		mainSql += " WHERE " + levelArr[nLev].fromAlias + ".acc_id = " + strconv.Itoa(firstId)
 
-		mainSql += " GROUP BY " + levelArr[nLev].fromAlias + ".run_id"
+		if len(levelArr[nLev].winArr) > 0 {
+
+			_, _, groupSql, err := levelArr[nLev].makeWinLevelSql(keyCols)
+			if err != nil {
+				return "", err
+			}
+			mainSql += groupSql
+
+		} else {
 
-		for _, d := range table.Dim {
-			mainSql += ", " + levelArr[nLev].fromAlias + "." + d.colName
+			mainSql += " GROUP BY " + levelArr[nLev].fromAlias + ".run_id"
+
+			for _, d := range table.Dim {
+				mainSql += ", " + levelArr[nLev].fromAlias + "." + d.colName
+			}
 		}
 
 		if nLev > 0 {
@@ -474,5 +546,5 @@ func makeAccAggrSql(table *TableMeta, calcId int, levelArr []levelDef) (string,
 	}
 	mainSql += " ) A"
 
-	return cteSql, mainSql, nil
+	return mainSql, nil
 }
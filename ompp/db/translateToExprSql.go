@@ -93,7 +93,7 @@ func partialTranslateToExprSql(
 	//  SELECT B.run_id, CalcId AS calc_id, B.dim0, B.dim1, (B.src0 + B.src1) AS calc_value
 	//  FROM B
 	//
-	cteSql, mainSql, isRunCompare, err := translateExprCalcToSql(table, paramCols, calcLt.CalcId, calcLt.Calculate)
+	cteSql, mainSql, isRunCompare, err := translateExprCalcToSql(modelDef, table, paramCols, calcLt.CalcId, calcLt.Calculate)
 	if err != nil {
 		return []string{}, "", false, errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": " + err.Error())
 	}
@@ -207,6 +207,7 @@ func partialTranslateToExprSql(
 
 // Translate output table expression calculation to sql query.
 // Only simple functions allowed in expression calculation.
+// Calculation can use expressions of other output table, joined by matching dimensions, ex.: Expr0 / table.ageSexIncome.Expr1
 //
 // Return array of CTE sql, SELECT for value calculation
 // and bool flag: if true then it is multiple runs comparison else expression calculation inside of a single run(s).
@@ -220,17 +221,50 @@ func partialTranslateToExprSql(
 //
 // SELECT B.run_id, CalcId AS calc_id, B.dim0, B.dim1, (B.src0 + B.src1) AS calc_value
 // FROM B
-func translateExprCalcToSql(table *TableMeta, paramCols map[string]paramColumn, calcId int, calculateExpr string) ([]string, string, bool, error) {
+func translateExprCalcToSql(modelDef *ModelMeta, table *TableMeta, paramCols map[string]paramColumn, calcId int, calculateExpr string) ([]string, string, bool, error) {
 
 	// clean source calculation from cr lf and unsafe sql quotes
-	// return error if unsafe sql or comment found outside of 'quotes', ex.: -- ; DELETE INSERT UPDATE...
 	expr := cleanSourceExpr(calculateExpr)
+
+	// make sql column names as src0,...,srcN and make sure column names are different from expression names
+	exprCount := len(table.Expr)
+	srcCols := make([]string, exprCount)
+	xSrcCol := ""
+
+	nU := 0
+	for isFound := true; isFound; {
+		isFound = false
+
+		for k := 0; !isFound && k < exprCount; k++ {
+			srcCols[k] = "src" + strings.Repeat("_", nU) + strconv.Itoa(k)
+			xSrcCol = "xsrc" + strings.Repeat("_", nU)
+			for j := 0; !isFound && j < exprCount; j++ {
+				isFound = srcCols[k] == table.Expr[j].Name || xSrcCol == table.Expr[j].Name
+			}
+		}
+		if isFound { // column name exist as expression name: use _ undescore to create unique names
+			nU++
+		}
+	}
+
+	// translate expressions of other output tables by replacing it with CTE alias and CTE value column name:
+	//	table.ageSexIncome.Expr0          => XB102_0.xsrc
+	//	table.ageSexIncome.Expr0[base]    => XB102_0.xsrc
+	//	table.ageSexIncome.Expr0[variant] => XV102_0.xsrc
+	// it must be done before any other substitution because other output table expression name can be the same as current table expression name
+	// and before unsafe sql check because output table name can contain sql keyword, ex.: table.AgeTable.Expr0
+	expr, xCols, err := translateCrossTableNames(modelDef, table, expr, xSrcCol)
+	if err != nil {
+		return []string{}, "", false, err
+	}
+
+	// return error if unsafe sql or comment found outside of 'quotes', ex.: -- ; DELETE INSERT UPDATE...
 	if err := errorIfUnsafeSqlOrComment(expr); err != nil {
 		return []string{}, "", false, err
 	}
 
 	// translate (substitute) all simple functions: OM_DIV_BY OM_IF...
-	expr, err := translateAllSimpleFnc(expr)
+	expr, err = translateAllSimpleFnc(expr)
 	if err != nil {
 		return []string{}, "", false, err
 	}
@@ -305,25 +339,6 @@ func translateExprCalcToSql(table *TableMeta, paramCols map[string]paramColumn,
 	}
 	paramJoinArr := []string{}
 
-	// make sql column names as src0,...,srcN and make sure column names are different from expression names
-	exprCount := len(table.Expr)
-	srcCols := make([]string, exprCount)
-
-	nU := 0
-	for isFound := true; isFound; {
-		isFound = false
-
-		for k := 0; !isFound && k < exprCount; k++ {
-			srcCols[k] = "src" + strings.Repeat("_", nU) + strconv.Itoa(k)
-			for j := 0; !isFound && j < exprCount; j++ {
-				isFound = srcCols[k] == table.Expr[j].Name
-			}
-		}
-		if isFound { // column name exist as expression name: use _ undescore to create unique names
-			nU++
-		}
-	}
-
 	// find expression names:
 	// it can be Expr0[base] and Expr0[variant],... or just Expr0, Expr1,... without [base] and [variant]
 	baseNames := make([]string, exprCount)
@@ -546,6 +561,17 @@ func translateExprCalcToSql(table *TableMeta, paramCols map[string]paramColumn,
 		return []string{}, expr, false, errors.New("invalid use of parameter run comparison name in expression: " + calculateExpr)
 	}
 
+	// validate other output table names:
+	// if it is run comparison then output table name cannot be simple else output table name cannot be [base] or [variant]
+	for _, xc := range xCols {
+		if !isSrcOnly && xc.isSimple {
+			return []string{}, expr, false, errors.New("invalid use of output table name in run comparison: " + xc.name + " : " + calculateExpr)
+		}
+		if isSrcOnly && !xc.isSimple {
+			return []string{}, expr, false, errors.New("invalid use of output table run comparison name in expression: " + xc.name + " : " + calculateExpr)
+		}
+	}
+
 	// validate: expression should not have any param. or [base] or [variant]
 	nStart = 0
 	for nEnd := 0; nStart >= 0 && nEnd >= 0; {
@@ -740,6 +766,53 @@ func translateExprCalcToSql(table *TableMeta, paramCols map[string]paramColumn,
 		}
 	}
 
+	// if there are any other output tables in expression then append CTE for each other table expression and inner join:
+	//
+	//	cx102_0 (run_id, dim1, xsrc) AS
+	//	(
+	//	  SELECT BR.run_id, C.dim0, C.expr_value
+	//	  FROM ageSexIncome_v_2012820 C
+	//	  INNER JOIN run_table BR ON (BR.base_run_id = C.run_id AND BR.table_hid = 102)
+	//	  WHERE C.expr_id = 0
+	//	)
+	//	INNER JOIN cx102_0 XB102_0 ON (XB102_0.run_id = B.run_id AND XB102_0.dim1 = B.dim1)
+	//	INNER JOIN cx102_0 XV102_0 ON (XV102_0.run_id = V.run_id AND XV102_0.dim1 = B.dim1)
+	//
+	// CTE dimension columns are named as dimension columns of current table
+	for _, xc := range xCols {
+
+		cte := xc.cteName + " (run_id"
+		body := "SELECT BR.run_id"
+		for j, d := range xc.table.Dim {
+			cte += ", " + table.Dim[xc.dimIdx[j]].colName
+			body += ", C." + d.colName
+		}
+		cte += ", " + xSrcCol + ") AS (" + body + ", C.expr_value" +
+			" FROM " + xc.table.DbExprTable + " C" +
+			" INNER JOIN run_table BR ON (BR.base_run_id = C.run_id AND BR.table_hid = " + strconv.Itoa(xc.table.TableHid) + ")" +
+			" WHERE C.expr_id = " + strconv.Itoa(xc.table.Expr[xc.exprIdx].ExprId) +
+			")"
+
+		isFound := false
+		for k := 0; !isFound && k < len(cteSql); k++ {
+			isFound = cte == cteSql[k]
+		}
+		if !isFound {
+			cteSql = append(cteSql, cte)
+		}
+
+		runAlias := "B"
+		if xc.isVar {
+			runAlias = "V"
+		}
+		mainSql += " INNER JOIN " + xc.cteName + " " + xc.alias + " ON (" + xc.alias + ".run_id = " + runAlias + ".run_id"
+		for j := range xc.table.Dim {
+			cn := table.Dim[xc.dimIdx[j]].colName
+			mainSql += " AND " + xc.alias + "." + cn + " = B." + cn
+		}
+		mainSql += ")"
+	}
+
 	// if there are any parameters in expression then append parameter inner joins
 	slices.Sort(paramJoinArr)
 
@@ -749,3 +822,193 @@ func translateExprCalcToSql(table *TableMeta, paramCols map[string]paramColumn,
 
 	return cteSql, mainSql, !isSrcOnly, nil
 }
+
+// expression of other output table used in calculation, ex.: table.ageSexIncome.Expr0[base]
+type crossTableColumn struct {
+	name     string     // source name, ex.: table.ageSexIncome.Expr0[base]
+	table    *TableMeta // other output table
+	exprIdx  int        // index of expression in other output table
+	isSimple bool       // if true then it is used in calculation without comparison
+	isVar    bool       // if true then it is used for the variant run in comparison
+	dimIdx   []int      // for each dimension of other table: index of matching dimension in current table
+	cteName  string     // CTE name, ex.: cx102_0
+	alias    string     // CTE alias, ex.: XB102_0 or XV102_0
+}
+
+// Translate expressions of other output tables by replacing it with CTE alias and CTE value column name:
+//
+//	table.ageSexIncome.Expr0          => XB102_0.xsrc
+//	table.ageSexIncome.Expr0[base]    => XB102_0.xsrc
+//	table.ageSexIncome.Expr0[variant] => XV102_0.xsrc
+//
+// Return translated expression and list of other tables expressions used in calculation.
+// Each dimension of other table must match by type to a dimension of current table,
+// if current table has more than one dimension of the same type then dimension name must be the same.
+func translateCrossTableNames(modelDef *ModelMeta, table *TableMeta, expr string, valueCol string) (string, []crossTableColumn, error) {
+
+	const prefix = "table."
+	xCols := []crossTableColumn{}
+
+	isNameRune := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+	nStart := 0
+	for nEnd := 0; nStart >= 0 && nEnd >= 0; {
+
+		var err error
+		if nStart, nEnd, err = nextUnquoted(expr, nStart); err != nil {
+			return "", xCols, err
+		}
+		if nStart < 0 || nEnd < 0 { // end of source formula
+			break
+		}
+
+		// find table. prefix delimited by space or left delimiter
+		n := -1
+		for p := 0; p < nEnd-nStart; {
+
+			i := strings.Index(expr[nStart+p:nEnd], prefix)
+			if i < 0 {
+				break
+			}
+			i += p
+			isOk := i == 0
+			if !isOk {
+				r, _ := utf8.DecodeLastRuneInString(expr[nStart : nStart+i])
+				isOk = unicode.IsSpace(r) || strings.ContainsRune(leftDelims, r)
+			}
+			if isOk {
+				n = i
+				break
+			}
+			p = i + len(prefix)
+		}
+		if n < 0 {
+			nStart = nEnd // to the next 'unquoted part' of calculation string
+			continue
+		}
+
+		// parse table.Name.Expr and optional [base] or [variant] suffix
+		nPos := nStart + n + len(prefix)
+		tEnd := nPos
+		for tEnd < nEnd {
+			r, w := utf8.DecodeRuneInString(expr[tEnd:])
+			if !isNameRune(r) {
+				break
+			}
+			tEnd += w
+		}
+		if tEnd >= nEnd || expr[tEnd] != '.' || tEnd == nPos {
+			return "", xCols, errors.New("invalid output table name, expected: table.Name.Expression at: " + expr[nStart+n:nEnd])
+		}
+		eEnd := tEnd + 1
+		for eEnd < nEnd {
+			r, w := utf8.DecodeRuneInString(expr[eEnd:])
+			if !isNameRune(r) {
+				break
+			}
+			eEnd += w
+		}
+		tName := expr[nPos:tEnd]
+		eName := expr[tEnd+1 : eEnd]
+
+		isBase := strings.HasPrefix(expr[eEnd:nEnd], "[base]")
+		isVar := strings.HasPrefix(expr[eEnd:nEnd], "[variant]")
+		srcEnd := eEnd
+		if isBase {
+			srcEnd += len("[base]")
+		}
+		if isVar {
+			srcEnd += len("[variant]")
+		}
+		srcName := expr[nStart+n : srcEnd]
+
+		// find other output table and expression
+		tIdx, ok := modelDef.OutTableByName(tName)
+		if !ok {
+			return "", xCols, errors.New("output table not found: " + tName + " : " + srcName)
+		}
+		xt := &modelDef.Table[tIdx]
+		if xt.TableHid == table.TableHid {
+			return "", xCols, errors.New("invalid use of current output table name, use expression name without table prefix: " + srcName)
+		}
+
+		eIdx := -1
+		for k := range xt.Expr {
+			if xt.Expr[k].Name == eName {
+				eIdx = k
+				break
+			}
+		}
+		if eIdx < 0 {
+			return "", xCols, errors.New("output table " + tName + " does not have expression: " + eName + " : " + srcName)
+		}
+
+		// match other table dimensions to current table dimensions by type and name
+		dimIdx, err := matchCrossTableDims(table, xt)
+		if err != nil {
+			return "", xCols, errors.New(err.Error() + " : " + srcName)
+		}
+
+		// make CTE alias and substitute name with sql column
+		sfx := strconv.Itoa(xt.TableHid) + "_" + strconv.Itoa(xt.Expr[eIdx].ExprId)
+		xc := crossTableColumn{
+			name:     srcName,
+			table:    xt,
+			exprIdx:  eIdx,
+			isSimple: !isBase && !isVar,
+			isVar:    isVar,
+			dimIdx:   dimIdx,
+			cteName:  "cx" + sfx,
+			alias:    "XB" + sfx,
+		}
+		if isVar {
+			xc.alias = "XV" + sfx
+		}
+
+		isFound := false
+		for k := 0; !isFound && k < len(xCols); k++ {
+			isFound = xCols[k].alias == xc.alias && xCols[k].isSimple == xc.isSimple
+		}
+		if !isFound {
+			xCols = append(xCols, xc)
+		}
+
+		expr = expr[:nStart+n] + xc.alias + "." + valueCol + expr[srcEnd:]
+		// continue with the same 'unquoted part' of calculation string
+	}
+
+	return expr, xCols, nil
+}
+
+// Match dimensions of other output table to dimensions of current table.
+// Return index of current table dimension for each dimension of other table.
+// Dimensions must be the same type, if current table has more than one dimension of that type then dimension names must be the same.
+func matchCrossTableDims(table *TableMeta, xt *TableMeta) ([]int, error) {
+
+	dimIdx := make([]int, len(xt.Dim))
+	isUsed := make([]bool, len(table.Dim))
+
+	for j := range xt.Dim {
+
+		dimIdx[j] = -1
+		nType := 0
+
+		for k := range table.Dim {
+			if isUsed[k] || table.Dim[k].TypeId != xt.Dim[j].TypeId {
+				continue
+			}
+			nType++
+			if dimIdx[j] < 0 || table.Dim[k].Name == xt.Dim[j].Name {
+				dimIdx[j] = k
+			}
+		}
+		if dimIdx[j] < 0 {
+			return nil, errors.New("dimension " + xt.Dim[j].Name + " of output table " + xt.Name + " does not match any dimension of output table " + table.Name)
+		}
+		if nType > 1 && table.Dim[dimIdx[j]].Name != xt.Dim[j].Name {
+			return nil, errors.New("dimension " + xt.Dim[j].Name + " of output table " + xt.Name + " match more than one dimension of output table " + table.Name + ", dimension names must be the same")
+		}
+		isUsed[dimIdx[j]] = true
+	}
+	return dimIdx, nil
+}
@@ -11,7 +11,7 @@ import (
 
 // Translate microdata aggregation into sql query.
 func translateToMicroAggrSql(
-	modelDef *ModelMeta, entity *EntityMeta, entityGen *EntityGenMeta, readLt *ReadLayout, calcLt *CalculateLayout, groupBy []string, runIds []int,
+	dbFacet Facet, modelDef *ModelMeta, entity *EntityMeta, entityGen *EntityGenMeta, readLt *ReadLayout, calcLt *CalculateLayout, groupBy []string, runIds []int,
 ) (string, error) {
 
 	// make sql:
@@ -42,7 +42,7 @@ func translateToMicroAggrSql(
 	}
 
 	// translate calculation to sql
-	mainSql, _, err := partialTranslateToMicroSql(modelDef, entity, entityGen, aggrCols, paramCols, readLt, calcLt, runIds)
+	mainSql, _, err := partialTranslateToMicroSql(dbFacet, modelDef, entity, entityGen, aggrCols, paramCols, readLt, calcLt, runIds)
 	if err != nil {
 		return "", err
 	}
@@ -111,7 +111,7 @@ func makeMicroAggrCols(entity *EntityMeta, entityGen *EntityGenMeta, groupBy []s
 // Or simple expression calculation inside of single run, in that case layout.FromId and runIds[] are merged.
 // Only simple functions allowed in expression calculation.
 func partialTranslateToMicroSql(
-	modelDef *ModelMeta, entity *EntityMeta, entityGen *EntityGenMeta, aggrCols []aggrColumn, paramCols map[string]paramColumn, readLt *ReadLayout, calcLt *CalculateLayout, runIds []int,
+	dbFacet Facet, modelDef *ModelMeta, entity *EntityMeta, entityGen *EntityGenMeta, aggrCols []aggrColumn, paramCols map[string]paramColumn, readLt *ReadLayout, calcLt *CalculateLayout, runIds []int,
 ) (
 	string, bool, error,
 ) {
@@ -158,7 +158,7 @@ func partialTranslateToMicroSql(
 	// ) A
 	// WHERE A.attr1 = .....
 	//
-	mainSql, isRunCompare, err := translateMicroCalcToSql(entity, entityGen, aggrCols, paramCols, calcLt.CalcId, calcLt.Calculate)
+	mainSql, isRunCompare, err := translateMicroCalcToSql(dbFacet, entity, entityGen, aggrCols, paramCols, calcLt.CalcId, calcLt.Calculate)
 	if err != nil {
 		return "", false, errors.New("Error at " + entity.Name + " " + calcLt.Calculate + ": " + err.Error())
 	}
@@ -282,7 +282,7 @@ func partialTranslateToMicroSql(
 //			  GROUP BY M1.run_id, M1.attr1, M1.attr2
 //			) A
 func translateMicroCalcToSql(
-	entity *EntityMeta, entityGen *EntityGenMeta, aggrCols []aggrColumn, paramCols map[string]paramColumn, calcId int, calculateExpr string,
+	dbFacet Facet, entity *EntityMeta, entityGen *EntityGenMeta, aggrCols []aggrColumn, paramCols map[string]paramColumn, calcId int, calculateExpr string,
 ) (
 	string, bool, error,
 ) {
@@ -382,7 +382,7 @@ func translateMicroCalcToSql(
 	}
 
 	// parse aggregation expression
-	levelArr, err := parseAggrCalculation(aggrCols, paramCols, startExpr, makeAttrColName, makeParamColName)
+	levelArr, err := parseAggrCalculation(dbFacet, aggrCols, paramCols, startExpr, makeAttrColName, makeParamColName)
 	if err != nil {
 		return "", false, err
 	}
@@ -488,6 +488,15 @@ func makeMicroMainAggrSql(
 	}
 	mainSql += ", A.calc_value FROM ( "
 
+	// group by columns: run_id, attr1, attr2,...
+	// if level has MySQL percentile window columns then level is aggregated from window function subquery
+	keyCols := []string{"run_id"}
+	for _, c := range aggrCols {
+		if c.isGroup {
+			keyCols = append(keyCols, c.colName)
+		}
+	}
+
 	// main aggregation sql body
 	for nLev, lv := range levelArr {
 
@@ -498,18 +507,29 @@ func makeMicroMainAggrSql(
 		//   INNER JOIN par_103 M1P103 ON (M1P103.run_id = M1.run_id)
 		//   INNER JOIN
 		//   (
-		mainSql += "SELECT " + lv.fromAlias + ".run_id"
+		if len(lv.winArr) > 0 {
 
-		for _, c := range aggrCols {
-			if c.isGroup {
-				mainSql += ", " + lv.fromAlias + "." + c.colName
+			outerSql, innerSql, _, err := lv.makeWinLevelSql(keyCols)
+			if err != nil {
+				return "", err
 			}
-		}
+			mainSql += outerSql + innerSql
+
+		} else {
+
+			mainSql += "SELECT " + lv.fromAlias + ".run_id"
 
-		for _, expr := range lv.exprArr {
-			mainSql += ", " + expr.sqlExpr
-			if expr.colName != "" {
-				mainSql += " AS " + expr.colName
+			for _, c := range aggrCols {
+				if c.isGroup {
+					mainSql += ", " + lv.fromAlias + "." + c.colName
+				}
+			}
+
+			for _, expr := range lv.exprArr {
+				mainSql += ", " + expr.sqlExpr
+				if expr.colName != "" {
+					mainSql += " AS " + expr.colName
+				}
 			}
 		}
 
@@ -533,11 +553,22 @@ func makeMicroMainAggrSql(
 	//
 	for nLev := len(levelArr) - 1; nLev >= 0; nLev-- {
 
-		mainSql += " GROUP BY " + levelArr[nLev].fromAlias + ".run_id"
+		if len(levelArr[nLev].winArr) > 0 {
 
-		for _, c := range aggrCols {
-			if c.isGroup {
-				mainSql += ", " + levelArr[nLev].fromAlias + "." + c.colName
+			_, _, groupSql, err := levelArr[nLev].makeWinLevelSql(keyCols)
+			if err != nil {
+				return "", err
+			}
+			mainSql += groupSql
+
+		} else {
+
+			mainSql += " GROUP BY " + levelArr[nLev].fromAlias + ".run_id"
+
+			for _, c := range aggrCols {
+				if c.isGroup {
+					mainSql += ", " + levelArr[nLev].fromAlias + "." + c.colName
+				}
 			}
 		}
 
@@ -577,7 +608,11 @@ func makeMicroCteAggrSql(
 	for _, c := range aggrCols {
 		if c.isGroup {
 			cHdr += ", " + c.colName
-			cBody += ", C." + c.colName
+			if c.binSql != "" {
+				cBody += ", " + c.binSql // binned attribute: CASE WHEN C.attr4 < 18 THEN 0 .... END
+			} else {
+				cBody += ", C." + c.colName
+			}
 		}
 		if c.isSimple {
 			isAnySimple = true
@@ -0,0 +1,201 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Translate aggregation of output table expressions across multiple model runs to sql query,
+// apply dimension filters and selected run id's.
+// Return CTE sql and main sql.
+//
+// Expressions are aggregated over the list of runs: base run layout.FromId and runIds[] are merged.
+// Result of aggregation is a single value for each cell, it is returned with run id of the base run, ex.: OM_AVG(Expr0).
+// If base run id layout.FromId is not specified then first run id from runIds[] is used as result run id.
+func partialTranslateToRunAggrSql(
+	dbFacet Facet, modelDef *ModelMeta, table *TableMeta, paramCols map[string]paramColumn, readLt *ReadLayout, calcLt *CalculateLayout, runIds []int,
+) (
+	string, string, error,
+) {
+
+	// merge base run id and run id's: base run id is a result run id
+	rIds := []int{}
+	if readLt.FromId > 0 {
+		rIds = append(rIds, readLt.FromId)
+	}
+	for _, rId := range runIds {
+
+		isFound := false
+		for k := 0; !isFound && k < len(rIds); k++ {
+			isFound = rIds[k] == rId
+		}
+		if !isFound {
+			rIds = append(rIds, rId)
+		}
+	}
+	if len(rIds) <= 0 {
+		return "", "", errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": invalid (empty) list of model runs to aggregate")
+	}
+
+	// translate output table run aggregation expression into sql query:
+	//   WITH rsrc (run_id, acc_id, sub_id, dim0, dim1, acc_value) AS
+	//   (
+	//     SELECT
+	//       219, C.expr_id, BR.run_id, C.dim0, C.dim1, C.expr_value
+	//     FROM age_expr C
+	//     INNER JOIN run_table BR ON (BR.base_run_id = C.run_id AND BR.table_hid = 101)
+	//     WHERE BR.run_id IN (219, 221, 222)
+	//   )
+	//   SELECT
+	//     A.run_id, CalcId AS calc_id, A.dim0, A.dim1, A.calc_value
+	//   FROM
+	//   (
+	//     SELECT
+	//       M1.run_id, M1.dim0, M1.dim1,
+	//       AVG(M1.acc_value) AS calc_value
+	//     FROM rsrc M1
+	//     WHERE M1.acc_id = 0
+	//     GROUP BY M1.run_id, M1.dim0, M1.dim1
+	//   ) A
+	// WHERE A.run_id IN (219, 221, 222)
+	// AND A.dim0 = .....
+	// ORDER BY 1, 2, 3, 4
+	//
+	cteSql, mainSql, err := translateRunAggrToSql(dbFacet, table, paramCols, calcLt.CalcId, calcLt.Calculate, rIds)
+	if err != nil {
+		return "", "", errors.New("Error at " + table.Name + " " + calcLt.Calculate + ": " + err.Error())
+	}
+
+	// make where clause and dimension filters and append it to main sql query
+	where, err := makeAggrWhereSql(modelDef, table, readLt, calcLt, runIds)
+	if err != nil {
+		return "", "", err
+	}
+	mainSql += where
+
+	return cteSql, mainSql, nil
+}
+
+// Translate aggregation of output table expressions across model runs into sql query.
+// Calculation must return a single value as a result of aggregation, ex.: AVG(expr_value).
+// First run id in runIds[] is a result run id, all runs from runIds[] are aggregated.
+//
+// Source CTE has the same columns as accumulators CTE, where acc_id is expression id and sub_id is a source run id.
+// It allows to use the same aggregation functions for model runs as for sub-values, ex.: OM_SD(Expr0) is standard deviation across runs.
+//
+//	WITH rsrc (run_id, acc_id, sub_id, dim0, dim1, acc_value) AS
+//	(
+//	  SELECT
+//	    219, C.expr_id, BR.run_id, C.dim0, C.dim1, C.expr_value
+//	  FROM age_expr C
+//	  INNER JOIN run_table BR ON (BR.base_run_id = C.run_id AND BR.table_hid = 101)
+//	  WHERE BR.run_id IN (219, 221, 222)
+//	)
+//	SELECT
+//	  A.run_id, CalcId AS calc_id, A.dim0, A.dim1, A.calc_value
+//	FROM
+//	(
+//	  SELECT
+//	    M1.run_id, M1.dim0, M1.dim1,
+//	    AVG(M1.acc_value) AS calc_value
+//	  FROM rsrc M1
+//	  WHERE M1.acc_id = 0
+//	  GROUP BY M1.run_id, M1.dim0, M1.dim1
+//	) A
+func translateRunAggrToSql(dbFacet Facet, table *TableMeta, paramCols map[string]paramColumn, calcId int, calculateExpr string, runIds []int) (string, string, error) {
+
+	if len(runIds) <= 0 {
+		return "", "", errors.New("invalid (empty) list of model runs to aggregate")
+	}
+
+	// clean source calculation from cr lf and unsafe sql quotes
+	// return error if unsafe sql or comment found outside of 'quotes', ex.: -- ; DELETE INSERT UPDATE...
+	startExpr := cleanSourceExpr(calculateExpr)
+	err := errorIfUnsafeSqlOrComment(startExpr)
+	if err != nil {
+		return "", "", err
+	}
+	if strings.Contains(startExpr, "[base]") || strings.Contains(startExpr, "[variant]") {
+		return "", "", errors.New("aggregation across model runs cannot be a run comparison [base] or [variant]: " + calculateExpr)
+	}
+
+	// translate (substitute) all simple functions: OM_DIV_BY OM_IF...
+	startExpr, err = translateAllSimpleFnc(startExpr)
+	if err != nil {
+		return "", "", err
+	}
+
+	// aggregation expression columns: all output table expressions can be aggregated across runs
+	aggrCols := make([]aggrColumn, len(table.Expr))
+	aggrIds := make([]int, len(table.Expr))
+	aggrNames := make([]string, len(table.Expr))
+
+	for k := range table.Expr {
+		aggrCols[k] = aggrColumn{
+			name:    table.Expr[k].Name,
+			colName: table.Expr[k].colName,
+			isAggr:  true,
+		}
+		aggrIds[k] = table.Expr[k].ExprId
+		aggrNames[k] = table.Expr[k].colName
+	}
+
+	// produce expression column name: Expr0 => M1.acc_value or Expr4 => L1A4.expr4
+	makeExprColName := func(
+		name string, nameIdx int, isSimple, isVar bool, firstAlias string, levelAccAlias string, isFirstExpr bool,
+	) string {
+
+		if isFirstExpr {
+			return firstAlias + "." + "acc_value" // first expression: Expr0 => acc_value
+		}
+		return levelAccAlias + "." + aggrNames[nameIdx] // any other expression: Expr4 => expr4
+	}
+
+	// parse aggregation expression
+	levelArr, err := parseAggrCalculation(dbFacet, aggrCols, paramCols, startExpr, makeExprColName, makeSimpleParamColName(paramCols))
+	if err != nil {
+		return "", "", err
+	}
+
+	// build output sql from parser state: CTE and main sql query
+	//
+	//	rsrc (run_id, acc_id, sub_id, dim0, dim1, acc_value) AS
+	//	(
+	//	  SELECT 219, C.expr_id, BR.run_id, C.dim0, C.dim1, C.expr_value
+	//	  FROM age_expr C
+	//	  INNER JOIN run_table BR ON (BR.base_run_id = C.run_id AND BR.table_hid = 101)
+	//	  WHERE BR.run_id IN (219, 221, 222)
+	//	)
+	cteSql := "rsrc (run_id, acc_id, sub_id"
+	for _, d := range table.Dim {
+		cteSql += ", " + d.colName
+	}
+	cteSql += ", acc_value) AS" +
+		" (" +
+		"SELECT " + strconv.Itoa(runIds[0]) + ", C.expr_id, BR.run_id"
+	for _, d := range table.Dim {
+		cteSql += ", C." + d.colName
+	}
+	cteSql += ", C.expr_value" +
+		" FROM " + table.DbExprTable + " C" +
+		" INNER JOIN run_table BR ON (BR.base_run_id = C.run_id AND BR.table_hid = " + strconv.Itoa(table.TableHid) + ")" +
+		" WHERE BR.run_id IN ("
+	for k := range runIds {
+		if k > 0 {
+			cteSql += ", "
+		}
+		cteSql += strconv.Itoa(runIds[k])
+	}
+	cteSql += "))"
+
+	mainSql, err := makeAggrLevelSql(table, calcId, levelArr, "rsrc", aggrIds, aggrNames)
+	if err != nil {
+		return "", "", err
+	}
+
+	return cteSql, mainSql, nil
+}
@@ -246,11 +246,17 @@ func doInsertModel(trx DbTrx, modelDef *ModelMeta) error {
 		}
 	}
 
+	// create workset history table, if not exists
+	err = TrxUpdate(trx.Tx, sqlCreateWorksetHistoryTable(trx.Dbf))
+	if err != nil {
+		return err
+	}
+
 	// for each parameter:
 	// if parameter not exist then insert into parameter_dic, parameter_dims
 	// update parameter Hid with actual db value
 	// insert into model_parameter_dic to append this parameter to the model
-	// if parameter not exist then create db tables for parameter values
+	// if parameter not exist then create db tables for parameter values and parameter history values
 	// if db table names is "" empty then make db table names for parameter values
 	for idx := range modelDef.Param {
 
@@ -371,6 +377,16 @@ func doInsertModel(trx DbTrx, modelDef *ModelMeta) error {
 			if err != nil {
 				return err
 			}
+
+			// create parameter history values table
+			hSql, err := sqlCreateParamHistoryTable(trx.Dbf, &modelDef.Param[idx])
+			if err != nil {
+				return err
+			}
+			err = TrxUpdate(trx.Tx, hSql)
+			if err != nil {
+				return err
+			}
 		}
 
 		// append parameter into model parameter list, if not in the list
@@ -10,20 +10,129 @@ import (
 	"time"
 
 	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
 )
 
+// WorksetUpdateAction is a user name and update action to save workset parameter version in history
+// and workset update date-time expected by the user.
+//
+// Parameter version is saved as part of update transaction, after workset "locked" for update.
+// If update action is nil or action name is empty then update does not save a version in workset history.
+// If expected update date-time is not empty then it is compared with workset_lst.update_dt inside of update transaction
+// and if workset was updated by other user then update fails with ErrWorksetUpdated error.
+type WorksetUpdateAction struct {
+	UserName       string // user who does parameter update
+	Action         string // update action, ex.: page, csv, copy-run, delete, restore
+	UpdateDateTime string // if not empty then expected workset update date-time, ex.: 2012-08-17 16:05:59.123
+}
+
+// ErrWorksetUpdated is returned if workset update date-time is not the same as expected: workset was updated by other user.
+var ErrWorksetUpdated = errors.New("workset is updated by other user")
+
+// afterCheck return copy of update action without expected update date-time.
+// It is used by next steps of multi-step update after first step checked and changed workset update date-time.
+func (upd *WorksetUpdateAction) afterCheck() *WorksetUpdateAction {
+	if upd == nil || upd.UpdateDateTime == "" {
+		return upd
+	}
+	u := *upd
+	u.UpdateDateTime = ""
+	return &u
+}
+
+// trxCheckWorksetUpdate check workset update date-time inside of update transaction.
+// If update action has expected update date-time then workset_lst.update_dt is updated only if it is equal to expected value,
+// otherwise workset is updated by other user or deleted and ErrWorksetUpdated returned.
+// It must be called before any other update of workset_lst.update_dt in the same transaction.
+func trxCheckWorksetUpdate(trx *sql.Tx, setId int, upd *WorksetUpdateAction) error {
+
+	if upd == nil || upd.UpdateDateTime == "" {
+		return nil // update date-time check not required
+	}
+
+	// UPDATE workset_lst SET update_dt = '2012-08-17 16:05:59.123'
+	// WHERE set_id = 22 AND update_dt = '2012-08-17 16:04:58.456'
+	q := "UPDATE workset_lst SET update_dt = " + ToQuoted(helper.MakeDateTime(time.Now())) +
+		" WHERE set_id = " + strconv.Itoa(setId) + " AND update_dt = " + ToQuoted(upd.UpdateDateTime)
+	omppLog.LogSql(q)
+
+	res, err := trx.Exec(q)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return ErrWorksetUpdated
+	}
+	return nil
+}
+
 // UpdateWorksetReadonly update workset readonly status.
+//
+// Workset which has sparse parameters cannot be read-only: model does not resolve sparse parameters
+// and input workset of the model run must be read-only.
 func UpdateWorksetReadonly(dbConn *sql.DB, setId int, isReadonly bool) error {
+	return UpdateWorksetReadonlyChecked(dbConn, setId, isReadonly, nil)
+}
 
-	return Update(dbConn,
+// UpdateWorksetReadonlyChecked update workset readonly status.
+// If update action has expected update date-time then it is checked in the same transaction.
+func UpdateWorksetReadonlyChecked(dbConn *sql.DB, setId int, isReadonly bool, upd *WorksetUpdateAction) error {
+
+	if isReadonly {
+		setRow, err := GetWorkset(dbConn, setId)
+		if err != nil {
+			return err
+		}
+		if setRow != nil {
+			if err = checkWorksetNotSparse(dbConn, setRow); err != nil {
+				return err
+			}
+		}
+	}
+
+	// do update in transaction scope
+	trx, err := dbConn.Begin()
+	if err != nil {
+		return err
+	}
+	if err = trxCheckWorksetUpdate(trx, setId, upd); err != nil {
+		trx.Rollback()
+		return err
+	}
+	err = TrxUpdate(trx,
 		"UPDATE workset_lst"+
 			" SET is_readonly = "+toBoolSqlConst(isReadonly)+", "+" update_dt = "+ToQuoted(helper.MakeDateTime(time.Now()))+
 			" WHERE set_id ="+strconv.Itoa(setId))
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+	trx.Commit()
+
+	return nil
 }
 
 // UpdateWorksetReadonlyByName update workset readonly status by workset name.
+//
+// Workset which has sparse parameters cannot be read-only.
 func UpdateWorksetReadonlyByName(dbConn *sql.DB, modelId int, name string, isReadonly bool) error {
 
+	if isReadonly {
+		setRow, err := GetWorksetByName(dbConn, modelId, name)
+		if err != nil {
+			return err
+		}
+		if setRow != nil {
+			if err = checkWorksetNotSparse(dbConn, setRow); err != nil {
+				return err
+			}
+		}
+	}
+
 	return Update(dbConn,
 		"UPDATE workset_lst"+
 			" SET is_readonly = "+toBoolSqlConst(isReadonly)+", "+" update_dt = "+ToQuoted(helper.MakeDateTime(time.Now()))+
@@ -150,7 +259,15 @@ func doRenameWorkset(trx *sql.Tx, setId int, newSetName string, isAnyReadonly bo
 //
 // Merge does merge of text metadata with existing workset or create empty new workset.
 // If workset exist then text is updated if such language already exist or inserted if no text in that language.
+//
+// Workset which has sparse parameters cannot be read-only.
 func (meta *WorksetMeta) UpdateWorkset(dbConn *sql.DB, modelDef *ModelMeta, isReplace bool, langDef *LangMeta) error {
+	return meta.UpdateWorksetChecked(dbConn, modelDef, isReplace, langDef, nil)
+}
+
+// UpdateWorksetChecked create new workset metadata, replace or merge existing workset metadata in database.
+// If update action has expected update date-time then existing workset update date-time is checked in the same transaction.
+func (meta *WorksetMeta) UpdateWorksetChecked(dbConn *sql.DB, modelDef *ModelMeta, isReplace bool, langDef *LangMeta, upd *WorksetUpdateAction) error {
 
 	// validate parameters
 	if modelDef == nil {
@@ -166,12 +283,25 @@ func (meta *WorksetMeta) UpdateWorkset(dbConn *sql.DB, modelDef *ModelMeta, isRe
 		return errors.New("workset: " + meta.Set.Name + " invalid model id " + strconv.Itoa(meta.Set.ModelId) + " expected: " + strconv.Itoa(modelDef.Model.ModelId))
 	}
 
+	// existing workset which has sparse parameters cannot be read-only
+	if meta.Set.IsReadonly {
+		setRow, err := GetWorksetByName(dbConn, modelDef.Model.ModelId, meta.Set.Name)
+		if err != nil {
+			return err
+		}
+		if setRow != nil {
+			if err = checkWorksetNotSparse(dbConn, setRow); err != nil {
+				return err
+			}
+		}
+	}
+
 	// do update in transaction scope
 	trx, err := dbConn.Begin()
 	if err != nil {
 		return err
 	}
-	err = doUpdateWorkset(trx, modelDef, meta, isReplace, langDef)
+	err = doUpdateWorkset(trx, modelDef, meta, isReplace, langDef, upd)
 	if err != nil {
 		trx.Rollback()
 		return err
@@ -185,7 +315,7 @@ func (meta *WorksetMeta) UpdateWorkset(dbConn *sql.DB, modelDef *ModelMeta, isRe
 // It does update as part of transaction
 // Set name is used to find workset and set id updated with actual database value
 // Workset must be read-write for replace or merge.
-func doUpdateWorkset(trx *sql.Tx, modelDef *ModelMeta, meta *WorksetMeta, isReplace bool, langDef *LangMeta) error {
+func doUpdateWorkset(trx *sql.Tx, modelDef *ModelMeta, meta *WorksetMeta, isReplace bool, langDef *LangMeta, upd *WorksetUpdateAction) error {
 
 	smId := strconv.Itoa(modelDef.Model.ModelId)
 
@@ -240,6 +370,11 @@ func doUpdateWorkset(trx *sql.Tx, modelDef *ModelMeta, meta *WorksetMeta, isRepl
 		return errors.New("failed to update: workset already exists and it is read-only: " + strconv.Itoa(meta.Set.SetId) + ": " + meta.Set.Name)
 	}
 
+	// if workset expected to exist then check if it is not updated by other user
+	if err = trxCheckWorksetUpdate(trx, setId, upd); err != nil {
+		return err
+	}
+
 	// if workset not exist then create new empty workset
 	if setId <= 0 {
 
@@ -0,0 +1,500 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// WorksetParamFormula describes formula-based bulk update of workset parameter values.
+//
+// Formula is an arithmetic expression over current parameter value and other parameters values, for example:
+//
+//	ageSex * 1.05
+//	OM_IF(ageSex > 0.5 THEN 0.5 ELSE ageSex)
+//	ageSex * salaryFull / OM_DIV_BY(StartingSeed)
+//	0.3
+//
+// Each dimension of other parameter must be joined to dimension of updated parameter with the same type.
+// Other parameter values selected from the same workset if workset contains that parameter else from workset base run.
+// If other parameter has the same number of sub-values then it joined by sub-value id else default sub-value is used.
+//
+// Filters select cells to update and combined by AND, ex.: Region = North.
+// Filter by parameter name is a filter by current parameter value, ex.: ageSex > 0.5.
+type WorksetParamFormula struct {
+	Name       string           // parameter name
+	Formula    string           // new value expression, ex.: ageSex * 1.05
+	Filter     []FilterColumn   // dimension or parameter value filters by enum codes, combined by AND
+	FilterById []FilterIdColumn // dimension filters by enum ids, combined by AND
+}
+
+// sql keywords which can be used in parameter formula, for example as result of OM_IF() translation
+var paramFormulaKeywords = []string{"CASE", "WHEN", "THEN", "ELSE", "END", "NULL", "AND", "OR", "NOT", "IS", "IN", "BETWEEN"}
+
+// UpdateWorksetParameterFormula does bulk update of workset parameter values by formula and return number of changed cells.
+//
+// Only cells selected by filters and where formula result is different from current value are updated.
+// If formula result is NULL, ex.: division by zero in OM_DIV_BY(), then cell is not updated.
+// Parameter must be float type and must be included in workset, workset must be read-write.
+// Updated values must be valid by parameter validation rules.
+// Update is done in transaction scope.
+// If update action not nil then current parameter values saved in workset history in the same transaction.
+func UpdateWorksetParameterFormula(dbConn *sql.DB, modelDef *ModelMeta, setName string, layout *WorksetParamFormula, upd *WorksetUpdateAction) (int64, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return 0, errors.New("invalid (empty) model metadata")
+	}
+	if setName == "" {
+		return 0, errors.New("invalid (empty) workset name")
+	}
+	if layout == nil {
+		return 0, errors.New("invalid (empty) parameter formula layout")
+	}
+	if layout.Name == "" {
+		return 0, errors.New("invalid (empty) parameter name")
+	}
+	if cleanSourceExpr(layout.Formula) == "" {
+		return 0, errors.New("invalid (empty) parameter formula: " + layout.Name)
+	}
+
+	idx, ok := modelDef.ParamByName(layout.Name)
+	if !ok {
+		return 0, errors.New("model: " + modelDef.Model.Name + " parameter " + layout.Name + " not found")
+	}
+	param := &modelDef.Param[idx]
+
+	if !param.typeOf.IsFloat() {
+		return 0, errors.New("invalid parameter type, expected: float: " + param.Name)
+	}
+
+	// sparse parameter must be resolved before update: only overridden cells are stored in workset
+	if setRow, err := GetWorksetByName(dbConn, modelDef.Model.ModelId, setName); err == nil && setRow != nil {
+		isSparse, err := isSparseWorksetParam(dbConn, setRow, param.Name)
+		if err != nil {
+			return 0, err
+		}
+		if isSparse {
+			return 0, errors.New("failed to update: parameter " + param.Name + " is sparse, it must be resolved in workset: " + setName)
+		}
+	}
+
+	// updated values must be valid by parameter validation rules
+	rules, err := GetParamRules(dbConn, modelDef, param.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	// if workset history db tables not exist then history is not saved
+	upd = upd.historyCheck(dbConn, param)
+
+	// do update in transaction scope
+	trx, err := dbConn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	n, err := doUpdateWorksetParameterFormula(trx, modelDef, setName, param, layout, rules, upd)
+	if err != nil {
+		trx.Rollback()
+		return 0, err
+	}
+	trx.Commit()
+
+	return n, nil
+}
+
+// doUpdateWorksetParameterFormula does bulk update of workset parameter values by formula and return number of changed cells.
+// It does update as part of transaction and check updated values by parameter validation rules.
+// If update action not nil then current parameter values saved in workset history before update.
+func doUpdateWorksetParameterFormula(
+	trx *sql.Tx, modelDef *ModelMeta, setName string, param *ParamMeta, layout *WorksetParamFormula, rules []ParamRule, upd *WorksetUpdateAction,
+) (int64, error) {
+
+	// "lock" workset to prevent update or use by the model
+	smId := strconv.Itoa(modelDef.Model.ModelId)
+
+	err := TrxUpdate(trx,
+		"UPDATE workset_lst"+
+			" SET is_readonly = is_readonly + 1"+
+			" WHERE model_id = "+smId+" AND set_name = "+ToQuoted(setName))
+	if err != nil {
+		return 0, err
+	}
+
+	// check if workset exist and not readonly, get workset base run id
+	var setId, nRd, baseRunId int
+	err = TrxSelectFirst(trx,
+		"SELECT set_id, is_readonly, base_run_id FROM workset_lst"+
+			" WHERE model_id = "+smId+" AND set_name = "+ToQuoted(setName),
+		func(row *sql.Row) error {
+			var brId sql.NullInt64
+			if err := row.Scan(&setId, &nRd, &brId); err != nil {
+				return err
+			}
+			if brId.Valid {
+				baseRunId = int(brId.Int64)
+			}
+			return nil
+		})
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, errors.New("failed to update: workset not found: " + setName)
+	case err != nil:
+		return 0, err
+	case nRd != 1:
+		return 0, errors.New("failed to update: workset is read-only: " + setName)
+	}
+
+	// parameter must be in workset already
+	subCount, _, err := trxWorksetParamSubCount(trx, setId, param.ParamHid)
+	if err != nil {
+		return 0, err
+	}
+	if subCount <= 0 {
+		return 0, errors.New("failed to update: workset " + setName + " does not contain parameter " + param.Name)
+	}
+	if err = trxCheckWorksetUpdate(trx, setId, upd); err != nil {
+		return 0, err
+	}
+
+	// save current parameter values in workset history
+	verId, err := trxSaveParamHistory(trx, setId, param, upd)
+	if err != nil {
+		return 0, err
+	}
+
+	// translate formula into sql: replace parameter names by current value column and other parameters sub-queries
+	tbl := param.DbSetTable
+
+	expr, err := translateParamFormulaExpr(layout.Formula,
+		func(name string) (string, bool, error) {
+
+			if name == param.Name {
+				return tbl + ".param_value", true, nil
+			}
+			k, ok := modelDef.ParamByName(name)
+			if !ok {
+				return "", false, nil
+			}
+			q, e := trxParamFormulaRefSql(trx, param, tbl, &modelDef.Param[k], setId, baseRunId, subCount)
+			return q, true, e
+		})
+	if err != nil {
+		return 0, errors.New("Error in formula of parameter " + param.Name + ": " + err.Error())
+	}
+
+	// make where: workset id and dimension or value filters
+	where := " WHERE " + tbl + ".set_id = " + strconv.Itoa(setId)
+
+	for k := range layout.Filter {
+
+		var f string
+		var e error
+
+		if layout.Filter[k].Name == param.Name {
+			f, e = makeWhereValueFilter(
+				&layout.Filter[k], tbl, "param_value", "", 0, param.typeOf, param.Name, "parameter "+param.Name)
+		} else {
+
+			dix := -1
+			for j := range param.Dim {
+				if param.Dim[j].Name == layout.Filter[k].Name {
+					dix = j
+					break
+				}
+			}
+			if dix < 0 {
+				return 0, errors.New("parameter " + param.Name + " does not have dimension " + layout.Filter[k].Name)
+			}
+			f, e = makeWhereFilter(
+				&layout.Filter[k], tbl, param.Dim[dix].colName, param.Dim[dix].typeOf, false, param.Dim[dix].Name, "parameter "+param.Name)
+		}
+		if e != nil {
+			return 0, e
+		}
+		where += " AND " + f
+	}
+
+	for k := range layout.FilterById {
+
+		dix := -1
+		for j := range param.Dim {
+			if param.Dim[j].Name == layout.FilterById[k].Name {
+				dix = j
+				break
+			}
+		}
+		if dix < 0 {
+			return 0, errors.New("parameter " + param.Name + " does not have dimension " + layout.FilterById[k].Name)
+		}
+
+		f, e := makeWhereIdFilter(
+			&layout.FilterById[k], tbl, param.Dim[dix].colName, param.Dim[dix].typeOf, param.Dim[dix].Name, "parameter "+param.Name)
+		if e != nil {
+			return 0, e
+		}
+		where += " AND " + f
+	}
+
+	// update only cells where new value is not NULL and different from current value
+	where += " AND (" + expr + ") IS NOT NULL" +
+		" AND (" + tbl + ".param_value IS NULL OR " + tbl + ".param_value <> (" + expr + "))"
+
+	// count cells to update
+	var nCell int64
+	err = TrxSelectFirst(trx,
+		"SELECT COUNT(*) FROM "+tbl+where,
+		func(row *sql.Row) error {
+			return row.Scan(&nCell)
+		})
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	// update parameter values
+	if nCell > 0 {
+		err = TrxUpdate(trx, "UPDATE "+tbl+" SET param_value = ("+expr+")"+where)
+		if err != nil {
+			return 0, err
+		}
+		if err = trxValidateWorksetParam(trx, param, setId, rules); err != nil {
+			return 0, err
+		}
+	}
+
+	// keep in workset history only cells changed by update
+	if err = trxTrimParamHistory(trx, setId, param, verId); err != nil {
+		return 0, err
+	}
+
+	// "unlock" workset: restore original value of is_readonly=0
+	err = TrxUpdate(trx,
+		"UPDATE workset_lst"+
+			" SET is_readonly = 0,"+
+			" update_dt = "+ToQuoted(helper.MakeDateTime(time.Now()))+
+			" WHERE set_id = "+strconv.Itoa(setId))
+	if err != nil {
+		return 0, err
+	}
+
+	return nCell, nil
+}
+
+// return parameter sub-value count and default sub-value id from workset_parameter, sub-value count is zero if parameter not in workset
+func trxWorksetParamSubCount(trx *sql.Tx, setId int, paramHid int) (int, int, error) {
+
+	var nSub, defId int
+	err := TrxSelectFirst(trx,
+		"SELECT sub_count, default_sub_id FROM workset_parameter"+
+			" WHERE set_id = "+strconv.Itoa(setId)+
+			" AND parameter_hid = "+strconv.Itoa(paramHid),
+		func(row *sql.Row) error {
+			return row.Scan(&nSub, &defId)
+		})
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, 0, nil
+	case err != nil:
+		return 0, 0, err
+	}
+	return nSub, defId, nil
+}
+
+// return sql sub-query to select other parameter value for current cell of updated parameter, ex.:
+//
+//	(SELECT O.param_value FROM salaryFull_w2012_817 O WHERE O.set_id = 101 AND O.sub_id = 0 AND O.dim0 = ageSex_w2012_817.dim1)
+//
+// Other parameter values selected from the same workset if workset contains that parameter else from workset base run.
+// Each dimension of other parameter joined to dimension of updated parameter with the same type,
+// if there are multiple dimensions of the same type then dimension with the same name is preferred.
+func trxParamFormulaRefSql(trx *sql.Tx, param *ParamMeta, tbl string, ref *ParamMeta, setId, baseRunId, subCount int) (string, error) {
+
+	if !ref.typeOf.IsFloat() && !ref.typeOf.IsInt() {
+		return "", errors.New("invalid type of parameter " + ref.Name + ", expected: float or integer")
+	}
+
+	// join other parameter dimensions to updated parameter dimensions by type
+	isUsed := make([]bool, param.Rank)
+	dimSql := ""
+
+	for j := range ref.Dim {
+
+		dix := -1
+		for k := range param.Dim {
+			if isUsed[k] || param.Dim[k].TypeId != ref.Dim[j].TypeId {
+				continue
+			}
+			if dix < 0 || param.Dim[k].Name == ref.Dim[j].Name {
+				dix = k
+			}
+		}
+		if dix < 0 {
+			return "", errors.New("parameter " + param.Name + " does not have dimension of the same type as " + ref.Name + " dimension " + ref.Dim[j].Name)
+		}
+		isUsed[dix] = true
+		dimSql += " AND O." + ref.Dim[j].colName + " = " + tbl + "." + param.Dim[dix].colName
+	}
+
+	// find other parameter values: in the same workset or in workset base run
+	nSub, defId, err := trxWorksetParamSubCount(trx, setId, ref.ParamHid)
+	if err != nil {
+		return "", err
+	}
+	srcSql := ""
+
+	if nSub > 0 {
+		srcSql = ref.DbSetTable + " O WHERE O.set_id = " + strconv.Itoa(setId)
+	} else {
+
+		if baseRunId <= 0 {
+			return "", errors.New("workset does not contain parameter " + ref.Name + " and not run-based, workset id: " + strconv.Itoa(setId))
+		}
+		var srcRunId int
+		err = TrxSelectFirst(trx,
+			"SELECT base_run_id, sub_count FROM run_parameter"+
+				" WHERE run_id = "+strconv.Itoa(baseRunId)+
+				" AND parameter_hid = "+strconv.Itoa(ref.ParamHid),
+			func(row *sql.Row) error {
+				return row.Scan(&srcRunId, &nSub)
+			})
+		switch {
+		case err == sql.ErrNoRows:
+			return "", errors.New("parameter " + ref.Name + " not found in workset base run, run id: " + strconv.Itoa(baseRunId))
+		case err != nil:
+			return "", err
+		}
+		defId = 0
+		srcSql = ref.DbRunTable + " O WHERE O.run_id = " + strconv.Itoa(srcRunId)
+	}
+
+	// join by sub-value id if other parameter has the same number of sub-values else use default sub-value
+	subSql := " AND O.sub_id = " + strconv.Itoa(defId)
+	if nSub > 1 && nSub == subCount {
+		subSql = " AND O.sub_id = " + tbl + ".sub_id"
+	}
+
+	return "(SELECT O.param_value FROM " + srcSql + subSql + dimSql + ")", nil
+}
+
+// translateParamFormulaExpr translate workset parameter formula into sql, ex.:
+//
+//	OM_IF(ageSex > 0.5 THEN ageSex * 1.05 ELSE ageSex) => CASE WHEN T.param_value > 0.5 THEN T.param_value * 1.05 ELSE T.param_value END
+//
+// Each name outside of sql 'quotes' replaced by sql returned from nameToSql(), if it is a parameter name.
+// Function names and sql keywords, ex.: CASE WHEN, are not replaced, any other name is an error.
+// Only functions supported by Go evaluation are allowed, ex.: ABS, SQRT, ROUND, POWER, OM_IF, OM_DIV_BY, any other function is an error.
+func translateParamFormulaExpr(src string, nameToSql func(name string) (string, bool, error)) (string, error) {
+
+	// clean source expression and check for unsafe sql
+	expr := cleanSourceExpr(src)
+	if expr == "" {
+		return "", errors.New("invalid (empty) parameter formula")
+	}
+	if err := errorIfUnsafeSqlOrComment(expr); err != nil {
+		return "", err
+	}
+
+	// translate non-aggregation functions: OM_IF, OM_DIV_BY
+	expr, err := translateAllSimpleFnc(expr)
+	if err != nil {
+		return "", err
+	}
+
+	isNameRune := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	// in each unquoted part of expression replace parameter names
+	var sb strings.Builder
+	nPos := 0
+	nStart := 0
+
+	for nEnd := 0; nStart >= 0 && nEnd >= 0; {
+
+		if nStart, nEnd, err = nextUnquoted(expr, nStart); err != nil {
+			return "", err
+		}
+		if nStart < 0 || nEnd < 0 { // end of source expression
+			break
+		}
+		sb.WriteString(expr[nPos:nStart]) // copy 'quoted' part of expression
+
+		part := expr[nStart:nEnd]
+
+		for n := 0; n < len(part); {
+
+			r, w := utf8.DecodeRuneInString(part[n:])
+
+			// name must start from letter or _ and cannot be a part of number, ex.: 1.0e-37
+			isName := r == '_' || unicode.IsLetter(r)
+			if isName && n > 0 {
+				pr, _ := utf8.DecodeLastRuneInString(part[:n])
+				isName = !isNameRune(pr) && pr != '.'
+			}
+			if !isName {
+				sb.WriteRune(r)
+				n += w
+				continue
+			}
+
+			// find end of the name
+			nLen := n + w
+			for nLen < len(part) {
+				r, w = utf8.DecodeRuneInString(part[nLen:])
+				if !isNameRune(r) {
+					break
+				}
+				nLen += w
+			}
+			name := part[n:nLen]
+			n = nLen
+
+			// function name is not replaced, ex.: ABS(
+			// only functions supported by Go evaluation are allowed, OM_ functions already translated into sql
+			if strings.HasPrefix(strings.TrimLeftFunc(part[n:], unicode.IsSpace), "(") {
+				fn := strings.ToUpper(name)
+				if _, ok := evalFncArgCount[fn]; !ok || strings.HasPrefix(fn, "OM_") {
+					return "", errors.New("Error in expression, unknown function: " + name + ": " + src)
+				}
+				sb.WriteString(name)
+				continue
+			}
+
+			q, ok, e := nameToSql(name)
+			if e != nil {
+				return "", e
+			}
+			if ok {
+				sb.WriteString(q)
+				continue
+			}
+
+			// if this is not a parameter name then it must be sql keyword
+			isKey := false
+			for _, kw := range paramFormulaKeywords {
+				if isKey = strings.EqualFold(name, kw); isKey {
+					break
+				}
+			}
+			if !isKey {
+				return "", errors.New("Error in expression, unknown name: " + name + ": " + src)
+			}
+			sb.WriteString(name)
+		}
+
+		nPos = nEnd
+		nStart = nEnd
+	}
+	sb.WriteString(expr[nPos:]) // copy the rest of expression
+
+	return sb.String(), nil
+}
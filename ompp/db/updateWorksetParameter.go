@@ -21,8 +21,9 @@ import (
 //
 // Set name is used to find workset and set id updated with actual database value.
 // Workset must be read-write for replace or merge.
+// If update action not nil then current parameter values saved in workset history in the same transaction.
 func (meta *WorksetMeta) UpdateWorksetParameterFrom(
-	dbConn Dbc, modelDef *ModelMeta, isReplaceMeta bool, param *ParamRunSetPub, langDef *LangMeta, from func() (interface{}, error),
+	dbConn Dbc, modelDef *ModelMeta, isReplaceMeta bool, param *ParamRunSetPub, langDef *LangMeta, upd *WorksetUpdateAction, from func() (interface{}, error),
 ) (int, error) {
 
 	// validate parameters
@@ -42,20 +43,40 @@ func (meta *WorksetMeta) UpdateWorksetParameterFrom(
 		return 0, errors.New("workset: " + meta.Set.Name + " invalid model id " + strconv.Itoa(meta.Set.ModelId) + " expected: " + strconv.Itoa(modelDef.Model.ModelId))
 	}
 
-	// do update in transaction scope
-	trx, err := dbConn.Begin()
-	if err != nil {
-		return 0, err
-	}
-
 	// if parameter values supplied then sub-value count must be positive
 	isData := from != nil
 	if isData && param.SubCount <= 0 {
 		return 0, errors.New("parameter sub-value count must be positive: " + strconv.Itoa(param.SubCount) + ": " + param.Name)
 	}
 
+	// if parameter values supplied then it must be valid by parameter validation rules
+	var rules []ParamRule
+	if isData {
+		r, err := GetParamRules(dbConn.DB, modelDef, param.Name)
+		if err != nil {
+			return 0, err
+		}
+		rules = r
+	}
+
+	var pm *ParamMeta
+	if k, ok := modelDef.ParamByName(param.Name); ok {
+		pm = &modelDef.Param[k]
+	} else {
+		return 0, errors.New("parameter not found: " + param.Name)
+	}
+
+	// if workset history db tables not exist then history is not saved
+	upd = upd.historyCheck(dbConn.DB, pm)
+
+	// do update in transaction scope
+	trx, err := dbConn.Begin()
+	if err != nil {
+		return 0, err
+	}
+
 	// create, replace or merge workset metadata
-	paramHid, err := doUpdateWorksetParameterMeta(trx, modelDef, meta, isReplaceMeta, param, isData, langDef)
+	paramHid, verId, err := doUpdateWorksetParameterMeta(trx, modelDef, meta, isReplaceMeta, param, isData, langDef, upd)
 	if err != nil {
 		trx.Rollback()
 		return 0, err
@@ -64,21 +85,19 @@ func (meta *WorksetMeta) UpdateWorksetParameterFrom(
 	// if new parameter values supplied then insert or update parameter values in workset
 	if paramHid > 0 && isData {
 
-		var pm *ParamMeta
-		if k, ok := modelDef.ParamByName(param.Name); ok {
-			pm = &modelDef.Param[k]
-		} else {
-			trx.Rollback()
-			return 0, errors.New("parameter not found: " + param.Name)
-		}
-
-		err = doWriteSetParameterFrom(DbTrx{Tx: trx, Dbf: dbConn.Dbf}, pm, meta.Set.SetId, param.SubCount, param.DefaultSubId, false, from, "")
+		err = doWriteSetParameterFrom(DbTrx{Tx: trx, Dbf: dbConn.Dbf}, pm, meta.Set.SetId, param.SubCount, param.DefaultSubId, false, from, "", rules, nil)
 		if err != nil {
 			trx.Rollback()
 			return 0, err
 		}
 	}
 
+	// keep in workset history only cells changed by update
+	if err = trxTrimParamHistory(trx, meta.Set.SetId, pm, verId); err != nil {
+		trx.Rollback()
+		return 0, err
+	}
+
 	trx.Commit()
 
 	return paramHid, nil
@@ -93,6 +112,12 @@ func (meta *WorksetMeta) UpdateWorksetParameterFrom(
 // If parameter not exist in workset then function does nothing (it is empty operation).
 // If input array of ParamRunSetTxtPub is empty then it is empty operation and return is success.
 func UpdateWorksetParameterText(dbConn *sql.DB, modelDef *ModelMeta, setName string, paramTxtPub []ParamRunSetTxtPub, langDef *LangMeta) error {
+	return UpdateWorksetParameterTextChecked(dbConn, modelDef, setName, paramTxtPub, langDef, nil)
+}
+
+// UpdateWorksetParameterTextChecked merge parameter value notes into workset_parameter_txt table.
+// If update action has expected update date-time then it is checked in the same transaction.
+func UpdateWorksetParameterTextChecked(dbConn *sql.DB, modelDef *ModelMeta, setName string, paramTxtPub []ParamRunSetTxtPub, langDef *LangMeta, upd *WorksetUpdateAction) error {
 
 	// validate parameters
 	if len(paramTxtPub) <= 0 {
@@ -131,7 +156,7 @@ func UpdateWorksetParameterText(dbConn *sql.DB, modelDef *ModelMeta, setName str
 	if err != nil {
 		return err
 	}
-	err = doUpdateWorksetParameterText(trx, modelDef, setName, paramLst, langDef)
+	err = doUpdateWorksetParameterText(trx, modelDef, setName, paramLst, langDef, upd)
 	if err != nil {
 		trx.Rollback()
 		return err
@@ -151,14 +176,16 @@ func UpdateWorksetParameterText(dbConn *sql.DB, modelDef *ModelMeta, setName str
 //
 // Set name is used to find workset and set id updated with actual database value.
 // Workset must be read-write for replace or merge.
+// If update action not nil then current parameter values saved in workset history before update.
+// Return parameter Hid and workset history version id, it is zero if history not saved.
 func doUpdateWorksetParameterMeta(
-	trx *sql.Tx, modelDef *ModelMeta, wm *WorksetMeta, isReplaceMeta bool, param *ParamRunSetPub, isData bool, langDef *LangMeta,
-) (int, error) {
+	trx *sql.Tx, modelDef *ModelMeta, wm *WorksetMeta, isReplaceMeta bool, param *ParamRunSetPub, isData bool, langDef *LangMeta, upd *WorksetUpdateAction,
+) (int, int, error) {
 
 	// find model parameter hId by name
 	idx, ok := modelDef.ParamByName(param.Name)
 	if !ok {
-		return 0, errors.New("model: " + modelDef.Model.Name + " parameter " + param.Name + " not found")
+		return 0, 0, errors.New("model: " + modelDef.Model.Name + " parameter " + param.Name + " not found")
 	}
 	paramHid := modelDef.Param[idx].ParamHid
 	spHid := strconv.Itoa(paramHid)
@@ -171,7 +198,7 @@ func doUpdateWorksetParameterMeta(
 			" SET is_readonly = is_readonly + 1"+
 			" WHERE model_id = "+strconv.Itoa(modelDef.Model.ModelId)+" AND set_name = "+ToQuoted(wm.Set.Name))
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
 	// check if workset exist and not readonly
@@ -187,14 +214,24 @@ func doUpdateWorksetParameterMeta(
 		})
 	switch {
 	case err == sql.ErrNoRows:
-		return 0, errors.New("failed to update: workset not found: " + wm.Set.Name)
+		return 0, 0, errors.New("failed to update: workset not found: " + wm.Set.Name)
 	case err != nil:
-		return 0, err
+		return 0, 0, err
 	case nRd != 1:
-		return 0, errors.New("failed to update: workset is read-only: " + wm.Set.Name)
+		return 0, 0, errors.New("failed to update: workset is read-only: " + wm.Set.Name)
 	}
 	wm.Set.SetId = setId // workset exist, id may be different
 
+	if err = trxCheckWorksetUpdate(trx, setId, upd); err != nil {
+		return 0, 0, err
+	}
+
+	// save current parameter values in workset history
+	verId, err := trxSaveParamHistory(trx, setId, &modelDef.Param[idx], upd)
+	if err != nil {
+		return 0, 0, err
+	}
+
 	// check if parameter exist in workset_parameter
 	sId := strconv.Itoa(wm.Set.SetId)
 
@@ -209,7 +246,7 @@ func doUpdateWorksetParameterMeta(
 			" WHERE set_id = "+sId+" AND parameter_hid = "+spHid)
 	}
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	err = TrxSelectFirst(trx,
 		"SELECT parameter_hid FROM workset_parameter WHERE set_id = "+sId+" AND parameter_hid = "+spHid,
@@ -222,13 +259,13 @@ func doUpdateWorksetParameterMeta(
 
 		})
 	if err != nil && err != sql.ErrNoRows {
-		return 0, err
+		return 0, 0, err
 	}
 	isParamExist := err == nil // not sql.ErrNoRows
 
 	// if no data then parameter must exist in workset
 	if !isData && !isParamExist {
-		return 0, errors.New("parameter: " + param.Name + " must exist in workset: " + wm.Set.Name)
+		return 0, 0, errors.New("parameter: " + param.Name + " must exist in workset: " + wm.Set.Name)
 	}
 
 	// if this is merge then insert ot update workset_parameter
@@ -241,7 +278,7 @@ func doUpdateWorksetParameterMeta(
 				"INSERT INTO workset_parameter (set_id, parameter_hid, sub_count, default_sub_id) VALUES ("+
 					sId+", "+spHid+", "+strconv.Itoa(param.SubCount)+", "+strconv.Itoa(param.DefaultSubId)+")")
 			if err != nil {
-				return 0, err
+				return 0, 0, err
 			}
 		}
 
@@ -249,17 +286,17 @@ func doUpdateWorksetParameterMeta(
 
 		err = TrxUpdate(trx, "DELETE FROM workset_parameter_txt WHERE set_id = "+sId+" AND parameter_hid = "+spHid)
 		if err != nil {
-			return 0, err
+			return 0, 0, err
 		}
 		err = TrxUpdate(trx, "DELETE FROM workset_parameter WHERE set_id = "+sId+" AND parameter_hid = "+spHid)
 		if err != nil {
-			return 0, err
+			return 0, 0, err
 		}
 		err = TrxUpdate(trx,
 			"INSERT INTO workset_parameter (set_id, parameter_hid, sub_count, default_sub_id) VALUES ("+
 				sId+", "+spHid+", "+strconv.Itoa(param.SubCount)+", "+strconv.Itoa(param.DefaultSubId)+")")
 		if err != nil {
-			return 0, err
+			return 0, 0, err
 		}
 	}
 
@@ -277,7 +314,7 @@ func doUpdateWorksetParameterMeta(
 						" AND parameter_hid = "+spHid+
 						" AND lang_id = "+slId)
 				if err != nil {
-					return 0, err
+					return 0, 0, err
 				}
 			}
 			err = TrxUpdate(trx,
@@ -288,7 +325,7 @@ func doUpdateWorksetParameterMeta(
 					" WHERE set_id = "+sId+
 					" AND parameter_hid = "+spHid)
 			if err != nil {
-				return 0, err
+				return 0, 0, err
 			}
 		}
 	}
@@ -301,10 +338,10 @@ func doUpdateWorksetParameterMeta(
 			" update_dt = "+ToQuoted(wm.Set.UpdateDateTime)+
 			" WHERE set_id = "+strconv.Itoa(setId))
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
-	return paramHid, nil
+	return paramHid, verId, nil
 }
 
 // doUpdateWorksetParameterText merge workset parameters value notes.
@@ -314,7 +351,7 @@ func doUpdateWorksetParameterMeta(
 // Workset must exist and must be read-write for replace or merge.
 //
 // If parameter not exist in workset then function does nothing (it is empty operation).
-func doUpdateWorksetParameterText(trx *sql.Tx, modelDef *ModelMeta, setName string, paramLst []worksetParam, langDef *LangMeta) error {
+func doUpdateWorksetParameterText(trx *sql.Tx, modelDef *ModelMeta, setName string, paramLst []worksetParam, langDef *LangMeta, upd *WorksetUpdateAction) error {
 
 	// "lock" workset to prevent update or use by the model
 	err := TrxUpdate(trx,
@@ -344,6 +381,9 @@ func doUpdateWorksetParameterText(trx *sql.Tx, modelDef *ModelMeta, setName stri
 	case nRd != 1:
 		return errors.New("failed to update: workset is read-only: " + setName)
 	}
+	if err = trxCheckWorksetUpdate(trx, setId, upd); err != nil {
+		return err
+	}
 	sId := strconv.Itoa(setId)
 
 	// merge parameter(s) value notes
@@ -0,0 +1,71 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"testing"
+)
+
+func TestWorksetUpdateCheck(t *testing.T) {
+
+	dbConn, modelDef, langDef := createTestModel(t)
+
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, modelDef, langDef, "r1", &v)
+	setId := createTestWorkset(t, dbConn, modelDef, langDef, "ws", r1, &v)
+
+	// return current workset update date-time
+	updateDt := func() string {
+		ws, err := GetWorkset(dbConn.DB, setId)
+		if err != nil || ws == nil {
+			t.Fatal("****FAIL: read workset:", setId, err)
+		}
+		return ws.UpdateDateTime
+	}
+	staleDt := "2000-01-01 00:00:00.000"
+
+	// update date-time is different: workset updated by other user and not changed
+	upd := &WorksetUpdateAction{UserName: "u1", Action: "page", UpdateDateTime: staleDt}
+
+	if err := UpdateWorksetReadonlyChecked(dbConn.DB, setId, true, upd); err != ErrWorksetUpdated {
+		t.Error("****FAIL: expected workset update conflict, found:", err)
+	}
+	err := WriteParameterFrom(dbConn, modelDef,
+		&WriteParamLayout{WriteLayout: WriteLayout{Name: "salarySex", ToId: setId}, SubCount: 1, IsPage: true, UpdateAction: upd},
+		paramCellsFrom([]CellParam{{cellIdValue: cellIdValue{DimIds: []int{1}, Value: 25.0}}}))
+	if err != ErrWorksetUpdated {
+		t.Error("****FAIL: expected workset parameter update conflict, found:", err)
+	}
+	if fv := readTestParam(t, dbConn, modelDef, "salarySex", setId, true); fv[1] != 20.0 {
+		t.Error("****FAIL: expected not updated parameter value: 20 found:", fv[1])
+	}
+	if err = DeleteWorksetChecked(dbConn.DB, setId, upd); err != ErrWorksetUpdated {
+		t.Error("****FAIL: expected workset delete conflict, found:", err)
+	}
+	if ws, err := GetWorkset(dbConn.DB, setId); err != nil || ws == nil || ws.IsReadonly {
+		t.Fatal("****FAIL: expected not changed read-write workset, found:", ws, err)
+	}
+
+	// update date-time is the same: workset updated
+	upd.UpdateDateTime = updateDt()
+
+	err = WriteParameterFrom(dbConn, modelDef,
+		&WriteParamLayout{WriteLayout: WriteLayout{Name: "salarySex", ToId: setId}, SubCount: 1, IsPage: true, UpdateAction: upd},
+		paramCellsFrom([]CellParam{{cellIdValue: cellIdValue{DimIds: []int{1}, Value: 25.0}}}))
+	if err != nil {
+		t.Fatal("****FAIL: update workset parameter:", err)
+	}
+	if fv := readTestParam(t, dbConn, modelDef, "salarySex", setId, true); fv[1] != 25.0 {
+		t.Error("****FAIL: expected updated parameter value: 25 found:", fv[1])
+	}
+
+	upd.UpdateDateTime = updateDt()
+
+	if err = UpdateWorksetReadonlyChecked(dbConn.DB, setId, true, upd); err != nil {
+		t.Fatal("****FAIL: update workset read-only status:", err)
+	}
+	if ws, err := GetWorkset(dbConn.DB, setId); err != nil || ws == nil || !ws.IsReadonly {
+		t.Error("****FAIL: expected read-only workset, found:", ws, err)
+	}
+}
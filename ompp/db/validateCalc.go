@@ -0,0 +1,638 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ValidateCalcLayout describes output table or microdata calculation to validate.
+type ValidateCalcLayout struct {
+	Name      string   // output table name or entity name
+	IsMicro   bool     // if true then it is microdata calculation and name is an entity name
+	Calculate string   // expression to validate, ex.: Expr0[variant] - Expr0[base] or OM_AVG(acc0)
+	IsAggr    bool     // if true then it is output table accumulators aggregation
+	IsRunAggr bool     // if true then it is aggregation of output table expressions across model runs
+	GroupBy   []string // microdata group by attributes
+}
+
+// CalcValidate is a result of calculation validation.
+type CalcValidate struct {
+	Calculate    string              // source calculation expression
+	IsValid      bool                // if true then expression is valid
+	IsRunCompare bool                // if true then it is a run comparison: name[base] and name[variant] are used
+	ResultType   string              // inferred result type: float, int, bool or string, empty if expression is not valid
+	Error        []CalcValidateError // list of errors, empty if expression is valid
+}
+
+// CalcValidateError is an error found in calculation expression.
+type CalcValidateError struct {
+	Pos  int    // zero-based character position in source expression, -1 if position is unknown
+	Len  int    // length of invalid part of expression in characters, zero if unknown
+	Name string // unknown or invalid name, empty if error is not related to the name
+	Msg  string // error message
+}
+
+// kind of calculation expression token
+type calcTokenKind int
+
+const (
+	nameToken   calcTokenKind = iota // name: Expr0, acc1, param.Name, table.Name.Expr0, CASE, WHEN
+	fncToken                         // function name followed by (: OM_AVG( or ABS(
+	numberToken                      // number: 1, 1.5, 2.0e-3
+	quotedToken                      // 'quoted' string
+	opToken                          // operator: + - * / = < > <= >= <> != || AND OR...
+	openToken                        // opening parenthesis (
+	closeToken                       // closing parenthesis )
+	commaToken                       // comma ,
+)
+
+// calculation expression token
+type calcToken struct {
+	kind   calcTokenKind // token kind
+	text   string        // token text, ex.: Expr0 or OM_AVG
+	suffix string        // name suffix: base or variant
+	isInt  bool          // if true then it is integer number
+	pos    int           // zero-based character position in source expression
+	len    int           // token length in characters, including [suffix]
+}
+
+// sql keywords allowed in calculation expression
+var calcSqlKeywords = []string{
+	"AND", "AS", "BETWEEN", "CASE", "CAST", "ELSE", "END", "FALSE", "FLOAT", "IN", "INTEGER",
+	"IS", "LIKE", "NOT", "NULL", "OR", "REAL", "THEN", "TRUE", "WHEN",
+}
+
+// ValidateCalculate parse and validate output table or microdata calculation expression without running it.
+//
+// It checks names of output table expressions, accumulators, entity attributes, parameters and other output tables,
+// usage of name[base] and name[variant], function names, 'quotes' and (parentheses).
+// Each error returned with character position in source expression, so user interface can highlight it.
+// If there are no such errors then expression translated into sql and translation error returned without position.
+// Result type is inferred from top level of expression: comparison or logical operator is bool,
+// OM_COUNT() is int, 'quoted' string is string and anything else is float.
+//
+// It return an error only if model metadata, output table or entity not found.
+func ValidateCalculate(modelDef *ModelMeta, layout *ValidateCalcLayout) (*CalcValidate, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if layout == nil {
+		return nil, errors.New("invalid (empty) calculation layout")
+	}
+	if layout.Name == "" {
+		return nil, errors.New("invalid (empty) output table or entity name")
+	}
+
+	var table *TableMeta
+	var entity *EntityMeta
+
+	if layout.IsMicro {
+		k, ok := modelDef.EntityByName(layout.Name)
+		if !ok {
+			return nil, errors.New("entity not found: " + layout.Name)
+		}
+		entity = &modelDef.Entity[k]
+	} else {
+		k, ok := modelDef.OutTableByName(layout.Name)
+		if !ok {
+			return nil, errors.New("output table not found: " + layout.Name)
+		}
+		table = &modelDef.Table[k]
+	}
+
+	cv := CalcValidate{
+		Calculate: layout.Calculate,
+		Error:     []CalcValidateError{},
+	}
+	if strings.TrimSpace(layout.Calculate) == "" {
+		cv.Error = append(cv.Error, CalcValidateError{Pos: -1, Msg: "invalid (empty) calculation expression"})
+		return &cv, nil
+	}
+
+	// split expression into tokens and check names, functions and parentheses
+	src := cleanSourceExpr(layout.Calculate)
+
+	tl, eLst := tokenizeCalc(src)
+	cv.Error = append(cv.Error, eLst...)
+
+	isCompare, eLst := validateCalcTokens(modelDef, table, entity, layout, tl)
+	cv.Error = append(cv.Error, eLst...)
+	cv.IsRunCompare = isCompare
+
+	if len(cv.Error) > 0 {
+		return &cv, nil
+	}
+
+	// translate expression into sql to find any other errors
+	readLt := ReadLayout{Name: layout.Name, FromId: 1}
+	var err error
+
+	if !layout.IsMicro {
+
+		calcLt := []CalculateTableLayout{{
+			CalculateLayout: CalculateLayout{Calculate: layout.Calculate, CalcId: CALCULATED_ID_OFFSET, Name: "calc_validate"},
+			IsAggr:          layout.IsAggr,
+			IsRunAggr:       layout.IsRunAggr,
+		}}
+		_, err = translateTableCalcToSql(DefaultFacet, modelDef, table, &readLt, calcLt, []int{2})
+
+	} else {
+
+		// entity generation with all entity attributes
+		eg := EntityGenMeta{
+			entityGenRow: entityGenRow{
+				ModelId:       modelDef.Model.ModelId,
+				EntityId:      entity.EntityId,
+				EntityHid:     entity.EntityHid,
+				DbEntityTable: entity.Name + "_validate",
+			},
+			GenAttr: make([]entityGenAttrRow, len(entity.Attr)),
+		}
+		for k := range entity.Attr {
+			eg.GenAttr[k].AttrId = entity.Attr[k].AttrId
+		}
+
+		calcLt := CalculateMicroLayout{
+			Calculation: []CalculateLayout{{Calculate: layout.Calculate, CalcId: CALCULATED_ID_OFFSET, Name: "calc_validate"}},
+			GroupBy:     layout.GroupBy,
+		}
+		_, err = translateMicroToSql(DefaultFacet, modelDef, entity, &eg, &readLt, &calcLt, []int{2})
+	}
+	if err != nil {
+		cv.Error = append(cv.Error, CalcValidateError{Pos: -1, Msg: err.Error()})
+		return &cv, nil
+	}
+
+	cv.IsValid = true
+	cv.ResultType = inferCalcResultType(tl)
+	return &cv, nil
+}
+
+// split calculation expression into tokens, return list of tokens and errors found.
+// Positions and lengths of tokens are in characters (runes), not in bytes.
+func tokenizeCalc(src string) ([]calcToken, []CalcValidateError) {
+
+	const opChars = "+-*/%^|&~!=<>"
+
+	tl := []calcToken{}
+	eLst := []CalcValidateError{}
+
+	isNameRune := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+	rPos := 0 // current position in characters
+	for n := 0; n < len(src); {
+
+		r, w := utf8.DecodeRuneInString(src[n:])
+		nr := rune(0)
+		if n+w < len(src) {
+			nr, _ = utf8.DecodeRuneInString(src[n+w:])
+		}
+		e := n + w // end of token, bytes position
+
+		switch {
+		case unicode.IsSpace(r):
+
+		case r == '\'': // 'quoted' string, doubled '' quote is a part of the string
+
+			isClosed := false
+			for e < len(src) && !isClosed {
+				if src[e] == '\'' {
+					if e+1 < len(src) && src[e+1] == '\'' {
+						e += 2
+						continue
+					}
+					isClosed = true
+				}
+				_, cw := utf8.DecodeRuneInString(src[e:])
+				e += cw
+			}
+			rl := utf8.RuneCountInString(src[n:e])
+			if !isClosed {
+				eLst = append(eLst, CalcValidateError{Pos: rPos, Len: rl, Msg: "unbalanced 'quotes', closing quote not found"})
+				return tl, eLst
+			}
+			tl = append(tl, calcToken{kind: quotedToken, text: src[n:e], pos: rPos, len: rl})
+
+		case r == '_' || unicode.IsLetter(r): // name or function: Expr0, param.Name, table.Name.Expr0, OM_AVG(
+
+			for e < len(src) {
+				c, cw := utf8.DecodeRuneInString(src[e:])
+				if !isNameRune(c) && c != '.' {
+					break
+				}
+				e += cw
+			}
+			t := calcToken{kind: nameToken, text: src[n:e], pos: rPos}
+
+			// name[base] or name[variant] suffix
+			if e < len(src) && src[e] == '[' {
+				j := strings.IndexByte(src[e:], ']')
+				if j < 0 {
+					eLst = append(eLst, CalcValidateError{
+						Pos: rPos + utf8.RuneCountInString(src[n:e]), Len: utf8.RuneCountInString(src[e:]), Msg: "closing bracket ] not found"})
+					return tl, eLst
+				}
+				t.suffix = src[e+1 : e+j]
+				e += j + 1
+			}
+			t.len = utf8.RuneCountInString(src[n:e])
+
+			// if name followed by ( then it is a function name
+			if strings.HasPrefix(strings.TrimLeftFunc(src[e:], unicode.IsSpace), "(") {
+				t.kind = fncToken
+			}
+			tl = append(tl, t)
+
+		case unicode.IsDigit(r) || r == '.' && unicode.IsDigit(nr): // number: 12, 1.5, .5, 2e-3
+
+			isInt := r != '.'
+			for e < len(src) {
+				c := src[e]
+				if c >= '0' && c <= '9' {
+					e++
+					continue
+				}
+				if c == '.' {
+					isInt = false
+					e++
+					continue
+				}
+				if (c == 'e' || c == 'E') && e+1 < len(src) {
+					isInt = false
+					e++
+					if src[e] == '+' || src[e] == '-' {
+						e++
+					}
+					continue
+				}
+				break
+			}
+			tl = append(tl, calcToken{kind: numberToken, text: src[n:e], isInt: isInt, pos: rPos, len: utf8.RuneCountInString(src[n:e])})
+
+		case r == '(':
+			tl = append(tl, calcToken{kind: openToken, text: "(", pos: rPos, len: 1})
+		case r == ')':
+			tl = append(tl, calcToken{kind: closeToken, text: ")", pos: rPos, len: 1})
+		case r == ',':
+			tl = append(tl, calcToken{kind: commaToken, text: ",", pos: rPos, len: 1})
+
+		case r == '-' && nr == '-':
+			eLst = append(eLst, CalcValidateError{Pos: rPos, Len: utf8.RuneCountInString(src[n:]), Msg: "SQL -- comment is not allowed"})
+			return tl, eLst
+
+		case strings.ContainsRune(opChars, r): // operator: + - * / < <= >= <> != ||
+
+			if strings.ContainsRune(opChars, nr) && nr != '-' && nr != '+' && nr != '~' && nr != '!' {
+				e += utf8.RuneLen(nr)
+			}
+			tl = append(tl, calcToken{kind: opToken, text: src[n:e], pos: rPos, len: utf8.RuneCountInString(src[n:e])})
+
+		case r == '[':
+
+			j := strings.IndexByte(src[n:], ']')
+			if j < 0 {
+				j = len(src) - n - 1
+			}
+			e = n + j + 1
+			eLst = append(eLst, CalcValidateError{
+				Pos: rPos, Len: utf8.RuneCountInString(src[n:e]), Msg: "invalid use of " + src[n:e] + ", it must follow the name, ex.: Expr0[base]"})
+
+		case r == ';':
+			eLst = append(eLst, CalcValidateError{Pos: rPos, Len: 1, Msg: "semicolon is not allowed"})
+		case r == '\\':
+			eLst = append(eLst, CalcValidateError{Pos: rPos, Len: 1, Msg: "SQL \\ escape sequence is not allowed"})
+		default:
+			eLst = append(eLst, CalcValidateError{Pos: rPos, Len: 1, Msg: "unexpected character: " + string(r)})
+		}
+
+		rPos += utf8.RuneCountInString(src[n:e])
+		n = e
+	}
+
+	return tl, eLst
+}
+
+// validate calculation tokens: names, functions, [base] and [variant] usage and parentheses.
+// Return true if it is a run comparison and list of errors found.
+func validateCalcTokens(
+	modelDef *ModelMeta, table *TableMeta, entity *EntityMeta, layout *ValidateCalcLayout, tl []calcToken,
+) (
+	bool, []CalcValidateError,
+) {
+
+	eLst := []CalcValidateError{}
+
+	addErr := func(t *calcToken, name, msg string) {
+		eLst = append(eLst, CalcValidateError{Pos: t.pos, Len: t.len, Name: name, Msg: msg})
+	}
+	isAggrAllowed := layout.IsMicro || layout.IsAggr || layout.IsRunAggr
+	isSuffixAllowed := layout.IsMicro || !layout.IsAggr && !layout.IsRunAggr
+
+	// name usage: simple name or name[base] or name[variant], for parameters and for other names
+	simpleIdx := []int{}
+	suffixIdx := []int{}
+	paramSimpleIdx := []int{}
+	paramSuffixIdx := []int{}
+	isBase := false
+	isVar := false
+	isAggrFnc := false
+
+	openIdx := []int{}
+
+	for k := range tl {
+
+		t := &tl[k]
+
+		switch t.kind {
+		case openToken:
+			openIdx = append(openIdx, k)
+			continue
+		case closeToken:
+			if len(openIdx) <= 0 {
+				addErr(t, "", "unexpected closing parenthesis, opening parenthesis not found")
+			} else {
+				openIdx = openIdx[:len(openIdx)-1]
+			}
+			continue
+		case nameToken, fncToken:
+		default:
+			continue
+		}
+
+		// name or function: check name suffix, unsafe sql and function name
+		name := t.text
+
+		if t.suffix != "" {
+			if t.suffix != "base" && t.suffix != "variant" {
+				addErr(t, name, "invalid name suffix ["+t.suffix+"], expected: [base] or [variant]")
+				continue
+			}
+			if !isSuffixAllowed {
+				addErr(t, name, "aggregation cannot be a run comparison, [base] or [variant] is not allowed: "+name+"["+t.suffix+"]")
+				continue
+			}
+		}
+		if !strings.Contains(name, ".") && errorIfUnsafeSqlKeyword(name) != nil {
+			addErr(t, name, "unsafe SQL keyword is not allowed: "+name)
+			continue
+		}
+
+		if t.kind == fncToken {
+
+			if t.suffix != "" {
+				addErr(t, name, "invalid use of [base] or [variant] after function name: "+name)
+				continue
+			}
+			if !strings.HasPrefix(strings.ToUpper(name), "OM_") {
+				continue // sql function, ex.: ABS SQRT
+			}
+			isFound := false
+			for j := 0; !isFound && j < len(simpleFncLst); j++ {
+				isFound = simpleFncLst[j] == name
+			}
+			if isFound {
+				continue
+			}
+			for j := 0; !isFound && j < len(aggrFncLst); j++ {
+				isFound = aggrFncLst[j] == name
+			}
+			if !isFound {
+				addErr(t, name, "unknown function: "+name)
+				continue
+			}
+			isAggrFnc = true
+			if !isAggrAllowed {
+				addErr(t, name, "aggregation function is not allowed in output table expression calculation: "+name)
+			}
+			continue
+		}
+
+		// sql keyword
+		isKey := false
+		for j := 0; !isKey && j < len(calcSqlKeywords); j++ {
+			isKey = strings.EqualFold(calcSqlKeywords[j], name)
+		}
+		if isKey {
+			if t.suffix != "" {
+				addErr(t, name, "invalid use of [base] or [variant] after SQL keyword: "+name)
+			}
+			continue
+		}
+
+		// parameter: param.Name
+		if strings.HasPrefix(name, "param.") {
+
+			pn := name[len("param."):]
+			j, ok := modelDef.ParamByName(pn)
+			if !ok {
+				addErr(t, pn, "parameter not found: "+pn)
+				continue
+			}
+			p := &modelDef.Param[j]
+			if p.Rank != 0 || p.typeOf == nil || !p.typeOf.IsFloat() && !p.typeOf.IsInt() {
+				addErr(t, pn, "parameter must a be numeric scalar: "+pn)
+				continue
+			}
+			if t.suffix != "" {
+				paramSuffixIdx = append(paramSuffixIdx, k)
+			} else {
+				paramSimpleIdx = append(paramSimpleIdx, k)
+			}
+			continue
+		}
+
+		// other output table expression: table.Name.Expr0
+		if strings.HasPrefix(name, "table.") {
+
+			if layout.IsMicro || layout.IsAggr || layout.IsRunAggr {
+				addErr(t, name, "other output table can be used only in output table expression calculation: "+name)
+				continue
+			}
+			parts := strings.Split(name[len("table."):], ".")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				addErr(t, name, "invalid output table name, expected: table.Name.Expression at: "+name)
+				continue
+			}
+			j, ok := modelDef.OutTableByName(parts[0])
+			if !ok {
+				addErr(t, parts[0], "output table not found: "+parts[0])
+				continue
+			}
+			xt := &modelDef.Table[j]
+			if xt.TableHid == table.TableHid {
+				addErr(t, name, "invalid use of current output table name, use expression name without table prefix: "+name)
+				continue
+			}
+			isFound := false
+			for i := 0; !isFound && i < len(xt.Expr); i++ {
+				isFound = xt.Expr[i].Name == parts[1]
+			}
+			if !isFound {
+				addErr(t, parts[1], "output table "+xt.Name+" does not have expression: "+parts[1])
+				continue
+			}
+			if _, err := matchCrossTableDims(table, xt); err != nil {
+				addErr(t, name, err.Error())
+				continue
+			}
+		} else {
+
+			// output table expression, accumulator or entity attribute
+			if strings.Contains(name, ".") {
+				addErr(t, name, "invalid name: "+name)
+				continue
+			}
+			if msg := validateCalcName(table, entity, layout, name); msg != "" {
+				addErr(t, name, msg)
+				continue
+			}
+		}
+
+		if t.suffix != "" {
+			suffixIdx = append(suffixIdx, k)
+			isBase = isBase || t.suffix == "base"
+			isVar = isVar || t.suffix == "variant"
+		} else {
+			simpleIdx = append(simpleIdx, k)
+		}
+	}
+
+	// all parentheses must be closed
+	for _, k := range openIdx {
+		addErr(&tl[k], "", "closing parenthesis not found")
+	}
+
+	// names must be either name[base] and name[variant] or simple names, it cannot be mixed
+	isCompare := len(suffixIdx) > 0
+
+	if isCompare {
+		for _, k := range simpleIdx {
+			addErr(&tl[k], tl[k].text, "invalid use of simple name in run comparison, expected: "+tl[k].text+"[base] or "+tl[k].text+"[variant]")
+		}
+		if !isBase || !isVar {
+			addErr(&tl[suffixIdx[0]], tl[suffixIdx[0]].text, "run comparison must use both [base] and [variant] names")
+		}
+		for _, k := range paramSimpleIdx {
+			addErr(&tl[k], tl[k].text, "invalid use of parameter name in run comparison, expected: "+tl[k].text+"[base] or "+tl[k].text+"[variant]")
+		}
+	} else {
+		for _, k := range paramSuffixIdx {
+			addErr(&tl[k], tl[k].text, "invalid use of parameter run comparison name, it is not a run comparison: "+tl[k].text+"["+tl[k].suffix+"]")
+		}
+	}
+
+	// microdata calculation and output table aggregation must use aggregation function
+	if isAggrAllowed && !isAggrFnc {
+		eLst = append(eLst, CalcValidateError{Pos: -1, Msg: "aggregation function not found, ex.: OM_AVG"})
+	}
+
+	return isCompare, eLst
+}
+
+// validate output table expression, accumulator or entity attribute name.
+// Return error message or empty string if name is valid.
+func validateCalcName(table *TableMeta, entity *EntityMeta, layout *ValidateCalcLayout, name string) string {
+
+	if layout.IsMicro {
+		if _, ok := entity.AttrByName(name); !ok {
+			return "entity " + entity.Name + " does not have attribute: " + name
+		}
+		return ""
+	}
+
+	isExpr := false
+	for k := 0; !isExpr && k < len(table.Expr); k++ {
+		isExpr = table.Expr[k].Name == name
+	}
+	isAcc := false
+	for k := 0; !isAcc && k < len(table.Acc); k++ {
+		isAcc = table.Acc[k].Name == name
+	}
+	isDim := false
+	for k := 0; !isDim && k < len(table.Dim); k++ {
+		isDim = table.Dim[k].Name == name
+	}
+
+	switch {
+	case layout.IsAggr && isAcc || !layout.IsAggr && isExpr:
+		return ""
+	case isDim:
+		return "dimension name cannot be used in calculation: " + name
+	case layout.IsAggr && isExpr:
+		return "expression name cannot be used in accumulators aggregation, use accumulator name: " + name
+	case !layout.IsAggr && isAcc:
+		return "accumulator name can be used only in accumulators aggregation: " + name
+	case layout.IsAggr:
+		return "output table " + table.Name + " does not have accumulator: " + name
+	}
+	return "output table " + table.Name + " does not have expression: " + name
+}
+
+// infer calculation result type from top level of expression tokens: float, int, bool or string
+func inferCalcResultType(tl []calcToken) string {
+
+	if len(tl) == 1 && tl[0].kind == quotedToken {
+		return "string"
+	}
+	if len(tl) == 1 && tl[0].kind == numberToken && tl[0].isInt {
+		return "int"
+	}
+
+	// top level comparison or logical operator: result is bool
+	nLevel := 0
+	for _, t := range tl {
+
+		switch t.kind {
+		case openToken:
+			nLevel++
+		case closeToken:
+			nLevel--
+		case nameToken:
+			switch strings.ToUpper(t.text) {
+			case "CASE":
+				nLevel++
+			case "END":
+				nLevel--
+			case "AND", "OR", "NOT", "IS", "IN", "BETWEEN", "LIKE":
+				if nLevel == 0 {
+					return "bool"
+				}
+			}
+		case opToken:
+			switch t.text {
+			case "=", "==", "<", ">", "<=", ">=", "<>", "!=":
+				if nLevel == 0 {
+					return "bool"
+				}
+			}
+		}
+	}
+
+	// count function: OM_COUNT(...) or OM_COUNT_IF(...)
+	if len(tl) >= 3 && tl[0].kind == fncToken && (tl[0].text == "OM_COUNT" || tl[0].text == "OM_COUNT_IF") {
+
+		nLevel = 0
+		for k := 1; k < len(tl); k++ {
+			if tl[k].kind == openToken {
+				nLevel++
+			}
+			if tl[k].kind == closeToken {
+				nLevel--
+				if nLevel == 0 {
+					if k == len(tl)-1 {
+						return "int"
+					}
+					break
+				}
+			}
+		}
+	}
+	return "float"
+}
@@ -0,0 +1,705 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+)
+
+// ModelVerify is a result of model data integrity verification:
+// model runs value digests, orphaned rows in value tables and metadata rows which reference missing Hid's.
+type ModelVerify struct {
+	ModelName   string             // model name
+	ModelDigest string             // model digest
+	IsValid     bool               // if true then no errors found
+	Run         []RunVerify        // model runs value digest verification results
+	Orphan      []OrphanVerify     // orphaned rows in parameter, output table and microdata value tables
+	MissingHid  []MissingHidVerify // metadata rows which are reference to missing Hid's
+}
+
+// RunVerify is a result of model run values verification: parameters, output tables and microdata digests.
+type RunVerify struct {
+	RunId     int           // model run id
+	RunDigest string        // model run digest
+	Name      string        // model run name
+	Status    string        // model run status
+	IsValid   bool          // if true then all stored digests are equal to actual values digest
+	Param     []ValueVerify // run parameters digest verification
+	Table     []ValueVerify // run output tables digest verification
+	Entity    []ValueVerify // run microdata digest verification
+}
+
+// ValueVerify is a result of model run parameter, output table or microdata digest verification.
+type ValueVerify struct {
+	Name         string // parameter name, output table name or entity name
+	Hid          int    // parameter Hid, output table Hid or entity generation Hid
+	BaseRunId    int    // base run id where values are stored
+	RowCount     int    // actual row count
+	Digest       string // value digest stored in database
+	ActualDigest string // value digest calculated from actual values
+	IsValid      bool   // if true then stored digest is equal to actual values digest
+	Msg          string // if not empty then error message, e.g. unable to calculate digest
+}
+
+// OrphanVerify is a group of value rows which are not referenced by any model run or workset.
+type OrphanVerify struct {
+	Kind     string // orphaned rows kind: parameter, workset, accumulator, expression or microdata
+	Name     string // parameter name, output table name or entity name
+	DbTable  string // db table name where orphaned rows found
+	Id       int    // run id or set id of orphaned rows
+	RowCount int    // orphaned rows count
+}
+
+// MissingHidVerify is a metadata row which is a reference to missing Hid or missing base run id.
+type MissingHidVerify struct {
+	DbTable string // metadata table name: run_parameter, run_table, run_entity or workset_parameter
+	Column  string // column name: parameter_hid, table_hid, entity_gen_hid or base_run_id
+	Id      int    // run id or set id
+	Hid     int    // missing Hid or base run id value
+}
+
+// VerifyModelRuns verify model data integrity: recalculate and compare value digests of model runs,
+// find orphaned rows in value tables and metadata rows which are reference to missing Hid's.
+//
+// If runIdLst is empty then all completed runs of the model verified.
+// Double format is used for float model types digest calculation, if non-empty format supplied.
+func VerifyModelRuns(dbConn *sql.DB, modelDef *ModelMeta, runIdLst []int, doubleFmt string) (*ModelVerify, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+
+	mv := ModelVerify{
+		ModelName:   modelDef.Model.Name,
+		ModelDigest: modelDef.Model.Digest,
+		IsValid:     true,
+		Run:         []RunVerify{},
+		Orphan:      []OrphanVerify{},
+		MissingHid:  []MissingHidVerify{},
+	}
+
+	// if run list not specified then verify all completed model runs
+	if len(runIdLst) <= 0 {
+
+		rLst, err := GetRunList(dbConn, modelDef.Model.ModelId)
+		if err != nil {
+			return nil, err
+		}
+		for k := range rLst {
+			if IsRunCompleted(rLst[k].Status) {
+				runIdLst = append(runIdLst, rLst[k].RunId)
+			}
+		}
+	}
+
+	for _, runId := range runIdLst {
+
+		rv, err := VerifyRun(dbConn, modelDef, runId, doubleFmt)
+		if err != nil {
+			return nil, err
+		}
+		mv.Run = append(mv.Run, *rv)
+		mv.IsValid = mv.IsValid && rv.IsValid
+	}
+
+	// find orphaned value rows and metadata rows which are reference to missing Hid's
+	oLst, err := verifyOrphanRows(dbConn, modelDef)
+	if err != nil {
+		return nil, err
+	}
+	mv.Orphan = oLst
+
+	hLst, err := verifyMissingHid(dbConn, modelDef.Model.ModelId)
+	if err != nil {
+		return nil, err
+	}
+	mv.MissingHid = hLst
+
+	mv.IsValid = mv.IsValid && len(mv.Orphan) <= 0 && len(mv.MissingHid) <= 0
+
+	return &mv, nil
+}
+
+// VerifyRun recalculate parameters, output tables and microdata value digests of model run and compare it with digests stored in database.
+//
+// Model run must be completed: success, error or exit.
+// Double format is used for float model types digest calculation, if non-empty format supplied.
+func VerifyRun(dbConn *sql.DB, modelDef *ModelMeta, runId int, doubleFmt string) (*RunVerify, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+
+	// check if model run exist, belong to the model and completed
+	runRow, err := GetRun(dbConn, runId)
+	if err != nil {
+		return nil, err
+	}
+	if runRow == nil {
+		return nil, errors.New("model run not found, id: " + strconv.Itoa(runId))
+	}
+	if runRow.ModelId != modelDef.Model.ModelId {
+		return nil, errors.New("model run " + strconv.Itoa(runId) + " does not belong to model " + modelDef.Model.Name + " " + modelDef.Model.Digest)
+	}
+	if !IsRunCompleted(runRow.Status) {
+		return nil, errors.New("model run not completed, id: " + strconv.Itoa(runId))
+	}
+	srId := strconv.Itoa(runId)
+
+	rv := RunVerify{
+		RunId:     runRow.RunId,
+		RunDigest: runRow.RunDigest,
+		Name:      runRow.Name,
+		Status:    runRow.Status,
+		IsValid:   true,
+		Param:     []ValueVerify{},
+		Table:     []ValueVerify{},
+		Entity:    []ValueVerify{},
+	}
+
+	// verify run parameters digest
+	err = SelectRows(dbConn,
+		"SELECT parameter_hid, base_run_id, value_digest FROM run_parameter WHERE run_id = "+srId+" ORDER BY 1",
+		func(rows *sql.Rows) error {
+			var vv ValueVerify
+			var svd sql.NullString
+			if err := rows.Scan(&vv.Hid, &vv.BaseRunId, &svd); err != nil {
+				return err
+			}
+			if svd.Valid {
+				vv.Digest = svd.String
+			}
+			rv.Param = append(rv.Param, vv)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range rv.Param {
+
+		vv := &rv.Param[k]
+
+		idx, ok := modelDef.ParamByHid(vv.Hid)
+		if !ok {
+			vv.Msg = "parameter not found by Hid: " + strconv.Itoa(vv.Hid)
+			continue
+		}
+		param := &modelDef.Param[idx]
+		vv.Name = param.Name
+
+		hMd5, digestFrom, _, err := digestParameterFrom(modelDef, param, doubleFmt)
+		if err != nil {
+			return nil, err
+		}
+
+		// SELECT sub_id, dim0, dim1, param_value FROM ageSex_p2012_817 WHERE run_id = 1234 ORDER BY 1, 2, 3
+		q := "SELECT sub_id, "
+		for j := range param.Dim {
+			q += param.Dim[j].colName + ", "
+		}
+		q += "param_value FROM " + param.DbRunTable + " WHERE run_id = " + strconv.Itoa(vv.BaseRunId)
+		q += makeOrderBy(param.Rank, nil, 1)
+
+		scanBuf, fc := scanSqlRowToCellParam(param)
+
+		err = SelectRows(dbConn, q,
+			func(rows *sql.Rows) error {
+
+				if e := rows.Scan(scanBuf...); e != nil {
+					return e
+				}
+				var c = CellParam{cellIdValue: cellIdValue{DimIds: make([]int, param.Rank)}}
+				if e := fc(&c); e != nil {
+					return e
+				}
+				vv.RowCount++
+
+				return digestFrom(c)
+			})
+		verifyDigestResult(vv, hMd5, err)
+	}
+
+	// verify run output tables digest
+	err = SelectRows(dbConn,
+		"SELECT table_hid, base_run_id, value_digest FROM run_table WHERE run_id = "+srId+" ORDER BY 1",
+		func(rows *sql.Rows) error {
+			var vv ValueVerify
+			var svd sql.NullString
+			if err := rows.Scan(&vv.Hid, &vv.BaseRunId, &svd); err != nil {
+				return err
+			}
+			if svd.Valid {
+				vv.Digest = svd.String
+			}
+			rv.Table = append(rv.Table, vv)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range rv.Table {
+
+		vv := &rv.Table[k]
+
+		idx, ok := modelDef.OutTableByHid(vv.Hid)
+		if !ok {
+			vv.Msg = "output table not found by Hid: " + strconv.Itoa(vv.Hid)
+			continue
+		}
+		table := &modelDef.Table[idx]
+		vv.Name = table.Name
+
+		hMd5, err := verifyTableDigest(dbConn, modelDef, table, vv, doubleFmt)
+		verifyDigestResult(vv, hMd5, err)
+	}
+
+	// verify run microdata digest
+	egLst, err := GetEntityGenList(dbConn, runId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = SelectRows(dbConn,
+		"SELECT entity_gen_hid, base_run_id, value_digest FROM run_entity WHERE run_id = "+srId+" ORDER BY 1",
+		func(rows *sql.Rows) error {
+			var vv ValueVerify
+			var svd sql.NullString
+			if err := rows.Scan(&vv.Hid, &vv.BaseRunId, &svd); err != nil {
+				return err
+			}
+			if svd.Valid {
+				vv.Digest = svd.String
+			}
+			rv.Entity = append(rv.Entity, vv)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range rv.Entity {
+
+		vv := &rv.Entity[k]
+
+		var entityGen *EntityGenMeta
+		for j := range egLst {
+			if egLst[j].GenHid == vv.Hid {
+				entityGen = &egLst[j]
+				break
+			}
+		}
+		if entityGen == nil {
+			vv.Msg = "entity generation not found by Hid: " + strconv.Itoa(vv.Hid)
+			continue
+		}
+
+		idx, ok := modelDef.EntityByKey(entityGen.EntityId)
+		if !ok {
+			vv.Msg = "entity not found by id: " + strconv.Itoa(entityGen.EntityId)
+			continue
+		}
+		entity := &modelDef.Entity[idx]
+		vv.Name = entity.Name
+
+		hMd5, err := verifyMicrodataDigest(dbConn, modelDef, entity, entityGen, vv, doubleFmt)
+		verifyDigestResult(vv, hMd5, err)
+	}
+
+	// run is valid if all digests are valid
+	for k := range rv.Param {
+		rv.IsValid = rv.IsValid && rv.Param[k].IsValid
+	}
+	for k := range rv.Table {
+		rv.IsValid = rv.IsValid && rv.Table[k].IsValid
+	}
+	for k := range rv.Entity {
+		rv.IsValid = rv.IsValid && rv.Entity[k].IsValid
+	}
+
+	return &rv, nil
+}
+
+// verifyDigestResult set actual digest and compare it with stored digest value.
+func verifyDigestResult(vv *ValueVerify, hSum hash.Hash, err error) {
+	if err != nil {
+		vv.Msg = err.Error()
+		return
+	}
+	vv.ActualDigest = fmt.Sprintf("%x", hSum.Sum(nil))
+	vv.IsValid = vv.Digest != "" && vv.Digest == vv.ActualDigest
+}
+
+// verifyTableDigest calculate output table digest from accumulators and expressions values of base run.
+func verifyTableDigest(dbConn *sql.DB, modelDef *ModelMeta, table *TableMeta, vv *ValueVerify, doubleFmt string) (hash.Hash, error) {
+
+	sBaseId := strconv.Itoa(vv.BaseRunId)
+
+	hMd5, digestAcc, _, err := digestAccumulatorsFrom(modelDef, table, doubleFmt)
+	if err != nil {
+		return nil, err
+	}
+
+	// SELECT acc_id, sub_id, dim0, dim1, acc_value FROM salarySex_a2012820 WHERE run_id = 1234 ORDER BY 1, 2, 3, 4
+	q := "SELECT acc_id, sub_id, "
+	for k := range table.Dim {
+		q += table.Dim[k].colName + ", "
+	}
+	q += "acc_value FROM " + table.DbAccTable + " WHERE run_id = " + sBaseId
+	q += makeOrderBy(table.Rank, nil, 2)
+
+	var n1, n2 int
+	d := make([]int, table.Rank)
+	var vf sql.NullFloat64
+
+	scanBuf := []interface{}{&n1, &n2}
+	for k := 0; k < table.Rank; k++ {
+		scanBuf = append(scanBuf, &d[k])
+	}
+	scanBuf = append(scanBuf, &vf)
+
+	err = SelectRows(dbConn, q,
+		func(rows *sql.Rows) error {
+
+			if e := rows.Scan(scanBuf...); e != nil {
+				return e
+			}
+			var c = CellAcc{cellIdValue: cellIdValue{DimIds: make([]int, table.Rank)}, AccId: n1, SubId: n2}
+			copy(c.DimIds, d)
+			c.IsNull = !vf.Valid
+			c.Value = 0.0
+			if !c.IsNull {
+				c.Value = vf.Float64
+			}
+			vv.RowCount++
+
+			return digestAcc(c)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	digestExpr, _, err := digestExpressionsFrom(modelDef, table, doubleFmt, hMd5)
+	if err != nil {
+		return nil, err
+	}
+
+	// SELECT expr_id, dim0, dim1, expr_value FROM salarySex_v2012820 WHERE run_id = 1234 ORDER BY 1, 2, 3
+	q = "SELECT expr_id, "
+	for k := range table.Dim {
+		q += table.Dim[k].colName + ", "
+	}
+	q += "expr_value FROM " + table.DbExprTable + " WHERE run_id = " + sBaseId
+	q += makeOrderBy(table.Rank, nil, 1)
+
+	scanBuf = []interface{}{&n1}
+	for k := 0; k < table.Rank; k++ {
+		scanBuf = append(scanBuf, &d[k])
+	}
+	scanBuf = append(scanBuf, &vf)
+
+	err = SelectRows(dbConn, q,
+		func(rows *sql.Rows) error {
+
+			if e := rows.Scan(scanBuf...); e != nil {
+				return e
+			}
+			var c = CellExpr{cellIdValue: cellIdValue{DimIds: make([]int, table.Rank)}, ExprId: n1}
+			copy(c.DimIds, d)
+			c.IsNull = !vf.Valid
+			c.Value = 0.0
+			if !c.IsNull {
+				c.Value = vf.Float64
+			}
+			vv.RowCount++
+
+			return digestExpr(c)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return hMd5, nil
+}
+
+// verifyMicrodataDigest calculate entity microdata digest from microdata values of base run.
+func verifyMicrodataDigest(
+	dbConn *sql.DB, modelDef *ModelMeta, entity *EntityMeta, entityGen *EntityGenMeta, vv *ValueVerify, doubleFmt string,
+) (hash.Hash, error) {
+
+	entityAttrs := make([]EntityAttrRow, len(entityGen.GenAttr))
+
+	for k, ga := range entityGen.GenAttr {
+
+		aIdx, ok := entity.AttrByKey(ga.AttrId)
+		if !ok {
+			return nil, errors.New("entity attribute id not found: " + strconv.Itoa(ga.AttrId) + " " + entity.Name)
+		}
+		entityAttrs[k] = entity.Attr[aIdx]
+	}
+
+	hMd5, digestFrom, err := digestMicrodataFrom(modelDef, entity.Name, entityGen, &vv.RowCount, doubleFmt)
+	if err != nil {
+		return nil, err
+	}
+
+	// SELECT entity_key, attr4, attr7 FROM Person_g87abcdef WHERE run_id = 1234 ORDER BY 1
+	q := "SELECT entity_key "
+	for _, ea := range entityAttrs {
+		q += ", " + ea.colName
+	}
+	q += " FROM " + entityGen.DbEntityTable + " WHERE run_id = " + strconv.Itoa(vv.BaseRunId) + " ORDER BY 1"
+
+	scanBuf, fc := scanSqlRowToCellMicro(entity, entityAttrs)
+
+	err = SelectRows(dbConn, q,
+		func(rows *sql.Rows) error {
+
+			if e := rows.Scan(scanBuf...); e != nil {
+				return e
+			}
+			c := CellMicro{Attr: make([]attrValue, len(entityAttrs))}
+			if e := fc(&c); e != nil {
+				return e
+			}
+			return digestFrom(c)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return hMd5, nil
+}
+
+// verifyOrphanRows return groups of value rows which are not referenced by any model run or workset:
+// parameter run values, workset values, output table accumulators and expressions and entity microdata.
+func verifyOrphanRows(dbConn *sql.DB, modelDef *ModelMeta) ([]OrphanVerify, error) {
+
+	oLst := []OrphanVerify{}
+
+	// select count of orphaned rows grouped by run id or set id
+	selectOrphan := func(kind, name, dbTable, q string) error {
+		return SelectRows(dbConn, q,
+			func(rows *sql.Rows) error {
+				ov := OrphanVerify{Kind: kind, Name: name, DbTable: dbTable}
+				if err := rows.Scan(&ov.Id, &ov.RowCount); err != nil {
+					return err
+				}
+				oLst = append(oLst, ov)
+				return nil
+			})
+	}
+
+	// parameter run values and workset values
+	for k := range modelDef.Param {
+
+		param := &modelDef.Param[k]
+		sHid := strconv.Itoa(param.ParamHid)
+
+		// SELECT V.run_id, COUNT(*) FROM ageSex_p2012_817 V
+		// WHERE NOT EXISTS (SELECT * FROM run_parameter R WHERE R.base_run_id = V.run_id AND R.parameter_hid = 1)
+		// GROUP BY V.run_id
+		// ORDER BY 1
+		err := selectOrphan("parameter", param.Name, param.DbRunTable,
+			"SELECT V.run_id, COUNT(*) FROM "+param.DbRunTable+" V"+
+				" WHERE NOT EXISTS"+
+				" (SELECT * FROM run_parameter R WHERE R.base_run_id = V.run_id AND R.parameter_hid = "+sHid+")"+
+				" GROUP BY V.run_id"+
+				" ORDER BY 1")
+		if err != nil {
+			return nil, err
+		}
+
+		err = selectOrphan("workset", param.Name, param.DbSetTable,
+			"SELECT V.set_id, COUNT(*) FROM "+param.DbSetTable+" V"+
+				" WHERE NOT EXISTS"+
+				" (SELECT * FROM workset_parameter W WHERE W.set_id = V.set_id AND W.parameter_hid = "+sHid+")"+
+				" GROUP BY V.set_id"+
+				" ORDER BY 1")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// output tables accumulators and expressions
+	for k := range modelDef.Table {
+
+		table := &modelDef.Table[k]
+		sHid := strconv.Itoa(table.TableHid)
+
+		err := selectOrphan("accumulator", table.Name, table.DbAccTable,
+			"SELECT V.run_id, COUNT(*) FROM "+table.DbAccTable+" V"+
+				" WHERE NOT EXISTS"+
+				" (SELECT * FROM run_table R WHERE R.base_run_id = V.run_id AND R.table_hid = "+sHid+")"+
+				" GROUP BY V.run_id"+
+				" ORDER BY 1")
+		if err != nil {
+			return nil, err
+		}
+
+		err = selectOrphan("expression", table.Name, table.DbExprTable,
+			"SELECT V.run_id, COUNT(*) FROM "+table.DbExprTable+" V"+
+				" WHERE NOT EXISTS"+
+				" (SELECT * FROM run_table R WHERE R.base_run_id = V.run_id AND R.table_hid = "+sHid+")"+
+				" GROUP BY V.run_id"+
+				" ORDER BY 1")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// entity microdata tables of all model entity generations
+	type genItem struct {
+		genHid int    // entity generation Hid
+		name   string // entity name
+		tbl    string // entity microdata db table
+	}
+	var genLst []genItem
+
+	err := SelectRows(dbConn,
+		"SELECT EG.entity_gen_hid, M.model_entity_id, EG.db_entity_table"+
+			" FROM entity_gen EG"+
+			" INNER JOIN model_entity_dic M ON (M.entity_hid = EG.entity_hid)"+
+			" WHERE M.model_id = "+strconv.Itoa(modelDef.Model.ModelId)+
+			" ORDER BY 1",
+		func(rows *sql.Rows) error {
+			var gi genItem
+			var eId int
+			if err := rows.Scan(&gi.genHid, &eId, &gi.tbl); err != nil {
+				return err
+			}
+			if idx, ok := modelDef.EntityByKey(eId); ok {
+				gi.name = modelDef.Entity[idx].Name
+			}
+			genLst = append(genLst, gi)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, gi := range genLst {
+
+		err := selectOrphan("microdata", gi.name, gi.tbl,
+			"SELECT V.run_id, COUNT(*) FROM "+gi.tbl+" V"+
+				" WHERE NOT EXISTS"+
+				" (SELECT * FROM run_entity R WHERE R.base_run_id = V.run_id AND R.entity_gen_hid = "+strconv.Itoa(gi.genHid)+")"+
+				" GROUP BY V.run_id"+
+				" ORDER BY 1")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return oLst, nil
+}
+
+// verifyMissingHid return model runs and worksets metadata rows which are reference to missing Hid's or to missing base run id.
+func verifyMissingHid(dbConn *sql.DB, modelId int) ([]MissingHidVerify, error) {
+
+	hLst := []MissingHidVerify{}
+	smId := strconv.Itoa(modelId)
+
+	// select run id or set id and missing Hid
+	selectMissing := func(dbTable, column, q string) error {
+		return SelectRows(dbConn, q,
+			func(rows *sql.Rows) error {
+				mh := MissingHidVerify{DbTable: dbTable, Column: column}
+				if err := rows.Scan(&mh.Id, &mh.Hid); err != nil {
+					return err
+				}
+				hLst = append(hLst, mh)
+				return nil
+			})
+	}
+
+	err := selectMissing("run_parameter", "parameter_hid",
+		"SELECT R.run_id, R.parameter_hid"+
+			" FROM run_parameter R"+
+			" INNER JOIN run_lst H ON (H.run_id = R.run_id)"+
+			" WHERE H.model_id = "+smId+
+			" AND NOT EXISTS"+
+			" (SELECT * FROM model_parameter_dic M WHERE M.model_id = H.model_id AND M.parameter_hid = R.parameter_hid)"+
+			" ORDER BY 1, 2")
+	if err != nil {
+		return nil, err
+	}
+
+	err = selectMissing("run_parameter", "base_run_id",
+		"SELECT R.run_id, R.base_run_id"+
+			" FROM run_parameter R"+
+			" INNER JOIN run_lst H ON (H.run_id = R.run_id)"+
+			" WHERE H.model_id = "+smId+
+			" AND NOT EXISTS (SELECT * FROM run_lst B WHERE B.run_id = R.base_run_id)"+
+			" ORDER BY 1, 2")
+	if err != nil {
+		return nil, err
+	}
+
+	err = selectMissing("run_table", "table_hid",
+		"SELECT R.run_id, R.table_hid"+
+			" FROM run_table R"+
+			" INNER JOIN run_lst H ON (H.run_id = R.run_id)"+
+			" WHERE H.model_id = "+smId+
+			" AND NOT EXISTS"+
+			" (SELECT * FROM model_table_dic M WHERE M.model_id = H.model_id AND M.table_hid = R.table_hid)"+
+			" ORDER BY 1, 2")
+	if err != nil {
+		return nil, err
+	}
+
+	err = selectMissing("run_table", "base_run_id",
+		"SELECT R.run_id, R.base_run_id"+
+			" FROM run_table R"+
+			" INNER JOIN run_lst H ON (H.run_id = R.run_id)"+
+			" WHERE H.model_id = "+smId+
+			" AND NOT EXISTS (SELECT * FROM run_lst B WHERE B.run_id = R.base_run_id)"+
+			" ORDER BY 1, 2")
+	if err != nil {
+		return nil, err
+	}
+
+	err = selectMissing("run_entity", "entity_gen_hid",
+		"SELECT R.run_id, R.entity_gen_hid"+
+			" FROM run_entity R"+
+			" INNER JOIN run_lst H ON (H.run_id = R.run_id)"+
+			" WHERE H.model_id = "+smId+
+			" AND NOT EXISTS (SELECT * FROM entity_gen EG WHERE EG.entity_gen_hid = R.entity_gen_hid)"+
+			" ORDER BY 1, 2")
+	if err != nil {
+		return nil, err
+	}
+
+	err = selectMissing("run_entity", "base_run_id",
+		"SELECT R.run_id, R.base_run_id"+
+			" FROM run_entity R"+
+			" INNER JOIN run_lst H ON (H.run_id = R.run_id)"+
+			" WHERE H.model_id = "+smId+
+			" AND NOT EXISTS (SELECT * FROM run_lst B WHERE B.run_id = R.base_run_id)"+
+			" ORDER BY 1, 2")
+	if err != nil {
+		return nil, err
+	}
+
+	err = selectMissing("workset_parameter", "parameter_hid",
+		"SELECT W.set_id, W.parameter_hid"+
+			" FROM workset_parameter W"+
+			" INNER JOIN workset_lst S ON (S.set_id = W.set_id)"+
+			" WHERE S.model_id = "+smId+
+			" AND NOT EXISTS"+
+			" (SELECT * FROM model_parameter_dic M WHERE M.model_id = S.model_id AND M.parameter_hid = W.parameter_hid)"+
+			" ORDER BY 1, 2")
+	if err != nil {
+		return nil, err
+	}
+
+	return hLst, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestVerifyModelRuns(t *testing.T) {
+
+	dbConn, modelDef, langDef := createTestModel(t)
+
+	// second run has the same parameter values as first run: parameter values stored in first run
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, modelDef, langDef, "r1", &v)
+	r2 := createTestRun(t, dbConn, modelDef, langDef, "r2", &v)
+	createTestWorkset(t, dbConn, modelDef, langDef, "s1", r1, &v)
+
+	// verify valid database
+	mv, err := VerifyModelRuns(dbConn.DB, modelDef, nil, "")
+	if err != nil {
+		t.Fatal("****FAIL: verify model runs:", err)
+	}
+	if !mv.IsValid || len(mv.Run) != 2 || len(mv.Orphan) != 0 || len(mv.MissingHid) != 0 {
+		t.Fatal("****FAIL: expected valid model, found:", mv)
+	}
+	for _, rv := range mv.Run {
+		if !rv.IsValid || len(rv.Param) != 2 || len(rv.Table) != 1 {
+			t.Error("****FAIL: expected valid run:", rv)
+		}
+		for _, vv := range rv.Param {
+			if vv.BaseRunId != r1 {
+				t.Error("****FAIL: expected parameter base run id:", r1, "found:", vv.BaseRunId, vv.Name, "run:", rv.RunId)
+			}
+		}
+	}
+	if vv := mv.Run[0].Table[0]; vv.Name != "incomeSex" || vv.RowCount != 6 {
+		t.Error("****FAIL: expected incomeSex 6 rows, found:", vv.Name, vv.RowCount)
+	}
+
+	// make invalid database:
+	// change parameter value of first run, it is also a parameter value of the second run
+	// insert orphaned parameter and accumulator rows of not existing run
+	// insert run_table row which is a reference to missing table Hid
+	pm := &modelDef.Param[0]
+	tm := &modelDef.Table[0]
+	missingHid := tm.TableHid + 1000
+	sqlLst := []string{
+		"UPDATE " + pm.DbRunTable + " SET param_value = 11 WHERE run_id = " + strconv.Itoa(r1) + " AND dim0 = 0",
+		"INSERT INTO " + pm.DbRunTable + " (run_id, sub_id, dim0, param_value) VALUES (9999, 0, 0, 1)",
+		"INSERT INTO " + pm.DbRunTable + " (run_id, sub_id, dim0, param_value) VALUES (9999, 0, 1, 2)",
+		"INSERT INTO " + tm.DbAccTable + " (run_id, acc_id, sub_id, dim0, acc_value) VALUES (9998, 0, 0, 0, 1)",
+		"INSERT INTO run_table (run_id, table_hid, base_run_id, value_digest) VALUES (" + strconv.Itoa(r2) + ", " + strconv.Itoa(missingHid) + ", " + strconv.Itoa(r2) + ", 'abc')",
+	}
+	for _, q := range sqlLst {
+		if _, err = dbConn.Exec(q); err != nil {
+			t.Fatal("****FAIL:", err, q)
+		}
+	}
+
+	mv, err = VerifyModelRuns(dbConn.DB, modelDef, nil, "")
+	if err != nil {
+		t.Fatal("****FAIL: verify model runs:", err)
+	}
+	if mv.IsValid {
+		t.Error("****FAIL: expected invalid model")
+	}
+
+	// both runs are invalid because parameter stored in first run is changed
+	for _, rv := range mv.Run {
+		if rv.IsValid {
+			t.Error("****FAIL: expected invalid run:", rv.RunId)
+		}
+		for _, vv := range rv.Param {
+			if vv.IsValid != (vv.Name != pm.Name) {
+				t.Error("****FAIL: invalid parameter verification result:", rv.RunId, vv)
+			}
+		}
+	}
+
+	// second run contains table row with missing Hid
+	isFound := false
+	for _, vv := range mv.Run[1].Table {
+		if vv.Hid == missingHid {
+			isFound = true
+			if vv.IsValid || vv.Msg == "" {
+				t.Error("****FAIL: expected output table not found message:", vv)
+			}
+		}
+	}
+	if !isFound {
+		t.Error("****FAIL: run table with missing Hid not found in run:", r2)
+	}
+
+	expectedOrphan := []OrphanVerify{
+		{Kind: "parameter", Name: pm.Name, DbTable: pm.DbRunTable, Id: 9999, RowCount: 2},
+		{Kind: "accumulator", Name: tm.Name, DbTable: tm.DbAccTable, Id: 9998, RowCount: 1},
+	}
+	if len(mv.Orphan) != len(expectedOrphan) {
+		t.Fatal("****FAIL: expected orphans:", expectedOrphan, "found:", mv.Orphan)
+	}
+	for k := range expectedOrphan {
+		if mv.Orphan[k] != expectedOrphan[k] {
+			t.Error("****FAIL: expected orphan:", expectedOrphan[k], "found:", mv.Orphan[k])
+		}
+	}
+
+	if len(mv.MissingHid) != 1 {
+		t.Fatal("****FAIL: expected one missing Hid, found:", mv.MissingHid)
+	}
+	if mh := mv.MissingHid[0]; mh.DbTable != "run_table" || mh.Column != "table_hid" || mh.Id != r2 || mh.Hid != missingHid {
+		t.Error("****FAIL: invalid missing Hid:", mh)
+	}
+
+	// verify only first run
+	rv, err := VerifyRun(dbConn.DB, modelDef, r1, "")
+	if err != nil {
+		t.Fatal("****FAIL: verify run:", err)
+	}
+	if rv.IsValid || rv.RunId != r1 || len(rv.Table) != 1 || !rv.Table[0].IsValid {
+		t.Error("****FAIL: invalid run verification result:", rv)
+	}
+
+	if _, err = VerifyRun(dbConn.DB, modelDef, 9999, ""); err == nil {
+		t.Error("****FAIL: expected error on verification of not existing run")
+	}
+}
@@ -0,0 +1,1177 @@
+// Copyright (c) 2021 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// WorksetParamHistory is a saved version of workset parameter: workset_param_history db row and parameter name.
+//
+// Version is saved before workset parameter update and contains parameter values as it was before update.
+// Only cells changed by the update are stored, parameter values of the version are current values
+// merged with cells stored in that version and all following versions of the parameter.
+// Version id is unique inside of the workset and increasing: versions of all workset parameters are numbered together.
+type WorksetParamHistory struct {
+	SetId          int    // set_id         INT          NOT NULL, -- workset id
+	VersionId      int    // version_id     INT          NOT NULL, -- version id, unique inside of the workset
+	ParamHid       int    // parameter_hid  INT          NOT NULL, -- parameter unique id
+	Name           string // parameter name
+	SubCount       int    // sub_count      INT          NOT NULL, -- sub-values count, zero if parameter was not in workset
+	DefaultSubId   int    // default_sub_id INT          NOT NULL, -- default sub-value id
+	UserName       string // user_name      VARCHAR(255) NOT NULL, -- user who did parameter update
+	Action         string // action_name    VARCHAR(32)  NOT NULL, -- update action, ex.: page, csv, copy-run, delete, restore
+	UpdateDateTime string // update_dt      VARCHAR(32)  NOT NULL, -- date-time when version saved
+}
+
+// ParamHistoryAction is a user name and update action to save workset parameter version in history.
+//
+// It is the same as WorksetUpdateAction and kept for compatibility.
+type ParamHistoryAction = WorksetUpdateAction
+
+// WorksetParamCellDiff is a difference of parameter cell between two versions of workset parameter.
+//
+// Old and New values are CellParam or CellCodeParam if cell converted to enum codes.
+type WorksetParamCellDiff struct {
+	Kind string      // change kind: insert, delete or update
+	Old  interface{} // cell value in older version, nil if cell inserted
+	New  interface{} // cell value in newer version, nil if cell deleted
+}
+
+// db table name of workset history versions
+const worksetHistoryTable = "workset_param_history"
+
+// return db table name of parameter history values, ex.: ageSex_h2012_817
+func paramHistoryTable(param *ParamMeta) string {
+	p, s := makeDbTablePrefixSuffix(param.Name, param.Digest)
+	return p + "_h" + s
+}
+
+// return true if db table exists: select from that table does not return an error
+func isDbTableExist(dbConn *sql.DB, tableName string) bool {
+	err := SelectFirst(dbConn,
+		"SELECT 1 FROM "+tableName+" WHERE 1 = 0",
+		func(row *sql.Row) error {
+			var n int
+			return row.Scan(&n)
+		})
+	return err == nil || err == sql.ErrNoRows
+}
+
+// sqlCreateWorksetHistoryTable return create table for workset history versions:
+//
+// CREATE TABLE workset_param_history
+// (
+// set_id         INT          NOT NULL,
+// version_id     INT          NOT NULL,
+// parameter_hid  INT          NOT NULL,
+// sub_count      INT          NOT NULL,
+// default_sub_id INT          NOT NULL,
+// user_name      VARCHAR(255) NOT NULL,
+// action_name    VARCHAR(32)  NOT NULL,
+// update_dt      VARCHAR(32)  NOT NULL,
+// PRIMARY KEY (set_id, version_id)
+// )
+func sqlCreateWorksetHistoryTable(dbFacet Facet) string {
+
+	return dbFacet.createTableIfNotExist(worksetHistoryTable, "("+
+		"set_id INT NOT NULL, "+
+		"version_id INT NOT NULL, "+
+		"parameter_hid INT NOT NULL, "+
+		"sub_count INT NOT NULL, "+
+		"default_sub_id INT NOT NULL, "+
+		"user_name VARCHAR(255) NOT NULL, "+
+		"action_name VARCHAR(32) NOT NULL, "+
+		"update_dt VARCHAR(32) NOT NULL, "+
+		"PRIMARY KEY (set_id, version_id)"+
+		")")
+}
+
+// sqlCreateParamHistoryTable return create table for parameter history values:
+//
+// CREATE TABLE ageSex_h2012_817
+// (
+// set_id      INT      NOT NULL,
+// version_id  INT      NOT NULL,
+// sub_id      SMALLINT NOT NULL,
+// dim0        INT      NOT NULL,
+// dim1        INT      NOT NULL,
+// param_value FLOAT    NULL,
+// is_absent   SMALLINT NOT NULL,
+// PRIMARY KEY (set_id, version_id, sub_id, dim0, dim1)
+// )
+//
+// Row is a cell value before update, if is_absent is not zero then cell did not exist before update, it was inserted.
+func sqlCreateParamHistoryTable(dbFacet Facet, param *ParamMeta) (string, error) {
+
+	tname, err := param.typeOf.sqlColumnType(dbFacet)
+	if err != nil {
+		return "", err
+	}
+	colPart := ""
+	keyPart := ""
+	for k := range param.Dim {
+		colPart += param.Dim[k].colName + " INT NOT NULL, "
+		keyPart += ", " + param.Dim[k].colName
+	}
+
+	return dbFacet.createTableIfNotExist(paramHistoryTable(param), "("+
+		"set_id INT NOT NULL, "+
+		"version_id INT NOT NULL, "+
+		"sub_id SMALLINT NOT NULL, "+
+		colPart+
+		"param_value "+tname+" NULL, "+
+		"is_absent SMALLINT NOT NULL, "+
+		"PRIMARY KEY (set_id, version_id, sub_id"+keyPart+")"+
+		")"), nil
+}
+
+// UpgradeWorksetHistory create workset history db tables if not exist: workset_param_history and history tables of model parameters.
+//
+// History tables are created together with model parameter tables when model inserted into database.
+// Database created before workset history was introduced does not have history tables and must be upgraded,
+// for example by: dbcopy -m modelOne -dbcopy.UpgradeHistory
+// Until database upgraded workset parameters updates are not saved in history.
+// If all history tables already exist then it is empty operation and database is not updated.
+func UpgradeWorksetHistory(dbConn Dbc, modelDef *ModelMeta) error {
+
+	// validate parameters
+	if modelDef == nil {
+		return errors.New("invalid (empty) model metadata")
+	}
+
+	// make list of missing history tables
+	qLst := []string{}
+	if !isDbTableExist(dbConn.DB, worksetHistoryTable) {
+		qLst = append(qLst, sqlCreateWorksetHistoryTable(dbConn.Dbf))
+	}
+	for k := range modelDef.Param {
+
+		if isDbTableExist(dbConn.DB, paramHistoryTable(&modelDef.Param[k])) {
+			continue
+		}
+		q, err := sqlCreateParamHistoryTable(dbConn.Dbf, &modelDef.Param[k])
+		if err != nil {
+			return err
+		}
+		qLst = append(qLst, q)
+	}
+	if len(qLst) <= 0 {
+		return nil // all history tables already exist
+	}
+
+	// create history tables in transaction scope
+	trx, err := dbConn.Begin()
+	if err != nil {
+		return err
+	}
+	for _, q := range qLst {
+		if err = TrxUpdate(trx, q); err != nil {
+			trx.Rollback()
+			return err
+		}
+	}
+	trx.Commit()
+	return nil
+}
+
+// historyCheck return copy of update action with empty action name if workset history db tables not exist.
+// Empty action name means workset history is not saved, all other update action properties are unchanged.
+// It must be called before update transaction started because it is using select from tables which may not exist.
+func (upd *WorksetUpdateAction) historyCheck(dbConn *sql.DB, paramLst ...*ParamMeta) *WorksetUpdateAction {
+
+	if upd == nil || upd.Action == "" {
+		return upd // workset history not required
+	}
+
+	isExist := isDbTableExist(dbConn, worksetHistoryTable)
+	for k := 0; isExist && k < len(paramLst); k++ {
+		isExist = isDbTableExist(dbConn, paramHistoryTable(paramLst[k]))
+	}
+	if isExist {
+		return upd
+	}
+
+	u := *upd
+	u.Action = ""
+	return &u
+}
+
+// return true if workset history table exist and list of history db tables of workset parameters which has history values.
+// It must be called before delete transaction started because it is using select from tables which may not exist.
+func worksetHistoryTables(dbConn *sql.DB, setId int) (bool, []string, error) {
+
+	if !isDbTableExist(dbConn, worksetHistoryTable) {
+		return false, []string{}, nil // no workset history in database
+	}
+
+	tLst := []string{}
+	err := SelectRows(dbConn,
+		"SELECT DISTINCT P.parameter_name, P.parameter_digest"+
+			" FROM "+worksetHistoryTable+" H"+
+			" INNER JOIN parameter_dic P ON (P.parameter_hid = H.parameter_hid)"+
+			" WHERE H.set_id = "+strconv.Itoa(setId),
+		func(rows *sql.Rows) error {
+			var pm ParamMeta
+			if err := rows.Scan(&pm.Name, &pm.Digest); err != nil {
+				return err
+			}
+			tLst = append(tLst, paramHistoryTable(&pm))
+			return nil
+		})
+	if err != nil {
+		return false, nil, err
+	}
+	return true, tLst, nil
+}
+
+// trxDeleteWorksetHistory delete all versions of workset parameters.
+// It does update as part of transaction.
+func trxDeleteWorksetHistory(trx *sql.Tx, setId int, isHistory bool, tblLst []string) error {
+
+	if !isHistory {
+		return nil // no workset history in database
+	}
+	sId := strconv.Itoa(setId)
+
+	for _, t := range tblLst {
+		if err := TrxUpdate(trx, "DELETE FROM "+t+" WHERE set_id = "+sId); err != nil {
+			return err
+		}
+	}
+	return TrxUpdate(trx, "DELETE FROM "+worksetHistoryTable+" WHERE set_id = "+sId)
+}
+
+// return workset id and read-only status by model id and workset name, return zero set id if workset not found
+func trxWorksetIdByName(trx *sql.Tx, modelId int, setName string) (int, bool, error) {
+
+	setId := 0
+	nRd := 0
+	err := TrxSelectFirst(trx,
+		"SELECT set_id, is_readonly FROM workset_lst"+
+			" WHERE model_id = "+strconv.Itoa(modelId)+" AND set_name = "+ToQuoted(setName),
+		func(row *sql.Row) error {
+			if err := row.Scan(&setId, &nRd); err != nil {
+				return err
+			}
+			return nil
+		})
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, false, nil // workset not found
+	case err != nil:
+		return 0, false, err
+	}
+	return setId, nRd != 0, nil
+}
+
+// trxSaveParamHistory insert new version into workset_param_history and copy current parameter values into history table.
+// It does update as part of transaction, workset history tables must already exist.
+// It must be called after workset "locked" by update of workset_lst.is_readonly, that lock does serialize new version id.
+// After parameter values updated trxTrimParamHistory() must be called to keep in history only cells changed by update.
+// If update action is nil or action name is empty then nothing saved and return is zero version id.
+// Return new version id.
+func trxSaveParamHistory(trx *sql.Tx, setId int, param *ParamMeta, upd *WorksetUpdateAction) (int, error) {
+
+	if upd == nil || upd.Action == "" {
+		return 0, nil // workset history not required
+	}
+	sId := strconv.Itoa(setId)
+	sHid := strconv.Itoa(param.ParamHid)
+
+	// get parameter sub-value count, it is zero if parameter not in workset
+	nSub := 0
+	defSubId := 0
+	err := TrxSelectFirst(trx,
+		"SELECT sub_count, default_sub_id FROM workset_parameter WHERE set_id = "+sId+" AND parameter_hid = "+sHid,
+		func(row *sql.Row) error {
+			if err := row.Scan(&nSub, &defSubId); err != nil {
+				return err
+			}
+			return nil
+		})
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	// new version id: max of existing workset versions + 1
+	verId := 0
+	err = TrxSelectFirst(trx,
+		"SELECT COALESCE(MAX(version_id), 0) + 1 FROM "+worksetHistoryTable+" WHERE set_id = "+sId,
+		func(row *sql.Row) error {
+			if err := row.Scan(&verId); err != nil {
+				return err
+			}
+			return nil
+		})
+	if err != nil {
+		return 0, err
+	}
+	sVer := strconv.Itoa(verId)
+
+	// insert version row and copy parameter values into history
+	err = TrxUpdate(trx,
+		"INSERT INTO "+worksetHistoryTable+
+			" (set_id, version_id, parameter_hid, sub_count, default_sub_id, user_name, action_name, update_dt)"+
+			" VALUES ("+
+			sId+", "+
+			sVer+", "+
+			sHid+", "+
+			strconv.Itoa(nSub)+", "+
+			strconv.Itoa(defSubId)+", "+
+			toQuotedMax(upd.UserName, 255)+", "+
+			toQuotedMax(upd.Action, 32)+", "+
+			ToQuoted(helper.MakeDateTime(time.Now()))+")")
+	if err != nil {
+		return 0, err
+	}
+
+	if nSub > 0 {
+
+		dimCols := ""
+		for k := range param.Dim {
+			dimCols += param.Dim[k].colName + ", "
+		}
+		err = TrxUpdate(trx,
+			"INSERT INTO "+paramHistoryTable(param)+
+				" (set_id, version_id, sub_id, "+dimCols+"param_value, is_absent)"+
+				" SELECT set_id, "+sVer+", sub_id, "+dimCols+"param_value, 0"+
+				" FROM "+param.DbSetTable+
+				" WHERE set_id = "+sId)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return verId, nil
+}
+
+// trxTrimParamHistory keep in parameter version only cells changed by update, it must be called after parameter values updated.
+// Cells which are the same in version and in current values are deleted from version.
+// Cells inserted by update are stored in version as absent cells: it is not exist in that version.
+// It does update as part of transaction. If version id is zero then nothing is done: history is not saved.
+func trxTrimParamHistory(trx *sql.Tx, setId int, param *ParamMeta, versionId int) error {
+
+	if versionId <= 0 {
+		return nil // workset history not saved
+	}
+	sId := strconv.Itoa(setId)
+	sVer := strconv.Itoa(versionId)
+	hTbl := paramHistoryTable(param)
+
+	dimCols := ""
+	for k := range param.Dim {
+		dimCols += param.Dim[k].colName + ", "
+	}
+
+	// INSERT INTO ageSex_h2012_817 (set_id, version_id, sub_id, dim0, dim1, param_value, is_absent)
+	// SELECT S.set_id, 3, S.sub_id, S.dim0, S.dim1, S.param_value, 1
+	// FROM ageSex_w2012_817 S
+	// WHERE S.set_id = 22
+	// AND NOT EXISTS
+	// (
+	//   SELECT * FROM ageSex_h2012_817 H
+	//   WHERE H.set_id = S.set_id AND H.version_id = 3 AND H.sub_id = S.sub_id AND H.dim0 = S.dim0 AND H.dim1 = S.dim1
+	// )
+	err := TrxUpdate(trx,
+		"INSERT INTO "+hTbl+
+			" (set_id, version_id, sub_id, "+dimCols+"param_value, is_absent)"+
+			" SELECT S.set_id, "+sVer+", S.sub_id, "+paramHistoryCols("S", param)+"S.param_value, 1"+
+			" FROM "+param.DbSetTable+" S"+
+			" WHERE S.set_id = "+sId+
+			" AND NOT EXISTS"+
+			" (SELECT * FROM "+hTbl+" H"+
+			" WHERE H.set_id = S.set_id AND H.version_id = "+sVer+" AND "+paramHistoryKeyJoin("H", "S", param)+")")
+	if err != nil {
+		return err
+	}
+
+	// DELETE FROM ageSex_h2012_817
+	// WHERE set_id = 22 AND version_id = 3 AND is_absent = 0
+	// AND EXISTS
+	// (
+	//   SELECT * FROM ageSex_w2012_817 S
+	//   WHERE S.set_id = ageSex_h2012_817.set_id AND S.sub_id = ageSex_h2012_817.sub_id AND S.dim0 = ... AND S.dim1 = ...
+	//   AND (S.param_value = ageSex_h2012_817.param_value OR S.param_value IS NULL AND ageSex_h2012_817.param_value IS NULL)
+	// )
+	return TrxUpdate(trx,
+		"DELETE FROM "+hTbl+
+			" WHERE set_id = "+sId+" AND version_id = "+sVer+" AND is_absent = 0"+
+			" AND EXISTS"+
+			" (SELECT * FROM "+param.DbSetTable+" S"+
+			" WHERE S.set_id = "+hTbl+".set_id AND "+paramHistoryKeyJoin("S", hTbl, param)+
+			" AND (S.param_value = "+hTbl+".param_value OR S.param_value IS NULL AND "+hTbl+".param_value IS NULL))")
+}
+
+// return parameter dimension columns with table alias prefix, ex.: S.dim0, S.dim1,
+func paramHistoryCols(alias string, param *ParamMeta) string {
+	cols := ""
+	for k := range param.Dim {
+		cols += alias + "." + param.Dim[k].colName + ", "
+	}
+	return cols
+}
+
+// return join condition of parameter cells by sub-value id and dimensions, ex.: H.sub_id = S.sub_id AND H.dim0 = S.dim0
+func paramHistoryKeyJoin(left, right string, param *ParamMeta) string {
+	q := left + ".sub_id = " + right + ".sub_id"
+	for k := range param.Dim {
+		q += " AND " + left + "." + param.Dim[k].colName + " = " + right + "." + param.Dim[k].colName
+	}
+	return q
+}
+
+// return sql to select cells of parameter version stored in history.
+// Each cell is selected from the first version where it is stored, starting from version id and up to max version id.
+// Cells which are not stored in any of those versions are not changed since that version.
+// If max version id is zero then there is no upper limit.
+//
+//	SELECT H.set_id, H.sub_id, H.dim0, H.dim1, H.param_value, H.is_absent
+//	FROM ageSex_h2012_817 H
+//	WHERE H.set_id = 22
+//	AND H.version_id =
+//	(
+//	  SELECT MIN(X.version_id) FROM ageSex_h2012_817 X
+//	  WHERE X.set_id = H.set_id AND X.version_id >= 3 AND X.sub_id = H.sub_id AND X.dim0 = H.dim0 AND X.dim1 = H.dim1
+//	)
+func paramHistoryVersionSql(setId int, param *ParamMeta, versionId, maxVersionId int) string {
+
+	hTbl := paramHistoryTable(param)
+
+	q := "SELECT H.set_id, H.sub_id, " + paramHistoryCols("H", param) + "H.param_value, H.is_absent" +
+		" FROM " + hTbl + " H" +
+		" WHERE H.set_id = " + strconv.Itoa(setId) +
+		" AND H.version_id =" +
+		" (SELECT MIN(X.version_id) FROM " + hTbl + " X" +
+		" WHERE X.set_id = H.set_id AND X.version_id >= " + strconv.Itoa(versionId)
+	if maxVersionId > 0 {
+		q += " AND X.version_id <= " + strconv.Itoa(maxVersionId)
+	}
+	q += " AND " + paramHistoryKeyJoin("X", "H", param) + ")"
+	return q
+}
+
+// GetWorksetHistoryList return list of saved versions of workset parameters, ordered by version id.
+// If parameter name is empty then versions of all workset parameters returned.
+// If there is no history saved for that workset then return is empty list.
+func GetWorksetHistoryList(dbConn *sql.DB, modelDef *ModelMeta, setName, paramName string) ([]WorksetParamHistory, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+	if !isDbTableExist(dbConn, worksetHistoryTable) {
+		return []WorksetParamHistory{}, nil // no history saved yet
+	}
+
+	q := "SELECT H.set_id, H.version_id, H.parameter_hid, H.sub_count, H.default_sub_id, H.user_name, H.action_name, H.update_dt" +
+		" FROM " + worksetHistoryTable + " H" +
+		" INNER JOIN workset_lst W ON (W.set_id = H.set_id)" +
+		" WHERE W.model_id = " + strconv.Itoa(modelDef.Model.ModelId) +
+		" AND W.set_name = " + ToQuoted(setName)
+
+	if paramName != "" {
+		k, ok := modelDef.ParamByName(paramName)
+		if !ok {
+			return nil, errors.New("parameter not found: " + paramName)
+		}
+		q += " AND H.parameter_hid = " + strconv.Itoa(modelDef.Param[k].ParamHid)
+	}
+	q += " ORDER BY 1, 2"
+
+	hLst := []WorksetParamHistory{}
+
+	err := SelectRows(dbConn, q,
+		func(rows *sql.Rows) error {
+			var h WorksetParamHistory
+			if err := rows.Scan(
+				&h.SetId, &h.VersionId, &h.ParamHid, &h.SubCount, &h.DefaultSubId, &h.UserName, &h.Action, &h.UpdateDateTime); err != nil {
+				return err
+			}
+			if k, ok := modelDef.ParamByHid(h.ParamHid); ok {
+				h.Name = modelDef.Param[k].Name
+			}
+			hLst = append(hLst, h)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return hLst, nil
+}
+
+// ReadWorksetHistoryTo read parameter values (sub id, dimensions, value) of workset parameter version and process each row by cvtTo().
+// If version id is zero then current workset parameter values are read.
+func ReadWorksetHistoryTo(dbConn *sql.DB, modelDef *ModelMeta, setName, paramName string, versionId int, cvtTo func(src interface{}) (bool, error)) error {
+
+	// validate parameters
+	if modelDef == nil {
+		return errors.New("invalid (empty) model metadata")
+	}
+	if setName == "" {
+		return errors.New("invalid (empty) workset name")
+	}
+	if versionId < 0 {
+		return errors.New("invalid workset parameter version id: " + strconv.Itoa(versionId))
+	}
+
+	var param *ParamMeta
+	if k, ok := modelDef.ParamByName(paramName); ok {
+		param = &modelDef.Param[k]
+	} else {
+		return errors.New("parameter not found: " + paramName)
+	}
+
+	q, err := worksetHistorySql(dbConn, modelDef, setName, param, versionId)
+	if err != nil {
+		return err
+	}
+
+	// select parameter cells: (sub id, dimension(s) enum ids, parameter value)
+	scanBuf, fc := scanSqlRowToCellParam(param)
+
+	err = SelectRowsTo(dbConn, q,
+		func(rows *sql.Rows) (bool, error) {
+
+			if e := rows.Scan(scanBuf...); e != nil {
+				return false, e
+			}
+
+			var c = CellParam{cellIdValue: cellIdValue{DimIds: make([]int, param.Rank)}}
+			if e := fc(&c); e != nil {
+				return false, e
+			}
+
+			return cvtTo(c) // process cell
+		})
+	return err
+}
+
+// return sql to select parameter values of workset parameter version, if version id is zero then select current values:
+//
+//	SELECT sub_id, dim0, dim1, param_value FROM ageSex_w2012_817 WHERE set_id = 22 ORDER BY 1, 2, 3
+//
+// Version values are cells stored in history in that version or following versions merged with current values:
+//
+//	SELECT sub_id, dim0, dim1, param_value FROM
+//	(
+//	  SELECT H.set_id, H.sub_id, H.dim0, H.dim1, H.param_value, H.is_absent FROM ageSex_h2012_817 H WHERE ...first version >= 3...
+//	  UNION ALL
+//	  SELECT S.set_id, S.sub_id, S.dim0, S.dim1, S.param_value, 0 FROM ageSex_w2012_817 S WHERE ...not in any version >= 3...
+//	) V
+//	WHERE V.is_absent = 0
+//	ORDER BY 1, 2, 3
+func worksetHistorySql(dbConn *sql.DB, modelDef *ModelMeta, setName string, param *ParamMeta, versionId int) (string, error) {
+
+	// find workset id by name
+	setId := 0
+	err := SelectFirst(dbConn,
+		"SELECT set_id FROM workset_lst"+
+			" WHERE model_id = "+strconv.Itoa(modelDef.Model.ModelId)+" AND set_name = "+ToQuoted(setName),
+		func(row *sql.Row) error {
+			return row.Scan(&setId)
+		})
+	switch {
+	case err == sql.ErrNoRows:
+		return "", errors.New("workset not found: " + setName)
+	case err != nil:
+		return "", err
+	}
+	sId := strconv.Itoa(setId)
+
+	q := "SELECT sub_id, "
+	for k := range param.Dim {
+		q += param.Dim[k].colName + ", "
+	}
+	if versionId <= 0 {
+		q += "param_value FROM " + param.DbSetTable + " WHERE set_id = " + sId
+	} else {
+
+		// version must exist and must be a version of that parameter
+		if !isDbTableExist(dbConn, worksetHistoryTable) {
+			return "", errors.New("workset parameter version not found: " + setName + ": " + param.Name + ": " + strconv.Itoa(versionId))
+		}
+		hid := 0
+		err = SelectFirst(dbConn,
+			"SELECT parameter_hid FROM "+worksetHistoryTable+" WHERE set_id = "+sId+" AND version_id = "+strconv.Itoa(versionId),
+			func(row *sql.Row) error {
+				return row.Scan(&hid)
+			})
+		switch {
+		case err == sql.ErrNoRows || err == nil && hid != param.ParamHid:
+			return "", errors.New("workset parameter version not found: " + setName + ": " + param.Name + ": " + strconv.Itoa(versionId))
+		case err != nil:
+			return "", err
+		}
+
+		// version values: cells stored in history and current values of cells not changed since that version
+		q += "param_value FROM" +
+			" (" +
+			paramHistoryVersionSql(setId, param, versionId, 0) +
+			" UNION ALL" +
+			" SELECT S.set_id, S.sub_id, " + paramHistoryCols("S", param) + "S.param_value, 0" +
+			" FROM " + param.DbSetTable + " S" +
+			" WHERE S.set_id = " + sId +
+			" AND NOT EXISTS" +
+			" (SELECT * FROM " + paramHistoryTable(param) + " X" +
+			" WHERE X.set_id = S.set_id AND X.version_id >= " + strconv.Itoa(versionId) + " AND " + paramHistoryKeyJoin("X", "S", param) + ")" +
+			" ) V" +
+			" WHERE V.is_absent = 0"
+	}
+
+	q += " ORDER BY 1"
+	for k := range param.Dim {
+		q += ", " + strconv.Itoa(k+2)
+	}
+	return q, nil
+}
+
+// DiffWorksetHistory compare two versions of workset parameter and return list of inserted, deleted and updated cells.
+// If version id is zero then current workset parameter values are used.
+// Cells are compared by sub-value id and dimension items, result is ordered by sub-value id and dimension items.
+func DiffWorksetHistory(dbConn *sql.DB, modelDef *ModelMeta, setName, paramName string, fromVersionId, toVersionId int) ([]WorksetParamCellDiff, error) {
+
+	// read both versions into the lists
+	readCells := func(verId int) ([]CellParam, error) {
+		cLst := []CellParam{}
+		err := ReadWorksetHistoryTo(dbConn, modelDef, setName, paramName, verId,
+			func(src interface{}) (bool, error) {
+				cLst = append(cLst, src.(CellParam))
+				return true, nil
+			})
+		return cLst, err
+	}
+	fromLst, err := readCells(fromVersionId)
+	if err != nil {
+		return nil, err
+	}
+	toLst, err := readCells(toVersionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffParamCells(fromLst, toLst), nil
+}
+
+// diffParamCells compare two lists of parameter cells and return list of inserted, deleted and updated cells.
+// Cells are matched by sub-value id and dimension items.
+func diffParamCells(fromLst, toLst []CellParam) []WorksetParamCellDiff {
+
+	// make a key of the cell: sub id and dimension items
+	cellKey := func(c *CellParam) string {
+		k := strconv.Itoa(c.SubId)
+		for _, d := range c.DimIds {
+			k += "," + strconv.Itoa(d)
+		}
+		return k
+	}
+	isLess := func(a, b *CellParam) bool {
+		if a.SubId != b.SubId {
+			return a.SubId < b.SubId
+		}
+		for k := 0; k < len(a.DimIds) && k < len(b.DimIds); k++ {
+			if a.DimIds[k] != b.DimIds[k] {
+				return a.DimIds[k] < b.DimIds[k]
+			}
+		}
+		return false
+	}
+
+	toIdx := make(map[string]int, len(toLst))
+	for k := range toLst {
+		toIdx[cellKey(&toLst[k])] = k
+	}
+
+	dLst := []WorksetParamCellDiff{}
+	isFound := make([]bool, len(toLst))
+
+	for k := range fromLst {
+
+		j, ok := toIdx[cellKey(&fromLst[k])]
+		if !ok {
+			dLst = append(dLst, WorksetParamCellDiff{Kind: "delete", Old: fromLst[k]})
+			continue
+		}
+		isFound[j] = true
+
+		if fromLst[k].IsNull != toLst[j].IsNull || !fromLst[k].IsNull && fromLst[k].Value != toLst[j].Value {
+			dLst = append(dLst, WorksetParamCellDiff{Kind: "update", Old: fromLst[k], New: toLst[j]})
+		}
+	}
+	for j := range toLst {
+		if !isFound[j] {
+			dLst = append(dLst, WorksetParamCellDiff{Kind: "insert", New: toLst[j]})
+		}
+	}
+
+	// order by cell sub-value id and dimension items
+	sort.SliceStable(dLst, func(i, j int) bool {
+		a, ok := dLst[i].Old.(CellParam)
+		if !ok {
+			a = dLst[i].New.(CellParam)
+		}
+		b, ok := dLst[j].Old.(CellParam)
+		if !ok {
+			b = dLst[j].New.(CellParam)
+		}
+		return isLess(&a, &b)
+	})
+	return dLst
+}
+
+// RestoreWorksetParamHistory restore workset parameter values from saved version.
+//
+// Current parameter values saved in history before restore, so restore can be undone.
+// If parameter was not in workset at that version then parameter deleted from workset.
+// Workset must be read-write. Return version id of saved current parameter values.
+// Restore is saved in history as "restore" action of userName.
+func RestoreWorksetParamHistory(dbConn Dbc, modelDef *ModelMeta, setName, paramName string, versionId int, userName string) (int, error) {
+	return RestoreWorksetParamHistoryChecked(dbConn, modelDef, setName, paramName, versionId, &WorksetUpdateAction{UserName: userName})
+}
+
+// RestoreWorksetParamHistoryChecked restore workset parameter values from saved version.
+// Restore is saved in history as "restore" action of update action user.
+// If update action has expected update date-time then it is checked in the same transaction.
+func RestoreWorksetParamHistoryChecked(dbConn Dbc, modelDef *ModelMeta, setName, paramName string, versionId int, upd *WorksetUpdateAction) (int, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return 0, errors.New("invalid (empty) model metadata")
+	}
+	if setName == "" {
+		return 0, errors.New("invalid (empty) workset name")
+	}
+	if versionId <= 0 {
+		return 0, errors.New("invalid workset parameter version id: " + strconv.Itoa(versionId))
+	}
+
+	hLst, err := GetWorksetHistoryList(dbConn.DB, modelDef, setName, paramName)
+	if err != nil {
+		return 0, err
+	}
+	var hv *WorksetParamHistory
+	for k := range hLst {
+		if hLst[k].VersionId == versionId {
+			hv = &hLst[k]
+			break
+		}
+	}
+	if hv == nil {
+		return 0, errors.New("workset parameter version not found: " + setName + ": " + paramName + ": " + strconv.Itoa(versionId))
+	}
+
+	nv, err := doRestoreWorksetHistory(dbConn, modelDef, setName, []WorksetParamHistory{*hv}, upd)
+	if err != nil {
+		return 0, err
+	}
+	return nv, nil
+}
+
+// RestoreWorksetHistory restore all workset parameters to the state as it was at the moment when version saved.
+//
+// Each parameter updated after that moment is restored from its first version saved after that moment, including that version.
+// Current values of restored parameters are saved in history before restore, so restore can be undone.
+// Workset must be read-write. Return the last version id of saved current parameter values or zero if nothing restored.
+// Restore is saved in history as "restore" action of userName.
+func RestoreWorksetHistory(dbConn Dbc, modelDef *ModelMeta, setName string, versionId int, userName string) (int, error) {
+	return RestoreWorksetHistoryChecked(dbConn, modelDef, setName, versionId, &WorksetUpdateAction{UserName: userName})
+}
+
+// RestoreWorksetHistoryChecked restore all workset parameters to the state as it was at the moment when version saved.
+// Restore is saved in history as "restore" action of update action user.
+// If update action has expected update date-time then it is checked in the same transaction.
+func RestoreWorksetHistoryChecked(dbConn Dbc, modelDef *ModelMeta, setName string, versionId int, upd *WorksetUpdateAction) (int, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return 0, errors.New("invalid (empty) model metadata")
+	}
+	if setName == "" {
+		return 0, errors.New("invalid (empty) workset name")
+	}
+	if versionId <= 0 {
+		return 0, errors.New("invalid workset version id: " + strconv.Itoa(versionId))
+	}
+
+	hLst, err := GetWorksetHistoryList(dbConn.DB, modelDef, setName, "")
+	if err != nil {
+		return 0, err
+	}
+
+	// for each parameter find first version starting from restore version
+	isFound := false
+	isParam := map[int]bool{}
+	rLst := []WorksetParamHistory{}
+
+	for k := range hLst {
+		if hLst[k].VersionId < versionId {
+			continue
+		}
+		isFound = isFound || hLst[k].VersionId == versionId
+		if !isParam[hLst[k].ParamHid] {
+			isParam[hLst[k].ParamHid] = true
+			rLst = append(rLst, hLst[k])
+		}
+	}
+	if !isFound {
+		return 0, errors.New("workset version not found: " + setName + ": " + strconv.Itoa(versionId))
+	}
+
+	return doRestoreWorksetHistory(dbConn, modelDef, setName, rLst, upd)
+}
+
+// restore workset parameters from saved versions in transaction scope.
+// Current values of each parameter saved as new version before restore.
+// Return the last version id of saved current parameter values.
+func doRestoreWorksetHistory(dbConn Dbc, modelDef *ModelMeta, setName string, hLst []WorksetParamHistory, upd *WorksetUpdateAction) (int, error) {
+
+	// find parameters
+	pLst := make([]*ParamMeta, len(hLst))
+
+	for k := range hLst {
+		j, ok := modelDef.ParamByHid(hLst[k].ParamHid)
+		if !ok {
+			return 0, errors.New("parameter not found, id: " + strconv.Itoa(hLst[k].ParamHid))
+		}
+		pLst[k] = &modelDef.Param[j]
+	}
+
+	// do restore in transaction scope
+	trx, err := dbConn.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	setId, isReadonly, err := trxWorksetIdByName(trx, modelDef.Model.ModelId, setName)
+	switch {
+	case err != nil:
+		trx.Rollback()
+		return 0, err
+	case setId <= 0:
+		trx.Rollback()
+		return 0, errors.New("workset not found: " + setName)
+	case isReadonly:
+		trx.Rollback()
+		return 0, errors.New("failed to restore: workset is read-only: " + setName)
+	}
+	if err = trxCheckWorksetUpdate(trx, setId, upd); err != nil {
+		trx.Rollback()
+		return 0, err
+	}
+
+	ra := &WorksetUpdateAction{Action: "restore"}
+	if upd != nil {
+		ra.UserName = upd.UserName
+	}
+	nv := 0
+	for k := range hLst {
+
+		nv, err = trxRestoreParamHistory(trx, setId, pLst[k], &hLst[k], ra)
+		if err != nil {
+			trx.Rollback()
+			return 0, err
+		}
+	}
+	trx.Commit()
+
+	return nv, nil
+}
+
+// trxRestoreParamHistory replace workset parameter values by values from history version.
+// If parameter was not in workset at that version then parameter deleted from workset.
+// Current parameter values saved as new version before restore.
+// It does update as part of transaction. Return version id of saved current parameter values.
+func trxRestoreParamHistory(trx *sql.Tx, setId int, param *ParamMeta, hv *WorksetParamHistory, upd *WorksetUpdateAction) (int, error) {
+
+	sId := strconv.Itoa(setId)
+	sHid := strconv.Itoa(param.ParamHid)
+
+	// "lock" workset to prevent update or use by the model
+	err := TrxUpdate(trx,
+		"UPDATE workset_lst SET is_readonly = is_readonly + 1 WHERE set_id = "+sId)
+	if err != nil {
+		return 0, err
+	}
+
+	// check if workset is read-write
+	nRd := 0
+	err = TrxSelectFirst(trx,
+		"SELECT is_readonly FROM workset_lst WHERE set_id = "+sId,
+		func(row *sql.Row) error {
+			if err := row.Scan(&nRd); err != nil {
+				return err
+			}
+			return nil
+		})
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, errors.New("workset not found, id: " + sId)
+	case err != nil:
+		return 0, err
+	case nRd != 1:
+		return 0, errors.New("failed to restore: workset is read-only, id: " + sId)
+	}
+
+	// save current parameter values as new version
+	nv, err := trxSaveParamHistory(trx, setId, param, upd)
+	if err != nil {
+		return 0, err
+	}
+
+	// delete current parameter values
+	err = TrxUpdate(trx, "DELETE FROM "+param.DbSetTable+" WHERE set_id = "+sId)
+	if err != nil {
+		return 0, err
+	}
+
+	if hv.SubCount <= 0 {
+
+		// parameter was not in workset: delete parameter from workset
+		err = TrxUpdate(trx, "DELETE FROM workset_parameter_txt WHERE set_id = "+sId+" AND parameter_hid = "+sHid)
+		if err != nil {
+			return 0, err
+		}
+		err = TrxUpdate(trx, "DELETE FROM workset_parameter WHERE set_id = "+sId+" AND parameter_hid = "+sHid)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+
+		// update sub-values count or insert parameter into workset
+		isExist := false
+		err = TrxSelectFirst(trx,
+			"SELECT parameter_hid FROM workset_parameter WHERE set_id = "+sId+" AND parameter_hid = "+sHid,
+			func(row *sql.Row) error {
+				var n int
+				if err := row.Scan(&n); err != nil {
+					return err
+				}
+				isExist = true
+				return nil
+			})
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+
+		q := ""
+		if isExist {
+			q = "UPDATE workset_parameter" +
+				" SET sub_count = " + strconv.Itoa(hv.SubCount) + ", default_sub_id = " + strconv.Itoa(hv.DefaultSubId) +
+				" WHERE set_id = " + sId + " AND parameter_hid = " + sHid
+		} else {
+			q = "INSERT INTO workset_parameter (set_id, parameter_hid, sub_count, default_sub_id)" +
+				" VALUES (" + sId + ", " + sHid + ", " + strconv.Itoa(hv.SubCount) + ", " + strconv.Itoa(hv.DefaultSubId) + ")"
+		}
+		if err = TrxUpdate(trx, q); err != nil {
+			return 0, err
+		}
+
+		// copy parameter values from history:
+		// current values saved as new version, each cell is from first version starting from restore version
+		dimCols := ""
+		for k := range param.Dim {
+			dimCols += param.Dim[k].colName + ", "
+		}
+		err = TrxUpdate(trx,
+			"INSERT INTO "+param.DbSetTable+
+				" (set_id, sub_id, "+dimCols+"param_value)"+
+				" SELECT V.set_id, V.sub_id, "+paramHistoryCols("V", param)+"V.param_value"+
+				" FROM ("+paramHistoryVersionSql(setId, param, hv.VersionId, nv)+") V"+
+				" WHERE V.is_absent = 0")
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// keep in history only cells changed by restore
+	if err = trxTrimParamHistory(trx, setId, param, nv); err != nil {
+		return 0, err
+	}
+
+	// "unlock" workset before commit: restore original value of is_readonly=0
+	err = TrxUpdate(trx,
+		"UPDATE workset_lst"+
+			" SET is_readonly = 0,"+
+			" update_dt = "+ToQuoted(helper.MakeDateTime(time.Now()))+
+			" WHERE set_id = "+sId)
+
+	return nv, err // return last error, if any
+}
+
+// return true if workset history table exist and map of model parameters Hid to existing parameter history db tables.
+// It must be called before delete transaction started because it is using select from tables which may not exist.
+func modelHistoryTables(dbConn *sql.DB, modelId int) (bool, map[int]string, error) {
+
+	if !isDbTableExist(dbConn, worksetHistoryTable) {
+		return false, map[int]string{}, nil // no workset history in database
+	}
+
+	pLst := []ParamMeta{}
+	err := SelectRows(dbConn,
+		"SELECT P.parameter_hid, P.parameter_name, P.parameter_digest"+
+			" FROM model_parameter_dic M"+
+			" INNER JOIN parameter_dic P ON (P.parameter_hid = M.parameter_hid)"+
+			" WHERE M.model_id = "+strconv.Itoa(modelId),
+		func(rows *sql.Rows) error {
+			var pm ParamMeta
+			if err := rows.Scan(&pm.ParamHid, &pm.Name, &pm.Digest); err != nil {
+				return err
+			}
+			pLst = append(pLst, pm)
+			return nil
+		})
+	if err != nil {
+		return false, nil, err
+	}
+
+	hTbls := map[int]string{}
+	for k := range pLst {
+		if t := paramHistoryTable(&pLst[k]); isDbTableExist(dbConn, t) {
+			hTbls[pLst[k].ParamHid] = t
+		}
+	}
+	return true, hTbls, nil
+}
+
+// trxDeleteModelHistory delete all versions of parameters of all model worksets.
+// It does update as part of transaction.
+func trxDeleteModelHistory(trx *sql.Tx, modelId int, isHistory bool, historyTbls map[int]string) error {
+
+	if !isHistory {
+		return nil // no workset history in database
+	}
+	smId := strconv.Itoa(modelId)
+
+	for _, t := range historyTbls {
+		err := TrxUpdate(trx,
+			"DELETE FROM "+t+" WHERE EXISTS"+
+				" (SELECT set_id FROM workset_lst M WHERE M.set_id = "+t+".set_id AND M.model_id = "+smId+")")
+		if err != nil {
+			return err
+		}
+	}
+	return TrxUpdate(trx,
+		"DELETE FROM "+worksetHistoryTable+" WHERE EXISTS"+
+			" (SELECT set_id FROM workset_lst M WHERE M.set_id = "+worksetHistoryTable+".set_id AND M.model_id = "+smId+")")
+}
+
+// trxSaveWorksetHistory save current values of all workset parameters as new versions in workset history.
+// It does update as part of transaction, it must be called after workset "locked" by update of workset_lst.is_readonly.
+func trxSaveWorksetHistory(trx *sql.Tx, setId int, modelDef *ModelMeta, upd *WorksetUpdateAction) error {
+
+	hIds := []int{}
+	err := TrxSelectRows(trx,
+		"SELECT parameter_hid FROM workset_parameter WHERE set_id = "+strconv.Itoa(setId)+" ORDER BY 1",
+		func(rows *sql.Rows) error {
+			var n int
+			if err := rows.Scan(&n); err != nil {
+				return err
+			}
+			hIds = append(hIds, n)
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	for _, hId := range hIds {
+
+		k, ok := modelDef.ParamByHid(hId)
+		if !ok {
+			return errors.New("parameter not found, id: " + strconv.Itoa(hId))
+		}
+		if _, err = trxSaveParamHistory(trx, setId, &modelDef.Param[k], upd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneWorksetHistory delete old versions of workset parameters and keep only last keepCount versions of each parameter.
+//
+// If keepCount is zero then all versions of workset parameters deleted.
+// Remaining versions can be read, compared and restored as before: old versions are not required to restore newer versions.
+// Return number of deleted versions.
+func PruneWorksetHistory(dbConn *sql.DB, modelDef *ModelMeta, setName string, keepCount int) (int, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return 0, errors.New("invalid (empty) model metadata")
+	}
+	if setName == "" {
+		return 0, errors.New("invalid (empty) workset name")
+	}
+	if keepCount < 0 {
+		return 0, errors.New("invalid number of workset history versions to keep: " + strconv.Itoa(keepCount))
+	}
+
+	hLst, err := GetWorksetHistoryList(dbConn, modelDef, setName, "")
+	if err != nil {
+		return 0, err
+	}
+	if len(hLst) <= 0 {
+		return 0, nil // no history saved
+	}
+
+	// for each parameter find last version to delete: versions are ordered by version id
+	nVer := map[int]int{}
+	for k := range hLst {
+		nVer[hLst[k].ParamHid]++
+	}
+	lastDel := map[int]int{}
+	nDel := 0
+	for k := range hLst {
+		if nVer[hLst[k].ParamHid] > keepCount {
+			nVer[hLst[k].ParamHid]--
+			lastDel[hLst[k].ParamHid] = hLst[k].VersionId
+			nDel++
+		}
+	}
+	if nDel <= 0 {
+		return 0, nil // nothing to delete
+	}
+
+	// delete versions in transaction scope
+	trx, err := dbConn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	sId := strconv.Itoa(hLst[0].SetId)
+
+	for hId, verId := range lastDel {
+
+		k, ok := modelDef.ParamByHid(hId)
+		if !ok {
+			trx.Rollback()
+			return 0, errors.New("parameter not found, id: " + strconv.Itoa(hId))
+		}
+		sVer := strconv.Itoa(verId)
+
+		err = TrxUpdate(trx,
+			"DELETE FROM "+paramHistoryTable(&modelDef.Param[k])+" WHERE set_id = "+sId+" AND version_id <= "+sVer)
+		if err != nil {
+			trx.Rollback()
+			return 0, err
+		}
+		err = TrxUpdate(trx,
+			"DELETE FROM "+worksetHistoryTable+
+				" WHERE set_id = "+sId+" AND parameter_hid = "+strconv.Itoa(hId)+" AND version_id <= "+sVer)
+		if err != nil {
+			trx.Rollback()
+			return 0, err
+		}
+	}
+	trx.Commit()
+
+	return nDel, nil
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"strconv"
+	"testing"
+)
+
+func TestWorksetHistory(t *testing.T) {
+
+	dbConn, modelDef, langDef := createTestModel(t)
+
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, modelDef, langDef, "r1", &v)
+	setId := createTestWorkset(t, dbConn, modelDef, langDef, "ws", r1, &v)
+
+	k, ok := modelDef.ParamByName("salarySex")
+	if !ok {
+		t.Fatal("****FAIL: parameter not found: salarySex")
+	}
+	param := &modelDef.Param[k]
+
+	// update workset parameter and save previous values in history
+	writeParam := func(cells []CellParam, isPage bool, action string) {
+		err := WriteParameterFrom(dbConn, modelDef,
+			&WriteParamLayout{
+				WriteLayout:  WriteLayout{Name: "salarySex", ToId: setId},
+				SubCount:     1,
+				IsPage:       isPage,
+				UpdateAction: &WorksetUpdateAction{UserName: "u1", Action: action},
+			},
+			paramCellsFrom(cells))
+		if err != nil {
+			t.Fatal("****FAIL: update workset parameter:", action, err)
+		}
+	}
+	// return number of cells stored in history version
+	countVersionCells := func(verId int) int {
+		n := 0
+		err := SelectFirst(dbConn.DB,
+			"SELECT COUNT(*) FROM "+paramHistoryTable(param)+" WHERE set_id = "+strconv.Itoa(setId)+" AND version_id = "+strconv.Itoa(verId),
+			func(row *sql.Row) error {
+				return row.Scan(&n)
+			})
+		if err != nil {
+			t.Fatal("****FAIL: count history cells:", verId, err)
+		}
+		return n
+	}
+	// read parameter values of history version, zero version is current values
+	readVersion := func(verId int) [2]float64 {
+		var r [2]float64
+		n := 0
+		err := ReadWorksetHistoryTo(dbConn.DB, modelDef, "ws", "salarySex", verId, func(src interface{}) (bool, error) {
+			c, ok := src.(CellParam)
+			if !ok || len(c.DimIds) != 1 {
+				t.Fatal("****FAIL: invalid parameter cell:", src)
+			}
+			r[c.DimIds[0]], _ = c.Value.(float64)
+			n++
+			return true, nil
+		})
+		if err != nil {
+			t.Fatal("****FAIL: read workset history version:", verId, err)
+		}
+		if n != 2 {
+			t.Error("****FAIL: expected 2 cells in version:", verId, "found:", n)
+		}
+		return r
+	}
+
+	// update page of single cell: only changed cell stored in history
+	writeParam([]CellParam{{cellIdValue: cellIdValue{DimIds: []int{1}, Value: 25.0}}}, true, "page")
+	if n := countVersionCells(1); n != 1 {
+		t.Error("****FAIL: expected 1 cell in version 1, found:", n)
+	}
+
+	// replace all values: only changed cell stored in history
+	writeParam([]CellParam{
+		{cellIdValue: cellIdValue{DimIds: []int{0}, Value: 30.0}},
+		{cellIdValue: cellIdValue{DimIds: []int{1}, Value: 25.0}},
+	}, false, "csv")
+	if n := countVersionCells(2); n != 1 {
+		t.Error("****FAIL: expected 1 cell in version 2, found:", n)
+	}
+
+	// list of versions
+	hLst, err := GetWorksetHistoryList(dbConn.DB, modelDef, "ws", "")
+	if err != nil {
+		t.Fatal("****FAIL: read workset history list:", err)
+	}
+	if len(hLst) != 2 {
+		t.Fatal("****FAIL: expected 2 history versions, found:", hLst)
+	}
+	for j, a := range []string{"page", "csv"} {
+		if h := hLst[j]; h.VersionId != j+1 || h.Name != "salarySex" || h.Action != a || h.UserName != "u1" || h.SubCount != 1 {
+			t.Error("****FAIL: invalid history version:", j+1, h)
+		}
+	}
+
+	// versions are reconstructed from changed cells and current values
+	for verId, ev := range map[int][2]float64{0: {30, 25}, 1: {10, 20}, 2: {10, 25}} {
+		if fv := readVersion(verId); fv != ev {
+			t.Error("****FAIL: version:", verId, "expected:", ev, "found:", fv)
+		}
+	}
+
+	dLst, err := DiffWorksetHistory(dbConn.DB, modelDef, "ws", "salarySex", 1, 0)
+	if err != nil {
+		t.Fatal("****FAIL: compare workset history versions:", err)
+	}
+	if len(dLst) != 2 || dLst[0].Kind != "update" || dLst[1].Kind != "update" {
+		t.Error("****FAIL: expected 2 updated cells, found:", dLst)
+	}
+	if dLst, err = DiffWorksetHistory(dbConn.DB, modelDef, "ws", "salarySex", 2, 0); err != nil || len(dLst) != 1 {
+		t.Error("****FAIL: expected 1 updated cell, found:", dLst, err)
+	}
+
+	// restore parameter version: current values saved as new version
+	upd := &WorksetUpdateAction{UserName: "u2"}
+
+	nv, err := RestoreWorksetParamHistory(dbConn, modelDef, "ws", "salarySex", 1, "u2")
+	if err != nil {
+		t.Fatal("****FAIL: restore workset parameter:", err)
+	}
+	if nv != 3 || countVersionCells(3) != 2 {
+		t.Error("****FAIL: expected version 3 with 2 cells, found:", nv, countVersionCells(3))
+	}
+	if fv := readTestParam(t, dbConn, modelDef, "salarySex", setId, true); fv[0] != 10.0 || fv[1] != 20.0 {
+		t.Error("****FAIL: expected restored values: 10, 20 found:", fv)
+	}
+	if fv := readVersion(1); fv != [2]float64{10, 20} {
+		t.Error("****FAIL: version: 1 expected: 10, 20 found:", fv)
+	}
+
+	// restore workset to the state before version 3
+	nv, err = RestoreWorksetHistoryChecked(dbConn, modelDef, "ws", 3, upd)
+	if err != nil {
+		t.Fatal("****FAIL: restore workset:", err)
+	}
+	if nv != 4 {
+		t.Error("****FAIL: expected version 4, found:", nv)
+	}
+	if fv := readTestParam(t, dbConn, modelDef, "salarySex", setId, true); fv[0] != 30.0 || fv[1] != 25.0 {
+		t.Error("****FAIL: expected restored values: 30, 25 found:", fv)
+	}
+	if fv := readTestParam(t, dbConn, modelDef, "startAge", setId, true); fv[0] != 18 && fv[0] != int64(18) {
+		t.Error("****FAIL: expected not changed startAge: 18 found:", fv)
+	}
+
+	// keep only last version: older versions deleted and last version is still valid
+	n, err := PruneWorksetHistory(dbConn.DB, modelDef, "ws", 1)
+	if err != nil {
+		t.Fatal("****FAIL: prune workset history:", err)
+	}
+	if n != 3 {
+		t.Error("****FAIL: expected 3 deleted versions, found:", n)
+	}
+	if hLst, err = GetWorksetHistoryList(dbConn.DB, modelDef, "ws", ""); err != nil || len(hLst) != 1 || hLst[0].VersionId != 4 {
+		t.Fatal("****FAIL: expected only version 4, found:", hLst, err)
+	}
+	if fv := readVersion(4); fv != [2]float64{10, 20} {
+		t.Error("****FAIL: version: 4 expected: 10, 20 found:", fv)
+	}
+	if _, err = PruneWorksetHistory(dbConn.DB, modelDef, "ws", -1); err == nil {
+		t.Error("****FAIL: expected error on negative number of versions to keep")
+	}
+
+	// history deleted together with workset
+	if err = DeleteWorkset(dbConn.DB, setId); err != nil {
+		t.Fatal("****FAIL: delete workset:", err)
+	}
+	nh := 0
+	err = SelectFirst(dbConn.DB,
+		"SELECT COUNT(*) FROM "+worksetHistoryTable+" WHERE set_id = "+strconv.Itoa(setId),
+		func(row *sql.Row) error {
+			return row.Scan(&nh)
+		})
+	if err != nil {
+		t.Fatal("****FAIL: count workset history:", err)
+	}
+	if nh != 0 || countVersionCells(4) != 0 {
+		t.Error("****FAIL: expected no history of deleted workset, found:", nh, countVersionCells(4))
+	}
+
+	// history tables dropped together with the model
+	if err = DeleteModel(dbConn.DB, modelDef.Model.ModelId); err != nil {
+		t.Fatal("****FAIL: delete model:", err)
+	}
+	if isDbTableExist(dbConn.DB, paramHistoryTable(param)) {
+		t.Error("****FAIL: expected parameter history table dropped:", paramHistoryTable(param))
+	}
+}
+
+func TestWorksetHistoryNoTables(t *testing.T) {
+
+	dbConn, modelDef, langDef := createTestModel(t)
+
+	v := testValues{salary: [2]float64{10, 20}, startAge: 18, income: [2]float64{100, 200}}
+	r1 := createTestRun(t, dbConn, modelDef, langDef, "r1", &v)
+	setId := createTestWorkset(t, dbConn, modelDef, langDef, "ws", r1, &v)
+
+	// database without workset history tables: parameter updated and history is not saved
+	if _, err := dbConn.Exec("DROP TABLE " + worksetHistoryTable); err != nil {
+		t.Fatal("****FAIL: drop workset history table:", err)
+	}
+
+	err := WriteParameterFrom(dbConn, modelDef,
+		&WriteParamLayout{
+			WriteLayout:  WriteLayout{Name: "salarySex", ToId: setId},
+			SubCount:     1,
+			IsPage:       true,
+			UpdateAction: &WorksetUpdateAction{UserName: "u1", Action: "page"},
+		},
+		paramCellsFrom([]CellParam{{cellIdValue: cellIdValue{DimIds: []int{1}, Value: 25.0}}}))
+	if err != nil {
+		t.Fatal("****FAIL: update workset parameter:", err)
+	}
+	if fv := readTestParam(t, dbConn, modelDef, "salarySex", setId, true); fv[0] != 10.0 || fv[1] != 25.0 {
+		t.Error("****FAIL: expected updated values: 10, 25 found:", fv)
+	}
+
+	hLst, err := GetWorksetHistoryList(dbConn.DB, modelDef, "ws", "")
+	if err != nil || len(hLst) != 0 {
+		t.Error("****FAIL: expected empty workset history, found:", hLst, err)
+	}
+
+	// upgrade database: history tables created and next update saved in history
+	if err = UpgradeWorksetHistory(dbConn, modelDef); err != nil {
+		t.Fatal("****FAIL: create workset history tables:", err)
+	}
+	if _, err = DeleteWorksetParameterChecked(dbConn.DB, modelDef, "ws", "startAge", &WorksetUpdateAction{UserName: "u1", Action: "delete"}); err != nil {
+		t.Fatal("****FAIL: delete workset parameter:", err)
+	}
+	if hLst, err = GetWorksetHistoryList(dbConn.DB, modelDef, "ws", ""); err != nil || len(hLst) != 1 || hLst[0].Name != "startAge" {
+		t.Error("****FAIL: expected startAge version in workset history, found:", hLst, err)
+	}
+}
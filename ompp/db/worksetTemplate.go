@@ -0,0 +1,488 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// WorksetTemplate is a scenario definition: base run or base workset and list of parameter overrides.
+//
+// New workset created with base run and include all parameters from base workset and all overridden parameters.
+// If overridden parameter is not in base workset then it is copied from base run.
+// Overrides applied in the order of definition, for example, in yaml:
+//
+//	BaseRun: Default
+//	Txt:
+//	  - LangCode: EN
+//	    Descr: Higher salary scenario
+//	Override:
+//	  - Name: salaryFull
+//	    Filter:
+//	      - Name: dim0
+//	        Op: IN
+//	        Values: [L, M]
+//	    Value: 1000
+//	  - Name: ageSex
+//	    Formula: ageSex * 1.05
+//
+// Template stored together with the workset and can be re-applied to create the same workset from newer base run.
+type WorksetTemplate struct {
+	Name          string             // template name
+	BaseRun       string             // base run digest, stamp or name, if empty then base run of base workset is used
+	BaseSet       string             // base workset name, if not empty then all parameters of base workset are included
+	Txt           []DescrNote        // workset description and notes by language
+	Override      []TemplateOverride // parameter overrides, applied in order of definition
+	BaseRunDigest string             // actual base run digest, result of template apply, input value ignored
+	ApplyDateTime string             // date-time of template apply, input value ignored
+}
+
+// TemplateOverride is an override of parameter values: filters and new value or formula.
+//
+// If Formula is not empty then it is used to calculate new values, see WorksetParamFormula for details.
+// Else selected cells updated by Value, it must be enum code for enum-based parameter or null for NULL value.
+type TemplateOverride struct {
+	Name    string         // parameter name
+	Filter  []FilterColumn // dimension filters by enum codes, combined by AND, if empty then all cells updated
+	Value   interface{}    // new value of selected cells
+	Formula string         // formula to calculate new values, ex.: ageSex * 1.05
+	Count   int64          // number of updated cells, result of template apply, input value ignored
+}
+
+// GetWorksetTemplate return template which was applied to create the workset.
+// Return nil if workset was not created from template.
+func GetWorksetTemplate(dbConn *sql.DB, modelDef *ModelMeta, setName string) (*WorksetTemplate, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+
+	val, err := getWorksetExtra(dbConn, modelDef, setName, templateWorksetExtra)
+	if err != nil {
+		return nil, err
+	}
+	if val == "" {
+		return nil, nil
+	}
+
+	var tpl WorksetTemplate
+	if err = json.Unmarshal([]byte(val), &tpl); err != nil {
+		return nil, errors.New("invalid workset template: " + setName + ": " + err.Error())
+	}
+	return &tpl, nil
+}
+
+// ReapplyWorksetTemplate create workset again from stored workset template using new base run.
+// If base run digest, stamp or name is empty then template base run is used.
+// All workset parameters deleted and created again from base run, base workset and template overrides.
+// If update action not nil then current values of workset parameters saved in workset history before update.
+func ReapplyWorksetTemplate(dbConn Dbc, modelDef *ModelMeta, langDef *LangMeta, setName string, baseRun string, upd *WorksetUpdateAction) (*WorksetTemplate, error) {
+
+	tpl, err := GetWorksetTemplate(dbConn.DB, modelDef, setName)
+	if err != nil {
+		return nil, err
+	}
+	if tpl == nil {
+		return nil, errors.New("workset was not created from template: " + setName)
+	}
+	if baseRun != "" {
+		tpl.BaseRun = baseRun
+	}
+	return ApplyWorksetTemplate(dbConn, modelDef, langDef, setName, tpl, true, upd)
+}
+
+// ApplyWorksetTemplate create workset from template: base run or base workset and parameter overrides.
+//
+// If isReplace is false then it is an error if workset already exist.
+// If isReplace is true and workset exist then all workset parameters deleted and created again, workset read-only status is not changed.
+// Overridden parameter values must be valid by parameter validation rules.
+// Template stored with the workset and returned with actual base run digest and number of updated cells by each override.
+// If update action not nil then each parameter delete, insert and override is saved in workset history.
+func ApplyWorksetTemplate(dbConn Dbc, modelDef *ModelMeta, langDef *LangMeta, setName string, tpl *WorksetTemplate, isReplace bool, upd *WorksetUpdateAction) (*WorksetTemplate, error) {
+
+	// validate parameters
+	if modelDef == nil {
+		return nil, errors.New("invalid (empty) model metadata, look like model not found")
+	}
+	if tpl == nil {
+		return nil, errors.New("invalid (empty) workset template")
+	}
+	if setName == "" {
+		setName = tpl.Name
+	}
+	if setName == "" {
+		return nil, errors.New("invalid (empty) workset name")
+	}
+	if tpl.BaseRun == "" && tpl.BaseSet == "" {
+		return nil, errors.New("invalid workset template, it must have base run or base workset: " + setName)
+	}
+	for k := range tpl.Override {
+		if _, ok := modelDef.ParamByName(tpl.Override[k].Name); !ok {
+			return nil, errors.New("invalid workset template, parameter not found: " + tpl.Override[k].Name)
+		}
+	}
+
+	// find base workset and base run: base run must be completed
+	var basePub *WorksetPub
+	baseSetId := 0
+	baseRunId := 0
+
+	if tpl.BaseSet != "" {
+		w, err := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, tpl.BaseSet)
+		if err != nil {
+			return nil, err
+		}
+		if w == nil {
+			return nil, errors.New("base workset not found: " + tpl.BaseSet)
+		}
+		wf, err := GetWorksetFull(dbConn.DB, w, "")
+		if err != nil {
+			return nil, err
+		}
+		if basePub, err = wf.ToPublic(dbConn.DB, modelDef); err != nil {
+			return nil, err
+		}
+		baseSetId = w.SetId
+		baseRunId = w.BaseRunId
+	}
+
+	var baseRun *RunRow
+	var err error
+	if tpl.BaseRun != "" {
+		baseRun, err = GetRunByDigestStampName(dbConn.DB, modelDef.Model.ModelId, tpl.BaseRun)
+	} else {
+		if baseRunId > 0 {
+			baseRun, err = GetRun(dbConn.DB, baseRunId)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if baseRun != nil {
+		if baseRun.ModelId != modelDef.Model.ModelId {
+			return nil, errors.New("base run not found: " + tpl.BaseRun)
+		}
+		if !IsRunCompleted(baseRun.Status) {
+			return nil, errors.New("base run not completed: " + baseRun.Name + " " + baseRun.RunDigest)
+		}
+	}
+	if baseRun == nil && tpl.BaseRun != "" {
+		return nil, errors.New("base run not found: " + tpl.BaseRun)
+	}
+
+	// list of workset parameters: all parameters of base workset and all overridden parameters
+	isBaseSet := map[string]int{}
+	if basePub != nil {
+		for k := range basePub.Param {
+			isBaseSet[basePub.Param[k].Name] = k
+		}
+	}
+	isOverride := map[string]bool{}
+	for k := range tpl.Override {
+		isOverride[tpl.Override[k].Name] = true
+	}
+
+	paramLst := []ParamRunSetPub{}
+	srcLst := []ReadParamLayout{}
+
+	for k := range modelDef.Param {
+
+		name := modelDef.Param[k].Name
+		if j, ok := isBaseSet[name]; ok {
+			p := basePub.Param[j]
+			p.ValueDigest = ""
+			paramLst = append(paramLst, p)
+			srcLst = append(srcLst, ReadParamLayout{ReadLayout: ReadLayout{Name: name, FromId: baseSetId}, IsFromSet: true})
+			continue
+		}
+		if !isOverride[name] {
+			continue
+		}
+		if baseRun == nil {
+			return nil, errors.New("parameter not found in base workset and there is no base run: " + name)
+		}
+		paramLst = append(paramLst, ParamRunSetPub{ParamRunSetTxtPub: ParamRunSetTxtPub{Name: name}})
+		srcLst = append(srcLst, ReadParamLayout{ReadLayout: ReadLayout{Name: name, FromId: baseRun.RunId}})
+	}
+
+	// if workset exist then it must be replaced: make it read-write and delete all existing parameters
+	wsRow, err := GetWorksetByName(dbConn.DB, modelDef.Model.ModelId, setName)
+	if err != nil {
+		return nil, err
+	}
+	isReadonly := false
+	if wsRow != nil {
+		if !isReplace {
+			return nil, errors.New("workset already exists: " + setName)
+		}
+		isReadonly = wsRow.IsReadonly
+
+		if err = UpdateWorksetReadonlyChecked(dbConn.DB, wsRow.SetId, false, upd); err != nil {
+			if err == ErrWorksetUpdated {
+				return nil, err
+			}
+			return nil, errors.New("failed to clear workset read-only status: " + setName + ": " + err.Error())
+		}
+		upd = upd.afterCheck() // workset update date-time checked by first update
+		if err = deleteWorksetAllParameters(dbConn.DB, modelDef, wsRow.SetId, upd); err != nil {
+			return nil, errors.New("failed to delete workset parameters: " + setName + ": " + err.Error())
+		}
+	}
+
+	// create or replace workset metadata
+	pub := WorksetPub{
+		WorksetHdrPub: WorksetHdrPub{
+			ModelName:   modelDef.Model.Name,
+			ModelDigest: modelDef.Model.Digest,
+			Name:        setName,
+			Txt:         tpl.Txt,
+		},
+		Param: []ParamRunSetPub{},
+	}
+	if baseRun != nil {
+		pub.BaseRunDigest = baseRun.RunDigest
+	}
+	ws, err := pub.FromPublic(dbConn.DB, modelDef)
+	if err != nil {
+		return nil, err
+	}
+	if err = ws.UpdateWorksetChecked(dbConn.DB, modelDef, wsRow != nil, langDef, upd); err != nil {
+		return nil, err
+	}
+	setId := ws.Set.SetId
+
+	// copy parameters from base workset or base run
+	for k := range paramLst {
+
+		cLst := []CellParam{}
+		subIds := map[int]bool{}
+
+		_, err = ReadParameterTo(dbConn.DB, modelDef, &srcLst[k], func(src interface{}) (bool, error) {
+			c := src.(CellParam)
+			cLst = append(cLst, c)
+			subIds[c.SubId] = true
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(cLst) <= 0 {
+			return nil, errors.New("missing parameter values: " + paramLst[k].Name)
+		}
+		if paramLst[k].SubCount <= 0 {
+			paramLst[k].SubCount = len(subIds)
+		}
+		if !subIds[paramLst[k].DefaultSubId] {
+			paramLst[k].DefaultSubId = cLst[0].SubId
+		}
+
+		n := 0
+		from := func() (interface{}, error) {
+			if n >= len(cLst) {
+				return nil, nil // end of data
+			}
+			n++
+			return cLst[n-1], nil
+		}
+		if _, err = ws.UpdateWorksetParameterFrom(dbConn, modelDef, true, &paramLst[k], langDef, upd, from); err != nil {
+			return nil, err
+		}
+	}
+
+	// apply overrides in order of definition
+	for k := range tpl.Override {
+
+		ov := &tpl.Override[k]
+
+		if ov.Formula != "" {
+			ov.Count, err = UpdateWorksetParameterFormula(dbConn.DB, modelDef, setName, &WorksetParamFormula{Name: ov.Name, Formula: ov.Formula, Filter: ov.Filter}, upd)
+		} else {
+			ov.Count, err = overrideWorksetParameterValue(dbConn, modelDef, setId, ov, upd)
+		}
+		if err != nil {
+			return nil, errors.New("failed to apply override of parameter: " + ov.Name + ": " + err.Error())
+		}
+	}
+
+	// restore workset read-only status
+	if isReadonly {
+		if err = UpdateWorksetReadonly(dbConn.DB, setId, true); err != nil {
+			return nil, err
+		}
+	}
+
+	// store template with actual base run digest
+	tpl.BaseRunDigest = pub.BaseRunDigest
+	tpl.ApplyDateTime = helper.MakeDateTime(time.Now())
+
+	js, err := json.Marshal(tpl)
+	if err != nil {
+		return nil, err
+	}
+	if len(js) > optionDbMax {
+		return nil, errors.New("invalid workset template, it is too long: " + setName)
+	}
+	if err = updateWorksetExtra(dbConn.DB, modelDef, setName, templateWorksetExtra, string(js)); err != nil {
+		return nil, err
+	}
+
+	// store source of workset parameters: base workset or base run
+	psLst := make([]WorksetParamSource, len(paramLst))
+	for k := range paramLst {
+		psLst[k] = WorksetParamSource{Name: paramLst[k].Name, Kind: "template"}
+		if srcLst[k].IsFromSet {
+			psLst[k].SetName = tpl.BaseSet
+		} else {
+			psLst[k].RunName = baseRun.Name
+			psLst[k].RunDigest = baseRun.RunDigest
+		}
+	}
+	if err = updateWorksetParamSource(dbConn.DB, modelDef, setName, psLst); err != nil {
+		return nil, err
+	}
+
+	return tpl, nil
+}
+
+// overrideWorksetParameterValue update workset parameter cells selected by filters with new value.
+// Return number of updated cells.
+func overrideWorksetParameterValue(dbConn Dbc, modelDef *ModelMeta, setId int, ov *TemplateOverride, upd *WorksetUpdateAction) (int64, error) {
+
+	idx, ok := modelDef.ParamByName(ov.Name)
+	if !ok {
+		return 0, errors.New("parameter not found: " + ov.Name)
+	}
+	param := &modelDef.Param[idx]
+
+	val, isNull, err := templateParamValue(param, ov.Value)
+	if err != nil {
+		return 0, err
+	}
+
+	nSub, _, err := GetWorksetParam(dbConn.DB, setId, param.ParamHid)
+	if err != nil {
+		return 0, err
+	}
+	if nSub <= 0 {
+		return 0, errors.New("workset does not contain parameter: " + ov.Name)
+	}
+
+	// read selected cells and replace values
+	cvt := CellParamConverter{ModelDef: modelDef, Name: param.Name}
+	toCode, err := cvt.IdToCodeCell(modelDef, param.Name)
+	if err != nil {
+		return 0, err
+	}
+	toId, err := cvt.CodeToIdCell(modelDef, param.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	cLst := []interface{}{}
+	_, err = ReadParameterTo(dbConn.DB, modelDef,
+		&ReadParamLayout{ReadLayout: ReadLayout{Name: param.Name, FromId: setId, Filter: ov.Filter}, IsFromSet: true},
+		func(src interface{}) (bool, error) {
+			c, e := toCode(src)
+			if e != nil {
+				return false, e
+			}
+			cc := c.(CellCodeParam)
+			cc.IsNull = isNull
+			cc.Value = val
+
+			if c, e = toId(cc); e != nil {
+				return false, e
+			}
+			cLst = append(cLst, c)
+			return true, nil
+		})
+	if err != nil {
+		return 0, err
+	}
+	if len(cLst) <= 0 {
+		return 0, nil // no cells selected by filters
+	}
+
+	// write updated cells
+	n := 0
+	from := func() (interface{}, error) {
+		if n >= len(cLst) {
+			return nil, nil // end of data
+		}
+		n++
+		return cLst[n-1], nil
+	}
+	err = WriteParameterFrom(dbConn, modelDef,
+		&WriteParamLayout{WriteLayout: WriteLayout{Name: param.Name, ToId: setId}, SubCount: nSub, IsPage: true, UpdateAction: upd},
+		from)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(cLst)), nil
+}
+
+// templateParamValue convert template override value to parameter cell value.
+// Return enum code for enum-based parameter and true if value is NULL.
+// Value can be a string, ex.: "0.5" or "true" or a json value of the parameter type.
+func templateParamValue(param *ParamMeta, src interface{}) (interface{}, bool, error) {
+
+	if src == nil {
+		if !param.IsExtendable {
+			return nil, true, errors.New("invalid parameter value, it cannot be NULL: " + param.Name)
+		}
+		return nil, true, nil
+	}
+
+	// convert value to string
+	sv := ""
+	switch v := src.(type) {
+	case string:
+		sv = v
+	case float64:
+		sv = strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		sv = fmt.Sprint(v)
+	}
+
+	switch {
+	case !param.typeOf.IsBuiltIn(): // enum code
+		return sv, false, nil
+	case param.typeOf.IsBool():
+		if v, ok := src.(bool); ok {
+			return v, false, nil
+		}
+		v, err := strconv.ParseBool(sv)
+		if err != nil {
+			return nil, false, errors.New("invalid parameter value, expected: boolean: " + param.Name + ": " + sv)
+		}
+		return v, false, nil
+	case param.typeOf.IsString():
+		return sv, false, nil
+	case param.typeOf.IsFloat():
+		v, err := strconv.ParseFloat(sv, 64)
+		if err != nil {
+			return nil, false, errors.New("invalid parameter value, expected: float: " + param.Name + ": " + sv)
+		}
+		return v, false, nil
+	case param.typeOf.IsInt():
+		v, err := strconv.ParseFloat(sv, 64)
+		if err != nil || v != math.Trunc(v) {
+			return nil, false, errors.New("invalid parameter value, expected: integer: " + param.Name + ": " + sv)
+		}
+		return int64(v), false, nil
+	}
+	return nil, false, errors.New("invalid parameter type, value cannot be converted: " + param.Name + ": " + sv)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"testing"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+func TestTemplateParamValue(t *testing.T) {
+
+	dbl := testParamOf("p_double", 14, "double", true)
+	num := testParamOf("p_int", 7, "int", false)
+	isOk := testParamOf("p_bool", 1, "bool", false)
+	sex := testParamOf("p_SEX", 101, "SEX", false)
+
+	// valid values
+	for _, tc := range []struct {
+		param    *ParamMeta
+		src      interface{}
+		expected interface{}
+		isNull   bool
+	}{
+		{param: dbl, src: 0.5, expected: 0.5},
+		{param: dbl, src: "1.25", expected: 1.25},
+		{param: dbl, src: nil, expected: nil, isNull: true},
+		{param: num, src: 42.0, expected: int64(42)},
+		{param: num, src: "7", expected: int64(7)},
+		{param: isOk, src: true, expected: true},
+		{param: isOk, src: "false", expected: false},
+		{param: sex, src: "M", expected: "M"},
+	} {
+		v, isNull, err := templateParamValue(tc.param, tc.src)
+		if err != nil {
+			t.Error("****FAIL:", tc.param.Name, tc.src, err)
+			continue
+		}
+		if v != tc.expected || isNull != tc.isNull {
+			t.Error("****FAIL:", tc.param.Name, tc.src, "expected:", tc.expected, tc.isNull, "found:", v, isNull)
+		}
+	}
+
+	// invalid values
+	for _, tc := range []struct {
+		param *ParamMeta
+		src   interface{}
+	}{
+		{param: dbl, src: "abc"},
+		{param: num, src: 1.5},
+		{param: num, src: nil},
+		{param: isOk, src: "maybe"},
+	} {
+		if _, _, err := templateParamValue(tc.param, tc.src); err == nil {
+			t.Error("****FAIL: expected an error:", tc.param.Name, tc.src)
+		} else {
+			t.Log("OK:", err)
+		}
+	}
+
+	// template can be defined in yaml, keys are the same as json
+	src := "BaseRun: Default\n" +
+		"Override:\n" +
+		"  - Name: salaryFull\n" +
+		"    Filter:\n" +
+		"      - Name: dim0\n" +
+		"        Op: IN\n" +
+		"        Values: [L, M]\n" +
+		"    Value: 1000\n" +
+		"  - Name: ageSex\n" +
+		"    Formula: ageSex * 1.05\n"
+
+	var tpl WorksetTemplate
+	if _, err := helper.FromYaml([]byte(src), &tpl); err != nil {
+		t.Fatal(err)
+	}
+	if tpl.BaseRun != "Default" || len(tpl.Override) != 2 ||
+		len(tpl.Override[0].Filter) != 1 || tpl.Override[0].Filter[0].Op != InOpFilter || len(tpl.Override[0].Filter[0].Values) != 2 ||
+		tpl.Override[0].Value != 1000.0 || tpl.Override[1].Formula != "ageSex * 1.05" {
+		t.Error("****FAIL: yaml template:", tpl)
+	}
+}
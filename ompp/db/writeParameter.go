@@ -23,6 +23,7 @@ import (
 // If workset already contain parameter values then values updated else inserted.
 // If only "page" of workset parameter rows supplied (layout.IsPage is true)
 // then each row deleted by primary key before insert else all rows deleted by one delete by set id.
+// If layout.UpdateAction is not nil then current workset parameter values saved in workset history in the same transaction.
 //
 // Double format is used for float model types digest calculation, if non-empty format supplied.
 func WriteParameterFrom(dbConn Dbc, modelDef *ModelMeta, layout *WriteParamLayout, from func() (interface{}, error)) error {
@@ -59,6 +60,7 @@ func WriteParameterFrom(dbConn Dbc, modelDef *ModelMeta, layout *WriteParamLayou
 	}
 
 	var defSubId int = 0
+	var rules []ParamRule
 	if !layout.IsToRun {
 		n, defId, e := GetWorksetParam(dbConn.DB, layout.ToId, param.ParamHid)
 		if e != nil {
@@ -68,6 +70,17 @@ func WriteParameterFrom(dbConn Dbc, modelDef *ModelMeta, layout *WriteParamLayou
 			return errors.New("parameter not found: " + layout.Name + " in workset: " + strconv.Itoa(layout.ToId))
 		}
 		defSubId = defId
+
+		// workset parameter values must be valid by parameter validation rules
+		if rules, e = GetParamRules(dbConn.DB, modelDef, param.Name); e != nil {
+			return e
+		}
+	}
+
+	// skip workset history if history tables not exist
+	upd := layout.UpdateAction
+	if !layout.IsToRun {
+		upd = upd.historyCheck(dbConn.DB, param)
 	}
 
 	// do insert or update parameter in transaction scope
@@ -78,7 +91,7 @@ func WriteParameterFrom(dbConn Dbc, modelDef *ModelMeta, layout *WriteParamLayou
 	if layout.IsToRun {
 		err = doWriteRunParameterFrom(DbTrx{Tx: trx, Dbf: dbConn.Dbf}, modelDef, param, layout.ToId, layout.SubCount, from, layout.DoubleFmt)
 	} else {
-		err = doWriteSetParameterFrom(DbTrx{Tx: trx, Dbf: dbConn.Dbf}, param, layout.ToId, layout.SubCount, defSubId, layout.IsPage, from, layout.DoubleFmt)
+		err = doWriteSetParameterFrom(DbTrx{Tx: trx, Dbf: dbConn.Dbf}, param, layout.ToId, layout.SubCount, defSubId, layout.IsPage, from, layout.DoubleFmt, rules, upd)
 	}
 	if err != nil {
 		trx.Rollback()
@@ -257,10 +270,17 @@ func doWriteRunParameterFrom(
 // doWriteSetParameterFrom insert or update parameter values in workset.
 // It does insert as part of transaction
 // If workset already contain parameter values then values updated else inserted.
+// If parameter validation rules not empty then parameter values checked after update and any violation is an error.
+// If update action not nil then workset update date-time checked and current parameter values saved in workset history before update.
 func doWriteSetParameterFrom(
-	trx DbTrx, param *ParamMeta, setId int, subCount int, defaultSubId int, isPage bool, from func() (interface{}, error), doubleFmt string,
+	trx DbTrx, param *ParamMeta, setId int, subCount int, defaultSubId int, isPage bool, from func() (interface{}, error), doubleFmt string, rules []ParamRule, upd *WorksetUpdateAction,
 ) error {
 
+	// check if workset is not updated by other user, it must be done before update_dt changed by workset update
+	if err := trxCheckWorksetUpdate(trx.Tx, setId, upd); err != nil {
+		return err
+	}
+
 	// start workset update
 	sId := strconv.Itoa(setId)
 	err := TrxUpdate(trx.Tx,
@@ -291,6 +311,12 @@ func doWriteSetParameterFrom(
 		return errors.New("cannot update parameter " + param.Name + ", workset is readonly, id: " + sId)
 	}
 
+	// save current parameter values in workset history
+	verId, err := trxSaveParamHistory(trx.Tx, setId, param, upd)
+	if err != nil {
+		return err
+	}
+
 	// delete existing parameter values and insert new values
 	if !isPage {
 		if err = TrxUpdate(trx.Tx, "DELETE FROM "+param.DbSetTable+" WHERE set_id = "+sId); err != nil {
@@ -315,6 +341,16 @@ func doWriteSetParameterFrom(
 		}
 	}
 
+	// check parameter values by validation rules
+	if err = trxValidateWorksetParam(trx.Tx, param, setId, rules); err != nil {
+		return err
+	}
+
+	// keep in workset history only changed cells
+	if err = trxTrimParamHistory(trx.Tx, setId, param, verId); err != nil {
+		return err
+	}
+
 	// update completed: reset readonly status to "read-write"
 	err = TrxUpdate(trx.Tx, "UPDATE workset_lst SET is_readonly = 0 WHERE set_id = "+sId)
 	if err != nil {
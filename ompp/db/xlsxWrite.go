@@ -0,0 +1,364 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+
+	"github.com/openmpp/go/ompp/helper"
+)
+
+// XlsxMetaSheet is a name of metadata sheet in model run or workset .xlsx workbook.
+const XlsxMetaSheet = "_metadata"
+
+// XlsxWriteOptions are options to write model run or workset values into .xlsx workbook.
+type XlsxWriteOptions struct {
+	Lang        string        // if not empty then language of dimension labels, enum labels and notes else enum codes
+	IsIdCsv     bool          // if true then use enum id's else use enum codes or labels
+	DoubleFmt   string        // if not empty then format string is used to sprintf if value type is float, double, long double
+	IsNoZeroCsv bool          // if true then do not write zero values into output tables sheets
+	IsNoNullCsv bool          // if true then do not write NULL values into output tables sheets
+	TxtMeta     *ModelTxtMeta // language-specific model metadata, it is required if Lang not empty
+	MsgDef      []LangMsg     // translated strings, it is used for output table expressions labels
+}
+
+// WriteRunXlsx write model run into .xlsx workbook.
+//
+// First sheet is a run metadata: run name, status, description, notes and run options,
+// next sheets are model run parameters and output tables expressions, one sheet per parameter or output table.
+// Sheets are written one by one and each sheet rows are not kept in memory.
+func WriteRunXlsx(dbConn *sql.DB, modelDef *ModelMeta, meta *RunMeta, opts *XlsxWriteOptions, xw *helper.XlsxWriter) error {
+
+	if modelDef == nil || meta == nil {
+		return errors.New("invalid (empty) model metadata or run metadata")
+	}
+	r := &meta.Run
+
+	// metadata sheet: model, run, description, notes and run options
+	descr, note := "", ""
+	lc := xlsxLangCode(modelDef, opts)
+	for k := range meta.Txt {
+		if meta.Txt[k].LangCode == lc {
+			descr, note = meta.Txt[k].Descr, meta.Txt[k].Note
+			break
+		}
+	}
+	rows := [][]string{
+		{"name", "value"},
+		{"model_name", modelDef.Model.Name},
+		{"model_digest", modelDef.Model.Digest},
+		{"model_version", modelDef.Model.Version},
+		{"run_id", strconv.Itoa(r.RunId)},
+		{"run_name", r.Name},
+		{"run_digest", r.RunDigest},
+		{"run_stamp", r.RunStamp},
+		{"status", r.Status},
+		{"sub_count", strconv.Itoa(r.SubCount)},
+		{"create_dt", r.CreateDateTime},
+		{"update_dt", r.UpdateDateTime},
+		{"lang_code", lc},
+		{"descr", descr},
+		{"note", note},
+	}
+	optKeys := make([]string, 0, len(meta.Opts))
+	for key := range meta.Opts {
+		optKeys = append(optKeys, key)
+	}
+	slices.Sort(optKeys)
+
+	for _, key := range optKeys {
+		rows = append(rows, []string{key, meta.Opts[key]})
+	}
+	if err := writeXlsxMetaSheet(rows, xw); err != nil {
+		return err
+	}
+
+	// run parameters
+	for k := range meta.Param {
+
+		idx, ok := modelDef.ParamByHid(meta.Param[k].ParamHid)
+		if !ok {
+			return errors.New("parameter not found by Hid: " + strconv.Itoa(meta.Param[k].ParamHid))
+		}
+		if err := writeParamXlsxSheet(dbConn, modelDef, modelDef.Param[idx].Name, r.RunId, false, opts, xw); err != nil {
+			return err
+		}
+	}
+
+	// run output tables expressions, suppressed tables are not in run results
+	for k := range meta.Table {
+
+		idx, ok := modelDef.OutTableByHid(meta.Table[k].TableHid)
+		if !ok {
+			return errors.New("output table not found by Hid: " + strconv.Itoa(meta.Table[k].TableHid))
+		}
+		if err := writeTableXlsxSheet(dbConn, modelDef, modelDef.Table[idx].Name, r.RunId, opts, xw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteWorksetXlsx write workset (input set of model parameters) into .xlsx workbook.
+//
+// First sheet is a workset metadata: name, base run, description and notes,
+// next sheets are workset parameters, one sheet per parameter.
+// If workset written with enum codes then workbook layout is the same as expected to import workset from .xlsx.
+func WriteWorksetXlsx(dbConn *sql.DB, modelDef *ModelMeta, meta *WorksetMeta, opts *XlsxWriteOptions, xw *helper.XlsxWriter) error {
+
+	if modelDef == nil || meta == nil {
+		return errors.New("invalid (empty) model metadata or workset metadata")
+	}
+	ws := &meta.Set
+
+	// metadata sheet: model, workset, description and notes
+	descr, note := "", ""
+	lc := xlsxLangCode(modelDef, opts)
+	for k := range meta.Txt {
+		if meta.Txt[k].LangCode == lc {
+			descr, note = meta.Txt[k].Descr, meta.Txt[k].Note
+			break
+		}
+	}
+	baseRunId := ""
+	if ws.BaseRunId > 0 {
+		baseRunId = strconv.Itoa(ws.BaseRunId)
+	}
+	rows := [][]string{
+		{"name", "value"},
+		{"model_name", modelDef.Model.Name},
+		{"model_digest", modelDef.Model.Digest},
+		{"model_version", modelDef.Model.Version},
+		{"set_id", strconv.Itoa(ws.SetId)},
+		{"set_name", ws.Name},
+		{"base_run_id", baseRunId},
+		{"is_readonly", strconv.FormatBool(ws.IsReadonly)},
+		{"update_dt", ws.UpdateDateTime},
+		{"lang_code", lc},
+		{"descr", descr},
+		{"note", note},
+	}
+	if err := writeXlsxMetaSheet(rows, xw); err != nil {
+		return err
+	}
+
+	// workset parameters
+	for k := range meta.Param {
+
+		idx, ok := modelDef.ParamByHid(meta.Param[k].ParamHid)
+		if !ok {
+			return errors.New("parameter not found by Hid: " + strconv.Itoa(meta.Param[k].ParamHid))
+		}
+		if err := writeParamXlsxSheet(dbConn, modelDef, modelDef.Param[idx].Name, ws.SetId, true, opts, xw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// return language code of metadata notes: options language or model default language
+func xlsxLangCode(modelDef *ModelMeta, opts *XlsxWriteOptions) string {
+	if opts.Lang != "" {
+		return opts.Lang
+	}
+	return modelDef.Model.DefaultLangCode
+}
+
+// write metadata sheet rows: name and value
+func writeXlsxMetaSheet(rows [][]string, xw *helper.XlsxWriter) error {
+
+	if _, err := xw.AddSheet(XlsxMetaSheet); err != nil {
+		return err
+	}
+	for k := range rows {
+		if err := xw.WriteRow(rows[k], nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write parameter sheet: header row and parameter values from model run or workset.
+// Sheet layout is the same as parameter csv file: sub_id,dim0,dim1,param_value
+func writeParamXlsxSheet(dbConn *sql.DB, modelDef *ModelMeta, name string, fromId int, isFromSet bool, opts *XlsxWriteOptions, xw *helper.XlsxWriter) error {
+
+	idx, ok := modelDef.ParamByName(name)
+	if !ok {
+		return errors.New("parameter not found: " + name)
+	}
+	param := &modelDef.Param[idx]
+
+	// create converter from db cell into sheet row []string
+	var hdr []string
+	var cvtRow func(interface{}, []string) (bool, error)
+	var err error
+
+	cvtParam := CellParamConverter{
+		ModelDef:  modelDef,
+		Name:      name,
+		IsIdCsv:   opts.IsIdCsv,
+		DoubleFmt: opts.DoubleFmt,
+	}
+	switch {
+	case opts.IsIdCsv:
+		hdr, err = cvtParam.CsvHeader()
+		if err == nil {
+			cvtRow, err = cvtParam.ToCsvIdRow()
+		}
+	case opts.Lang == "" || opts.TxtMeta == nil:
+		hdr, err = cvtParam.CsvHeader()
+		if err == nil {
+			cvtRow, err = cvtParam.ToCsvRow()
+		}
+	default:
+		cvtLoc := CellParamLocaleConverter{
+			CellParamConverter: cvtParam,
+			Lang:               opts.Lang,
+			DimsTxt:            opts.TxtMeta.ParamDimsTxt,
+			EnumTxt:            opts.TxtMeta.TypeEnumTxt,
+		}
+		hdr, err = cvtLoc.CsvHeader()
+		if err == nil {
+			cvtRow, err = cvtLoc.ToCsvRow()
+		}
+	}
+	if err != nil {
+		return errors.New("failed to create parameter converter: " + name + ": " + err.Error())
+	}
+
+	// number columns: sub_id, dimension if it is integer or enum id and parameter value if it is a number
+	isNum := make([]bool, len(hdr))
+	isNum[0] = true
+	for k := range param.Dim {
+		isNum[k+1] = opts.IsIdCsv || param.Dim[k].typeOf.IsBuiltIn()
+	}
+	isNum[param.Rank+1] = param.typeOf.IsFloat() || param.typeOf.IsInt() || opts.IsIdCsv && !param.typeOf.IsBuiltIn()
+
+	// write header and parameter values
+	if _, err = xw.AddSheet(name); err != nil {
+		return err
+	}
+	if err = xw.WriteRow(hdr, nil); err != nil {
+		return err
+	}
+
+	cs := make([]string, len(hdr))
+	cvtWr := func(c interface{}) (bool, error) {
+		isNotEmpty, e := cvtRow(c, cs)
+		if e != nil {
+			return false, e
+		}
+		if isNotEmpty {
+			if e = xw.WriteRow(cs, isNum); e != nil {
+				return false, e
+			}
+		}
+		return true, nil
+	}
+
+	lt := ReadParamLayout{
+		ReadLayout: ReadLayout{Name: name, FromId: fromId},
+		IsFromSet:  isFromSet,
+	}
+	if _, err = ReadParameterTo(dbConn, modelDef, &lt, cvtWr); err != nil {
+		return errors.New("failed to write parameter: " + name + ": " + err.Error())
+	}
+	return nil
+}
+
+// write output table sheet: header row and all output table expressions values from model run.
+// Sheet layout is the same as output table expressions csv file: expr_name,dim0,dim1,expr_value
+func writeTableXlsxSheet(dbConn *sql.DB, modelDef *ModelMeta, name string, runId int, opts *XlsxWriteOptions, xw *helper.XlsxWriter) error {
+
+	idx, ok := modelDef.OutTableByName(name)
+	if !ok {
+		return errors.New("output table not found: " + name)
+	}
+	table := &modelDef.Table[idx]
+
+	// create converter from db cell into sheet row []string
+	var hdr []string
+	var cvtRow func(interface{}, []string) (bool, error)
+	var err error
+
+	cvtExpr := CellExprConverter{CellTableConverter: CellTableConverter{
+		ModelDef:    modelDef,
+		Name:        name,
+		IsIdCsv:     opts.IsIdCsv,
+		DoubleFmt:   opts.DoubleFmt,
+		IsNoZeroCsv: opts.IsNoZeroCsv,
+		IsNoNullCsv: opts.IsNoNullCsv,
+	}}
+	switch {
+	case opts.IsIdCsv:
+		hdr, err = cvtExpr.CsvHeader()
+		if err == nil {
+			cvtRow, err = cvtExpr.ToCsvIdRow()
+		}
+	case opts.Lang == "" || opts.TxtMeta == nil:
+		hdr, err = cvtExpr.CsvHeader()
+		if err == nil {
+			cvtRow, err = cvtExpr.ToCsvRow()
+		}
+	default:
+		cvtLoc := CellExprLocaleConverter{
+			CellExprConverter: cvtExpr,
+			Lang:              opts.Lang,
+			MsgDef:            opts.MsgDef,
+			DimsTxt:           opts.TxtMeta.TableDimsTxt,
+			EnumTxt:           opts.TxtMeta.TypeEnumTxt,
+			ExprTxt:           opts.TxtMeta.TableExprTxt,
+		}
+		hdr, err = cvtLoc.CsvHeader()
+		if err == nil {
+			cvtRow, err = cvtLoc.ToCsvRow()
+		}
+	}
+	if err != nil {
+		return errors.New("failed to create output table converter: " + name + ": " + err.Error())
+	}
+
+	// number columns: expression id, dimension if it is integer or enum id and expression value
+	isNum := make([]bool, len(hdr))
+	isNum[0] = opts.IsIdCsv
+	for k := range table.Dim {
+		isNum[k+1] = opts.IsIdCsv || table.Dim[k].typeOf.IsBuiltIn()
+	}
+	isNum[table.Rank+1] = true
+
+	// write header and output table expressions values
+	if _, err = xw.AddSheet(name); err != nil {
+		return err
+	}
+	if err = xw.WriteRow(hdr, nil); err != nil {
+		return err
+	}
+
+	cs := make([]string, len(hdr))
+	cvtWr := func(c interface{}) (bool, error) {
+		isNotEmpty, e := cvtRow(c, cs)
+		if e != nil {
+			return false, e
+		}
+		if isNotEmpty {
+			if e = xw.WriteRow(cs, isNum); e != nil {
+				return false, e
+			}
+		}
+		return true, nil
+	}
+
+	lt := ReadTableLayout{
+		ReadLayout: ReadLayout{Name: name, FromId: runId},
+	}
+	if _, err = ReadOutputTableTo(dbConn, modelDef, &lt, cvtWr); err != nil {
+		return errors.New("failed to write output table: " + name + ": " + err.Error())
+	}
+	return nil
+}
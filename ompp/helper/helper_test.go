@@ -115,3 +115,39 @@ func TestParseCvsLine(t *testing.T) {
 	checkString(vLst, 0, `"unbalanced quoutes ,`)
 	checkSize(vLst, 1)
 }
+
+func TestFromYaml(t *testing.T) {
+
+	type item struct {
+		Name   string
+		Values []string
+	}
+	type doc struct {
+		Name  string
+		Count int
+		Item  []item
+	}
+
+	// yaml and json must be decoded into the same values
+	for _, src := range []string{
+		"Name: abc\nCount: 2\nItem:\n  - Name: x\n    Values: [a, b]\n  - Name: y\n    Values:\n      - c\n",
+		`{"Name": "abc", "Count": 2, "Item": [{"Name": "x", "Values": ["a", "b"]}, {"Name": "y", "Values": ["c"]}]}`,
+	} {
+		var d doc
+		isOk, err := FromYaml([]byte(src), &d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isOk || d.Name != "abc" || d.Count != 2 || len(d.Item) != 2 ||
+			d.Item[0].Name != "x" || len(d.Item[0].Values) != 2 || d.Item[1].Values[0] != "c" {
+			t.Error("****FAIL: yaml decode:", isOk, d)
+		}
+	}
+
+	// empty source
+	var d doc
+	isOk, err := FromYaml([]byte("  "), &d)
+	if err != nil || isOk {
+		t.Error("****FAIL: expected empty result:", isOk, err)
+	}
+}
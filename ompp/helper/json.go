@@ -6,8 +6,12 @@ package helper
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
+	"io/fs"
 	"os"
+
+	"gopkg.in/yaml.v3"
 )
 
 // FromJsonFile reads read from json file and convert to destination pointer.
@@ -45,6 +49,40 @@ func FromJsonFile(jsonPath string, dst interface{}) (bool, error) {
 	return true, nil
 }
 
+// FromJsonFs reads json file from file system, e.g. from zip archive, and convert to destination pointer.
+func FromJsonFs(fsys fs.FS, name string, dst interface{}) (bool, error) {
+
+	if name == "" || name == "." {
+		return false, nil // file path is empty
+	}
+
+	// open file and convert to utf-8
+	f, err := fsys.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil // return: json file not exist
+		}
+		return false, ErrorNew("json file open error:", err)
+	}
+	defer f.Close()
+
+	// assume utf-8 as default encoding on any OS because json file must be unicode
+	rd, err := ReaderToUtf8(f, "utf-8")
+	if err != nil {
+		return false, ErrorNew("json file read error:", err)
+	}
+
+	// decode json
+	err = json.NewDecoder(rd).Decode(dst)
+	if err != nil {
+		if err == io.EOF {
+			return false, nil // return "not exist" if json file empty
+		}
+		return false, ErrorNew("json decode error:", err)
+	}
+	return true, nil
+}
+
 // FromJson restore from json string bytes and convert to destination pointer.
 func FromJson(srcJson []byte, dst interface{}) (bool, error) {
 
@@ -58,6 +96,26 @@ func FromJson(srcJson []byte, dst interface{}) (bool, error) {
 	return true, nil
 }
 
+// FromYaml restore from yaml or json string bytes and convert to destination pointer.
+// Yaml is converted into json before decode, therefore yaml keys are the same as json keys, e.g.: Name, SubCount.
+func FromYaml(srcYaml []byte, dst interface{}) (bool, error) {
+
+	var src interface{}
+	err := yaml.Unmarshal(srcYaml, &src)
+	if err != nil {
+		return false, ErrorNew("yaml decode error:", err)
+	}
+	if src == nil {
+		return false, nil // return "not exist" if yaml empty
+	}
+
+	srcJson, err := json.Marshal(src)
+	if err != nil {
+		return false, ErrorNew("yaml to json conversion error:", err)
+	}
+	return FromJson(srcJson, dst)
+}
+
 // ToJsonFile convert source to json and write into jsonPath file.
 func ToJsonFile(jsonPath string, src interface{}) error {
 
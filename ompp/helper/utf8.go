@@ -4,8 +4,10 @@
 package helper
 
 import (
+	"bufio"
 	"errors"
 	"io"
+	"io/fs"
 	"os"
 	"runtime"
 	"unicode/utf8"
@@ -161,3 +163,122 @@ func Utf8Reader(f *os.File, encodingName string) (io.Reader, error) {
 
 	return transform.NewReader(f, unicode.BOMOverride(enc.NewDecoder())), nil
 }
+
+// FsFileToUtf8 read file content from file system, e.g. from zip archive, and convert it to UTF-8 string.
+// Encoding rules are the same as in FileToUtf8().
+func FsFileToUtf8(fsys fs.FS, name string, encodingName string) (string, error) {
+
+	// open file and create utf-8 transform reader
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", errors.New("file open error: " + err.Error())
+	}
+	defer f.Close()
+
+	rd, err := ReaderToUtf8(f, encodingName)
+	if err != nil {
+		return "", errors.New("failed to create utf-8 reader " + encodingName + " : " + err.Error())
+	}
+
+	// read and convert into utf-8
+	bt, err := io.ReadAll(rd)
+	if err != nil {
+		return "", errors.New("read to utf-8 error: " + err.Error())
+	}
+	return string(bt), nil
+}
+
+// ReaderToUtf8 return a reader to transform source stream content to utf-8.
+//
+// It is a streaming version of Utf8Reader() which does not require source to be seekable,
+// for example, source can be a file inside of zip archive.
+// Encoding rules are the same as in Utf8Reader(): BOM is used, if present,
+// else if encodingName is "" empty then source content probed to see is it already utf-8,
+// else if encodingName explicitly specified then it is used,
+// if none of above then assume default encoding: "windows-1252" on Windows and "utf-8" on Linux.
+func ReaderToUtf8(rd io.Reader, encodingName string) (io.Reader, error) {
+
+	// validate parameters
+	if rd == nil {
+		return nil, errors.New("invalid (nil) source reader")
+	}
+	br := bufio.NewReaderSize(rd, utf8ProbeLen)
+
+	// detect BOM
+	bom, err := br.Peek(utf8.UTFMax)
+	nBom := len(bom)
+	if err != nil && err != io.EOF {
+		return nil, errors.New("read error: " + err.Error())
+	}
+	if nBom == 0 { // empty source: return as is
+		return br, nil
+	}
+
+	// if utf-8 BOM then skip it and return source reader
+	if nBom >= len(Utf8bom) && bom[0] == Utf8bom[0] && bom[1] == Utf8bom[1] && bom[2] == Utf8bom[2] {
+		if _, err := br.Discard(len(Utf8bom)); err != nil {
+			return nil, errors.New("read error: " + err.Error())
+		}
+		return br, nil
+	}
+
+	// ambiguos utf-16LE and utf32-LE detection: assume utf-32LE because 00 00 is very unlikely in text file
+	if nBom >= len(Utf32LEbom) && bom[0] == Utf32LEbom[0] && bom[1] == Utf32LEbom[1] && bom[2] == Utf32LEbom[2] && bom[3] == Utf32LEbom[3] {
+		return transform.NewReader(br, utf32.UTF32(utf32.LittleEndian, utf32.UseBOM).NewDecoder()), nil
+	}
+	if nBom >= len(Utf32BEbom) && bom[0] == Utf32BEbom[0] && bom[1] == Utf32BEbom[1] && bom[2] == Utf32BEbom[2] && bom[3] == Utf32BEbom[3] {
+		return transform.NewReader(br, utf32.UTF32(utf32.BigEndian, utf32.UseBOM).NewDecoder()), nil
+	}
+	if nBom >= len(Utf16LEbom) && bom[0] == Utf16LEbom[0] && bom[1] == Utf16LEbom[1] {
+		return transform.NewReader(br, unicode.BOMOverride(encoding.Nop.NewDecoder())), nil
+	}
+	if nBom >= len(Utf16BEbom) && bom[0] == Utf16BEbom[0] && bom[1] == Utf16BEbom[1] {
+		return transform.NewReader(br, unicode.BOMOverride(encoding.Nop.NewDecoder())), nil
+	}
+	// no BOM detected
+
+	// encoding not specified then probe source to check is it utf-8
+	if encodingName == "" {
+
+		// peek probe bytes: source stays at the beginning
+		buf, err := br.Peek(utf8ProbeLen)
+		if err != nil && err != io.EOF {
+			return nil, errors.New("read error: " + err.Error())
+		}
+		nProbe := len(buf)
+
+		// check if all runes are utf-8
+		nPos := 0
+		for nPos < nProbe {
+			r, n := utf8.DecodeRune(buf)
+			if n <= 0 || r == utf8.RuneError { // if eof or not utf-8 rune
+				break
+			}
+			nPos += n
+			buf = buf[n:]
+		}
+
+		// source is utf-8 if:
+		// all runes are utf-8 and source size less than max probe size or source size exceeds probe size
+		if nPos >= nProbe || nPos >= utf8ProbeLen-utf8.UTFMax {
+			return br, nil // utf-8 source: return source reader
+		}
+	}
+
+	// if encoding is not explicitly specified then use OS default
+	if encodingName == "" {
+		if runtime.GOOS == "windows" {
+			encodingName = "windows-1252"
+		} else {
+			encodingName = "utf-8"
+		}
+	}
+
+	// get encoding by name
+	enc, err := htmlindex.Get(encodingName)
+	if err != nil {
+		return nil, errors.New("invalid encoding: " + encodingName + " " + err.Error())
+	}
+
+	return transform.NewReader(br, unicode.BOMOverride(enc.NewDecoder())), nil
+}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"os"
 	"testing"
+	"testing/fstest"
 )
 
 // expected result
@@ -97,3 +98,57 @@ func TestUtf8Reader(t *testing.T) {
 
 	checkString("tst_win1252.txt + tst_win1251.txt:", buf.String(), expectedUtf8Test)
 }
+
+func TestFsFileToUtf8(t *testing.T) {
+
+	// compare result and report error
+	checkString := func(name, val, expected string) {
+		if val != expected {
+			t.Errorf("%s: INVALID \n:%s:", name, val)
+		}
+	}
+
+	// test: read file content from file system to UTF-8 string, auto detect encoding
+	fsys := os.DirFS("testdata")
+
+	for _, name := range []string{
+		"tst_utf8_no_bom.txt", "tst_utf8_bom.txt", "tst_utf16_LE.txt", "tst_utf16_BE.txt", "tst_utf32_LE.txt", "tst_utf32_BE.txt",
+	} {
+		s, err := FsFileToUtf8(fsys, name, "")
+		if err != nil {
+			t.Error(err)
+		}
+		checkString(name, s, expectedUtf8Test)
+	}
+
+	// test: explicit encoding, source is not seekable
+	b1, err := os.ReadFile("testdata/tst_win1252.txt")
+	if err != nil {
+		t.Error(err)
+	}
+	b2, err := os.ReadFile("testdata/tst_win1251.txt")
+	if err != nil {
+		t.Error(err)
+	}
+	mfs := fstest.MapFS{
+		"1252.txt":  &fstest.MapFile{Data: b1},
+		"1251.txt":  &fstest.MapFile{Data: b2},
+		"empty.txt": &fstest.MapFile{Data: []byte{}},
+	}
+
+	s1, err := FsFileToUtf8(mfs, "1252.txt", "windows-1252")
+	if err != nil {
+		t.Error(err)
+	}
+	s2, err := FsFileToUtf8(mfs, "1251.txt", "windows-1251")
+	if err != nil {
+		t.Error(err)
+	}
+	checkString("tst_win1252.txt + tst_win1251.txt:", s1+"\r\n"+s2, expectedUtf8Test)
+
+	s, err := FsFileToUtf8(mfs, "empty.txt", "")
+	if err != nil {
+		t.Error(err)
+	}
+	checkString("empty.txt", s, "")
+}
@@ -0,0 +1,377 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package helper
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// XlsxMaxColumn is max number of columns in xlsx sheet, last Excel column is XFD.
+const XlsxMaxColumn = 16384
+
+// XlsxReader is a minimal reader of Excel .xlsx workbook: list of sheet names and sheet cell values as strings.
+//
+// Cell formatting, formulas and styles are ignored, only cell values are read:
+// shared strings, inline strings, numbers and booleans.
+type XlsxReader struct {
+	zr     *zip.Reader // xlsx workbook is a zip archive
+	closer io.Closer   // if not nil then workbook file opened by OpenXlsx()
+	sheets []xlsxSheet // workbook sheets: name and path inside of zip archive
+	strs   []string    // workbook shared strings
+}
+
+// workbook sheet name and path to sheet xml inside of zip archive
+type xlsxSheet struct {
+	name string // sheet name
+	path string // path inside of zip archive, e.g.: xl/worksheets/sheet1.xml
+}
+
+// xl/workbook.xml: list of sheets
+type xlsxWorkbookXml struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RId  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xl/_rels/workbook.xml.rels: map relationship id to the sheet xml file
+type xlsxRelsXml struct {
+	Rels []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// shared string item or inline string: plain text or list of rich text runs
+type xlsxSi struct {
+	T *string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+// xl/sharedStrings.xml: list of shared strings
+type xlsxSstXml struct {
+	Si []xlsxSi `xml:"si"`
+}
+
+// sheet cell: reference, type and value
+type xlsxC struct {
+	R  string  `xml:"r,attr"`
+	T  string  `xml:"t,attr"`
+	V  string  `xml:"v"`
+	Is *xlsxSi `xml:"is"`
+}
+
+// OpenXlsx open .xlsx workbook file, read list of sheets and shared strings.
+// Caller must Close() reader to release workbook file.
+func OpenXlsx(filePath string) (*XlsxReader, error) {
+
+	zrc, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, errors.New("xlsx file open error: " + filePath + ": " + err.Error())
+	}
+
+	xr, err := newXlsxReader(&zrc.Reader)
+	if err != nil {
+		zrc.Close()
+		return nil, errors.New("xlsx file read error: " + filePath + ": " + err.Error())
+	}
+	xr.closer = zrc
+
+	return xr, nil
+}
+
+// NewXlsxReader create reader of .xlsx workbook from the source, for example from uploaded file.
+// Size is a source size in bytes.
+func NewXlsxReader(r io.ReaderAt, size int64) (*XlsxReader, error) {
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.New("xlsx read error: " + err.Error())
+	}
+	return newXlsxReader(zr)
+}
+
+// Close workbook file if it was opened by OpenXlsx.
+func (xr *XlsxReader) Close() error {
+	if xr.closer != nil {
+		return xr.closer.Close()
+	}
+	return nil
+}
+
+// SheetNames return list of workbook sheet names in workbook order.
+func (xr *XlsxReader) SheetNames() []string {
+
+	nl := make([]string, len(xr.sheets))
+	for k := range xr.sheets {
+		nl[k] = xr.sheets[k].name
+	}
+	return nl
+}
+
+// ReadSheet read sheet rows and pass each row to cvt() as slice of cell values.
+//
+// Row number is one-based row number in the sheet, e.g. 1 is a first row.
+// Empty rows are skipped and empty cells are "" empty strings.
+// Boolean cells converted into "true" or "false", numbers and strings passed as is.
+// Row slice is reused between cvt() calls, cvt() must copy it if row values must be kept.
+// cvt() return true to continue or false to stop rows processing.
+func (xr *XlsxReader) ReadSheet(name string, cvt func(row []string, rowNum int) (bool, error)) error {
+
+	// find sheet by name
+	sp := ""
+	for k := range xr.sheets {
+		if xr.sheets[k].name == name {
+			sp = xr.sheets[k].path
+			break
+		}
+	}
+	if sp == "" {
+		return errors.New("xlsx sheet not found: " + name)
+	}
+
+	f, err := xr.zr.Open(sp)
+	if err != nil {
+		return errors.New("xlsx sheet open error: " + name + ": " + err.Error())
+	}
+	defer f.Close()
+
+	// read sheet rows: <row r="1"><c r="A1" t="s"><v>0</v></c></row>
+	dec := xml.NewDecoder(f)
+	var row []string
+	nRow := 0
+	isRow := false
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.New("xlsx sheet read error: " + name + ": " + err.Error())
+		}
+
+		switch t := tok.(type) {
+
+		case xml.StartElement:
+
+			switch t.Name.Local {
+			case "row":
+				isRow = true
+				row = row[:0]
+				nRow++
+				for _, a := range t.Attr {
+					if a.Name.Local == "r" {
+						if n, e := strconv.Atoi(a.Value); e == nil {
+							nRow = n
+						}
+					}
+				}
+
+			case "c":
+				if !isRow {
+					continue
+				}
+				var c xlsxC
+				if e := dec.DecodeElement(&c, &t); e != nil {
+					return errors.New("xlsx sheet cell read error: " + name + " " + XlsxCellRef(nRow, len(row)) + ": " + e.Error())
+				}
+
+				// cell position: column index from cell reference or next cell
+				nCol := len(row)
+				if c.R != "" {
+					_, col, ok := parseXlsxCellRef(c.R)
+					if !ok {
+						return errors.New("xlsx sheet invalid cell reference: " + name + " " + c.R)
+					}
+					nCol = col
+				}
+				if nCol >= XlsxMaxColumn {
+					return errors.New("xlsx sheet has too many columns: " + name + " " + XlsxCellRef(nRow, nCol))
+				}
+				for len(row) <= nCol {
+					row = append(row, "")
+				}
+
+				v, e := xr.cellValue(&c)
+				if e != nil {
+					return errors.New("xlsx sheet cell read error: " + name + " " + XlsxCellRef(nRow, nCol) + ": " + e.Error())
+				}
+				row[nCol] = v
+			}
+
+		case xml.EndElement:
+
+			if t.Name.Local == "row" && isRow {
+				isRow = false
+
+				// skip empty rows
+				isEmpty := true
+				for k := 0; isEmpty && k < len(row); k++ {
+					isEmpty = row[k] == ""
+				}
+				if isEmpty {
+					continue
+				}
+
+				isNext, e := cvt(row, nRow)
+				if e != nil {
+					return e
+				}
+				if !isNext {
+					return nil
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// XlsxCellRef return cell reference, e.g.: C5 from one-based row number and zero-based column index.
+func XlsxCellRef(rowNum, colIdx int) string {
+
+	if colIdx < 0 {
+		return strconv.Itoa(rowNum)
+	}
+
+	// convert column index into letters: 0 => A, 25 => Z, 26 => AA
+	col := ""
+	for n := colIdx + 1; n > 0; n = (n - 1) / 26 {
+		col = string(rune('A'+(n-1)%26)) + col
+	}
+	return col + strconv.Itoa(rowNum)
+}
+
+// parse cell reference, e.g.: C5 and return one-based row number and zero-based column index.
+// Column must not be after last Excel column XFD.
+func parseXlsxCellRef(ref string) (int, int, bool) {
+
+	nCol := 0
+	k := 0
+	for ; k < len(ref) && ref[k] >= 'A' && ref[k] <= 'Z'; k++ {
+		nCol = nCol*26 + int(ref[k]-'A'+1)
+		if nCol > XlsxMaxColumn {
+			return 0, 0, false
+		}
+	}
+	if k == 0 || k >= len(ref) {
+		return 0, 0, false
+	}
+
+	nRow, err := strconv.Atoi(ref[k:])
+	if err != nil {
+		return 0, 0, false
+	}
+	return nRow, nCol - 1, true
+}
+
+// create xlsx reader: read workbook sheets list and shared strings
+func newXlsxReader(zr *zip.Reader) (*XlsxReader, error) {
+
+	xr := &XlsxReader{zr: zr, sheets: []xlsxSheet{}, strs: []string{}}
+
+	// read workbook sheets and relationships to find sheet xml path
+	var wb xlsxWorkbookXml
+	if ok, err := xr.readXml("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("invalid xlsx workbook, not found: xl/workbook.xml")
+	}
+
+	var rels xlsxRelsXml
+	if _, err := xr.readXml("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+
+	for _, s := range wb.Sheets {
+
+		p := ""
+		for _, r := range rels.Rels {
+			if r.Id == s.RId {
+				if strings.HasPrefix(r.Target, "/") {
+					p = strings.TrimPrefix(r.Target, "/")
+				} else {
+					p = path.Join("xl", r.Target)
+				}
+				break
+			}
+		}
+		if p == "" {
+			return nil, errors.New("invalid xlsx workbook, sheet not found: " + s.Name)
+		}
+		xr.sheets = append(xr.sheets, xlsxSheet{name: s.Name, path: p})
+	}
+
+	// read shared strings, it is optional
+	var sst xlsxSstXml
+	if _, err := xr.readXml("xl/sharedStrings.xml", &sst); err != nil {
+		return nil, err
+	}
+	for k := range sst.Si {
+		xr.strs = append(xr.strs, sst.Si[k].text())
+	}
+
+	return xr, nil
+}
+
+// read xml file from workbook zip archive, return false if file not exist.
+func (xr *XlsxReader) readXml(name string, dst interface{}) (bool, error) {
+
+	f, err := xr.zr.Open(name)
+	if err != nil {
+		return false, nil // file not exist
+	}
+	defer f.Close()
+
+	if err = xml.NewDecoder(f).Decode(dst); err != nil {
+		return true, errors.New("xlsx read error: " + name + ": " + err.Error())
+	}
+	return true, nil
+}
+
+// return cell value as string
+func (xr *XlsxReader) cellValue(c *xlsxC) (string, error) {
+
+	switch c.T {
+	case "s": // shared string
+		n, err := strconv.Atoi(strings.TrimSpace(c.V))
+		if err != nil || n < 0 || n >= len(xr.strs) {
+			return "", errors.New("invalid shared string index: " + c.V)
+		}
+		return xr.strs[n], nil
+	case "inlineStr":
+		if c.Is != nil {
+			return c.Is.text(), nil
+		}
+		return "", nil
+	case "b":
+		if strings.TrimSpace(c.V) == "1" {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	return c.V, nil // number, formula string or error value
+}
+
+// return text of shared string item or inline string
+func (si *xlsxSi) text() string {
+
+	if si.T != nil {
+		return *si.T
+	}
+	s := ""
+	for k := range si.R {
+		s += si.R[k].T
+	}
+	return s
+}
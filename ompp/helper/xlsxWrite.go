@@ -0,0 +1,271 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package helper
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// XlsxMaxSheetName is max length of xlsx sheet name, Excel does not allow longer names.
+const XlsxMaxSheetName = 31
+
+// XlsxWriter is a minimal streaming writer of Excel .xlsx workbook.
+//
+// Each sheet rows are written directly into workbook zip archive and not kept in memory,
+// sheets must be written one by one: AddSheet() and WriteRow() for each sheet row.
+// Cell values are written as numbers or inline strings, no shared strings, no styles.
+type XlsxWriter struct {
+	zw     *zip.Writer   // xlsx workbook is a zip archive
+	closer io.Closer     // if not nil then workbook file created by CreateXlsx()
+	sheets []string      // workbook sheet names
+	wr     *bufio.Writer // current sheet writer, nil if there is no sheet started
+	nRow   int           // current sheet last row number
+}
+
+// CreateXlsx create new .xlsx workbook file.
+// Caller must Close() writer to complete workbook and close the file.
+func CreateXlsx(filePath string) (*XlsxWriter, error) {
+
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.New("xlsx file create error: " + filePath + ": " + err.Error())
+	}
+
+	xw := NewXlsxWriter(f)
+	xw.closer = f
+	return xw, nil
+}
+
+// NewXlsxWriter create writer of .xlsx workbook into the output stream, for example into http response.
+// Caller must Close() writer to complete workbook, output stream is not closed.
+func NewXlsxWriter(w io.Writer) *XlsxWriter {
+	return &XlsxWriter{zw: zip.NewWriter(w), sheets: []string{}}
+}
+
+// AddSheet complete current sheet and start new sheet.
+// Sheet name is cleaned from invalid characters, truncated to 31 characters and made unique in the workbook.
+// It return actual sheet name.
+func (xw *XlsxWriter) AddSheet(name string) (string, error) {
+
+	if err := xw.endSheet(); err != nil {
+		return "", err
+	}
+
+	// make unique sheet name, Excel sheet names are case-insensitive
+	sn := XlsxUniqueSheetName(name, xw.sheets)
+	xw.sheets = append(xw.sheets, sn)
+
+	// start sheet xml
+	w, err := xw.zw.Create("xl/worksheets/sheet" + strconv.Itoa(len(xw.sheets)) + ".xml")
+	if err != nil {
+		return "", errors.New("xlsx sheet create error: " + sn + ": " + err.Error())
+	}
+	xw.wr = bufio.NewWriter(w)
+	xw.nRow = 0
+
+	_, err = xw.wr.WriteString(xml.Header +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return "", errors.New("xlsx sheet write error: " + sn + ": " + err.Error())
+	}
+	return sn, nil
+}
+
+// WriteRow append row to the current sheet.
+//
+// If isNum[k] is true and value is a number then cell written as number else as string.
+// isNum can be nil or shorter than row: all other cells are strings. Empty "" cells are skipped.
+func (xw *XlsxWriter) WriteRow(row []string, isNum []bool) error {
+
+	if xw.wr == nil {
+		return errors.New("xlsx write error: sheet not started")
+	}
+	xw.nRow++
+	sr := strconv.Itoa(xw.nRow)
+
+	xw.wr.WriteString(`<row r="` + sr + `">`)
+
+	for k, v := range row {
+		if v == "" {
+			continue
+		}
+		ref := XlsxCellRef(xw.nRow, k)
+
+		if k < len(isNum) && isNum[k] {
+			if f, e := strconv.ParseFloat(v, 64); e == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+				xw.wr.WriteString(`<c r="` + ref + `"><v>` + v + `</v></c>`)
+				continue
+			}
+		}
+		xw.wr.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(xw.wr, []byte(v))
+		xw.wr.WriteString(`</t></is></c>`)
+	}
+
+	if _, err := xw.wr.WriteString(`</row>`); err != nil {
+		return errors.New("xlsx row write error: " + err.Error())
+	}
+	return nil
+}
+
+// Close complete current sheet, write workbook sheets list and close the file if it was created by CreateXlsx.
+func (xw *XlsxWriter) Close() error {
+
+	err := xw.endSheet()
+
+	if err == nil && len(xw.sheets) <= 0 { // workbook must have at least one sheet
+		if _, err = xw.AddSheet("Sheet1"); err == nil {
+			err = xw.endSheet()
+		}
+	}
+	if err == nil {
+		err = xw.writeWorkbook()
+	}
+	if e := xw.zw.Close(); err == nil && e != nil {
+		err = errors.New("xlsx write error: " + e.Error())
+	}
+	if xw.closer != nil {
+		if e := xw.closer.Close(); err == nil && e != nil {
+			err = errors.New("xlsx file close error: " + e.Error())
+		}
+		xw.closer = nil
+	}
+	return err
+}
+
+// XlsxUniqueSheetName return valid sheet name which is unique in the list of existing sheet names.
+// If sheet name already exist then name truncated and ~1, ~2,... suffix appended, Excel sheet names are case-insensitive.
+func XlsxUniqueSheetName(name string, sheets []string) string {
+
+	isUnique := func(s string) bool {
+		for k := range sheets {
+			if strings.EqualFold(sheets[k], s) {
+				return false
+			}
+		}
+		return true
+	}
+	sn := XlsxSheetName(name)
+
+	for n := 1; !isUnique(sn); n++ {
+		sfx := "~" + strconv.Itoa(n)
+		sn = truncateUtf8(XlsxSheetName(name), XlsxMaxSheetName-len(sfx)) + sfx
+	}
+	return sn
+}
+
+// XlsxSheetName return valid sheet name: replace : \ / ? * [ ] by _ underscore and truncate to 31 characters.
+func XlsxSheetName(name string) string {
+
+	sn := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	sn = strings.Trim(truncateUtf8(sn, XlsxMaxSheetName), "'")
+
+	if sn == "" {
+		return "_"
+	}
+	return sn
+}
+
+// complete current sheet: write sheet xml end and flush sheet rows
+func (xw *XlsxWriter) endSheet() error {
+
+	if xw.wr == nil {
+		return nil
+	}
+	xw.wr.WriteString(`</sheetData></worksheet>`)
+
+	err := xw.wr.Flush()
+	xw.wr = nil
+	if err != nil {
+		return errors.New("xlsx sheet write error: " + err.Error())
+	}
+	return nil
+}
+
+// write workbook content types, relationships and sheets list
+func (xw *XlsxWriter) writeWorkbook() error {
+
+	write := func(name string, content string) error {
+		w, err := xw.zw.Create(name)
+		if err == nil {
+			_, err = io.WriteString(w, xml.Header+content)
+		}
+		if err != nil {
+			return errors.New("xlsx write error: " + name + ": " + err.Error())
+		}
+		return nil
+	}
+
+	ct := `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`
+	wb := `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"` +
+		` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`
+	rels := `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`
+
+	for k := range xw.sheets {
+		n := strconv.Itoa(k + 1)
+
+		ct += `<Override PartName="/xl/worksheets/sheet` + n + `.xml"` +
+			` ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`
+
+		var sb strings.Builder
+		xml.EscapeText(&sb, []byte(xw.sheets[k]))
+		wb += `<sheet name="` + sb.String() + `" sheetId="` + n + `" r:id="rId` + n + `"/>`
+
+		rels += `<Relationship Id="rId` + n + `"` +
+			` Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"` +
+			` Target="worksheets/sheet` + n + `.xml"/>`
+	}
+	ct += `</Types>`
+	wb += `</sheets></workbook>`
+	rels += `</Relationships>`
+
+	if err := write("[Content_Types].xml", ct); err != nil {
+		return err
+	}
+	err := write("_rels/.rels",
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+			`<Relationship Id="rId1"`+
+			` Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"`+
+			` Target="xl/workbook.xml"/>`+
+			`</Relationships>`)
+	if err != nil {
+		return err
+	}
+	if err = write("xl/workbook.xml", wb); err != nil {
+		return err
+	}
+	return write("xl/_rels/workbook.xml.rels", rels)
+}
+
+// truncate string to max bytes length without breaking utf-8 characters
+func truncateUtf8(s string, maxLen int) string {
+
+	if len(s) <= maxLen {
+		return s
+	}
+	n := 0
+	for k := range s {
+		if k > maxLen {
+			break
+		}
+		n = k
+	}
+	return s[:n]
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package helper
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestXlsxCellRef(t *testing.T) {
+
+	for _, tc := range []struct {
+		row, col int
+		ref      string
+	}{
+		{1, 0, "A1"}, {5, 2, "C5"}, {10, 25, "Z10"}, {7, 26, "AA7"}, {3, 27, "AB3"}, {2, 701, "ZZ2"}, {1, 702, "AAA1"}, {4, 16383, "XFD4"},
+	} {
+		if ref := XlsxCellRef(tc.row, tc.col); ref != tc.ref {
+			t.Errorf("XlsxCellRef(%d, %d): %s expected: %s", tc.row, tc.col, ref, tc.ref)
+		}
+		row, col, ok := parseXlsxCellRef(tc.ref)
+		if !ok || row != tc.row || col != tc.col {
+			t.Errorf("parseXlsxCellRef(%s): %d %d %t expected: %d %d", tc.ref, row, col, ok, tc.row, tc.col)
+		}
+	}
+
+	// invalid references and columns after XFD
+	for _, ref := range []string{"", "A", "12", "XFE1", "ZZZZZZZ1", "ZZZZZZZZZZZZZZZZZZZZ1"} {
+		if _, _, ok := parseXlsxCellRef(ref); ok {
+			t.Errorf("parseXlsxCellRef(%s) expected to fail", ref)
+		}
+	}
+}
+
+func TestXlsxReader(t *testing.T) {
+
+	// make minimal workbook in memory: two sheets, shared strings, inline string, boolean and missing cells
+	files := map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="ageSex" sheetId="1" r:id="rId1"/><sheet name="isOldAge" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>sub_id</t></si><si><t>dim0</t></si><si><r><t>param_</t></r><r><t>value</t></r></si><si><t>10-20</t></si>
+</sst>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c></row>
+<row r="2"><c r="A2"><v>0</v></c><c r="B2" t="s"><v>3</v></c><c r="C2"><v>0.5</v></c></row>
+<row r="3"></row>
+<row r="5"><c r="A5"><v>1</v></c><c r="C5" t="inlineStr"><is><t>abc</t></is></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="B1" t="b"><v>1</v></c><c r="C1" t="b"><v>0</v></c></row>
+</sheetData></worksheet>`,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	xr, err := NewXlsxReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xr.Close()
+
+	if nl := strings.Join(xr.SheetNames(), ","); nl != "ageSex,isOldAge" {
+		t.Errorf("sheet names: %s expected: ageSex,isOldAge", nl)
+	}
+
+	// read sheet rows and compare with expected
+	readSheet := func(name string) []string {
+		rows := []string{}
+		err := xr.ReadSheet(name, func(row []string, rowNum int) (bool, error) {
+			rows = append(rows, XlsxCellRef(rowNum, 0)+":"+strings.Join(row, ","))
+			return true, nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		return rows
+	}
+
+	if r := strings.Join(readSheet("ageSex"), "|"); r != "A1:sub_id,dim0,param_value|A2:0,10-20,0.5|A5:1,,abc" {
+		t.Errorf("ageSex rows: %s", r)
+	}
+	if r := strings.Join(readSheet("isOldAge"), "|"); r != "A1:,true,false" {
+		t.Errorf("isOldAge rows: %s", r)
+	}
+
+	if err = xr.ReadSheet("notExist", func(row []string, rowNum int) (bool, error) { return true, nil }); err == nil {
+		t.Error("expected error for sheet: notExist")
+	}
+}
+
+func TestXlsxWriter(t *testing.T) {
+
+	// write workbook: two sheets, duplicate and invalid sheet names, numbers, strings and empty cells
+	var buf bytes.Buffer
+	xw := NewXlsxWriter(&buf)
+
+	sheetRows := map[string][][]string{}
+	for _, s := range []struct {
+		name   string
+		expect string
+		rows   [][]string
+	}{
+		{"ageSex", "ageSex", [][]string{{"sub_id", "dim0", "param_value"}, {"0", "10-20", "0.5"}, {"1", "", "<a & b>"}}},
+		{"ageSex", "ageSex~1", [][]string{{"x"}}},
+		{"Very/long:name[of]the_sheet_over_31_chars", "Very_long_name_of_the_sheet_ove", [][]string{{"1e-3", "NaN"}}},
+	} {
+		sn, err := xw.AddSheet(s.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sn != s.expect {
+			t.Errorf("sheet name: %s expected: %s", sn, s.expect)
+		}
+		for _, r := range s.rows {
+			if err = xw.WriteRow(r, []bool{true, false, true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sheetRows[sn] = s.rows
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// read workbook and compare with source rows
+	xr, err := NewXlsxReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xr.Close()
+
+	if nl := strings.Join(xr.SheetNames(), "|"); nl != "ageSex|ageSex~1|Very_long_name_of_the_sheet_ove" {
+		t.Errorf("sheet names: %s", nl)
+	}
+	for sn, src := range sheetRows {
+		n := 0
+		err := xr.ReadSheet(sn, func(row []string, rowNum int) (bool, error) {
+			if n >= len(src) || strings.Join(row, ",") != strings.Join(src[n], ",") {
+				t.Errorf("sheet %s row %d: %q", sn, rowNum, row)
+			}
+			n++
+			return true, nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if n != len(src) {
+			t.Errorf("sheet %s rows count: %d expected: %d", sn, n, len(src))
+		}
+	}
+}
@@ -44,11 +44,23 @@ func PackZip(srcPath string, isCleanDstDir bool, dstDir string) (string, error)
 	}
 	defer zf.Close()
 
-	zwr := zip.NewWriter(zf)
-	defer zwr.Close()
+	if err = PackZipTo(zf, srcPath); err != nil {
+		return "", err
+	}
+	return zipPath, nil
+}
+
+// PackZipTo write zip archive of specified file or directory and all subdirs into output stream, for example into http response.
+// Archive names are relative to source base directory, the same as in PackZip().
+func PackZipTo(w io.Writer, srcPath string) error {
+
+	cleanPath := filepath.Clean(srcPath)
+	baseDir := filepath.Dir(cleanPath)
+
+	zwr := zip.NewWriter(w)
 
 	// walk in source directory and compress files and subdirs
-	err = filepath.WalkDir(cleanPath, func(src string, de fs.DirEntry, err error) error {
+	err := filepath.WalkDir(cleanPath, func(src string, de fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -80,10 +92,34 @@ func PackZip(srcPath string, isCleanDstDir bool, dstDir string) (string, error)
 		return err
 	})
 
+	if e := zwr.Close(); err == nil && e != nil {
+		err = e
+	}
 	if err != nil {
-		return "", errors.New("failed pack to zip: " + err.Error())
+		return errors.New("failed pack to zip: " + err.Error())
 	}
-	return zipPath, nil
+	return nil
+}
+
+// OpenZipFS open zip archive as read-only file system to read files without unpacking archive on disk.
+// If rootDir is not "" empty then it is a root directory of result file system, e.g.: "modelOne" for modelOne.zip.
+// Caller must close zip archive by returned io.Closer.
+func OpenZipFS(zipPath string, rootDir string) (fs.FS, io.Closer, error) {
+
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, nil, errors.New("open zip file failed: " + err.Error())
+	}
+	if rootDir == "" || rootDir == "." {
+		return zr, zr, nil
+	}
+
+	fsys, err := fs.Sub(zr, rootDir)
+	if err != nil {
+		zr.Close()
+		return nil, nil, errors.New("invalid zip root directory: " + rootDir + " : " + err.Error())
+	}
+	return fsys, zr, nil
 }
 
 // UnpackZip unpack zip archive into specified directory, creating it if not exist.
@@ -0,0 +1,78 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package helper
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenZipFS(t *testing.T) {
+
+	// make source directory: m1/m1.json and m1/set.Default/parameters/p1.csv
+	srcDir := filepath.Join(t.TempDir(), "m1")
+	pDir := filepath.Join(srcDir, "set.Default", "parameters")
+
+	if err := os.MkdirAll(pDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "m1.json"), []byte(`{"Name":"m1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pDir, "p1.csv"), []byte("sub_id,value\r\n0,1\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// pack into m1.zip and read files from the archive without unpacking
+	zipPath, err := PackZip(srcDir, true, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(zipPath) != "m1.zip" {
+		t.Errorf("invalid zip name: %s", zipPath)
+	}
+
+	fsys, zc, err := OpenZipFS(zipPath, "m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zc.Close()
+
+	var m struct{ Name string }
+	isExist, err := FromJsonFs(fsys, "m1.json", &m)
+	if err != nil {
+		t.Error(err)
+	}
+	if !isExist || m.Name != "m1" {
+		t.Errorf("invalid m1.json: %v %v", isExist, m)
+	}
+
+	isExist, err = FromJsonFs(fsys, "not-exist.json", &m)
+	if err != nil || isExist {
+		t.Errorf("expected not exist json: %v %v", isExist, err)
+	}
+
+	s, err := FsFileToUtf8(fsys, "set.Default/parameters/p1.csv", "")
+	if err != nil {
+		t.Error(err)
+	}
+	if s != "sub_id,value\r\n0,1\r\n" {
+		t.Errorf("invalid p1.csv: %s", s)
+	}
+
+	fl, err := fs.Glob(fsys, "set.*")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(fl) != 1 || fl[0] != "set.Default" {
+		t.Errorf("invalid set.* glob: %v", fl)
+	}
+
+	// invalid root directory
+	if _, _, err = OpenZipFS(zipPath, "../m1"); err == nil {
+		t.Error("expected error on invalid zip root directory")
+	}
+}
@@ -151,6 +151,49 @@ func (me *ModelMetaEncoder) DoEncode(isPack bool, je *json.Encoder) error {
 	return je.Encode(mcp)
 }
 
+// SetDerived append derived measures to output tables and entities.
+// Measure label and notes are in user preferred language, model default language or first language of the measure.
+func (me *ModelMetaEncoder) SetDerived(dmLst []db.DerivedMeasure) {
+
+	for k := range dmLst {
+
+		dn := DerivedDescrNote{Derived: &dmLst[k]}
+
+		if len(dmLst[k].Txt) > 0 {
+
+			var nf, i int
+			for ; i < len(dmLst[k].Txt); i++ {
+				if dmLst[k].Txt[i].LangCode == me.preferedLangCode {
+					break // language match
+				}
+				if dmLst[k].Txt[i].LangCode == me.defaultLangCode {
+					nf = i // index of default language
+				}
+			}
+			if i >= len(dmLst[k].Txt) {
+				i = nf // use default language or zero index row
+			}
+			dn.DescrNote = dmLst[k].Txt[i]
+		}
+
+		if dmLst[k].TableName != "" {
+			for j := range me.MetaDescrNote.TableTxt {
+				if me.MetaDescrNote.TableTxt[j].Table.Name == dmLst[k].TableName {
+					me.MetaDescrNote.TableTxt[j].TableDerived = append(me.MetaDescrNote.TableTxt[j].TableDerived, dn)
+					break
+				}
+			}
+		} else {
+			for j := range me.MetaDescrNote.EntityTxt {
+				if me.MetaDescrNote.EntityTxt[j].Entity.Name == dmLst[k].EntityName {
+					me.MetaDescrNote.EntityTxt[j].EntityDerived = append(me.MetaDescrNote.EntityTxt[j].EntityDerived, dn)
+					break
+				}
+			}
+		}
+	}
+}
+
 // model metadata db rows with language-specific description and notes.
 // It is sliced by one single language, but it can be different single language for each row.
 // It is either user preferred language, model default language, first of the row or empty "" language.
@@ -195,6 +238,7 @@ type TableDescrNote struct {
 	TableDimsTxt []TableDimsDescrNote // output table dimension text rows: table_dims_txt join to model_table_dic
 	TableAccTxt  []TableAccDescrNote  // output table accumulator text rows: table_acc_txt join to model_table_dic
 	TableExprTxt []TableExprDescrNote // output table expression text rows: table_expr_txt join to model_table_dic
+	TableDerived []DerivedDescrNote   // output table derived measures: user-defined calculations stored in model database
 }
 
 // TableDimsDescrNote is join of table_dims, model_table_dic, table_dims_txt
@@ -215,11 +259,18 @@ type TableExprDescrNote struct {
 	DescrNote aDescrNote       // from table_expr_txt
 }
 
+// DerivedDescrNote is derived measure of output table or entity with label and notes
+type DerivedDescrNote struct {
+	Derived   *db.DerivedMeasure // derived measure: name, calculation, output table or entity name
+	DescrNote db.DescrNote       // measure label and notes
+}
+
 // EntityDescrNote is join of entity_dic, model_entity_dic, entity_dic_txt, entity_attr_txt
 type EntityDescrNote struct {
 	Entity        *db.EntityDicRow      // entity row: entity_dic join to model_entity_dic
 	DescrNote     aDescrNote            // from entity_dic_txt
 	EntityAttrTxt []EntityAttrDescrNote // entity attribute text rows: entity_attr, model_entity_dic, entity_attr_txt
+	EntityDerived []DerivedDescrNote    // entity derived measures: user-defined calculations stored in model database
 }
 
 // EntityAttrDescrNote is join of entity_attr, model_entity_dic, entity_attr_txt
@@ -245,7 +296,7 @@ type EntityGroupDescrNote struct {
 func (me *ModelMetaEncoder) New(meta *db.ModelMeta, txtMeta *db.ModelTxtMeta, lc string, lcd string) error {
 
 	if meta == nil || txtMeta == nil {
-		errors.New("Error: invalid (empty) model metadata")
+		return errors.New("Error: invalid (empty) model metadata")
 	}
 	me.preferedLangCode = lc
 	me.defaultLangCode = lcd
@@ -296,6 +347,7 @@ func (me *ModelMetaEncoder) New(meta *db.ModelMeta, txtMeta *db.ModelTxtMeta, lc
 		mt.TableTxt[k].TableDimsTxt = make([]TableDimsDescrNote, len(meta.Table[k].Dim))
 		mt.TableTxt[k].TableAccTxt = make([]TableAccDescrNote, len(meta.Table[k].Acc))
 		mt.TableTxt[k].TableExprTxt = make([]TableExprDescrNote, len(meta.Table[k].Expr))
+		mt.TableTxt[k].TableDerived = []DerivedDescrNote{}
 		mt.TableTxt[k].LangCode = &emptyStr
 		mt.TableTxt[k].TableDescr = &emptyStr
 		mt.TableTxt[k].TableNote = &emptyStr
@@ -328,6 +380,7 @@ func (me *ModelMetaEncoder) New(meta *db.ModelMeta, txtMeta *db.ModelTxtMeta, lc
 	for k := range mt.EntityTxt {
 		mt.EntityTxt[k].Entity = &meta.Entity[k].EntityDicRow
 		mt.EntityTxt[k].EntityAttrTxt = make([]EntityAttrDescrNote, len(meta.Entity[k].Attr))
+		mt.EntityTxt[k].EntityDerived = []DerivedDescrNote{}
 		mt.EntityTxt[k].DescrNote.LangCode = &emptyStr
 		mt.EntityTxt[k].DescrNote.Descr = &emptyStr
 		mt.EntityTxt[k].DescrNote.Note = &emptyStr
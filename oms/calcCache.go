@@ -0,0 +1,271 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/openmpp/go/ompp/db"
+)
+
+const calcCacheDefaultRows = 100000 // default max number of rows in calculation results cache
+
+// calcCache is in-memory cache of output table and microdata calculation results.
+//
+// Values of completed model runs are immutable and calculation results can be reused.
+// Cache key is a model digest, run id's, calculation expressions, filters and page layout.
+// Cache is bounded by total number of rows, least recently used results are evicted first.
+// Results are invalidated when model run deleted or model closed.
+type calcCache struct {
+	theLock    sync.Mutex               // mutex to lock for cache operations
+	maxRows    int                      // max total number of rows in cache, if <= 0 then cache disabled
+	nRows      int                      // current total number of rows in cache
+	itemMap    map[string]*list.Element // map of cache key to element of LRU list
+	lru        *list.List               // list of cached results, most recently used first
+	hitCount   int64                    // number of cache hits
+	missCount  int64                    // number of cache misses
+	evictCount int64                    // number of results evicted from cache
+}
+
+// calculation results cache item
+type calcCacheItem struct {
+	key    string            // cache key
+	digest string            // model digest
+	runIds []int             // model run id's of calculation results
+	cells  []interface{}     // result rows: output table or microdata calculated cells
+	pageLt db.ReadPageLayout // result page layout
+}
+
+// CalcCacheState is public state of calculation results cache: size and hit / miss statistics.
+type CalcCacheState struct {
+	IsEnabled bool  // if true then calculation results cache enabled
+	MaxRows   int   // max total number of rows in cache
+	Rows      int   // current total number of rows in cache
+	Count     int   // number of cached results
+	Hit       int64 // number of cache hits
+	Miss      int64 // number of cache misses
+	Evict     int64 // number of results evicted from cache
+}
+
+// calculation results cache
+var theCalcCache = calcCache{
+	maxRows: calcCacheDefaultRows,
+	itemMap: map[string]*list.Element{},
+	lru:     list.New(),
+}
+
+// set max total number of rows in cache, if <= 0 then cache disabled
+func (cc *calcCache) setMaxRows(maxRows int) {
+
+	cc.theLock.Lock()
+	defer cc.theLock.Unlock()
+
+	cc.maxRows = maxRows
+	cc.evict(0)
+}
+
+// return max total number of rows in cache, if <= 0 then cache disabled
+func (cc *calcCache) getMaxRows() int {
+
+	cc.theLock.Lock()
+	defer cc.theLock.Unlock()
+
+	return cc.maxRows
+}
+
+// return cache key: calculation kind, model digest, run id's and json of calculation and read layout
+func (cc *calcCache) makeKey(kind, digest string, runIds []int, calcLayout ...interface{}) (string, bool) {
+
+	js, err := json.Marshal(calcLayout)
+	if err != nil {
+		return "", false // calculation cannot be cached
+	}
+
+	ids := make([]string, len(runIds))
+	for k := range runIds {
+		ids[k] = strconv.Itoa(runIds[k])
+	}
+	return kind + ":" + digest + ":" + strings.Join(ids, ",") + ":" + string(js), true
+}
+
+// return cached results or read it from database using readDb() and store results in cache.
+// Each result row passed into cvtWr().
+// If cache disabled or calculation results cannot be cached then simply return readDb() results.
+func (cc *calcCache) readTo(
+	kind, digest string, runIds []int, cvtWr func(src interface{}) (bool, error), readDb func(cvtWr func(src interface{}) (bool, error)) (*db.ReadPageLayout, error), calcLayout ...interface{},
+) (*db.ReadPageLayout, error) {
+
+	maxRows := cc.getMaxRows()
+	if maxRows <= 0 {
+		return readDb(cvtWr) // cache disabled
+	}
+	key, ok := cc.makeKey(kind, digest, runIds, calcLayout...)
+	if !ok {
+		return readDb(cvtWr)
+	}
+
+	// if results found in cache then pass each row to the writer
+	if cells, lt, ok := cc.get(key); ok {
+
+		for _, c := range cells {
+			isNext, err := cvtWr(c)
+			if err != nil {
+				return nil, err
+			}
+			if !isNext {
+				break
+			}
+		}
+		return &lt, nil
+	}
+
+	// read from database and collect result rows
+	cells := []interface{}{}
+	isAll := true
+
+	lt, err := readDb(func(src interface{}) (bool, error) {
+
+		isNext, e := cvtWr(src)
+		if e != nil || !isNext {
+			isAll = false // results are incomplete
+		}
+		if isAll && len(cells) < maxRows {
+			cells = append(cells, src)
+		} else {
+			isAll = false // results are too large or incomplete
+		}
+		return isNext, e
+	})
+	if err != nil {
+		return nil, err
+	}
+	if isAll && lt != nil {
+		cc.put(key, digest, runIds, cells, *lt)
+	}
+	return lt, nil
+}
+
+// return cached result rows and page layout by cache key
+func (cc *calcCache) get(key string) ([]interface{}, db.ReadPageLayout, bool) {
+
+	cc.theLock.Lock()
+	defer cc.theLock.Unlock()
+
+	el, ok := cc.itemMap[key]
+	if !ok {
+		cc.missCount++
+		return nil, db.ReadPageLayout{}, false
+	}
+	cc.hitCount++
+	cc.lru.MoveToFront(el)
+
+	ci := el.Value.(*calcCacheItem)
+	return ci.cells, ci.pageLt, true
+}
+
+// store result rows and page layout in cache, evict least recently used results if cache is full
+func (cc *calcCache) put(key, digest string, runIds []int, cells []interface{}, lt db.ReadPageLayout) {
+
+	cc.theLock.Lock()
+	defer cc.theLock.Unlock()
+
+	if cc.maxRows <= 0 || len(cells) > cc.maxRows {
+		return // cache disabled or results are too large
+	}
+	if el, ok := cc.itemMap[key]; ok {
+		cc.remove(el) // replace existing results
+	}
+	cc.evict(len(cells))
+
+	ci := &calcCacheItem{
+		key:    key,
+		digest: digest,
+		runIds: slices.Clone(runIds),
+		cells:  cells,
+		pageLt: lt,
+	}
+	cc.itemMap[key] = cc.lru.PushFront(ci)
+	cc.nRows += len(cells)
+}
+
+// evict least recently used results until there is a space for nAdd rows, lock must be acquired by caller
+func (cc *calcCache) evict(nAdd int) {
+
+	for el := cc.lru.Back(); el != nil && cc.nRows+nAdd > cc.maxRows; el = cc.lru.Back() {
+		cc.remove(el)
+		cc.evictCount++
+	}
+}
+
+// remove results from cache, lock must be acquired by caller
+func (cc *calcCache) remove(el *list.Element) {
+
+	ci := el.Value.(*calcCacheItem)
+	cc.nRows -= len(ci.cells)
+	delete(cc.itemMap, ci.key)
+	cc.lru.Remove(el)
+}
+
+// remove all results of model run from cache
+func (cc *calcCache) deleteRun(digest string, runId int) {
+
+	cc.theLock.Lock()
+	defer cc.theLock.Unlock()
+
+	for el := cc.lru.Front(); el != nil; {
+		next := el.Next()
+		if ci := el.Value.(*calcCacheItem); ci.digest == digest && slices.Contains(ci.runIds, runId) {
+			cc.remove(el)
+		}
+		el = next
+	}
+}
+
+// remove all results of the model from cache
+func (cc *calcCache) deleteModel(digest string) {
+
+	cc.theLock.Lock()
+	defer cc.theLock.Unlock()
+
+	for el := cc.lru.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*calcCacheItem).digest == digest {
+			cc.remove(el)
+		}
+		el = next
+	}
+}
+
+// remove all results from cache
+func (cc *calcCache) clear() {
+
+	cc.theLock.Lock()
+	defer cc.theLock.Unlock()
+
+	cc.itemMap = map[string]*list.Element{}
+	cc.lru.Init()
+	cc.nRows = 0
+}
+
+// return public state of cache: size and hit / miss statistics
+func (cc *calcCache) state() CalcCacheState {
+
+	cc.theLock.Lock()
+	defer cc.theLock.Unlock()
+
+	return CalcCacheState{
+		IsEnabled: cc.maxRows > 0,
+		MaxRows:   cc.maxRows,
+		Rows:      cc.nRows,
+		Count:     cc.lru.Len(),
+		Hit:       cc.hitCount,
+		Miss:      cc.missCount,
+		Evict:     cc.evictCount,
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"container/list"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/openmpp/go/ompp/db"
+)
+
+// return new empty calculation results cache
+func newTestCalcCache(maxRows int) *calcCache {
+	return &calcCache{
+		maxRows: maxRows,
+		itemMap: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// return cache keys, most recently used first
+func testCacheKeys(cc *calcCache) []string {
+	keys := []string{}
+	for el := cc.lru.Front(); el != nil; el = el.Next() {
+		keys = append(keys, el.Value.(*calcCacheItem).key)
+	}
+	return keys
+}
+
+// return result rows as cells and database reader which is counting number of database reads
+func testCacheReader(cells []interface{}, nRead *int) func(cvtWr func(src interface{}) (bool, error)) (*db.ReadPageLayout, error) {
+
+	return func(cvtWr func(src interface{}) (bool, error)) (*db.ReadPageLayout, error) {
+		*nRead++
+		for _, c := range cells {
+			isNext, err := cvtWr(c)
+			if err != nil {
+				return nil, err
+			}
+			if !isNext {
+				break
+			}
+		}
+		return &db.ReadPageLayout{Size: int64(len(cells)), IsLastPage: true}, nil
+	}
+}
+
+func TestCalcCacheEviction(t *testing.T) {
+
+	cc := newTestCalcCache(5)
+	lt := db.ReadPageLayout{}
+
+	cc.put("a", "m1", []int{1}, []interface{}{1, 2}, lt)
+	cc.put("b", "m1", []int{2}, []interface{}{1, 2}, lt)
+
+	// most recently used results moved to the front
+	if _, _, ok := cc.get("a"); !ok {
+		t.Fatal("****FAIL: expected cached results: a")
+	}
+	if keys := testCacheKeys(cc); !slices.Equal(keys, []string{"a", "b"}) {
+		t.Error("****FAIL: expected cache order: a, b found:", keys)
+	}
+
+	// least recently used results evicted first
+	cc.put("c", "m1", []int{3}, []interface{}{1, 2}, lt)
+
+	if keys := testCacheKeys(cc); !slices.Equal(keys, []string{"c", "a"}) {
+		t.Error("****FAIL: expected cache order: c, a found:", keys)
+	}
+	if _, _, ok := cc.get("b"); ok {
+		t.Error("****FAIL: expected evicted results: b")
+	}
+	st := cc.state()
+	if st.Rows != 4 || st.Count != 2 || st.Evict != 1 || st.Hit != 1 || st.Miss != 1 {
+		t.Error("****FAIL: invalid cache state:", st)
+	}
+
+	// results larger than cache are not stored
+	cc.put("d", "m1", []int{4}, []interface{}{1, 2, 3, 4, 5, 6}, lt)
+	if keys := testCacheKeys(cc); !slices.Equal(keys, []string{"c", "a"}) {
+		t.Error("****FAIL: expected cache order: c, a found:", keys)
+	}
+
+	// replace existing results and evict all others
+	cc.put("a", "m1", []int{1}, []interface{}{1, 2, 3, 4, 5}, lt)
+	if keys := testCacheKeys(cc); !slices.Equal(keys, []string{"a"}) || cc.state().Rows != 5 {
+		t.Error("****FAIL: expected only replaced results: a found:", keys, cc.state())
+	}
+
+	// reduce cache size: results evicted, disable cache: all results evicted
+	cc.setMaxRows(4)
+	if st = cc.state(); st.Count != 0 || st.Rows != 0 || !st.IsEnabled {
+		t.Error("****FAIL: expected empty enabled cache:", st)
+	}
+	cc.put("a", "m1", []int{1}, []interface{}{1}, lt)
+	cc.setMaxRows(0)
+	if st = cc.state(); st.Count != 0 || st.Rows != 0 || st.IsEnabled {
+		t.Error("****FAIL: expected empty disabled cache:", st)
+	}
+}
+
+func TestCalcCacheReadTo(t *testing.T) {
+
+	cc := newTestCalcCache(10)
+	cells := []interface{}{"r1", "r2", "r3"}
+	nRead := 0
+	readDb := testCacheReader(cells, &nRead)
+
+	// incomplete results are not cached: reader stopped after first row
+	rLst := []interface{}{}
+	_, err := cc.readTo("table", "m1", []int{1}, func(src interface{}) (bool, error) {
+		rLst = append(rLst, src)
+		return false, nil
+	}, readDb, "expr0")
+	if err != nil {
+		t.Fatal("****FAIL: read calculation:", err)
+	}
+	if len(rLst) != 1 || cc.state().Count != 0 {
+		t.Error("****FAIL: expected one row and no cached results, found:", rLst, cc.state())
+	}
+
+	// results are not cached on error
+	errWr := errors.New("write error")
+	_, err = cc.readTo("table", "m1", []int{1}, func(src interface{}) (bool, error) { return true, errWr }, readDb, "expr0")
+	if err != errWr || cc.state().Count != 0 {
+		t.Error("****FAIL: expected error and no cached results, found:", err, cc.state())
+	}
+
+	// complete results cached and next read is from cache
+	readAll := func() []interface{} {
+		rLst := []interface{}{}
+		lt, err := cc.readTo("table", "m1", []int{1}, func(src interface{}) (bool, error) {
+			rLst = append(rLst, src)
+			return true, nil
+		}, readDb, "expr0")
+		if err != nil {
+			t.Fatal("****FAIL: read calculation:", err)
+		}
+		if lt == nil || lt.Size != 3 || !lt.IsLastPage {
+			t.Error("****FAIL: invalid page layout:", lt)
+		}
+		return rLst
+	}
+	nRead = 0
+
+	for k := 0; k < 2; k++ {
+		if rLst = readAll(); !slices.Equal(rLst, cells) {
+			t.Error("****FAIL: expected:", cells, "found:", rLst)
+		}
+	}
+	if nRead != 1 || cc.state().Count != 1 || cc.state().Hit != 1 {
+		t.Error("****FAIL: expected one database read and one cache hit, found:", nRead, cc.state())
+	}
+
+	// different calculation is a different cache key
+	_, err = cc.readTo("table", "m1", []int{1}, func(src interface{}) (bool, error) { return true, nil }, readDb, "expr1")
+	if err != nil {
+		t.Fatal("****FAIL: read calculation:", err)
+	}
+	if nRead != 2 || cc.state().Count != 2 {
+		t.Error("****FAIL: expected two database reads and two cached results, found:", nRead, cc.state())
+	}
+
+	// results larger than cache are not stored
+	small := newTestCalcCache(2)
+	nRead = 0
+	for k := 0; k < 2; k++ {
+		if _, err = small.readTo("table", "m1", []int{1}, func(src interface{}) (bool, error) { return true, nil }, readDb, "expr0"); err != nil {
+			t.Fatal("****FAIL: read calculation:", err)
+		}
+	}
+	if nRead != 2 || small.state().Count != 0 {
+		t.Error("****FAIL: expected two database reads and no cached results, found:", nRead, small.state())
+	}
+
+	// cache disabled: always read from database
+	cc.setMaxRows(0)
+	nRead = 0
+	readAll()
+	readAll()
+	if nRead != 2 {
+		t.Error("****FAIL: expected two database reads from disabled cache, found:", nRead)
+	}
+}
+
+func TestCalcCacheInvalidate(t *testing.T) {
+
+	cc := newTestCalcCache(100)
+	lt := db.ReadPageLayout{}
+
+	cc.put("a", "m1", []int{1}, []interface{}{1}, lt)
+	cc.put("b", "m1", []int{1, 2}, []interface{}{1}, lt)
+	cc.put("c", "m1", []int{2}, []interface{}{1}, lt)
+	cc.put("d", "m2", []int{1}, []interface{}{1}, lt)
+	cc.put("e", "m2", []int{3}, []interface{}{1}, lt)
+
+	// delete model run: all results which are using that run id are removed
+	cc.deleteRun("m1", 1)
+	if keys := testCacheKeys(cc); !slices.Equal(keys, []string{"e", "d", "c"}) {
+		t.Error("****FAIL: expected cache: e, d, c found:", keys)
+	}
+
+	// delete model: all results of the model are removed
+	cc.deleteModel("m2")
+	if keys := testCacheKeys(cc); !slices.Equal(keys, []string{"c"}) || cc.state().Rows != 1 {
+		t.Error("****FAIL: expected cache: c found:", keys, cc.state())
+	}
+
+	// clear cache
+	cc.put("f", "m2", []int{3}, []interface{}{1}, lt)
+	cc.clear()
+	if st := cc.state(); st.Count != 0 || st.Rows != 0 || len(cc.itemMap) != 0 {
+		t.Error("****FAIL: expected empty cache:", st)
+	}
+}
@@ -102,6 +102,21 @@ func getInt64RequestParam(r *http.Request, name string, defaultVal int64) (int64
 	return defaultVal, false // value is not integer
 }
 
+// get user name of http request: basic authentication user name
+// or X-Forwarded-User header if oms is behind trusted reverse proxy or client address
+func getRequestUser(r *http.Request) string {
+
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		return u
+	}
+	if theCfg.isProxyUser {
+		if u := r.Header.Get("X-Forwarded-User"); u != "" {
+			return u
+		}
+	}
+	return r.RemoteAddr
+}
+
 // match request language with UI supported languages and return prefered language name
 // get languages accepted by browser and by optional preferred language request parameter, for example: ..../lang/EN
 func preferedRequestLang(r *http.Request, name string) string {
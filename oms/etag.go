@@ -0,0 +1,161 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// etagOf return ETag of source metadata: quoted md5 digest of json representation.
+// Return empty "" string if source is nil or json marshal failed.
+func etagOf(src interface{}) string {
+
+	if src == nil {
+		return ""
+	}
+	b, err := json.Marshal(src)
+	if err != nil {
+		omppLog.Log("Error at ETag json marshal: ", err.Error())
+		return ""
+	}
+	return "\"" + fmt.Sprintf("%x", md5.Sum(b)) + "\""
+}
+
+// setETag set ETag response header if ETag is not empty
+func setETag(w http.ResponseWriter, etag string) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+}
+
+// isIfMatch return true if request does not have If-Match header or If-Match header match current ETag of the resource.
+// If there is no match then respond with 412 Precondition Failed and return false.
+// If resource does not exist then ETag is empty "" and any If-Match header is a conflict, including If-Match: *
+func isIfMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+
+	im := r.Header.Get("If-Match")
+	if im == "" {
+		return true // no If-Match header: update is not conditional
+	}
+
+	if etag != "" {
+		for _, t := range strings.Split(im, ",") {
+			t = strings.TrimSpace(t)
+			if t == "*" || t == etag {
+				return true
+			}
+		}
+	}
+
+	omppLog.Log("Precondition failed: If-Match: ", im, " ETag: ", etag, " ", r.Method, " ", r.URL)
+	http.Error(w, helper.MsgL(preferedRequestLang(r, ""), "Precondition failed: resource is updated by other user, reload it and try again"), http.StatusPreconditionFailed)
+	return false
+}
+
+// isWorksetIfMatch return true if request does not have If-Match header or If-Match header match current ETag of the workset.
+// If there is no match then respond with 412 Precondition Failed and return false.
+// On success it also return workset update date-time to check it again inside of update transaction,
+// or empty "" string if request does not have If-Match header or it is If-Match: *
+func isWorksetIfMatch(w http.ResponseWriter, r *http.Request, dn, wsn string) (string, bool) {
+
+	var ws *db.WorksetRow
+	etag := ""
+	if dn != "" && wsn != "" {
+		if wr, ok := theCatalog.WorksetByName(dn, wsn); ok && wr != nil {
+			ws = wr
+			etag = etagOf(ws)
+		}
+	}
+	if !isIfMatch(w, r, etag) {
+		return "", false
+	}
+
+	im := strings.TrimSpace(r.Header.Get("If-Match"))
+	if im == "" || im == "*" || ws == nil {
+		return "", true
+	}
+	return ws.UpdateDateTime, true
+}
+
+// isWorksetUpdatedError return true if error is workset update date-time conflict detected inside of update transaction.
+// If it is a conflict then respond with 412 Precondition Failed.
+func isWorksetUpdatedError(w http.ResponseWriter, r *http.Request, err error) bool {
+
+	if err != db.ErrWorksetUpdated {
+		return false
+	}
+	omppLog.Log("Precondition failed: ", err, " ", r.Method, " ", r.URL)
+	http.Error(w, helper.MsgL(preferedRequestLang(r, ""), "Precondition failed: resource is updated by other user, reload it and try again"), http.StatusPreconditionFailed)
+	return true
+}
+
+// WorksetETag return ETag of workset by model digest-or-name and workset name.
+// ETag is derived from workset_lst row, which include update date-time of the workset.
+// Return empty "" string if workset not found.
+func (mc *ModelCatalog) WorksetETag(dn, wsn string) string {
+
+	if dn == "" || wsn == "" {
+		return ""
+	}
+	ws, ok := mc.WorksetByName(dn, wsn)
+	if !ok || ws == nil {
+		return ""
+	}
+	return etagOf(ws)
+}
+
+// RunETag return ETag of model run by model digest-or-name and run digest-or-stamp-or-name.
+// ETag is derived from run digest, run status and run update date-time.
+// Return empty "" string if model run not found.
+func (mc *ModelCatalog) RunETag(dn, rdsn string) string {
+
+	if dn == "" || rdsn == "" {
+		return ""
+	}
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return ""
+	}
+	r, err := db.GetRunByDigestStampName(dbConn.DB, meta.Model.ModelId, rdsn)
+	if err != nil {
+		omppLog.Log("Error at get run ETag: ", dn, ": ", rdsn, ": ", err.Error())
+		return ""
+	}
+	if r == nil {
+		return ""
+	}
+	return runETagOf(r.RunDigest, r.Status, r.UpdateDateTime)
+}
+
+// runETagOf return ETag of model run: quoted md5 digest of run digest, run status and run update date-time.
+func runETagOf(runDigest, status, updateDt string) string {
+	return etagOf(struct {
+		RunDigest      string
+		Status         string
+		UpdateDateTime string
+	}{runDigest, status, updateDt})
+}
+
+// TaskETag return ETag of modeling task definition by model digest-or-name and task name.
+// ETag is derived from task text in all languages and list of task worksets, task run history is not included.
+// Return empty "" string if task not found.
+func (mc *ModelCatalog) TaskETag(dn, tn string) string {
+
+	if dn == "" || tn == "" {
+		return ""
+	}
+	tp, _, ok := mc.TaskTextFull(dn, tn, true, nil)
+	if !ok || tp == nil {
+		return ""
+	}
+	return etagOf(&tp.TaskDefPub)
+}
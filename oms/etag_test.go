@@ -0,0 +1,115 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/openmpp/go/ompp/db"
+)
+
+func TestETag(t *testing.T) {
+
+	if e := etagOf(nil); e != "" {
+		t.Error("****FAIL: expected empty ETag of nil, found:", e)
+	}
+
+	ws := db.WorksetRow{SetId: 1, Name: "ws", UpdateDateTime: "2021-01-01 00:00:00.000"}
+	e1 := etagOf(&ws)
+	if len(e1) != 34 || !strings.HasPrefix(e1, "\"") || !strings.HasSuffix(e1, "\"") {
+		t.Error("****FAIL: expected quoted md5 ETag, found:", e1)
+	}
+	if e := etagOf(&ws); e != e1 {
+		t.Error("****FAIL: expected the same ETag of the same workset:", e1, "found:", e)
+	}
+	ws.UpdateDateTime = "2021-01-01 00:00:01.000"
+	if e := etagOf(&ws); e == e1 {
+		t.Error("****FAIL: expected different ETag of updated workset:", e)
+	}
+
+	// model run ETag changed only by run digest, status and update date-time
+	r1 := runETagOf("d1", db.DoneRunStatus, "2021-01-01 00:00:00.000")
+	if r := runETagOf("d1", db.DoneRunStatus, "2021-01-01 00:00:00.000"); r != r1 {
+		t.Error("****FAIL: expected the same model run ETag:", r1, "found:", r)
+	}
+	for _, r := range []string{
+		runETagOf("d2", db.DoneRunStatus, "2021-01-01 00:00:00.000"),
+		runETagOf("d1", db.ErrorRunStatus, "2021-01-01 00:00:00.000"),
+		runETagOf("d1", db.DoneRunStatus, "2021-01-01 00:00:01.000"),
+	} {
+		if r == r1 {
+			t.Error("****FAIL: expected different model run ETag:", r)
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+
+	uiLangMatcher = language.NewMatcher([]language.Tag{language.English})
+
+	etag := etagOf(&db.WorksetRow{SetId: 1, Name: "ws"})
+
+	// return request with optional If-Match header
+	newRequest := func(ifMatch string) *http.Request {
+		r := httptest.NewRequest(http.MethodPatch, "/api/model/m/workset/ws", nil)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		return r
+	}
+
+	for _, c := range []struct {
+		ifMatch string
+		etag    string
+		isOk    bool
+	}{
+		{"", etag, true},
+		{"", "", true},
+		{etag, etag, true},
+		{"\"other\", " + etag, etag, true},
+		{"*", etag, true},
+		{"\"other\"", etag, false},
+		{"*", "", false},
+		{etag, "", false},
+	} {
+		w := httptest.NewRecorder()
+		isOk := isIfMatch(w, newRequest(c.ifMatch), c.etag)
+
+		if isOk != c.isOk {
+			t.Error("****FAIL: If-Match:", c.ifMatch, "ETag:", c.etag, "expected:", c.isOk, "found:", isOk)
+		}
+		if !c.isOk && w.Code != http.StatusPreconditionFailed {
+			t.Error("****FAIL: If-Match:", c.ifMatch, "ETag:", c.etag, "expected status 412, found:", w.Code)
+		}
+		if c.isOk && w.Code != http.StatusOK {
+			t.Error("****FAIL: If-Match:", c.ifMatch, "ETag:", c.etag, "expected no response, found:", w.Code)
+		}
+	}
+
+	// workset not found: conditional update is a conflict, unconditional update is not checked
+	w := httptest.NewRecorder()
+	if updDt, ok := isWorksetIfMatch(w, newRequest(etag), "", ""); ok || updDt != "" || w.Code != http.StatusPreconditionFailed {
+		t.Error("****FAIL: expected 412 for not found workset, found:", ok, updDt, w.Code)
+	}
+	w = httptest.NewRecorder()
+	if updDt, ok := isWorksetIfMatch(w, newRequest(""), "", ""); !ok || updDt != "" {
+		t.Error("****FAIL: expected success without If-Match, found:", ok, updDt, w.Code)
+	}
+
+	// workset updated by other user inside of update transaction
+	w = httptest.NewRecorder()
+	if !isWorksetUpdatedError(w, newRequest(etag), db.ErrWorksetUpdated) || w.Code != http.StatusPreconditionFailed {
+		t.Error("****FAIL: expected 412 on workset update conflict, found:", w.Code)
+	}
+	w = httptest.NewRecorder()
+	if isWorksetUpdatedError(w, newRequest(etag), errors.New("other error")) || w.Code != http.StatusOK {
+		t.Error("****FAIL: expected no response on other error, found:", w.Code)
+	}
+}
@@ -291,3 +291,262 @@ func (mc *ModelCatalog) EntityGenAttrsRunList(dn string, runId int, entityName s
 
 	return ent, entGen, attrs, runEnt, nil
 }
+
+// DerivedMeasureList return list of derived measures by model digest-or-name.
+func (mc *ModelCatalog) DerivedMeasureList(dn string) ([]db.DerivedMeasure, bool) {
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return []db.DerivedMeasure{}, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.DerivedMeasure{}, false
+	}
+
+	dmLst, err := db.GetDerivedMeasureList(dbConn.DB, meta)
+	if err != nil {
+		omppLog.Log("Error at get derived measures:", dn, ": ", err)
+		return []db.DerivedMeasure{}, false
+	}
+	return dmLst, true
+}
+
+// ParamRuleList return list of parameters validation rules by model digest-or-name.
+func (mc *ModelCatalog) ParamRuleList(dn string) ([]db.ParamRules, bool) {
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return []db.ParamRules{}, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.ParamRules{}, false
+	}
+
+	prLst, err := db.GetParamRuleList(dbConn.DB, meta)
+	if err != nil {
+		omppLog.Log("Error at get parameter rules:", dn, ": ", err)
+		return []db.ParamRules{}, false
+	}
+	return prLst, true
+}
+
+// WorksetImport return provenance of workset parameters imported from upstream model run.
+// If workset was not created by import from upstream model then return is empty list.
+func (mc *ModelCatalog) WorksetImport(dn, wsn string) ([]db.ParamImportSource, bool) {
+
+	// if model digest-or-name or workset name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return []db.ParamImportSource{}, false
+	}
+	if wsn == "" {
+		omppLog.Log("Warning: invalid (empty) workset name")
+		return []db.ParamImportSource{}, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.ParamImportSource{}, false
+	}
+
+	isLst, err := db.GetWorksetImport(dbConn.DB, meta, wsn)
+	if err != nil {
+		omppLog.Log("Error at get workset import:", dn, ": ", wsn, ": ", err)
+		return []db.ParamImportSource{}, false
+	}
+	return isLst, true
+}
+
+// WorksetMergeReport return report of three-way merge which created the workset.
+// If workset was not created by merge then return is nil.
+func (mc *ModelCatalog) WorksetMergeReport(dn, wsn string) (*db.WorksetMergeReport, bool) {
+
+	// if model digest-or-name or workset name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return nil, false
+	}
+	if wsn == "" {
+		omppLog.Log("Warning: invalid (empty) workset name")
+		return nil, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return nil, false
+	}
+
+	rpt, err := db.GetWorksetMergeReport(dbConn.DB, meta, wsn)
+	if err != nil {
+		omppLog.Log("Error at get workset merge report:", dn, ": ", wsn, ": ", err)
+		return nil, false
+	}
+	return rpt, true
+}
+
+// WorksetTemplate return template which was applied to create the workset.
+// If workset was not created from template then return is nil.
+func (mc *ModelCatalog) WorksetTemplate(dn, wsn string) (*db.WorksetTemplate, bool) {
+
+	// if model digest-or-name or workset name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return nil, false
+	}
+	if wsn == "" {
+		omppLog.Log("Warning: invalid (empty) workset name")
+		return nil, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return nil, false
+	}
+
+	tpl, err := db.GetWorksetTemplate(dbConn.DB, meta, wsn)
+	if err != nil {
+		omppLog.Log("Error at get workset template:", dn, ": ", wsn, ": ", err)
+		return nil, false
+	}
+	return tpl, true
+}
+
+// ValidateWorkset check workset parameters values by parameters validation rules and return list of violations.
+func (mc *ModelCatalog) ValidateWorkset(dn, wsn string) ([]db.ParamRuleViolation, bool) {
+
+	// if model digest-or-name or workset name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return []db.ParamRuleViolation{}, false
+	}
+	if wsn == "" {
+		omppLog.Log("Warning: invalid (empty) workset name")
+		return []db.ParamRuleViolation{}, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.ParamRuleViolation{}, false
+	}
+
+	vLst, err := db.ValidateWorkset(dbConn.DB, meta, wsn)
+	if err != nil {
+		omppLog.Log("Error at workset validation:", dn, ": ", wsn, ": ", err)
+		return []db.ParamRuleViolation{}, false
+	}
+	return vLst, true
+}
+
+// WorksetParamSource return sources of workset parameters: model runs or worksets where parameter values copied from.
+func (mc *ModelCatalog) WorksetParamSource(dn, wsn string) ([]db.WorksetParamSource, bool) {
+
+	// if model digest-or-name or workset name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return []db.WorksetParamSource{}, false
+	}
+	if wsn == "" {
+		omppLog.Log("Warning: invalid (empty) workset name")
+		return []db.WorksetParamSource{}, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.WorksetParamSource{}, false
+	}
+
+	srcLst, err := db.GetWorksetParamSource(dbConn.DB, meta, wsn)
+	if err != nil {
+		omppLog.Log("Error at get workset parameters source:", dn, ": ", wsn, ": ", err)
+		return []db.WorksetParamSource{}, false
+	}
+	return srcLst, true
+}
+
+// RunParamLineage return lineage of model run parameter or all model run parameters if parameter name is empty.
+// Lineage starts from model run and goes back to input workset, saved versions of workset parameter and source model runs or worksets.
+func (mc *ModelCatalog) RunParamLineage(dn, rdsn, name string) ([]db.ParamLineage, bool) {
+
+	// if model digest-or-name or run digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return []db.ParamLineage{}, false
+	}
+	if rdsn == "" {
+		omppLog.Log("Warning: invalid (empty) run digest or stamp or name")
+		return []db.ParamLineage{}, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.ParamLineage{}, false
+	}
+
+	// get run_lst db row by digest, stamp or run name
+	r, err := db.GetRunByDigestStampName(dbConn.DB, meta.Model.ModelId, rdsn)
+	if err != nil {
+		omppLog.Log("Error at get model run:", dn, ":", rdsn, ":", err.Error())
+		return []db.ParamLineage{}, false
+	}
+	if r == nil {
+		omppLog.Log("Warning: model run not found:", dn, ":", rdsn)
+		return []db.ParamLineage{}, false
+	}
+
+	plLst, err := db.GetRunParamLineage(dbConn.DB, meta, r, name)
+	if err != nil {
+		omppLog.Log("Error at get model run parameter lineage:", dn, ": ", rdsn, ": ", name, ": ", err)
+		return []db.ParamLineage{}, false
+	}
+	return plLst, true
+}
+
+// WorksetParamStorage return storage size of each workset parameter: number of stored cells and number of cells of resolved parameter.
+func (mc *ModelCatalog) WorksetParamStorage(dn, wsn string) ([]db.WorksetParamStorage, bool) {
+
+	// if model digest-or-name or workset name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return []db.WorksetParamStorage{}, false
+	}
+	if wsn == "" {
+		omppLog.Log("Warning: invalid (empty) workset name")
+		return []db.WorksetParamStorage{}, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.WorksetParamStorage{}, false
+	}
+
+	psLst, err := db.GetWorksetParamStorage(dbConn.DB, meta, wsn)
+	if err != nil {
+		omppLog.Log("Error at get workset parameters storage:", dn, ": ", wsn, ": ", err)
+		return []db.WorksetParamStorage{}, false
+	}
+	return psLst, true
+}
@@ -129,6 +129,40 @@ func modelDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 }
 
+// verify model data integrity: recalculate value digests of model runs and find orphaned rows or missing Hid's.
+//
+//	POST /api/admin/model/:model/verify
+//	POST /api/admin/model/:model/run/:run/verify
+//
+// Model identified by digest-or-name.
+// Model run identified by run digest, run stamp or run name, model run must be completed.
+// If model run not specified then all completed model runs are verified.
+// Response is a json verification report:
+// parameters, output tables and microdata digests mismatch, orphaned value rows and metadata rows which are reference to missing Hid's.
+func modelVerifyHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	rdsn := getRequestParam(r, "run")
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	if dn == "" {
+		omppLog.Log("Error: invalid (empty) model digest and name")
+		http.Error(w, helper.MsgL(lang, "Invalid (empty) model digest and name"), http.StatusBadRequest)
+		return
+	}
+
+	mv, ok, err := theCatalog.VerifyModelRuns(dn, rdsn)
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Failed to verify model", ": ", dn, " ", rdsn), http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		http.Error(w, helper.MsgL(lang, "Model run not found or not completed", ": ", dn, " ", rdsn), http.StatusBadRequest)
+		return
+	}
+	jsonResponse(w, r, mv)
+}
+
 // open SQLite db file and get all models from it.
 //
 //	POST /api/admin/db-file-open/:path
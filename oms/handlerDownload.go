@@ -36,6 +36,7 @@ func modelDownloadPostHandler(w http.ResponseWriter, r *http.Request) {
 		NoMicrodata       bool
 		Utf8BomIntoCsv    bool
 		IdCsv             bool
+		Xlsx              bool
 	}{}
 	if !jsonRequestDecode(w, r, false, &opts) {
 		return // error at json decode, response done with http error
@@ -117,6 +118,8 @@ func modelDownloadPostHandler(w http.ResponseWriter, r *http.Request) {
 // it is only to analyze model output values CSV data using some other tools
 // If NoMicrodata is true then microdata not included in result.
 // If Utf8BomIntoCsv is true then add utf-8 byte order mark into csv files
+// If Xlsx is true then result is .xlsx workbook instead of zip archive: one sheet per parameter or output table.
+// Workbook contains dimension labels in language of the request, if IdCsv is true then enum id's.
 func runDownloadPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	// url or query parameters
@@ -130,6 +133,7 @@ func runDownloadPostHandler(w http.ResponseWriter, r *http.Request) {
 		NoMicrodata       bool
 		Utf8BomIntoCsv    bool
 		IdCsv             bool
+		Xlsx              bool
 	}{}
 	if !jsonRequestDecode(w, r, false, &opts) {
 		return // error at json decode, response done with http error
@@ -211,7 +215,12 @@ func runDownloadPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create model run download files on separate thread
-	cmd, cmdMsg := makeRunDownloadCommand(mb, r0.RunId, logPath, opts.NoAccumulatorsCsv, opts.NoMicrodata, opts.Utf8BomIntoCsv, opts.IdCsv, lang)
+	xlsxLang := ""
+	if opts.Xlsx && !opts.IdCsv {
+		xlsxLang = theCatalog.languageTagMatch(mb.model.Digest, getRequestLang(r, "lang"))
+	}
+	cmd, cmdMsg := makeRunDownloadCommand(
+		mb, r0.RunId, logPath, opts.NoAccumulatorsCsv, opts.NoMicrodata, opts.Utf8BomIntoCsv, opts.IdCsv, opts.Xlsx, xlsxLang, lang)
 
 	go makeDownload(baseName, cmd, cmdMsg, logPath)
 
@@ -226,6 +235,8 @@ func runDownloadPostHandler(w http.ResponseWriter, r *http.Request) {
 // Dimension(s) and enum-based parameters returned as enum codes, not enum id's.
 // Json is posted to specify download options.
 // If Utf8BomIntoCsv is true then add utf-8 byte order mark into csv files
+// If Xlsx is true then result is .xlsx workbook instead of zip archive: one sheet per parameter.
+// Workbook contains dimension labels in language of the request, if IdCsv is true then enum id's.
 func worksetDownloadPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	// url or query parameters
@@ -237,6 +248,7 @@ func worksetDownloadPostHandler(w http.ResponseWriter, r *http.Request) {
 	opts := struct {
 		Utf8BomIntoCsv bool
 		IdCsv          bool
+		Xlsx           bool
 	}{}
 
 	if !jsonRequestDecode(w, r, false, &opts) {
@@ -310,7 +322,11 @@ func worksetDownloadPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create model scenario download files on separate thread
-	cmd, cmdMsg := makeWorksetDownloadCommand(mb, ws.Name, logPath, opts.Utf8BomIntoCsv, opts.IdCsv, lang)
+	xlsxLang := ""
+	if opts.Xlsx && !opts.IdCsv {
+		xlsxLang = theCatalog.languageTagMatch(mb.model.Digest, getRequestLang(r, "lang"))
+	}
+	cmd, cmdMsg := makeWorksetDownloadCommand(mb, ws.Name, logPath, opts.Utf8BomIntoCsv, opts.IdCsv, opts.Xlsx, xlsxLang, lang)
 
 	go makeDownload(baseName, cmd, cmdMsg, logPath)
 
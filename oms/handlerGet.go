@@ -245,6 +245,142 @@ func modelProfileListHandler(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, r, pl)
 }
 
+// return list of derived measures by model digest-or-name:
+//
+//	GET /api/model/:model/derived-list
+//
+// Derived measure is a user-defined named calculation of output table or microdata entity.
+// Measure name can be used instead of calculation expression to read, calculate or compare output table or microdata values.
+func derivedMeasureListHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+
+	dmLst, _ := theCatalog.DerivedMeasureList(dn)
+	jsonResponse(w, r, dmLst)
+}
+
+// return list of parameters validation rules by model digest-or-name:
+//
+//	GET /api/model/:model/parameter-rules
+//
+// Validation rules are checked on each update of workset parameter values, see db.ParamRule for details.
+func paramRuleListHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+
+	prLst, _ := theCatalog.ParamRuleList(dn)
+	jsonResponse(w, r, prLst)
+}
+
+// worksetValidateHandler check workset parameters values by parameters validation rules and return list of violations:
+//
+//	GET /api/model/:model/workset/:set/validate
+//
+// Only parameters included in workset are validated, parameters from workset base run are not.
+// If there are no violations then return is empty list.
+// Dimension items of violation returned as enum codes.
+func worksetValidateHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	wsn := getRequestParam(r, "set")
+
+	vLst, _ := theCatalog.ValidateWorkset(dn, wsn)
+	jsonResponse(w, r, vLst)
+}
+
+// worksetImportHandler return provenance of workset parameters imported from upstream model run:
+//
+//	GET /api/model/:model/workset/:set/import
+//
+// If workset was not created by import from upstream model then return is empty list.
+func worksetImportHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	wsn := getRequestParam(r, "set")
+
+	isLst, _ := theCatalog.WorksetImport(dn, wsn)
+	jsonResponse(w, r, isLst)
+}
+
+// worksetMergeReportHandler return report of three-way merge which created the workset:
+//
+//	GET /api/model/:model/workset/:set/merge-report
+//
+// If workset was not created by merge then return is null.
+// Conflicts list can be truncated if it is too long, full list returned only by merge request.
+func worksetMergeReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	wsn := getRequestParam(r, "set")
+
+	rpt, _ := theCatalog.WorksetMergeReport(dn, wsn)
+	jsonResponse(w, r, rpt)
+}
+
+// worksetTemplateHandler return template which was applied to create the workset:
+//
+//	GET /api/model/:model/workset/:set/template
+//
+// Template includes actual base run digest and number of updated cells by each override.
+// If workset was not created from template then return is null.
+func worksetTemplateHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	wsn := getRequestParam(r, "set")
+
+	tpl, _ := theCatalog.WorksetTemplate(dn, wsn)
+	jsonResponse(w, r, tpl)
+}
+
+// worksetSourceHandler return sources of workset parameters: model runs or worksets where parameter values copied from:
+//
+//	GET /api/model/:model/workset/:set/source
+//
+// If workset parameters were not copied from model run or other workset then return is empty list.
+func worksetSourceHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	wsn := getRequestParam(r, "set")
+
+	srcLst, _ := theCatalog.WorksetParamSource(dn, wsn)
+	jsonResponse(w, r, srcLst)
+}
+
+// worksetStorageHandler return storage size of each workset parameter:
+//
+//	GET /api/model/:model/workset/:set/storage
+//
+// For each workset parameter it is number of cells stored in workset, number of cells of resolved parameter and number of saved cells.
+// If parameter is sparse then only overridden cells are stored in workset and other cells resolved from base run or default workset.
+func worksetStorageHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	wsn := getRequestParam(r, "set")
+
+	psLst, _ := theCatalog.WorksetParamStorage(dn, wsn)
+	jsonResponse(w, r, psLst)
+}
+
+// runParameterLineageHandler return lineage of model run parameter or all model run parameters:
+//
+//	GET /api/model/:model/run/:run/lineage
+//	GET /api/model/:model/run/:run/parameter/:name/lineage
+//
+// Lineage starts from model run and goes back to the origin of parameter values:
+// input workset, saved versions of workset parameter, model runs or worksets where parameter values copied from.
+// Each step of lineage includes digest of parameter values at that step.
+// If multiple models with same name exist then result is undefined.
+// If multiple runs with same stamp or name exist then result is undefined.
+func runParameterLineageHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	rdsn := getRequestParam(r, "run")
+	name := getRequestParam(r, "name")
+
+	plLst, _ := theCatalog.RunParamLineage(dn, rdsn, name)
+	jsonResponse(w, r, plLst)
+}
+
 // runListHandler return list of run_lst db rows by model digest-or-name:
 // GET /api/model/:model/run-list
 // If multiple models with same name exist only one is returned.
@@ -380,6 +516,8 @@ func runTextHandler(w http.ResponseWriter, r *http.Request) {
 	rqLangTags := getRequestLang(r, "lang") // get optional language argument and languages accepted by browser
 
 	rp, _ := theCatalog.RunTextFull(dn, rdsn, false, rqLangTags)
+
+	setETag(w, theCatalog.RunETag(dn, rdsn))
 	jsonResponse(w, r, rp)
 }
 
@@ -399,7 +537,10 @@ func runAllTextHandler(w http.ResponseWriter, r *http.Request) {
 	dn := getRequestParam(r, "model")
 	rdsn := getRequestParam(r, "run")
 
-	rp, _ := theCatalog.RunTextFull(dn, rdsn, true, nil)
+	rp, ok := theCatalog.RunTextFull(dn, rdsn, true, nil)
+	if ok && rp != nil {
+		setETag(w, runETagOf(rp.RunDigest, rp.Status, rp.UpdateDateTime))
+	}
 	jsonResponse(w, r, rp)
 }
 
@@ -446,6 +587,8 @@ func worksetStatusHandler(w http.ResponseWriter, r *http.Request) {
 	ws, ok := theCatalog.WorksetByName(dn, wsn)
 	if !ok {
 		omppLog.Log("Warning workset status not found:", dn, ":", wsn)
+	} else {
+		setETag(w, etagOf(ws))
 	}
 
 	jsonResponse(w, r, ws) // return non-empty workset_lst row if no errors and workset exist
@@ -480,6 +623,8 @@ func worksetTextHandler(w http.ResponseWriter, r *http.Request) {
 	rqLangTags := getRequestLang(r, "lang") // get optional language argument and languages accepted by browser
 
 	wp, _, _ := theCatalog.WorksetTextFull(dn, wsn, false, rqLangTags)
+
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
 	jsonResponse(w, r, wp)
 }
 
@@ -496,6 +641,8 @@ func worksetAllTextHandler(w http.ResponseWriter, r *http.Request) {
 	wsn := getRequestParam(r, "set")
 
 	wp, _, _ := theCatalog.WorksetTextFull(dn, wsn, true, nil)
+
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
 	jsonResponse(w, r, wp)
 }
 
@@ -539,6 +686,8 @@ func taskSetsHandler(w http.ResponseWriter, r *http.Request) {
 	name := getRequestParam(r, "task")
 
 	tpl, _ := theCatalog.TaskSets(dn, name)
+
+	setETag(w, theCatalog.TaskETag(dn, name))
 	jsonResponse(w, r, tpl)
 }
 
@@ -656,6 +805,8 @@ func taskTextHandler(w http.ResponseWriter, r *http.Request) {
 
 	tp, trs, _ := theCatalog.TaskTextFull(dn, tn, false, rqLangTags)
 
+	setETag(w, theCatalog.TaskETag(dn, tn))
+
 	jsonResponse(w, r,
 		&struct {
 			Task *db.TaskPub
@@ -678,7 +829,10 @@ func taskAllTextHandler(w http.ResponseWriter, r *http.Request) {
 	dn := getRequestParam(r, "model")
 	tn := getRequestParam(r, "task")
 
-	tp, trs, _ := theCatalog.TaskTextFull(dn, tn, true, nil)
+	tp, trs, ok := theCatalog.TaskTextFull(dn, tn, true, nil)
+	if ok {
+		setETag(w, etagOf(&tp.TaskDefPub))
+	}
 
 	jsonResponse(w, r,
 		&struct {
@@ -104,6 +104,11 @@ func doModelTextHandler(w http.ResponseWriter, r *http.Request, isPack bool) {
 		return
 	}
 
+	// append derived measures to output tables and entities, if there are any stored in model database
+	if dmLst, ok := theCatalog.DerivedMeasureList(mdRow.Digest); ok {
+		me.SetDerived(dmLst)
+	}
+
 	// write json response
 	jsonSetHeaders(w, r)
 
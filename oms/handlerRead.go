@@ -61,6 +61,11 @@ func doReadParameterPageHandler(w http.ResponseWriter, r *http.Request, srcArg s
 	}
 	layout.IsFromSet = isSet // overwrite json value, it was likely default
 
+	// workset parameter values version: ETag of the workset
+	if isSet {
+		setETag(w, theCatalog.WorksetETag(dn, src))
+	}
+
 	// get converter from id's cell into code cell
 	var cvtCell func(interface{}) (interface{}, error)
 	if isCode {
@@ -188,6 +193,7 @@ func runTableCalcIdPageReadHandler(w http.ResponseWriter, r *http.Request) {
 // doTableCalcGetPageHandler calculate a "page" of additional measures for output table using expressions or by aggregating accumulators.
 // Json is posted to specify table name, "page" size and additional measures calculations,
 // see db.ReadCalculteTableLayout for more details.
+// If IsGoEval is true then calculation done in Go else calculation translated into sql.
 // Page is part of output table values defined by zero-based "start" row number and row count.
 // If row count <= 0 then all rows returned.
 // Dimension items returned enum id's or as enum codes and for dimension type simple as string values.
@@ -226,7 +232,7 @@ func doReadTableCalcPageHandler(w http.ResponseWriter, r *http.Request, isCode b
 
 	// calculate output table measure and read measure page into json array response, convert enum id's to code if requested
 	lt, ok := theCatalog.ReadOutTableCalculateTo(
-		dn, rdsn, &db.ReadTableLayout{ReadLayout: layout.ReadLayout}, layout.Calculation, runIds, cvtWr,
+		dn, rdsn, &db.ReadTableLayout{ReadLayout: layout.ReadLayout, IsGoEval: layout.IsGoEval}, layout.Calculation, runIds, cvtWr,
 	)
 	if !ok {
 		http.Error(w, helper.MsgL(lang, "Error at run output table calculate:", rdsn, ":", layout.Name), http.StatusBadRequest)
@@ -262,6 +268,7 @@ func runTableCompareIdPageReadHandler(w http.ResponseWriter, r *http.Request) {
 // doReadTableComparePageHandler compare model runs with base run and return a "page" of comparison values or calculated additional measures.
 // Json is posted to specify table name, "page" size and additional measures calculations,
 // see db.ReadCompareTableLayout for more details.
+// If IsGoEval is true then comparison done in Go else comparison translated into sql.
 // Page is part of output table values defined by zero-based "start" row number and row count.
 // If row count <= 0 then all rows returned.
 // Dimension items returned enum id's or as enum codes and for dimension type simple as string values.
@@ -325,7 +332,7 @@ func doReadTableComparePageHandler(w http.ResponseWriter, r *http.Request, isCod
 
 	// calculate output table measure and read measure page into json array response, convert enum id's to code if requested
 	lt, ok := theCatalog.ReadOutTableCalculateTo(
-		dn, rdsn, &db.ReadTableLayout{ReadLayout: layout.ReadLayout}, layout.Calculation, runIds, cvtWr,
+		dn, rdsn, &db.ReadTableLayout{ReadLayout: layout.ReadLayout, IsGoEval: layout.IsGoEval}, layout.Calculation, runIds, cvtWr,
 	)
 	if !ok {
 		http.Error(w, helper.MsgL(lang, "Error at run output table compare:", rdsn, ":", layout.Name), http.StatusBadRequest)
@@ -344,6 +351,254 @@ func doReadTableComparePageHandler(w http.ResponseWriter, r *http.Request, isCod
 	w.Write([]byte("}")) // end of data page and end of json
 }
 
+// runParameterComparePageReadHandler compare parameter values of base model run with other runs or worksets
+// and return a "page" of comparison expressions.
+// POST /api/model/:model/run/:run/parameter/compare
+// Dimension(s) returned as enum codes.
+func runParameterComparePageReadHandler(w http.ResponseWriter, r *http.Request) {
+	doReadParameterComparePageHandler(w, r, "run", false, true)
+}
+
+// runParameterCompareIdPageReadHandler compare parameter values of base model run with other runs or worksets
+// and return a "page" of comparison expressions.
+// POST /api/model/:model/run/:run/parameter/compare-id
+// Dimension(s) returned as enum id, not enum codes.
+func runParameterCompareIdPageReadHandler(w http.ResponseWriter, r *http.Request) {
+	doReadParameterComparePageHandler(w, r, "run", false, false)
+}
+
+// worksetParameterComparePageReadHandler compare parameter values of base workset with other runs or worksets
+// and return a "page" of comparison expressions.
+// POST /api/model/:model/workset/:set/parameter/compare
+// Dimension(s) returned as enum codes.
+func worksetParameterComparePageReadHandler(w http.ResponseWriter, r *http.Request) {
+	doReadParameterComparePageHandler(w, r, "set", true, true)
+}
+
+// worksetParameterCompareIdPageReadHandler compare parameter values of base workset with other runs or worksets
+// and return a "page" of comparison expressions.
+// POST /api/model/:model/workset/:set/parameter/compare-id
+// Dimension(s) returned as enum id, not enum codes.
+func worksetParameterCompareIdPageReadHandler(w http.ResponseWriter, r *http.Request) {
+	doReadParameterComparePageHandler(w, r, "set", true, false)
+}
+
+// doReadParameterComparePageHandler compare parameter values of base run or workset with variant runs or worksets
+// and return a "page" of comparison values.
+// Json is posted to specify parameter name, "page" size, variant runs and worksets and comparison expressions,
+// see db.ReadCompareParamLayout for more details.
+// If comparison expressions are empty then result is base value, variant value and difference: variant - base.
+// Page is part of parameter comparison values defined by zero-based "start" row number and row count.
+// If row count <= 0 then all rows returned.
+// Dimension items returned enum id's or as enum codes.
+func doReadParameterComparePageHandler(w http.ResponseWriter, r *http.Request, srcArg string, isSet, isCode bool) {
+
+	// url parameters
+	dn := getRequestParam(r, "model")  // model digest-or-name
+	src := getRequestParam(r, srcArg)  // base workset name or run digest-or-stamp-or-name
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	// decode json request body
+	var layout db.ReadCompareParamLayout
+	if !jsonRequestDecode(w, r, true, &layout) {
+		return // error at json decode, response done with http error
+	}
+	layout.IsFromSet = isSet // overwrite json value, it was likely default
+
+	// find base run or workset
+	base, label, ok := theCatalog.ParameterCompareSource(dn, src, isSet)
+	if !ok {
+		omppLog.Log("Error at parameter compare: base run or workset not found or run not completed successfully:", src)
+		http.Error(w, helper.FmtL(lang, "Error at parameter compare: %s: base run or workset not found: %s", layout.Name, src), http.StatusBadRequest)
+		return
+	}
+	layout.FromId = base.Id
+
+	// find all variant runs and worksets, make map of source id to run digest or workset name
+	srcLabels := map[int]string{base.Id: label}
+	vLst := []db.CompareParamSource{}
+
+	appendVariant := func(vName string, isVarSet bool) bool {
+
+		v, vLabel, ok := theCatalog.ParameterCompareSource(dn, vName, isVarSet)
+		if !ok {
+			omppLog.Log("Error at parameter compare: run or workset not found or run not completed successfully:", vName)
+			http.Error(w, helper.FmtL(lang, "Error at parameter compare: %s: run or workset not found: %s", layout.Name, vName), http.StatusBadRequest)
+			return false
+		}
+		if _, isFound := srcLabels[v.Id]; !isFound {
+			srcLabels[v.Id] = vLabel
+			vLst = append(vLst, v)
+		}
+		return true
+	}
+	for _, rn := range layout.Runs {
+		if !appendVariant(rn, false) {
+			return
+		}
+	}
+	for _, wn := range layout.Worksets {
+		if !appendVariant(wn, true) {
+			return
+		}
+	}
+	if len(vLst) <= 0 {
+		omppLog.Log("Error at parameter compare: no runs or worksets to compare with:", dn, ":", layout.Name)
+		http.Error(w, helper.FmtL(lang, "Error at parameter compare: %s: no runs or worksets to compare with: %s", layout.Name, src), http.StatusBadRequest)
+		return
+	}
+
+	// by default return base value, variant value and difference
+	if len(layout.Calculation) <= 0 {
+		cLt, err := db.ParamCompareCalculation(layout.Name, "diff")
+		if err != nil {
+			omppLog.Log("Error at parameter compare:", dn, ":", layout.Name, ":", err.Error())
+			http.Error(w, helper.MsgL(lang, "Error at parameter compare:", layout.Name), http.StatusBadRequest)
+			return
+		}
+		layout.Calculation = cLt
+	}
+
+	// if required get converter from id's cell into code cell
+	var cvtCell func(interface{}) (interface{}, error)
+	if isCode {
+		cvtCell, ok = theCatalog.ParameterToCodeCalcCellConverter(dn, layout.Name, layout.Calculation, srcLabels)
+		if !ok {
+			http.Error(w, helper.MsgL(lang, "Error at parameter compare:", layout.Name), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// write to response: page layout and page data
+	jsonSetHeaders(w, r) // start response with set json headers, i.e. content type
+
+	w.Write([]byte("{\"Page\":[")) // start of data page and start of json output array
+
+	enc := json.NewEncoder(w)
+	cvtWr := jsonCellWriter(w, enc, cvtCell)
+
+	// read parameter comparison page into json array response, convert enum id's to code if requested
+	lt, ok := theCatalog.CompareParameterTo(dn, &layout.ReadCalculteParamLayout, vLst, cvtWr)
+	if !ok {
+		http.Error(w, helper.MsgL(lang, "Error at parameter compare:", src, ":", layout.Name), http.StatusBadRequest)
+		return
+	}
+
+	w.Write([]byte{']'}) // end of data page array
+
+	// continue response with output page layout: offset, size, last page flag
+	w.Write([]byte(",\"Layout\":"))
+
+	err := json.NewEncoder(w).Encode(lt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	w.Write([]byte("}")) // end of data page and end of json
+}
+
+// runRunParameterDiffGetHandler compare all parameters of base model run and variant model run.
+// GET /api/model/:model/run/:run/parameter-diff/run/:variant
+func runRunParameterDiffGetHandler(w http.ResponseWriter, r *http.Request) {
+	doParameterDiffGetHandler(w, r, "run", false, false)
+}
+
+// runWorksetParameterDiffGetHandler compare all parameters of base model run and variant workset.
+// GET /api/model/:model/run/:run/parameter-diff/workset/:variant
+func runWorksetParameterDiffGetHandler(w http.ResponseWriter, r *http.Request) {
+	doParameterDiffGetHandler(w, r, "run", false, true)
+}
+
+// worksetRunParameterDiffGetHandler compare all parameters of base workset and variant model run.
+// GET /api/model/:model/workset/:set/parameter-diff/run/:variant
+func worksetRunParameterDiffGetHandler(w http.ResponseWriter, r *http.Request) {
+	doParameterDiffGetHandler(w, r, "set", true, false)
+}
+
+// worksetWorksetParameterDiffGetHandler compare all parameters of base workset and variant workset.
+// GET /api/model/:model/workset/:set/parameter-diff/workset/:variant
+func worksetWorksetParameterDiffGetHandler(w http.ResponseWriter, r *http.Request) {
+	doParameterDiffGetHandler(w, r, "set", true, true)
+}
+
+// doParameterDiffGetHandler compare all parameters of base run or workset and variant run or workset.
+// Response is a list of all model parameters with is-different flag
+// and count of base and variant cells which are not found in other source or have different value.
+func doParameterDiffGetHandler(w http.ResponseWriter, r *http.Request, srcArg string, isSet, isVarSet bool) {
+
+	// url parameters
+	dn := getRequestParam(r, "model")      // model digest-or-name
+	src := getRequestParam(r, srcArg)      // base workset name or run digest-or-stamp-or-name
+	vName := getRequestParam(r, "variant") // variant workset name or run digest-or-stamp-or-name
+	lang := preferedRequestLang(r, "")     // get prefered language for messages
+
+	// find base and variant run or workset
+	base, _, ok := theCatalog.ParameterCompareSource(dn, src, isSet)
+	if !ok {
+		http.Error(w, helper.MsgL(lang, "Error at parameters compare, base run or workset not found:", src), http.StatusBadRequest)
+		return
+	}
+	variant, _, ok := theCatalog.ParameterCompareSource(dn, vName, isVarSet)
+	if !ok {
+		http.Error(w, helper.MsgL(lang, "Error at parameters compare, run or workset not found:", vName), http.StatusBadRequest)
+		return
+	}
+
+	smLst, ok := theCatalog.CompareParameterSummary(dn, base, variant)
+	if !ok {
+		http.Error(w, helper.MsgL(lang, "Error at parameters compare:", src, ":", vName), http.StatusBadRequest)
+		return
+	}
+	jsonResponse(w, r, smLst)
+}
+
+// runDiffGetHandler compare base and variant model runs: run options, parameters and output tables.
+// GET /api/model/:model/run/:run/run-diff/:variant
+// Response contains run options which are different, parameters with different values and count of different cells,
+// output tables with different values and largest absolute and relative change of each expression.
+func runDiffGetHandler(w http.ResponseWriter, r *http.Request) {
+
+	// url parameters
+	dn := getRequestParam(r, "model")      // model digest-or-name
+	rdsn := getRequestParam(r, "run")      // base run digest-or-stamp-or-name
+	vName := getRequestParam(r, "variant") // variant run digest-or-stamp-or-name
+	lang := preferedRequestLang(r, "")     // get prefered language for messages
+
+	rd, ok := theCatalog.CompareRuns(dn, rdsn, vName)
+	if !ok {
+		http.Error(w, helper.MsgL(lang, "Error at model runs compare:", rdsn, ":", vName), http.StatusBadRequest)
+		return
+	}
+	jsonResponse(w, r, rd)
+}
+
+// calcValidateHandler parse and validate output table or microdata calculation expression without running it.
+// POST /api/model/:model/calc-validate
+// Json is posted to specify output table or entity name and calculation expression, see db.ValidateCalcLayout for details.
+// Response contains list of errors with character positions in expression, run comparison flag and inferred result type.
+func calcValidateHandler(w http.ResponseWriter, r *http.Request) {
+
+	// url parameters
+	dn := getRequestParam(r, "model")  // model digest-or-name
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	// decode json request body
+	var layout db.ValidateCalcLayout
+	if !jsonRequestDecode(w, r, true, &layout) {
+		return // error at json decode, response done with http error
+	}
+	if layout.IsMicro && !theCfg.isMicrodata {
+		http.Error(w, helper.MsgL(lang, "Error: microdata not allowed:", layout.Name), http.StatusBadRequest)
+		return
+	}
+
+	cv, ok := theCatalog.ValidateCalculate(dn, &layout)
+	if !ok {
+		http.Error(w, helper.MsgL(lang, "Error at calculation validate:", layout.Name), http.StatusBadRequest)
+		return
+	}
+	jsonResponse(w, r, cv)
+}
+
 // check if all runs completed successfully and return run id's for all existing runs, skip runs which do exist.
 func isSuccessAllRuns(digest string, runLst []string) ([]int, bool) {
 
@@ -452,6 +707,11 @@ func doParameterGetPageHandler(w http.ResponseWriter, r *http.Request, srcArg st
 		}
 	}
 
+	// workset parameter values version: ETag of the workset
+	if isSet {
+		setETag(w, theCatalog.WorksetETag(dn, src))
+	}
+
 	// write to response
 	jsonSetHeaders(w, r) // start response with set json headers, i.e. content type
 
@@ -578,7 +838,7 @@ func runTableCalcPageGetHandler(w http.ResponseWriter, r *http.Request) {
 	dn := getRequestParam(r, "model")  // model digest-or-name
 	rdsn := getRequestParam(r, "run")  // run digest-or-stamp-or-name
 	name := getRequestParam(r, "name") // output table name
-	calc := getRequestParam(r, "calc") // calculation function name: sum avg count min max var sd se cv
+	calc := getRequestParam(r, "calc") // calculation function name: sum avg count min max var sd se cv median
 	lang := preferedRequestLang(r, "") // get prefered language for messages
 
 	// validate parameters: page offset, page size and calculation expression
@@ -652,14 +912,45 @@ func runTableCalcPageGetHandler(w http.ResponseWriter, r *http.Request) {
 // If row count <= 0 then all rows returned.
 // Enum-based dimension items returned as enum codes.
 func runTableComparePageGetHandler(w http.ResponseWriter, r *http.Request) {
+	doTableRunsPageGetHandler(w, r, false)
+}
+
+// runTableRunAggrPageGetHandler aggregate output table expressions across model runs and return a "page" of aggregated measures.
+//
+// It is either aggregation for each expression: SUM AVG COUNT MIN MAX VAR SD SE CV MEDIAN,
+// or SUMMARY of each expression: average, standard deviation, 95% confidence interval, minimum and maximum.
+// For example, SD is: OM_SD(expr0), OM_SD(expr1),....
+// Or arbitrary comma separated aggregation expression(s): OM_AVG(expr0) , OM_MAX(expr1) - OM_MIN(expr1) , ....
+// Aggregation is done across base run and all variant runs, result returned as base run values.
+// Variant runs can be a comma separated list of run digests or run stamps or run names.
+// If run name contains comma then name must be "double quoted" or 'single quoted'.
+//
+// GET /api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant
+// GET /api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant/start/:start
+// GET /api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant/start/:start/count/:count
+//
+// Page is part of output table values defined by zero-based "start" row number and row count.
+// If row count <= 0 then all rows returned.
+// Enum-based dimension items returned as enum codes.
+func runTableRunAggrPageGetHandler(w http.ResponseWriter, r *http.Request) {
+	doTableRunsPageGetHandler(w, r, true)
+}
+
+// doTableRunsPageGetHandler compare model runs or aggregate output table expressions across model runs
+// and return a "page" of calculated measures.
+func doTableRunsPageGetHandler(w http.ResponseWriter, r *http.Request, isRunAggr bool) {
 
 	// url or query parameters
-	dn := getRequestParam(r, "model")        // model digest-or-name
-	rdsn := getRequestParam(r, "run")        // base run digest-or-stamp-or-name
-	name := getRequestParam(r, "name")       // output table name
+	dn := getRequestParam(r, "model")   // model digest-or-name
+	rdsn := getRequestParam(r, "run")   // base run digest-or-stamp-or-name
+	name := getRequestParam(r, "name")  // output table name
+	vr := getRequestParam(r, "variant") // variant run digest-or-stamp-or-name
+	lang := preferedRequestLang(r, "")  // get prefered language for messages
+
 	compare := getRequestParam(r, "compare") // comparison function name: diff ratio percent
-	vr := getRequestParam(r, "variant")      // variant run digest-or-stamp-or-name
-	lang := preferedRequestLang(r, "")       // get prefered language for messages
+	if isRunAggr {
+		compare = getRequestParam(r, "aggr") // aggregation function name: avg sd summary
+	}
 
 	// validate parameters: page offset, page size and calculation expression
 	if compare == "" {
@@ -690,7 +981,12 @@ func runTableComparePageGetHandler(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	calcLt, ok := theCatalog.TableExprCompareLayout(dn, name, compare)
+	var calcLt []db.CalculateTableLayout
+	if isRunAggr {
+		calcLt, ok = theCatalog.TableRunAggrLayout(dn, name, compare)
+	} else {
+		calcLt, ok = theCatalog.TableExprCompareLayout(dn, name, compare)
+	}
 	if !ok {
 		http.Error(w, helper.MsgL(lang, "Invalid comparison expression", compare), http.StatusBadRequest)
 		return
@@ -885,7 +1181,9 @@ func doMicrodataGetPageHandler(w http.ResponseWriter, r *http.Request, isCode bo
 // POST /api/model/:model/run/:run/microdata/calc
 // It can be multiple aggregations of value attributes (float of integer type), group by dimension attributes (enum-based or bool type).
 // For example: GroupBy: [AgeGroup, Sex] and Calculation: [OM_AVG(Income), OM_MAX(Salary+Pension)]
-// Enum-based microdata attributes returned as enum codes.
+// Numeric group by attributes can be binned, for example: GroupBy: [AgeGroup, Income] and Bins: [{Name: Income, Kind: QUANTILE, Count: 4}]
+// If IsGoEval is true then aggregation done in Go else aggregation translated into sql.
+// Enum-based microdata attributes returned as enum codes, binned attributes returned as bin labels.
 func runMicrodataCalcPageReadHandler(w http.ResponseWriter, r *http.Request) {
 
 	// url parameters
@@ -1018,6 +1316,7 @@ func doReadMicrodataCalcPageHandler(w http.ResponseWriter, r *http.Request, dn s
 // All comparisons and aggregations grouped by dimension attributes (enum-based or bool type).
 // It can be multiple aggregations of value attributes (float of integer type), group by dimension attributes (enum-based or bool type).
 // For example: GroupBy: [AgeGroup, Sex] and Calculation: [OM_AVG(Income[variant]-Income[base]) , OM_MAX(Salary+Pension)]
+// If IsGoEval is true then aggregation done in Go else aggregation translated into sql.
 // Enum-based microdata attributes returned as enum codes.
 func runMicrodataComparePageReadHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -1101,6 +1400,7 @@ func runMicrodataComparePageGetHandler(w http.ResponseWriter, r *http.Request) {
 // Page of values is a rows from microdata value table started at zero based offset row
 // and up to max page size rows, if page size <= 0 then all values returned.
 // Enum-based microdata attributes returned as enum codes or enum id's.
+// Numeric group by attributes can be binned by optional bins query parameter, for example: ?bins=Income=quantile(4)
 func doMicrodataCalcGetPageHandler(w http.ResponseWriter, r *http.Request, isCode bool, calcKey string, isVar bool) {
 
 	// url or query parameters
@@ -1111,6 +1411,13 @@ func doMicrodataCalcGetPageHandler(w http.ResponseWriter, r *http.Request, isCod
 	cLst := helper.ParseCsvLine(getRequestParam(r, calcKey), 0)       // list of aggregations of value attribute(s), comma-separated
 	lang := preferedRequestLang(r, "")                                // get prefered language for messages
 
+	// optional binning of numeric group by attributes: Income=width(0,1000,10);AgeYears=breaks(0,18,65);Salary=quantile(4)
+	bins, err := db.ParseMicroBinList(getRequestParam(r, "bins"))
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Invalid microdata group by bins:", name, ":", err.Error()), http.StatusBadRequest)
+		return
+	}
+
 	// url or query parameters: page offset and page size
 	start, ok := getInt64RequestParam(r, "start", 0)
 	if !ok {
@@ -1146,6 +1453,7 @@ func doMicrodataCalcGetPageHandler(w http.ResponseWriter, r *http.Request, isCod
 	calcLt := db.CalculateMicroLayout{
 		Calculation: []db.CalculateLayout{},
 		GroupBy:     groupBy,
+		Bins:        bins,
 	}
 
 	for j := range cLst {
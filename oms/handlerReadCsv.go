@@ -360,7 +360,7 @@ func doTableCalcGetCsvHandler(w http.ResponseWriter, r *http.Request, isCode, is
 	dn := getRequestParam(r, "model")  // model digest-or-name
 	rdsn := getRequestParam(r, "run")  // run digest-or-stamp-or-name
 	name := getRequestParam(r, "name") // output table name
-	calc := getRequestParam(r, "calc") // calculation function name: sum avg count min max var sd se cv
+	calc := getRequestParam(r, "calc") // calculation function name: sum avg count min max var sd se cv median
 	lang := preferedRequestLang(r, "") // get prefered language for messages
 
 	if calc == "" {
@@ -742,6 +742,7 @@ func runMicrodataCalcIdCsvBomGetHandler(w http.ResponseWriter, r *http.Request)
 // If run name contains comma then name must be "double quoted" or 'single quoted'.
 // For example: "Year 1995, 1996" or: 'Age [30, 40]'
 // Enum-based attributes returned as enum codes or enum id's.
+// Numeric group by attributes can be binned by optional bins query parameter, for example: ?bins=Income=quantile(4)
 func doMicrodataCalcGetCsvHandler(w http.ResponseWriter, r *http.Request, isCode, isBom bool, calcKey string, isVar bool) {
 
 	// url or query parameters
@@ -752,6 +753,13 @@ func doMicrodataCalcGetCsvHandler(w http.ResponseWriter, r *http.Request, isCode
 	cLst := helper.ParseCsvLine(getRequestParam(r, calcKey), 0)       // list of aggregations or comparisons, comma-separated
 	lang := preferedRequestLang(r, "")                                // get prefered language for messages
 
+	// optional binning of numeric group by attributes: Income=width(0,1000,10);AgeYears=breaks(0,18,65);Salary=quantile(4)
+	bins, err := db.ParseMicroBinList(getRequestParam(r, "bins"))
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Invalid microdata group by bins:", name, ":", err.Error()), http.StatusBadRequest)
+		return
+	}
+
 	// return error if microdata disabled
 	if !theCfg.isMicrodata {
 		http.Error(w, helper.MsgL(lang, "Error: microdata not allowed:", dn, rdsn), http.StatusBadRequest)
@@ -776,6 +784,7 @@ func doMicrodataCalcGetCsvHandler(w http.ResponseWriter, r *http.Request, isCode
 	calcLt := db.CalculateMicroLayout{
 		Calculation: []db.CalculateLayout{},
 		GroupBy:     groupBy,
+		Bins:        bins,
 	}
 
 	for j := range cLst {
@@ -175,6 +175,22 @@ func serviceRefreshDiskUseHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Location", "/api/service/disk-use/refresh/"+strconv.FormatBool(theCfg.isDiskUse))
 }
 
+// return state of output tables and microdata calculation results cache: size and hit / miss statistics.
+//
+//	GET /api/service/calc-cache
+func serviceCalcCacheHandler(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, r, theCalcCache.state())
+}
+
+// remove all results from output tables and microdata calculation results cache.
+//
+//	POST /api/service/calc-cache/clear
+func serviceCalcCacheClearHandler(w http.ResponseWriter, r *http.Request) {
+
+	theCalcCache.clear()
+	w.Header().Set("Content-Location", "/api/service/calc-cache/clear")
+}
+
 // job control state, log file content and run progress
 type runJobState struct {
 	JobStatus string      // if not empty then job run status: success, error, exit
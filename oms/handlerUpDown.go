@@ -168,11 +168,43 @@ func fileTreeUploadGetHandler(w http.ResponseWriter, r *http.Request) {
 	doFileTreeGet(theCfg.uploadDir, false, "folder", false, w, r)
 }
 
+// return download folder as .zip archive, archive is written into response without creating .zip file on disk.
+//
+//	GET /api/download/zip/:folder
+//
+// Download must be completed: folder.ready.download.log file must exist.
+func downloadZipGetHandler(w http.ResponseWriter, r *http.Request) {
+
+	// url or query parameters
+	folder := getRequestParam(r, "folder")
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	// validate folder name: it must be a name only, it cannot be any path
+	if folder == "" || folder == "." || folder == ".." || folder != filepath.Base(helper.CleanFileName(folder)) {
+		http.Error(w, helper.MsgL(lang, "Folder name invalid (or empty):", folder), http.StatusBadRequest)
+		return
+	}
+	folderPath := filepath.Join(theCfg.downloadDir, folder)
+
+	if !helper.IsDirExist(folderPath) || !helper.IsFileExist(folderPath+".ready.download.log") {
+		http.Error(w, helper.MsgL(lang, "Folder not found:", folder), http.StatusBadRequest)
+		return
+	}
+
+	// write .zip archive into response
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+`"`+folder+".zip"+`"`)
+
+	if err := helper.PackZipTo(w, folderPath); err != nil {
+		omppLog.Log("Error at pack download folder to zip: ", folder, ": ", err.Error())
+	}
+}
+
 // delete download files by folder name.
 //
 //	DELETE /api/download/delete/:folder
 //
-// Delete folder, .zip file and .download.log files
+// Delete folder, .zip or .xlsx file and .download.log files
 func downloadDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	upDownDelete("download", theCfg.downloadDir, false, w, r)
 
@@ -191,7 +223,7 @@ func downloadDeleteAsyncHandler(w http.ResponseWriter, r *http.Request) {
 //
 //	DELETE /api/upload/delete/:folder
 //
-// Delete folder, .zip file and .upload.log files
+// Delete folder, .zip or .xlsx file and .upload.log files
 func uploadDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	upDownDelete("upload", theCfg.uploadDir, false, w, r)
 
@@ -341,7 +373,7 @@ func upDownAllDelete(upDown string, upDownDir string, isAsync bool, w http.Respo
 }
 
 // upDownDelete delete download or upload files by folder name.
-// Delete folder, .zip file and .up-or-down.log files
+// Delete folder, .zip or .xlsx file and .up-or-down.log files
 func upDownDelete(upDown string, upDownDir string, isAsync bool, w http.ResponseWriter, r *http.Request) {
 
 	// url or query parameters
@@ -409,6 +441,9 @@ func doDeleteUpDown(upDown string, upDownDir string, isAsync bool, folder, lang
 		if !removeUpDownFile(upDown, basePath+".zip", logPath, baseName+".zip") {
 			return
 		}
+		if !removeUpDownFile(upDown, basePath+".xlsx", logPath, baseName+".xlsx") {
+			return
+		}
 		if !removeUpDownDir(upDown, basePath, logPath, baseName) {
 			return
 		}
@@ -105,6 +105,98 @@ func profileOptionDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// derivedMeasureReplaceHandler insert new or replace existing derived measure of the model:
+// PATCH /api/model/:model/derived
+// Json content: derived measure, see db.DerivedMeasure for details.
+// Measure calculation is validated and error returned if calculation is not valid.
+func derivedMeasureReplaceHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	var dm db.DerivedMeasure
+	if !jsonRequestDecode(w, r, true, &dm) {
+		return // error at json decode, response done with http error
+	}
+
+	ok, err := theCatalog.ReplaceDerivedMeasure(dn, &dm)
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Derived measure update failed:", dm.Name, ":", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		w.Header().Set("Content-Location", "/api/model/"+dn+"/derived/"+dm.Name)
+		w.Header().Set("Content-Type", "text/plain")
+	}
+}
+
+// derivedMeasureDeleteHandler delete derived measure of the model:
+// DELETE /api/model/:model/derived/:name
+// If no such derived measure exist in database then no error, empty operation.
+func derivedMeasureDeleteHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	name := getRequestParam(r, "name")
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	ok, err := theCatalog.DeleteDerivedMeasure(dn, name)
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Derived measure delete failed:", name), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		w.Header().Set("Content-Location", "/api/model/"+dn+"/derived/"+name)
+		w.Header().Set("Content-Type", "text/plain")
+	}
+}
+
+// paramRulesReplaceHandler insert new or replace existing validation rules of model parameter:
+// PATCH /api/model/:model/parameter/:name/rules
+// Json content: array of parameter rules, see db.ParamRule for details.
+// If rules array is empty then parameter rules are deleted.
+// Rules are checked and error returned if any rule is not valid for that parameter.
+func paramRulesReplaceHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	name := getRequestParam(r, "name")
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	var rules []db.ParamRule
+	if !jsonRequestDecode(w, r, true, &rules) {
+		return // error at json decode, response done with http error
+	}
+
+	ok, err := theCatalog.ReplaceParamRules(dn, name, rules)
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Parameter rules update failed:", name, ":", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		w.Header().Set("Content-Location", "/api/model/"+dn+"/parameter/"+name+"/rules")
+		w.Header().Set("Content-Type", "text/plain")
+	}
+}
+
+// paramRulesDeleteHandler delete validation rules of model parameter:
+// DELETE /api/model/:model/parameter/:name/rules
+// If parameter does not have any rules then no error, empty operation.
+func paramRulesDeleteHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	name := getRequestParam(r, "name")
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	ok, err := theCatalog.DeleteParamRules(dn, name)
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Parameter rules delete failed:", name), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		w.Header().Set("Content-Location", "/api/model/"+dn+"/parameter/"+name+"/rules")
+		w.Header().Set("Content-Type", "text/plain")
+	}
+}
+
 // runDeleteStartHandler start delete model run including output table values, input parameters and microdata
 // by model digest-or-name and run digest-or-stamp-or-name:
 // DELETE /api/model/:model/run/:run
@@ -112,12 +204,17 @@ func profileOptionDeleteHandler(w http.ResponseWriter, r *http.Request) {
 // If multiple models with same name exist then result is undefined.
 // If multiple runs with same stamp or name exist then result is undefined.
 // If no such model run exist in database then no error, empty operation.
+// If request has If-Match header and it does not match model run ETag then return 412 Precondition Failed.
 func runDeleteStartHandler(w http.ResponseWriter, r *http.Request) {
 
 	dn := getRequestParam(r, "model")
 	rdsn := getRequestParam(r, "run")
 	lang := preferedRequestLang(r, "") // get prefered language for messages
 
+	if !isIfMatch(w, r, theCatalog.RunETag(dn, rdsn)) {
+		return // model run updated by other user, response done with http error
+	}
+
 	// delete model run
 	ok, err := theCatalog.DeleteRunStart(dn, rdsn)
 	if err != nil {
@@ -170,6 +267,7 @@ func runListDeleteStartHandler(w http.ResponseWriter, r *http.Request) {
 // If multiple models with same name exist then result is undefined.
 // If multiple runs with same stamp or name exist then result is undefined.
 // If no such model run exist in database then no error, empty operation.
+// If request has If-Match header and it does not match model run ETag then return 412 Precondition Failed.
 func runTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 
 	lang := preferedRequestLang(r, "") // get prefered language for messages
@@ -180,6 +278,25 @@ func runTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 		return // error at json decode, response done with http error
 	}
 
+	// check If-Match precondition by model digest-or-name and run digest-or-stamp-or-name
+	if r.Header.Get("If-Match") != "" {
+
+		mdn := rp.ModelDigest
+		if mdn == "" {
+			mdn = rp.ModelName
+		}
+		rn := rp.RunDigest
+		if rn == "" {
+			rn = rp.RunStamp
+		}
+		if rn == "" {
+			rn = rp.Name
+		}
+		if !isIfMatch(w, r, theCatalog.RunETag(mdn, rn)) {
+			return // model run updated by other user, response done with http error
+		}
+	}
+
 	// update run text in model catalog
 	ok, dn, rdsn, err := theCatalog.UpdateRunText(&rp)
 	if err != nil {
@@ -189,6 +306,7 @@ func runTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	if ok {
 		w.Header().Set("Content-Location", "/api/model/"+dn+"/run/"+rdsn)
+		setETag(w, theCatalog.RunETag(dn, rdsn))
 		w.Header().Set("Content-Type", "text/plain")
 	}
 }
@@ -201,6 +319,7 @@ func runTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 // If model run does not exist then return error.
 // Input json must be array of ParamRunSetTxtPub,
 // if parameters text array is empty then nothing updated, it is empty operation return is success
+// If request has If-Match header and it does not match model run ETag then return 412 Precondition Failed.
 func runParameterTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 
 	// url or query parameters
@@ -214,6 +333,10 @@ func runParameterTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 		return // error at json decode, response done with http error
 	}
 
+	if !isIfMatch(w, r, theCatalog.RunETag(dn, rdsn)) {
+		return // model run updated by other user, response done with http error
+	}
+
 	// update run parameter(s) value notes in model catalog
 	ok, err := theCatalog.UpdateRunParameterText(dn, rdsn, pvtLst)
 	if err != nil {
@@ -223,6 +346,7 @@ func runParameterTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	if ok {
 		w.Header().Set("Content-Location", "/api/model/"+dn+"/run/"+rdsn+"/parameter-text")
+		setETag(w, theCatalog.RunETag(dn, rdsn))
 		w.Header().Set("Content-Type", "text/plain")
 	}
 }
@@ -234,12 +358,17 @@ func runParameterTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 // If multiple models with same name exist then result is undefined.
 // If task does not exists in database then it is empty operation.
 // If modeling task is running during delete then result is undefined and model may fail with database error.
+// If request has If-Match header and it does not match task ETag then return 412 Precondition Failed.
 func taskDeleteHandler(w http.ResponseWriter, r *http.Request) {
 
 	dn := getRequestParam(r, "model")
 	tn := getRequestParam(r, "task")
 	lang := preferedRequestLang(r, "") // get prefered language for messages
 
+	if !isIfMatch(w, r, theCatalog.TaskETag(dn, tn)) {
+		return // task updated by other user, response done with http error
+	}
+
 	// delete modeling task
 	ok, err := theCatalog.DeleteTask(dn, tn)
 	if err != nil {
@@ -279,6 +408,7 @@ func taskDefMergeHandler(w http.ResponseWriter, r *http.Request) {
 // taskDefUpdateHandler replace or merge task definition: task text (description and notes) and task input worksets into database.
 // It does replace or merge task_txt and task_set db rows.
 // If task does not exist then new task created.
+// If request has If-Match header and it does not match task ETag then return 412 Precondition Failed.
 func taskDefUpdateHandler(w http.ResponseWriter, r *http.Request, isReplace bool) {
 
 	lang := preferedRequestLang(r, "") // get prefered language for messages
@@ -295,6 +425,18 @@ func taskDefUpdateHandler(w http.ResponseWriter, r *http.Request, isReplace bool
 		tpd.Name = "task_" + ts
 	}
 
+	// check If-Match precondition by model digest-or-name and task name
+	if r.Header.Get("If-Match") != "" {
+
+		mdn := tpd.ModelDigest
+		if mdn == "" {
+			mdn = tpd.ModelName
+		}
+		if !isIfMatch(w, r, theCatalog.TaskETag(mdn, tpd.Name)) {
+			return // task updated by other user, response done with http error
+		}
+	}
+
 	// update task definition in model catalog
 	ok, dn, tn, err := theCatalog.UpdateTaskDef(isReplace, &tpd)
 	if err != nil {
@@ -304,6 +446,7 @@ func taskDefUpdateHandler(w http.ResponseWriter, r *http.Request, isReplace bool
 	}
 	if ok {
 		w.Header().Set("Content-Location", "/api/model/"+dn+"/task/"+tn)
+		setETag(w, theCatalog.TaskETag(dn, tn))
 		jsonResponse(w, r,
 			struct {
 				Name string // task name
@@ -20,6 +20,7 @@ import (
 // POST /api/model/:model/workset/:set/readonly/:readonly
 // If multiple models with same name exist then result is undefined.
 // If no such workset exist in database then empty result returned.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
 func worksetReadonlyUpdateHandler(w http.ResponseWriter, r *http.Request) {
 
 	dn := getRequestParam(r, "model")
@@ -33,14 +34,23 @@ func worksetReadonlyUpdateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
 	// update workset read-only status
-	digest, ws, ok, err := theCatalog.UpdateWorksetReadonly(dn, wsn, isReadonly)
+	digest, ws, ok, err := theCatalog.UpdateWorksetReadonly(dn, wsn, isReadonly, requestUpdateAction(r, "", updDt))
 	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
 		http.Error(w, helper.MsgL(lang, "Error at updating workset read-only flag", wsn), http.StatusBadRequest)
 		return
 	}
 	if ok {
 		w.Header().Set("Content-Location", "/api/model/"+digest+"/workset/"+ws.Name)
+		setETag(w, etagOf(ws))
 	} else {
 		ws = &db.WorksetRow{}
 	}
@@ -109,7 +119,7 @@ func worksetCreateHandler(w http.ResponseWriter, r *http.Request) {
 		Param: []db.ParamRunSetPub{},
 	}
 
-	ok, _, wsRow, err := theCatalog.UpdateWorkset(true, &newWp)
+	ok, _, wsRow, err := theCatalog.UpdateWorkset(true, &newWp, nil)
 	if err != nil {
 		http.Error(w, helper.MsgL(lang, "Failed to create workset metadata", dn, ":", wsn, ":", err), http.StatusBadRequest)
 		return
@@ -123,13 +133,13 @@ func worksetCreateHandler(w http.ResponseWriter, r *http.Request) {
 	for k := range wp.Param {
 		switch wp.Param[k].Kind {
 		case "run":
-			if e := theCatalog.CopyParameterToWsFromRun(dn, wsn, wp.Param[k].Name, false, wp.Param[k].From); e != nil {
+			if e := theCatalog.CopyParameterToWsFromRun(dn, wsn, wp.Param[k].Name, false, wp.Param[k].From, nil); e != nil {
 				http.Error(w, helper.MsgL(lang, "Failed to copy parameter from model run", wsn, ":", wp.Param[k].Name, ":", wp.Param[k].From, ":", e), http.StatusBadRequest)
 				return
 			}
 			continue
 		case "set":
-			if e := theCatalog.CopyParameterBetweenWs(dn, wsn, wp.Param[k].Name, false, wp.Param[k].From); e != nil {
+			if e := theCatalog.CopyParameterBetweenWs(dn, wsn, wp.Param[k].Name, false, wp.Param[k].From, nil); e != nil {
 				http.Error(w, helper.MsgL(lang, "Failed to copy parameter from workset", wsn, ":", wp.Param[k].Name, ":", wp.Param[k].From, ":", e), http.StatusBadRequest)
 				return
 			}
@@ -140,7 +150,7 @@ func worksetCreateHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, helper.MsgL(lang, "Invalid (or empty) workset parameter values", wsn, ":", wp.Param[k].Name), http.StatusBadRequest)
 			return
 		}
-		if _, e := theCatalog.UpdateWorksetParameter(true, &newWp, &wp.Param[k].ParamRunSetPub, wp.Param[k].Value); e != nil {
+		if _, e := theCatalog.UpdateWorksetParameter(true, &newWp, &wp.Param[k].ParamRunSetPub, wp.Param[k].Value, nil); e != nil {
 			http.Error(w, helper.MsgL(lang, "Failed to update workset parameter", wsn, ":", wp.Param[k].Name, ":", e), http.StatusBadRequest)
 			return
 		}
@@ -148,7 +158,7 @@ func worksetCreateHandler(w http.ResponseWriter, r *http.Request) {
 
 	// if required make workset read-only
 	if wp.IsReadonly {
-		theCatalog.UpdateWorksetReadonly(dn, wsn, wp.IsReadonly)
+		theCatalog.UpdateWorksetReadonly(dn, wsn, wp.IsReadonly, nil)
 	}
 	w.Header().Set("Content-Location", "/api/model/"+dn+"/workset/"+wsn) // respond with workset location
 	jsonResponse(w, r, wsRow)
@@ -198,6 +208,7 @@ func worksetMergeHandler(w http.ResponseWriter, r *http.Request) {
 // Json content: workset "public" metadata.
 // If parameter not already exist in workset then parameter values must be supplied.
 // It is an error to add parameter metadata without parameter values.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
 func worksetUpdateHandler(isReplace bool, w http.ResponseWriter, r *http.Request) {
 
 	lang := preferedRequestLang(r, "") // get prefered language for messages
@@ -224,6 +235,11 @@ func worksetUpdateHandler(isReplace bool, w http.ResponseWriter, r *http.Request
 		newWp.Name = "set_" + ts
 	}
 
+	updDt, isOk := isWorksetIfMatch(w, r, dn, newWp.Name)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
 	// get existing workset metadata
 	oldWp, _, err := theCatalog.WorksetTextFull(dn, newWp.Name, true, nil)
 	if err != nil {
@@ -250,8 +266,11 @@ func worksetUpdateHandler(isReplace bool, w http.ResponseWriter, r *http.Request
 	isReadonly := newWp.IsReadonly
 	newWp.IsReadonly = false
 
-	ok, _, wsRow, err := theCatalog.UpdateWorkset(isReplace, &newWp)
+	ok, _, wsRow, err := theCatalog.UpdateWorkset(isReplace, &newWp, requestUpdateAction(r, "", updDt))
 	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
 		http.Error(w, helper.MsgL(lang, "Failed to update workset metadata", dn, ":", newWp.Name, ":", err), http.StatusBadRequest)
 		return
 	}
@@ -306,7 +325,7 @@ func worksetUpdateHandler(isReplace bool, w http.ResponseWriter, r *http.Request
 		csvRd.TrimLeadingSpace = true
 		csvRd.ReuseRecord = true
 
-		_, err = theCatalog.UpdateWorksetParameterCsv(isReplace, &newWp, &newParamLst[np], csvRd)
+		_, err = theCatalog.UpdateWorksetParameterCsv(isReplace, &newWp, &newParamLst[np], csvRd, requestUpdateAction(r, "csv", ""))
 		part.Close() // done with csv parameter data
 		if err != nil {
 			http.Error(w, helper.MsgL(lang, "Failed to update workset parameter", newWp.Name, ":", name, ":", err), http.StatusBadRequest)
@@ -323,7 +342,7 @@ func worksetUpdateHandler(isReplace bool, w http.ResponseWriter, r *http.Request
 		}
 
 		// update only parameter metadata
-		_, err = theCatalog.UpdateWorksetParameterCsv(isReplace, &newWp, &newParamLst[k], nil)
+		_, err = theCatalog.UpdateWorksetParameterCsv(isReplace, &newWp, &newParamLst[k], nil, requestUpdateAction(r, "metadata", ""))
 		if err != nil {
 			http.Error(w, helper.MsgL(lang, "Failed to update workset parameter", newWp.Name, ":", newParamLst[k].Name, ":", err), http.StatusBadRequest)
 			return
@@ -332,10 +351,11 @@ func worksetUpdateHandler(isReplace bool, w http.ResponseWriter, r *http.Request
 
 	// if required make workset read-only
 	if isReadonly {
-		theCatalog.UpdateWorksetReadonly(dn, newWp.Name, isReadonly)
+		theCatalog.UpdateWorksetReadonly(dn, newWp.Name, isReadonly, nil)
 	}
 
 	w.Header().Set("Content-Location", "/api/model/"+dn+"/workset/"+newWp.Name) // respond with workset location
+	setETag(w, theCatalog.WorksetETag(dn, newWp.Name))
 	jsonResponse(w, r, wsRow)
 }
 
@@ -343,15 +363,24 @@ func worksetUpdateHandler(isReplace bool, w http.ResponseWriter, r *http.Request
 // DELETE /api/model/:model/workset/:set
 // If multiple models with same name exist then result is undefined.
 // If no such workset exist in database then no error, empty operation.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
 func worksetDeleteHandler(w http.ResponseWriter, r *http.Request) {
 
 	dn := getRequestParam(r, "model")
 	wsn := getRequestParam(r, "set")
 	lang := preferedRequestLang(r, "") // get prefered language for messages
 
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
 	// delete workset
-	ok, err := theCatalog.DeleteWorkset(dn, wsn)
+	ok, err := theCatalog.DeleteWorkset(dn, wsn, requestUpdateAction(r, "", updDt))
 	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
 		http.Error(w, helper.MsgL(lang, "Workset delete failed", dn, ":", wsn), http.StatusBadRequest)
 		return
 	}
@@ -388,14 +417,14 @@ func worksetListDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// set read-write status
-		_, _, _, err := theCatalog.UpdateWorksetReadonly(dn, name, false)
+		_, _, _, err := theCatalog.UpdateWorksetReadonly(dn, name, false, nil)
 		if err != nil {
 			http.Error(w, helper.MsgL(lang, "Error at clear workset read-only", dn, ":", name), http.StatusBadRequest)
 			return
 		}
 
 		// delete workset
-		ok, err := theCatalog.DeleteWorkset(dn, name)
+		ok, err := theCatalog.DeleteWorkset(dn, name, nil)
 		if err != nil {
 			http.Error(w, helper.MsgL(lang, "Workset delete failed", dn, ":", name), http.StatusBadRequest)
 			return
@@ -429,6 +458,7 @@ func parameterIdPageUpdateHandler(w http.ResponseWriter, r *http.Request) {
 // doUpdateParameterPageHandler update a "page" of workset parameter values.
 // Page is part of parameter values defined by zero-based "start" row number and row count.
 // Dimension(s) and enum-based parameters can be as enum codes or enum id's.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
 func doUpdateParameterPageHandler(w http.ResponseWriter, r *http.Request, isCode bool) {
 
 	// url or query parameters
@@ -496,22 +526,296 @@ func doUpdateParameterPageHandler(w http.ResponseWriter, r *http.Request, isCode
 		}
 	}
 
-	// update parameter values
-	err := theCatalog.UpdateWorksetParameterPage(dn, wsn, name, from)
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
+	// update parameter values and save current parameter values in workset history
+	err := theCatalog.UpdateWorksetParameterPage(dn, wsn, name, requestUpdateAction(r, "page", updDt), from)
 	if err != nil {
 		omppLog.LogNoLT(err)
-		http.Error(w, helper.MsgL(lang, "Workset parameter update failed", wsn, ":", name), http.StatusBadRequest)
+		http.Error(w, helper.MsgL(lang, "Workset parameter update failed", wsn, ":", name, ":", err), http.StatusBadRequest)
 		return
 	}
 
 	w.Header().Set("Content-Location", "/api/model/"+dn+"/workset/"+wsn+"/parameter/"+name) // respond with workset parameter location
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
 	w.Header().Set("Content-Type", "text/plain")
 }
 
+// parameterFormulaUpdateHandler does bulk update of workset parameter values by formula:
+// PATCH /api/model/:model/workset/:set/parameter/:name/new/formula
+// Json body expected to contain formula and optional dimension filters, for example:
+//
+//	{"Formula": "ageSex * 1.05", "Filter": [{"Name": "dim1", "Op": "=", "Values": ["M"]}]}
+//
+// Formula is an arithmetic expression over current parameter value and other parameters values, ex.: ageSex * salaryFull.
+// Formula can use OM_IF, OM_DIV_BY and math functions: ABS, SQRT, EXP, LN, LOG10, POWER, FLOOR, CEIL, CEILING, ROUND, SIGN, MOD.
+// Filters are combined by AND, filter by parameter name is a filter by current value, ex.: ageSex > 0.5.
+// Parameter must be float type and already in workset, workset must be in read-write state.
+// Current parameter values are saved in workset history before update.
+// Response is parameter name and number of changed cells.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
+func parameterFormulaUpdateHandler(w http.ResponseWriter, r *http.Request) {
+
+	// url or query parameters
+	dn := getRequestParam(r, "model")  // model digest-or-name
+	wsn := getRequestParam(r, "set")   // workset name
+	name := getRequestParam(r, "name") // parameter name
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	// decode json formula and filters
+	var layout db.WorksetParamFormula
+	if !jsonRequestDecode(w, r, true, &layout) {
+		return // error at json decode, response done with http error
+	}
+	layout.Name = name
+
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
+	// update parameter values and save current parameter values in workset history
+	n, err := theCatalog.UpdateWorksetParameterFormula(dn, wsn, &layout, requestUpdateAction(r, "formula", updDt))
+	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
+		http.Error(w, helper.MsgL(lang, "Workset parameter update failed", wsn, ":", name, ":", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Location", "/api/model/"+dn+"/workset/"+wsn+"/parameter/"+name) // respond with workset parameter location
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
+	jsonResponse(w, r,
+		struct {
+			Name  string // parameter name
+			Count int64  // number of changed cells
+		}{
+			Name:  name,
+			Count: n,
+		},
+	)
+}
+
+// parameterSparseUpdateHandler make workset parameter sparse: store in workset only overridden cells:
+// PATCH /api/model/:model/workset/:set/parameter/:name/sparse
+// Cells which are the same as in workset base run (or default workset if workset is not run-based) are removed from workset.
+// Parameter values are resolved on read: overridden cells from workset and all other cells from base run or default workset.
+// Response is parameter storage size: number of stored (overridden) cells and number of saved cells.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
+func parameterSparseUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	doParameterSparseUpdate(w, r, true)
+}
+
+// parameterResolveUpdateHandler store all cells of sparse workset parameter in workset:
+// PATCH /api/model/:model/workset/:set/parameter/:name/resolve
+// After update parameter is not sparse anymore.
+// Response is parameter storage size.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
+func parameterResolveUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	doParameterSparseUpdate(w, r, false)
+}
+
+// make workset parameter sparse or resolve sparse workset parameter
+func doParameterSparseUpdate(w http.ResponseWriter, r *http.Request, isSparse bool) {
+
+	// url or query parameters
+	dn := getRequestParam(r, "model")  // model digest-or-name
+	wsn := getRequestParam(r, "set")   // workset name
+	name := getRequestParam(r, "name") // parameter name
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
+	// update parameter and save current parameter values in workset history
+	action := "resolve"
+	if isSparse {
+		action = "sparse"
+	}
+	ps, err := theCatalog.UpdateWorksetParameterSparse(dn, wsn, name, isSparse, requestUpdateAction(r, action, updDt))
+	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
+		http.Error(w, helper.MsgL(lang, "Workset parameter update failed", wsn, ":", name, ":", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Location", "/api/model/"+dn+"/workset/"+wsn+"/parameter/"+name) // respond with workset parameter location
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
+	jsonResponse(w, r, ps)
+}
+
+// worksetImportFromRunHandler create new workset from upstream model run using model parameters import:
+//
+//	PUT /api/model/:model/workset/:set/import/from-model/:from-model/run/:run
+//
+// Each parameter of the model which is imported from upstream model (model_parameter_import) is included in new workset,
+// values are read from upstream model run output table or parameter.
+// It is an error if workset already exist. Upstream model run must be completed.
+// Response is a list of imported parameters provenance: upstream model, run, output table or parameter and value digest.
+func worksetImportFromRunHandler(w http.ResponseWriter, r *http.Request) {
+
+	// url or query parameters
+	dn := getRequestParam(r, "model")        // model digest-or-name
+	wsn := getRequestParam(r, "set")         // workset name
+	upDn := getRequestParam(r, "from-model") // upstream model digest-or-name
+	rdsn := getRequestParam(r, "run")        // upstream run digest or stamp or name
+	lang := preferedRequestLang(r, "")       // get prefered language for messages
+
+	isLst, err := theCatalog.ImportWorksetFromRun(dn, wsn, upDn, rdsn, requestUpdateAction(r, "import", ""))
+	if err != nil {
+		omppLog.LogNoLT(err)
+		http.Error(w, helper.FmtL(lang, "Workset import failed %s: from model: %s run: %s", wsn, upDn, rdsn), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Location", "/api/model/"+dn+"/workset/"+wsn) // respond with workset location
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
+	jsonResponse(w, r, isLst)
+}
+
+// worksetThreeWayMergeHandler does three-way merge of two worksets using base run and save result into new workset:
+//
+//	PUT /api/model/:model/workset/:set/three-way-merge
+//
+// Json content: names of workset A and workset B, optional base run digest, stamp or name and conflict resolution:
+//
+//	{"SetA": "MyDataA", "SetB": "MyDataB", "BaseRun": "Default", "Resolve": "a"}
+//
+// If base run is empty then base run of workset A is used.
+// Cells changed only in one workset merged automatically, cells changed in both worksets are conflicts.
+// Conflict resolution can be: "a" or "b" to use workset A or workset B value, "base" or empty to keep base run value.
+// Merged workset created as read-write, it is an error if such workset already exist.
+// Response is a merge report: summary by parameters and list of conflicts, dimension items are enum codes.
+func worksetThreeWayMergeHandler(w http.ResponseWriter, r *http.Request) {
+
+	// url or query parameters
+	dn := getRequestParam(r, "model")  // model digest-or-name
+	wsn := getRequestParam(r, "set")   // merged workset name
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	// decode json merge request
+	var wm db.WorksetMerge
+	if !jsonRequestDecode(w, r, true, &wm) {
+		return // error at json decode, response done with http error
+	}
+	wm.Name = wsn
+
+	rpt, err := theCatalog.MergeWorksets(dn, &wm)
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Workset merge failed", wsn, ":", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Location", "/api/model/"+dn+"/workset/"+wsn) // respond with workset location
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
+	jsonResponse(w, r, rpt)
+}
+
+// worksetTemplateApplyHandler create new workset from template: base run or base workset and parameter overrides:
+//
+//	PUT /api/model/:model/workset/:set/template
+//
+// Template content can be json (Content-Type: application/json) or yaml, for example:
+//
+//	BaseRun: Default
+//	Override:
+//	  - Name: ageSex
+//	    Filter:
+//	      - Name: dim0
+//	        Op: IN
+//	        Values: [10-20, 20-30]
+//	    Value: 0.5
+//	  - Name: salaryFull
+//	    Formula: salaryFull * 1.05
+//
+// Dimension items in filters and enum-based values expected to be enum codes, see db.WorksetTemplate for details.
+// It is an error if workset already exist. Template is stored with the workset and can be re-applied to newer base run.
+// Response is a template with actual base run digest and number of updated cells by each override.
+func worksetTemplateApplyHandler(w http.ResponseWriter, r *http.Request) {
+
+	// url or query parameters
+	dn := getRequestParam(r, "model")  // model digest-or-name
+	wsn := getRequestParam(r, "set")   // workset name
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	// decode json or yaml template
+	src, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Failed to read workset template", wsn, ":", err), http.StatusBadRequest)
+		return
+	}
+	var tpl db.WorksetTemplate
+	isOk, err := helper.FromYaml(src, &tpl)
+	if err != nil {
+		omppLog.Log("Workset template decode error at ", r.URL.String(), ": ", err.Error())
+		http.Error(w, helper.MsgL(lang, "Workset template decode error", wsn), http.StatusBadRequest)
+		return
+	}
+	if !isOk {
+		http.Error(w, helper.MsgL(lang, "Invalid (empty) workset template", wsn), http.StatusBadRequest)
+		return
+	}
+
+	t, err := theCatalog.ApplyWorksetTemplate(dn, wsn, false, &tpl, requestUpdateAction(r, "template", ""))
+	if err != nil {
+		http.Error(w, helper.MsgL(lang, "Workset template apply failed", wsn, ":", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Location", "/api/model/"+dn+"/workset/"+wsn) // respond with workset location
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
+	jsonResponse(w, r, t)
+}
+
+// worksetTemplateReapplyHandler create workset again from stored workset template using new base run:
+//
+//	PATCH /api/model/:model/workset/:set/template
+//	PATCH /api/model/:model/workset/:set/template/base-run/:run
+//
+// If base run digest, stamp or name is not specified then template base run is used.
+// All workset parameters deleted and created again from base run, base workset and template overrides.
+// Response is a template with actual base run digest and number of updated cells by each override.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
+func worksetTemplateReapplyHandler(w http.ResponseWriter, r *http.Request) {
+
+	// url or query parameters
+	dn := getRequestParam(r, "model")  // model digest-or-name
+	wsn := getRequestParam(r, "set")   // workset name
+	rdsn := getRequestParam(r, "run")  // new base run digest or stamp or name
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
+	t, err := theCatalog.ReapplyWorksetTemplate(dn, wsn, rdsn, requestUpdateAction(r, "template", updDt))
+	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
+		http.Error(w, helper.MsgL(lang, "Workset template apply failed", wsn, ":", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Location", "/api/model/"+dn+"/workset/"+wsn) // respond with workset location
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
+	jsonResponse(w, r, t)
+}
+
 // worksetParameterDeleteHandler delete workset parameter:
 // DELETE /api/model/:model/workset/:set/parameter/:name
 // If multiple models with same name exist then result is undefined.
 // If no such parameter or workset exist in database then no error, empty operation.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
 func worksetParameterDeleteHandler(w http.ResponseWriter, r *http.Request) {
 
 	dn := getRequestParam(r, "model")
@@ -519,9 +823,17 @@ func worksetParameterDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	name := getRequestParam(r, "name")
 	lang := preferedRequestLang(r, "") // get prefered language for messages
 
-	// delete workset parameter
-	ok, err := theCatalog.DeleteWorksetParameter(dn, wsn, name)
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
+	// delete workset parameter and save current parameter values in workset history
+	ok, err := theCatalog.DeleteWorksetParameter(dn, wsn, name, requestUpdateAction(r, "delete", updDt))
 	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
 		http.Error(w, helper.MsgL(lang, "Workset parameter delete failed", wsn, ":", name), http.StatusBadRequest)
 		return
 	}
@@ -554,6 +866,7 @@ func worksetParameterRunMergeHandler(w http.ResponseWriter, r *http.Request) {
 
 // worksetParameterRunCopy do copy parameter from model run into workset.
 // if isReplace is true then it insert new else merge: insert new or update existing.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
 func worksetParameterRunCopy(isReplace bool, w http.ResponseWriter, r *http.Request) {
 
 	// url or query parameters
@@ -563,9 +876,17 @@ func worksetParameterRunCopy(isReplace bool, w http.ResponseWriter, r *http.Requ
 	rdsn := getRequestParam(r, "run")  // source run digest or stamp or name
 	lang := preferedRequestLang(r, "") // get prefered language for messages
 
-	// copy workset parameter from model run
-	err := theCatalog.CopyParameterToWsFromRun(dn, wsn, name, isReplace, rdsn)
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
+	// copy workset parameter from model run and save current parameter values in workset history
+	err := theCatalog.CopyParameterToWsFromRun(dn, wsn, name, isReplace, rdsn, requestUpdateAction(r, "copy-run", updDt))
 	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
 		omppLog.LogNoLT(err)
 		http.Error(w, helper.FmtL(lang, "Workset parameter copy failed %s: %s from run: %s", wsn, name, rdsn), http.StatusBadRequest)
 		return
@@ -595,6 +916,7 @@ func worksetParameterMergeFromWsHandler(w http.ResponseWriter, r *http.Request)
 
 // worksetParameterCopyFromWs does copy parameter from one workset to another.
 // if isReplace is true then it does insert new else merge: insert new or update existing.
+// If request has If-Match header and it does not match destination workset ETag then return 412 Precondition Failed.
 func worksetParameterCopyFromWs(isReplace bool, w http.ResponseWriter, r *http.Request) {
 
 	// url or query parameters
@@ -604,9 +926,17 @@ func worksetParameterCopyFromWs(isReplace bool, w http.ResponseWriter, r *http.R
 	srcWsName := getRequestParam(r, "from-set") // source run digest or name
 	lang := preferedRequestLang(r, "")          // get prefered language for messages
 
-	// copy workset parameter from other workset
-	err := theCatalog.CopyParameterBetweenWs(dn, dstWsName, name, isReplace, srcWsName)
+	updDt, isOk := isWorksetIfMatch(w, r, dn, dstWsName)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
+	// copy workset parameter from other workset and save current parameter values in workset history
+	err := theCatalog.CopyParameterBetweenWs(dn, dstWsName, name, isReplace, srcWsName, requestUpdateAction(r, "copy-set", updDt))
 	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
 		omppLog.LogNoLT(err)
 		http.Error(w, helper.FmtL(lang, "Workset parameter copy failed %s: %s from run: %s", dstWsName, name, srcWsName), http.StatusBadRequest)
 		return
@@ -623,6 +953,7 @@ func worksetParameterCopyFromWs(isReplace bool, w http.ResponseWriter, r *http.R
 // If parameter not exist in workset then return error.
 // Input json must be array of ParamRunSetTxtPub,
 // if parameters text array is empty then nothing updated, it is empty operation return is success
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
 func worksetParameterTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 
 	// url or query parameters
@@ -636,9 +967,17 @@ func worksetParameterTextMergeHandler(w http.ResponseWriter, r *http.Request) {
 		return // error at json decode, response done with http error
 	}
 
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
 	// update workset parameter value notes in model catalog
-	ok, err := theCatalog.UpdateWorksetParameterText(dn, wsn, pvtLst)
+	ok, err := theCatalog.UpdateWorksetParameterText(dn, wsn, pvtLst, requestUpdateAction(r, "", updDt))
 	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
 		omppLog.LogNoLT(err)
 		http.Error(w, helper.MsgL(lang, "Workset parameter(s) value notes update failed", dn, ":", wsn, ":", err), http.StatusBadRequest)
 		return
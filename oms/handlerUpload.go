@@ -152,6 +152,8 @@ func runUploadPostHandler(w http.ResponseWriter, r *http.Request) {
 //
 // Zip archive is the same as created by dbcopy command line utilty.
 // Dimension(s) and enum-based parameters returned as enum codes, not enum id's.
+// Instead of zip archive it can be Excel workbook modelName.set.WorksetName.xlsx with one sheet per parameter,
+// sheet layout is the same as parameter csv file: sub_id,dim0,dim1,param_value
 // Posted multi-part form can have optional "workset-upload-options" part with json upload options
 // Upload option NoDigestCheck=true do suppress model digest verification:
 // model digest in source zip is ignored, only model name is used and that allows to upload worksets into different model version.
@@ -221,7 +223,7 @@ func worksetUploadPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer part.Close()
 
-	// check file name: it should be modelName.set.WorksetName.zip
+	// check file name: it should be modelName.set.WorksetName.zip or modelName.set.WorksetName.xlsx
 	// if workset name not specified in URL the get it from file name
 	fName := part.FileName()
 	ext := path.Ext(fName)
@@ -235,12 +237,14 @@ func worksetUploadPostHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, helper.MsgL(lang, "Error: invalid (or empty) file name:", fName), http.StatusBadRequest)
 		return
 	}
-	if ext != ".zip" || !strings.HasPrefix(baseName, mpn) {
-		http.Error(w, helper.MsgL(lang, "Error: file name must be:", mpn+"Name.zip"), http.StatusBadRequest)
+	isXlsx := ext == ".xlsx"
+
+	if ext != ".zip" && !isXlsx || !strings.HasPrefix(baseName, mpn) {
+		http.Error(w, helper.MsgL(lang, "Error: file name must be:", mpn+"Name.zip", "or", mpn+"Name.xlsx"), http.StatusBadRequest)
 		return
 	}
 	if wsn != "" && setName != wsn {
-		http.Error(w, helper.MsgL(lang, "Error: invalid file name, expected:", mpn+wsn+".zip"), http.StatusBadRequest)
+		http.Error(w, helper.MsgL(lang, "Error: invalid file name, expected:", mpn+wsn+ext), http.StatusBadRequest)
 		return
 	}
 
@@ -285,7 +289,7 @@ func worksetUploadPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// save set.zip into upload directory
+	// save set.zip or set.xlsx into upload directory
 	saveToPath := filepath.Join(theCfg.uploadDir, fName)
 
 	helper.SaveTo(saveToPath, part)
@@ -296,7 +300,7 @@ func worksetUploadPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create model scenario upload files on separate thread
-	cmd, cmdMsg := makeWorksetUploadCommand(mb, setName, logPath, isNoDigestCheck, lang)
+	cmd, cmdMsg := makeWorksetUploadCommand(mb, setName, logPath, isNoDigestCheck, isXlsx, lang)
 
 	go makeUpload(baseName, cmd, cmdMsg, logPath)
 
@@ -0,0 +1,183 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/helper"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// worksetHistoryGetHandler return list of saved versions of all workset parameters:
+// GET /api/model/:model/workset/:set/history
+// Version is saved before each workset parameter update: it contains parameter values before update,
+// user name, update action and date-time.
+// If multiple models with same name exist then result is undefined.
+// If no history saved for that workset then empty list returned.
+func worksetHistoryGetHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	wsn := getRequestParam(r, "set")
+
+	hLst, _ := theCatalog.WorksetHistoryList(dn, wsn, "")
+	jsonResponse(w, r, hLst)
+}
+
+// worksetParameterHistoryGetHandler return list of saved versions of workset parameter:
+// GET /api/model/:model/workset/:set/parameter/:name/history
+// If multiple models with same name exist then result is undefined.
+// If no history saved for that parameter then empty list returned.
+func worksetParameterHistoryGetHandler(w http.ResponseWriter, r *http.Request) {
+
+	dn := getRequestParam(r, "model")
+	wsn := getRequestParam(r, "set")
+	name := getRequestParam(r, "name")
+
+	hLst, _ := theCatalog.WorksetHistoryList(dn, wsn, name)
+	jsonResponse(w, r, hLst)
+}
+
+// worksetParameterHistoryDiffGetHandler compare two versions of workset parameter:
+// GET /api/model/:model/workset/:set/parameter/:name/history-diff/:from/:to
+// If version is zero then current workset parameter values are used, for example: history-diff/3/0 is the difference
+// between version 3 and current values.
+// Response is a list of inserted, deleted and updated cells with old and new cell values.
+// Dimension(s) and enum-based parameters returned as enum codes.
+func worksetParameterHistoryDiffGetHandler(w http.ResponseWriter, r *http.Request) {
+	doWorksetParameterHistoryDiffHandler(w, r, true)
+}
+
+// worksetParameterHistoryDiffIdGetHandler compare two versions of workset parameter:
+// GET /api/model/:model/workset/:set/parameter/:name/history-diff-id/:from/:to
+// If version is zero then current workset parameter values are used.
+// Response is a list of inserted, deleted and updated cells with old and new cell values.
+// Dimension(s) and enum-based parameters returned as enum id, not enum codes.
+func worksetParameterHistoryDiffIdGetHandler(w http.ResponseWriter, r *http.Request) {
+	doWorksetParameterHistoryDiffHandler(w, r, false)
+}
+
+// doWorksetParameterHistoryDiffHandler compare two versions of workset parameter.
+// Dimension(s) and enum-based parameters returned as enum codes or enum id's.
+func doWorksetParameterHistoryDiffHandler(w http.ResponseWriter, r *http.Request, isCode bool) {
+
+	// url or query parameters
+	dn := getRequestParam(r, "model")  // model digest-or-name
+	wsn := getRequestParam(r, "set")   // workset name
+	name := getRequestParam(r, "name") // parameter name
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	fromVer, ok := getIntRequestParam(r, "from", 0)
+	if !ok || fromVer < 0 {
+		http.Error(w, helper.MsgL(lang, "Invalid workset parameter version", wsn, ":", name, ":", getRequestParam(r, "from")), http.StatusBadRequest)
+		return
+	}
+	toVer, ok := getIntRequestParam(r, "to", 0)
+	if !ok || toVer < 0 {
+		http.Error(w, helper.MsgL(lang, "Invalid workset parameter version", wsn, ":", name, ":", getRequestParam(r, "to")), http.StatusBadRequest)
+		return
+	}
+
+	dLst, ok := theCatalog.WorksetHistoryDiff(dn, wsn, name, fromVer, toVer)
+	if !ok {
+		http.Error(w, helper.MsgL(lang, "Error at workset parameter versions compare", wsn, ":", name), http.StatusBadRequest)
+		return
+	}
+
+	// convert cells from enum id's to enum codes
+	if isCode {
+
+		cvt, ok := theCatalog.ParameterCellConverter(false, dn, name)
+		if !ok {
+			http.Error(w, helper.MsgL(lang, "Error at workset parameter versions compare", wsn, ":", name), http.StatusBadRequest)
+			return
+		}
+		for k := range dLst {
+
+			var err error
+			if dLst[k].Old != nil {
+				if dLst[k].Old, err = cvt(dLst[k].Old); err != nil {
+					omppLog.Log("Error at parameter cell conversion: ", name, ": ", err.Error())
+					http.Error(w, helper.MsgL(lang, "Error at workset parameter versions compare", wsn, ":", name), http.StatusBadRequest)
+					return
+				}
+			}
+			if dLst[k].New != nil {
+				if dLst[k].New, err = cvt(dLst[k].New); err != nil {
+					omppLog.Log("Error at parameter cell conversion: ", name, ": ", err.Error())
+					http.Error(w, helper.MsgL(lang, "Error at workset parameter versions compare", wsn, ":", name), http.StatusBadRequest)
+					return
+				}
+			}
+		}
+	}
+	jsonResponse(w, r, dLst)
+}
+
+// worksetHistoryRestoreHandler restore all workset parameters to the state when version saved:
+// PUT /api/model/:model/workset/:set/history-restore/:version
+// Each parameter updated after that version saved is restored from its first version saved after that moment.
+// Current values of restored parameters are saved in history before restore, so restore can be undone.
+// Workset must be in read-write state.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
+func worksetHistoryRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	doWorksetHistoryRestore(w, r, "")
+}
+
+// worksetParameterHistoryRestoreHandler restore workset parameter values from saved version:
+// PUT /api/model/:model/workset/:set/parameter/:name/history-restore/:version
+// Current parameter values are saved in history before restore, so restore can be undone.
+// If parameter was not in workset at that version then parameter deleted from workset.
+// Workset must be in read-write state.
+// If request has If-Match header and it does not match workset ETag then return 412 Precondition Failed.
+func worksetParameterHistoryRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	doWorksetHistoryRestore(w, r, getRequestParam(r, "name"))
+}
+
+// doWorksetHistoryRestore restore workset parameter or all workset parameters from saved version.
+func doWorksetHistoryRestore(w http.ResponseWriter, r *http.Request, name string) {
+
+	// url or query parameters
+	dn := getRequestParam(r, "model")  // model digest-or-name
+	wsn := getRequestParam(r, "set")   // workset name
+	lang := preferedRequestLang(r, "") // get prefered language for messages
+
+	verId, ok := getIntRequestParam(r, "version", 0)
+	if !ok || verId <= 0 {
+		http.Error(w, helper.MsgL(lang, "Invalid workset parameter version", wsn, ":", name, ":", getRequestParam(r, "version")), http.StatusBadRequest)
+		return
+	}
+
+	updDt, isOk := isWorksetIfMatch(w, r, dn, wsn)
+	if !isOk {
+		return // workset updated by other user, response done with http error
+	}
+
+	nv, err := theCatalog.RestoreWorksetParamHistory(dn, wsn, name, verId, requestUpdateAction(r, "restore", updDt))
+	if err != nil {
+		if isWorksetUpdatedError(w, r, err) {
+			return // workset updated by other user, response done with http error
+		}
+		omppLog.LogNoLT(err)
+		http.Error(w, helper.MsgL(lang, "Workset restore failed", wsn, ":", name, ":", verId), http.StatusBadRequest)
+		return
+	}
+
+	loc := "/api/model/" + dn + "/workset/" + wsn
+	if name != "" {
+		loc += "/parameter/" + name
+	}
+	w.Header().Set("Content-Location", loc+"/history/"+strconv.Itoa(nv))
+	setETag(w, theCatalog.WorksetETag(dn, wsn))
+	w.Header().Set("Content-Type", "text/plain")
+}
+
+// make workset update action of http request: user name, parameter update action, ex.: page, csv, delete,
+// and workset update date-time from If-Match header to check it inside of update transaction.
+// If action is empty then parameter versions are not saved in workset history.
+func requestUpdateAction(r *http.Request, action, updateDt string) *db.WorksetUpdateAction {
+	return &db.WorksetUpdateAction{UserName: getRequestUser(r), Action: action, UpdateDateTime: updateDt}
+}
@@ -28,8 +28,8 @@ type UpDownStatusLog struct {
 	IsFolder      bool     // if true then download (or upload) folder exist
 	Folder        string   // content of "Folder:"
 	FolderModTime int64    // folder modification time in milliseconds since epoch
-	IsZip         bool     // if true then download (or upload) zip exist
-	ZipFileName   string   // zip file name
+	IsZip         bool     // if true then download (or upload) zip or xlsx exist
+	ZipFileName   string   // zip or xlsx file name
 	ZipModTime    int64    // zip modification time in milliseconds since epoch
 	ZipSize       int64    // zip file size
 	LogFileName   string   // log file name
@@ -91,14 +91,25 @@ func makeModelDownloadCommand(mb modelBasic, logPath string, isNoAcc bool, isNoM
 }
 
 // make dbcopy command to prepare model run download
-func makeRunDownloadCommand(mb modelBasic, runId int, logPath string, isNoAcc bool, isNoMd bool, isCsvBom bool, isIdCsv bool, msgLang string) (*exec.Cmd, string) {
+// if isXlsx is true then model run parameters and output tables exported into .xlsx workbook,
+// if xlsxLang is not empty then workbook contains language-specific labels else enum codes or enum id's
+func makeRunDownloadCommand(
+	mb modelBasic, runId int, logPath string, isNoAcc bool, isNoMd bool, isCsvBom bool, isIdCsv bool, isXlsx bool, xlsxLang string, msgLang string,
+) (*exec.Cmd, string) {
 
 	// make dbcopy message for user log
 	cmdMsg := "dbcopy -m " + mb.model.Name +
 		" -dbcopy.IdOutputNames=false" +
 		" -dbcopy.RunId " + strconv.Itoa(runId) +
-		" -dbcopy.Zip" +
 		" -dbcopy.OutputDir " + theCfg.downloadDir
+	if isXlsx {
+		cmdMsg += " -dbcopy.Xlsx"
+		if xlsxLang != "" {
+			cmdMsg += " -dbcopy.Language " + xlsxLang
+		}
+	} else {
+		cmdMsg += " -dbcopy.Zip"
+	}
 	if isNoAcc {
 		cmdMsg += " -dbcopy.NoAccumulatorsCsv"
 	}
@@ -127,10 +138,17 @@ func makeRunDownloadCommand(mb modelBasic, runId int, logPath string, isNoAcc bo
 		"-m", mb.model.Name,
 		"-dbcopy.IdOutputNames=false",
 		"-dbcopy.RunId", strconv.Itoa(runId),
-		"-dbcopy.Zip",
 		"-dbcopy.OutputDir", downDir,
 		"-dbcopy.FromSqlite", dbPathRel,
 	}
+	if isXlsx {
+		cArgs = append(cArgs, "-dbcopy.Xlsx")
+		if xlsxLang != "" {
+			cArgs = append(cArgs, "-dbcopy.Language", xlsxLang)
+		}
+	} else {
+		cArgs = append(cArgs, "-dbcopy.Zip")
+	}
 	if isNoAcc {
 		cArgs = append(cArgs, "-dbcopy.NoAccumulatorsCsv")
 	}
@@ -155,14 +173,25 @@ func makeRunDownloadCommand(mb modelBasic, runId int, logPath string, isNoAcc bo
 }
 
 // make dbcopy command to prepare model workset download
-func makeWorksetDownloadCommand(mb modelBasic, setName string, logPath string, isCsvBom bool, isIdCsv bool, msgLang string) (*exec.Cmd, string) {
+// if isXlsx is true then workset parameters exported into .xlsx workbook,
+// if xlsxLang is not empty then workbook contains language-specific labels else enum codes or enum id's
+func makeWorksetDownloadCommand(
+	mb modelBasic, setName string, logPath string, isCsvBom bool, isIdCsv bool, isXlsx bool, xlsxLang string, msgLang string,
+) (*exec.Cmd, string) {
 
 	// make dbcopy message for user log
 	cmdMsg := "dbcopy -m " + mb.model.Name +
 		" -dbcopy.IdOutputNames=false" +
 		" -dbcopy.SetName " + setName +
-		" -dbcopy.Zip" +
 		" -dbcopy.OutputDir " + theCfg.downloadDir
+	if isXlsx {
+		cmdMsg += " -dbcopy.Xlsx"
+		if xlsxLang != "" {
+			cmdMsg += " -dbcopy.Language " + xlsxLang
+		}
+	} else {
+		cmdMsg += " -dbcopy.Zip"
+	}
 	if isCsvBom {
 		cmdMsg += " -dbcopy.Utf8BomIntoCsv"
 	}
@@ -185,10 +214,17 @@ func makeWorksetDownloadCommand(mb modelBasic, setName string, logPath string, i
 		"-m", mb.model.Name,
 		"-dbcopy.IdOutputNames=false",
 		"-dbcopy.SetName", setName,
-		"-dbcopy.Zip",
 		"-dbcopy.OutputDir", downDir,
 		"-dbcopy.FromSqlite", dbPathRel,
 	}
+	if isXlsx {
+		cArgs = append(cArgs, "-dbcopy.Xlsx")
+		if xlsxLang != "" {
+			cArgs = append(cArgs, "-dbcopy.Language", xlsxLang)
+		}
+	} else {
+		cArgs = append(cArgs, "-dbcopy.Zip")
+	}
 	if isCsvBom {
 		cArgs = append(cArgs, "-dbcopy.Utf8BomIntoCsv")
 	}
@@ -249,14 +285,21 @@ func makeRunUploadCommand(mb modelBasic, runName string, logPath string, msgLang
 }
 
 // make dbcopy command to prepare model workset import into database after upload
-func makeWorksetUploadCommand(mb modelBasic, setName string, logPath string, isNoDigestCheck bool, msgLang string) (*exec.Cmd, string) {
+// if isXlsx is true then workset parameters imported from .xlsx workbook else from .zip archive
+func makeWorksetUploadCommand(mb modelBasic, setName string, logPath string, isNoDigestCheck bool, isXlsx bool, msgLang string) (*exec.Cmd, string) {
+
+	// input is a workset .zip archive or .xlsx workbook
+	inpArg := "-dbcopy.Zip"
+	if isXlsx {
+		inpArg = "-dbcopy.Xlsx"
+	}
 
 	// make dbcopy message for user log
 	cmdMsg := "dbcopy -m " + mb.model.Name +
 		" -dbcopy.IdOutputNames=false" +
 		" -dbcopy.SetName " + setName +
 		" -dbcopy.To db" +
-		" -dbcopy.Zip" +
+		" " + inpArg +
 		" -dbcopy.InputDir " + theCfg.uploadDir
 	if isNoDigestCheck {
 		cmdMsg += " -dbcopy.NoDigestCheck"
@@ -278,7 +321,7 @@ func makeWorksetUploadCommand(mb modelBasic, setName string, logPath string, isN
 		"-dbcopy.IdOutputNames=false",
 		"-dbcopy.SetName", setName,
 		"-dbcopy.To", "db",
-		"-dbcopy.Zip",
+		inpArg,
 		"-dbcopy.InputDir", upDir,
 		"-dbcopy.ToSqlite", dbPathRel,
 	}
@@ -332,7 +375,7 @@ func makeDownload(baseName string, cmd *exec.Cmd, cmdMsg string, logPath string)
 
 // makeUpload invoke dbcopy to create model upload directory and .zip file:
 // 1. delete existing: previous upload log file and model.xyz directory.
-// 2. start dbcopy to unzip uploaded file and import into it model database.
+// 2. start dbcopy to read uploaded .zip file, without unpacking it, and import into model database.
 // 3. if dbcopy done OK then rename log file into model......ready.upload.log else into model......error.upload.log
 func makeUpload(baseName string, cmd *exec.Cmd, cmdMsg string, logPath string) {
 	runUpDownDbcopy("upload", theCfg.uploadDir, baseName, cmd, cmdMsg, logPath)
@@ -340,9 +383,9 @@ func makeUpload(baseName string, cmd *exec.Cmd, cmdMsg string, logPath string) {
 
 // runUpDownDbcopy invoke dbcopy to export from dbd into download .zip or import from uploaded .zip into model database.
 // 1. delete existing: previous log file and model.xyz directory.
-// 2. if download then delete existing model.xyz.zip
-// 3. if download: start dbcopy to export model data into .zip file.
-// 3. if upload: stsrt dbopy to unzip uploaded file and import into it model database.
+// 2. if download then delete existing model.xyz.zip and model.xyz.xlsx
+// 3. if download: start dbcopy to export model data into .zip file or .xlsx workbook.
+// 3. if upload: stsrt dbopy to read uploaded .zip file, without unpacking it, and import into model database.
 // 4. if dbcopy done OK then rename log file into model......ready.up-or-down.log else into model......error.up-or-down.log
 func runUpDownDbcopy(upDown string, upDownDir string, baseName string, cmd *exec.Cmd, cmdMsg string, logPath string) {
 
@@ -358,6 +401,9 @@ func runUpDownDbcopy(upDown string, upDownDir string, baseName string, cmd *exec
 	if upDown == "download" && !removeUpDownFile(upDown, basePath+".zip", logPath, baseName+".zip") {
 		return
 	}
+	if upDown == "download" && !removeUpDownFile(upDown, basePath+".xlsx", logPath, baseName+".xlsx") {
+		return
+	}
 	if !removeUpDownDir(upDown, basePath, logPath, baseName) {
 		return
 	}
@@ -496,7 +542,7 @@ func renameToUpDownErrorLog(upDown string, logPath string, logErrMsg string, err
 }
 
 // update file status of download or upload files:
-// check if zip exist, if folder exist and retirve file modification time in milliseconds since epoch
+// check if zip or xlsx exist, if folder exist and retirve file modification time in milliseconds since epoch
 func updateStatUpDownLog(logName string, uds *UpDownStatusLog, upDownDir string) {
 
 	// check if download zip or download folder exist
@@ -516,6 +562,15 @@ func updateStatUpDownLog(logName string, uds *UpDownStatusLog, upDownDir string)
 				uds.ZipSize = fi.Size()
 				uds.ZipModTime = fi.ModTime().UnixNano() / int64(time.Millisecond)
 			}
+			if !uds.IsZip { // download can be .xlsx workbook instead of .zip
+				fi, e = helper.FileStat(filepath.Join(upDownDir, uds.Folder+".xlsx"))
+				uds.IsZip = e == nil
+				if uds.IsZip {
+					uds.ZipFileName = uds.Folder + ".xlsx"
+					uds.ZipSize = fi.Size()
+					uds.ZipModTime = fi.ModTime().UnixNano() / int64(time.Millisecond)
+				}
+			}
 		}
 	}
 
@@ -101,6 +101,13 @@ Following arguments supporetd by oms:
 
 	if true then allow model runs microdata usage else model microdata API disabled.
 
+-oms.CalcCacheRows 100000
+
+	max number of rows in output tables and microdata calculation results cache, default: 100000.
+	Calculation results of completed model runs are cached in memory to speed up repeated requests,
+	least recently used results are removed from cache if max number of rows reached.
+	Use zero value to disable calculation results cache.
+
 -oms.ApiOnly false
 
 	if true then API only web-service, it is false by default and oms also act as http server for openM++ UI.
@@ -109,6 +116,12 @@ Following arguments supporetd by oms:
 
 	if true then log HTTP requests on console and/or log file.
 
+-oms.TrustProxyUser false
+
+	if true then oms is running behind trusted authenticating reverse proxy
+	and user name of HTTP request is taken from X-Forwarded-User header, e.g. to store user name in workset history.
+	Do not enable it if oms can be accessed directly, because any client can set X-Forwarded-User header.
+
 -oms.Readonly
 
 	if true then only read API enabled, no update, upload, model run or admin API allowed, download partially disabled
@@ -198,6 +211,7 @@ const (
 	filesDirArgKey     = "oms.FilesDir"          // user files directory, if relative then must be relative to oms root directory, if user home exists then: home/io
 	isMicrodataArgKey  = "oms.AllowMicrodata"    // if true then allow model run microdata
 	logRequestArgKey   = "oms.LogRequest"        // if true then log http request
+	proxyUserArgKey    = "oms.TrustProxyUser"    // if true then use X-Forwarded-User header from trusted reverse proxy as request user name
 	apiOnlyArgKey      = "oms.ApiOnly"           // if true then API only web-service, no web UI
 	readOnlyArgKey     = "oms.Readonly"          // if true then only read API enabled, no update, download, upload, model run or admin API allowed
 	adminAllArgKey     = "oms.AdminAll"          // if true then allow global administrative routes: /admin-all/
@@ -207,6 +221,7 @@ const (
 	msgLangArgKey      = "OpenM.MessageLanguage" // oms prefered output messages language, e.g. fr-CA
 	encodingArgKey     = "oms.CodePage"          // code page for converting source files, e.g. windows-1252
 	doubleFormatArgKey = "oms.DoubleFormat"      // format to convert float or double value to string, e.g. %.15g
+	calcCacheArgKey    = "oms.CalcCacheRows"     // max number of rows in calculation results cache, if zero then cache disabled
 )
 
 // server run configuration
@@ -228,6 +243,7 @@ var theCfg = struct {
 	isDiskUse    bool              // if true then control disk space usage, it enabled if etc/disk.ini exists
 	isAdminAll   bool              // if true then allow global administrative routes: /admin-all
 	isReadonly   bool              // if true then only read API enabled, no update, upload, model run or admin API allowed, download partially disabled
+	isProxyUser  bool              // if true then oms is behind trusted reverse proxy and X-Forwarded-User header is request user name
 	jobDir       string            // job control directory
 	omsName      string            // oms instance name, if empty then derived from address to listen
 	dbcopyPath   string            // if download or upload allowed then it is path to dbcopy.exe
@@ -292,6 +308,7 @@ func mainBody(args []string) error {
 	_ = flag.String(jobDirArgKey, "", "job control directory, if relative then must be relative to root directory")
 	_ = flag.String(omsNameArgKey, "", "instance name, automatically generated if empty")
 	_ = flag.Bool(logRequestArgKey, false, "if true then log HTTP requests")
+	_ = flag.Bool(proxyUserArgKey, false, "if true then use X-Forwarded-User header from trusted reverse proxy as request user name")
 	_ = flag.Bool(apiOnlyArgKey, false, "if true then API only web-service, no web UI")
 	_ = flag.Bool(readOnlyArgKey, false, "if true then only read API enabled, no update, upload, model run or admin API")
 	_ = flag.Bool(adminAllArgKey, false, "if true then allow global administrative routes: /admin-all/")
@@ -301,6 +318,7 @@ func mainBody(args []string) error {
 	_ = flag.String(msgLangArgKey, "", "oms output messages language, e.g.: fr-CA, default: current user OS language")
 	_ = flag.String(encodingArgKey, "", "code page to convert source file into utf-8, e.g.: windows-1252")
 	_ = flag.String(doubleFormatArgKey, theCfg.doubleFmt, "format to convert float or double value to string")
+	_ = flag.Int(calcCacheArgKey, calcCacheDefaultRows, "max number of rows in calculation results cache, if zero then cache disabled")
 	_ = flag.String(pidFileArgKey, "", "file path to save OMS process ID")
 
 	// pairs of full and short argument names to map short name to full name
@@ -317,11 +335,13 @@ func mainBody(args []string) error {
 	isApiOnly := runOpts.Bool(apiOnlyArgKey)
 	theCfg.isMicrodata = runOpts.Bool(isMicrodataArgKey)
 	theCfg.isReadonly = runOpts.Bool(readOnlyArgKey)
+	theCfg.isProxyUser = runOpts.Bool(proxyUserArgKey)
 	isAdmin := !runOpts.Bool(noAdminArgKey)
 	theCfg.isAdminAll = !theCfg.isReadonly && isAdmin && runOpts.Bool(adminAllArgKey)
 	isShutdown := !theCfg.isReadonly && !runOpts.Bool(noShutdownArgKey)
 	theCfg.doubleFmt = runOpts.String(doubleFormatArgKey)
 	theCfg.encodingName = runOpts.String(encodingArgKey)
+	theCalcCache.setMaxRows(runOpts.Int(calcCacheArgKey, calcCacheDefaultRows))
 
 	// get server config environmemt variables and pass it to UI
 	env := os.Environ()
@@ -606,8 +626,8 @@ func mainBody(args []string) error {
 	router.SetGlobalCors(&vestigo.CorsAccessControl{
 		AllowOrigin:      []string{"*"},
 		AllowCredentials: true,
-		AllowHeaders:     []string{"Content-Type"},
-		ExposeHeaders:    []string{"Content-Type", "Content-Location"},
+		AllowHeaders:     []string{"Content-Type", "If-Match"},
+		ExposeHeaders:    []string{"Content-Type", "Content-Location", "ETag"},
 	})
 
 	apiGetRoutes(router)     // web-service /api routes to get metadata
@@ -66,6 +66,12 @@ func apiGetRoutes(router *vestigo.Router) {
 	// GET /api/model/:model/profile-list
 	router.Get("/api/model/:model/profile-list", modelProfileListHandler, logRequest)
 
+	// GET /api/model/:model/derived-list
+	router.Get("/api/model/:model/derived-list", derivedMeasureListHandler, logRequest)
+
+	// GET /api/model/:model/parameter-rules
+	router.Get("/api/model/:model/parameter-rules", paramRuleListHandler, logRequest)
+
 	//
 	// GET model run results
 	//
@@ -110,6 +116,11 @@ func apiGetRoutes(router *vestigo.Router) {
 	// GET /api/model/:model/run/:run/text-all
 	router.Get("/api/model/:model/run/:run/text-all", runAllTextHandler, logRequest)
 
+	// GET /api/model/:model/run/:run/lineage
+	// GET /api/model/:model/run/:run/parameter/:name/lineage
+	router.Get("/api/model/:model/run/:run/lineage", runParameterLineageHandler, logRequest)
+	router.Get("/api/model/:model/run/:run/parameter/:name/lineage", runParameterLineageHandler, logRequest)
+
 	//
 	// GET model set of input parameters (workset)
 	//
@@ -140,6 +151,39 @@ func apiGetRoutes(router *vestigo.Router) {
 	// GET /api/model/:model/workset/:set/text-all
 	router.Get("/api/model/:model/workset/:set/text-all", worksetAllTextHandler, logRequest)
 
+	// GET /api/model/:model/workset/:set/validate
+	router.Get("/api/model/:model/workset/:set/validate", worksetValidateHandler, logRequest)
+
+	// GET /api/model/:model/workset/:set/import
+	router.Get("/api/model/:model/workset/:set/import", worksetImportHandler, logRequest)
+
+	// GET /api/model/:model/workset/:set/merge-report
+	router.Get("/api/model/:model/workset/:set/merge-report", worksetMergeReportHandler, logRequest)
+
+	// GET /api/model/:model/workset/:set/template
+	router.Get("/api/model/:model/workset/:set/template", worksetTemplateHandler, logRequest)
+
+	// GET /api/model/:model/workset/:set/source
+	router.Get("/api/model/:model/workset/:set/source", worksetSourceHandler, logRequest)
+
+	// GET /api/model/:model/workset/:set/storage
+	router.Get("/api/model/:model/workset/:set/storage", worksetStorageHandler, logRequest)
+
+	// GET /api/model/:model/workset/:set/history
+	// GET /api/model/:model/workset/:set/parameter/:name/history
+	router.Get("/api/model/:model/workset/:set/history", worksetHistoryGetHandler, logRequest)
+	router.Get("/api/model/:model/workset/:set/parameter/:name/history", worksetParameterHistoryGetHandler, logRequest)
+
+	// GET /api/model/:model/workset/:set/parameter/:name/history-diff/:from/:to
+	// GET /api/model/:model/workset/:set/parameter/:name/history-diff-id/:from/:to
+	router.Get("/api/model/:model/workset/:set/parameter/:name/history-diff/:from/:to", worksetParameterHistoryDiffGetHandler, logRequest)
+	router.Get("/api/model/:model/workset/:set/parameter/:name/history-diff-id/:from/:to", worksetParameterHistoryDiffIdGetHandler, logRequest)
+	// reject if request ill-formed
+	router.Get("/api/model/:model/workset/:set/parameter/:name/history-diff/", http.NotFound)
+	router.Get("/api/model/:model/workset/:set/parameter/:name/history-diff/:from/", http.NotFound)
+	router.Get("/api/model/:model/workset/:set/parameter/:name/history-diff-id/", http.NotFound)
+	router.Get("/api/model/:model/workset/:set/parameter/:name/history-diff-id/:from/", http.NotFound)
+
 	//
 	// GET modeling tasks and task run history
 	//
@@ -202,6 +246,16 @@ func apiReadRoutes(router *vestigo.Router) {
 	router.Post("/api/model/:model/run/:run/parameter/value", runParameterPageReadHandler, logRequest)
 	router.Post("/api/model/:model/run/:run/parameter/value-id", runParameterIdPageReadHandler, logRequest)
 
+	// POST /api/model/:model/workset/:set/parameter/compare
+	// POST /api/model/:model/workset/:set/parameter/compare-id
+	router.Post("/api/model/:model/workset/:set/parameter/compare", worksetParameterComparePageReadHandler, logRequest)
+	router.Post("/api/model/:model/workset/:set/parameter/compare-id", worksetParameterCompareIdPageReadHandler, logRequest)
+
+	// POST /api/model/:model/run/:run/parameter/compare
+	// POST /api/model/:model/run/:run/parameter/compare-id
+	router.Post("/api/model/:model/run/:run/parameter/compare", runParameterComparePageReadHandler, logRequest)
+	router.Post("/api/model/:model/run/:run/parameter/compare-id", runParameterCompareIdPageReadHandler, logRequest)
+
 	// POST /api/model/:model/run/:run/table/value
 	// POST /api/model/:model/run/:run/table/value-id
 	router.Post("/api/model/:model/run/:run/table/value", runTablePageReadHandler, logRequest)
@@ -217,6 +271,9 @@ func apiReadRoutes(router *vestigo.Router) {
 	router.Post("/api/model/:model/run/:run/table/compare", runTableComparePageReadHandler, logRequest)
 	router.Post("/api/model/:model/run/:run/table/compare-id", runTableCompareIdPageReadHandler, logRequest)
 
+	// POST /api/model/:model/calc-validate
+	router.Post("/api/model/:model/calc-validate", calcValidateHandler, logRequest)
+
 	if theCfg.isMicrodata {
 
 		// POST /api/model/:model/run/:run/microdata/value
@@ -259,6 +316,27 @@ func apiReadRoutes(router *vestigo.Router) {
 	router.Get("/api/model/:model/run/:run/parameter/:name/value/start/", http.NotFound)
 	router.Get("/api/model/:model/run/:run/parameter/:name/value/start/:start/count/", http.NotFound)
 
+	// GET /api/model/:model/run/:run/parameter-diff/run/:variant
+	// GET /api/model/:model/run/:run/parameter-diff/workset/:variant
+	// GET /api/model/:model/workset/:set/parameter-diff/run/:variant
+	// GET /api/model/:model/workset/:set/parameter-diff/workset/:variant
+	router.Get("/api/model/:model/run/:run/parameter-diff/run/:variant", runRunParameterDiffGetHandler, logRequest)
+	router.Get("/api/model/:model/run/:run/parameter-diff/workset/:variant", runWorksetParameterDiffGetHandler, logRequest)
+	router.Get("/api/model/:model/workset/:set/parameter-diff/run/:variant", worksetRunParameterDiffGetHandler, logRequest)
+	router.Get("/api/model/:model/workset/:set/parameter-diff/workset/:variant", worksetWorksetParameterDiffGetHandler, logRequest)
+	// reject if request ill-formed
+	router.Get("/api/model/:model/run/:run/parameter-diff/", http.NotFound)
+	router.Get("/api/model/:model/run/:run/parameter-diff/run/", http.NotFound)
+	router.Get("/api/model/:model/run/:run/parameter-diff/workset/", http.NotFound)
+	router.Get("/api/model/:model/workset/:set/parameter-diff/", http.NotFound)
+	router.Get("/api/model/:model/workset/:set/parameter-diff/run/", http.NotFound)
+	router.Get("/api/model/:model/workset/:set/parameter-diff/workset/", http.NotFound)
+
+	// GET /api/model/:model/run/:run/run-diff/:variant
+	router.Get("/api/model/:model/run/:run/run-diff/:variant", runDiffGetHandler, logRequest)
+	// reject if request ill-formed
+	router.Get("/api/model/:model/run/:run/run-diff/", http.NotFound)
+
 	// GET /api/model/:model/run/:run/table/:name/expr
 	// GET /api/model/:model/run/:run/table/:name/expr/start/:start
 	// GET /api/model/:model/run/:run/table/:name/expr/start/:start/count/:count
@@ -317,6 +395,18 @@ func apiReadRoutes(router *vestigo.Router) {
 	router.Get("/api/model/:model/run/:run/table/:name/compare/:compare/variant/:variant/start/", http.NotFound)
 	router.Get("/api/model/:model/run/:run/table/:name/compare/:compare/variant/:variant/start/:start/count/", http.NotFound)
 
+	// GET /api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant
+	// GET /api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant/start/:start
+	// GET /api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant/start/:start/count/:count
+	router.Get("/api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant", runTableRunAggrPageGetHandler, logRequest)
+	router.Get("/api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant/start/:start", runTableRunAggrPageGetHandler, logRequest)
+	router.Get("/api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant/start/:start/count/:count", runTableRunAggrPageGetHandler, logRequest)
+	// reject if request ill-formed
+	router.Get("/api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/", http.NotFound)
+	router.Get("/api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant/", http.NotFound)
+	router.Get("/api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant/start/", http.NotFound)
+	router.Get("/api/model/:model/run/:run/table/:name/run-aggr/:aggr/variant/:variant/start/:start/count/", http.NotFound)
+
 	if theCfg.isMicrodata {
 
 		// GET /api/model/:model/run/:run/microdata/:name/value
@@ -477,6 +567,19 @@ func apiUpdateRoutes(router *vestigo.Router) {
 	router.Delete("/api/model/:model/profile/:profile/key/:key", profileOptionDeleteHandler, logRequest)
 	router.Delete("/api/model/:model/profile/:profile/key/", http.NotFound)
 
+	// PATCH /api/model/:model/derived
+	router.Patch("/api/model/:model/derived", derivedMeasureReplaceHandler, logRequest)
+	router.Patch("/api/model/:model/derived/", http.NotFound)
+
+	// DELETE /api/model/:model/derived/:name
+	router.Delete("/api/model/:model/derived/:name", derivedMeasureDeleteHandler, logRequest)
+	router.Delete("/api/model/:model/derived/", http.NotFound)
+
+	// PATCH  /api/model/:model/parameter/:name/rules
+	// DELETE /api/model/:model/parameter/:name/rules
+	router.Patch("/api/model/:model/parameter/:name/rules", paramRulesReplaceHandler, logRequest)
+	router.Delete("/api/model/:model/parameter/:name/rules", paramRulesDeleteHandler, logRequest)
+
 	//
 	// update model set of input parameters (workset)
 	//
@@ -507,10 +610,34 @@ func apiUpdateRoutes(router *vestigo.Router) {
 	// PATCH /api/model/:model/workset/:set/parameter/:name/new/value-id
 	router.Patch("/api/model/:model/workset/:set/parameter/:name/new/value-id", parameterIdPageUpdateHandler, logRequest)
 
+	// PATCH /api/model/:model/workset/:set/parameter/:name/new/formula
+	router.Patch("/api/model/:model/workset/:set/parameter/:name/new/formula", parameterFormulaUpdateHandler, logRequest)
+
+	// PATCH /api/model/:model/workset/:set/parameter/:name/sparse
+	// PATCH /api/model/:model/workset/:set/parameter/:name/resolve
+	router.Patch("/api/model/:model/workset/:set/parameter/:name/sparse", parameterSparseUpdateHandler, logRequest)
+	router.Patch("/api/model/:model/workset/:set/parameter/:name/resolve", parameterResolveUpdateHandler, logRequest)
+
 	// DELETE /api/model/:model/workset/:set/parameter/:name
 	router.Delete("/api/model/:model/workset/:set/parameter/:name", worksetParameterDeleteHandler, logRequest)
 	router.Delete("/api/model/:model/workset/:set/parameter/", http.NotFound)
 
+	// PUT  /api/model/:model/workset/:set/template
+	router.Put("/api/model/:model/workset/:set/template", worksetTemplateApplyHandler, logRequest)
+
+	// PATCH /api/model/:model/workset/:set/template
+	// PATCH /api/model/:model/workset/:set/template/base-run/:run
+	router.Patch("/api/model/:model/workset/:set/template", worksetTemplateReapplyHandler, logRequest)
+	router.Patch("/api/model/:model/workset/:set/template/base-run/:run", worksetTemplateReapplyHandler, logRequest)
+	router.Patch("/api/model/:model/workset/:set/template/base-run/", http.NotFound)
+
+	// PUT  /api/model/:model/workset/:set/three-way-merge
+	router.Put("/api/model/:model/workset/:set/three-way-merge", worksetThreeWayMergeHandler, logRequest)
+
+	// PUT  /api/model/:model/workset/:set/import/from-model/:from-model/run/:run
+	router.Put("/api/model/:model/workset/:set/import/from-model/:from-model/run/:run", worksetImportFromRunHandler, logRequest)
+	router.Put("/api/model/:model/workset/:set/import/from-model/:from-model/run/", http.NotFound)
+
 	// PUT  /api/model/:model/workset/:set/copy/parameter/:name/from-run/:run
 	router.Put("/api/model/:model/workset/:set/copy/parameter/:name/from-run/:run", worksetParameterRunCopyHandler, logRequest)
 	router.Put("/api/model/:model/workset/:set/copy/parameter/:name/from-run/", http.NotFound)
@@ -530,6 +657,13 @@ func apiUpdateRoutes(router *vestigo.Router) {
 	// PATCH /api/model/:model/workset/:set/parameter-text
 	router.Patch("/api/model/:model/workset/:set/parameter-text", worksetParameterTextMergeHandler, logRequest)
 
+	// PUT /api/model/:model/workset/:set/history-restore/:version
+	// PUT /api/model/:model/workset/:set/parameter/:name/history-restore/:version
+	router.Put("/api/model/:model/workset/:set/history-restore/:version", worksetHistoryRestoreHandler, logRequest)
+	router.Put("/api/model/:model/workset/:set/parameter/:name/history-restore/:version", worksetParameterHistoryRestoreHandler, logRequest)
+	router.Put("/api/model/:model/workset/:set/history-restore/", http.NotFound)
+	router.Put("/api/model/:model/workset/:set/parameter/:name/history-restore/", http.NotFound)
+
 	//
 	// update model run
 	//
@@ -606,6 +740,10 @@ func apiDownloadRoutes(router *vestigo.Router) {
 	router.Get("/api/download/file-tree/:folder", fileTreeDownloadGetHandler, logRequest)
 	router.Get("/api/download/file-tree/", http.NotFound)
 
+	// GET /api/download/zip/:folder
+	router.Get("/api/download/zip/:folder", downloadZipGetHandler, logRequest)
+	router.Get("/api/download/zip/", http.NotFound)
+
 	// POST /api/download/model/:model
 	// POST /api/download/model/:model/lang/:lang
 	router.Post("/api/download/model/:model", modelDownloadPostHandler, logRequest)
@@ -780,6 +918,12 @@ func apiServiceRoutes(router *vestigo.Router) {
 	// POST /api/service/disk-use/refresh
 	router.Post("/api/service/disk-use/refresh", serviceRefreshDiskUseHandler, logRequest)
 
+	// GET /api/service/calc-cache
+	router.Get("/api/service/calc-cache", serviceCalcCacheHandler, logRequest)
+
+	// POST /api/service/calc-cache/clear
+	router.Post("/api/service/calc-cache/clear", serviceCalcCacheClearHandler, logRequest)
+
 	if !theCfg.isReadonly {
 
 		// GET /api/service/job/active/:job
@@ -824,6 +968,12 @@ func apiAdminRoutes(router *vestigo.Router) {
 	// POST /api/admin/model/:model/close
 	router.Post("/api/admin/model/:model/close", modelCloseHandler, logRequest)
 
+	// POST /api/admin/model/:model/verify
+	// POST /api/admin/model/:model/run/:run/verify
+	router.Post("/api/admin/model/:model/verify", modelVerifyHandler, logRequest)
+	router.Post("/api/admin/model/:model/run/:run/verify", modelVerifyHandler, logRequest)
+	router.Post("/api/admin/model/:model/run/", http.NotFound)
+
 	// POST /api/admin/db-file-open/:path
 	router.Post("/api/admin/db-file-open/:path", modelOpenDbFileHandler, logRequest)
 	router.Post("/api/admin/db-file-open/", http.NotFound)
@@ -136,6 +136,7 @@ func (mc *ModelCatalog) ReadOutTableTo(dn, rdsn string, layout *db.ReadTableLayo
 // Page started at zero based offset row and up to max page size rows, if page size <= 0 then all values returned.
 // Values can be from expression table, accumulator table or "all accumulators" view.
 // Rows can be filtered and ordered (see db.ReadTableLayout for details).
+// If layout.IsGoEval is true then calculation done in Go else calculation translated into sql.
 func (mc *ModelCatalog) ReadOutTableCalculateTo(
 	dn, rdsn string, layout *db.ReadTableLayout, calcLt []db.CalculateTableLayout, runIds []int, cvtWr func(src interface{}) (bool, error),
 ) (*db.ReadPageLayout, bool) {
@@ -184,8 +185,16 @@ func (mc *ModelCatalog) ReadOutTableCalculateTo(
 	}
 	layout.FromId = r.RunId // source run id
 
-	// read output table page
-	lt, err := db.ReadOutputTableCalculteTo(dbConn.DB, meta, layout, calcLt, runIds, cvtWr)
+	// read output table page from calculation results cache or from database
+	lt, err := theCalcCache.readTo(
+		"table", meta.Model.Digest, append([]int{layout.FromId}, runIds...), cvtWr,
+		func(wr func(src interface{}) (bool, error)) (*db.ReadPageLayout, error) {
+			if layout.IsGoEval {
+				return db.EvalOutputTableCalculteTo(dbConn.DB, meta, layout, calcLt, runIds, wr)
+			}
+			return db.ReadOutputTableCalculteTo(dbConn.DB, meta, layout, calcLt, runIds, wr)
+		},
+		layout, calcLt)
 	if err != nil {
 		omppLog.Log("Error at read output table: ", dn, ": ", layout.Name, ": ", err.Error())
 		return nil, false // return empty result: values select error
@@ -264,6 +273,7 @@ func (mc *ModelCatalog) ReadMicrodataTo(dn, rdsn string, layout *db.ReadMicroLay
 // Optional list of run id's can be supplied to read more than one run from output table.
 // Page started at zero based offset row and up to max page size rows, if page size <= 0 then all values returned.
 // Rows can be filtered and ordered (see db.ReadLayout for details).
+// If calcLt.IsGoEval is true then aggregation done in Go else aggregation translated into sql.
 func (mc *ModelCatalog) ReadMicrodataCalculateTo(
 	dn, rdsn string, layout *db.ReadMicroLayout, calcLt *db.CalculateMicroLayout, runIds []int, cvtWr func(src interface{}) (bool, error),
 ) (*db.ReadPageLayout, bool) {
@@ -323,8 +333,16 @@ func (mc *ModelCatalog) ReadMicrodataCalculateTo(
 		layout.GenDigest = entGen.GenDigest
 	}
 
-	// read microdata values page
-	lt, err := db.ReadMicrodataCalculateTo(dbConn.DB, meta, layout, calcLt, runIds, cvtWr)
+	// read microdata aggregation page from calculation results cache or from database
+	lt, err := theCalcCache.readTo(
+		"microdata", meta.Model.Digest, append([]int{layout.FromId}, runIds...), cvtWr,
+		func(wr func(src interface{}) (bool, error)) (*db.ReadPageLayout, error) {
+			if calcLt.IsGoEval {
+				return db.EvalMicrodataCalculateTo(dbConn.DB, meta, layout, calcLt, runIds, wr)
+			}
+			return db.ReadMicrodataCalculateTo(dbConn.DB, meta, layout, calcLt, runIds, wr)
+		},
+		layout, calcLt)
 	if err != nil {
 		omppLog.Log("Error at read microdata: ", dn, ": ", layout.Name, ": ", layout.GenDigest, ": ", err.Error())
 		return nil, false // return empty result: values select error
@@ -332,3 +350,180 @@ func (mc *ModelCatalog) ReadMicrodataCalculateTo(
 
 	return lt, true
 }
+
+// ParameterCompareSource find model run or workset to compare parameter values and return it source id and label.
+// Model run identified by digest-or-stamp-or-name and must be completed successfully, workset identified by name.
+// Source label is a run digest or workset name.
+func (mc *ModelCatalog) ParameterCompareSource(dn, src string, isSet bool) (db.CompareParamSource, string, bool) {
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Error: invalid (empty) model digest and name")
+		return db.CompareParamSource{}, "", false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return db.CompareParamSource{}, "", false
+	}
+
+	// find workset id by name
+	if isSet {
+
+		w, err := db.GetWorksetByName(dbConn.DB, meta.Model.ModelId, src)
+		if err != nil {
+			omppLog.Log("Error at get workset status:", meta.Model.Name, ":", src, ":", err.Error())
+			return db.CompareParamSource{}, "", false // return empty result: workset select error
+		}
+		if w == nil {
+			omppLog.Log("Warning: workset not found: ", meta.Model.Name, ": ", src)
+			return db.CompareParamSource{}, "", false // return empty result: workset_lst row not found
+		}
+		return db.CompareParamSource{Id: w.SetId, IsFromSet: true}, w.Name, true
+	}
+	// else find model run by digest, stamp or run name
+
+	r, ok := mc.CompletedRunByDigestOrStampOrName(dn, src)
+	if !ok {
+		return db.CompareParamSource{}, "", false // return empty result: run select error
+	}
+	if r.Status != db.DoneRunStatus {
+		omppLog.Log("Warning: model run not completed successfully: ", src, ": ", r.Status)
+		return db.CompareParamSource{}, "", false
+	}
+	return db.CompareParamSource{Id: r.RunId, IsFromSet: false}, r.RunDigest, true
+}
+
+// CompareParameterTo select "page" of parameter comparison between base run or workset and variant runs or worksets
+// and pass each row into cvtWr().
+// Base is defined by layout FromId and IsFromSet, variants by the list of run id's and set id's.
+// Page of values is a rows of comparison expressions started at zero based offset row
+// and up to max page size rows, if page size <= 0 then all values returned.
+// Rows can be filtered by dimensions and ordered (see db.ReadCalculteParamLayout for details).
+func (mc *ModelCatalog) CompareParameterTo(
+	dn string, layout *db.ReadCalculteParamLayout, variantLst []db.CompareParamSource, cvtWr func(src interface{}) (bool, error),
+) (*db.ReadPageLayout, bool) {
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Error: invalid (empty) model digest and name")
+		return nil, false
+	}
+	if layout.Name == "" {
+		omppLog.Log("Error: invalid (empty) parameter name")
+		return nil, false
+	}
+	if len(layout.Calculation) <= 0 {
+		omppLog.Log("Error: invalid (empty) parameter comparison expression(s): ", layout.Name)
+		return nil, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return nil, false
+	}
+
+	// check if parameter name exist in the model
+	if _, ok = meta.ParamByName(layout.Name); !ok {
+		omppLog.Log("Warning: parameter not found: ", layout.Name)
+		return nil, false // return empty result: parameter not found or error
+	}
+
+	// read parameter comparison page
+	lt, err := db.CompareParameterTo(dbConn.DB, meta, layout, variantLst, cvtWr)
+	if err != nil {
+		omppLog.Log("Error at parameter compare: ", dn, ": ", layout.Name, ": ", err.Error())
+		return nil, false // return empty result: values select error
+	}
+
+	return lt, true
+}
+
+// CompareParameterSummary compare all model parameters between base and variant run or workset.
+// Return list of parameters where for each parameter is-different flag and count of different base and variant cells.
+func (mc *ModelCatalog) CompareParameterSummary(dn string, base, variant db.CompareParamSource) ([]db.ParamCompareSummary, bool) {
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Error: invalid (empty) model digest and name")
+		return []db.ParamCompareSummary{}, false
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.ParamCompareSummary{}, false
+	}
+
+	smLst, err := db.CompareParameterSummary(dbConn.DB, meta, base, variant)
+	if err != nil {
+		omppLog.Log("Error at parameters compare: ", dn, ": ", err.Error())
+		return []db.ParamCompareSummary{}, false
+	}
+	return smLst, true
+}
+
+// CompareRuns return run options, parameters and output tables which are different between base and variant model runs.
+// Model runs must be completed successfully, runs can be found by run digest, run stamp or run name.
+func (mc *ModelCatalog) CompareRuns(dn, baseRdsn, varRdsn string) (*db.RunDiff, bool) {
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Error: invalid (empty) model digest and name")
+		return nil, false
+	}
+
+	// find base and variant model runs
+	baseRun, ok := mc.CompletedRunByDigestOrStampOrName(dn, baseRdsn)
+	if !ok {
+		return nil, false // return empty result: run select error
+	}
+	varRun, ok := mc.CompletedRunByDigestOrStampOrName(dn, varRdsn)
+	if !ok {
+		return nil, false // return empty result: run select error
+	}
+
+	// get model metadata and database connection
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return nil, false
+	}
+
+	rd, err := db.CompareRuns(dbConn.DB, meta, baseRun.RunId, varRun.RunId)
+	if err != nil {
+		omppLog.Log("Error at model runs compare: ", dn, ": ", baseRdsn, ": ", varRdsn, ": ", err.Error())
+		return nil, false
+	}
+	return rd, true
+}
+
+// ValidateCalculate parse and validate output table or microdata calculation expression without running it.
+// Return validation result with list of errors or false on error, for example, if model or output table not found.
+func (mc *ModelCatalog) ValidateCalculate(dn string, layout *db.ValidateCalcLayout) (*db.CalcValidate, bool) {
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Error: invalid (empty) model digest and name")
+		return nil, false
+	}
+
+	// get model metadata
+	meta, _, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return nil, false
+	}
+
+	cv, err := db.ValidateCalculate(meta, layout)
+	if err != nil {
+		omppLog.Log("Error at calculation validate: ", dn, ": ", layout.Name, ": ", err.Error())
+		return nil, false
+	}
+	return cv, true
+}
@@ -60,12 +60,37 @@ func (rsc *RunCatalog) runModel(job *RunJob, queueJobPath string, hfCfg hostIni,
 	}
 	binDir := mb.binDir
 
+	// if input workset has sparse parameters then create resolved copy of workset and use it for model run
+	rsSrc, rsName, err := resolveRunWorkset(job, rs)
+	if err != nil {
+		omppLog.Log("Model run error: ", err)
+		moveJobQueueToFailed(queueJobPath, rs.SubmitStamp, rs.ModelName, rs.ModelDigest, rs.RunStamp, false)
+		rs.IsFinal = true
+		return rs, err
+	}
+	isStarted := false
+	delResolved := func() {
+		if rsName != "" {
+			if e := theCatalog.UpdateRunSparseWorkset(rs.ModelDigest, rs.RunStamp, rsSrc); e != nil {
+				omppLog.Log("Error at update input workset of model run: ", rs.RunStamp, ": ", rsSrc.Name, ": ", e)
+			}
+			if _, e := theCatalog.DeleteWorkset(rs.ModelDigest, rsName, nil); e != nil {
+				omppLog.Log("Error at delete resolved workset: ", rsName, ": ", e)
+			}
+		}
+	}
+	defer func() {
+		if !isStarted {
+			delResolved()
+		}
+	}()
+
 	wDir := binDir
 	if job.Dir != "" {
 		wDir = filepath.Join(binRoot, job.Dir)
 	}
 
-	binDir, err := filepath.Abs(binDir) // relative to work dir fails on Windows if models are at \\UNC\path
+	binDir, err = filepath.Abs(binDir) // relative to work dir fails on Windows if models are at \\UNC\path
 	if err != nil {
 		binDir = binRoot
 	}
@@ -202,6 +227,7 @@ func (rsc *RunCatalog) runModel(job *RunJob, queueJobPath string, hfCfg hostIni,
 		return rs, err // exit with error: model failed to start
 	}
 	// else model started
+	isStarted = true
 	rs.pid = cmd.Process.Pid
 	cmdStart := time.Now().Unix() // model started, Unix seconds
 	rsc.updateRunStateProcess(rs, false)
@@ -241,6 +267,7 @@ func (rsc *RunCatalog) runModel(job *RunJob, queueJobPath string, hfCfg hostIni,
 		// wait for model run to be completed
 		e := cmd.Wait()
 		cmdStop := time.Now().Unix()
+		delResolved()
 
 		if e != nil {
 			omppLog.Log("Model run error: ", e)
@@ -442,6 +469,87 @@ func (rsc *RunCatalog) stopModelRun(modelDigest string, stamp string) (bool, str
 	return true, rsl.SubmitStamp, jobPath, false
 }
 
+// resolveRunWorkset create resolved copy of model run input workset if workset has sparse parameters.
+// Input workset can be specified by OpenM.SetId or OpenM.SetName run option.
+// Model run options updated to use resolved workset by name.
+// Resolved workset is deleted by caller after model run and model run options updated to refer to source workset.
+// Modeling task worksets are not resolved: it is an error if task workset has sparse parameters.
+// Return source workset row and name of resolved workset or nil if input workset does not have sparse parameters.
+func resolveRunWorkset(job *RunJob, rs *RunState) (*db.WorksetRow, string, error) {
+
+	// find input workset and modeling task in run options: OpenM.SetName, OpenM.SetId, OpenM.TaskName, OpenM.TaskId
+	nameKey := ""
+	idKey := ""
+	tn := ""
+	taskId := 0
+	for krq, val := range job.Opts {
+
+		if val == "" {
+			continue
+		}
+		key := strings.TrimPrefix(krq, "-")
+
+		switch {
+		case strings.EqualFold(key, "OpenM.SetName"):
+			nameKey = krq
+		case strings.EqualFold(key, "OpenM.SetId"):
+			idKey = krq
+		case strings.EqualFold(key, "OpenM.TaskName"):
+			tn = val
+		case strings.EqualFold(key, "OpenM.TaskId"):
+			if n, e := strconv.Atoi(val); e == nil {
+				taskId = n
+			}
+		}
+	}
+
+	// modeling task worksets must not have sparse parameters
+	if tn != "" || taskId > 0 {
+
+		wsn, err := theCatalog.TaskSparseWorkset(rs.ModelDigest, taskId, tn)
+		if err != nil {
+			return nil, "", errors.New("Error at find sparse worksets of modeling task " + tn + " of model " + rs.ModelName + ": " + err.Error())
+		}
+		if wsn != "" {
+			return nil, "", errors.New("Modeling task " + tn + " workset " + wsn + " has sparse parameters, resolve workset parameters before model run")
+		}
+	}
+
+	setId := 0
+	if idKey != "" {
+		if n, e := strconv.Atoi(job.Opts[idKey]); e == nil {
+			setId = n
+		}
+	}
+	wsn := ""
+	if nameKey != "" {
+		wsn = job.Opts[nameKey]
+	}
+	if setId <= 0 && wsn == "" {
+		return nil, "", nil // model run does not use input workset
+	}
+
+	src, dstName, err := theCatalog.ResolveSparseWorkset(rs.ModelDigest, setId, wsn, rs.RunStamp)
+	if err != nil {
+		return nil, "", errors.New("Error at resolve input workset " + wsn + " " + job.Opts[idKey] + " of model " + rs.ModelName + ": " + err.Error())
+	}
+	if src == nil {
+		return nil, "", nil // workset does not have sparse parameters
+	}
+	omppLog.Log("Run model: ", rs.ModelName, " using resolved input workset: ", dstName)
+
+	// use resolved workset by name
+	if idKey != "" {
+		delete(job.Opts, idKey)
+	}
+	if nameKey == "" {
+		nameKey = "OpenM.SetName"
+	}
+	job.Opts[nameKey] = dstName
+
+	return src, dstName, nil
+}
+
 // make model run command line arguments and create ini file if required
 func makeRunArgsIni(binDir, workDir, logDir string, job *RunJob, rs *RunState) ([]string, string, error) {
 
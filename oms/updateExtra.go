@@ -129,3 +129,115 @@ func (mc *ModelCatalog) DeleteProfileOption(dn, profile, key string) (bool, erro
 
 	return true, nil
 }
+
+// ReplaceDerivedMeasure insert new or replace existing derived measure of the model.
+func (mc *ModelCatalog) ReplaceDerivedMeasure(dn string, dm *db.DerivedMeasure) (bool, error) {
+
+	// if model digest-or-name or measure name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return false, nil
+	}
+	if dm.Name == "" {
+		omppLog.Log("Warning: invalid (empty) derived measure name")
+		return false, nil
+	}
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return false, nil
+	}
+
+	err := db.UpdateDerivedMeasure(dbConn.DB, meta, dm)
+	if err != nil {
+		omppLog.Log("Error at update derived measure: ", dn, ": ", dm.Name, ": ", err.Error())
+		return false, err
+	}
+	theCalcCache.deleteModel(meta.Model.Digest) // cached results can be calculated using previous measure definition
+
+	return true, nil
+}
+
+// DeleteDerivedMeasure delete derived measure of the model.
+// If no such measure exist in database then no error, empty operation.
+func (mc *ModelCatalog) DeleteDerivedMeasure(dn, name string) (bool, error) {
+
+	// if model digest-or-name or measure name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return false, nil
+	}
+	if name == "" {
+		omppLog.Log("Warning: invalid (empty) derived measure name")
+		return false, nil
+	}
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return false, nil
+	}
+
+	err := db.DeleteDerivedMeasure(dbConn.DB, meta, name)
+	theCalcCache.deleteModel(meta.Model.Digest) // cached results can be calculated using deleted measure
+	if err != nil {
+		omppLog.Log("Error at delete derived measure: ", dn, ": ", name, ": ", err.Error())
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReplaceParamRules insert new or replace existing validation rules of model parameter.
+func (mc *ModelCatalog) ReplaceParamRules(dn, name string, rules []db.ParamRule) (bool, error) {
+
+	// if model digest-or-name or parameter name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return false, nil
+	}
+	if name == "" {
+		omppLog.Log("Warning: invalid (empty) parameter name")
+		return false, nil
+	}
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return false, nil
+	}
+
+	err := db.UpdateParamRules(dbConn.DB, meta, name, rules)
+	if err != nil {
+		omppLog.Log("Error at update parameter rules: ", dn, ": ", name, ": ", err.Error())
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteParamRules delete validation rules of model parameter.
+// If parameter does not have any rules then no error, empty operation.
+func (mc *ModelCatalog) DeleteParamRules(dn, name string) (bool, error) {
+
+	// if model digest-or-name or parameter name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return false, nil
+	}
+	if name == "" {
+		omppLog.Log("Warning: invalid (empty) parameter name")
+		return false, nil
+	}
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return false, nil
+	}
+
+	err := db.DeleteParamRules(dbConn.DB, meta, name)
+	if err != nil {
+		omppLog.Log("Error at delete parameter rules: ", dn, ": ", name, ": ", err.Error())
+		return false, err
+	}
+
+	return true, nil
+}
@@ -107,6 +107,8 @@ func (mc *ModelCatalog) refreshSqlite(modelDir, modelLogDir string) error {
 	}
 
 	mc.modelLst = mLst // set new list of the models
+	theCalcCache.clear()
+
 	return nil
 }
 
@@ -363,8 +365,10 @@ func (mc *ModelCatalog) closeAll() error {
 		}
 	}
 
-	// clear model list
+	// clear model list and calculation results cache
 	mc.modelLst = []modelDef{}
+	theCalcCache.clear()
+
 	return firstErr
 }
 
@@ -394,6 +398,7 @@ func (mc *ModelCatalog) closeModel(dn string) (string, string, error) {
 			isFound = true
 			name = mc.modelLst[k].meta.Model.Name
 			binDir = mc.modelLst[k].binDir
+			theCalcCache.deleteModel(mc.modelLst[k].meta.Model.Digest)
 			continue
 		}
 		mc.modelLst[n] = mc.modelLst[k]
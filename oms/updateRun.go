@@ -49,6 +49,39 @@ func (mc *ModelCatalog) UpdateRunStatus(dn, rdsn string, status string) (bool, e
 	return true, nil
 }
 
+// UpdateRunSparseWorkset replace resolved copy of input workset by source sparse workset in model run options.
+// Model run is found by run stamp.
+func (mc *ModelCatalog) UpdateRunSparseWorkset(dn, stamp string, setRow *db.WorksetRow) error {
+
+	// validate parameters
+	if dn == "" {
+		return errors.New("Invalid (empty) model digest and name")
+	}
+	if stamp == "" {
+		return errors.New("Invalid (empty) model run stamp. Model: " + dn)
+	}
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return errors.New("Model digest or name not found: " + dn)
+	}
+
+	// find model run by stamp
+	r, err := db.GetRunByStamp(dbConn.DB, meta.Model.ModelId, stamp)
+	if err != nil {
+		omppLog.Log("Error at get model run: ", dn, ": ", stamp, ": ", err.Error())
+		return err
+	}
+	if r == nil {
+		return nil // model run not found: nothing to update
+	}
+
+	if err = db.UpdateRunSparseWorkset(dbConn.DB, r.RunId, setRow); err != nil {
+		omppLog.Log("Error at update model run input workset: ", dn, ": ", stamp, ": ", err.Error())
+		return err
+	}
+	return nil
+}
+
 // Start a separate thread to delete model run including output table values, input parameters and microdata.
 func (mc *ModelCatalog) DeleteRunStart(dn, rdsn string) (bool, error) {
 
@@ -78,15 +111,20 @@ func (mc *ModelCatalog) DeleteRunStart(dn, rdsn string) (bool, error) {
 	}
 
 	// do delete run from database in background
-	go func(dbc *sql.DB, runId int, dn, rdsn string) {
+	// remove run calculation results from cache before and after delete
+	theCalcCache.deleteRun(meta.Model.Digest, r.RunId)
+
+	go func(dbc *sql.DB, digest string, runId int, dn, rdsn string) {
 
 		e := db.DeleteRun(dbc, runId)
+		theCalcCache.deleteRun(digest, runId)
+
 		if e != nil {
 			omppLog.Log("Error at delete model run: ", dn, ": ", rdsn, ": ", e.Error())
 		} else {
 			omppLog.Log("Deleted model run: ", dn, ": ", rdsn)
 		}
-	}(dbConn.DB, r.RunId, dn, rdsn)
+	}(dbConn.DB, meta.Model.Digest, r.RunId, dn, rdsn)
 
 	return true, nil
 }
@@ -127,6 +165,11 @@ func (mc *ModelCatalog) DeleteRunListStart(dn string, rdsnLst []string) (bool, e
 	}
 
 	// do delete runs from database in background
+	// remove runs calculation results from cache before and after delete
+	for _, rId := range rIds {
+		theCalcCache.deleteRun(meta.Model.Digest, rId)
+	}
+
 	go func(dbc *sql.DB, modelDn string, runIds []int, rdsnMap map[int]string) {
 
 		slices.Sort(rIds) // delete runs in descending order
@@ -139,6 +182,8 @@ func (mc *ModelCatalog) DeleteRunListStart(dn string, rdsnLst []string) (bool, e
 			}
 
 			e := db.DeleteRun(dbc, runIds[k])
+			theCalcCache.deleteRun(meta.Model.Digest, runIds[k])
+
 			if e != nil {
 				omppLog.Log("Error at delete model run: ", modelDn, ": ", runIds[k], " ", rdsnMap[runIds[k]], ": ", e.Error())
 				return
@@ -16,7 +16,8 @@ import (
 )
 
 // UpdateWorksetReadonly update workset read-only status by model digest-or-name and workset name.
-func (mc *ModelCatalog) UpdateWorksetReadonly(dn, wsn string, isReadonly bool) (string, *db.WorksetRow, bool, error) {
+// If update action has expected update date-time then it is checked in the same transaction.
+func (mc *ModelCatalog) UpdateWorksetReadonly(dn, wsn string, isReadonly bool, upd *db.WorksetUpdateAction) (string, *db.WorksetRow, bool, error) {
 
 	// if model digest-or-name or workset name is empty then return empty results
 	if dn == "" {
@@ -45,7 +46,7 @@ func (mc *ModelCatalog) UpdateWorksetReadonly(dn, wsn string, isReadonly bool) (
 	}
 
 	// update workset readonly status
-	err = db.UpdateWorksetReadonly(dbConn.DB, w.SetId, isReadonly)
+	err = db.UpdateWorksetReadonlyChecked(dbConn.DB, w.SetId, isReadonly, upd)
 	if err != nil {
 		omppLog.Log("Error at update workset status: ", dn, ": ", wsn, ": ", err.Error())
 		return "", &db.WorksetRow{}, false, err // return empty result: workset select error
@@ -66,8 +67,9 @@ func (mc *ModelCatalog) UpdateWorksetReadonly(dn, wsn string, isReadonly bool) (
 }
 
 // UpdateWorkset update workset metadata: create new workset, replace existsing or merge metadata.
+// If update action has expected update date-time then existing workset update date-time is checked in the same transaction.
 // Return: isUpdated true/false flag, isEraseParam true/false warining flag, workset_lst db row and error
-func (mc *ModelCatalog) UpdateWorkset(isReplace bool, wp *db.WorksetPub) (bool, bool, *db.WorksetRow, error) {
+func (mc *ModelCatalog) UpdateWorkset(isReplace bool, wp *db.WorksetPub, upd *db.WorksetUpdateAction) (bool, bool, *db.WorksetRow, error) {
 
 	// if model digest-or-name or workset name is empty then return empty results
 	dn := wp.ModelDigest
@@ -142,7 +144,7 @@ func (mc *ModelCatalog) UpdateWorkset(isReplace bool, wp *db.WorksetPub) (bool,
 	}
 
 	// update workset metadata
-	err = wm.UpdateWorkset(dbConn.DB, meta, isReplace, langMeta)
+	err = wm.UpdateWorksetChecked(dbConn.DB, meta, isReplace, langMeta, upd)
 	if err != nil {
 		omppLog.Log("Error at update workset: ", dn, ": ", wp.Name, ": ", err.Error())
 		return false, isEraseParam, nil, err
@@ -163,7 +165,8 @@ func (mc *ModelCatalog) UpdateWorkset(isReplace bool, wp *db.WorksetPub) (bool,
 }
 
 // DeleteWorkset do delete workset, including parameter values from database.
-func (mc *ModelCatalog) DeleteWorkset(dn, wsn string) (bool, error) {
+// If update action has expected update date-time then it is checked in the same transaction.
+func (mc *ModelCatalog) DeleteWorkset(dn, wsn string, upd *db.WorksetUpdateAction) (bool, error) {
 
 	// if model digest-or-name or workset name is empty then return empty results
 	if dn == "" {
@@ -191,7 +194,7 @@ func (mc *ModelCatalog) DeleteWorkset(dn, wsn string) (bool, error) {
 	}
 
 	// delete workset from database
-	err = db.DeleteWorkset(dbConn.DB, w.SetId)
+	err = db.DeleteWorksetChecked(dbConn.DB, w.SetId, upd)
 	if err != nil {
 		omppLog.Log("Error at delete workset: ", dn, ": ", wsn, ": ", err.Error())
 		return false, err
@@ -201,8 +204,9 @@ func (mc *ModelCatalog) DeleteWorkset(dn, wsn string) (bool, error) {
 }
 
 // UpdateWorksetParameter replace or merge parameter metadata into workset and replace parameter values.
+// If update action not nil then current parameter values saved in workset history.
 func (mc *ModelCatalog) UpdateWorksetParameter(
-	isReplace bool, wp *db.WorksetPub, param *db.ParamRunSetPub, cArr []db.CellCodeParam,
+	isReplace bool, wp *db.WorksetPub, param *db.ParamRunSetPub, cArr []db.CellCodeParam, upd *db.WorksetUpdateAction,
 ) (
 	bool, error) {
 
@@ -277,7 +281,7 @@ func (mc *ModelCatalog) UpdateWorksetParameter(
 	}
 
 	// update workset parameter metadata and parameter values
-	hId, err := wm.UpdateWorksetParameterFrom(dbConn, meta, isReplace, param, langMeta, from)
+	hId, err := wm.UpdateWorksetParameterFrom(dbConn, meta, isReplace, param, langMeta, upd, from)
 	if err != nil {
 		omppLog.Log("Error at update workset: ", dn, ": ", wp.Name, ": ", err.Error())
 		return false, err
@@ -286,7 +290,7 @@ func (mc *ModelCatalog) UpdateWorksetParameter(
 }
 
 // UpdateWorksetParameterText do merge (insert or update) parameters value notes.
-func (mc *ModelCatalog) UpdateWorksetParameterText(dn, wsn string, pvtLst []db.ParamRunSetTxtPub) (bool, error) {
+func (mc *ModelCatalog) UpdateWorksetParameterText(dn, wsn string, pvtLst []db.ParamRunSetTxtPub, upd *db.WorksetUpdateAction) (bool, error) {
 
 	// validate parameters
 	if len(pvtLst) <= 0 {
@@ -348,8 +352,11 @@ func (mc *ModelCatalog) UpdateWorksetParameterText(dn, wsn string, pvtLst []db.P
 	}
 
 	// update workset parameter notes
-	err = db.UpdateWorksetParameterText(dbConn.DB, meta, wsn, pvtLst, langMeta)
+	err = db.UpdateWorksetParameterTextChecked(dbConn.DB, meta, wsn, pvtLst, langMeta, upd)
 	if err != nil {
+		if err == db.ErrWorksetUpdated {
+			return false, err
+		}
 		return false, errors.New("Error at update workset parameter notes: " + dn + ": " + wsn + ": " + err.Error())
 	}
 
@@ -357,8 +364,9 @@ func (mc *ModelCatalog) UpdateWorksetParameterText(dn, wsn string, pvtLst []db.P
 }
 
 // UpdateWorksetParameterCsv replace or merge parameter metadata into workset and replace parameter values from csv reader.
+// If update action not nil then current parameter values saved in workset history.
 func (mc *ModelCatalog) UpdateWorksetParameterCsv(
-	isReplace bool, wp *db.WorksetPub, param *db.ParamRunSetPub, csvRd *csv.Reader,
+	isReplace bool, wp *db.WorksetPub, param *db.ParamRunSetPub, csvRd *csv.Reader, upd *db.WorksetUpdateAction,
 ) (
 	bool, error) {
 
@@ -461,7 +469,7 @@ func (mc *ModelCatalog) UpdateWorksetParameterCsv(
 	}
 
 	// update workset parameter metadata and parameter values
-	hId, err := wm.UpdateWorksetParameterFrom(dbConn, meta, isReplace, param, langMeta, from)
+	hId, err := wm.UpdateWorksetParameterFrom(dbConn, meta, isReplace, param, langMeta, upd, from)
 	if err != nil {
 		omppLog.Log("Error at update workset: ", dn, ": ", wp.Name, ": ", err.Error())
 		return false, err
@@ -471,7 +479,8 @@ func (mc *ModelCatalog) UpdateWorksetParameterCsv(
 
 // UpdateWorksetParameterPage merge "page" of parameter values into workset.
 // Parameter must be already in workset and identified by model digest-or-name, set name, parameter name.
-func (mc *ModelCatalog) UpdateWorksetParameterPage(dn, wsn, name string, from func() (interface{}, error)) error {
+// If update action not nil then current parameter values saved in workset history.
+func (mc *ModelCatalog) UpdateWorksetParameterPage(dn, wsn, name string, upd *db.WorksetUpdateAction, from func() (interface{}, error)) error {
 
 	// if model digest-or-name, set name or paramete name is empty then return empty results
 	if dn == "" {
@@ -508,10 +517,11 @@ func (mc *ModelCatalog) UpdateWorksetParameterPage(dn, wsn, name string, from fu
 		return errors.New("Workset " + wsn + " not found in model: " + dn)
 	}
 	layout := db.WriteParamLayout{
-		WriteLayout: db.WriteLayout{Name: name, ToId: ws.SetId},
-		IsToRun:     false,
-		IsPage:      true,
-		DoubleFmt:   theCfg.doubleFmt,
+		WriteLayout:  db.WriteLayout{Name: name, ToId: ws.SetId},
+		IsToRun:      false,
+		IsPage:       true,
+		DoubleFmt:    theCfg.doubleFmt,
+		UpdateAction: upd,
 	}
 
 	// parameter must be in workset already
@@ -528,8 +538,178 @@ func (mc *ModelCatalog) UpdateWorksetParameterPage(dn, wsn, name string, from fu
 	return db.WriteParameterFrom(dbConn, meta, &layout, from)
 }
 
+// UpdateWorksetParameterFormula does bulk update of workset parameter values by formula and return number of changed cells.
+// Parameter must be already in workset and identified by model digest-or-name, set name, parameter name.
+// If update action not nil then current parameter values saved in workset history.
+func (mc *ModelCatalog) UpdateWorksetParameterFormula(dn, wsn string, layout *db.WorksetParamFormula, upd *db.WorksetUpdateAction) (int64, error) {
+
+	// if model digest-or-name, set name or paramete name is empty then return empty results
+	if dn == "" {
+		return 0, errors.New("Invalid (empty) model digest and name")
+	}
+	if wsn == "" {
+		return 0, errors.New("Invalid (empty) workset name. Model: " + dn)
+	}
+	if layout == nil || layout.Name == "" {
+		return 0, errors.New("Invalid (empty) parameter name. Model: " + dn + " workset: " + wsn)
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return 0, errors.New("Error: model digest or name not found: " + dn)
+	}
+
+	n, err := db.UpdateWorksetParameterFormula(dbConn.DB, meta, wsn, layout, upd)
+	if err != nil {
+		omppLog.Log("Error at update workset parameter by formula: ", dn, ": ", wsn, ": ", layout.Name, ": ", err.Error())
+		return 0, err
+	}
+	return n, nil
+}
+
+// ImportWorksetFromRun create new downstream model workset from upstream model run using model parameters import.
+// Each downstream model parameter imported from upstream model is included in new workset.
+// Upstream model run must be completed, run status one of: s=success, x=exit, e=error.
+// It is an error if workset already exist. Return list of imported parameters provenance.
+// If update action not nil then import of each parameter saved in workset history.
+func (mc *ModelCatalog) ImportWorksetFromRun(dn, wsn, upDn, rdsn string, upd *db.WorksetUpdateAction) ([]db.ParamImportSource, error) {
+
+	// validate parameters
+	if dn == "" {
+		return nil, errors.New("Workset import failed: invalid (empty) model digest and name")
+	}
+	if wsn == "" {
+		return nil, errors.New("Workset import failed: invalid (empty) workset name")
+	}
+	if upDn == "" {
+		return nil, errors.New("Workset import failed: invalid (empty) upstream model digest and name")
+	}
+	if rdsn == "" {
+		return nil, errors.New("Workset import failed: invalid (empty) model run digest or stamp or name")
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return nil, errors.New("Model digest or name not found: " + dn)
+	}
+	upMeta, upConn, ok := mc.modelMeta(upDn)
+	if !ok {
+		return nil, errors.New("Upstream model digest or name not found: " + upDn)
+	}
+	langMeta := mc.modelLangMeta(dn)
+	if langMeta == nil {
+		return nil, errors.New("Error: model language list not found: " + dn)
+	}
+
+	// find upstream model run by digest or stamp or name: it must be completed
+	r, ok := mc.CompletedRunByDigestOrStampOrName(upDn, rdsn)
+	if !ok || r == nil {
+		return nil, errors.New("Model run not found or not completed: " + upDn + ": " + rdsn)
+	}
+
+	isLst, err := db.ImportWorksetFromRun(upConn.DB, upMeta, r, dbConn, meta, langMeta, wsn, upd)
+	if err != nil {
+		omppLog.Log("Error at workset import: ", dn, ": ", wsn, " from: ", upDn, ": ", rdsn, ": ", err.Error())
+		return nil, err
+	}
+	return isLst, nil
+}
+
+// MergeWorksets does three-way merge of workset A and workset B using base run and save result into new workset.
+// Cells changed only in one workset merged automatically, cells changed in both worksets are conflicts.
+// It is an error if merged workset already exist. Return merge report with list of conflicts.
+func (mc *ModelCatalog) MergeWorksets(dn string, wm *db.WorksetMerge) (*db.WorksetMergeReport, error) {
+
+	// validate parameters
+	if dn == "" {
+		return nil, errors.New("Workset merge failed: invalid (empty) model digest and name")
+	}
+	if wm == nil || wm.Name == "" {
+		return nil, errors.New("Workset merge failed: invalid (empty) workset name")
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return nil, errors.New("Model digest or name not found: " + dn)
+	}
+	langMeta := mc.modelLangMeta(dn)
+	if langMeta == nil {
+		return nil, errors.New("Error: model language list not found: " + dn)
+	}
+
+	rpt, err := db.MergeWorksets(dbConn, meta, langMeta, wm)
+	if err != nil {
+		omppLog.Log("Error at workset merge: ", dn, ": ", wm.Name, " from: ", wm.SetA, ", ", wm.SetB, ": ", err.Error())
+		return nil, err
+	}
+	return rpt, nil
+}
+
+// ApplyWorksetTemplate create workset from template: base run or base workset and parameter overrides.
+// If isReplace is false then it is an error if workset already exist,
+// if isReplace is true then existing workset parameters deleted and created again.
+// Return template with actual base run digest and number of updated cells by each override.
+// If update action not nil then each parameter update saved in workset history.
+func (mc *ModelCatalog) ApplyWorksetTemplate(dn, wsn string, isReplace bool, tpl *db.WorksetTemplate, upd *db.WorksetUpdateAction) (*db.WorksetTemplate, error) {
+
+	// validate parameters
+	if dn == "" {
+		return nil, errors.New("Workset template apply failed: invalid (empty) model digest and name")
+	}
+	if wsn == "" {
+		return nil, errors.New("Workset template apply failed: invalid (empty) workset name")
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return nil, errors.New("Model digest or name not found: " + dn)
+	}
+	langMeta := mc.modelLangMeta(dn)
+	if langMeta == nil {
+		return nil, errors.New("Error: model language list not found: " + dn)
+	}
+
+	t, err := db.ApplyWorksetTemplate(dbConn, meta, langMeta, wsn, tpl, isReplace, upd)
+	if err != nil {
+		omppLog.Log("Error at workset template apply: ", dn, ": ", wsn, ": ", err.Error())
+		return nil, err
+	}
+	return t, nil
+}
+
+// ReapplyWorksetTemplate create workset again from stored workset template using new base run.
+// If base run digest, stamp or name is empty then template base run is used.
+// If update action not nil then current values of workset parameters saved in workset history.
+func (mc *ModelCatalog) ReapplyWorksetTemplate(dn, wsn, rdsn string, upd *db.WorksetUpdateAction) (*db.WorksetTemplate, error) {
+
+	// validate parameters
+	if dn == "" {
+		return nil, errors.New("Workset template apply failed: invalid (empty) model digest and name")
+	}
+	if wsn == "" {
+		return nil, errors.New("Workset template apply failed: invalid (empty) workset name")
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return nil, errors.New("Model digest or name not found: " + dn)
+	}
+	langMeta := mc.modelLangMeta(dn)
+	if langMeta == nil {
+		return nil, errors.New("Error: model language list not found: " + dn)
+	}
+
+	t, err := db.ReapplyWorksetTemplate(dbConn, meta, langMeta, wsn, rdsn, upd)
+	if err != nil {
+		omppLog.Log("Error at workset template apply: ", dn, ": ", wsn, ": ", rdsn, ": ", err.Error())
+		return nil, err
+	}
+	return t, nil
+}
+
 // DeleteWorksetParameter do delete workset parameter metadata and values from database.
-func (mc *ModelCatalog) DeleteWorksetParameter(dn, wsn, name string) (bool, error) {
+// If update action not nil then current parameter values saved in workset history.
+func (mc *ModelCatalog) DeleteWorksetParameter(dn, wsn, name string, upd *db.WorksetUpdateAction) (bool, error) {
 
 	// if model digest-or-name or workset name is empty then return empty results
 	if dn == "" {
@@ -552,7 +732,7 @@ func (mc *ModelCatalog) DeleteWorksetParameter(dn, wsn, name string) (bool, erro
 	}
 
 	// delete workset from database
-	hId, err := db.DeleteWorksetParameter(dbConn.DB, meta.Model.ModelId, wsn, name)
+	hId, err := db.DeleteWorksetParameterChecked(dbConn.DB, meta, wsn, name, upd)
 	if err != nil {
 		omppLog.Log("Error at update workset: ", dn, ": ", wsn, ": ", err.Error())
 		return false, err
@@ -565,7 +745,8 @@ func (mc *ModelCatalog) DeleteWorksetParameter(dn, wsn, name string) (bool, erro
 // If isReplace is false then existing parameter values and metadata deleted and new inserted from model run.
 // Destination workset must be in read-write state.
 // Source model run must be completed, run status one of: s=success, x=exit, e=error.
-func (mc *ModelCatalog) CopyParameterToWsFromRun(dn, wsn, name string, isReplace bool, rdsn string) error {
+// If update action not nil then current parameter values saved in workset history.
+func (mc *ModelCatalog) CopyParameterToWsFromRun(dn, wsn, name string, isReplace bool, rdsn string, upd *db.WorksetUpdateAction) error {
 
 	// validate parameters
 	if dn == "" {
@@ -621,8 +802,11 @@ func (mc *ModelCatalog) CopyParameterToWsFromRun(dn, wsn, name string, isReplace
 	}
 
 	// copy parameter into workset from model run
-	err := db.CopyParameterFromRun(dbConn.DB, meta, ws, name, isReplace, r)
+	err := db.CopyParameterFromRunChecked(dbConn.DB, meta, ws, name, isReplace, r, upd)
 	if err != nil {
+		if err == db.ErrWorksetUpdated {
+			return err
+		}
 		return errors.New("Parameter copy failed: " + wsn + ": " + name + ": " + err.Error())
 	}
 	return nil
@@ -633,7 +817,8 @@ func (mc *ModelCatalog) CopyParameterToWsFromRun(dn, wsn, name string, isReplace
 // If isReplace is false then existing parameter values and metadata deleted and new inserted from source workset.
 // Destination workset must be in read-write state.
 // Source workset must be read-only.
-func (mc *ModelCatalog) CopyParameterBetweenWs(dn, dstWsName, name string, isReplace bool, srcWsName string) error {
+// If update action not nil then current parameter values saved in workset history.
+func (mc *ModelCatalog) CopyParameterBetweenWs(dn, dstWsName, name string, isReplace bool, srcWsName string, upd *db.WorksetUpdateAction) error {
 
 	// validate parameters
 	if dn == "" {
@@ -692,9 +877,145 @@ func (mc *ModelCatalog) CopyParameterBetweenWs(dn, dstWsName, name string, isRep
 	}
 
 	// copy parameter from one workset to another
-	err := db.CopyParameterFromWorkset(dbConn.DB, meta, dstWs, name, isReplace, srcWs)
+	err := db.CopyParameterFromWorksetChecked(dbConn.DB, meta, dstWs, name, isReplace, srcWs, upd)
 	if err != nil {
+		if err == db.ErrWorksetUpdated {
+			return err
+		}
 		return errors.New("Parameter copy failed: " + dstWsName + ": " + name + ": " + err.Error())
 	}
 	return nil
 }
+
+// UpdateWorksetParameterSparse make workset parameter sparse or resolve sparse parameter and return parameter storage size.
+// Sparse parameter stores in workset only cells which are different from workset base run or default workset.
+// Resolved parameter stores in workset all cells. Workset must be in read-write state.
+// If update action not nil then current parameter values saved in workset history.
+func (mc *ModelCatalog) UpdateWorksetParameterSparse(dn, wsn, name string, isSparse bool, upd *db.WorksetUpdateAction) (*db.WorksetParamStorage, error) {
+
+	// if model digest-or-name, set name or paramete name is empty then return empty results
+	if dn == "" {
+		return nil, errors.New("Invalid (empty) model digest and name")
+	}
+	if wsn == "" {
+		return nil, errors.New("Invalid (empty) workset name. Model: " + dn)
+	}
+	if name == "" {
+		return nil, errors.New("Invalid (empty) parameter name. Model: " + dn + " workset: " + wsn)
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return nil, errors.New("Error: model digest or name not found: " + dn)
+	}
+
+	var ps *db.WorksetParamStorage
+	var err error
+	if isSparse {
+		ps, err = db.MakeWorksetParameterSparse(dbConn, meta, wsn, name, upd)
+	} else {
+		ps, err = db.ResolveWorksetParameter(dbConn, meta, wsn, name, upd)
+	}
+	if err != nil {
+		omppLog.Log("Error at update workset sparse parameter: ", dn, ": ", wsn, ": ", name, ": ", err.Error())
+		return nil, err
+	}
+	return ps, nil
+}
+
+// ResolveSparseWorkset create new read-only workset where all sparse parameters of source workset are resolved.
+// Source workset is found by id, if id is positive, or by name.
+// New workset name is source workset name and the stamp: wsn.stamp.
+// Return source workset row and new workset name
+// or nil if source workset does not have any sparse parameters, new workset is not created in that case.
+func (mc *ModelCatalog) ResolveSparseWorkset(dn string, setId int, wsn, stamp string) (*db.WorksetRow, string, error) {
+
+	// validate parameters
+	if dn == "" {
+		return nil, "", errors.New("Workset resolve failed: invalid (empty) model digest and name")
+	}
+	if setId <= 0 && wsn == "" {
+		return nil, "", errors.New("Workset resolve failed: invalid (empty) workset name")
+	}
+	if stamp == "" {
+		return nil, "", errors.New("Workset resolve failed: invalid (empty) stamp")
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return nil, "", errors.New("Model digest or name not found: " + dn)
+	}
+	langMeta := mc.modelLangMeta(dn)
+	if langMeta == nil {
+		return nil, "", errors.New("Error: model language list not found: " + dn)
+	}
+
+	// find source workset by id or by name
+	var w *db.WorksetRow
+	var err error
+	if setId > 0 {
+		w, err = db.GetWorkset(dbConn.DB, setId)
+	} else {
+		w, err = db.GetWorksetByName(dbConn.DB, meta.Model.ModelId, wsn)
+	}
+	if err != nil {
+		omppLog.Log("Error at get workset: ", dn, ": ", setId, ": ", wsn, ": ", err.Error())
+		return nil, "", err
+	}
+	if w == nil || w.ModelId != meta.Model.ModelId {
+		return nil, "", nil // workset not found: model run fails with workset not found error
+	}
+	dstName := w.Name + "." + stamp
+
+	isResolved, err := db.ResolveSparseWorkset(dbConn, meta, langMeta, w.Name, dstName)
+	if err != nil {
+		omppLog.Log("Error at workset resolve: ", dn, ": ", w.Name, ": ", dstName, ": ", err.Error())
+		return nil, "", err
+	}
+	if !isResolved {
+		return nil, "", nil
+	}
+	return w, dstName, nil
+}
+
+// TaskSparseWorkset return name of first modeling task workset which has sparse parameters
+// or empty "" string if task worksets do not have sparse parameters.
+// Task is found by id, if id is positive, or by name.
+func (mc *ModelCatalog) TaskSparseWorkset(dn string, taskId int, tn string) (string, error) {
+
+	// validate parameters
+	if dn == "" {
+		return "", errors.New("Invalid (empty) model digest and name")
+	}
+	if taskId <= 0 && tn == "" {
+		return "", errors.New("Invalid (empty) modeling task name. Model: " + dn)
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return "", errors.New("Model digest or name not found: " + dn)
+	}
+
+	// find task by id or by name
+	if taskId <= 0 {
+		t, err := db.GetTaskByName(dbConn.DB, meta.Model.ModelId, tn)
+		if err != nil {
+			omppLog.Log("Error at get modeling task: ", dn, ": ", tn, ": ", err.Error())
+			return "", err
+		}
+		if t == nil {
+			return "", nil // task not found: model run fails with task not found error
+		}
+		taskId = t.TaskId
+	}
+
+	w, err := db.FindTaskSparseWorkset(dbConn.DB, taskId)
+	if err != nil {
+		omppLog.Log("Error at get modeling task worksets: ", dn, ": ", taskId, ": ", tn, ": ", err.Error())
+		return "", err
+	}
+	if w == nil {
+		return "", nil
+	}
+	return w.Name, nil
+}
@@ -174,7 +174,7 @@ func (mc *ModelCatalog) TableToCodeCalcCellConverter(
 }
 
 // TableAggrExprCalculateLayout return calculate layout
-// either for all expressions by aggregation name: sum avg count min max var sd se cv
+// either for all expressions by aggregation name: sum avg count min max var sd se cv median
 // or from comma separated list of aggregation exprission(s), for example: OM_AVG(acc0) , OM_SD(acc1)
 func (mc *ModelCatalog) TableAggrExprCalculateLayout(dn string, name string, aggr string) ([]db.CalculateTableLayout, bool) {
 
@@ -199,6 +199,8 @@ func (mc *ModelCatalog) TableAggrExprCalculateLayout(dn string, name string, agg
 		fnc = "OM_SE"
 	case "cv":
 		fnc = "OM_CV"
+	case "median":
+		fnc = "OM_MEDIAN"
 	default: // comma separated list of expressions
 
 		calcLt := []db.CalculateTableLayout{}
@@ -288,6 +290,115 @@ func (mc *ModelCatalog) TableAggrExprCalculateLayout(dn string, name string, agg
 	return calcLt, true
 }
 
+// TableRunAggrLayout return calculate layout to aggregate output table expressions across multiple model runs
+// either for all expressions by aggregation name: sum avg count min max var sd se cv median
+// or summary statistics for all expressions: average, standard deviation, 95% confidence interval, minimum and maximum
+// or from comma separated list of aggregation exprission(s), for example: OM_AVG(Expr0) , OM_SD(Expr1)
+func (mc *ModelCatalog) TableRunAggrLayout(dn string, name string, aggr string) ([]db.CalculateTableLayout, bool) {
+
+	// check aggregation operation
+	fncLst := []string{}
+	switch aggr {
+	case "sum":
+		fncLst = []string{"OM_SUM"}
+	case "avg":
+		fncLst = []string{"OM_AVG"}
+	case "count":
+		fncLst = []string{"OM_COUNT"}
+	case "min":
+		fncLst = []string{"OM_MIN"}
+	case "max":
+		fncLst = []string{"OM_MAX"}
+	case "var":
+		fncLst = []string{"OM_VAR"}
+	case "sd":
+		fncLst = []string{"OM_SD"}
+	case "se":
+		fncLst = []string{"OM_SE"}
+	case "cv":
+		fncLst = []string{"OM_CV"}
+	case "median":
+		fncLst = []string{"OM_MEDIAN"}
+	case "summary":
+		fncLst = []string{"OM_AVG", "OM_SD", "CI95_LOW", "CI95_HIGH", "OM_MIN", "OM_MAX"}
+	default: // comma separated list of expressions
+
+		calcLt := []db.CalculateTableLayout{}
+
+		ce := helper.ParseCsvLine(aggr, 0)
+		for j := range ce {
+
+			if ce[j] != "" {
+				calcLt = append(calcLt, db.CalculateTableLayout{
+					CalculateLayout: db.CalculateLayout{
+						Calculate: ce[j],
+						CalcId:    j + db.CALCULATED_ID_OFFSET,
+						Name:      "ex_" + strconv.Itoa(j+db.CALCULATED_ID_OFFSET),
+					},
+					IsRunAggr: true,
+				})
+			}
+		}
+		if len(calcLt) <= 0 {
+			omppLog.Log("Error: invalid (empty) calculation expression")
+			return []db.CalculateTableLayout{}, false
+		}
+
+		return calcLt, true
+	}
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Error: invalid (empty) model digest and name")
+		return []db.CalculateTableLayout{}, false
+	}
+
+	// get model metadata and database connection
+	meta, _, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Error: model digest or name not found: ", dn)
+		return []db.CalculateTableLayout{}, false // return empty result: model not found or error
+	}
+
+	// find output table by name
+	idx, ok := meta.OutTableByName(name)
+	if !ok {
+		omppLog.Log("Error: model output table not found: ", dn, ": ", name)
+		return []db.CalculateTableLayout{}, false
+	}
+	table := &meta.Table[idx]
+
+	// aggregate each output table expression across model runs
+	// for example: if table has Expr0 and Expr1 values and aggregation is SD
+	// then append to calculation: OM_SD(Expr0), OM_SD(Expr1)
+	calcLt := []db.CalculateTableLayout{}
+
+	for _, ex := range table.Expr {
+		for k, fnc := range fncLst {
+
+			// confidence interval is: average +/- 1.96 * standard error
+			calc := fnc + "(" + ex.Name + ")"
+			switch fnc {
+			case "CI95_LOW":
+				calc = "OM_AVG(" + ex.Name + ") - 1.96 * OM_SE(" + ex.Name + ")"
+			case "CI95_HIGH":
+				calc = "OM_AVG(" + ex.Name + ") + 1.96 * OM_SE(" + ex.Name + ")"
+			}
+
+			calcLt = append(calcLt, db.CalculateTableLayout{
+				CalculateLayout: db.CalculateLayout{
+					Calculate: calc,
+					CalcId:    ex.ExprId + (k+1)*db.CALCULATED_ID_OFFSET,
+					Name:      fnc + "_" + ex.Name,
+				},
+				IsRunAggr: true,
+			})
+		}
+	}
+
+	return calcLt, true
+}
+
 // TableExprCompareLayout return calculate layout
 // either for all expressions by name: diff ratio percent
 // or from comma separated list of exprission(s), for example: expr0 , 7 + expr1[variant] + expr2[base]
@@ -832,6 +943,7 @@ func (mc *ModelCatalog) MicrodataCalcToCsvConverter(
 		},
 		CalcMaps: db.EmptyCalcMaps(),
 		GroupBy:  calcLt.GroupBy,
+		Bins:     calcLt.Bins,
 	}
 	if e := cvtMicro.SetCalcIdNameMap(calcLt.Calculation); e != nil {
 		return 0, []int{}, "", nil,
@@ -878,3 +990,54 @@ func (mc *ModelCatalog) MicrodataCalcToCsvConverter(
 
 	return baseRunId, runIds, entGen.GenDigest, &cvtMicro, nil
 }
+
+// ParameterToCodeCalcCellConverter return parameter comparison value converter from id's cell into code cell.
+// Map of variant id to label is a map of run id's to run digest and set id's to workset name.
+func (mc *ModelCatalog) ParameterToCodeCalcCellConverter(
+	dn string, name string, calcLt []db.CalculateLayout, srcIdToLabel map[int]string,
+) (func(interface{}) (interface{}, error), bool) {
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Error: invalid (empty) model digest and name")
+		return nil, false
+	}
+
+	// get model metadata and database connection
+	meta, _, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Error: model digest or name not found: ", dn)
+		return nil, false // return empty result: model not found or error
+	}
+
+	// check if parameter name exist in the model
+	if _, ok = meta.ParamByName(name); !ok {
+		omppLog.Log("Error: model parameter not found: ", dn, ": ", name)
+		return nil, false
+	}
+
+	// create converter
+	cpc := db.CellParamCalcConverter{
+		CellParamConverter: db.CellParamConverter{
+			ModelDef:  meta,
+			Name:      name,
+			IsIdCsv:   false,
+			DoubleFmt: theCfg.doubleFmt,
+		},
+		CalcMaps: db.EmptyCalcMaps(),
+	}
+	if e := cpc.SetCalcIdNameMap(calcLt); e != nil {
+		omppLog.Log("Failed to create parameter cell id's to code converter: ", name, ": ", e.Error())
+		return nil, false
+	}
+	for id, label := range srcIdToLabel {
+		cpc.RunIdToLabel[id] = label
+	}
+
+	cvt, err := cpc.IdToCodeCell(meta, name)
+	if err != nil {
+		omppLog.Log("Failed to create parameter cell id's to code converter: ", name, ": ", err.Error())
+		return nil, false
+	}
+	return cvt, true
+}
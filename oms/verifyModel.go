@@ -0,0 +1,46 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// VerifyModelRuns recalculate value digests of model run(s) and compare it with digests stored in database,
+// find orphaned rows in value tables and metadata rows which are reference to missing Hid's.
+//
+// If run digest-or-stamp-or-name is empty then all completed model runs are verified.
+// Return verification results and true if model found and model run found or not specified.
+func (mc *ModelCatalog) VerifyModelRuns(dn, rdsn string) (*db.ModelVerify, bool, error) {
+
+	// if model digest-or-name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return nil, false, nil
+	}
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return nil, false, nil
+	}
+
+	// if model run specified then it must be completed
+	runIdLst := []int{}
+
+	if rdsn != "" {
+		r, ok := mc.CompletedRunByDigestOrStampOrName(dn, rdsn)
+		if !ok || r == nil {
+			return nil, false, nil // model run not found or not completed
+		}
+		runIdLst = append(runIdLst, r.RunId)
+	}
+
+	mv, err := db.VerifyModelRuns(dbConn.DB, meta, runIdLst, theCfg.doubleFmt)
+	if err != nil {
+		omppLog.Log("Error at model verification:", dn, ":", rdsn, ":", err.Error())
+		return nil, false, err
+	}
+	return mv, true, nil
+}
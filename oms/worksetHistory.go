@@ -0,0 +1,105 @@
+// Copyright (c) 2016 OpenM++
+// This code is licensed under the MIT license (see LICENSE.txt for details)
+
+package main
+
+import (
+	"errors"
+
+	"github.com/openmpp/go/ompp/db"
+	"github.com/openmpp/go/ompp/omppLog"
+)
+
+// WorksetHistoryList return list of saved versions of workset parameters.
+// If parameter name is empty then versions of all workset parameters returned.
+func (mc *ModelCatalog) WorksetHistoryList(dn, wsn, name string) ([]db.WorksetParamHistory, bool) {
+
+	// if model digest-or-name or workset name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return []db.WorksetParamHistory{}, false
+	}
+	if wsn == "" {
+		omppLog.Log("Warning: invalid (empty) workset name")
+		return []db.WorksetParamHistory{}, false
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.WorksetParamHistory{}, false
+	}
+
+	hLst, err := db.GetWorksetHistoryList(dbConn.DB, meta, wsn, name)
+	if err != nil {
+		omppLog.Log("Error at get workset history: ", dn, ": ", wsn, ": ", err.Error())
+		return []db.WorksetParamHistory{}, false
+	}
+	return hLst, true
+}
+
+// WorksetHistoryDiff compare two versions of workset parameter and return list of inserted, deleted and updated cells.
+// If version id is zero then current workset parameter values are used.
+// Cells dimension(s) and enum-based values are enum id's.
+func (mc *ModelCatalog) WorksetHistoryDiff(dn, wsn, name string, fromVer, toVer int) ([]db.WorksetParamCellDiff, bool) {
+
+	// if model digest-or-name, set name or paramete name is empty then return empty results
+	if dn == "" {
+		omppLog.Log("Warning: invalid (empty) model digest and name")
+		return []db.WorksetParamCellDiff{}, false
+	}
+	if wsn == "" {
+		omppLog.Log("Warning: invalid (empty) workset name")
+		return []db.WorksetParamCellDiff{}, false
+	}
+	if name == "" {
+		omppLog.Log("Warning: invalid (empty) workset parameter name")
+		return []db.WorksetParamCellDiff{}, false
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		omppLog.Log("Warning: model digest or name not found:", dn)
+		return []db.WorksetParamCellDiff{}, false
+	}
+
+	dLst, err := db.DiffWorksetHistory(dbConn.DB, meta, wsn, name, fromVer, toVer)
+	if err != nil {
+		omppLog.Log("Error at workset parameter versions compare: ", dn, ": ", wsn, ": ", name, ": ", err.Error())
+		return []db.WorksetParamCellDiff{}, false
+	}
+	return dLst, true
+}
+
+// RestoreWorksetParamHistory restore workset parameter values from saved version.
+// If parameter name is empty then all workset parameters are restored to the state when that version saved.
+// Current values are saved in history before restore. Return version id of saved current values.
+// If update action has expected update date-time then it is checked in the same transaction.
+func (mc *ModelCatalog) RestoreWorksetParamHistory(dn, wsn, name string, versionId int, upd *db.WorksetUpdateAction) (int, error) {
+
+	// if model digest-or-name or set name is empty then return error
+	if dn == "" {
+		return 0, errors.New("Invalid (empty) model digest and name")
+	}
+	if wsn == "" {
+		return 0, errors.New("Invalid (empty) workset name. Model: " + dn)
+	}
+
+	meta, dbConn, ok := mc.modelMeta(dn)
+	if !ok {
+		return 0, errors.New("Error: model digest or name not found: " + dn)
+	}
+
+	var verId int
+	var err error
+	if name != "" {
+		verId, err = db.RestoreWorksetParamHistoryChecked(dbConn, meta, wsn, name, versionId, upd)
+	} else {
+		verId, err = db.RestoreWorksetHistoryChecked(dbConn, meta, wsn, versionId, upd)
+	}
+	if err != nil {
+		omppLog.Log("Error at restore workset history: ", dn, ": ", wsn, ": ", name, ": ", err.Error())
+		return 0, err
+	}
+	return verId, nil
+}